@@ -0,0 +1,849 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/embeddings"
+	"github.com/xiy/memory-mcp/internal/mcp"
+	"github.com/xiy/memory-mcp/internal/memory"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// openAdminSQLite opens the SQLite store at db_path for admin subcommands.
+// With store.shards it holds the request, audit and miss logs but only the
+// unsharded memories.
+func openAdminSQLite(configPath string) (config.Config, *store.SQLiteStore, error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return cfg, nil, err
+	}
+	if err := cfg.EnsurePaths(); err != nil {
+		return cfg, nil, err
+	}
+	if cfg.Store.Driver != "sqlite" {
+		return cfg, nil, fmt.Errorf("admin commands require store.driver sqlite (got %q)", cfg.Store.Driver)
+	}
+	st, err := store.OpenSQLite(context.Background(), cfg.DBPath, log.New(os.Stderr), sqliteOptions(cfg)...)
+	return cfg, st, err
+}
+
+// auditStore is a store that keeps the audit log, closed when done.
+type auditStore interface {
+	store.AuditStore
+	Close() error
+}
+
+// openAdminAudit opens the store holding the audit log: db_path for the
+// sqlite driver, or the postgres database.
+func openAdminAudit(configPath string) (config.Config, auditStore, error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return cfg, nil, err
+	}
+	if cfg.Store.Driver != "postgres" {
+		return openAdminSQLite(configPath)
+	}
+	st, _, err := openStore(context.Background(), cfg, log.New(os.Stderr))
+	if err != nil {
+		return cfg, nil, err
+	}
+	as, ok := st.(auditStore)
+	if !ok {
+		_ = st.Close()
+		return cfg, nil, fmt.Errorf("store.driver %s keeps no audit log", cfg.Store.Driver)
+	}
+	return cfg, as, nil
+}
+
+// openAdminStore opens the SQLite store for admin subcommands that work
+// on memories, with every store.shards database behind it.
+func openAdminStore(configPath string) (config.Config, sqliteStore, error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return cfg, nil, err
+	}
+	if err := cfg.EnsurePaths(); err != nil {
+		return cfg, nil, err
+	}
+	if cfg.Store.Driver != "sqlite" {
+		return cfg, nil, fmt.Errorf("admin commands require store.driver sqlite (got %q)", cfg.Store.Driver)
+	}
+	st, err := openSQLite(context.Background(), cfg, log.New(os.Stderr))
+	return cfg, st, err
+}
+
+func runAdminReembed(args []string) error {
+	fs := flag.NewFlagSet("admin reembed", flag.ContinueOnError)
+	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	model := fs.String("model", "", "Embedding model to migrate to; must match a configured embeddings provider")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *model == "" {
+		return errors.New("--model is required")
+	}
+
+	cfg, st, err := openAdminStore(*configPath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	provider := embeddings.NewForModel(cfg.Embeddings, *model)
+	if provider == nil {
+		return fmt.Errorf("no embeddings provider configured for model %q", *model)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	target, ok := st.(store.EmbeddingStore)
+	if !ok {
+		return errors.New("the store does not keep embeddings")
+	}
+	n, err := embeddings.Reembed(ctx, target, provider, *model, func(done int) {
+		fmt.Fprintf(os.Stderr, "embedded %d memories\n", done)
+	})
+	if err != nil {
+		return fmt.Errorf("reembed stopped after %d memories (rerun to resume): %w", n, err)
+	}
+	if jsonOutput {
+		return printJSON("admin reembed", map[string]any{"model": *model, "embedded": n})
+	}
+	fmt.Printf("embedded %d memories; active model is now %s\n", n, *model)
+	return nil
+}
+
+func runAdminCluster(args []string) error {
+	fs := flag.NewFlagSet("admin cluster", flag.ContinueOnError)
+	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	namespace := fs.String("namespace", "", "Namespace to cluster")
+	k := fs.Int("k", 0, "Number of clusters (0 picks one from the memory count)")
+	scope := fs.String("scope", "", "Only cluster memories of this scope: short, long, project or global")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *namespace == "" {
+		return errors.New("--namespace is required")
+	}
+
+	cfg, st, err := openAdminStore(*configPath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	svc, err := memory.NewService(st, cfg, log.New(os.Stderr))
+	if err != nil {
+		return err
+	}
+	res, err := svc.Cluster(context.Background(), types.ClusterInput{Namespace: *namespace, Scope: *scope, K: *k})
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		return printJSON("admin cluster", res)
+	}
+
+	fmt.Printf("%s: %d memories in %d clusters\n", res.Namespace, res.Memories, len(res.Clusters))
+	for i, c := range res.Clusters {
+		fmt.Printf("\n[%d] %s (%d)\n    %s: %s\n", i+1, c.Label, c.Size, c.RepresentativeID, c.Summary)
+	}
+	return nil
+}
+
+func runAdminDedupeReport(args []string) error {
+	fs := flag.NewFlagSet("admin dedupe-report", flag.ContinueOnError)
+	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	namespace := fs.String("namespace", "", "Only scan this namespace (default: every namespace)")
+	threshold := fs.Float64("threshold", memory.DefaultDuplicateThreshold, "Word-set similarity at which memories count as near duplicates")
+	apply := fs.Bool("apply", false, "Merge each group into one memory and expire the originals")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, st, err := openAdminStore(*configPath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	svc, err := memory.NewService(st, cfg, log.New(os.Stderr))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	ctx = memory.WithCaller(ctx, memory.Caller{Tool: "admin dedupe-report"})
+
+	groups, err := svc.FindDuplicates(ctx, *namespace, *threshold)
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		return dedupeJSON(ctx, svc, groups, *apply)
+	}
+	if len(groups) == 0 {
+		fmt.Println("no duplicate memories found")
+		return nil
+	}
+	merged := 0
+	for _, g := range groups {
+		kind := "exact"
+		if !g.Exact {
+			kind = fmt.Sprintf("%.2f", g.Similarity)
+		}
+		fmt.Printf("%s  %-5s  %s  %s\n", g.Namespace, kind, strings.Join(g.MemoryIDs, ","), truncateLine(g.Summary, 80))
+		if !*apply {
+			continue
+		}
+		res, err := mergeDuplicates(ctx, svc, g)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("  merged into %s\n", res.Merged.ID)
+		merged++
+	}
+	if *apply {
+		fmt.Printf("%d groups merged\n", merged)
+	} else {
+		fmt.Printf("%d groups; rerun with --apply to merge them\n", len(groups))
+	}
+	return nil
+}
+
+// dedupeMerge is one merged group in the dedupe-report --json document.
+type dedupeMerge struct {
+	MemoryIDs []string `json:"memory_ids"`
+	MergedID  string   `json:"merged_id"`
+}
+
+// dedupeJSON prints the dedupe-report --json document, merging the groups
+// first with apply.
+func dedupeJSON(ctx context.Context, svc *memory.Service, groups []types.DuplicateGroup, apply bool) error {
+	out := struct {
+		Applied bool                   `json:"applied"`
+		Groups  []types.DuplicateGroup `json:"groups"`
+		Merged  []dedupeMerge          `json:"merged"`
+	}{Applied: apply, Groups: groups, Merged: []dedupeMerge{}}
+	if out.Groups == nil {
+		out.Groups = []types.DuplicateGroup{}
+	}
+	for _, g := range groups {
+		if !apply {
+			break
+		}
+		res, err := mergeDuplicates(ctx, svc, g)
+		if err != nil {
+			return err
+		}
+		out.Merged = append(out.Merged, dedupeMerge{MemoryIDs: g.MemoryIDs, MergedID: res.Merged.ID})
+	}
+	return printJSON("admin dedupe-report", out)
+}
+
+func mergeDuplicates(ctx context.Context, svc *memory.Service, g types.DuplicateGroup) (types.MergeResult, error) {
+	res, err := svc.Merge(ctx, types.MergeInput{MemoryIDs: g.MemoryIDs, SourceAgent: "memory-mcp/dedupe", ExpireOriginals: true})
+	if err != nil {
+		return res, fmt.Errorf("merge %s: %w", strings.Join(g.MemoryIDs, ","), err)
+	}
+	return res, nil
+}
+
+func truncateLine(s string, limit int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > limit {
+		return string(r[:limit-3]) + "..."
+	}
+	return s
+}
+
+func runAdminReplay(args []string) error {
+	fs := flag.NewFlagSet("admin replay", flag.ContinueOnError)
+	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	allowWrites := fs.Bool("allow-writes", false, "Let replayed writes, merges and deletes change the store (default: replay read-only)")
+	var idArg string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		idArg, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if idArg == "" {
+		idArg = fs.Arg(0)
+	}
+	id, err := strconv.ParseInt(idArg, 10, 64)
+	if err != nil {
+		return errors.New("usage: memory-mcp admin replay <request-id> (IDs are shown by the admin dashboard)")
+	}
+
+	cfg, st, err := openAdminStore(*configPath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	ctx := context.Background()
+	rec, err := st.GetMCPRequestLog(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no logged request %d", id)
+	}
+	if err != nil {
+		return err
+	}
+	if rec.Params == "" {
+		return fmt.Errorf("request %d (%s) has no stored params; only tools/call requests can be replayed", id, rec.Method)
+	}
+
+	if !*allowWrites {
+		cfg.ReadOnly = true
+	}
+	logger := log.New(os.Stderr)
+	svc, err := memory.NewService(st, cfg, logger)
+	if err != nil {
+		return err
+	}
+	server := mcp.NewServer(svc, logger, nil,
+		mcp.WithTools(cfg.Tools),
+		mcp.WithDefaultNamespace(defaultNamespace(ctx, cfg, logger)),
+		mcp.WithClient(mcp.ClientInfo{Name: rec.ClientName, Version: rec.ClientVersion}))
+
+	mode := "read-only"
+	if *allowWrites {
+		mode = "writes allowed"
+	}
+	if jsonOutput {
+		started := time.Now()
+		res, err := server.CallTool(ctx, json.RawMessage(rec.Params))
+		out := replayJSON{Request: rec, Mode: mode, ElapsedNS: time.Since(started), Result: res}
+		if err != nil {
+			out.Error = err.Error()
+		}
+		return printJSON("admin replay", out)
+	}
+
+	status := "ok"
+	if !rec.Success {
+		status = "error: " + rec.ErrorText
+	}
+	client := rec.ClientName
+	if client == "" {
+		client = "(unknown client)"
+	}
+	fmt.Printf("request %d at %s from %s: %s %dms\nparams: %s\n\n", rec.ID, rec.CreatedAt.Format(time.RFC3339), client, status, rec.DurationMS, rec.Params)
+
+	started := time.Now()
+	res, err := server.CallTool(ctx, json.RawMessage(rec.Params))
+	elapsed := time.Since(started).Round(time.Millisecond)
+	if err != nil {
+		fmt.Printf("replay failed after %s: %v\n", elapsed, err)
+		return nil
+	}
+	fmt.Printf("replayed in %s (%s):\n", elapsed, mode)
+	contents, _ := res["content"].([]map[string]any)
+	for _, c := range contents {
+		fmt.Println(c["text"])
+	}
+	return nil
+}
+
+// replayJSON is the admin replay --json document. Error is set instead of
+// Result when the replayed call failed.
+type replayJSON struct {
+	Request   store.MCPRequestLog `json:"request"`
+	Mode      string              `json:"mode"`
+	ElapsedNS time.Duration       `json:"elapsed_ns"`
+	Result    map[string]any      `json:"result,omitempty"`
+	Error     string              `json:"error,omitempty"`
+}
+
+func runAdminMisses(args []string) error {
+	fs := flag.NewFlagSet("admin misses", flag.ContinueOnError)
+	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	namespace := fs.String("namespace", "", "Only list misses in this namespace (default: every namespace)")
+	limit := fs.Int("limit", 20, "Maximum number of queries to list")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, st, err := openAdminSQLite(*configPath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	misses, err := st.SearchMisses(context.Background(), *namespace, *limit)
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		if misses == nil {
+			misses = []store.SearchMiss{}
+		}
+		return printJSON("admin misses", misses)
+	}
+	if len(misses) == 0 {
+		if !cfg.RecordSearchMisses {
+			fmt.Println("no search misses recorded; record_search_misses is off")
+		} else {
+			fmt.Println("no search misses recorded")
+		}
+		return nil
+	}
+	for _, m := range misses {
+		last := m.LastSeen.UTC()
+		if cfg.AdminTimezone == "local" {
+			last = last.Local()
+		}
+		agent := m.SourceAgent
+		if agent == "" {
+			agent = "-"
+		}
+		fmt.Printf("%5d  %s  %s  %s  %s\n", m.Count, last.Format(time.RFC3339), m.Namespace, agent, truncateLine(m.Query, 80))
+	}
+	return nil
+}
+
+func runAdminAudit(args []string) error {
+	fs := flag.NewFlagSet("admin audit", flag.ContinueOnError)
+	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	namespace := fs.String("namespace", "", "Only list changes in this namespace and those under it")
+	memoryID := fs.String("memory", "", "Only list changes to this memory ID")
+	action := fs.String("action", "", "Only list this action: write, update, promote, demote, delete, expire or purge")
+	actor := fs.String("actor", "", "Only list changes by this agent or client")
+	limit := fs.Int("limit", 50, "Maximum number of changes to list")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	switch *action {
+	case "", store.AuditWrite, store.AuditUpdate, store.AuditPromote, store.AuditDemote, store.AuditDelete, store.AuditExpire, store.AuditPurge:
+	default:
+		return fmt.Errorf("invalid --action %q (expected write, update, promote, demote, delete, expire or purge)", *action)
+	}
+
+	cfg, st, err := openAdminAudit(*configPath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	entries, err := st.AuditLog(context.Background(), store.AuditFilter{
+		NamespacePrefix: *namespace,
+		MemoryID:        *memoryID,
+		Action:          *action,
+		Actor:           *actor,
+		Limit:           *limit,
+	})
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		if entries == nil {
+			entries = []store.AuditEntry{}
+		}
+		return printJSON("admin audit", entries)
+	}
+	if len(entries) == 0 {
+		if !cfg.AuditLog {
+			fmt.Println("no changes recorded; audit_log is off")
+		} else {
+			fmt.Println("no changes recorded")
+		}
+		return nil
+	}
+	for _, e := range entries {
+		at := e.At.UTC()
+		if cfg.AdminTimezone == "local" {
+			at = at.Local()
+		}
+		actor, tool := e.Actor, e.Tool
+		if actor == "" {
+			actor = "-"
+		}
+		if tool == "" {
+			tool = "-"
+		}
+		change := truncateLine(e.After, 60)
+		switch {
+		case e.After == "":
+			change = truncateLine(e.Before, 60)
+		case e.Before != "" && e.Before != e.After:
+			change = truncateLine(e.Before, 40) + " -> " + truncateLine(e.After, 40)
+		}
+		fmt.Printf("%s  %-7s  %s  %s  %s  %s  %s\n", at.Format(time.RFC3339), e.Action, e.MemoryID, e.Namespace, actor, tool, change)
+	}
+	return nil
+}
+
+func runAdminPurge(args []string) error {
+	fs := flag.NewFlagSet("admin purge", flag.ContinueOnError)
+	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	key := fs.String("key", "", "Top-level metadata key to match, e.g. user_id")
+	value := fs.String("value", "", "Value the key must equal, compared as text")
+	redact := fs.Bool("redact", false, "Blank content and summary and drop the key instead of deleting")
+	dryRun := fs.Bool("dry-run", false, "List matching memories without changing them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *key == "" || *value == "" {
+		return errors.New("--key and --value are required")
+	}
+
+	cfg, st, err := openAdminStore(*configPath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	svc, err := memory.NewService(st, cfg, log.New(os.Stderr))
+	if err != nil {
+		return err
+	}
+	ctx := memory.WithCaller(context.Background(), memory.Caller{Tool: "admin purge"})
+	res, err := svc.Purge(ctx, types.PurgeInput{Key: *key, Value: *value, Redact: *redact, DryRun: *dryRun})
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		return printJSON("admin purge", res)
+	}
+	verb := map[string]string{"delete": "deleted", "redact": "redacted"}[res.Mode]
+	if res.DryRun {
+		verb = "would " + res.Mode
+	}
+	fmt.Printf("%s %d memories with metadata %s=%s\n", verb, res.Count, *key, *value)
+	for _, m := range res.Memories {
+		fmt.Printf("  %s  %s  %s  %s\n", m.ID, m.Namespace, m.Scope, m.CreatedAt.Format(time.RFC3339))
+	}
+	if res.Requests > 0 {
+		fmt.Printf("cleared the params of %d logged requests\n", res.Requests)
+	}
+	return nil
+}
+
+func runAdminExport(args []string) error {
+	fs := flag.NewFlagSet("admin export", flag.ContinueOnError)
+	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	out := fs.String("out", "", "File to write, or - for stdout")
+	namespace := fs.String("namespace", "", "Only export this namespace and the namespaces below it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return errors.New("--out is required")
+	}
+	if *out == "-" && jsonOutput {
+		return errors.New("--json needs --out to name a file; stdout carries the export")
+	}
+
+	ctx := context.Background()
+	_, svc, st, _, err := openCLIService(ctx, *configPath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	w, report := os.Stdout, os.Stderr
+	if *out != "-" {
+		if w, err = os.Create(*out); err != nil {
+			return err
+		}
+		defer w.Close()
+		report = os.Stdout
+	}
+	res, err := svc.ExportJSONL(ctx, w, *namespace)
+	if err != nil {
+		return err
+	}
+	if w != os.Stdout {
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+	if jsonOutput {
+		return printJSON("admin export", res)
+	}
+	fmt.Fprintf(report, "exported %d memories\n", res.Exported)
+	return nil
+}
+
+func runAdminImport(args []string) error {
+	fs := flag.NewFlagSet("admin import", flag.ContinueOnError)
+	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	in := fs.String("in", "", "admin export file to read, or - for stdin")
+	var remaps stringList
+	fs.Var(&remaps, "remap", "Move namespace from, and those below it, to to: from=to (repeatable; the first match wins)")
+	dryRun := fs.Bool("dry-run", false, "Validate the file without importing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return errors.New("--in is required")
+	}
+	opts := memory.ImportOptions{DryRun: *dryRun}
+	for _, r := range remaps {
+		remap, err := memory.ParseNamespaceRemap(r)
+		if err != nil {
+			return err
+		}
+		opts.Remaps = append(opts.Remaps, remap)
+	}
+
+	r := io.Reader(os.Stdin)
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	ctx := memory.WithCaller(context.Background(), memory.Caller{Tool: "admin import"})
+	_, svc, st, _, err := openCLIService(ctx, *configPath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	res, err := svc.ImportJSONL(ctx, r, opts)
+	if err != nil {
+		if res.Imported > 0 {
+			return fmt.Errorf("%w (imported %d memories before the error)", err, res.Imported)
+		}
+		return err
+	}
+	if jsonOutput {
+		return printJSON("admin import", res)
+	}
+	if res.DryRun {
+		fmt.Printf("would import up to %d memories; IDs already present are skipped\n", res.Read)
+		return nil
+	}
+	fmt.Printf("imported %d of %d memories, skipped %d already present\n", res.Imported, res.Read, len(res.Skipped))
+	return nil
+}
+
+// snapshotName is what admin snapshot accepts as a name: a plain file
+// name without a leading dot, stored as <name>.db in snapshot_dir.
+var snapshotName = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9_.-]*$`)
+
+func runAdminSnapshot(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: memory-mcp admin snapshot create [name] | list | restore <name> [--to path]")
+	}
+	sub, args := args[0], args[1:]
+	fs := flag.NewFlagSet("admin snapshot "+sub, flag.ContinueOnError)
+	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	var to *string
+	if sub == "restore" {
+		to = fs.String("to", "", "Write the snapshot to this new file instead of replacing the database")
+	}
+	var name string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if name == "" {
+		name = fs.Arg(0)
+	}
+	if name != "" && !snapshotName.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name %q (expected letters, digits, '_', '-' and '.')", name)
+	}
+
+	switch sub {
+	case "create":
+		cfg, st, err := openAdminSQLite(*configPath)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+		if name == "" {
+			name = time.Now().UTC().Format("20060102T150405Z")
+		}
+		path, err := createSnapshot(cfg, st, name)
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			return printJSON("admin snapshot create", snapshotJSON{Name: name, Path: path})
+		}
+		fmt.Printf("created snapshot %s (%s)\n", name, path)
+		return nil
+	case "list":
+		return listSnapshots(*configPath)
+	case "restore":
+		if name == "" {
+			return errors.New("usage: memory-mcp admin snapshot restore <name> [--to path] (names are shown by admin snapshot list)")
+		}
+		if *to != "" {
+			cfg, err := config.Load(*configPath)
+			if err != nil {
+				return err
+			}
+			if err := cfg.EnsurePaths(); err != nil {
+				return err
+			}
+			dst := config.ExpandPath(*to)
+			if err := store.CopySQLite(context.Background(), snapshotPath(cfg, name), dst); err != nil {
+				return err
+			}
+			if jsonOutput {
+				return printJSON("admin snapshot restore", snapshotJSON{Name: name, Path: dst})
+			}
+			fmt.Printf("restored snapshot %s to %s\n", name, dst)
+			return nil
+		}
+		cfg, st, err := openAdminSQLite(*configPath)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+		src := snapshotPath(cfg, name)
+		if _, err := os.Stat(src); err != nil {
+			return fmt.Errorf("snapshot %s: %w", name, err)
+		}
+		before := "pre-restore-" + time.Now().UTC().Format("20060102T150405Z")
+		if _, err := createSnapshot(cfg, st, before); err != nil {
+			return err
+		}
+		if err := st.Restore(context.Background(), src); err != nil {
+			return err
+		}
+		if jsonOutput {
+			return printJSON("admin snapshot restore", snapshotJSON{Name: name, Path: cfg.DBPath, PreviousSnapshot: before})
+		}
+		fmt.Printf("restored snapshot %s into %s (previous database saved as snapshot %s)\n", name, cfg.DBPath, before)
+		fmt.Println("restart running serve processes; their caches still hold the old data")
+		return nil
+	default:
+		return fmt.Errorf("unknown admin snapshot command %q", sub)
+	}
+}
+
+// snapshotJSON describes a snapshot in the admin snapshot --json
+// documents. Path is the restored database for restore; PreviousSnapshot
+// names the snapshot taken of it first.
+// ftsJSON is the --json result of admin fts.
+type ftsJSON struct {
+	Memories int64 `json:"memories"`
+	FTSRows  int64 `json:"fts_rows"`
+}
+
+// runAdminFTS checks the FTS index of db_path and every shard against
+// their memories, or rebuilds it. check reads the whole index and fails on
+// the first mismatch.
+func runAdminFTS(args []string) error {
+	if len(args) == 0 || (args[0] != "check" && args[0] != "rebuild") {
+		return errors.New("usage: memory-mcp admin fts check | rebuild [--config path]")
+	}
+	sub := args[0]
+	fs := flag.NewFlagSet("admin fts "+sub, flag.ContinueOnError)
+	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	_, st, err := openAdminStore(*configPath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	ctx := context.Background()
+	health, err := st.IndexHealth(ctx)
+	if err != nil {
+		return err
+	}
+	if !health.FTSEnabled {
+		return errors.New("FTS5 is not enabled for this database; searches use LIKE")
+	}
+	if sub == "check" {
+		err = st.CheckFTS(ctx)
+	} else {
+		err = st.RebuildFTS(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	if health, err = st.IndexHealth(ctx); err != nil {
+		return err
+	}
+	if jsonOutput {
+		return printJSON("admin fts "+sub, ftsJSON{Memories: health.Memories, FTSRows: health.FTSRows})
+	}
+	verb := map[string]string{"check": "checked", "rebuild": "rebuilt"}[sub]
+	fmt.Printf("%s the FTS index: %d memories, %d indexed\n", verb, health.Memories, health.FTSRows)
+	return nil
+}
+
+type snapshotJSON struct {
+	Name             string    `json:"name"`
+	Path             string    `json:"path"`
+	ModifiedAt       time.Time `json:"modified_at,omitzero"`
+	Bytes            int64     `json:"bytes,omitempty"`
+	PreviousSnapshot string    `json:"previous_snapshot,omitempty"`
+}
+
+func snapshotPath(cfg config.Config, name string) string {
+	return filepath.Join(cfg.SnapshotDir, name+".db")
+}
+
+func createSnapshot(cfg config.Config, st *store.SQLiteStore, name string) (string, error) {
+	if err := os.MkdirAll(cfg.SnapshotDir, 0o755); err != nil {
+		return "", fmt.Errorf("create snapshot dir: %w", err)
+	}
+	path := snapshotPath(cfg, name)
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("snapshot %s already exists", name)
+	}
+	return path, st.Backup(context.Background(), path)
+}
+
+func listSnapshots(configPath string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+	if err := cfg.EnsurePaths(); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(cfg.SnapshotDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	list := []snapshotJSON{}
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".db")
+		if !ok || e.IsDir() || !snapshotName.MatchString(name) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		list = append(list, snapshotJSON{Name: name, Path: snapshotPath(cfg, name), ModifiedAt: info.ModTime().UTC(), Bytes: info.Size()})
+	}
+	if jsonOutput {
+		return printJSON("admin snapshot list", list)
+	}
+	for _, sn := range list {
+		at := sn.ModifiedAt
+		if cfg.AdminTimezone == "local" {
+			at = at.Local()
+		}
+		fmt.Printf("%-32s  %s  %8.1f MiB\n", sn.Name, at.Format(time.RFC3339), float64(sn.Bytes)/(1<<20))
+	}
+	if len(list) == 0 {
+		fmt.Printf("no snapshots in %s\n", cfg.SnapshotDir)
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/memory"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// stringList collects a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// openCLIService opens the configured store behind a memory service for
+// the write, search and pack commands. The caller closes the returned
+// store.
+func openCLIService(ctx context.Context, configPath string) (config.Config, *memory.Service, io.Closer, *log.Logger, error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return cfg, nil, nil, nil, err
+	}
+	if err := cfg.EnsurePaths(); err != nil {
+		return cfg, nil, nil, nil, err
+	}
+	logger := log.New(os.Stderr)
+	setLogLevel(logger, cfg.LogLevel)
+	st, _, err := openStore(ctx, cfg, logger)
+	if err != nil {
+		return cfg, nil, nil, nil, err
+	}
+	svc, err := memory.NewService(st, cfg, logger)
+	if err != nil {
+		st.Close()
+		return cfg, nil, nil, nil, err
+	}
+	return cfg, svc, st, logger, nil
+}
+
+// cliNamespace returns ns, or the configured default_namespace when ns is
+// empty.
+func cliNamespace(ctx context.Context, cfg config.Config, logger *log.Logger, ns string) (string, error) {
+	if ns != "" {
+		return ns, nil
+	}
+	if ns = defaultNamespace(ctx, cfg, logger); ns == "" {
+		return "", errors.New("--namespace is required when default_namespace is not set")
+	}
+	return ns, nil
+}
+
+// cliText joins the positional arguments, or reads stdin when the only one
+// is "-" or, with fromStdin, when there are none.
+func cliText(args []string, fromStdin bool) (string, error) {
+	if len(args) == 1 && args[0] == "-" {
+		fromStdin = true
+	} else if len(args) > 0 || !fromStdin {
+		return strings.Join(args, " "), nil
+	}
+	b, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("read stdin: %w", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func runWrite(args []string) error {
+	fs := flag.NewFlagSet("write", flag.ContinueOnError)
+	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	namespace := fs.String("namespace", "", "Namespace to write to (default: default_namespace)")
+	scope := fs.String("scope", "", "short, long, project or global (default: by content)")
+	summary := fs.String("summary", "", "Summary; derived from the content when empty")
+	importance := fs.Int("importance", 0, "Importance from 1 to 5 (default: importance_scoring or 3)")
+	agent := fs.String("source-agent", "", "Agent or person writing the memory")
+	ttl := fs.Duration("ttl", 0, "Lifetime of a short-term memory (default: default_short_ttl_hours)")
+	externalKey := fs.String("external-key", "", "Update the memory with this key in the namespace instead of adding one")
+	metadata := fs.String("metadata", "", "Metadata as a JSON object")
+	var tags stringList
+	fs.Var(&tags, "tag", "Tag to add to metadata tags (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	content, err := cliText(fs.Args(), true)
+	if err != nil {
+		return err
+	}
+	if content == "" {
+		return errors.New("usage: memory-mcp write [flags] <content> (or pipe the content on stdin)")
+	}
+	var meta map[string]any
+	if *metadata != "" {
+		if err := json.Unmarshal([]byte(*metadata), &meta); err != nil {
+			return fmt.Errorf("--metadata must be a JSON object: %w", err)
+		}
+	}
+	if len(tags) > 0 {
+		if meta == nil {
+			meta = map[string]any{}
+		}
+		existing, _ := meta["tags"].([]any)
+		for _, t := range tags {
+			existing = append(existing, t)
+		}
+		meta["tags"] = existing
+	}
+
+	ctx := memory.WithCaller(context.Background(), memory.Caller{Tool: "cli write"})
+	cfg, svc, st, logger, err := openCLIService(ctx, *configPath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	ns, err := cliNamespace(ctx, cfg, logger, *namespace)
+	if err != nil {
+		return err
+	}
+	rec, err := svc.Write(ctx, types.WriteInput{
+		Namespace:   ns,
+		Scope:       *scope,
+		Content:     content,
+		Summary:     *summary,
+		Importance:  *importance,
+		SourceAgent: *agent,
+		TTLSeconds:  int(ttl.Seconds()),
+		Metadata:    meta,
+		ExternalKey: *externalKey,
+	})
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		return printJSON("write", rec)
+	}
+	fmt.Println(rec.ID)
+	return nil
+}
+
+// searchJSON is the search --json document.
+type searchJSON struct {
+	Namespace string               `json:"namespace"`
+	Query     string               `json:"query"`
+	Results   []types.SearchResult `json:"results"`
+}
+
+func runSearch(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	namespace := fs.String("namespace", "", "Namespace to search (default: default_namespace)")
+	scope := fs.String("scope", "", "short, long, project, global or empty for all")
+	k := fs.Int("k", 0, "Number of results (default: default_search_k)")
+	filter := fs.String("filter", "", `Metadata filter, e.g. 'metadata.priority >= 2'`)
+	agent := fs.String("source-agent", "", "Searching agent, for per-agent ranking")
+	writtenBy := fs.String("written-by", "", "Only memories written by this source agent")
+	explain := fs.Bool("explain", false, "Show each result's score breakdown")
+	advanced := fs.Bool("advanced", false, "Treat the query as an SQLite FTS5 expression (NEAR, prefix*, OR, column filters)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	query, err := cliText(fs.Args(), false)
+	if err != nil {
+		return err
+	}
+
+	ctx := memory.WithCaller(context.Background(), memory.Caller{Tool: "cli search"})
+	cfg, svc, st, logger, err := openCLIService(ctx, *configPath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	defer svc.FlushAccess(ctx)
+	ns, err := cliNamespace(ctx, cfg, logger, *namespace)
+	if err != nil {
+		return err
+	}
+	results, err := svc.Search(ctx, types.SearchInput{
+		Namespace:     ns,
+		Query:         query,
+		Scope:         *scope,
+		K:             *k,
+		Filter:        *filter,
+		SourceAgent:   *agent,
+		WrittenBy:     *writtenBy,
+		Explain:       *explain,
+		AdvancedQuery: *advanced,
+	})
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		if results == nil {
+			results = []types.SearchResult{}
+		}
+		return printJSON("search", searchJSON{Namespace: ns, Query: query, Results: results})
+	}
+	if len(results) == 0 {
+		fmt.Fprintf(os.Stderr, "no memories in %s match %q\n", ns, query)
+		return nil
+	}
+	for _, r := range results {
+		text := cmp.Or(r.Snippet, r.Record.Summary, r.Record.Content)
+		fmt.Printf("%.3f  %s  %-5s  %s  %s\n", r.Score, r.Record.ID, r.Record.Scope, r.Record.CreatedAt.UTC().Format(time.DateOnly), truncateLine(text, 100))
+		if e := r.Explanation; e != nil {
+			fmt.Printf("       lexical %.3f  recency %.3f  importance %.3f  feedback %.3f  agent %.3f\n", e.Lexical, e.Recency, e.Importance, e.Feedback, e.Agent)
+		}
+	}
+	return nil
+}
+
+func runPack(args []string) error {
+	fs := flag.NewFlagSet("pack", flag.ContinueOnError)
+	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	namespace := fs.String("namespace", "", "Namespace to pack from (default: default_namespace)")
+	budget := fs.Int("budget", 512, "Maximum estimated tokens")
+	scope := fs.String("scope", "", "short, long, project, global or empty for all")
+	k := fs.Int("k", 0, "Number of candidate memories (default: max_context_pack_items)")
+	agent := fs.String("source-agent", "", "Requesting agent, for per-agent ranking")
+	format := fs.String("format", "", "markdown, xml, json, plain or a context_pack.templates name (default: context_pack.format)")
+	sections := fs.Bool("sections", false, "Group the pack into durable, recent and possibly stale sections")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	query, err := cliText(fs.Args(), false)
+	if err != nil {
+		return err
+	}
+
+	ctx := memory.WithCaller(context.Background(), memory.Caller{Tool: "cli pack"})
+	cfg, svc, st, logger, err := openCLIService(ctx, *configPath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	defer svc.FlushAccess(ctx)
+	ns, err := cliNamespace(ctx, cfg, logger, *namespace)
+	if err != nil {
+		return err
+	}
+	pack, err := svc.ContextPack(ctx, types.ContextPackInput{
+		Namespace:   ns,
+		Query:       query,
+		TokenBudget: *budget,
+		Scope:       *scope,
+		K:           *k,
+		SourceAgent: *agent,
+		Format:      *format,
+		Sections:    *sections,
+	})
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		if pack.MemoryIDs == nil {
+			pack.MemoryIDs = []string{}
+		}
+		return printJSON("pack", pack)
+	}
+	fmt.Println(pack.Text)
+	return nil
+}
@@ -2,58 +2,108 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/charmbracelet/log"
 
 	"github.com/xiy/memory-mcp/internal/admin"
+	"github.com/xiy/memory-mcp/internal/auth"
+	"github.com/xiy/memory-mcp/internal/autopromote"
+	"github.com/xiy/memory-mcp/internal/bench"
 	"github.com/xiy/memory-mcp/internal/bootstrap"
 	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/control"
+	"github.com/xiy/memory-mcp/internal/gitctx"
+	"github.com/xiy/memory-mcp/internal/llm"
+	"github.com/xiy/memory-mcp/internal/loadtest"
 	"github.com/xiy/memory-mcp/internal/mcp"
 	"github.com/xiy/memory-mcp/internal/memory"
+	"github.com/xiy/memory-mcp/internal/obsidian"
+	"github.com/xiy/memory-mcp/internal/reflection"
+	"github.com/xiy/memory-mcp/internal/seed"
+	"github.com/xiy/memory-mcp/internal/serverlog"
 	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/internal/tracing"
+	"github.com/xiy/memory-mcp/internal/transcript"
 	"github.com/xiy/memory-mcp/internal/ttl"
 )
 
 func main() {
-	if len(os.Args) < 2 {
+	args := parseGlobalFlags(os.Args[1:])
+	if len(args) < 1 {
 		usage()
 		os.Exit(2)
 	}
+	if err := run(args[0], args[1:]); err != nil {
+		if errors.Is(err, errUnknownCommand) {
+			usage()
+			os.Exit(2)
+		}
+		if jsonOutput {
+			writeJSONError(commandName(args), err)
+		} else {
+			fmt.Fprintln(os.Stderr, "error:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// errUnknownCommand makes main print the usage.
+var errUnknownCommand = errors.New("unknown command")
 
-	sub := os.Args[1]
+func run(sub string, args []string) error {
 	switch sub {
 	case "serve":
-		if err := runServe(os.Args[2:]); err != nil {
-			fmt.Fprintln(os.Stderr, "error:", err)
-			os.Exit(1)
-		}
+		return runServe(args)
 	case "bootstrap-clis":
-		if err := runBootstrap(os.Args[2:]); err != nil {
-			fmt.Fprintln(os.Stderr, "error:", err)
-			os.Exit(1)
-		}
+		return runBootstrap(args)
 	case "admin":
-		if err := runAdmin(os.Args[2:]); err != nil {
-			fmt.Fprintln(os.Stderr, "error:", err)
-			os.Exit(1)
-		}
+		return runAdmin(args)
+	case "bench":
+		return runBench(args)
+	case "loadtest":
+		return runLoadtest(args)
+	case "seed":
+		return runSeed(args)
+	case "obsidian-sync":
+		return runObsidianSync(args)
+	case "write":
+		return runWrite(args)
+	case "search":
+		return runSearch(args)
+	case "pack":
+		return runPack(args)
+	case "service":
+		return runService(args)
+	case "connect":
+		return runConnect(args)
 	case "version", "--version", "-v":
-		fmt.Println("memory-mcp v0.1.0")
+		if jsonOutput {
+			return printJSON("version", map[string]string{"version": version})
+		}
+		fmt.Println("memory-mcp " + version)
+		return nil
 	default:
-		usage()
-		os.Exit(2)
+		return errUnknownCommand
 	}
 }
 
 func runServe(args []string) error {
 	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
 	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	readOnly := fs.Bool("read-only", false, "Reject memory writes, promotions and deletes")
+	transport := fs.String("transport-mode", "", "Pin stdio framing: auto, framed or jsonl (overrides transport_mode)")
+	record := fs.String("record", "", "Append every message of the session to this transcript file")
+	listen := fs.String("listen", "", "Accept sessions on this socket (unix:<path> or host:port) instead of stdio (overrides listen)")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
@@ -62,37 +112,259 @@ func runServe(args []string) error {
 	if err != nil {
 		return err
 	}
+	if *readOnly {
+		cfg.ReadOnly = true
+	}
+	if *transport != "" {
+		cfg.TransportMode = *transport
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+	}
+	if *listen != "" {
+		cfg.Listen = *listen
+	}
+	if *record != "" && cfg.Listen != "" {
+		return errors.New("--record records a stdio session and cannot be used with listen")
+	}
 	if err := cfg.EnsurePaths(); err != nil {
 		return err
 	}
 
-	logger := log.NewWithOptions(os.Stderr, log.Options{ReportCaller: false, Prefix: cfg.ServerName})
+	logOut := serverlog.New(os.Stderr, cfg.ServerName)
+	logger := log.NewWithOptions(logOut, log.Options{ReportCaller: false, Prefix: cfg.ServerName})
 	setLogLevel(logger, cfg.LogLevel)
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	st, err := store.OpenSQLite(ctx, cfg.DBPath, logger)
+	stopTracing, err := tracing.Start(ctx, cfg.Tracing, mcp.ServerVersion, logger)
 	if err != nil {
+		logOut.Close()
+		return err
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := stopTracing(shutdownCtx); err != nil {
+			logger.Warn("flushing traces failed", "error", err)
+		}
+	}()
+
+	st, sink, err := openStore(ctx, cfg, logger)
+	if err != nil {
+		logOut.Close()
 		return err
 	}
 	defer st.Close()
+	if logSink, ok := st.(serverlog.Sink); ok {
+		logOut.Start(logSink)
+	}
+	defer logOut.Close()
 
-	svc, err := memory.NewService(st, cfg, logger)
+	var svcOpts []memory.Option
+	llmClient := llm.New(cfg.LLM)
+	if llmClient != nil {
+		svcOpts = append(svcOpts, memory.WithLLM(llmClient))
+	}
+	if cfg.ImportanceScoring == "llm" && llmClient == nil {
+		logger.Warn("importance_scoring is llm but llm.endpoint is empty; using the heuristic")
+	}
+	svc, err := memory.NewService(st, cfg, logger, svcOpts...)
 	if err != nil {
 		return err
 	}
 
-	go ttl.Start(ctx, logger, time.Duration(cfg.TTLCheckIntervalSeconds)*time.Second, svc)
+	if !cfg.ReadOnly {
+		go svc.TrackAccess(ctx)
+		// Runs before the store is closed.
+		defer svc.FlushAccess(context.Background())
+		go ttl.Start(ctx, logger, time.Duration(cfg.TTLCheckIntervalSeconds)*time.Second, svc)
+	}
+	if cfg.Reflection.Enabled && !cfg.ReadOnly {
+		if llmClient == nil {
+			logger.Warn("reflection.enabled is set but llm.endpoint is empty; reflection disabled")
+		} else {
+			go reflection.Start(ctx, logger, time.Duration(cfg.Reflection.IntervalHours)*time.Hour, svc)
+		}
+	}
+	if cfg.AutoPromote.Enabled && !cfg.ReadOnly {
+		go autopromote.Start(ctx, logger, time.Duration(cfg.AutoPromote.IntervalMinutes)*time.Minute, svc)
+	}
+	if cfg.Obsidian.VaultDir != "" && cfg.Obsidian.SyncIntervalSeconds > 0 {
+		if src, ok := st.(obsidian.Source); ok {
+			go obsidian.Start(ctx, logger, time.Duration(cfg.Obsidian.SyncIntervalSeconds)*time.Second, src, obsidianOptions(cfg))
+		}
+	}
 
-	server := mcp.NewServer(svc, logger, st)
-	logger.Info("starting MCP stdio server", "db", cfg.DBPath)
+	if err := mcp.CheckToolsConfig(cfg.Tools); err != nil {
+		return err
+	}
+	namespace := defaultNamespace(ctx, cfg, logger)
+	if namespace != "" {
+		logger.Info("default namespace", "namespace", namespace)
+	}
+	instructions, err := mcp.RenderInstructions(cfg)
+	if err != nil {
+		return fmt.Errorf("render instructions: %w", err)
+	}
+	serverOpts := []mcp.ServerOption{
+		mcp.WithKeepalive(time.Duration(cfg.KeepaliveIntervalSeconds) * time.Second),
+		mcp.WithTransportMode(cfg.TransportMode),
+		mcp.WithInstructions(instructions),
+		mcp.WithTools(cfg.Tools),
+		mcp.WithDefaultNamespace(namespace),
+	}
+	authn, err := auth.New(cfg.Auth)
+	if err != nil {
+		return err
+	}
+	if authn != nil {
+		// Socket sessions each send their own key; the environment key
+		// belongs to the client that started a stdio server.
+		var key string
+		if cfg.Listen == "" && cfg.Auth.KeyEnv != "" {
+			key = os.Getenv(cfg.Auth.KeyEnv)
+		}
+		serverOpts = append(serverOpts, mcp.WithAuth(authn, key))
+	}
+	if *record != "" {
+		f, err := os.OpenFile(*record, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return fmt.Errorf("open transcript: %w", err)
+		}
+		defer f.Close()
+		logger.Info("recording session transcript", "path", *record)
+		serverOpts = append(serverOpts, mcp.WithRecorder(transcript.NewWriter(f)))
+	}
+	server := mcp.NewServer(svc, logger, sink, serverOpts...)
+	if cfg.ControlDir != "" {
+		ctlDone := make(chan struct{})
+		go func() {
+			defer close(ctlDone)
+			if err := control.Listen(ctx, cfg.ControlDir, logger, server); err != nil {
+				logger.Warn("control socket unavailable; admin --attach will not see this server", "error", err)
+			}
+		}()
+		// Wait for the socket to be removed before exiting.
+		defer func() {
+			cancel()
+			<-ctlDone
+		}()
+	}
+	if cfg.Listen != "" {
+		ln, err := mcp.Listen(cfg.Listen)
+		if err != nil {
+			return fmt.Errorf("listen on %s: %w", cfg.Listen, err)
+		}
+		logger.Info("starting MCP socket server", "listen", cfg.Listen, "driver", cfg.Store.Driver, "db", storeLocation(cfg), "read_only", cfg.ReadOnly, "transport_mode", cfg.TransportMode)
+		if err := server.ServeListener(ctx, ln); err != nil && err != context.Canceled {
+			return err
+		}
+		return nil
+	}
+	logger.Info("starting MCP stdio server", "driver", cfg.Store.Driver, "db", storeLocation(cfg), "read_only", cfg.ReadOnly, "transport_mode", cfg.TransportMode)
 	if err := server.Serve(ctx, os.Stdin, os.Stdout); err != nil && err != context.Canceled {
 		return err
 	}
 	return nil
 }
 
+// defaultNamespace resolves default_namespace, reading git placeholders
+// from the working directory. Problems are logged and disable the default.
+func defaultNamespace(ctx context.Context, cfg config.Config, logger *log.Logger) string {
+	var gitVars map[string]string
+	if cfg.NeedsGit() {
+		info, err := gitctx.Detect(ctx, ".")
+		if err != nil {
+			logger.Warn("cannot derive namespace from git", "error", err)
+		} else {
+			gitVars = info.Vars()
+		}
+	}
+	namespace, err := cfg.ResolveDefaultNamespace(gitVars)
+	if err != nil {
+		logger.Warn("ignoring default_namespace", "error", err)
+	}
+	return namespace
+}
+
+// openStore opens the backend registered as store.driver. The request log
+// sink is only available on SQLite; other drivers return a nil sink.
+func openStore(ctx context.Context, cfg config.Config, logger *log.Logger) (store.Store, mcp.RequestLogSink, error) {
+	st, err := store.Open(ctx, cfg.Store.Driver, store.Backend{
+		Path:      cfg.DBPath,
+		Shards:    cfg.Store.Shards,
+		Dir:       cfg.Store.Dir,
+		GitCommit: cfg.Store.GitCommit,
+		Options:   sqliteOptions(cfg),
+		DSN:       cfg.Store.PostgresDSN(),
+		PostgresOptions: []store.PostgresOption{
+			store.WithPostgresAnalyzer(cfg.Store.FTSMetadataKeys, cfg.Store.CJKNgram),
+		},
+	}, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	sink, _ := st.(mcp.RequestLogSink)
+	return st, sink, nil
+}
+
+// sqliteStore is a SQLiteStore, or a ShardedStore over several of them
+// when store.shards is set.
+type sqliteStore interface {
+	store.Store
+	mcp.RequestLogSink
+	GetMCPRequestLog(ctx context.Context, id int64) (store.MCPRequestLog, error)
+	RecentMCPRequestLogs(ctx context.Context, limit int) ([]store.MCPRequestLog, error)
+	MCPRequestLogsAfter(ctx context.Context, afterID int64, limit int) ([]store.MCPRequestLog, error)
+	RecentMemories(ctx context.Context, limit int) ([]store.RecentMemory, error)
+	IndexHealth(ctx context.Context) (store.IndexHealth, error)
+	CheckFTS(ctx context.Context) error
+	RebuildFTS(ctx context.Context) error
+	RecentServerLogs(ctx context.Context, limit int) ([]store.ServerLog, error)
+	RequestsByClient(ctx context.Context, limit int) ([]store.ClientRequests, error)
+	store.PromotionStore
+}
+
+// openSQLite opens db_path, with every store.shards database behind it
+// when shards are configured. extra options follow the configured ones.
+func openSQLite(ctx context.Context, cfg config.Config, logger *log.Logger, extra ...store.Option) (sqliteStore, error) {
+	opts := append(sqliteOptions(cfg), extra...)
+	if len(cfg.Store.Shards) > 0 {
+		return store.OpenSharded(ctx, cfg.DBPath, cfg.Store.Shards, logger, opts...)
+	}
+	return store.OpenSQLite(ctx, cfg.DBPath, logger, opts...)
+}
+
+func sqliteOptions(cfg config.Config) []store.Option {
+	return []store.Option{
+		store.WithReadConns(cfg.Store.ReadConns),
+		store.WithWriteConns(cfg.Store.WriteConns),
+		store.WithBusyTimeout(time.Duration(cfg.Store.BusyTimeoutMS) * time.Millisecond),
+		store.WithFTSMetadataKeys(cfg.Store.FTSMetadataKeys...),
+		store.WithCJKNgram(cfg.Store.CJKNgram),
+		store.WithMetadataColumns(cfg.Store.MetadataColumns...),
+		store.WithRecordCache(cfg.Cache.Records, time.Duration(cfg.Cache.RecordsTTLSeconds)*time.Second),
+	}
+}
+
+func storeLocation(cfg config.Config) string {
+	switch cfg.Store.Driver {
+	case "markdown":
+		return cfg.Store.Dir
+	case "memory":
+		return "(in memory)"
+	case "postgres":
+		// Never log a password from the DSN.
+		if u, err := url.Parse(cfg.Store.PostgresDSN()); err == nil && u.Host != "" {
+			return u.Redacted()
+		}
+		return "(postgres)"
+	}
+	return cfg.DBPath
+}
+
 func runBootstrap(args []string) error {
 	fs := flag.NewFlagSet("bootstrap-clis", flag.ContinueOnError)
 	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
@@ -109,7 +381,12 @@ func runBootstrap(args []string) error {
 	}
 
 	logger := log.New(os.Stderr)
-	return bootstrap.Bootstrap(logger, bootstrap.Options{
+	var runner bootstrap.Runner
+	if jsonOutput {
+		// Keep stdout for the JSON document.
+		runner = bootstrap.OSRunner{Stdout: os.Stderr}
+	}
+	res, err := bootstrap.Bootstrap(logger, bootstrap.Options{
 		ConfigPath: *configPath,
 		Scope:      *scope,
 		ServerName: *serverName,
@@ -119,12 +396,51 @@ func runBootstrap(args []string) error {
 		Claude:     *claude,
 		Gemini:     *gemini,
 		DryRun:     *dryRun,
-	}, nil)
+	}, runner)
+	if err != nil || !jsonOutput {
+		return err
+	}
+	if res.Commands == nil {
+		res.Commands = []bootstrap.CommandResult{}
+	}
+	return printJSON("bootstrap-clis", res)
 }
 
 func runAdmin(args []string) error {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case "reembed":
+			return runAdminReembed(args[1:])
+		case "cluster":
+			return runAdminCluster(args[1:])
+		case "dedupe-report":
+			return runAdminDedupeReport(args[1:])
+		case "replay":
+			return runAdminReplay(args[1:])
+		case "misses":
+			return runAdminMisses(args[1:])
+		case "audit":
+			return runAdminAudit(args[1:])
+		case "purge":
+			return runAdminPurge(args[1:])
+		case "snapshot":
+			return runAdminSnapshot(args[1:])
+		case "fts":
+			return runAdminFTS(args[1:])
+		case "export":
+			return runAdminExport(args[1:])
+		case "import":
+			return runAdminImport(args[1:])
+		default:
+			return fmt.Errorf("unknown admin command %q", args[0])
+		}
+	}
 	fs := flag.NewFlagSet("admin", flag.ContinueOnError)
 	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	attach := fs.Bool("attach", false, "Also show live state of running serve processes via their control sockets")
+	readOnly := fs.Bool("read-only", false, "Open the database read-only so the dashboard never takes the write lock")
+	replica := fs.String("replica", "", "Read this replica or snapshot of db_path instead (implies --read-only)")
+	immutable := fs.Bool("immutable", false, "Assume nothing writes the database while the dashboard runs and skip locking (implies --read-only)")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
@@ -136,9 +452,23 @@ func runAdmin(args []string) error {
 	if err := cfg.EnsurePaths(); err != nil {
 		return err
 	}
+	if *attach && cfg.ControlDir == "" {
+		return fmt.Errorf("admin --attach requires control_dir")
+	}
 
+	if cfg.Store.Driver != "sqlite" {
+		return fmt.Errorf("admin dashboard requires store.driver sqlite (got %q)", cfg.Store.Driver)
+	}
+
+	var extra []store.Option
+	if *replica != "" {
+		cfg.DBPath = config.ExpandPath(*replica)
+	}
+	if *readOnly || *replica != "" || *immutable {
+		extra = append(extra, store.WithReadOnly(*immutable))
+	}
 	logger := log.New(os.Stderr)
-	st, err := store.OpenSQLite(context.Background(), cfg.DBPath, logger)
+	st, err := openSQLite(context.Background(), cfg, logger, extra...)
 	if err != nil {
 		return err
 	}
@@ -147,7 +477,243 @@ func runAdmin(args []string) error {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	return admin.Run(ctx, st)
+	if jsonOutput {
+		return printAdminStats(ctx, cfg, st, *attach)
+	}
+	opts := admin.Options{
+		LocalTime:  cfg.AdminTimezone == "local",
+		Config:     cfg,
+		ConfigPath: *configPath,
+		ReadOnly:   *readOnly || *replica != "" || *immutable,
+	}
+	if *attach {
+		opts.Attach = func(ctx context.Context) ([]mcp.Snapshot, error) {
+			return control.Attach(ctx, cfg.ControlDir)
+		}
+	}
+	return admin.Run(ctx, st, opts)
+}
+
+// adminStats is the admin --json document, a one-off of the dashboard's
+// stats and index health.
+type adminStats struct {
+	DBPath  string            `json:"db_path"`
+	Stats   store.Stats       `json:"stats"`
+	Index   store.IndexHealth `json:"index"`
+	Servers []mcp.Snapshot    `json:"servers,omitempty"`
+}
+
+func printAdminStats(ctx context.Context, cfg config.Config, st sqliteStore, attach bool) error {
+	out := adminStats{DBPath: cfg.DBPath}
+	var err error
+	if out.Stats, err = st.Stats(ctx, time.Now().UTC()); err != nil {
+		return err
+	}
+	if out.Index, err = st.IndexHealth(ctx); err != nil {
+		return err
+	}
+	if attach {
+		if out.Servers, err = control.Attach(ctx, cfg.ControlDir); err != nil {
+			return err
+		}
+	}
+	return printJSON("admin", out)
+}
+
+func runObsidianSync(args []string) error {
+	fs := flag.NewFlagSet("obsidian-sync", flag.ContinueOnError)
+	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	vault := fs.String("vault", "", "Obsidian vault directory (overrides obsidian.vault_dir)")
+	interval := fs.Duration("interval", 0, "Repeat the sync at this interval until interrupted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return err
+	}
+	if *vault != "" {
+		cfg.Obsidian.VaultDir = *vault
+	}
+	if err := cfg.EnsurePaths(); err != nil {
+		return err
+	}
+	if cfg.Obsidian.VaultDir == "" {
+		return fmt.Errorf("no vault configured; set obsidian.vault_dir or pass --vault")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	logger := log.New(os.Stderr)
+	st, _, err := openStore(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	src, ok := st.(obsidian.Source)
+	if !ok {
+		return fmt.Errorf("store driver %q does not support listing memories", cfg.Store.Driver)
+	}
+	res, err := obsidian.Sync(ctx, src, obsidianOptions(cfg))
+	if err != nil {
+		return err
+	}
+	logger.Info("obsidian sync complete", "vault", cfg.Obsidian.VaultDir, "written", res.Written, "unchanged", res.Unchanged, "removed", res.Removed)
+	if jsonOutput {
+		if err := printJSON("obsidian-sync", res); err != nil {
+			return err
+		}
+	}
+	if *interval > 0 {
+		obsidian.Start(ctx, logger, *interval, src, obsidianOptions(cfg))
+	}
+	return nil
+}
+
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	sizes := fs.String("sizes", "1000,10000", "Comma-separated database sizes (rows) to measure")
+	queries := fs.Int("queries", 200, "Searches timed per size and path")
+	dir := fs.String("dir", "", "Directory for benchmark databases (default: temp dir, removed afterwards)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var sizeList []int
+	for _, part := range strings.Split(*sizes, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid size %q", part)
+		}
+		sizeList = append(sizeList, n)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	rep, err := bench.Run(ctx, bench.Options{Sizes: sizeList, Queries: *queries, Dir: *dir})
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		return printJSON("bench", rep)
+	}
+	return rep.Write(os.Stdout)
+}
+
+func runLoadtest(args []string) error {
+	fs := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	command := fs.String("command", "", "Server command launched per worker over stdio, e.g. \"memory-mcp serve --config cfg.yaml\"")
+	socket := fs.String("socket", "", "Server socket address (unix:<path>, /path or host:port)")
+	concurrency := fs.Int("concurrency", 5, "Concurrent workers, one connection each")
+	requests := fs.Int("requests", 0, "Total tool calls (default: run for --duration)")
+	duration := fs.Duration("duration", 10*time.Second, "Run time when --requests is not set")
+	mix := fs.String("mix", "write=1,search=3,pack=1", "Tool mix weights (write, search, pack)")
+	namespace := fs.String("namespace", "loadtest/repo/main/load", "Namespace used for generated calls")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var dial loadtest.Dialer
+	switch {
+	case *command != "" && *socket != "":
+		return fmt.Errorf("use either --command or --socket, not both")
+	case *command != "":
+		dial = loadtest.StdioDialer(strings.Fields(*command))
+	case *socket != "":
+		dial = loadtest.SocketDialer(*socket)
+	default:
+		return fmt.Errorf("one of --command or --socket is required")
+	}
+	weights, err := loadtest.ParseMix(*mix)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	rep, runErr := loadtest.Run(ctx, loadtest.Options{
+		Dial:        dial,
+		Concurrency: *concurrency,
+		Requests:    *requests,
+		Duration:    *duration,
+		Mix:         weights,
+		Namespace:   *namespace,
+	})
+	if jsonOutput {
+		if runErr != nil {
+			return runErr
+		}
+		return printJSON("loadtest", rep)
+	}
+	if err := rep.Write(os.Stdout); err != nil {
+		return err
+	}
+	return runErr
+}
+
+func runSeed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	count := fs.Int("count", 200, "Number of memories to generate")
+	namespaces := fs.String("namespaces", strings.Join(seed.DefaultNamespaces, ","), "Comma-separated namespaces to spread memories across")
+	maxAge := fs.Duration("max-age", 30*24*time.Hour, "Oldest age for generated long-term memories")
+	seedValue := fs.Int64("seed", 0, "Random seed for reproducible fixtures (default: time-based)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return err
+	}
+	if err := cfg.EnsurePaths(); err != nil {
+		return err
+	}
+
+	var nsList []string
+	for _, ns := range strings.Split(*namespaces, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			nsList = append(nsList, ns)
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	logger := log.New(os.Stderr)
+	st, _, err := openStore(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	res, err := seed.Run(ctx, st, cfg, logger, seed.Options{
+		Count:      *count,
+		Namespaces: nsList,
+		MaxAge:     *maxAge,
+		Seed:       *seedValue,
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info("seeded memories", "db", storeLocation(cfg), "written", res.Written, "promoted", res.Promoted, "namespaces", len(res.ByNamespace))
+	if jsonOutput {
+		return printJSON("seed", res)
+	}
+	return nil
+}
+
+func obsidianOptions(cfg config.Config) obsidian.Options {
+	return obsidian.Options{VaultDir: cfg.Obsidian.VaultDir, Folder: cfg.Obsidian.Folder}
 }
 
 func setLogLevel(logger *log.Logger, level string) {
@@ -167,9 +733,30 @@ func usage() {
 	fmt.Print(`memory-mcp
 
 Usage:
-  memory-mcp serve [--config path]
+  memory-mcp [--json] <command> ...
+  memory-mcp serve [--config path] [--read-only] [--transport-mode auto|framed|jsonl] [--record transcript.txt] [--listen addr]
+  memory-mcp service install [--listen addr] [--dry-run] | uninstall [--dry-run] | status [--listen addr] [--config path]
+  memory-mcp connect [--socket addr] [--config path]
   memory-mcp bootstrap-clis [--config path] [--all|--codex --claude --gemini] [--scope user|project]
-  memory-mcp admin [--config path]
+  memory-mcp admin [--config path] [--attach] [--read-only] [--replica path] [--immutable]
+  memory-mcp admin reembed --model name [--config path]
+  memory-mcp admin cluster --namespace ns [--k N] [--scope short|long|project|global] [--config path]
+  memory-mcp admin dedupe-report [--namespace ns] [--threshold 0.8] [--apply] [--config path]
+  memory-mcp admin replay <request-id> [--allow-writes] [--config path]
+  memory-mcp admin misses [--namespace ns] [--limit N] [--config path]
+  memory-mcp admin audit [--namespace ns] [--memory id] [--action a] [--actor name] [--limit N] [--config path]
+  memory-mcp admin purge --key k --value v [--redact] [--dry-run] [--config path]
+  memory-mcp admin snapshot create [name] | list | restore <name> [--to path] [--config path]
+  memory-mcp admin fts check | rebuild [--config path]
+  memory-mcp admin export --out file|- [--namespace ns] [--config path]
+  memory-mcp admin import --in file|- [--remap from=to ...] [--dry-run] [--config path]
+  memory-mcp bench [--sizes 1000,10000,100000] [--queries 200]
+  memory-mcp loadtest (--command "memory-mcp serve ..." | --socket addr) [--concurrency 5] [--requests N | --duration 10s] [--mix write=1,search=3,pack=1]
+  memory-mcp seed [--config path] [--count 200] [--namespaces a/b/c,d/e/f] [--max-age 720h] [--seed N]
+  memory-mcp write [--config path] [--namespace ns] [--scope short|long|project|global] [--summary s] [--importance N] [--tag t ...] [--metadata json] [--ttl 48h] [--external-key k] [--source-agent name] <content | ->
+  memory-mcp search [--config path] [--namespace ns] [--scope short|long|project|global] [--k N] [--filter expr] [--source-agent name] [--explain] <query | ->
+  memory-mcp pack [--config path] [--namespace ns] [--budget 512] [--scope short|long|project|global] [--k N] [--source-agent name] <query | ->
+  memory-mcp obsidian-sync [--config path] [--vault dir] [--interval 10m]
   memory-mcp version
 `)
 }
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+const version = "v0.1.0"
+
+// jsonSchemaVersion versions the --json output. It is raised only when a
+// field is removed or changes meaning; new fields can appear at any time.
+const jsonSchemaVersion = 1
+
+// jsonOutput is set by the global --json flag.
+var jsonOutput bool
+
+// jsonEnvelope wraps every --json document: one per command run, on
+// stdout. Exactly one of Result and Error is set.
+type jsonEnvelope struct {
+	SchemaVersion int    `json:"schema_version"`
+	Command       string `json:"command"`
+	Result        any    `json:"result,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// parseGlobalFlags removes the global flags from args. --json is accepted
+// anywhere before a "--" argument, which ends flag parsing.
+func parseGlobalFlags(args []string) []string {
+	out := make([]string, 0, len(args))
+	for i, a := range args {
+		if a == "--" {
+			return append(out, args[i:]...)
+		}
+		if a == "--json" || a == "-json" {
+			jsonOutput = true
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// commandName names the command args run for --json: the subcommand and,
+// for admin and service, their own subcommands, e.g. "admin snapshot list".
+func commandName(args []string) string {
+	name := args[0]
+	if name != "admin" && name != "service" {
+		return name
+	}
+	for _, a := range args[1:min(len(args), 3)] {
+		if strings.HasPrefix(a, "-") {
+			break
+		}
+		name += " " + a
+		if a != "snapshot" && a != "fts" {
+			break
+		}
+	}
+	return name
+}
+
+// printJSON writes result for command as a --json document.
+func printJSON(command string, result any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jsonEnvelope{SchemaVersion: jsonSchemaVersion, Command: command, Result: result})
+}
+
+// writeJSONError reports a failed command as a --json document.
+func writeJSONError(command string, err error) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(jsonEnvelope{SchemaVersion: jsonSchemaVersion, Command: command, Error: err.Error()})
+}
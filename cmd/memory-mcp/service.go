@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/bootstrap"
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/mcp"
+	"github.com/xiy/memory-mcp/internal/service"
+)
+
+// defaultServiceListen is the socket a service listens on when neither
+// --listen nor listen in the config sets one.
+const defaultServiceListen = "unix:~/.memory-mcp/memory-mcp.sock"
+
+func runService(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: memory-mcp service install | uninstall | status [--config path]")
+	}
+	sub, args := args[0], args[1:]
+	fs := flag.NewFlagSet("service "+sub, flag.ContinueOnError)
+	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	var listen *string
+	dryRun := new(bool)
+	if sub != "uninstall" {
+		listen = fs.String("listen", "", "Socket the service serves on (default: listen from the config, else "+defaultServiceListen+")")
+	}
+	if sub != "status" {
+		dryRun = fs.Bool("dry-run", false, "Print the unit path and commands without changing anything")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	absConfig, err := filepath.Abs(*configPath)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.Load(absConfig)
+	if err != nil {
+		return err
+	}
+	if listen != nil && *listen != "" {
+		cfg.Listen = *listen
+	}
+	if cfg.Listen == "" {
+		cfg.Listen = defaultServiceListen
+	}
+	if err := cfg.EnsurePaths(); err != nil {
+		return err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	opts := service.Options{
+		GOOS:       runtime.GOOS,
+		Home:       home,
+		UID:        os.Getuid(),
+		ConfigPath: absConfig,
+		Listen:     cfg.Listen,
+		LogPath:    filepath.Join(filepath.Dir(cfg.DBPath), "service.log"),
+	}
+	runner := bootstrap.OSRunner{}
+	if jsonOutput {
+		runner.Stdout = os.Stderr
+	}
+
+	switch sub {
+	case "install":
+		if opts.Binary, err = os.Executable(); err != nil {
+			return err
+		}
+		if opts.Binary, err = filepath.EvalSymlinks(opts.Binary); err != nil {
+			return err
+		}
+		res, err := service.Install(opts, runner, *dryRun)
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			return printJSON("service install", res)
+		}
+		if *dryRun {
+			fmt.Printf("would install %s serving %s and run:\n  %s\n", res.UnitPath, res.Listen, strings.Join(res.Commands, "\n  "))
+			return nil
+		}
+		fmt.Printf("installed %s serving %s\n", res.UnitPath, res.Listen)
+		fmt.Printf("register it with agent CLIs: memory-mcp bootstrap-clis --serve-command \"memory-mcp connect --socket %s\"\n", res.Listen)
+		return nil
+	case "uninstall":
+		res, err := service.Uninstall(opts, runner, *dryRun)
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			return printJSON("service uninstall", res)
+		}
+		if len(res.Commands) == 0 {
+			fmt.Printf("no service installed at %s\n", res.UnitPath)
+			return nil
+		}
+		if *dryRun {
+			fmt.Printf("would run:\n  %s\nand remove %s\n", strings.Join(res.Commands, "\n  "), res.UnitPath)
+			return nil
+		}
+		fmt.Printf("removed %s\n", res.UnitPath)
+		return nil
+	case "status":
+		return serviceStatus(opts, runner)
+	default:
+		return fmt.Errorf("unknown service command %q", sub)
+	}
+}
+
+// serviceStatusJSON is the service status --json document.
+type serviceStatusJSON struct {
+	UnitPath  string `json:"unit_path"`
+	Installed bool   `json:"installed"`
+	Listen    string `json:"listen"`
+	Reachable bool   `json:"reachable"`
+	Error     string `json:"error,omitempty"`
+}
+
+// serviceStatus reports whether the unit is installed and the socket
+// accepts connections, after the service manager's own status.
+func serviceStatus(opts service.Options, runner service.Runner) error {
+	path, err := service.UnitPath(opts)
+	if err != nil {
+		return err
+	}
+	st := serviceStatusJSON{UnitPath: path, Listen: opts.Listen}
+	if _, err := os.Stat(path); err == nil {
+		st.Installed = true
+	}
+	network, address := mcp.SocketAddr(opts.Listen)
+	if c, err := net.DialTimeout(network, address, 2*time.Second); err != nil {
+		st.Error = err.Error()
+	} else {
+		c.Close()
+		st.Reachable = true
+	}
+	if jsonOutput {
+		return printJSON("service status", st)
+	}
+	if st.Installed {
+		c := service.StatusCommand(opts)
+		// The service manager exits non-zero for stopped services.
+		_ = runner.Run(c.Name, c.Args...)
+		fmt.Println()
+	}
+	installed := "not installed"
+	if st.Installed {
+		installed = "installed"
+	}
+	reachable := "accepting connections"
+	if !st.Reachable {
+		reachable = "not reachable: " + st.Error
+	}
+	fmt.Printf("%s (%s)\n%s: %s\n", installed, st.UnitPath, st.Listen, reachable)
+	return nil
+}
+
+// runConnect bridges stdio to a serve --listen socket, so agent CLIs that
+// launch an MCP server as a command can share a running one.
+func runConnect(args []string) error {
+	fs := flag.NewFlagSet("connect", flag.ContinueOnError)
+	configPath := fs.String("config", "config/memory-mcp.yaml", "Path to config file")
+	socket := fs.String("socket", "", "Server socket, unix:<path> or host:port (default: listen from the config)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	addr := *socket
+	if addr == "" {
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			return err
+		}
+		if err := cfg.EnsurePaths(); err != nil {
+			return err
+		}
+		if addr = cfg.Listen; addr == "" {
+			return errors.New("--socket is required when listen is not set in the config")
+		}
+	} else if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		addr = "unix:" + config.ExpandPath(path)
+	}
+
+	network, address := mcp.SocketAddr(addr)
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w (is the server running? see memory-mcp service status)", addr, err)
+	}
+	defer conn.Close()
+	go func() {
+		_, _ = io.Copy(conn, os.Stdin)
+		// Let the server see the end of the session.
+		if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+			_ = cw.CloseWrite()
+		}
+	}()
+	_, err = io.Copy(os.Stdout, conn)
+	return err
+}
@@ -0,0 +1,357 @@
+package admin
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// browseLimit is how many of the newest memories the browser loads and
+// searches.
+const browseLimit = 500
+
+// browserStore is what the memory browser reads and changes.
+type browserStore interface {
+	ListMemories(ctx context.Context, f store.ListFilter) ([]types.MemoryRecord, error)
+	Promote(ctx context.Context, id string, now time.Time) error
+	RecordPromotion(ctx context.Context, p store.Promotion) error
+	UpdateMemory(ctx context.Context, id string, fn func(*types.MemoryRecord) error) (types.MemoryRecord, error)
+	DeleteMemories(ctx context.Context, ids []string) (int64, error)
+}
+
+type browseMsg struct {
+	records []types.MemoryRecord
+	err     error
+}
+
+// actionMsg reports a browser action; the list is reloaded after it.
+type actionMsg struct {
+	note string
+	err  error
+}
+
+// browser is the state of the memory browser, opened with the m key.
+type browser struct {
+	active   bool
+	detail   bool
+	readOnly bool
+	// searching is set while the query is being typed.
+	searching bool
+	query     string
+	records   []types.MemoryRecord
+	// items are the records matching query, in records order.
+	items  []types.MemoryRecord
+	cursor int
+	// confirmID is the memory awaiting a y to be deleted.
+	confirmID string
+	status    string
+}
+
+func (b *browser) setRecords(records []types.MemoryRecord) {
+	var selected string
+	if rec, ok := b.selected(); ok {
+		selected = rec.ID
+	}
+	b.records = records
+	b.filter()
+	if i := slices.IndexFunc(b.items, func(r types.MemoryRecord) bool { return r.ID == selected }); i >= 0 {
+		b.cursor = i
+	}
+}
+
+func (b *browser) filter() {
+	b.items = filterMemories(b.records, b.query)
+	b.cursor = min(b.cursor, max(0, len(b.items)-1))
+	if len(b.items) == 0 {
+		b.detail = false
+	}
+}
+
+func (b *browser) selected() (types.MemoryRecord, bool) {
+	if b.cursor < 0 || b.cursor >= len(b.items) {
+		return types.MemoryRecord{}, false
+	}
+	return b.items[b.cursor], true
+}
+
+// filterMemories keeps the records containing every whitespace-separated
+// term of query, case-insensitively, in their ID, namespace, scope,
+// summary, content, source agent or tags.
+func filterMemories(records []types.MemoryRecord, query string) []types.MemoryRecord {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return records
+	}
+	var out []types.MemoryRecord
+	for _, rec := range records {
+		text := strings.ToLower(strings.Join([]string{
+			rec.ID, rec.Namespace, rec.Scope, rec.Summary, rec.Content, rec.SourceAgent, fmt.Sprint(rec.Metadata["tags"]),
+		}, "\n"))
+		if !slices.ContainsFunc(terms, func(t string) bool { return !strings.Contains(text, t) }) {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+func (m model) updateBrowser(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	b := &m.browse
+	key := msg.String()
+	if b.searching {
+		switch msg.Type {
+		case tea.KeyCtrlC:
+			return m, tea.Quit
+		case tea.KeyEnter:
+			b.searching = false
+		case tea.KeyEsc:
+			b.searching, b.query = false, ""
+			b.filter()
+		case tea.KeyBackspace:
+			if r := []rune(b.query); len(r) > 0 {
+				b.query = string(r[:len(r)-1])
+				b.filter()
+			}
+		case tea.KeyRunes, tea.KeySpace:
+			b.query += string(msg.Runes)
+			b.cursor = 0
+			b.filter()
+		}
+		return m, nil
+	}
+	if b.confirmID != "" {
+		id := b.confirmID
+		b.confirmID = ""
+		if key != "y" {
+			b.status = "delete cancelled"
+			return m, nil
+		}
+		b.status = "deleting " + id
+		return m, deleteMemoryCmd(m.ctx, m.st, id)
+	}
+
+	b.status = ""
+	switch key {
+	case "q", "ctrl+c":
+		m = m.appendLog("received quit signal")
+		return m, tea.Quit
+	case "m":
+		b.active = false
+	case "esc", "backspace":
+		switch {
+		case b.detail:
+			b.detail = false
+		case b.query != "":
+			b.query = ""
+			b.filter()
+		case key == "esc":
+			b.active = false
+		}
+	case "up", "k":
+		b.cursor = max(0, b.cursor-1)
+	case "down", "j":
+		b.cursor = min(max(0, len(b.items)-1), b.cursor+1)
+	case "pgup":
+		b.cursor = max(0, b.cursor-10)
+	case "pgdown":
+		b.cursor = min(max(0, len(b.items)-1), b.cursor+10)
+	case "home", "g":
+		b.cursor = 0
+	case "end", "G":
+		b.cursor = max(0, len(b.items)-1)
+	case "enter":
+		_, b.detail = b.selected()
+	case "/":
+		b.searching, b.detail = true, false
+	case "r":
+		return m, loadBrowseCmd(m.ctx, m.st)
+	case "t":
+		if m.loc == time.UTC {
+			m.loc = time.Local
+		} else {
+			m.loc = time.UTC
+		}
+	case "p", "d", "+", "=", "-":
+		rec, ok := b.selected()
+		if !ok {
+			return m, nil
+		}
+		if b.readOnly {
+			b.status = "read-only: reopen the dashboard without --read-only, --replica or --immutable to change memories"
+			return m, nil
+		}
+		switch key {
+		case "p":
+			if rec.Scope != "short" {
+				b.status = rec.ID + " is already " + rec.Scope
+				return m, nil
+			}
+			return m, promoteMemoryCmd(m.ctx, m.st, rec)
+		case "d":
+			b.confirmID = rec.ID
+			b.status = fmt.Sprintf("delete %s? press y to confirm, any other key to cancel", rec.ID)
+		case "+", "=":
+			if rec.Importance < 5 {
+				return m, setImportanceCmd(m.ctx, m.st, rec.ID, rec.Importance+1)
+			}
+		case "-":
+			if rec.Importance > 1 {
+				return m, setImportanceCmd(m.ctx, m.st, rec.ID, rec.Importance-1)
+			}
+		}
+	}
+	return m, nil
+}
+
+func loadBrowseCmd(ctx context.Context, st browserStore) tea.Cmd {
+	return func() tea.Msg {
+		records, err := st.ListMemories(ctx, store.ListFilter{
+			IncludeExpired: true,
+			Now:            time.Now().UTC(),
+			Sort:           store.SortCreatedDesc,
+			Limit:          browseLimit,
+		})
+		return browseMsg{records: records, err: err}
+	}
+}
+
+func promoteMemoryCmd(ctx context.Context, st browserStore, rec types.MemoryRecord) tea.Cmd {
+	return func() tea.Msg {
+		now := time.Now().UTC()
+		if err := st.Promote(ctx, rec.ID, now); err != nil {
+			return actionMsg{err: fmt.Errorf("promote %s: %w", rec.ID, err)}
+		}
+		err := st.RecordPromotion(ctx, store.Promotion{
+			MemoryID:    rec.ID,
+			Namespace:   rec.Namespace,
+			PriorScope:  rec.Scope,
+			Reason:      "promoted in the admin browser",
+			SourceAgent: "admin",
+			PromotedAt:  now,
+		})
+		if err != nil {
+			return actionMsg{err: err}
+		}
+		return actionMsg{note: "promoted " + rec.ID}
+	}
+}
+
+func setImportanceCmd(ctx context.Context, st browserStore, id string, importance int) tea.Cmd {
+	return func() tea.Msg {
+		_, err := st.UpdateMemory(ctx, id, func(rec *types.MemoryRecord) error {
+			rec.Importance = importance
+			return nil
+		})
+		if err != nil {
+			return actionMsg{err: fmt.Errorf("update %s: %w", id, err)}
+		}
+		return actionMsg{note: fmt.Sprintf("set importance of %s to %d", id, importance)}
+	}
+}
+
+func deleteMemoryCmd(ctx context.Context, st browserStore, id string) tea.Cmd {
+	return func() tea.Msg {
+		if _, err := st.DeleteMemories(ctx, []string{id}); err != nil {
+			return actionMsg{err: fmt.Errorf("delete %s: %w", id, err)}
+		}
+		return actionMsg{note: "deleted " + id}
+	}
+}
+
+func (m model) viewBrowser(title string, width, height int) string {
+	b := m.browse
+	help := "↑/↓ select • enter details • / search • p promote • d delete • +/- importance • r reload • esc back • m dashboard • q quit"
+	if b.searching {
+		help = "type to search • enter keep results • esc clear"
+	}
+	meta := lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render(help)
+
+	search := "Search: " + b.query
+	if b.searching {
+		search += "█"
+	}
+	status := fmt.Sprintf("%d of %d memories (newest %d loaded)", len(b.items), len(b.records), browseLimit)
+	if b.status != "" {
+		status = b.status
+	}
+	header := search + "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render(status)
+
+	var pane string
+	if rec, ok := b.selected(); ok && b.detail {
+		pane = renderPane("Memory "+rec.ID, header+"\n\n"+formatMemoryDetail(rec, m.loc), width, height)
+	} else {
+		pane = renderPane("Memories", header+"\n\n"+formatBrowseList(b.items, b.cursor, max(1, height-8), m.loc), width, height)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, title, meta, "", pane)
+}
+
+// formatBrowseList renders up to rows items, scrolled so cursor is shown.
+func formatBrowseList(items []types.MemoryRecord, cursor, rows int, loc *time.Location) string {
+	if len(items) == 0 {
+		return "(no matching memories)"
+	}
+	start := 0
+	if cursor >= rows {
+		start = cursor - rows + 1
+	}
+	end := min(len(items), start+rows)
+	selected := lipgloss.NewStyle().Reverse(true)
+	lines := make([]string, 0, end-start)
+	for i := start; i < end; i++ {
+		rec := items[i]
+		line := fmt.Sprintf("[%s] %s %d %-20s %s",
+			formatClock(rec.CreatedAt, loc),
+			scopeLetter(rec.Scope),
+			rec.Importance,
+			truncateText(rec.Namespace, 20),
+			truncateText(compactWhitespace(cmp.Or(rec.Summary, rec.Content)), 72),
+		)
+		if i == cursor {
+			line = selected.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatMemoryDetail(rec types.MemoryRecord, loc *time.Location) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Namespace:     %s\n", rec.Namespace)
+	fmt.Fprintf(&b, "Scope:         %s\n", rec.Scope)
+	fmt.Fprintf(&b, "Importance:    %d\n", rec.Importance)
+	if rec.SourceAgent != "" {
+		fmt.Fprintf(&b, "Source agent:  %s\n", rec.SourceAgent)
+	}
+	if rec.ExternalKey != "" {
+		fmt.Fprintf(&b, "External key:  %s\n", rec.ExternalKey)
+	}
+	fmt.Fprintf(&b, "Created:       %s\n", formatTime(rec.CreatedAt, loc))
+	fmt.Fprintf(&b, "Last accessed: %s (%d accesses)\n", formatTime(rec.LastAccessedAt, loc), rec.AccessCount)
+	if rec.ExpiresAt != nil {
+		fmt.Fprintf(&b, "Expires:       %s\n", formatTime(*rec.ExpiresAt, loc))
+	}
+	if rec.PromotedAt != nil {
+		fmt.Fprintf(&b, "Promoted:      %s\n", formatTime(*rec.PromotedAt, loc))
+	}
+	if rec.Summary != "" {
+		fmt.Fprintf(&b, "\nSummary:\n%s\n", rec.Summary)
+	}
+	fmt.Fprintf(&b, "\nContent:\n%s", rec.Content)
+	if len(rec.Metadata) > 0 {
+		meta, err := json.MarshalIndent(rec.Metadata, "", "  ")
+		if err != nil {
+			meta = []byte(err.Error())
+		}
+		fmt.Fprintf(&b, "\n\nMetadata:\n%s", meta)
+	}
+	return b.String()
+}
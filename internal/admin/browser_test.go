@@ -0,0 +1,101 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestFilterMemories(t *testing.T) {
+	t.Parallel()
+	recs := []types.MemoryRecord{
+		{ID: "a", Namespace: "acme/api", Content: "Deploy window is 9-11", Metadata: map[string]any{"tags": []any{"ops"}}},
+		{ID: "b", Namespace: "acme/web", Content: "Use pnpm"},
+	}
+	for query, want := range map[string]int{"": 2, "DEPLOY": 1, "acme pnpm": 1, "ops": 1, "deploy pnpm": 0} {
+		if got := filterMemories(recs, query); len(got) != want {
+			t.Fatalf("filterMemories(%q) = %d records, want %d", query, len(got), want)
+		}
+	}
+}
+
+func TestBrowser_SearchAndActions(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "browse.db"), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	now := time.Now().UTC()
+	expires := now.Add(time.Hour)
+	_, err = st.InsertMemories(ctx, []types.MemoryRecord{
+		{ID: "m-1", Namespace: "acme/api", Scope: "short", Content: "deploy window is 9-11", Importance: 3, CreatedAt: now.Add(-time.Minute), LastAccessedAt: now, ExpiresAt: &expires},
+		{ID: "m-2", Namespace: "acme/web", Scope: "long", Content: "use pnpm", Importance: 2, CreatedAt: now, LastAccessedAt: now},
+	})
+	if err != nil {
+		t.Fatalf("InsertMemories() error = %v", err)
+	}
+
+	var m tea.Model = model{ctx: ctx, loc: time.UTC, st: st}
+	// press sends keys and runs the commands they return, as the program
+	// would, until none is left.
+	press := func(keys ...tea.KeyMsg) {
+		t.Helper()
+		for _, key := range keys {
+			var cmd tea.Cmd
+			m, cmd = m.Update(key)
+			for cmd != nil {
+				m, cmd = m.Update(cmd())
+			}
+		}
+	}
+	runes := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+
+	press(runes("m"))
+	if b := m.(model).browse; !b.active || len(b.items) != 2 || b.items[0].ID != "m-2" {
+		t.Fatalf("browser after m = %+v, want both memories newest first", b)
+	}
+	press(runes("/"), runes("d"), runes("eploy"), tea.KeyMsg{Type: tea.KeyEnter})
+	if b := m.(model).browse; b.searching || len(b.items) != 1 || b.items[0].ID != "m-1" {
+		t.Fatalf("browser after search = %+v, want only m-1", b)
+	}
+	press(runes("p"), runes("+"))
+	rec, err := st.GetMemory(ctx, "m-1")
+	if err != nil || rec.Scope != "long" || rec.Importance != 4 {
+		t.Fatalf("GetMemory() after promote and + = %+v, %v", rec, err)
+	}
+	if promos, err := st.RecentPromotions(ctx, 5); err != nil || len(promos) != 1 || promos[0].PriorScope != "short" {
+		t.Fatalf("RecentPromotions() = %+v, %v", promos, err)
+	}
+	press(runes("d"), runes("n"))
+	if _, err := st.GetMemory(ctx, "m-1"); err != nil {
+		t.Fatalf("GetMemory() after cancelled delete error = %v", err)
+	}
+	press(runes("d"), runes("y"))
+	if _, err := st.GetMemory(ctx, "m-1"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetMemory() after delete error = %v, want sql.ErrNoRows", err)
+	}
+	if b := m.(model).browse; len(b.records) != 1 || len(b.items) != 0 {
+		t.Fatalf("browser after delete = %+v, want m-2 loaded and filtered out", b)
+	}
+
+	readOnly := m.(model)
+	readOnly.browse.readOnly, readOnly.browse.query = true, ""
+	readOnly.browse.filter()
+	m = readOnly
+	press(runes("-"))
+	if rec, err := st.GetMemory(ctx, "m-2"); err != nil || rec.Importance != 2 {
+		t.Fatalf("GetMemory() after read-only - = %+v, %v, want importance unchanged", rec, err)
+	}
+}
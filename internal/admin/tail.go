@@ -0,0 +1,196 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/xiy/memory-mcp/internal/store"
+)
+
+const (
+	// tailInterval is how often the live tail polls for new requests.
+	tailInterval = 500 * time.Millisecond
+	// tailBuffer is how many requests the live tail keeps.
+	tailBuffer = 500
+)
+
+// tailMsg carries the requests logged after the tail's cursor. gen ties it
+// to the tail session that asked, so a stale poll from an earlier session
+// is dropped.
+type tailMsg struct {
+	gen  int
+	rows []store.MCPRequestLog
+	err  error
+}
+
+type tailTickMsg struct{ gen int }
+
+// tail is the state of the live request tail, opened with the l key.
+type tail struct {
+	active bool
+	gen    int
+	// cursor is the ID of the newest request received.
+	cursor int64
+	// rows are the received requests, oldest first.
+	rows       []store.MCPRequestLog
+	errorsOnly bool
+	// editing is set while the tool filter is being typed.
+	editing bool
+	tool    string
+	lastErr error
+}
+
+// matches reports whether row passes the tool and error filters.
+func (t tail) matches(row store.MCPRequestLog) bool {
+	if t.errorsOnly && row.Success {
+		return false
+	}
+	if t.tool == "" {
+		return true
+	}
+	name := strings.ToLower(row.Method + ":" + row.ToolName)
+	return strings.Contains(name, strings.ToLower(strings.TrimSpace(t.tool)))
+}
+
+func (t *tail) append(rows []store.MCPRequestLog) {
+	if len(rows) == 0 {
+		return
+	}
+	t.rows = append(t.rows, rows...)
+	if len(t.rows) > tailBuffer {
+		t.rows = slices.Clone(t.rows[len(t.rows)-tailBuffer:])
+	}
+	t.cursor = rows[len(rows)-1].ID
+}
+
+// startTail opens the tail with the newest requests already logged.
+func (m model) startTail() (model, tea.Cmd) {
+	m.tail.active = true
+	m.tail.gen++
+	m.tail.rows, m.tail.cursor, m.tail.lastErr = nil, 0, nil
+	gen, st, ctx := m.tail.gen, m.st, m.ctx
+	return m, func() tea.Msg {
+		rows, err := st.RecentMCPRequestLogs(ctx, tailBuffer)
+		slices.Reverse(rows)
+		return tailMsg{gen: gen, rows: rows, err: err}
+	}
+}
+
+func tailTickCmd(gen int) tea.Cmd {
+	return tea.Tick(tailInterval, func(time.Time) tea.Msg { return tailTickMsg{gen: gen} })
+}
+
+func pollTailCmd(ctx context.Context, st dashboardStore, gen int, cursor int64) tea.Cmd {
+	return func() tea.Msg {
+		rows, err := st.MCPRequestLogsAfter(ctx, cursor, tailBuffer)
+		return tailMsg{gen: gen, rows: rows, err: err}
+	}
+}
+
+// updateTail handles the tail's messages; ok is false for any other.
+func (m model) updateTail(msg tea.Msg) (_ model, _ tea.Cmd, ok bool) {
+	switch msg := msg.(type) {
+	case tailMsg:
+		if !m.tail.active || msg.gen != m.tail.gen {
+			return m, nil, true
+		}
+		m.tail.lastErr = msg.err
+		if msg.err == nil {
+			m.tail.append(msg.rows)
+		}
+		return m, tailTickCmd(msg.gen), true
+	case tailTickMsg:
+		if !m.tail.active || msg.gen != m.tail.gen {
+			return m, nil, true
+		}
+		return m, pollTailCmd(m.ctx, m.st, msg.gen, m.tail.cursor), true
+	}
+	return m, nil, false
+}
+
+func (m model) updateTailKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	t := &m.tail
+	if t.editing {
+		switch msg.Type {
+		case tea.KeyCtrlC:
+			return m, tea.Quit
+		case tea.KeyEnter:
+			t.editing = false
+		case tea.KeyEsc:
+			t.editing, t.tool = false, ""
+		case tea.KeyBackspace:
+			if r := []rune(t.tool); len(r) > 0 {
+				t.tool = string(r[:len(r)-1])
+			}
+		case tea.KeyRunes:
+			t.tool += string(msg.Runes)
+		}
+		return m, nil
+	}
+	switch msg.String() {
+	case "q", "ctrl+c":
+		m = m.appendLog("received quit signal")
+		return m, tea.Quit
+	case "l", "esc":
+		t.active = false
+	case "/":
+		t.editing = true
+	case "e":
+		t.errorsOnly = !t.errorsOnly
+	case "x":
+		t.rows = nil
+	case "t":
+		if m.loc == time.UTC {
+			m.loc = time.Local
+		} else {
+			m.loc = time.UTC
+		}
+	}
+	return m, nil
+}
+
+func (m model) viewTail(title string, width, height int) string {
+	t := m.tail
+	help := "/ filter by tool • e errors only • x clear • t toggle UTC/local • esc back • q quit"
+	if t.editing {
+		help = "type a tool or method name • enter keep filter • esc clear"
+	}
+	meta := lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render(help)
+
+	var shown []string
+	for _, row := range t.rows {
+		if t.matches(row) {
+			shown = append(shown, formatRequestLine(row, m.loc))
+		}
+	}
+	filters := "Tool: " + t.tool
+	if t.editing {
+		filters += "█"
+	}
+	if t.errorsOnly {
+		filters += "   errors only"
+	}
+	status := fmt.Sprintf("%d of %d requests • polling every %s", len(shown), len(t.rows), tailInterval)
+	if t.lastErr != nil {
+		status = "poll error: " + truncateText(compactWhitespace(t.lastErr.Error()), 100)
+	}
+	rows := 20
+	if height > 0 {
+		rows = max(1, height-8)
+	}
+	if len(shown) > rows {
+		shown = shown[len(shown)-rows:]
+	}
+	body := "(waiting for MCP requests)"
+	if len(shown) > 0 {
+		body = strings.Join(shown, "\n")
+	}
+	header := filters + "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render(status)
+	return lipgloss.JoinVertical(lipgloss.Left, title, meta, "", renderPane("Live MCP Requests", header+"\n\n"+body, width, height))
+}
@@ -0,0 +1,65 @@
+package admin
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/store"
+)
+
+func TestTail_FollowsNewRequests(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "tail.db"), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	now := time.Now().UTC()
+	logRequest := func(tool string, ok bool, at time.Time) {
+		t.Helper()
+		if err := st.InsertMCPRequestLog(ctx, store.MCPRequestLog{Method: "tools/call", ToolName: tool, Success: ok, CreatedAt: at}); err != nil {
+			t.Fatalf("InsertMCPRequestLog() error = %v", err)
+		}
+	}
+	logRequest("memory_search", true, now)
+	logRequest("memory_write", true, now.Add(time.Second))
+
+	update := func(m model, msg tea.Msg) (model, tea.Cmd) {
+		t.Helper()
+		next, cmd := m.Update(msg)
+		return next.(model), cmd
+	}
+	m, cmd := update(model{ctx: ctx, loc: time.UTC, st: st}, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")})
+	m, _ = update(m, cmd())
+	if len(m.tail.rows) != 2 || m.tail.rows[1].ToolName != "memory_write" {
+		t.Fatalf("tail after l = %+v, want both requests oldest first", m.tail.rows)
+	}
+
+	logRequest("memory_write", false, now.Add(2*time.Second))
+	m, cmd = update(m, tailTickMsg{gen: m.tail.gen})
+	m, _ = update(m, cmd())
+	if len(m.tail.rows) != 3 || m.tail.rows[2].Success {
+		t.Fatalf("tail after poll = %+v, want the failed write appended", m.tail.rows)
+	}
+	if _, cmd := update(m, tailTickMsg{gen: m.tail.gen - 1}); cmd != nil {
+		t.Fatal("stale tail tick scheduled a poll")
+	}
+
+	var shown int
+	m.tail.tool, m.tail.errorsOnly = "WRITE", true
+	for _, row := range m.tail.rows {
+		if m.tail.matches(row) {
+			shown++
+		}
+	}
+	if shown != 1 {
+		t.Fatalf("tail matches %d requests for failed writes, want 1", shown)
+	}
+}
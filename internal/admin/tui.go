@@ -8,31 +8,68 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"gopkg.in/yaml.v3"
 
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/mcp"
 	"github.com/xiy/memory-mcp/internal/store"
 )
 
 type tickMsg time.Time
 type dashboardMsg struct {
-	stats    store.Stats
-	reqLogs  []store.MCPRequestLog
-	memories []store.RecentMemory
-	err      error
-	duration time.Duration
+	stats      store.Stats
+	health     store.IndexHealth
+	reqLogs    []store.MCPRequestLog
+	memories   []store.RecentMemory
+	serverLogs []store.ServerLog
+	clients    []store.ClientRequests
+	promotions []store.Promotion
+	live       []mcp.Snapshot
+	err        error
+	duration   time.Duration
 }
 
 type dashboardStore interface {
 	Stats(ctx context.Context, now time.Time) (store.Stats, error)
 	RecentMCPRequestLogs(ctx context.Context, limit int) ([]store.MCPRequestLog, error)
+	MCPRequestLogsAfter(ctx context.Context, afterID int64, limit int) ([]store.MCPRequestLog, error)
 	RecentMemories(ctx context.Context, limit int) ([]store.RecentMemory, error)
+	IndexHealth(ctx context.Context) (store.IndexHealth, error)
+	RecentServerLogs(ctx context.Context, limit int) ([]store.ServerLog, error)
+	RequestsByClient(ctx context.Context, limit int) ([]store.ClientRequests, error)
+	RecentPromotions(ctx context.Context, limit int) ([]store.Promotion, error)
+	browserStore
+}
+
+// Options configure the admin dashboard.
+type Options struct {
+	// LocalTime renders timestamps in the local timezone instead of UTC.
+	LocalTime bool
+	// Config is the effective configuration, shown with the c key.
+	Config config.Config
+	// ConfigPath is the config file requested on the command line.
+	ConfigPath string
+	// Attach, when set, fetches live snapshots of running servers for a
+	// Live Servers pane.
+	Attach func(ctx context.Context) ([]mcp.Snapshot, error)
+	// ReadOnly disables the memory browser's promote, delete and
+	// importance actions.
+	ReadOnly bool
 }
 
 type model struct {
 	ctx           context.Context
+	loc           *time.Location
 	st            dashboardStore
 	stats         store.Stats
+	health        store.IndexHealth
 	reqLogs       []store.MCPRequestLog
 	memories      []store.RecentMemory
+	serverLogs    []store.ServerLog
+	clients       []store.ClientRequests
+	promotions    []store.Promotion
+	attach        func(ctx context.Context) ([]mcp.Snapshot, error)
+	live          []mcp.Snapshot
 	lastErr       error
 	lastTick      time.Time
 	logLines      []string
@@ -41,16 +78,28 @@ type model struct {
 	memoriesLimit int
 	width         int
 	height        int
+	showConfig    bool
+	configText    string
+	browse        browser
+	tail          tail
 }
 
 // Run starts a lightweight local admin dashboard.
-func Run(ctx context.Context, st dashboardStore) error {
+func Run(ctx context.Context, st dashboardStore, opts Options) error {
+	loc := time.UTC
+	if opts.LocalTime {
+		loc = time.Local
+	}
 	m := model{
 		ctx:           ctx,
+		loc:           loc,
 		st:            st,
 		maxLogs:       10,
 		requestsLimit: 8,
 		memoriesLimit: 8,
+		configText:    formatConfigPane(opts.Config, opts.ConfigPath),
+		attach:        opts.Attach,
+		browse:        browser{readOnly: opts.ReadOnly},
 	}
 	m = m.appendLog("admin UI started")
 	p := tea.NewProgram(m, tea.WithAltScreen())
@@ -59,29 +108,73 @@ func Run(ctx context.Context, st dashboardStore) error {
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Batch(fetchDashboardCmd(m.ctx, m.st, m.requestsLimit, m.memoriesLimit), tickCmd())
+	return tea.Batch(fetchDashboardCmd(m.ctx, m.st, m.attach, m.requestsLimit, m.memoriesLimit), tickCmd())
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m, cmd, ok := m.updateTail(msg); ok {
+		return m, cmd
+	}
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.browse.active {
+			return m.updateBrowser(msg)
+		}
+		if m.tail.active {
+			return m.updateTailKey(msg)
+		}
 		switch msg.String() {
 		case "q", "ctrl+c":
 			m = m.appendLog("received quit signal")
 			return m, tea.Quit
+		case "t":
+			if m.loc == time.UTC {
+				m.loc = time.Local
+			} else {
+				m.loc = time.UTC
+			}
+			m = m.appendLog("showing times in " + zoneLabel(m.loc))
+		case "c":
+			m.showConfig = !m.showConfig
+		case "m":
+			m.browse.active = true
+			return m, loadBrowseCmd(m.ctx, m.st)
+		case "l":
+			return m.startTail()
 		}
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+	case browseMsg:
+		if msg.err != nil {
+			m.browse.status = "load failed: " + compactWhitespace(msg.err.Error())
+			m = m.appendLog(fmt.Sprintf("browse error: %v", msg.err))
+		} else {
+			m.browse.setRecords(msg.records)
+		}
+	case actionMsg:
+		if msg.err != nil {
+			m.browse.status = compactWhitespace(msg.err.Error())
+			m = m.appendLog(fmt.Sprintf("browser error: %v", msg.err))
+			return m, nil
+		}
+		m.browse.status = msg.note
+		m = m.appendLog(msg.note)
+		return m, loadBrowseCmd(m.ctx, m.st)
 	case tickMsg:
 		m.lastTick = time.Time(msg)
-		return m, tea.Batch(fetchDashboardCmd(m.ctx, m.st, m.requestsLimit, m.memoriesLimit), tickCmd())
+		return m, tea.Batch(fetchDashboardCmd(m.ctx, m.st, m.attach, m.requestsLimit, m.memoriesLimit), tickCmd())
 	case dashboardMsg:
 		m.lastErr = msg.err
 		if msg.err == nil {
 			m.stats = msg.stats
+			m.health = msg.health
 			m.reqLogs = msg.reqLogs
 			m.memories = msg.memories
+			m.serverLogs = msg.serverLogs
+			m.clients = msg.clients
+			m.promotions = msg.promotions
+			m.live = msg.live
 			m = m.appendLog(fmt.Sprintf(
 				"refresh ok total=%d short=%d long=%d req=%d mem=%d (%s)",
 				msg.stats.Total,
@@ -100,7 +193,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m model) View() string {
 	title := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("205")).Render("memory-mcp admin")
-	meta := lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render("q to quit • refresh every 2s")
+	meta := lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render(
+		"q to quit • t to toggle UTC/local (" + zoneLabel(m.loc) + ") • c to toggle config • m to browse memories • l to tail requests • refresh every 2s")
 
 	statsBody := m.renderStats()
 	logBody := "(no log events yet)"
@@ -112,46 +206,105 @@ func (m model) View() string {
 	if m.width > 0 {
 		paneWidth = max(38, (m.width-3)/2)
 	}
+	rows := 4
+	if m.attach != nil {
+		rows = 5
+	}
 	paneHeight := 9
 	if m.height > 0 {
-		paneHeight = max(8, (m.height-8)/2)
+		paneHeight = max(8, (m.height-10)/rows)
+	}
+
+	if m.browse.active || m.tail.active {
+		height := 0
+		if m.height > 0 {
+			height = max(10, m.height-7)
+		}
+		if m.tail.active {
+			return m.viewTail(title, 2*paneWidth+1, height)
+		}
+		return m.viewBrowser(title, 2*paneWidth+1, height)
+	}
+
+	if m.showConfig {
+		return lipgloss.JoinVertical(
+			lipgloss.Left,
+			title,
+			meta,
+			"",
+			renderPane("Config", m.configText, 2*paneWidth+1, 0),
+		)
 	}
 
 	topRow := joinColumns(
 		renderPane("Stats", statsBody, paneWidth, paneHeight),
-		renderPane("General Logs", logBody, paneWidth, paneHeight),
+		renderPane("Index Health", formatIndexHealthPane(m.health, m.loc), paneWidth, paneHeight),
+	)
+	middleRow := joinColumns(
+		renderPane("MCP Requests", formatRequestPane(m.reqLogs, m.loc), paneWidth, paneHeight),
+		renderPane("Recent Memories", formatRecentMemoriesPane(m.memories, m.loc), paneWidth, paneHeight),
 	)
 	bottomRow := joinColumns(
-		renderPane("MCP Requests", formatRequestPane(m.reqLogs), paneWidth, paneHeight),
-		renderPane("Recent Memories", formatRecentMemoriesPane(m.memories), paneWidth, paneHeight),
+		renderPane("Server Logs", formatServerLogsPane(m.serverLogs, m.loc), paneWidth, paneHeight),
+		renderPane("General Logs", logBody, paneWidth, paneHeight),
 	)
 
-	return lipgloss.JoinVertical(
-		lipgloss.Left,
-		title,
-		meta,
-		"",
-		topRow,
-		bottomRow,
-	)
+	sections := []string{
+		title, meta, "", topRow, middleRow, bottomRow,
+		renderPane("Recent Promotions", formatPromotionsPane(m.promotions, m.loc), 2*paneWidth+1, paneHeight),
+	}
+	if m.attach != nil {
+		sections = append(sections, renderPane("Live Servers", formatLivePane(m.live, m.loc), 2*paneWidth+1, paneHeight))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
 }
 
 func (m model) renderStats() string {
 	body := fmt.Sprintf(
-		"Total memories:  %d\nShort-term:      %d\nLong-term:       %d\nExpired (now):   %d\nLast refresh:    %s",
+		"Total memories:  %d\nShort-term:      %d\nLong-term:       %d\nProject:         %d\nGlobal:          %d\nExpired (now):   %d\nFeedback:        %d useful / %d irrelevant\nLast refresh:    %s",
 		m.stats.Total,
 		m.stats.Short,
 		m.stats.Long,
+		m.stats.Project,
+		m.stats.Global,
 		m.stats.Expired,
-		formatTime(m.lastTick),
+		m.stats.Useful,
+		m.stats.Irrelevant,
+		formatTime(m.lastTick, m.loc),
 	)
+	if len(m.stats.Agents) > 0 {
+		body += "\nMemories by agent:"
+		for i, g := range m.stats.Agents {
+			if i == 3 {
+				break
+			}
+			name := g.Key
+			if name == "" {
+				name = "(unknown)"
+			}
+			body += fmt.Sprintf("\n  %-20s %d", truncateText(name, 20), g.Count)
+		}
+	}
+	if len(m.clients) > 0 {
+		body += "\nRequests by client:"
+		for i, c := range m.clients {
+			if i == 3 {
+				break
+			}
+			name := c.Client
+			if name == "" {
+				name = "(unknown)"
+			}
+			body += fmt.Sprintf("\n  %-20s %d (%d err)", truncateText(name, 20), c.Requests, c.Errors)
+		}
+	}
 	if m.lastErr != nil {
 		body += "\n\nLast error: " + truncateText(compactWhitespace(m.lastErr.Error()), 120)
 	}
 	return body
 }
 
-func fetchDashboardCmd(ctx context.Context, st dashboardStore, reqLimit, memLimit int) tea.Cmd {
+func fetchDashboardCmd(ctx context.Context, st dashboardStore, attach func(context.Context) ([]mcp.Snapshot, error), reqLimit, memLimit int) tea.Cmd {
 	return func() tea.Msg {
 		start := time.Now()
 		now := time.Now().UTC()
@@ -170,11 +323,43 @@ func fetchDashboardCmd(ctx context.Context, st dashboardStore, reqLimit, memLimi
 			return dashboardMsg{stats: s, reqLogs: reqLogs, err: err, duration: time.Since(start)}
 		}
 
+		health, err := st.IndexHealth(ctx)
+		if err != nil {
+			return dashboardMsg{stats: s, reqLogs: reqLogs, memories: memories, err: err, duration: time.Since(start)}
+		}
+
+		serverLogs, err := st.RecentServerLogs(ctx, reqLimit)
+		if err != nil {
+			return dashboardMsg{stats: s, health: health, reqLogs: reqLogs, memories: memories, err: err, duration: time.Since(start)}
+		}
+
+		clients, err := st.RequestsByClient(ctx, 3)
+		if err != nil {
+			return dashboardMsg{stats: s, health: health, reqLogs: reqLogs, memories: memories, serverLogs: serverLogs, err: err, duration: time.Since(start)}
+		}
+
+		promotions, err := st.RecentPromotions(ctx, reqLimit)
+		if err != nil {
+			return dashboardMsg{stats: s, health: health, reqLogs: reqLogs, memories: memories, serverLogs: serverLogs, clients: clients, err: err, duration: time.Since(start)}
+		}
+
+		var live []mcp.Snapshot
+		if attach != nil {
+			if live, err = attach(ctx); err != nil {
+				return dashboardMsg{stats: s, health: health, reqLogs: reqLogs, memories: memories, serverLogs: serverLogs, clients: clients, promotions: promotions, err: err, duration: time.Since(start)}
+			}
+		}
+
 		return dashboardMsg{
-			stats:    s,
-			reqLogs:  reqLogs,
-			memories: memories,
-			duration: time.Since(start),
+			stats:      s,
+			health:     health,
+			reqLogs:    reqLogs,
+			memories:   memories,
+			serverLogs: serverLogs,
+			clients:    clients,
+			promotions: promotions,
+			live:       live,
+			duration:   time.Since(start),
 		}
 	}
 }
@@ -183,18 +368,26 @@ func tickCmd() tea.Cmd {
 	return tea.Tick(2*time.Second, func(t time.Time) tea.Msg { return tickMsg(t) })
 }
 
-func formatTime(t time.Time) string {
+func formatTime(t time.Time, loc *time.Location) string {
 	if t.IsZero() {
 		return "-"
 	}
-	return t.Format(time.RFC3339)
+	return t.In(loc).Format(time.RFC3339)
+}
+
+func zoneLabel(loc *time.Location) string {
+	if loc == time.UTC {
+		return "UTC"
+	}
+	name, _ := time.Now().In(loc).Zone()
+	return "local " + name
 }
 
 func (m model) appendLog(line string) model {
 	if strings.TrimSpace(line) == "" {
 		return m
 	}
-	entry := fmt.Sprintf("[%s] %s", time.Now().UTC().Format("15:04:05"), line)
+	entry := fmt.Sprintf("[%s] %s", formatClock(time.Now(), m.loc), line)
 	m.logLines = append(m.logLines, entry)
 	if m.maxLogs <= 0 {
 		m.maxLogs = 10
@@ -230,50 +423,53 @@ func joinColumns(left, right string) string {
 	return lipgloss.JoinHorizontal(lipgloss.Top, left, " ", right)
 }
 
-func formatRequestPane(rows []store.MCPRequestLog) string {
+func formatRequestPane(rows []store.MCPRequestLog, loc *time.Location) string {
 	if len(rows) == 0 {
 		return "(no MCP requests yet)"
 	}
 	lines := make([]string, 0, len(rows))
 	for _, row := range rows {
-		method := strings.TrimSpace(row.Method)
-		if row.ToolName != "" {
-			method += ":" + strings.TrimSpace(row.ToolName)
-		}
-		status := "ok"
-		if !row.Success {
-			status = "err"
-		}
-		line := fmt.Sprintf(
-			"[%s] %-3s %-24s %4dms",
-			formatClock(row.CreatedAt),
-			status,
-			truncateText(method, 24),
-			max(0, row.DurationMS),
-		)
-		if !row.Success && strings.TrimSpace(row.ErrorText) != "" {
-			line += " " + truncateText(compactWhitespace(row.ErrorText), 52)
-		}
-		lines = append(lines, line)
+		lines = append(lines, formatRequestLine(row, loc))
 	}
 	return strings.Join(lines, "\n")
 }
 
-func formatRecentMemoriesPane(rows []store.RecentMemory) string {
+func formatRequestLine(row store.MCPRequestLog, loc *time.Location) string {
+	method := strings.TrimSpace(row.Method)
+	if row.ToolName != "" {
+		method += ":" + strings.TrimSpace(row.ToolName)
+	}
+	status := "ok"
+	if !row.Success {
+		status = "err"
+	}
+	line := fmt.Sprintf(
+		"[%s] %-3s %-24s %4dms",
+		formatClock(row.CreatedAt, loc),
+		status,
+		truncateText(method, 24),
+		max(0, row.DurationMS),
+	)
+	if row.ClientName != "" {
+		line += " " + truncateText(row.ClientName, 16)
+	}
+	if !row.Success && strings.TrimSpace(row.ErrorText) != "" {
+		line += " " + truncateText(compactWhitespace(row.ErrorText), 52)
+	}
+	return line
+}
+
+func formatRecentMemoriesPane(rows []store.RecentMemory, loc *time.Location) string {
 	if len(rows) == 0 {
 		return "(no memories yet)"
 	}
 	lines := make([]string, 0, len(rows))
 	for _, row := range rows {
-		scope := "L"
-		if row.Scope == "short" {
-			scope = "S"
-		}
 		summary := truncateText(compactWhitespace(row.Summary), 68)
 		line := fmt.Sprintf(
 			"[%s] %s %s :: %s",
-			formatClock(row.CreatedAt),
-			scope,
+			formatClock(row.CreatedAt, loc),
+			scopeLetter(row.Scope),
 			truncateText(row.Namespace, 20),
 			summary,
 		)
@@ -282,11 +478,161 @@ func formatRecentMemoriesPane(rows []store.RecentMemory) string {
 	return strings.Join(lines, "\n")
 }
 
-func formatClock(t time.Time) string {
+// scopeLetter abbreviates a memory scope for list panes: S, L, P or G.
+func scopeLetter(scope string) string {
+	if scope == "" {
+		return "?"
+	}
+	return strings.ToUpper(scope[:1])
+}
+
+func formatPromotionsPane(rows []store.Promotion, loc *time.Location) string {
+	if len(rows) == 0 {
+		return "(no promotions yet)"
+	}
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		by := row.SourceAgent
+		if by == "" {
+			by = "(unknown)"
+		}
+		reason := compactWhitespace(row.Reason)
+		if reason == "" {
+			reason = "(no reason given)"
+		}
+		lines = append(lines, fmt.Sprintf(
+			"[%s] %s->long %-24s %-20s by %-12s %s",
+			formatClock(row.PromotedAt, loc),
+			row.PriorScope,
+			truncateText(row.MemoryID, 24),
+			truncateText(row.Namespace, 20),
+			truncateText(by, 12),
+			truncateText(reason, 60),
+		))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatServerLogsPane(rows []store.ServerLog, loc *time.Location) string {
+	if len(rows) == 0 {
+		return "(no server log events yet)"
+	}
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		lines = append(lines, fmt.Sprintf(
+			"[%s] %-5s %s",
+			formatClock(row.CreatedAt, loc),
+			strings.ToUpper(row.Level),
+			truncateText(compactWhitespace(row.Message), 72),
+		))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatLivePane(snaps []mcp.Snapshot, loc *time.Location) string {
+	if len(snaps) == 0 {
+		return "(no running servers found in control_dir)"
+	}
+	var b strings.Builder
+	for i, snap := range snaps {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		client := strings.TrimSpace(snap.Client.Name + " " + snap.Client.Version)
+		if client == "" {
+			client = "(not initialized)"
+		}
+		fmt.Fprintf(&b, "pid %-7d %-24s up %-9s req %d err %d cache %d/%d pack %d/%d hit/miss",
+			snap.PID,
+			truncateText(client, 24),
+			snap.TS.Sub(snap.StartedAt).Round(time.Second),
+			snap.Requests,
+			snap.Errors,
+			snap.SearchCache.Hits,
+			snap.SearchCache.Misses,
+			snap.PackCache.Hits,
+			snap.PackCache.Misses,
+		)
+		if ka := snap.Keepalive; ka.IntervalSeconds > 0 {
+			fmt.Fprintf(&b, "\n  keepalive every %s: %d sent, %d answered, last rtt %dms",
+				time.Duration(ka.IntervalSeconds*float64(time.Second)), ka.Sent, ka.Answered, ka.LastRTTMS)
+			if ka.Unresponsive {
+				fmt.Fprintf(&b, " — UNRESPONSIVE (%d unanswered)", ka.Outstanding)
+			}
+		}
+		for _, call := range snap.InFlight {
+			fmt.Fprintf(&b, "\n  running %s since %s (%s)",
+				call.Tool,
+				formatClock(call.StartedAt, loc),
+				formatDuration(snap.TS.Sub(call.StartedAt)),
+			)
+		}
+	}
+	return b.String()
+}
+
+func formatConfigPane(cfg config.Config, requested string) string {
+	source := "Loaded from:  " + cfg.Path
+	if cfg.Path == "" {
+		source = fmt.Sprintf("No config file at %q; using built-in defaults", requested)
+	}
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		return source + "\n\n" + err.Error()
+	}
+	return source + "\nDatabase:     " + cfg.DBPath + "\n\n" + strings.TrimRight(string(out), "\n")
+}
+
+func formatIndexHealthPane(h store.IndexHealth, loc *time.Location) string {
+	var b strings.Builder
+	switch {
+	case h.FTSEnabled:
+		b.WriteString("FTS5:            enabled\n")
+	case h.FTSForcedOff:
+		b.WriteString("FTS5:            off (LIKE search forced)\n")
+	default:
+		b.WriteString("FTS5:            unavailable (LIKE fallback)\n")
+	}
+	if h.FTSEnabled || h.FTSForcedOff {
+		sync := "in sync"
+		if h.FTSRows != h.Memories {
+			sync = "DRIFT"
+		}
+		fmt.Fprintf(&b, "FTS rows:        %d / %d memories (%s)\n", h.FTSRows, h.Memories, sync)
+	}
+	last := "never"
+	if !h.LastReindex.IsZero() {
+		last = formatTime(h.LastReindex, loc)
+	}
+	fmt.Fprintf(&b, "Last reindex:    %s", last)
+	for i, o := range h.Sizes {
+		if i == 4 {
+			break
+		}
+		fmt.Fprintf(&b, "\n  %-28s %9s", truncateText(o.Name, 28), formatBytes(o.Bytes))
+	}
+	return b.String()
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+func formatClock(t time.Time, loc *time.Location) string {
 	if t.IsZero() {
 		return "--:--:--"
 	}
-	return t.UTC().Format("15:04:05")
+	if loc == nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format("15:04:05")
 }
 
 func truncateText(s string, limit int) string {
@@ -0,0 +1,151 @@
+// Package auth maps API keys and MCP client identities to grants: the
+// namespace prefixes a caller may use and whether it may change memories.
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/xiy/memory-mcp/internal/config"
+)
+
+// ErrUnauthenticated is returned by Resolve when a caller matches no
+// principal.
+var ErrUnauthenticated = errors.New("authentication required")
+
+// ErrForbidden is returned by Grant.Check when a grant does not cover an
+// operation.
+var ErrForbidden = errors.New("access denied")
+
+// Wildcard in a principal's namespaces grants every namespace.
+const Wildcard = "*"
+
+// Grant is what a principal may do. A nil *Grant is unrestricted, as for
+// the CLI, admin commands and background jobs.
+type Grant struct {
+	Principal string
+	// Namespaces are the prefixes the principal may use.
+	Namespaces []string
+	ReadOnly   bool
+}
+
+// Unrestricted reports whether g covers every namespace.
+func (g *Grant) Unrestricted() bool {
+	return g == nil || (len(g.Namespaces) == 1 && g.Namespaces[0] == Wildcard)
+}
+
+// Allows reports whether namespace is one of g's prefixes or below one.
+func (g *Grant) Allows(namespace string) bool {
+	if g.Unrestricted() {
+		return true
+	}
+	namespace = strings.Trim(strings.TrimSpace(namespace), "/")
+	for _, prefix := range g.Namespaces {
+		if namespace == prefix || strings.HasPrefix(namespace, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns an ErrForbidden error unless g may use namespace, and
+// change memories in it when write is set. An empty namespace stands for
+// every namespace, which only an unrestricted grant covers.
+func (g *Grant) Check(namespace string, write bool) error {
+	if g == nil {
+		return nil
+	}
+	if write && g.ReadOnly {
+		return fmt.Errorf("%w: %s is read-only", ErrForbidden, g.Principal)
+	}
+	if strings.TrimSpace(namespace) == "" {
+		if g.Unrestricted() {
+			return nil
+		}
+		return fmt.Errorf("%w: %s is limited to %s; name a namespace", ErrForbidden, g.Principal, strings.Join(g.Namespaces, ", "))
+	}
+	if !g.Allows(namespace) {
+		return fmt.Errorf("%w: %s may not use namespace %q", ErrForbidden, g.Principal, namespace)
+	}
+	return nil
+}
+
+// Authenticator resolves callers to the grants of auth.principals.
+type Authenticator struct {
+	keys    map[[sha256.Size]byte]*Grant
+	clients map[string]*Grant
+}
+
+// New builds an Authenticator from cfg, or returns nil when auth is
+// disabled. Keys named by key_env are read from the environment now.
+func New(cfg config.AuthConfig) (*Authenticator, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	a := &Authenticator{keys: map[[sha256.Size]byte]*Grant{}, clients: map[string]*Grant{}}
+	for _, p := range cfg.Principals {
+		g := &Grant{Principal: strings.TrimSpace(p.Name), ReadOnly: p.ReadOnly}
+		for _, ns := range p.Namespaces {
+			ns = strings.Trim(strings.TrimSpace(ns), "/")
+			if ns == Wildcard {
+				g.Namespaces = []string{Wildcard}
+				break
+			}
+			g.Namespaces = append(g.Namespaces, ns)
+		}
+		if p.KeySHA256 != "" {
+			var sum [sha256.Size]byte
+			if _, err := hex.Decode(sum[:], []byte(p.KeySHA256)); err != nil {
+				return nil, fmt.Errorf("auth principal %s: key_sha256: %w", g.Principal, err)
+			}
+			if err := a.addKey(sum, g); err != nil {
+				return nil, err
+			}
+		}
+		if p.KeyEnv != "" {
+			key := os.Getenv(p.KeyEnv)
+			if key == "" {
+				return nil, fmt.Errorf("auth principal %s: environment variable %s is not set", g.Principal, p.KeyEnv)
+			}
+			if err := a.addKey(sha256.Sum256([]byte(key)), g); err != nil {
+				return nil, err
+			}
+		}
+		for _, client := range p.Clients {
+			client = strings.ToLower(strings.TrimSpace(client))
+			if other, ok := a.clients[client]; ok {
+				return nil, fmt.Errorf("auth principals %s and %s both claim client %q", other.Principal, g.Principal, client)
+			}
+			a.clients[client] = g
+		}
+	}
+	return a, nil
+}
+
+func (a *Authenticator) addKey(sum [sha256.Size]byte, g *Grant) error {
+	if other, ok := a.keys[sum]; ok && other != g {
+		return fmt.Errorf("auth principals %s and %s share an API key", other.Principal, g.Principal)
+	}
+	a.keys[sum] = g
+	return nil
+}
+
+// Resolve returns the grant of the principal key belongs to or, when no
+// key was sent, the principal claiming client. A key that matches no
+// principal fails even when client would match one.
+func (a *Authenticator) Resolve(key, client string) (*Grant, error) {
+	if key != "" {
+		if g, ok := a.keys[sha256.Sum256([]byte(key))]; ok {
+			return g, nil
+		}
+		return nil, fmt.Errorf("%w: unknown API key", ErrUnauthenticated)
+	}
+	if g, ok := a.clients[strings.ToLower(strings.TrimSpace(client))]; ok && client != "" {
+		return g, nil
+	}
+	return nil, fmt.Errorf("%w: send an API key (client %q matches no principal)", ErrUnauthenticated, client)
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/xiy/memory-mcp/internal/config"
+)
+
+func TestGrant_Check(t *testing.T) {
+	t.Parallel()
+	g := &Grant{Principal: "ci", Namespaces: []string{"acme/api"}}
+	for ns, want := range map[string]bool{
+		"acme/api":        true,
+		"acme/api/main":   true,
+		"acme/apiserver":  false,
+		"acme":            false,
+		"":                false,
+		"beta/api/branch": false,
+	} {
+		if err := g.Check(ns, false); (err == nil) != want {
+			t.Fatalf("Check(%q) error = %v, want allowed %v", ns, err, want)
+		}
+	}
+	ro := &Grant{Principal: "viewer", Namespaces: []string{Wildcard}, ReadOnly: true}
+	if err := ro.Check("", false); err != nil {
+		t.Fatalf("wildcard Check(\"\") error = %v", err)
+	}
+	if err := ro.Check("acme/api", true); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("read-only Check(write) error = %v, want ErrForbidden", err)
+	}
+	var none *Grant
+	if err := none.Check("", true); err != nil {
+		t.Fatalf("nil grant Check() error = %v", err)
+	}
+}
+
+func TestAuthenticator_Resolve(t *testing.T) {
+	t.Setenv("MEMORY_MCP_TEST_KEY", "from-env")
+	sum := sha256.Sum256([]byte("s3cret"))
+	a, err := New(config.AuthConfig{Enabled: true, Principals: []config.PrincipalConfig{
+		{Name: "ci", KeySHA256: hex.EncodeToString(sum[:]), Namespaces: []string{"acme/api/"}},
+		{Name: "ops", KeyEnv: "MEMORY_MCP_TEST_KEY", Namespaces: []string{"*"}},
+		{Name: "local", Clients: []string{"Codex"}, Namespaces: []string{"acme"}, ReadOnly: true},
+	}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for _, tc := range []struct {
+		key, client, want string
+	}{
+		{"s3cret", "codex", "ci"},
+		{"from-env", "", "ops"},
+		{"", "codex", "local"},
+	} {
+		g, err := a.Resolve(tc.key, tc.client)
+		if err != nil || g.Principal != tc.want {
+			t.Fatalf("Resolve(%q, %q) = %+v, %v, want %s", tc.key, tc.client, g, err, tc.want)
+		}
+	}
+	if g, _ := a.Resolve("s3cret", ""); !slices.Equal(g.Namespaces, []string{"acme/api"}) {
+		t.Fatalf("Namespaces = %v, want trimmed prefixes", g.Namespaces)
+	}
+	for _, tc := range [][2]string{{"wrong", "codex"}, {"", "claude-code"}, {"", ""}} {
+		if _, err := a.Resolve(tc[0], tc[1]); !errors.Is(err, ErrUnauthenticated) {
+			t.Fatalf("Resolve(%q, %q) error = %v, want ErrUnauthenticated", tc[0], tc[1], err)
+		}
+	}
+
+	if a, err := New(config.AuthConfig{}); a != nil || err != nil {
+		t.Fatalf("New(disabled) = %v, %v, want nil", a, err)
+	}
+	if _, err := New(config.AuthConfig{Enabled: true, Principals: []config.PrincipalConfig{
+		{Name: "ci", KeyEnv: "MEMORY_MCP_TEST_UNSET_KEY", Namespaces: []string{"acme"}},
+	}}); err == nil {
+		t.Fatal("New() with an unset key_env error = nil")
+	}
+}
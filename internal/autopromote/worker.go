@@ -0,0 +1,35 @@
+package autopromote
+
+import (
+	"context"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// Promoter represents the promotion pass needed by the worker.
+type Promoter interface {
+	AutoPromote(ctx context.Context) (int, error)
+}
+
+// Start launches a periodic auto-promotion worker.
+func Start(ctx context.Context, logger *log.Logger, interval time.Duration, promoter Promoter) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := promoter.AutoPromote(ctx)
+			if err != nil {
+				logger.Warn("auto-promotion failed", "error", err)
+				continue
+			}
+			if n > 0 {
+				logger.Info("auto-promotion promoted memories", "count", n)
+			}
+		}
+	}
+}
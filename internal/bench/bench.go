@@ -0,0 +1,244 @@
+// Package bench measures write, search and context-pack performance against
+// throwaway SQLite databases of increasing size.
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/memory"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+const benchNamespace = "bench/repo/main/load"
+
+// Options control a benchmark run.
+type Options struct {
+	// Sizes are the database sizes (row counts) to measure at.
+	Sizes []int
+	// Queries is the number of searches timed per size and path.
+	Queries int
+	// Dir holds the temporary databases; a temp dir is used when empty.
+	Dir  string
+	Seed int64
+}
+
+// Latency summarizes a set of timings.
+type Latency struct {
+	P50 time.Duration `json:"p50_ns"`
+	P95 time.Duration `json:"p95_ns"`
+	P99 time.Duration `json:"p99_ns"`
+}
+
+// SizeReport holds measurements for one database size.
+type SizeReport struct {
+	Size         int     `json:"size"`
+	WritesPerSec float64 `json:"writes_per_sec"`
+	SearchFTS    Latency `json:"search_fts"`
+	SearchLIKE   Latency `json:"search_like"`
+	ContextPack  Latency `json:"context_pack"`
+}
+
+// Report is the result of Run.
+type Report struct {
+	Sizes []SizeReport `json:"sizes"`
+}
+
+// Run populates one database per size through the memory service and times
+// searches on the FTS and LIKE paths plus context-pack generation.
+func Run(ctx context.Context, opts Options) (Report, error) {
+	if len(opts.Sizes) == 0 {
+		opts.Sizes = []int{1000, 10000}
+	}
+	if opts.Queries <= 0 {
+		opts.Queries = 200
+	}
+	if opts.Seed == 0 {
+		opts.Seed = 1
+	}
+	dir := opts.Dir
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "memory-mcp-bench-")
+		if err != nil {
+			return Report{}, err
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+
+	var rep Report
+	for _, size := range opts.Sizes {
+		sr, err := runSize(ctx, filepath.Join(dir, fmt.Sprintf("bench-%d.db", size)), size, opts)
+		if err != nil {
+			return rep, fmt.Errorf("size %d: %w", size, err)
+		}
+		rep.Sizes = append(rep.Sizes, sr)
+	}
+	return rep, nil
+}
+
+func runSize(ctx context.Context, dbPath string, size int, opts Options) (SizeReport, error) {
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	cfg := config.Default()
+	cfg.DBPath = dbPath
+	// Measure the store, not the search or pack caches.
+	cfg.Cache.SearchEntries = 0
+	cfg.Cache.PackEntries = 0
+
+	st, err := store.OpenSQLite(ctx, dbPath, logger)
+	if err != nil {
+		return SizeReport{}, err
+	}
+	defer st.Close()
+	likeSt, err := store.OpenSQLite(ctx, dbPath, logger, store.WithoutFTS())
+	if err != nil {
+		return SizeReport{}, err
+	}
+	defer likeSt.Close()
+
+	svc, err := memory.NewService(st, cfg, logger)
+	if err != nil {
+		return SizeReport{}, err
+	}
+	likeSvc, err := memory.NewService(likeSt, cfg, logger)
+	if err != nil {
+		return SizeReport{}, err
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	started := time.Now()
+	for i := 0; i < size; i++ {
+		if _, err := svc.Write(ctx, syntheticWrite(rng, i)); err != nil {
+			return SizeReport{}, err
+		}
+	}
+	elapsed := time.Since(started)
+
+	sr := SizeReport{Size: size, WritesPerSec: float64(size) / elapsed.Seconds()}
+	queries := make([]string, opts.Queries)
+	for i := range queries {
+		queries[i] = SyntheticQuery(rng)
+	}
+
+	if sr.SearchFTS, err = timeQueries(queries, func(q string) error {
+		_, err := svc.Search(ctx, types.SearchInput{Namespace: benchNamespace, Query: q})
+		return err
+	}); err != nil {
+		return sr, err
+	}
+	if sr.SearchLIKE, err = timeQueries(queries, func(q string) error {
+		_, err := likeSvc.Search(ctx, types.SearchInput{Namespace: benchNamespace, Query: q})
+		return err
+	}); err != nil {
+		return sr, err
+	}
+	if sr.ContextPack, err = timeQueries(queries, func(q string) error {
+		_, err := svc.ContextPack(ctx, types.ContextPackInput{Namespace: benchNamespace, Query: q, TokenBudget: 512})
+		return err
+	}); err != nil {
+		return sr, err
+	}
+	return sr, nil
+}
+
+func timeQueries(queries []string, fn func(string) error) (Latency, error) {
+	samples := make([]time.Duration, 0, len(queries))
+	for _, q := range queries {
+		start := time.Now()
+		if err := fn(q); err != nil {
+			return Latency{}, err
+		}
+		samples = append(samples, time.Since(start))
+	}
+	return Summarize(samples), nil
+}
+
+// Summarize computes nearest-rank percentiles; samples is sorted in place.
+func Summarize(samples []time.Duration) Latency {
+	if len(samples) == 0 {
+		return Latency{}
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	return Latency{
+		P50: percentile(samples, 0.50),
+		P95: percentile(samples, 0.95),
+		P99: percentile(samples, 0.99),
+	}
+}
+
+// percentile picks the nearest-rank percentile from sorted samples.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	idx := int(float64(len(sorted))*p+0.5) - 1
+	idx = max(0, min(idx, len(sorted)-1))
+	return sorted[idx]
+}
+
+// Write renders the report as an aligned table.
+func (r Report) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "rows\twrites/s\tfts p50\tfts p95\tlike p50\tlike p95\tpack p50\tpack p95")
+	for _, s := range r.Sizes {
+		fmt.Fprintf(tw, "%d\t%.0f\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			s.Size, s.WritesPerSec,
+			round(s.SearchFTS.P50), round(s.SearchFTS.P95),
+			round(s.SearchLIKE.P50), round(s.SearchLIKE.P95),
+			round(s.ContextPack.P50), round(s.ContextPack.P95),
+		)
+	}
+	return tw.Flush()
+}
+
+func round(d time.Duration) string {
+	return d.Round(time.Microsecond).String()
+}
+
+var vocabulary = strings.Fields(`deploy build cache sqlite index query latency
+timeout retry schema migration token budget namespace agent promote expire
+config release branch review test flaky regression auth session cursor
+vector ranking recency importance summary context pack worker ticket`)
+
+func syntheticWrite(rng *rand.Rand, i int) types.WriteInput {
+	scope := "short"
+	if rng.Intn(4) == 0 {
+		scope = "long"
+	}
+	return types.WriteInput{
+		Namespace:  benchNamespace,
+		Scope:      scope,
+		Content:    SyntheticContent(rng, i),
+		Importance: 1 + rng.Intn(5),
+	}
+}
+
+// SyntheticContent returns a note of 12-35 words drawn from a small
+// engineering vocabulary, numbered i so repeated calls never collide.
+func SyntheticContent(rng *rand.Rand, i int) string {
+	words := make([]string, 12+rng.Intn(24))
+	for j := range words {
+		words[j] = vocabulary[rng.Intn(len(vocabulary))]
+	}
+	return fmt.Sprintf("note %d: %s", i, strings.Join(words, " "))
+}
+
+// SyntheticQuery returns a 1-3 term query over the same vocabulary as
+// SyntheticContent.
+func SyntheticQuery(rng *rand.Rand) string {
+	n := 1 + rng.Intn(3)
+	terms := make([]string, n)
+	for i := range terms {
+		terms[i] = vocabulary[rng.Intn(len(vocabulary))]
+	}
+	return strings.Join(terms, " ")
+}
@@ -3,6 +3,7 @@ package bootstrap
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -39,17 +40,39 @@ type Runner interface {
 }
 
 // OSRunner executes commands via os/exec.
-type OSRunner struct{}
+type OSRunner struct {
+	// Stdout receives the commands' output; nil means os.Stdout.
+	Stdout io.Writer
+}
 
-func (OSRunner) Run(name string, args ...string) error {
+func (r OSRunner) Run(name string, args ...string) error {
 	cmd := exec.Command(name, args...)
-	cmd.Stdout = os.Stdout
+	cmd.Stdout = r.Stdout
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
 
+// Result reports what Bootstrap did.
+type Result struct {
+	// AuditLog is the file the commands were listed in.
+	AuditLog string          `json:"audit_log"`
+	DryRun   bool            `json:"dry_run"`
+	Commands []CommandResult `json:"commands"`
+}
+
+// CommandResult is one bootstrap command and its outcome: "ran", "planned"
+// in a dry run, or "ignored" for a remove that failed because the server
+// was not registered.
+type CommandResult struct {
+	Command string `json:"command"`
+	Status  string `json:"status"`
+}
+
 // Bootstrap configures MCP servers for installed agent CLIs.
-func Bootstrap(logger *log.Logger, opts Options, runner Runner) error {
+func Bootstrap(logger *log.Logger, opts Options, runner Runner) (Result, error) {
 	if runner == nil {
 		runner = OSRunner{}
 	}
@@ -68,45 +91,50 @@ func Bootstrap(logger *log.Logger, opts Options, runner Runner) error {
 
 	cmds, err := BuildCommands(opts)
 	if err != nil {
-		return err
+		return Result{}, err
 	}
 	if len(cmds) == 0 {
-		return errors.New("no bootstrap commands generated")
+		return Result{}, errors.New("no bootstrap commands generated")
 	}
 
 	auditPath, err := auditLogPath()
 	if err != nil {
-		return err
+		return Result{}, err
 	}
 	if err := os.MkdirAll(filepath.Dir(auditPath), 0o755); err != nil {
-		return err
+		return Result{}, err
 	}
 	f, err := os.Create(auditPath)
 	if err != nil {
-		return err
+		return Result{}, err
 	}
 	defer f.Close()
 
+	res := Result{AuditLog: auditPath, DryRun: opts.DryRun}
+
 	fmt.Fprintf(f, "# memory-mcp bootstrap %s\n", time.Now().UTC().Format(time.RFC3339))
 	for _, c := range cmds {
 		line := c.Name + " " + strings.Join(c.Args, " ")
 		fmt.Fprintln(f, line)
 		logger.Info("bootstrap command", "cmd", line, "dry_run", opts.DryRun)
 		if opts.DryRun {
+			res.Commands = append(res.Commands, CommandResult{Command: line, Status: "planned"})
 			continue
 		}
 		if err := runner.Run(c.Name, c.Args...); err != nil {
 			// remove may fail when missing; ignore those to keep idempotency smooth.
 			if strings.Contains(line, " mcp remove ") {
 				logger.Debug("ignoring remove error", "cmd", line, "error", err)
+				res.Commands = append(res.Commands, CommandResult{Command: line, Status: "ignored"})
 				continue
 			}
-			return fmt.Errorf("run %q: %w", line, err)
+			return res, fmt.Errorf("run %q: %w", line, err)
 		}
+		res.Commands = append(res.Commands, CommandResult{Command: line, Status: "ran"})
 	}
 
 	logger.Info("bootstrap complete", "audit_log", auditPath)
-	return nil
+	return res, nil
 }
 
 // BuildCommands builds a deterministic bootstrap command list.
@@ -0,0 +1,147 @@
+// Package cache provides a small in-process LRU cache with optional
+// per-entry TTL and hit/miss counters.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/clock"
+)
+
+// Stats reports cache effectiveness.
+type Stats struct {
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+	Entries int    `json:"entries"`
+}
+
+// LRU is a fixed-capacity least-recently-used cache. Entries older than the
+// TTL are treated as misses and dropped on access. It is safe for
+// concurrent use.
+type LRU[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	clock    clock.Clock
+	order    *list.List
+	items    map[K]*list.Element
+	hits     uint64
+	misses   uint64
+}
+
+type entry[K comparable, V any] struct {
+	key      K
+	value    V
+	storedAt time.Time
+}
+
+// New returns an LRU holding at most capacity entries. ttl <= 0 disables
+// expiry; a nil clock uses the wall clock.
+func New[K comparable, V any](capacity int, ttl time.Duration, clk clock.Clock) *LRU[K, V] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	if clk == nil {
+		clk = clock.System{}
+	}
+	return &LRU[K, V]{
+		capacity: capacity,
+		ttl:      ttl,
+		clock:    clk,
+		order:    list.New(),
+		items:    make(map[K]*list.Element, capacity),
+	}
+}
+
+// Get returns the cached value for key and marks it recently used.
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		c.misses++
+		var zero V
+		return zero, false
+	}
+	e := el.Value.(*entry[K, V])
+	if c.ttl > 0 && c.clock.Now().Sub(e.storedAt) >= c.ttl {
+		c.removeElement(el)
+		c.misses++
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(el)
+	c.hits++
+	return e.value, true
+}
+
+// Put stores value under key, evicting the least recently used entry when
+// the cache is full.
+func (c *LRU[K, V]) Put(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock.Now()
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[K, V])
+		e.value = value
+		e.storedAt = now
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, storedAt: now})
+	for c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// Delete drops key if present.
+func (c *LRU[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+// DeleteFunc drops every entry whose key matches and returns how many were
+// removed.
+func (c *LRU[K, V]) DeleteFunc(match func(K) bool) int {
+	return c.DeleteEntryFunc(func(key K, _ V) bool { return match(key) })
+}
+
+// DeleteEntryFunc drops every entry whose key and value match and returns
+// how many were removed. Expired entries are offered too.
+func (c *LRU[K, V]) DeleteEntryFunc(match func(K, V) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for key, el := range c.items {
+		if match(key, el.Value.(*entry[K, V]).value) {
+			c.removeElement(el)
+			n++
+		}
+	}
+	return n
+}
+
+// Purge drops every entry. Counters are kept.
+func (c *LRU[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	clear(c.items)
+}
+
+// Stats returns the hit/miss counters and current size.
+func (c *LRU[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{Hits: c.hits, Misses: c.misses, Entries: len(c.items)}
+}
+
+func (c *LRU[K, V]) removeElement(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*entry[K, V]).key)
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/clock"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+	c := New[string, int](2, 0, nil)
+	c.Put("a", 1)
+	c.Put("b", 2)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	c.Put("c", 3)
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected b to be evicted as least recently used")
+	}
+	if v, ok := c.Get("c"); !ok || v != 3 {
+		t.Fatalf("Get(c) = %d, %v", v, ok)
+	}
+	st := c.Stats()
+	if st.Hits != 2 || st.Misses != 1 || st.Entries != 2 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestLRU_ExpiresAndDeletesByPredicate(t *testing.T) {
+	t.Parallel()
+	clk := clock.NewManual(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	c := New[string, string](8, time.Minute, clk)
+	c.Put("org/a|x", "1")
+	c.Put("org/a|y", "2")
+	c.Put("org/b|x", "3")
+
+	if n := c.DeleteFunc(func(k string) bool { return strings.HasPrefix(k, "org/a|") }); n != 2 {
+		t.Fatalf("DeleteFunc() = %d, want 2", n)
+	}
+	if _, ok := c.Get("org/b|x"); !ok {
+		t.Fatal("expected unrelated entry to survive")
+	}
+	c.Put("org/c|x", "4")
+	if n := c.DeleteEntryFunc(func(_, v string) bool { return v == "4" }); n != 1 {
+		t.Fatalf("DeleteEntryFunc() = %d, want 1", n)
+	}
+	clk.Advance(time.Minute)
+	if _, ok := c.Get("org/b|x"); ok {
+		t.Fatal("expected entry to expire after TTL")
+	}
+	if c.Stats().Entries != 0 {
+		t.Fatalf("expected expired entry to be dropped, stats %+v", c.Stats())
+	}
+}
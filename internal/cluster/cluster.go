@@ -0,0 +1,238 @@
+// Package cluster groups short texts into topics with k-means over TF-IDF
+// vectors and labels each group with its heaviest terms.
+package cluster
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxIterations = 20
+	labelTerms    = 3
+)
+
+// Doc is one text to cluster.
+type Doc struct {
+	ID   string
+	Text string
+}
+
+// Group is one topic: its label terms, member documents and the member
+// closest to the topic centre.
+type Group struct {
+	Terms          []string
+	Members        []string
+	Representative string
+}
+
+// Label joins the group's terms for display.
+func (g Group) Label() string {
+	return strings.Join(g.Terms, " / ")
+}
+
+type vector map[string]float64
+
+// Run clusters docs into at most k groups, largest first. k <= 0 picks
+// about sqrt(n/2), capped at 10. Results are deterministic for a given
+// input order.
+func Run(docs []Doc, k int) []Group {
+	if len(docs) == 0 {
+		return nil
+	}
+	if k <= 0 {
+		k = min(max(int(math.Round(math.Sqrt(float64(len(docs))/2))), 1), 10)
+	}
+	k = min(k, len(docs))
+
+	vecs := tfidf(docs)
+	centroids := seed(vecs, k)
+	assign := make([]int, len(vecs))
+	for iter := 0; iter < maxIterations; iter++ {
+		changed := iter == 0
+		for i, v := range vecs {
+			if best := nearest(v, centroids); best != assign[i] {
+				assign[i] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+		centroids = recentre(vecs, assign, len(centroids))
+	}
+
+	groups := make([]Group, len(centroids))
+	bestSim := make([]float64, len(centroids))
+	for i := range bestSim {
+		bestSim[i] = -1
+	}
+	for i, c := range assign {
+		groups[c].Members = append(groups[c].Members, docs[i].ID)
+		if sim := dot(vecs[i], centroids[c]); sim > bestSim[c] {
+			bestSim[c] = sim
+			groups[c].Representative = docs[i].ID
+		}
+	}
+	out := make([]Group, 0, len(groups))
+	for i, g := range groups {
+		if len(g.Members) == 0 {
+			continue
+		}
+		g.Terms = topTerms(centroids[i], labelTerms)
+		out = append(out, g)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return len(out[i].Members) > len(out[j].Members) })
+	return out
+}
+
+// seed picks the first document and then, repeatedly, the document least
+// similar to every centroid chosen so far.
+func seed(vecs []vector, k int) []vector {
+	centroids := []vector{vecs[0]}
+	chosen := map[int]bool{0: true}
+	for len(centroids) < k {
+		far, farSim := -1, math.Inf(1)
+		for i, v := range vecs {
+			if chosen[i] {
+				continue
+			}
+			sim := dot(v, centroids[nearest(v, centroids)])
+			if sim < farSim {
+				far, farSim = i, sim
+			}
+		}
+		if far < 0 {
+			break
+		}
+		chosen[far] = true
+		centroids = append(centroids, vecs[far])
+	}
+	return centroids
+}
+
+func nearest(v vector, centroids []vector) int {
+	best, bestSim := 0, math.Inf(-1)
+	for i, c := range centroids {
+		if sim := dot(v, c); sim > bestSim {
+			best, bestSim = i, sim
+		}
+	}
+	return best
+}
+
+func recentre(vecs []vector, assign []int, k int) []vector {
+	out := make([]vector, k)
+	for i := range out {
+		out[i] = vector{}
+	}
+	for i, v := range vecs {
+		for term, w := range v {
+			out[assign[i]][term] += w
+		}
+	}
+	for _, c := range out {
+		normalizeVec(c)
+	}
+	return out
+}
+
+func tfidf(docs []Doc) []vector {
+	df := map[string]int{}
+	tfs := make([]map[string]int, len(docs))
+	for i, d := range docs {
+		tf := map[string]int{}
+		for _, t := range Tokens(d.Text) {
+			tf[t]++
+		}
+		for t := range tf {
+			df[t]++
+		}
+		tfs[i] = tf
+	}
+	n := float64(len(docs))
+	vecs := make([]vector, len(docs))
+	for i, tf := range tfs {
+		v := vector{}
+		for t, c := range tf {
+			v[t] = (1 + math.Log(float64(c))) * math.Log(1+n/float64(df[t]))
+		}
+		normalizeVec(v)
+		vecs[i] = v
+	}
+	return vecs
+}
+
+func normalizeVec(v vector) {
+	var sum float64
+	for _, w := range v {
+		sum += w * w
+	}
+	if sum == 0 {
+		return
+	}
+	norm := math.Sqrt(sum)
+	for t := range v {
+		v[t] /= norm
+	}
+}
+
+func dot(a, b vector) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	var s float64
+	for t, w := range a {
+		s += w * b[t]
+	}
+	return s
+}
+
+func topTerms(v vector, n int) []string {
+	terms := make([]string, 0, len(v))
+	for t := range v {
+		terms = append(terms, t)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if v[terms[i]] != v[terms[j]] {
+			return v[terms[i]] > v[terms[j]]
+		}
+		return terms[i] < terms[j]
+	})
+	if len(terms) > n {
+		terms = terms[:n]
+	}
+	return terms
+}
+
+// Tokens lower-cases text and returns its words of three or more letters
+// or digits, minus common English stop words.
+func Tokens(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := fields[:0]
+	for _, f := range fields {
+		if len([]rune(f)) < 3 {
+			continue
+		}
+		if _, stop := stopWords[f]; stop {
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+var stopWords = map[string]struct{}{
+	"the": {}, "and": {}, "for": {}, "with": {}, "that": {}, "this": {}, "from": {},
+	"are": {}, "was": {}, "were": {}, "but": {}, "not": {}, "have": {}, "has": {},
+	"had": {}, "its": {}, "into": {}, "when": {}, "then": {}, "than": {}, "they": {},
+	"them": {}, "their": {}, "there": {}, "what": {}, "which": {}, "will": {},
+	"would": {}, "should": {}, "could": {}, "can": {}, "all": {}, "any": {},
+	"our": {}, "you": {}, "your": {}, "use": {}, "uses": {}, "used": {}, "also": {},
+	"after": {}, "before": {}, "about": {}, "been": {}, "being": {}, "more": {},
+	"only": {}, "over": {}, "some": {}, "such": {}, "each": {}, "other": {},
+}
@@ -0,0 +1,45 @@
+package cluster
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRun_GroupsByTopic(t *testing.T) {
+	t.Parallel()
+	docs := []Doc{
+		{ID: "d1", Text: "staging deploy failed on migration step"},
+		{ID: "a1", Text: "auth token refresh returns 401"},
+		{ID: "d2", Text: "deploy to staging needs migration lock"},
+		{ID: "a2", Text: "auth token expiry is 15 minutes"},
+		{ID: "d3", Text: "rollback staging deploy after migration"},
+		{ID: "a3", Text: "refresh auth token before expiry"},
+	}
+	groups := Run(docs, 2)
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %+v", groups)
+	}
+	got := map[string][]string{}
+	for _, g := range groups {
+		got[g.Members[0]] = g.Members
+	}
+	want := map[string][]string{"d1": {"d1", "d2", "d3"}, "a1": {"a1", "a2", "a3"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Run() members = %v, want %v", got, want)
+	}
+	for _, g := range groups {
+		if len(g.Terms) != labelTerms || g.Representative == "" {
+			t.Fatalf("unlabelled group %+v", g)
+		}
+	}
+}
+
+func TestRun_DefaultsKAndHandlesEmptyInput(t *testing.T) {
+	t.Parallel()
+	if got := Run(nil, 0); got != nil {
+		t.Fatalf("Run(nil) = %v", got)
+	}
+	if got := Run([]Doc{{ID: "x", Text: "lonely memory"}}, 0); len(got) != 1 || got[0].Representative != "x" {
+		t.Fatalf("Run(single) = %+v", got)
+	}
+}
@@ -1,16 +1,30 @@
 package config
 
 import (
+	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+	"text/template"
 
 	"gopkg.in/yaml.v3"
 )
 
+// DefaultInstructions is the built-in initialize instructions template.
+const DefaultInstructions = `Shared memory for agents working on the same code. Memories live in namespaces like <org>/<repo>/<branch>/<workstream> (pattern {{.NamespacePattern}}).
+- At the start of a task, call {{tool "memory_get_context_pack"}} with a short task description, then {{tool "memory_search"}} for specific modules, bugs or past decisions. Reuse what you find instead of re-deriving it.
+- Call {{tool "memory_write"}} when you learn something another agent would otherwise rediscover: a decision and its reason, a convention, a gotcha, an approach that failed, or where the task stands. Keep one fact per memory with a summary; use scope short for in-progress notes, long for durable knowledge, project for what every branch of the repository needs and global for what holds across repositories.
+- Use {{tool "memory_append"}} to extend a running log instead of writing near-duplicates, and {{tool "memory_promote"}} when a short-term note proves durable.
+- Use {{tool "memory_ask"}} for direct questions, and {{tool "memory_feedback"}} to mark results that helped or misled.
+- Never store secrets.`
+
+var metadataColumnPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // Config contains runtime configuration for memory-mcp.
 type Config struct {
 	ServerName              string `yaml:"server_name"`
@@ -21,6 +35,525 @@ type Config struct {
 	TTLCheckIntervalSeconds int    `yaml:"ttl_check_interval_seconds"`
 	MaxContextPackItems     int    `yaml:"max_context_pack_items"`
 	DefaultSearchK          int    `yaml:"default_search_k"`
+	// SlidingTTLHours maps a namespace or namespace ancestor to a window:
+	// a short-term memory returned by a search there expires no sooner
+	// than this many hours later. The most specific namespace wins.
+	SlidingTTLHours map[string]int `yaml:"sliding_ttl_hours"`
+	// LongTermRetention maps a namespace or namespace ancestor to limits
+	// after which the TTL worker deletes its long-term memories. The most
+	// specific namespace wins; long-term memories elsewhere never expire.
+	LongTermRetention map[string]RetentionConfig `yaml:"long_term_retention"`
+	// StopWordList is the built-in list of words dropped from search
+	// queries: "english" (default) or "none".
+	StopWordList string `yaml:"stop_word_list"`
+	// StopWords are dropped from search queries on top of StopWordList.
+	StopWords []string `yaml:"stop_words"`
+	// RecordSearchMisses keeps searches that return nothing, per namespace,
+	// for `admin misses`. Only the SQLite store keeps them.
+	RecordSearchMisses bool `yaml:"record_search_misses"`
+	// AuditLog records every write, update, promotion, deletion and expiry
+	// for `admin audit`. Only the SQLite store keeps it.
+	AuditLog bool `yaml:"audit_log"`
+	// SearchSuggestions caps the corrected queries memory_search offers
+	// when nothing matched; 0 disables them. Needs SQLite with FTS5.
+	SearchSuggestions int `yaml:"search_suggestions"`
+	// ImportanceScoring picks the importance of writes that give none:
+	// "off" (default) keeps 3, "heuristic" scores keywords and length, and
+	// "llm" asks llm.endpoint and falls back to the heuristic.
+	ImportanceScoring string `yaml:"importance_scoring"`
+	// IDFormat selects "uuid" (default), time-ordered "ulid", or "content"
+	// IDs hashed from namespace+content for idempotent re-ingestion.
+	// Existing IDs of any format keep working.
+	IDFormat string `yaml:"id_format"`
+	// IDPrefixes maps a namespace or namespace ancestor to a short label
+	// prepended to generated IDs, e.g. "acme/repoA": "repoA".
+	IDPrefixes map[string]string `yaml:"id_prefixes"`
+	// ContentSanitization is "replace" (default) to repair invalid UTF-8 and
+	// strip control characters from written text, or "reject" to refuse it.
+	ContentSanitization string `yaml:"content_sanitization"`
+	// AdminTimezone is "utc" (default) or "local" for admin dashboard times.
+	AdminTimezone string `yaml:"admin_timezone"`
+	// ReadOnly rejects every mutating tool call and disables TTL cleanup.
+	ReadOnly bool `yaml:"read_only"`
+	// ControlDir holds a control socket per serve process for
+	// `admin --attach`; empty disables the sockets.
+	ControlDir string `yaml:"control_dir"`
+	// SnapshotDir holds `admin snapshot` copies of the database; empty
+	// means a snapshots directory next to db_path.
+	SnapshotDir string `yaml:"snapshot_dir"`
+	// KeepaliveIntervalSeconds sends a ping to the client after this many
+	// idle seconds; 0 disables pings.
+	KeepaliveIntervalSeconds int `yaml:"keepalive_interval_seconds"`
+	// TransportMode is "auto" (default) to detect Content-Length framing or
+	// JSON lines per message, or "framed"/"jsonl" to pin one.
+	TransportMode string `yaml:"transport_mode"`
+	// Listen makes serve accept MCP sessions on a socket, "unix:<path>" or
+	// "host:port", instead of stdio; empty serves stdio.
+	Listen string `yaml:"listen"`
+	// Instructions is a text/template returned as the initialize
+	// instructions, telling clients when to search, write and request
+	// context packs. It sees .ServerName and .NamespacePattern, and
+	// {{tool "memory_write"}} gives a tool's exposed name. Empty omits the
+	// field.
+	Instructions string `yaml:"instructions"`
+	// DefaultNamespace is used by tools that need a namespace when a call
+	// omits it. ${GIT_ORG}, ${GIT_REPO} and ${GIT_BRANCH} come from the
+	// working directory's git checkout; other ${VAR}s from the environment.
+	DefaultNamespace string `yaml:"default_namespace"`
+	// GitNamespace derives DefaultNamespace as org/repo/branch from the
+	// working directory's git checkout when DefaultNamespace is empty.
+	GitNamespace bool `yaml:"git_namespace"`
+
+	Store      StoreConfig      `yaml:"store"`
+	Obsidian   ObsidianConfig   `yaml:"obsidian"`
+	Cache      CacheConfig      `yaml:"cache"`
+	Facts      FactsConfig      `yaml:"facts"`
+	LLM        LLMConfig        `yaml:"llm"`
+	Reflection ReflectionConfig `yaml:"reflection"`
+	Ranking    RankingConfig    `yaml:"ranking"`
+	Embeddings EmbeddingsConfig `yaml:"embeddings"`
+	Tools      ToolsConfig      `yaml:"tools"`
+	Auth       AuthConfig       `yaml:"auth"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	// ContextPack shapes the text memory_get_context_pack returns.
+	ContextPack ContextPackConfig `yaml:"context_pack"`
+	// WriteDedupe checks writes against similar memories already stored.
+	WriteDedupe WriteDedupeConfig `yaml:"write_dedupe"`
+	// Scopes configures the project and global scopes.
+	Scopes ScopesConfig `yaml:"scopes"`
+	// AutoPromote promotes heavily used short-term memories before they
+	// expire.
+	AutoPromote AutoPromoteConfig `yaml:"auto_promote"`
+
+	// Path is the absolute path of the file Load read; it is empty when no
+	// file was found and the defaults are in effect.
+	Path string `yaml:"-"`
+}
+
+// StoreConfig selects and configures the persistence backend.
+type StoreConfig struct {
+	// Driver names the backend in the store registry: "sqlite" (default),
+	// "markdown", "postgres" or "memory", which keeps memories in process
+	// only.
+	Driver string `yaml:"driver"`
+	// Dir is the root directory for the markdown driver.
+	Dir string `yaml:"dir"`
+	// GitCommit commits every change when Dir is inside a git work tree.
+	GitCommit bool `yaml:"git_commit"`
+	// DSN is the connection string of the postgres driver, e.g.
+	// postgres://memory@db.internal:5432/memory?sslmode=require.
+	DSN string `yaml:"dsn"`
+	// DSNEnv names an environment variable holding the DSN instead, which
+	// keeps a password out of the config file. It wins over DSN when the
+	// variable is set.
+	DSNEnv string `yaml:"dsn_env"`
+	// ReadConns sizes the SQLite read-only connection pool used for searches
+	// and lookups. Writes always go through a single connection. 0 shares the
+	// write connection for reads.
+	ReadConns int `yaml:"read_conns"`
+	// WriteConns sizes the SQLite write connection pool. SQLite has one
+	// writer, so more than 1 only helps when writes are short and callers
+	// would otherwise queue in Go rather than in SQLite.
+	WriteConns int `yaml:"write_conns"`
+	// BusyTimeoutMS is how long a SQLite connection waits for a lock held
+	// by another connection or process before failing.
+	BusyTimeoutMS int `yaml:"busy_timeout_ms"`
+	// FTSMetadataKeys lists top-level metadata keys whose values are added
+	// to the SQLite full-text index, e.g. ["ticket", "files"].
+	FTSMetadataKeys []string `yaml:"fts_metadata_keys"`
+	// CJKNgram indexes Chinese, Japanese and Korean text as overlapping
+	// n-grams of this many characters: 2 (default) or 3, or 0 to disable.
+	CJKNgram int `yaml:"cjk_ngram"`
+	// MetadataColumns lists top-level metadata keys materialized as indexed
+	// SQLite generated columns for fast equality filtering.
+	MetadataColumns []string `yaml:"metadata_columns"`
+	// Shards maps namespace prefixes to SQLite files of their own, e.g.
+	// {"acme/api": "~/.memory-mcp/acme-api.db"}. Namespaces under no
+	// prefix stay in db_path, and the longest matching prefix wins.
+	Shards map[string]string `yaml:"shards"`
+}
+
+// PostgresDSN returns the postgres connection string: the DSNEnv variable
+// when it is set, DSN otherwise.
+func (c StoreConfig) PostgresDSN() string {
+	if c.DSNEnv != "" {
+		if dsn := os.Getenv(c.DSNEnv); dsn != "" {
+			return dsn
+		}
+	}
+	return c.DSN
+}
+
+// RetentionConfig limits how long long-term memories are kept. A zero
+// field is no limit.
+type RetentionConfig struct {
+	// MaxAgeDays deletes memories this many days after they were written
+	// long-term or promoted.
+	MaxAgeDays int `yaml:"max_age_days"`
+	// StaleDays deletes memories last accessed this many days ago.
+	StaleDays int `yaml:"stale_days"`
+}
+
+// ObsidianConfig controls the one-way mirror of long-term, project and
+// global memories into an Obsidian vault.
+type ObsidianConfig struct {
+	VaultDir string `yaml:"vault_dir"`
+	// Folder is the vault-relative folder owned by the sync.
+	Folder string `yaml:"folder"`
+	// SyncIntervalSeconds enables periodic sync while serving when > 0.
+	SyncIntervalSeconds int `yaml:"sync_interval_seconds"`
+}
+
+// CacheConfig sizes the in-process caches. A zero size disables a cache.
+type CacheConfig struct {
+	// SearchEntries bounds cached search candidate lists keyed by
+	// (namespace, query, scope, k).
+	SearchEntries int `yaml:"search_entries"`
+	// SearchTTLSeconds bounds how long a cached search result is served.
+	SearchTTLSeconds int `yaml:"search_ttl_seconds"`
+	// PackEntries bounds cached context packs keyed by (namespace, query,
+	// budget, scope) and the other pack arguments.
+	PackEntries int `yaml:"pack_entries"`
+	// PackTTLSeconds bounds how long a cached context pack is served and
+	// must be > 0 while PackEntries is.
+	PackTTLSeconds int `yaml:"pack_ttl_seconds"`
+	// Records bounds the SQLite store's LRU of memory records by ID.
+	Records int `yaml:"records"`
+	// RecordsTTLSeconds bounds how long a cached record is served, and so
+	// how long a change made by another process can go unseen. It must be
+	// > 0 while Records is.
+	RecordsTTLSeconds int `yaml:"records_ttl_seconds"`
+}
+
+// FactsConfig controls extraction of subject–predicate–object facts from
+// memory content into the SQLite facts table.
+type FactsConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// LLMConfig points at an OpenAI-compatible chat completions endpoint used
+// to synthesize answers. An empty Endpoint disables LLM features.
+type LLMConfig struct {
+	// Endpoint is the API base URL, e.g. http://localhost:11434/v1.
+	Endpoint string `yaml:"endpoint"`
+	Model    string `yaml:"model"`
+	// APIKeyEnv names the environment variable holding the API key, so the
+	// key itself never lives in the config file.
+	APIKeyEnv      string `yaml:"api_key_env"`
+	TimeoutSeconds int    `yaml:"timeout_seconds"`
+	MaxTokens      int    `yaml:"max_tokens"`
+}
+
+// RankingConfig tunes search ranking.
+type RankingConfig struct {
+	// LexicalWeight, RecencyWeight, ImportanceWeight and FrequencyWeight
+	// scale the base terms of a search score, which each range from 0 to 1.
+	// The lexical weight is shared with semantic similarity when embeddings
+	// are on.
+	LexicalWeight    float64 `yaml:"lexical_weight"`
+	RecencyWeight    float64 `yaml:"recency_weight"`
+	ImportanceWeight float64 `yaml:"importance_weight"`
+	FrequencyWeight  float64 `yaml:"frequency_weight"`
+	// RecencyHalfLifeDays is the age at which a memory's recency term has
+	// fallen to half.
+	RecencyHalfLifeDays float64 `yaml:"recency_half_life_days"`
+	// FeedbackWeight scales the learned term derived from memory_feedback,
+	// which ranges from -1 (always irrelevant) to 1 (always useful).
+	FeedbackWeight float64 `yaml:"feedback_weight"`
+	// AgentWeight scales the per-agent term learned from the searching
+	// agent's feedback on memories with the same tags.
+	AgentWeight float64 `yaml:"agent_weight"`
+	// OwnAgentBoost is added to the score of memories whose source_agent
+	// is the searching agent; 0 ranks every author alike.
+	OwnAgentBoost float64 `yaml:"own_agent_boost"`
+	// Agents maps a source_agent to fixed score adjustments per metadata
+	// tag, e.g. {"codex": {"code": 0.1}, "claude": {"decision": 0.1}}.
+	Agents map[string]map[string]float64 `yaml:"agents"`
+	// NamespaceDecay multiplies the score of a memory found through
+	// include_ancestors or include_descendants once per namespace level
+	// between it and the searched namespace.
+	NamespaceDecay float64 `yaml:"namespace_decay"`
+}
+
+// EmbeddingsConfig lists the embedding providers tried in order, e.g. a
+// local Ollama first and a hosted API second.
+type EmbeddingsConfig struct {
+	Providers []EmbeddingProviderConfig `yaml:"providers"`
+	// FailureThreshold consecutive failures open a provider's circuit so it
+	// is skipped until CooldownSeconds have passed.
+	FailureThreshold int `yaml:"failure_threshold"`
+	CooldownSeconds  int `yaml:"cooldown_seconds"`
+	// SemanticWeight is the share of a search's relevance term taken by
+	// cosine similarity to the query; the rest stays lexical.
+	SemanticWeight float64 `yaml:"semantic_weight"`
+	// MinSimilarity is the cosine similarity a memory needs to be found
+	// by meaning alone, without matching the query's words.
+	MinSimilarity float64 `yaml:"min_similarity"`
+}
+
+// WriteDedupeConfig decides what a write does when its namespace already
+// holds a memory saying nearly the same thing.
+type WriteDedupeConfig struct {
+	// Policy is "off" (default), "reject" (fail the write), "update"
+	// (replace the stored memory's content) or "merge" (fold the write
+	// into the stored memory).
+	Policy string `yaml:"policy"`
+	// Threshold is the word-set similarity from which a write duplicates
+	// a stored memory.
+	Threshold float64 `yaml:"threshold"`
+	// SemanticThreshold, when > 0 and embeddings are configured, also
+	// counts memories at least this cosine-similar as duplicates.
+	SemanticThreshold float64 `yaml:"semantic_threshold"`
+}
+
+// ToolsConfig limits which MCP tools the server exposes.
+type ToolsConfig struct {
+	// Allow lists the only tools exposed; empty exposes every tool.
+	Allow []string `yaml:"allow"`
+	// Deny hides tools even when Allow lists them.
+	Deny []string `yaml:"deny"`
+	// Prefix is prepended to every exposed tool name, e.g. "shm_" turns
+	// memory_write into shm_memory_write.
+	Prefix string `yaml:"prefix"`
+	// Aliases maps a tool name to the exact name it is exposed as instead
+	// of the prefixed one.
+	Aliases map[string]string `yaml:"aliases"`
+}
+
+// AuthConfig restricts MCP callers to the namespaces granted to their API
+// key or client identity.
+type AuthConfig struct {
+	// Enabled rejects tool calls from callers that match no principal.
+	Enabled bool `yaml:"enabled"`
+	// KeyEnv names the environment variable a stdio server reads its
+	// client's API key from. Socket sessions send theirs in initialize.
+	KeyEnv     string            `yaml:"key_env"`
+	Principals []PrincipalConfig `yaml:"principals"`
+}
+
+// PrincipalConfig grants an API key or MCP client identity access to
+// namespace prefixes.
+type PrincipalConfig struct {
+	Name string `yaml:"name"`
+	// KeySHA256 is the hex SHA-256 digest of the principal's API key, so
+	// the key itself never lives in the config file.
+	KeySHA256 string `yaml:"key_sha256"`
+	// KeyEnv names an environment variable holding the key instead.
+	KeyEnv string `yaml:"key_env"`
+	// Clients lists MCP client names (clientInfo.name in initialize)
+	// matched when a session sends no key. Clients name themselves, so
+	// only use this for trusted local clients.
+	Clients []string `yaml:"clients"`
+	// Namespaces lists the namespace prefixes the principal may use; "*"
+	// grants every namespace.
+	Namespaces []string `yaml:"namespaces"`
+	// ReadOnly limits the principal to searches and lookups.
+	ReadOnly bool `yaml:"read_only"`
+}
+
+// TracingConfig exports OpenTelemetry spans of MCP requests, service
+// operations and SQL statements over OTLP/HTTP.
+type TracingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Endpoint is the collector's OTLP/HTTP base URL, e.g.
+	// http://localhost:4318. Empty falls back to the standard
+	// OTEL_EXPORTER_OTLP_* environment variables.
+	Endpoint string `yaml:"endpoint"`
+	// Headers are sent with every export, e.g. a hosted backend's API key.
+	Headers map[string]string `yaml:"headers"`
+	// SampleRatio is the share of new traces recorded, from 0 to 1. Calls
+	// continuing a sampled trace are always recorded.
+	SampleRatio float64 `yaml:"sample_ratio"`
+	// ServiceName is the service.name resource attribute of every span.
+	ServiceName string `yaml:"service_name"`
+}
+
+// ContextPackFormats are the built-in context pack formats.
+var ContextPackFormats = []string{"markdown", "xml", "json", "plain"}
+
+// ContextPackConfig picks how context packs are rendered.
+type ContextPackConfig struct {
+	// Format is used when a call names none: a built-in format or a key of
+	// Templates. Default "markdown".
+	Format string `yaml:"format"`
+	// Templates are named text/templates callers select as formats; one
+	// named like a built-in format replaces it. They see .Namespace, .Query
+	// and .Memories, each with .ID, .Namespace, .Scope, .Text, .Importance,
+	// .SourceAgent and .CreatedAt; sectioned packs also fill .Sections,
+	// each with .Name, .Title and .Memories. They may call json and xml to
+	// escape values.
+	Templates map[string]string `yaml:"templates"`
+	// Sections groups packs into durable knowledge, recent working notes
+	// and possibly stale memories, each with its own share of the budget.
+	Sections ContextPackSectionsConfig `yaml:"sections"`
+}
+
+// ContextPackSections are the sections of a sectioned context pack, in
+// the order they are rendered.
+var ContextPackSections = []string{"durable", "recent", "stale"}
+
+// ContextPackSectionsConfig configures sectioned context packs.
+type ContextPackSectionsConfig struct {
+	// Enabled sections every pack; a call can also ask with sections:
+	// true.
+	Enabled bool `yaml:"enabled"`
+	// StaleAfterDays is the age after which a long-term memory is
+	// possibly stale. Short-term memories are always recent.
+	StaleAfterDays int `yaml:"stale_after_days"`
+	// Budget maps each section to its share of the token budget; shares
+	// are divided by their sum.
+	Budget map[string]float64 `yaml:"budget"`
+}
+
+// Scopes are the memory scopes, narrowest first. Short-term memories
+// expire; long-term ones stay in their namespace; project ones are also
+// found from every namespace of their project, and global ones from every
+// namespace.
+var Scopes = []string{"short", "long", "project", "global"}
+
+// ScopesConfig configures the scopes above long-term.
+type ScopesConfig struct {
+	// ProjectDepth is how many leading namespace segments name a project:
+	// with 2 (default), acme/api/main/task belongs to project acme/api.
+	ProjectDepth int         `yaml:"project_depth"`
+	Project      ScopeConfig `yaml:"project"`
+	Global       ScopeConfig `yaml:"global"`
+}
+
+// ScopeConfig sets how long memories of a scope live and which scopes
+// they may be promoted from.
+type ScopeConfig struct {
+	// TTLHours expires memories this many hours after they are written or
+	// promoted; 0 keeps them.
+	TTLHours int `yaml:"ttl_hours"`
+	// PromoteFrom lists the narrower scopes memory_promote may move
+	// memories into this one from.
+	PromoteFrom []string `yaml:"promote_from"`
+}
+
+func (c ScopesConfig) validate() error {
+	if c.ProjectDepth < 1 {
+		return errors.New("scopes.project_depth must be >= 1")
+	}
+	for name, sc := range map[string]ScopeConfig{"project": c.Project, "global": c.Global} {
+		if sc.TTLHours < 0 {
+			return fmt.Errorf("scopes.%s.ttl_hours must be >= 0", name)
+		}
+		narrower := Scopes[:slices.Index(Scopes, name)]
+		for _, from := range sc.PromoteFrom {
+			if !slices.Contains(narrower, from) {
+				return fmt.Errorf("invalid scopes.%s.promote_from entry %q (expected %s)", name, from, strings.Join(narrower, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// ContextPackFuncs are the functions context pack templates may call.
+var ContextPackFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"xml": func(s string) (string, error) {
+		var b strings.Builder
+		err := xml.EscapeText(&b, []byte(s))
+		return b.String(), err
+	},
+}
+
+// ParseContextPackTemplate parses the context pack template called name.
+func ParseContextPackTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(ContextPackFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid context_pack.templates.%s: %w", name, err)
+	}
+	return t, nil
+}
+
+func (c ContextPackConfig) validate() error {
+	for name, text := range c.Templates {
+		if strings.TrimSpace(name) == "" {
+			return errors.New("context_pack.templates names must not be empty")
+		}
+		if _, err := ParseContextPackTemplate(name, text); err != nil {
+			return err
+		}
+	}
+	if c.Sections.StaleAfterDays <= 0 {
+		return errors.New("context_pack.sections.stale_after_days must be > 0")
+	}
+	var total float64
+	for name, share := range c.Sections.Budget {
+		if !slices.Contains(ContextPackSections, name) {
+			return fmt.Errorf("invalid context_pack.sections.budget key %q (expected %s)", name, strings.Join(ContextPackSections, ", "))
+		}
+		if share < 0 {
+			return fmt.Errorf("context_pack.sections.budget.%s must be >= 0", name)
+		}
+		total += share
+	}
+	if total <= 0 {
+		return errors.New("context_pack.sections.budget must give some section a share")
+	}
+	if _, ok := c.Templates[c.Format]; !ok && !slices.Contains(ContextPackFormats, c.Format) {
+		return fmt.Errorf("invalid context_pack.format %q (expected %s or a context_pack.templates name)", c.Format, strings.Join(ContextPackFormats, ", "))
+	}
+	return nil
+}
+
+// ToolName returns the name tool is exposed under.
+func (c ToolsConfig) ToolName(tool string) string {
+	if alias, ok := c.Aliases[tool]; ok {
+		return alias
+	}
+	return c.Prefix + tool
+}
+
+// EmbeddingProviderConfig configures one embedding endpoint.
+type EmbeddingProviderConfig struct {
+	// Kind is "ollama" (POST <endpoint>/api/embed), "openai"
+	// (POST <endpoint>/embeddings) or "hash", a local feature-hashing
+	// embedder that needs no endpoint.
+	Kind     string `yaml:"kind"`
+	Endpoint string `yaml:"endpoint"`
+	Model    string `yaml:"model"`
+	// APIKeyEnv names the environment variable holding the API key.
+	APIKeyEnv string `yaml:"api_key_env"`
+	// TimeoutSeconds bounds each call; 0 means 10.
+	TimeoutSeconds int `yaml:"timeout_seconds"`
+	// Dimensions sizes hash vectors; 0 means 256.
+	Dimensions int `yaml:"dimensions"`
+}
+
+// ReflectionConfig controls the periodic job that asks the LLM for
+// higher-level insights about a namespace's recent memories.
+type ReflectionConfig struct {
+	// Enabled starts the job while serving; it also needs llm.endpoint.
+	Enabled       bool `yaml:"enabled"`
+	IntervalHours int  `yaml:"interval_hours"`
+	// Namespaces limits reflection to these namespaces; empty reflects on
+	// every namespace with memories inside the window.
+	Namespaces  []string `yaml:"namespaces"`
+	WindowHours int      `yaml:"window_hours"`
+	// MinMemories skips namespaces with fewer new memories than this.
+	MinMemories int `yaml:"min_memories"`
+	MaxInsights int `yaml:"max_insights"`
+}
+
+// AutoPromoteConfig controls the periodic job that promotes short-term
+// memories to long-term once they have proven useful. A memory qualifies
+// when it crosses every threshold.
+type AutoPromoteConfig struct {
+	// Enabled starts the job while serving.
+	Enabled         bool `yaml:"enabled"`
+	IntervalMinutes int  `yaml:"interval_minutes"`
+	// MinAccessCount is how many times searches or context packs must have
+	// returned the memory.
+	MinAccessCount int `yaml:"min_access_count"`
+	MinImportance  int `yaml:"min_importance"`
+	// MinAgeHours skips memories written more recently than this.
+	MinAgeHours int `yaml:"min_age_hours"`
 }
 
 // Default returns a Config populated with safe defaults.
@@ -34,6 +567,92 @@ func Default() Config {
 		TTLCheckIntervalSeconds: 60,
 		MaxContextPackItems:     8,
 		DefaultSearchK:          10,
+		StopWordList:            "english",
+		RecordSearchMisses:      true,
+		AuditLog:                true,
+		SearchSuggestions:       3,
+		ImportanceScoring:       "off",
+		IDFormat:                "uuid",
+		ContentSanitization:     "replace",
+		AdminTimezone:           "utc",
+		ControlDir:              filepath.Join(userHomeDir(), ".memory-mcp", "control"),
+		TransportMode:           "auto",
+		Instructions:            DefaultInstructions,
+		Store: StoreConfig{
+			Driver:        "sqlite",
+			Dir:           filepath.Join(userHomeDir(), ".memory-mcp", "memories"),
+			ReadConns:     4,
+			WriteConns:    1,
+			BusyTimeoutMS: 5000,
+			CJKNgram:      2,
+		},
+		Obsidian: ObsidianConfig{
+			Folder: "memory-mcp",
+		},
+		Cache: CacheConfig{
+			SearchEntries:     256,
+			SearchTTLSeconds:  30,
+			PackEntries:       128,
+			PackTTLSeconds:    30,
+			Records:           1024,
+			RecordsTTLSeconds: 5,
+		},
+		LLM: LLMConfig{
+			APIKeyEnv:      "MEMORY_MCP_LLM_API_KEY",
+			TimeoutSeconds: 30,
+			MaxTokens:      512,
+		},
+		Reflection: ReflectionConfig{
+			IntervalHours: 24,
+			WindowHours:   168,
+			MinMemories:   5,
+			MaxInsights:   3,
+		},
+		AutoPromote: AutoPromoteConfig{
+			IntervalMinutes: 60,
+			MinAccessCount:  5,
+			MinImportance:   3,
+			MinAgeHours:     24,
+		},
+		Auth: AuthConfig{
+			KeyEnv: "MEMORY_MCP_API_KEY",
+		},
+		Tracing: TracingConfig{
+			SampleRatio: 1,
+			ServiceName: "memory-mcp",
+		},
+		ContextPack: ContextPackConfig{
+			Format: "markdown",
+			Sections: ContextPackSectionsConfig{
+				StaleAfterDays: 180,
+				Budget:         map[string]float64{"durable": 0.5, "recent": 0.3, "stale": 0.2},
+			},
+		},
+		WriteDedupe: WriteDedupeConfig{
+			Policy:    "off",
+			Threshold: 0.8,
+		},
+		Scopes: ScopesConfig{
+			ProjectDepth: 2,
+			Project:      ScopeConfig{PromoteFrom: []string{"short", "long"}},
+			Global:       ScopeConfig{PromoteFrom: []string{"short", "long", "project"}},
+		},
+		Embeddings: EmbeddingsConfig{
+			FailureThreshold: 3,
+			CooldownSeconds:  60,
+			SemanticWeight:   0.4,
+			MinSimilarity:    0.35,
+		},
+		Ranking: RankingConfig{
+			LexicalWeight:       0.60,
+			RecencyWeight:       0.25,
+			ImportanceWeight:    0.15,
+			FrequencyWeight:     0.1,
+			RecencyHalfLifeDays: 14,
+			FeedbackWeight:      0.1,
+			AgentWeight:         0.1,
+			NamespaceDecay:      0.7,
+		},
 	}
 }
 
@@ -55,6 +674,11 @@ func Load(path string) (Config, error) {
 	if err := yaml.Unmarshal(b, &cfg); err != nil {
 		return cfg, fmt.Errorf("parse config yaml: %w", err)
 	}
+	if abs, err := filepath.Abs(path); err == nil {
+		cfg.Path = abs
+	} else {
+		cfg.Path = path
+	}
 
 	if err := cfg.Validate(); err != nil {
 		return cfg, err
@@ -74,6 +698,45 @@ func (c *Config) Validate() error {
 	if c.DefaultShortTTLHours <= 0 {
 		return errors.New("default_short_ttl_hours must be > 0")
 	}
+	if _, err := template.New("instructions").Funcs(template.FuncMap{"tool": c.Tools.ToolName}).Parse(c.Instructions); err != nil {
+		return fmt.Errorf("invalid instructions template: %w", err)
+	}
+	switch c.ContentSanitization {
+	case "replace", "reject":
+	default:
+		return fmt.Errorf("invalid content_sanitization %q (expected replace or reject)", c.ContentSanitization)
+	}
+	if c.SearchSuggestions < 0 || c.SearchSuggestions > 10 {
+		return fmt.Errorf("invalid search_suggestions %d (expected 0 to 10)", c.SearchSuggestions)
+	}
+	switch c.ImportanceScoring {
+	case "off", "heuristic", "llm":
+	default:
+		return fmt.Errorf("invalid importance_scoring %q (expected off, heuristic or llm)", c.ImportanceScoring)
+	}
+	switch c.StopWordList {
+	case "english", "none":
+	default:
+		return fmt.Errorf("invalid stop_word_list %q (expected english or none)", c.StopWordList)
+	}
+	switch c.TransportMode {
+	case "auto", "framed", "jsonl":
+	default:
+		return fmt.Errorf("invalid transport_mode %q (expected auto, framed or jsonl)", c.TransportMode)
+	}
+	if c.KeepaliveIntervalSeconds < 0 {
+		return errors.New("keepalive_interval_seconds must be >= 0")
+	}
+	for ns, h := range c.SlidingTTLHours {
+		if strings.Trim(strings.TrimSpace(ns), "/") == "" || h <= 0 {
+			return fmt.Errorf("invalid sliding_ttl_hours entry %q: %d (expected a namespace and hours > 0)", ns, h)
+		}
+	}
+	for ns, r := range c.LongTermRetention {
+		if strings.Trim(strings.TrimSpace(ns), "/") == "" || r.MaxAgeDays < 0 || r.StaleDays < 0 || r.MaxAgeDays+r.StaleDays == 0 {
+			return fmt.Errorf("invalid long_term_retention entry %q (expected a namespace and max_age_days or stale_days > 0)", ns)
+		}
+	}
 	if c.TTLCheckIntervalSeconds <= 0 {
 		return errors.New("ttl_check_interval_seconds must be > 0")
 	}
@@ -86,12 +749,299 @@ func (c *Config) Validate() error {
 	if _, err := regexp.Compile(c.NamespacePattern); err != nil {
 		return fmt.Errorf("invalid namespace_pattern: %w", err)
 	}
+	switch c.IDFormat {
+	case "uuid", "ulid", "content":
+	default:
+		return fmt.Errorf("invalid id_format %q (expected uuid, ulid or content)", c.IDFormat)
+	}
+	switch c.AdminTimezone {
+	case "", "utc", "local":
+	default:
+		return fmt.Errorf("invalid admin_timezone %q (expected utc or local)", c.AdminTimezone)
+	}
+	switch c.Store.Driver {
+	case "sqlite", "memory":
+	case "markdown":
+		if c.Store.Dir == "" {
+			return errors.New("store.dir must not be empty for the markdown driver")
+		}
+	case "postgres":
+		if c.Store.DSN == "" && c.Store.DSNEnv == "" {
+			return errors.New("store.dsn or store.dsn_env is required for the postgres driver")
+		}
+	default:
+		return fmt.Errorf("invalid store.driver %q (expected sqlite, markdown, postgres or memory)", c.Store.Driver)
+	}
+	if c.Store.ReadConns < 0 {
+		return errors.New("store.read_conns must be >= 0")
+	}
+	if c.Store.WriteConns < 1 {
+		return errors.New("store.write_conns must be >= 1")
+	}
+	if c.Store.BusyTimeoutMS < 0 {
+		return errors.New("store.busy_timeout_ms must be >= 0")
+	}
+	for _, key := range c.Store.FTSMetadataKeys {
+		if strings.TrimSpace(key) == "" {
+			return errors.New("store.fts_metadata_keys must not contain empty keys")
+		}
+	}
+	switch c.Store.CJKNgram {
+	case 0, 2, 3:
+	default:
+		return fmt.Errorf("invalid store.cjk_ngram %d (expected 0, 2 or 3)", c.Store.CJKNgram)
+	}
+	for _, key := range c.Store.MetadataColumns {
+		if !metadataColumnPattern.MatchString(key) {
+			return fmt.Errorf("store.metadata_columns: %q must be a letter or underscore followed by letters, digits or underscores", key)
+		}
+	}
+	if len(c.Store.Shards) > 0 && c.Store.Driver != "sqlite" {
+		return fmt.Errorf("store.shards requires the sqlite driver (got %q)", c.Store.Driver)
+	}
+	shardPaths := map[string]string{ExpandPath(c.DBPath): "db_path"}
+	shardPrefixes := map[string]bool{}
+	for prefix, path := range c.Store.Shards {
+		trimmed := strings.Trim(prefix, "/")
+		if trimmed == "" {
+			return errors.New("store.shards must not contain an empty namespace prefix")
+		}
+		if shardPrefixes[trimmed] {
+			return fmt.Errorf("store.shards: %q is listed twice", trimmed)
+		}
+		shardPrefixes[trimmed] = true
+		if path == "" {
+			return fmt.Errorf("store.shards: %q has no database path", prefix)
+		}
+		if other, ok := shardPaths[ExpandPath(path)]; ok {
+			return fmt.Errorf("store.shards: %q uses the same database as %s", prefix, other)
+		}
+		shardPaths[ExpandPath(path)] = fmt.Sprintf("%q", prefix)
+	}
+	if c.Cache.SearchEntries < 0 {
+		return errors.New("cache.search_entries must be >= 0")
+	}
+	if c.Cache.SearchTTLSeconds < 0 {
+		return errors.New("cache.search_ttl_seconds must be >= 0")
+	}
+	if c.Cache.PackEntries < 0 {
+		return errors.New("cache.pack_entries must be >= 0")
+	}
+	// Packs are only dropped by writes to namespaces they searched, so the
+	// TTL is what bounds how stale a cached pack can get.
+	if c.Cache.PackEntries > 0 && c.Cache.PackTTLSeconds <= 0 {
+		return errors.New("cache.pack_ttl_seconds must be > 0 when cache.pack_entries is set")
+	}
+	if c.Cache.Records < 0 {
+		return errors.New("cache.records must be >= 0")
+	}
+	if c.Cache.Records > 0 && c.Cache.RecordsTTLSeconds <= 0 {
+		return errors.New("cache.records_ttl_seconds must be > 0 when cache.records is set")
+	}
+	if c.LLM.Endpoint != "" && c.LLM.TimeoutSeconds <= 0 {
+		return errors.New("llm.timeout_seconds must be > 0")
+	}
+	if c.LLM.MaxTokens < 0 {
+		return errors.New("llm.max_tokens must be >= 0")
+	}
+	if c.Reflection.Enabled {
+		if c.Reflection.IntervalHours <= 0 {
+			return errors.New("reflection.interval_hours must be > 0")
+		}
+		if c.Reflection.WindowHours <= 0 {
+			return errors.New("reflection.window_hours must be > 0")
+		}
+		if c.Reflection.MaxInsights <= 0 {
+			return errors.New("reflection.max_insights must be > 0")
+		}
+	}
+	if c.AutoPromote.Enabled {
+		if c.AutoPromote.IntervalMinutes <= 0 {
+			return errors.New("auto_promote.interval_minutes must be > 0")
+		}
+		if c.AutoPromote.MinAccessCount < 0 || c.AutoPromote.MinAgeHours < 0 {
+			return errors.New("auto_promote.min_access_count and auto_promote.min_age_hours must be >= 0")
+		}
+		if c.AutoPromote.MinImportance < 0 || c.AutoPromote.MinImportance > 5 {
+			return errors.New("auto_promote.min_importance must be between 0 and 5")
+		}
+	}
+	if c.Ranking.LexicalWeight < 0 {
+		return errors.New("ranking.lexical_weight must be >= 0")
+	}
+	if c.Ranking.RecencyWeight < 0 {
+		return errors.New("ranking.recency_weight must be >= 0")
+	}
+	if c.Ranking.ImportanceWeight < 0 {
+		return errors.New("ranking.importance_weight must be >= 0")
+	}
+	if c.Ranking.FrequencyWeight < 0 {
+		return errors.New("ranking.frequency_weight must be >= 0")
+	}
+	if c.Ranking.LexicalWeight+c.Ranking.RecencyWeight+c.Ranking.ImportanceWeight+c.Ranking.FrequencyWeight == 0 {
+		return errors.New("ranking: at least one of lexical_weight, recency_weight, importance_weight and frequency_weight must be > 0")
+	}
+	if c.Ranking.RecencyHalfLifeDays <= 0 {
+		return errors.New("ranking.recency_half_life_days must be > 0")
+	}
+	if c.Ranking.FeedbackWeight < 0 {
+		return errors.New("ranking.feedback_weight must be >= 0")
+	}
+	if c.Ranking.AgentWeight < 0 {
+		return errors.New("ranking.agent_weight must be >= 0")
+	}
+	if c.Ranking.OwnAgentBoost < 0 {
+		return errors.New("ranking.own_agent_boost must be >= 0")
+	}
+	if c.Ranking.NamespaceDecay <= 0 || c.Ranking.NamespaceDecay > 1 {
+		return errors.New("ranking.namespace_decay must be > 0 and <= 1")
+	}
+	switch c.WriteDedupe.Policy {
+	case "off", "reject", "update", "merge":
+	default:
+		return fmt.Errorf("write_dedupe.policy must be off, reject, update or merge (got %q)", c.WriteDedupe.Policy)
+	}
+	if c.WriteDedupe.Threshold <= 0 || c.WriteDedupe.Threshold > 1 {
+		return errors.New("write_dedupe.threshold must be > 0 and <= 1")
+	}
+	if c.WriteDedupe.SemanticThreshold < 0 || c.WriteDedupe.SemanticThreshold > 1 {
+		return errors.New("write_dedupe.semantic_threshold must be between 0 and 1")
+	}
+	for i, p := range c.Embeddings.Providers {
+		if p.Kind != "ollama" && p.Kind != "openai" && p.Kind != "hash" {
+			return fmt.Errorf("embeddings.providers[%d].kind must be ollama, openai or hash", i)
+		}
+		if strings.TrimSpace(p.Model) == "" || (p.Kind != "hash" && strings.TrimSpace(p.Endpoint) == "") {
+			return fmt.Errorf("embeddings.providers[%d] needs endpoint and model", i)
+		}
+		if p.Dimensions < 0 {
+			return fmt.Errorf("embeddings.providers[%d].dimensions must be >= 0", i)
+		}
+		if p.TimeoutSeconds < 0 {
+			return fmt.Errorf("embeddings.providers[%d].timeout_seconds must be >= 0", i)
+		}
+	}
+	if c.Embeddings.FailureThreshold <= 0 {
+		return errors.New("embeddings.failure_threshold must be > 0")
+	}
+	if c.Embeddings.CooldownSeconds < 0 {
+		return errors.New("embeddings.cooldown_seconds must be >= 0")
+	}
+	if c.Embeddings.SemanticWeight < 0 || c.Embeddings.SemanticWeight > 1 {
+		return errors.New("embeddings.semantic_weight must be between 0 and 1")
+	}
+	if c.Embeddings.MinSimilarity < 0 || c.Embeddings.MinSimilarity > 1 {
+		return errors.New("embeddings.min_similarity must be between 0 and 1")
+	}
+	if c.Obsidian.SyncIntervalSeconds < 0 {
+		return errors.New("obsidian.sync_interval_seconds must be >= 0")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return errors.New("tracing.sample_ratio must be between 0 and 1")
+	}
+	if c.Tracing.Enabled && strings.TrimSpace(c.Tracing.ServiceName) == "" {
+		return errors.New("tracing.service_name must not be empty")
+	}
+	if err := c.ContextPack.validate(); err != nil {
+		return err
+	}
+	if err := c.Scopes.validate(); err != nil {
+		return err
+	}
+	return c.Auth.validate()
+}
+
+var sha256HexPattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+func (c AuthConfig) validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if len(c.Principals) == 0 {
+		return errors.New("auth.principals must not be empty when auth is enabled")
+	}
+	names := map[string]bool{}
+	for i, p := range c.Principals {
+		name := strings.TrimSpace(p.Name)
+		if name == "" {
+			return fmt.Errorf("auth.principals[%d] needs a name", i)
+		}
+		if names[name] {
+			return fmt.Errorf("auth.principals: %q is listed twice", name)
+		}
+		names[name] = true
+		if p.KeySHA256 != "" && !sha256HexPattern.MatchString(p.KeySHA256) {
+			return fmt.Errorf("auth.principals[%d].key_sha256 must be 64 hex digits", i)
+		}
+		if p.KeySHA256 == "" && p.KeyEnv == "" && len(p.Clients) == 0 {
+			return fmt.Errorf("auth.principals[%d] needs key_sha256, key_env or clients", i)
+		}
+		if len(p.Namespaces) == 0 {
+			return fmt.Errorf("auth.principals[%d].namespaces must not be empty", i)
+		}
+		for _, ns := range p.Namespaces {
+			if strings.Trim(strings.TrimSpace(ns), "/") == "" {
+				return fmt.Errorf("auth.principals[%d].namespaces must not contain empty prefixes", i)
+			}
+		}
+	}
 	return nil
 }
 
+// GitNamespaceTemplate is the default namespace used with GitNamespace.
+const GitNamespaceTemplate = "${GIT_ORG}/${GIT_REPO}/${GIT_BRANCH}"
+
+// NeedsGit reports whether the default namespace uses git placeholders.
+func (c Config) NeedsGit() bool {
+	return (c.DefaultNamespace == "" && c.GitNamespace) || strings.Contains(c.DefaultNamespace, "GIT_")
+}
+
+// ResolveDefaultNamespace expands placeholders in DefaultNamespace (or
+// GitNamespaceTemplate with GitNamespace) from vars, falling back to the
+// environment, and checks the result against NamespacePattern. It returns
+// "" when no default is configured.
+func (c Config) ResolveDefaultNamespace(vars map[string]string) (string, error) {
+	tmpl := c.DefaultNamespace
+	if tmpl == "" && c.GitNamespace {
+		tmpl = GitNamespaceTemplate
+	}
+	ns := strings.TrimSpace(os.Expand(tmpl, func(key string) string {
+		if v, ok := vars[key]; ok {
+			return v
+		}
+		return os.Getenv(key)
+	}))
+	if ns == "" {
+		return "", nil
+	}
+	re, err := regexp.Compile(c.NamespacePattern)
+	if err != nil {
+		return "", err
+	}
+	if !re.MatchString(ns) {
+		return "", fmt.Errorf("default_namespace %q expands to %q, which does not match namespace_pattern", tmpl, ns)
+	}
+	return ns, nil
+}
+
 // EnsurePaths creates parent directories for config-managed paths.
 func (c *Config) EnsurePaths() error {
 	c.DBPath = ExpandPath(c.DBPath)
+	c.Store.Dir = ExpandPath(c.Store.Dir)
+	for prefix, path := range c.Store.Shards {
+		c.Store.Shards[prefix] = ExpandPath(path)
+	}
+	c.Obsidian.VaultDir = ExpandPath(c.Obsidian.VaultDir)
+	c.ControlDir = ExpandPath(c.ControlDir)
+	if path, ok := strings.CutPrefix(c.Listen, "unix:"); ok {
+		c.Listen = "unix:" + ExpandPath(path)
+	} else if strings.HasPrefix(c.Listen, "~") {
+		c.Listen = ExpandPath(c.Listen)
+	}
+	c.SnapshotDir = ExpandPath(c.SnapshotDir)
+	if c.SnapshotDir == "" {
+		c.SnapshotDir = filepath.Join(filepath.Dir(c.DBPath), "snapshots")
+	}
 	parent := filepath.Dir(c.DBPath)
 	if parent == "." {
 		return nil
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -15,3 +17,192 @@ func TestExpandPath(t *testing.T) {
 		t.Fatalf("expected expanded path to contain file name, got %q", got)
 	}
 }
+
+func TestLoad_RecordsSourcePath(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "memory-mcp.yaml")
+	if err := os.WriteFile(path, []byte("default_search_k: 7\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Path != path || cfg.DefaultSearchK != 7 {
+		t.Fatalf("Load() = path %q, k %d", cfg.Path, cfg.DefaultSearchK)
+	}
+	if cfg, err := Load(filepath.Join(dir, "missing.yaml")); err != nil || cfg.Path != "" {
+		t.Fatalf("Load(missing) = path %q, %v; want defaults", cfg.Path, err)
+	}
+}
+
+func TestResolveDefaultNamespace_ExpandsEnv(t *testing.T) {
+	t.Setenv("MEMORY_MCP_TEST_REPO", "ghost")
+	cfg := Default()
+	cfg.DefaultNamespace = "acme/${MEMORY_MCP_TEST_REPO}/main"
+	if ns, err := cfg.ResolveDefaultNamespace(nil); err != nil || ns != "acme/ghost/main" {
+		t.Fatalf("ResolveDefaultNamespace() = %q, %v", ns, err)
+	}
+	cfg.DefaultNamespace = "${MEMORY_MCP_TEST_UNSET}"
+	if ns, err := cfg.ResolveDefaultNamespace(nil); err != nil || ns != "" {
+		t.Fatalf("ResolveDefaultNamespace(unset) = %q, %v; want empty", ns, err)
+	}
+	cfg.DefaultNamespace = "acme/${MEMORY_MCP_TEST_UNSET}/main"
+	if _, err := cfg.ResolveDefaultNamespace(nil); err == nil {
+		t.Fatal("expected a namespace with an empty segment to be rejected")
+	}
+}
+
+func TestResolveDefaultNamespace_GitVars(t *testing.T) {
+	t.Parallel()
+	cfg := Default()
+	cfg.GitNamespace = true
+	vars := map[string]string{"GIT_ORG": "acme", "GIT_REPO": "ghost", "GIT_BRANCH": "main"}
+	if !cfg.NeedsGit() {
+		t.Fatal("expected git_namespace to need git")
+	}
+	if ns, err := cfg.ResolveDefaultNamespace(vars); err != nil || ns != "acme/ghost/main" {
+		t.Fatalf("ResolveDefaultNamespace() = %q, %v", ns, err)
+	}
+	cfg.DefaultNamespace = "${GIT_ORG}/${GIT_REPO}/${GIT_BRANCH}/agents"
+	if ns, err := cfg.ResolveDefaultNamespace(vars); err != nil || ns != "acme/ghost/main/agents" {
+		t.Fatalf("ResolveDefaultNamespace(template) = %q, %v", ns, err)
+	}
+}
+
+func TestValidate_RankingWeights(t *testing.T) {
+	t.Parallel()
+	cfg := Default()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Default().Validate() error = %v", err)
+	}
+	for name, edit := range map[string]func(*RankingConfig){
+		"negative weight": func(r *RankingConfig) { r.RecencyWeight = -0.1 },
+		"all weights zero": func(r *RankingConfig) {
+			r.LexicalWeight, r.RecencyWeight, r.ImportanceWeight, r.FrequencyWeight = 0, 0, 0, 0
+		},
+		"zero half-life":     func(r *RankingConfig) { r.RecencyHalfLifeDays = 0 },
+		"negative frequency": func(r *RankingConfig) { r.FrequencyWeight = -1 },
+	} {
+		cfg := Default()
+		edit(&cfg.Ranking)
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "ranking") {
+			t.Fatalf("Validate() with %s error = %v, want a ranking error", name, err)
+		}
+	}
+}
+
+func TestStoreConfig_PostgresDSN(t *testing.T) {
+	cfg := Default()
+	cfg.Store.Driver = "postgres"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "store.dsn") {
+		t.Fatalf("Validate() without a DSN error = %v, want a store.dsn error", err)
+	}
+	cfg.Store.DSN = "postgres://file/memory"
+	cfg.Store.DSNEnv = "MEMORY_MCP_TEST_DSN"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if got := cfg.Store.PostgresDSN(); got != "postgres://file/memory" {
+		t.Fatalf("PostgresDSN() with the variable unset = %q", got)
+	}
+	t.Setenv("MEMORY_MCP_TEST_DSN", "postgres://env/memory")
+	if got := cfg.Store.PostgresDSN(); got != "postgres://env/memory" {
+		t.Fatalf("PostgresDSN() = %q, want the environment value", got)
+	}
+}
+
+func TestValidate_Tracing(t *testing.T) {
+	t.Parallel()
+	for name, edit := range map[string]func(*TracingConfig){
+		"ratio above one": func(c *TracingConfig) { c.SampleRatio = 1.5 },
+		"negative ratio":  func(c *TracingConfig) { c.SampleRatio = -0.1 },
+		"no service name": func(c *TracingConfig) { c.Enabled, c.ServiceName = true, " " },
+	} {
+		cfg := Default()
+		edit(&cfg.Tracing)
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "tracing") {
+			t.Fatalf("Validate() with %s error = %v, want a tracing error", name, err)
+		}
+	}
+}
+
+func TestValidate_PackCacheNeedsATTL(t *testing.T) {
+	t.Parallel()
+	cfg := Default()
+	cfg.Cache.PackTTLSeconds = 0
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "cache.pack_ttl_seconds") {
+		t.Fatalf("Validate() with a pack cache and no TTL error = %v, want a cache.pack_ttl_seconds error", err)
+	}
+	cfg.Cache.PackEntries = 0
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() with the pack cache disabled error = %v", err)
+	}
+}
+
+func TestValidate_RecordCacheNeedsATTL(t *testing.T) {
+	t.Parallel()
+	cfg := Default()
+	cfg.Cache.RecordsTTLSeconds = 0
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "cache.records_ttl_seconds") {
+		t.Fatalf("Validate() with a record cache and no TTL error = %v, want a cache.records_ttl_seconds error", err)
+	}
+	cfg.Cache.Records = 0
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() with the record cache disabled error = %v", err)
+	}
+}
+
+func TestValidate_ContextPack(t *testing.T) {
+	t.Parallel()
+	for name, edit := range map[string]func(*ContextPackConfig){
+		"unknown format":  func(c *ContextPackConfig) { c.Format = "yaml" },
+		"broken template": func(c *ContextPackConfig) { c.Templates = map[string]string{"claude": "{{range .Memories}"} },
+		"unknown func":    func(c *ContextPackConfig) { c.Templates = map[string]string{"claude": "{{yaml .Query}}"} },
+	} {
+		cfg := Default()
+		edit(&cfg.ContextPack)
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "context_pack") {
+			t.Fatalf("Validate() with %s error = %v, want a context_pack error", name, err)
+		}
+	}
+	cfg := Default()
+	cfg.ContextPack.Format = "claude"
+	cfg.ContextPack.Templates = map[string]string{"claude": `{{range .Memories}}{{json .Text}}{{end}}`}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() with a template format error = %v", err)
+	}
+}
+
+func TestValidate_ContextPackSections(t *testing.T) {
+	t.Parallel()
+	for name, edit := range map[string]func(*ContextPackSectionsConfig){
+		"zero stale days": func(c *ContextPackSectionsConfig) { c.StaleAfterDays = 0 },
+		"unknown section": func(c *ContextPackSectionsConfig) { c.Budget = map[string]float64{"archive": 1} },
+		"negative share":  func(c *ContextPackSectionsConfig) { c.Budget = map[string]float64{"durable": 1, "stale": -0.5} },
+		"no share":        func(c *ContextPackSectionsConfig) { c.Budget = map[string]float64{"durable": 0} },
+	} {
+		cfg := Default()
+		edit(&cfg.ContextPack.Sections)
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "context_pack.sections") {
+			t.Fatalf("Validate() with %s error = %v, want a context_pack.sections error", name, err)
+		}
+	}
+}
+
+func TestValidate_Scopes(t *testing.T) {
+	t.Parallel()
+	for name, edit := range map[string]func(*ScopesConfig){
+		"zero project depth":   func(c *ScopesConfig) { c.ProjectDepth = 0 },
+		"negative ttl":         func(c *ScopesConfig) { c.Global.TTLHours = -1 },
+		"promote from wider":   func(c *ScopesConfig) { c.Project.PromoteFrom = []string{"global"} },
+		"promote from unknown": func(c *ScopesConfig) { c.Global.PromoteFrom = []string{"team"} },
+	} {
+		cfg := Default()
+		edit(&cfg.Scopes)
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "scopes.") {
+			t.Fatalf("Validate() with %s error = %v, want a scopes error", name, err)
+		}
+	}
+}
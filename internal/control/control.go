@@ -0,0 +1,106 @@
+// Package control exposes a running server's live state on a local Unix
+// socket so `admin --attach` can show what is not persisted in SQLite.
+//
+// Every serve process listens on <dir>/<pid>.sock and answers each
+// connection with one JSON-encoded mcp.Snapshot.
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/mcp"
+)
+
+const ioTimeout = 2 * time.Second
+
+// Snapshotter reports a server's live state.
+type Snapshotter interface {
+	Snapshot() mcp.Snapshot
+}
+
+// SocketPath returns the control socket of the process pid in dir.
+func SocketPath(dir string, pid int) string {
+	return filepath.Join(dir, fmt.Sprintf("%d.sock", pid))
+}
+
+// Listen serves snapshots of src on this process's socket in dir until ctx
+// is done, then removes the socket.
+func Listen(ctx context.Context, dir string, logger *log.Logger, src Snapshotter) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create control dir: %w", err)
+	}
+	path := SocketPath(dir, os.Getpid())
+	_ = os.Remove(path)
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("listen on control socket: %w", err)
+	}
+	defer os.Remove(path)
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("accept control connection: %w", err)
+		}
+		_ = conn.SetDeadline(time.Now().Add(ioTimeout))
+		if err := json.NewEncoder(conn).Encode(src.Snapshot()); err != nil {
+			logger.Debug("control snapshot not sent", "error", err)
+		}
+		conn.Close()
+	}
+}
+
+// Attach collects a snapshot from every live server in dir, ordered by
+// PID. Sockets left behind by servers that have exited are removed.
+func Attach(ctx context.Context, dir string) ([]mcp.Snapshot, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.sock"))
+	if err != nil {
+		return nil, err
+	}
+	var out []mcp.Snapshot
+	for _, path := range paths {
+		snap, err := fetch(ctx, path)
+		if err != nil {
+			if errors.Is(err, syscall.ECONNREFUSED) {
+				_ = os.Remove(path)
+			}
+			continue
+		}
+		out = append(out, snap)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].PID < out[j].PID })
+	return out, nil
+}
+
+func fetch(ctx context.Context, path string) (mcp.Snapshot, error) {
+	d := net.Dialer{Timeout: ioTimeout}
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return mcp.Snapshot{}, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(ioTimeout))
+	var snap mcp.Snapshot
+	if err := json.NewDecoder(conn).Decode(&snap); err != nil {
+		return mcp.Snapshot{}, fmt.Errorf("read snapshot from %s: %w", path, err)
+	}
+	return snap, nil
+}
@@ -0,0 +1,68 @@
+package control
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/mcp"
+)
+
+type fixedSnapshot mcp.Snapshot
+
+func (f fixedSnapshot) Snapshot() mcp.Snapshot { return mcp.Snapshot(f) }
+
+func TestAttach_ReadsLiveServersAndRemovesStaleSockets(t *testing.T) {
+	t.Parallel()
+	dir, err := os.MkdirTemp("", "ctl")
+	if err != nil {
+		t.Fatalf("MkdirTemp() error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A socket file with no listener behind it, as left by a killed server.
+	stale := filepath.Join(dir, "1.sock")
+	ln, err := net.Listen("unix", stale)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	want := fixedSnapshot{PID: os.Getpid(), Requests: 7, Client: mcp.ClientInfo{Name: "codex", Version: "1.2"},
+		InFlight: []mcp.ToolCall{{Tool: "memory_search", StartedAt: time.Now().UTC()}}}
+	done := make(chan error, 1)
+	go func() { done <- Listen(ctx, dir, log.NewWithOptions(io.Discard, log.Options{}), want) }()
+
+	var snaps []mcp.Snapshot
+	for range 100 {
+		if snaps, err = Attach(context.Background(), dir); err != nil {
+			t.Fatalf("Attach() error = %v", err)
+		}
+		if len(snaps) == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(snaps) != 1 || snaps[0].Requests != 7 || snaps[0].Client.Name != "codex" || len(snaps[0].InFlight) != 1 {
+		t.Fatalf("unexpected snapshots %+v", snaps)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatalf("expected stale socket to be removed, stat error = %v", err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	if _, err := os.Stat(SocketPath(dir, os.Getpid())); !os.IsNotExist(err) {
+		t.Fatalf("expected socket to be removed on shutdown, stat error = %v", err)
+	}
+}
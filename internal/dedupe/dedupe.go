@@ -0,0 +1,134 @@
+// Package dedupe finds exact and near-duplicate texts. Texts are exact
+// duplicates when they match after case and whitespace folding, and near
+// duplicates when the Jaccard similarity of their word sets reaches a
+// threshold.
+package dedupe
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/xiy/memory-mcp/internal/textnorm"
+)
+
+// Doc is one text to compare.
+type Doc struct {
+	ID   string
+	Text string
+}
+
+// Group is a set of duplicate documents in input order. Similarity is the
+// weakest pairwise link that joined the group, 1 for exact groups.
+type Group struct {
+	IDs        []string
+	Exact      bool
+	Similarity float64
+}
+
+// Find returns the groups of two or more documents that are duplicates of
+// each other, directly or through a chain of near duplicates.
+func Find(docs []Doc, threshold float64) []Group {
+	keys := make([]string, len(docs))
+	sets := make([]map[string]struct{}, len(docs))
+	for i, d := range docs {
+		keys[i] = Normalize(d.Text)
+		sets[i] = wordSet(d.Text)
+	}
+
+	parent := make([]int, len(docs))
+	weakest := make([]float64, len(docs))
+	for i := range parent {
+		parent[i] = i
+		weakest[i] = 1
+	}
+	var find func(int) int
+	find = func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+
+	for i := range docs {
+		for j := i + 1; j < len(docs); j++ {
+			sim := 1.0
+			if keys[i] != keys[j] {
+				sim = jaccard(sets[i], sets[j])
+				if sim < threshold {
+					continue
+				}
+			}
+			ri, rj := find(i), find(j)
+			w := min(weakest[ri], weakest[rj], sim)
+			if ri != rj {
+				parent[rj] = ri
+			}
+			weakest[ri] = w
+		}
+	}
+
+	byRoot := map[int]*Group{}
+	var order []int
+	for i, d := range docs {
+		r := find(i)
+		g, ok := byRoot[r]
+		if !ok {
+			g = &Group{Exact: true, Similarity: weakest[r]}
+			byRoot[r] = g
+			order = append(order, r)
+		}
+		if len(g.IDs) > 0 && keys[i] != keys[r] {
+			g.Exact = false
+		}
+		g.IDs = append(g.IDs, d.ID)
+	}
+	var out []Group
+	for _, r := range order {
+		if g := byRoot[r]; len(g.IDs) > 1 {
+			out = append(out, *g)
+		}
+	}
+	return out
+}
+
+// Similarity is the Jaccard similarity of a's and b's word sets, or 1 when
+// they are exact duplicates.
+func Similarity(a, b string) float64 {
+	if Normalize(a) == Normalize(b) {
+		return 1
+	}
+	return jaccard(wordSet(a), wordSet(b))
+}
+
+// Normalize folds case and Unicode variants and collapses whitespace.
+func Normalize(s string) string {
+	return strings.Join(strings.Fields(textnorm.Fold(s)), " ")
+}
+
+func wordSet(s string) map[string]struct{} {
+	out := map[string]struct{}{}
+	for _, w := range strings.FieldsFunc(textnorm.Fold(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		out[w] = struct{}{}
+	}
+	return out
+}
+
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	small, large := a, b
+	if len(small) > len(large) {
+		small, large = large, small
+	}
+	inter := 0
+	for w := range small {
+		if _, ok := large[w]; ok {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
@@ -0,0 +1,28 @@
+package dedupe
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFind_GroupsExactAndNearDuplicates(t *testing.T) {
+	t.Parallel()
+	docs := []Doc{
+		{ID: "a", Text: "Use pnpm for installs"},
+		{ID: "b", Text: "deploy window is 9 to 11 on weekdays"},
+		{ID: "c", Text: "use  PNPM for installs"},
+		{ID: "d", Text: "the deploy window is 9 to 11 on weekdays"},
+		{ID: "e", Text: "unrelated note about the cache"},
+	}
+	got := Find(docs, 0.8)
+	want := []Group{
+		{IDs: []string{"a", "c"}, Exact: true, Similarity: 1},
+		{IDs: []string{"b", "d"}, Exact: false, Similarity: 8.0 / 9.0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Find() = %+v, want %+v", got, want)
+	}
+	if got := Find(docs, 0.95); len(got) != 1 || !got[0].Exact {
+		t.Fatal("expected a stricter threshold to keep only the exact group")
+	}
+}
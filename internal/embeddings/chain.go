@@ -0,0 +1,139 @@
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/clock"
+)
+
+// ErrNoProvider is returned by Chain.Embed when every provider failed or
+// has an open circuit.
+var ErrNoProvider = errors.New("no embedding provider available")
+
+// Link is one named provider in a Chain.
+type Link struct {
+	Name     string
+	Provider Provider
+}
+
+// Chain tries its providers in order and returns the first vector. A
+// provider that fails threshold times in a row is skipped for cooldown, then
+// given one trial call while concurrent calls keep skipping it; success
+// closes its circuit again and failure reopens it for another cooldown.
+type Chain struct {
+	links     []Link
+	threshold int
+	cooldown  time.Duration
+	clock     clock.Clock
+
+	mu     sync.Mutex
+	states []breaker
+}
+
+type breaker struct {
+	failures  int
+	openUntil time.Time
+	// probing is set while the trial call after a cooldown is in flight.
+	probing bool
+}
+
+// LinkStatus reports one provider's circuit state.
+type LinkStatus struct {
+	Name      string    `json:"name"`
+	Failures  int       `json:"failures"`
+	Open      bool      `json:"open"`
+	OpenUntil time.Time `json:"open_until,omitzero"`
+}
+
+// NewChain returns a Chain over links. threshold <= 0 means 1.
+func NewChain(links []Link, threshold int, cooldown time.Duration, clk clock.Clock) *Chain {
+	return &Chain{
+		links:     links,
+		threshold: max(threshold, 1),
+		cooldown:  cooldown,
+		clock:     clk,
+		states:    make([]breaker, len(links)),
+	}
+}
+
+// Embed returns the first successful provider's vector. When none succeeds
+// the error wraps ErrNoProvider and each attempted provider's failure.
+func (c *Chain) Embed(ctx context.Context, text string) ([]float32, error) {
+	vec, _, err := c.EmbedWith(ctx, text)
+	return vec, err
+}
+
+// EmbedWith is Embed that also names the provider that answered.
+func (c *Chain) EmbedWith(ctx context.Context, text string) ([]float32, string, error) {
+	var errs []error
+	for i, l := range c.links {
+		if !c.allow(i) {
+			continue
+		}
+		vec, err := l.Provider.Embed(ctx, text)
+		c.record(i, err)
+		if err == nil {
+			return vec, l.Name, nil
+		}
+		if ctx.Err() != nil {
+			return nil, "", ctx.Err()
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", l.Name, err))
+	}
+	return nil, "", errors.Join(append([]error{ErrNoProvider}, errs...)...)
+}
+
+// Status reports every provider's circuit state.
+func (c *Chain) Status() []LinkStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock.Now()
+	out := make([]LinkStatus, len(c.links))
+	for i, l := range c.links {
+		st := c.states[i]
+		out[i] = LinkStatus{Name: l.Name, Failures: st.failures}
+		if now.Before(st.openUntil) {
+			out[i].Open = true
+			out[i].OpenUntil = st.openUntil
+		}
+	}
+	return out
+}
+
+// allow reports whether provider i may be called. Once its circuit has
+// tripped, only one caller at a time gets through; record ends the trial.
+func (c *Chain) allow(i int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := &c.states[i]
+	if c.clock.Now().Before(st.openUntil) {
+		return false
+	}
+	if st.failures < c.threshold {
+		return true
+	}
+	if st.probing {
+		return false
+	}
+	st.probing = true
+	return true
+}
+
+func (c *Chain) record(i int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := &c.states[i]
+	if err == nil {
+		*st = breaker{}
+		return
+	}
+	st.probing = false
+	st.failures++
+	if st.failures >= c.threshold {
+		st.openUntil = c.clock.Now().Add(c.cooldown)
+	}
+}
@@ -0,0 +1,149 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/clock"
+	"github.com/xiy/memory-mcp/internal/config"
+)
+
+type stubProvider struct {
+	vec   []float32
+	err   error
+	calls int
+}
+
+func (s *stubProvider) Embed(context.Context, string) ([]float32, error) {
+	s.calls++
+	return s.vec, s.err
+}
+
+func TestChain_FallsBackAndBreaksCircuit(t *testing.T) {
+	t.Parallel()
+	local := &stubProvider{err: errors.New("connection refused")}
+	hosted := &stubProvider{vec: []float32{1, 2}}
+	clk := clock.NewManual(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	c := NewChain([]Link{{Name: "ollama", Provider: local}, {Name: "openai", Provider: hosted}}, 2, time.Minute, clk)
+
+	for range 3 {
+		vec, name, err := c.EmbedWith(context.Background(), "hello")
+		if err != nil || name != "openai" || len(vec) != 2 {
+			t.Fatalf("EmbedWith() = %v, %q, %v; want the hosted fallback", vec, name, err)
+		}
+	}
+	if local.calls != 2 {
+		t.Fatalf("expected the open circuit to skip the local provider, got %d calls", local.calls)
+	}
+	if st := c.Status(); !st[0].Open || st[1].Open {
+		t.Fatalf("unexpected status %+v", st)
+	}
+
+	clk.Advance(time.Minute)
+	local.err, local.vec = nil, []float32{3}
+	if _, name, err := c.EmbedWith(context.Background(), "hello"); err != nil || name != "ollama" {
+		t.Fatalf("EmbedWith() = %q, %v; want the recovered local provider", name, err)
+	}
+	if st := c.Status(); st[0].Open || st[0].Failures != 0 {
+		t.Fatalf("expected the circuit to close after success, got %+v", st[0])
+	}
+
+	hosted.err, local.err = errors.New("down"), errors.New("down")
+	if _, err := c.Embed(context.Background(), "hello"); !errors.Is(err, ErrNoProvider) {
+		t.Fatalf("Embed() error = %v, want ErrNoProvider", err)
+	}
+}
+
+// blockingProvider fails until released, signalling each call on started.
+type blockingProvider struct {
+	started chan struct{}
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (b *blockingProvider) Embed(ctx context.Context, _ string) ([]float32, error) {
+	b.calls.Add(1)
+	b.started <- struct{}{}
+	select {
+	case <-b.release:
+		return nil, errors.New("still down")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestChain_HalfOpenAllowsOneTrialCall(t *testing.T) {
+	t.Parallel()
+	local := &blockingProvider{started: make(chan struct{}, 4), release: make(chan struct{})}
+	clk := clock.NewManual(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	c := NewChain([]Link{{Name: "ollama", Provider: local}}, 1, time.Minute, clk)
+
+	close(local.release)
+	if _, err := c.Embed(context.Background(), "hello"); !errors.Is(err, ErrNoProvider) {
+		t.Fatalf("Embed() error = %v, want ErrNoProvider", err)
+	}
+	<-local.started
+	local.release = make(chan struct{})
+	clk.Advance(time.Minute)
+
+	trial := make(chan error, 1)
+	go func() {
+		_, err := c.Embed(context.Background(), "hello")
+		trial <- err
+	}()
+	<-local.started
+	// The trial call is in flight: every other caller skips the provider.
+	for range 3 {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, err := c.Embed(ctx, "hello")
+		cancel()
+		if !errors.Is(err, ErrNoProvider) {
+			t.Fatalf("Embed() during the trial error = %v, want ErrNoProvider", err)
+		}
+	}
+	close(local.release)
+	if err := <-trial; !errors.Is(err, ErrNoProvider) {
+		t.Fatalf("trial Embed() error = %v, want ErrNoProvider", err)
+	}
+	if n := local.calls.Load(); n != 2 {
+		t.Fatalf("provider saw %d calls, want the first and one trial", n)
+	}
+	if st := c.Status(); !st[0].Open {
+		t.Fatalf("expected the failed trial to reopen the circuit, got %+v", st[0])
+	}
+}
+
+func TestHTTPProvider_DecodesBothAPIs(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req embedRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		switch r.URL.Path {
+		case "/api/embed":
+			_, _ = w.Write([]byte(`{"embeddings":[[0.5,0.25]]}`))
+		case "/v1/embeddings":
+			_, _ = w.Write([]byte(`{"data":[{"embedding":[1,0,0]}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	vec, err := NewHTTP(config.EmbeddingProviderConfig{Kind: "ollama", Endpoint: srv.URL, Model: "nomic-embed-text"}).Embed(context.Background(), "x")
+	if err != nil || len(vec) != 2 || vec[0] != 0.5 {
+		t.Fatalf("ollama Embed() = %v, %v", vec, err)
+	}
+	vec, err = NewHTTP(config.EmbeddingProviderConfig{Kind: "openai", Endpoint: srv.URL + "/v1/", Model: "text-embedding-3-small"}).Embed(context.Background(), "x")
+	if err != nil || len(vec) != 3 {
+		t.Fatalf("openai Embed() = %v, %v", vec, err)
+	}
+	if _, err := NewHTTP(config.EmbeddingProviderConfig{Kind: "openai", Endpoint: srv.URL + "/missing"}).Embed(context.Background(), "x"); err == nil {
+		t.Fatal("expected a 404 to fail")
+	}
+}
@@ -0,0 +1,70 @@
+package embeddings
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/xiy/memory-mcp/internal/lang"
+	"github.com/xiy/memory-mcp/internal/textnorm"
+)
+
+// DefaultHashDimensions is the vector size of a hash provider that does
+// not set dimensions.
+const DefaultHashDimensions = 256
+
+// HashProvider embeds text locally by feature hashing: every word stem and
+// character trigram is hashed to a signed vector component. It needs no
+// model or network, so it works offline, but it only captures shared words
+// and word fragments, not meaning; synonyms do not match.
+type HashProvider struct {
+	dim int
+}
+
+// NewHash returns a hash provider with dim components; dim <= 0 means
+// DefaultHashDimensions.
+func NewHash(dim int) *HashProvider {
+	if dim <= 0 {
+		dim = DefaultHashDimensions
+	}
+	return &HashProvider{dim: dim}
+}
+
+// Embed returns the unit-length vector for text, or a zero vector when
+// text has no words.
+func (p *HashProvider) Embed(_ context.Context, text string) ([]float32, error) {
+	vec := make([]float32, p.dim)
+	words := strings.FieldsFunc(textnorm.Fold(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		p.add(vec, "w:"+lang.Stem(w), 1)
+		runes := []rune("^" + w + "$")
+		for i := 0; i+3 <= len(runes); i++ {
+			p.add(vec, "t:"+string(runes[i:i+3]), 0.5)
+		}
+	}
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vec {
+			vec[i] *= scale
+		}
+	}
+	return vec, nil
+}
+
+func (p *HashProvider) add(vec []float32, feature string, weight float32) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	vec[sum%uint64(p.dim)] += weight
+}
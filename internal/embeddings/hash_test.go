@@ -0,0 +1,36 @@
+package embeddings
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xiy/memory-mcp/internal/store"
+)
+
+func TestHashProvider_SharedWordsAndFragmentsAreCloser(t *testing.T) {
+	t.Parallel()
+	p := NewHash(0)
+	embed := func(text string) []float32 {
+		t.Helper()
+		vec, err := p.Embed(context.Background(), text)
+		if err != nil {
+			t.Fatalf("Embed() error = %v", err)
+		}
+		if len(vec) != DefaultHashDimensions {
+			t.Fatalf("Embed() has %d dimensions, want %d", len(vec), DefaultHashDimensions)
+		}
+		return vec
+	}
+	q := embed("Deploying the Kubernetes cluster")
+	near := store.Cosine(q, embed("kubernetes cluster deployed"))
+	far := store.Cosine(q, embed("lunch menu for friday"))
+	if near < 0.5 || far > 0.2 {
+		t.Fatalf("cosine near = %.2f, far = %.2f, want near >= 0.5 and far <= 0.2", near, far)
+	}
+	if got := store.Cosine(q, embed("deploying the kubernetes CLUSTER")); got < 0.999 {
+		t.Fatalf("cosine of case variants = %.3f, want 1", got)
+	}
+	if vec := embed("  ... "); store.Cosine(vec, vec) != 0 {
+		t.Fatal("Embed(no words) is not a zero vector")
+	}
+}
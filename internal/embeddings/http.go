@@ -0,0 +1,111 @@
+package embeddings
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/config"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// HTTPProvider calls an Ollama (POST <endpoint>/api/embed) or
+// OpenAI-compatible (POST <endpoint>/embeddings) embedding endpoint.
+type HTTPProvider struct {
+	kind     string
+	endpoint string
+	model    string
+	apiKey   string
+	http     *http.Client
+}
+
+// NewHTTP returns a provider for cfg. The API key is read from the
+// environment variable named by cfg.APIKeyEnv.
+func NewHTTP(cfg config.EmbeddingProviderConfig) *HTTPProvider {
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	var key string
+	if cfg.APIKeyEnv != "" {
+		key = os.Getenv(cfg.APIKeyEnv)
+	}
+	return &HTTPProvider{
+		kind:     cfg.Kind,
+		endpoint: strings.TrimRight(cfg.Endpoint, "/"),
+		model:    cfg.Model,
+		apiKey:   key,
+		http:     &http.Client{Timeout: timeout},
+	}
+}
+
+type embedRequest struct {
+	Model string `json:"model"`
+	Input string `json:"input"`
+}
+
+// embedResponse covers both response shapes: Ollama's "embeddings" and
+// OpenAI's "data[].embedding".
+type embedResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+	Data       []struct {
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed returns the vector for text.
+func (p *HTTPProvider) Embed(ctx context.Context, text string) ([]float32, error) {
+	path := "/embeddings"
+	if p.kind == "ollama" {
+		path = "/api/embed"
+	}
+	body, err := json.Marshal(embedRequest{Model: p.model, Input: text})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build embedding request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embedding request: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read embedding response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding request: %s", resp.Status)
+	}
+
+	var out embedResponse
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("decode embedding response: %w", err)
+	}
+	var vec []float32
+	switch {
+	case len(out.Embeddings) > 0:
+		vec = out.Embeddings[0]
+	case len(out.Data) > 0:
+		vec = out.Data[0].Embedding
+	}
+	if len(vec) == 0 {
+		return nil, errors.New("embedding response has no vector")
+	}
+	return vec, nil
+}
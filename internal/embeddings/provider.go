@@ -1,8 +1,49 @@
+// Package embeddings turns text into vectors for semantic ranking. Providers
+// can be chained so a local model is tried before a hosted one.
 package embeddings
 
-import "context"
+import (
+	"context"
+	"strings"
+	"time"
 
-// Provider is reserved for v2 semantic ranking support.
+	"github.com/xiy/memory-mcp/internal/clock"
+	"github.com/xiy/memory-mcp/internal/config"
+)
+
+// Provider embeds one text.
 type Provider interface {
 	Embed(ctx context.Context, text string) ([]float32, error)
 }
+
+// New returns a Chain over cfg.Providers, or nil when none are configured.
+func New(cfg config.EmbeddingsConfig) *Chain {
+	return NewForModel(cfg, "")
+}
+
+// NewForModel is New restricted to the providers serving model; an empty
+// model keeps every provider. Vectors from different models are not
+// comparable, so a chain used to fill the store should serve one model.
+func NewForModel(cfg config.EmbeddingsConfig, model string) *Chain {
+	var links []Link
+	for _, p := range cfg.Providers {
+		if model != "" && p.Model != model {
+			continue
+		}
+		var provider Provider = NewHTTP(p)
+		if p.Kind == "hash" {
+			provider = NewHash(p.Dimensions)
+		}
+		links = append(links, Link{Name: p.Kind + ":" + p.Model, Provider: provider})
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	return NewChain(links, cfg.FailureThreshold, time.Duration(cfg.CooldownSeconds)*time.Second, clock.System{})
+}
+
+// ModelOf returns the model of a Link name as built by New.
+func ModelOf(name string) string {
+	_, model, _ := strings.Cut(name, ":")
+	return model
+}
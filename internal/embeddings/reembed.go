@@ -0,0 +1,56 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+const reembedBatch = 64
+
+// Text is the text embedded for a memory.
+func Text(rec types.MemoryRecord) string {
+	if s := strings.TrimSpace(rec.Summary); s != "" && !strings.HasPrefix(rec.Content, s) {
+		return s + "\n" + rec.Content
+	}
+	return rec.Content
+}
+
+// Reembed embeds every memory that lacks a model vector with p, then makes
+// model the store's active model, dropping vectors of the previous one.
+// It is resumable: vectors stored before a failure are kept and skipped on
+// the next run. progress, if set, is called after each batch with the
+// number of memories embedded so far.
+func Reembed(ctx context.Context, st store.EmbeddingStore, p Provider, model string, progress func(done int)) (int, error) {
+	done := 0
+	for {
+		batch, err := st.MemoriesMissingEmbedding(ctx, model, reembedBatch)
+		if err != nil {
+			return done, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for _, rec := range batch {
+			vec, err := p.Embed(ctx, Text(rec))
+			if err != nil {
+				return done, fmt.Errorf("embed %s: %w", rec.ID, err)
+			}
+			if err := st.PutEmbedding(ctx, rec.ID, model, vec, time.Now()); err != nil {
+				return done, err
+			}
+			done++
+		}
+		if progress != nil {
+			progress(done)
+		}
+	}
+	if err := st.ActivateEmbeddingModel(ctx, model); err != nil {
+		return done, err
+	}
+	return done, nil
+}
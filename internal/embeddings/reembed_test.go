@@ -0,0 +1,76 @@
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+type lengthProvider struct{ dim int }
+
+func (p lengthProvider) Embed(_ context.Context, text string) ([]float32, error) {
+	vec := make([]float32, p.dim)
+	vec[len(text)%p.dim] = 1
+	return vec, nil
+}
+
+func TestReembed_MigratesModelAndRejectsMixedVectors(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "embed.db"), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	now := time.Now().UTC()
+	for _, id := range []string{"a", "b", "c"} {
+		_, err := st.InsertMemory(ctx, types.MemoryRecord{ID: id, Namespace: "org/repo", Scope: "long", Content: "memory " + id,
+			Importance: 3, CreatedAt: now, LastAccessedAt: now})
+		if err != nil {
+			t.Fatalf("InsertMemory() error = %v", err)
+		}
+	}
+
+	if n, err := Reembed(ctx, st, lengthProvider{dim: 4}, "small", nil); err != nil || n != 3 {
+		t.Fatalf("Reembed(small) = %d, %v", n, err)
+	}
+	if m, ok, err := st.ActiveEmbeddingModel(ctx); err != nil || !ok || m != (store.EmbeddingModel{Name: "small", Dim: 4}) {
+		t.Fatalf("ActiveEmbeddingModel() = %+v, %v, %v", m, ok, err)
+	}
+	if err := st.PutEmbedding(ctx, "a", "small", make([]float32, 8), now); !errors.Is(err, store.ErrEmbeddingMismatch) {
+		t.Fatalf("PutEmbedding() with the wrong dimension error = %v, want ErrEmbeddingMismatch", err)
+	}
+
+	// Vectors for a new model are stored alongside but never compared
+	// until the migration activates it.
+	if err := st.PutEmbedding(ctx, "a", "large", make([]float32, 8), now); err != nil {
+		t.Fatalf("PutEmbedding(large) error = %v", err)
+	}
+	_, err = st.NearestEmbeddings(ctx, store.VectorQuery{Namespace: "org/repo", Model: "large", Vector: make([]float32, 8), Now: now})
+	if !errors.Is(err, store.ErrEmbeddingMismatch) {
+		t.Fatalf("NearestEmbeddings() with an inactive model error = %v, want ErrEmbeddingMismatch", err)
+	}
+
+	if n, err := Reembed(ctx, st, lengthProvider{dim: 8}, "large", nil); err != nil || n != 2 {
+		t.Fatalf("Reembed(large) = %d, %v; want the 2 memories without large vectors", n, err)
+	}
+	query, _ := lengthProvider{dim: 8}.Embed(ctx, "memory b")
+	matches, err := st.NearestEmbeddings(ctx, store.VectorQuery{Namespace: "org/repo", Model: "large", Vector: query, Now: now})
+	if err != nil {
+		t.Fatalf("NearestEmbeddings() error = %v", err)
+	}
+	if len(matches) != 3 || matches[0].Similarity != 1 {
+		t.Fatalf("unexpected matches %+v", matches)
+	}
+	if missing, err := st.MemoriesMissingEmbedding(ctx, "small", 10); err != nil || len(missing) != 3 {
+		t.Fatalf("expected old-model vectors to be dropped, got %d missing, %v", len(missing), err)
+	}
+}
@@ -0,0 +1,128 @@
+// Package facts extracts subject–predicate–object statements such as
+// "default TTL = 48h" from memory content using a small set of rules, so
+// they can be looked up exactly instead of through fuzzy search.
+package facts
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Triple is one extracted statement. Subject and Predicate are normalized
+// to lower case with single spaces; Object keeps its original spelling.
+type Triple struct {
+	Subject   string `json:"subject"`
+	Predicate string `json:"predicate"`
+	Object    string `json:"object"`
+}
+
+const (
+	maxSubjectWords = 6
+	maxObjectWords  = 12
+)
+
+// Verb phrases recognised between a subject and an object, longest first so
+// "defaults to" wins over a shorter overlap.
+var verbPhrases = []string{
+	"defaults to", "depends on", "runs on", "is set to", "is owned by",
+	"uses", "prefers", "requires", "owns", "is", "are",
+}
+
+var (
+	assignRE = regexp.MustCompile(`^(.+?)\s*(=|:=|->|=>)\s*(.+)$`)
+	colonRE  = regexp.MustCompile(`^([^:]+):\s+(.+)$`)
+	verbRE   = buildVerbRE()
+)
+
+func buildVerbRE() *regexp.Regexp {
+	alts := make([]string, len(verbPhrases))
+	for i, v := range verbPhrases {
+		alts[i] = regexp.QuoteMeta(v)
+	}
+	return regexp.MustCompile(`(?i)^(.+?)\s+(` + strings.Join(alts, "|") + `)\s+(.+)$`)
+}
+
+// Extract returns the statements found in content, one per sentence at
+// most, without duplicates. Assignments ("x = y", "x: y") map to the
+// predicate "=".
+func Extract(content string) []Triple {
+	var out []Triple
+	seen := map[Triple]struct{}{}
+	for _, sentence := range sentences(content) {
+		t, ok := extractSentence(sentence)
+		if !ok {
+			continue
+		}
+		if _, dup := seen[t]; dup {
+			continue
+		}
+		seen[t] = struct{}{}
+		out = append(out, t)
+	}
+	return out
+}
+
+func extractSentence(s string) (Triple, bool) {
+	s = strings.TrimLeft(s, "-*• \t")
+	if m := assignRE.FindStringSubmatch(s); m != nil {
+		return build(m[1], "=", m[3])
+	}
+	if m := colonRE.FindStringSubmatch(s); m != nil {
+		return build(m[1], "=", m[2])
+	}
+	if m := verbRE.FindStringSubmatch(s); m != nil {
+		return build(m[1], m[2], m[3])
+	}
+	return Triple{}, false
+}
+
+func build(subject, predicate, object string) (Triple, bool) {
+	subject = NormalizeTerm(subject)
+	object = strings.Join(strings.Fields(strings.TrimRight(object, ".;,!? ")), " ")
+	if subject == "" || object == "" {
+		return Triple{}, false
+	}
+	if n := len(strings.Fields(subject)); n > maxSubjectWords {
+		return Triple{}, false
+	}
+	if len(strings.Fields(object)) > maxObjectWords {
+		return Triple{}, false
+	}
+	return Triple{Subject: subject, Predicate: NormalizeTerm(predicate), Object: object}, true
+}
+
+// NormalizeTerm lower-cases s, collapses whitespace and trims surrounding
+// punctuation, matching how subjects and predicates are stored.
+func NormalizeTerm(s string) string {
+	s = strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	return strings.TrimFunc(s, func(r rune) bool {
+		return unicode.IsPunct(r) && r != '_' && r != '='
+	})
+}
+
+// sentences splits content into lines and then at ". ", "! " and "? ",
+// leaving decimals such as "1.5" intact.
+func sentences(content string) []string {
+	var out []string
+	for _, line := range strings.Split(content, "\n") {
+		start := 0
+		rs := []rune(line)
+		for i := 0; i < len(rs); i++ {
+			if !strings.ContainsRune(".!?", rs[i]) {
+				continue
+			}
+			if i+1 < len(rs) && !unicode.IsSpace(rs[i+1]) {
+				continue
+			}
+			if seg := strings.TrimSpace(string(rs[start:i])); seg != "" {
+				out = append(out, seg)
+			}
+			start = i + 1
+		}
+		if seg := strings.TrimSpace(string(rs[start:])); seg != "" {
+			out = append(out, seg)
+		}
+	}
+	return out
+}
@@ -0,0 +1,35 @@
+package facts
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	t.Parallel()
+	content := `Default TTL = 48h.
+- Cache size: 1024 entries
+The API server uses Postgres 16. The ingest worker depends on the queue!
+It was a long day and nothing else happened here worth noting at all really.
+Ratio is 1.5 on average.
+Default TTL = 48h`
+
+	got := Extract(content)
+	want := []Triple{
+		{Subject: "default ttl", Predicate: "=", Object: "48h"},
+		{Subject: "cache size", Predicate: "=", Object: "1024 entries"},
+		{Subject: "the api server", Predicate: "uses", Object: "Postgres 16"},
+		{Subject: "the ingest worker", Predicate: "depends on", Object: "the queue"},
+		{Subject: "ratio", Predicate: "is", Object: "1.5 on average"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Extract() =\n%#v\nwant\n%#v", got, want)
+	}
+}
+
+func TestExtract_SkipsLongSubjects(t *testing.T) {
+	t.Parallel()
+	if got := Extract("when we finally got the staging cluster back online the deploy is green"); len(got) != 0 {
+		t.Fatalf("expected no facts from a long clause, got %v", got)
+	}
+}
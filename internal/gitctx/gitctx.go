@@ -0,0 +1,96 @@
+// Package gitctx derives namespace parts from the git repository a server
+// runs in.
+package gitctx
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Info identifies a checkout: the remote's owner and repository name and
+// the current branch, each usable as one namespace segment.
+type Info struct {
+	Org    string
+	Repo   string
+	Branch string
+}
+
+// Vars returns Info as the GIT_ORG, GIT_REPO and GIT_BRANCH placeholders
+// accepted by default_namespace.
+func (i Info) Vars() map[string]string {
+	return map[string]string{"GIT_ORG": i.Org, "GIT_REPO": i.Repo, "GIT_BRANCH": i.Branch}
+}
+
+// Detect reads the origin remote (or the first remote) and current branch
+// of the work tree containing dir.
+func Detect(ctx context.Context, dir string) (Info, error) {
+	remotes, err := git(ctx, dir, "remote")
+	if err != nil {
+		return Info{}, err
+	}
+	names := strings.Fields(remotes)
+	if len(names) == 0 {
+		return Info{}, fmt.Errorf("git repository at %s has no remote", dir)
+	}
+	remote := names[0]
+	for _, name := range names {
+		if name == "origin" {
+			remote = name
+		}
+	}
+	url, err := git(ctx, dir, "remote", "get-url", remote)
+	if err != nil {
+		return Info{}, err
+	}
+	org, repo, ok := ParseRemote(url)
+	if !ok {
+		return Info{}, fmt.Errorf("cannot derive org/repo from remote %q", url)
+	}
+	// symbolic-ref fails on a detached HEAD.
+	branch, err := git(ctx, dir, "symbolic-ref", "--short", "-q", "HEAD")
+	if err != nil {
+		branch = "detached"
+	}
+	return Info{Org: segment(org), Repo: segment(repo), Branch: segment(branch)}, nil
+}
+
+// ParseRemote extracts owner and repository from a remote URL such as
+// git@github.com:acme/ghost.git, https://github.com/acme/ghost or
+// ssh://git@host:22/group/sub/ghost.git. Nested groups keep only the last
+// owner segment.
+func ParseRemote(url string) (org, repo string, ok bool) {
+	url = strings.TrimSpace(url)
+	if _, rest, found := strings.Cut(url, "://"); found {
+		// Drop the host, keeping the path.
+		_, url, _ = strings.Cut(rest, "/")
+	} else if _, rest, found := strings.Cut(url, ":"); found {
+		url = rest
+	}
+	url = strings.TrimSuffix(strings.Trim(url, "/"), ".git")
+	repo = path.Base(url)
+	org = path.Base(path.Dir(url))
+	if repo == "" || repo == "." || org == "" || org == "." || org == "/" {
+		return "", "", false
+	}
+	return org, repo, true
+}
+
+var invalidSegment = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// segment makes s a single namespace segment, e.g. feature/login becomes
+// feature-login.
+func segment(s string) string {
+	return strings.Trim(invalidSegment.ReplaceAllString(s, "-"), "-")
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
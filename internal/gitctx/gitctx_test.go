@@ -0,0 +1,53 @@
+package gitctx
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+)
+
+func TestParseRemote(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		url, org, repo string
+	}{
+		{"git@github.com:acme/ghost.git", "acme", "ghost"},
+		{"https://github.com/acme/ghost", "acme", "ghost"},
+		{"https://github.com/acme/ghost.git/", "acme", "ghost"},
+		{"ssh://git@gitlab.example.com:2222/group/sub/ghost.git", "sub", "ghost"},
+	}
+	for _, tc := range cases {
+		org, repo, ok := ParseRemote(tc.url)
+		if !ok || org != tc.org || repo != tc.repo {
+			t.Fatalf("ParseRemote(%q) = %q, %q, %v", tc.url, org, repo, ok)
+		}
+	}
+	if _, _, ok := ParseRemote("https://github.com/ghost"); ok {
+		t.Fatal("expected a remote without an owner to be rejected")
+	}
+}
+
+func TestDetect_UsesOriginAndBranch(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "--quiet", "-b", "feature/login"},
+		{"remote", "add", "upstream", "https://example.com/other/fork.git"},
+		{"remote", "add", "origin", "git@github.com:acme/ghost.git"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	info, err := Detect(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("Detect() error = %v", err)
+	}
+	if info != (Info{Org: "acme", Repo: "ghost", Branch: "feature-login"}) {
+		t.Fatalf("Detect() = %+v", info)
+	}
+}
@@ -0,0 +1,177 @@
+// Package ids generates identifiers for new memories.
+package ids
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Supported ID formats.
+const (
+	FormatUUID    = "uuid"
+	FormatULID    = "ulid"
+	FormatContent = "content"
+)
+
+// crockford is the ULID base32 alphabet.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var prefixExpr = regexp.MustCompile(`^[A-Za-z0-9_.]{1,16}$`)
+
+// Generator produces IDs in one configured format, optionally prefixed per
+// namespace.
+type Generator struct {
+	format   string
+	prefixes []namespacePrefix
+
+	mu      sync.Mutex
+	lastMS  uint64
+	lastRnd [10]byte
+}
+
+type namespacePrefix struct {
+	namespace string
+	prefix    string
+}
+
+// NewGenerator validates format and prefixes and returns a generator.
+// prefixes maps a namespace (or namespace ancestor such as "acme/repoA") to a
+// short label prepended to IDs as "<label>-<id>".
+func NewGenerator(format string, prefixes map[string]string) (*Generator, error) {
+	g := &Generator{}
+	switch format {
+	case "", FormatUUID:
+		g.format = FormatUUID
+	case FormatULID:
+		g.format = FormatULID
+	case FormatContent:
+		g.format = FormatContent
+	default:
+		return nil, fmt.Errorf("unknown id format %q (expected uuid, ulid or content)", format)
+	}
+
+	for ns, p := range prefixes {
+		ns = strings.Trim(strings.TrimSpace(ns), "/")
+		if ns == "" {
+			return nil, fmt.Errorf("id prefix %q has an empty namespace", p)
+		}
+		if !prefixExpr.MatchString(p) {
+			return nil, fmt.Errorf("invalid id prefix %q for namespace %q (1-16 of A-Z a-z 0-9 _ .)", p, ns)
+		}
+		g.prefixes = append(g.prefixes, namespacePrefix{namespace: ns, prefix: p})
+	}
+	// Longest namespace first so the most specific mapping wins.
+	sort.Slice(g.prefixes, func(i, j int) bool {
+		return len(g.prefixes[i].namespace) > len(g.prefixes[j].namespace)
+	})
+	return g, nil
+}
+
+// ContentAddressed reports whether IDs are derived from namespace and
+// content, so writing the same content twice yields the same ID.
+func (g *Generator) ContentAddressed() bool {
+	return g.format == FormatContent
+}
+
+// New returns an ID for a memory in namespace generated at t.
+func (g *Generator) New(namespace, content string, t time.Time) string {
+	var id string
+	switch g.format {
+	case FormatULID:
+		id = g.ulid(t)
+	case FormatContent:
+		id = contentID(namespace, content)
+	default:
+		id = uuid.NewString()
+	}
+	if p := g.prefixFor(namespace); p != "" {
+		return p + "-" + id
+	}
+	return id
+}
+
+func (g *Generator) prefixFor(namespace string) string {
+	for _, np := range g.prefixes {
+		if namespace == np.namespace || strings.HasPrefix(namespace, np.namespace+"/") {
+			return np.prefix
+		}
+	}
+	return ""
+}
+
+// contentID hashes namespace and content into a stable 128-bit hex ID.
+func contentID(namespace, content string) string {
+	h := sha256.New()
+	h.Write([]byte(namespace))
+	h.Write([]byte{0})
+	h.Write([]byte(content))
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// ulid returns a monotonic ULID: IDs minted in the same millisecond
+// increment the random component so they still sort in creation order.
+func (g *Generator) ulid(t time.Time) string {
+	ms := uint64(t.UnixMilli())
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if ms == g.lastMS && incrementBytes(g.lastRnd[:]) {
+		return encodeULID(ms, g.lastRnd)
+	}
+	if _, err := rand.Read(g.lastRnd[:]); err != nil {
+		panic(fmt.Sprintf("ids: read random: %v", err))
+	}
+	g.lastMS = ms
+	return encodeULID(ms, g.lastRnd)
+}
+
+// incrementBytes adds one to b as a big-endian integer and reports whether
+// it did so without overflowing.
+func incrementBytes(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeULID renders a 48-bit millisecond timestamp and 80 bits of entropy
+// as 26 Crockford base32 characters.
+func encodeULID(ms uint64, rnd [10]byte) string {
+	var raw [16]byte
+	raw[0] = byte(ms >> 40)
+	raw[1] = byte(ms >> 32)
+	raw[2] = byte(ms >> 24)
+	raw[3] = byte(ms >> 16)
+	raw[4] = byte(ms >> 8)
+	raw[5] = byte(ms)
+	copy(raw[6:], rnd[:])
+
+	// 128 bits are emitted as 26 5-bit groups; the first group carries the
+	// top 3 bits only.
+	var out [26]byte
+	var acc uint64
+	bits := 2 // pad so the 130-bit frame aligns to 5-bit groups
+	idx := 0
+	for _, b := range raw {
+		acc = acc<<8 | uint64(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			out[idx] = crockford[(acc>>uint(bits))&0x1f]
+			idx++
+		}
+	}
+	return string(out[:])
+}
@@ -0,0 +1,102 @@
+package ids
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestULID_SortsByCreationTime(t *testing.T) {
+	t.Parallel()
+	g, err := NewGenerator(FormatULID, nil)
+	if err != nil {
+		t.Fatalf("NewGenerator() error = %v", err)
+	}
+
+	base := time.Date(2026, 2, 17, 10, 0, 0, 0, time.UTC)
+	var got []string
+	for i := 0; i < 50; i++ {
+		// Several IDs per millisecond exercise the monotonic path.
+		got = append(got, g.New("org/repo", "", base.Add(time.Duration(i/5)*time.Millisecond)))
+	}
+	for _, id := range got {
+		if len(id) != 26 {
+			t.Fatalf("expected 26-char ULID, got %q", id)
+		}
+	}
+	if !sort.StringsAreSorted(got) {
+		t.Fatalf("expected ULIDs in creation order, got %v", got)
+	}
+}
+
+func TestEncodeULID_Timestamp(t *testing.T) {
+	t.Parallel()
+	if got := encodeULID(0, [10]byte{}); got != strings.Repeat("0", 26) {
+		t.Fatalf("encodeULID(0) = %q", got)
+	}
+	if got := encodeULID(1, [10]byte{}); got[:10] != "0000000001" {
+		t.Fatalf("expected timestamp prefix 0000000001, got %q", got[:10])
+	}
+}
+
+func TestNewGenerator_RejectsUnknownFormat(t *testing.T) {
+	t.Parallel()
+	if _, err := NewGenerator("snowflake", nil); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
+
+func TestGenerator_NamespacePrefixes(t *testing.T) {
+	t.Parallel()
+	g, err := NewGenerator(FormatULID, map[string]string{
+		"acme/repoA":      "repoA",
+		"acme/repoA/docs": "docsA",
+	})
+	if err != nil {
+		t.Fatalf("NewGenerator() error = %v", err)
+	}
+	now := time.Now()
+
+	cases := map[string]string{
+		"acme/repoA/main/build": "repoA-",
+		"acme/repoA/docs/intro": "docsA-",
+		"acme/repoAB/main":      "",
+	}
+	for ns, want := range cases {
+		id := g.New(ns, "", now)
+		if want == "" {
+			if strings.Contains(id, "-") {
+				t.Fatalf("expected unprefixed ID for %s, got %q", ns, id)
+			}
+			continue
+		}
+		if !strings.HasPrefix(id, want) {
+			t.Fatalf("expected prefix %q for %s, got %q", want, ns, id)
+		}
+	}
+
+	if _, err := NewGenerator(FormatUUID, map[string]string{"acme/repo": "bad prefix"}); err == nil {
+		t.Fatal("expected error for invalid prefix")
+	}
+}
+
+func TestGenerator_ContentAddressed(t *testing.T) {
+	t.Parallel()
+	g, err := NewGenerator(FormatContent, map[string]string{"acme/docs": "docs"})
+	if err != nil {
+		t.Fatalf("NewGenerator() error = %v", err)
+	}
+	a := g.New("acme/docs/intro", "default TTL is 48h", time.Now())
+	b := g.New("acme/docs/intro", "default TTL is 48h", time.Now().Add(time.Hour))
+	c := g.New("acme/docs/other", "default TTL is 48h", time.Now())
+	if a != b {
+		t.Fatalf("expected stable ID for identical content, got %q and %q", a, b)
+	}
+	if a == c {
+		t.Fatalf("expected namespace to change the ID, got %q twice", a)
+	}
+	if !strings.HasPrefix(a, "docs-") || len(a) != len("docs-")+32 {
+		t.Fatalf("unexpected content ID %q", a)
+	}
+}
@@ -0,0 +1,101 @@
+// Package lang detects the dominant language of memory text so the store
+// can pick an analyzer for it: stems for English, n-grams for CJK scripts.
+package lang
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Language codes returned by Detect, as ISO 639-1.
+const (
+	Undetermined = ""
+	English      = "en"
+	German       = "de"
+	French       = "fr"
+	Spanish      = "es"
+	Japanese     = "ja"
+	Chinese      = "zh"
+	Korean       = "ko"
+)
+
+// cjkWeight is how many Latin letters one CJK character counts for when
+// deciding which script dominates; a character carries roughly a word.
+const cjkWeight = 2
+
+// Detect returns the dominant language of text, or Undetermined when it has
+// no letters or they are of a script without a rule here.
+//
+// CJK text is told apart by script: any kana makes it Japanese, Hangul
+// Korean and Han alone Chinese. Latin text is matched against common
+// function words of each language; English wins ties, including text with
+// none, since short technical notes often have no function words.
+func Detect(text string) string {
+	var kana, han, hangul, latin int
+	for _, r := range text {
+		switch {
+		case r == 'ー' || unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+	cjk := kana + han + hangul
+	switch {
+	case cjk == 0 && latin == 0:
+		return Undetermined
+	case cjk*cjkWeight >= latin:
+		switch {
+		case hangul >= kana+han:
+			return Korean
+		case kana > 0:
+			return Japanese
+		default:
+			return Chinese
+		}
+	}
+	return detectLatin(text)
+}
+
+// functionWords are frequent, short words that mostly belong to one
+// language. Shared ones count for every language listing them.
+var functionWords = map[string][]string{
+	English: {"the", "and", "of", "to", "is", "in", "for", "on", "with", "that", "this", "it", "are", "be", "was", "not", "by", "as", "from", "or", "at", "we", "when", "should", "use", "before", "after"},
+	German:  {"der", "die", "das", "und", "ist", "nicht", "mit", "ein", "eine", "für", "auf", "wir", "sich", "den", "dem", "zu", "von", "auch", "wird", "bei"},
+	French:  {"le", "la", "les", "et", "est", "des", "une", "un", "du", "pour", "pas", "dans", "avec", "que", "qui", "sur", "nous", "ce", "sont", "au"},
+	Spanish: {"el", "la", "los", "las", "y", "es", "de", "que", "en", "una", "por", "para", "con", "no", "del", "se", "su", "al", "lo", "como"},
+}
+
+var latinOrder = []string{English, German, French, Spanish}
+
+var wordLanguages = func() map[string][]string {
+	m := map[string][]string{}
+	for _, code := range latinOrder {
+		for _, w := range functionWords[code] {
+			m[w] = append(m[w], code)
+		}
+	}
+	return m
+}()
+
+func detectLatin(text string) string {
+	hits := map[string]int{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		for _, code := range wordLanguages[w] {
+			hits[code]++
+		}
+	}
+	best := English
+	for _, code := range latinOrder {
+		if hits[code] > hits[best] {
+			best = code
+		}
+	}
+	return best
+}
@@ -0,0 +1,60 @@
+package lang
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+	cases := []struct{ text, want string }{
+		{"Deploy the billing service after the migration finishes.", English},
+		{"Rotate API keys quarterly", English},
+		{"Die Datenbank wird nicht mit dem Cluster synchronisiert.", German},
+		{"Les jobs de nuit sont bloqués dans la file pour le moment.", French},
+		{"El despliegue de la base de datos falla con un error.", Spanish},
+		{"本番環境のデプロイは金曜日に禁止", Japanese},
+		{"数据库迁移必须在周五之前完成", Chinese},
+		{"배포는 금요일에 금지됩니다", Korean},
+		{"Use kubectl to 部署服务到生产环境", Chinese},
+		{"12345 !!!", Undetermined},
+	}
+	for _, tc := range cases {
+		if got := Detect(tc.text); got != tc.want {
+			t.Fatalf("Detect(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestStem(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"caresses":       "caress",
+		"ponies":         "poni",
+		"cats":           "cat",
+		"agreed":         "agre",
+		"plastered":      "plaster",
+		"motoring":       "motor",
+		"sing":           "sing",
+		"conflated":      "conflat",
+		"sized":          "size",
+		"hopping":        "hop",
+		"falling":        "fall",
+		"filing":         "file",
+		"happy":          "happi",
+		"relational":     "relat",
+		"conditional":    "condit",
+		"rational":       "ration",
+		"digitizer":      "digit",
+		"hopefulness":    "hope",
+		"generalization": "gener",
+		"replacement":    "replac",
+		"adoption":       "adopt",
+		"controll":       "control",
+		"deployments":    "deploy",
+		"go":             "go",
+		"k8s":            "k8s",
+	}
+	for word, want := range cases {
+		if got := Stem(word); got != want {
+			t.Fatalf("Stem(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package lang
+
+import "strings"
+
+// Stem returns the Porter stem of a lower-case English word, so that
+// "deploying", "deployed" and "deploys" index alike. Words shorter than
+// three letters or with anything but a-z are returned unchanged.
+func Stem(word string) string {
+	if len(word) < 3 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+	w := step1a(word)
+	w = step1b(w)
+	w = step1c(w)
+	w = replaceFirst(w, step2Rules, 0)
+	w = replaceFirst(w, step3Rules, 0)
+	w = step4(w)
+	return step5(w)
+}
+
+// isCons reports whether w[i] is a consonant: not a vowel, and y only
+// after a vowel or at the start.
+func isCons(w string, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isCons(w, i-1)
+	}
+	return true
+}
+
+// measure counts the vowel-consonant sequences in w, Porter's m.
+func measure(w string) int {
+	n, i := 0, 0
+	for i < len(w) && isCons(w, i) {
+		i++
+	}
+	for i < len(w) {
+		for i < len(w) && !isCons(w, i) {
+			i++
+		}
+		if i == len(w) {
+			break
+		}
+		for i < len(w) && isCons(w, i) {
+			i++
+		}
+		n++
+	}
+	return n
+}
+
+func hasVowel(w string) bool {
+	for i := range len(w) {
+		if !isCons(w, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func endsDoubleCons(w string) bool {
+	n := len(w)
+	return n >= 2 && w[n-1] == w[n-2] && isCons(w, n-1)
+}
+
+// endsCVC reports a consonant-vowel-consonant ending whose last letter is
+// not w, x or y, as in "hop" but not "snow".
+func endsCVC(w string) bool {
+	n := len(w)
+	if n < 3 || !isCons(w, n-3) || isCons(w, n-2) || !isCons(w, n-1) {
+		return false
+	}
+	c := w[n-1]
+	return c != 'w' && c != 'x' && c != 'y'
+}
+
+func step1a(w string) string {
+	switch {
+	case strings.HasSuffix(w, "sses"), strings.HasSuffix(w, "ies"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(w, "ss"):
+		return w
+	case strings.HasSuffix(w, "s"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+func step1b(w string) string {
+	if stem, ok := strings.CutSuffix(w, "eed"); ok {
+		if measure(stem) > 0 {
+			return stem + "ee"
+		}
+		return w
+	}
+	stem, ok := strings.CutSuffix(w, "ed")
+	if !ok {
+		stem, ok = strings.CutSuffix(w, "ing")
+	}
+	if !ok || !hasVowel(stem) {
+		return w
+	}
+	switch {
+	case strings.HasSuffix(stem, "at"), strings.HasSuffix(stem, "bl"), strings.HasSuffix(stem, "iz"):
+		return stem + "e"
+	case endsDoubleCons(stem):
+		if c := stem[len(stem)-1]; c != 'l' && c != 's' && c != 'z' {
+			return stem[:len(stem)-1]
+		}
+	case measure(stem) == 1 && endsCVC(stem):
+		return stem + "e"
+	}
+	return stem
+}
+
+func step1c(w string) string {
+	if stem, ok := strings.CutSuffix(w, "y"); ok && hasVowel(stem) {
+		return stem + "i"
+	}
+	return w
+}
+
+type suffixRule struct{ suffix, repl string }
+
+var step2Rules = []suffixRule{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+	{"izer", "ize"}, {"abli", "able"}, {"alli", "al"}, {"entli", "ent"},
+	{"eli", "e"}, {"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"},
+	{"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"},
+	{"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+}
+
+var step3Rules = []suffixRule{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+	{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+// replaceFirst applies the first rule whose suffix w ends with, if the
+// remaining stem measures more than minMeasure. Later rules are not tried.
+func replaceFirst(w string, rules []suffixRule, minMeasure int) string {
+	for _, r := range rules {
+		if stem, ok := strings.CutSuffix(w, r.suffix); ok {
+			if measure(stem) > minMeasure {
+				return stem + r.repl
+			}
+			return w
+		}
+	}
+	return w
+}
+
+var step4Suffixes = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment",
+	"ent", "ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+func step4(w string) string {
+	for _, suffix := range step4Suffixes {
+		stem, ok := strings.CutSuffix(w, suffix)
+		if !ok {
+			continue
+		}
+		if suffix == "ion" && !strings.HasSuffix(stem, "s") && !strings.HasSuffix(stem, "t") {
+			continue
+		}
+		if measure(stem) > 1 {
+			return stem
+		}
+		return w
+	}
+	return w
+}
+
+func step5(w string) string {
+	if stem, ok := strings.CutSuffix(w, "e"); ok {
+		if m := measure(stem); m > 1 || m == 1 && !endsCVC(stem) {
+			w = stem
+		}
+	}
+	if strings.HasSuffix(w, "ll") && measure(w) > 1 {
+		w = w[:len(w)-1]
+	}
+	return w
+}
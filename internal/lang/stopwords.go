@@ -0,0 +1,41 @@
+package lang
+
+import (
+	"strings"
+
+	"github.com/xiy/memory-mcp/internal/textnorm"
+)
+
+// EnglishStopWords are words too common in questions to narrow a search,
+// such as the "what is the" of "what is the deployment process".
+var EnglishStopWords = []string{
+	"a", "about", "an", "and", "are", "as", "at", "be", "but", "by", "can",
+	"could", "did", "do", "does", "for", "from", "had", "has", "have", "how",
+	"i", "if", "in", "into", "is", "it", "its", "me", "my", "of", "on", "or",
+	"our", "should", "so", "than", "that", "the", "their", "them", "then",
+	"there", "these", "they", "this", "those", "to", "was", "we", "were",
+	"what", "when", "where", "which", "who", "why", "will", "with", "would",
+	"you", "your",
+}
+
+// StopWords is a set of folded words that queries drop.
+type StopWords map[string]struct{}
+
+// NewStopWords folds the words of every list into one set.
+func NewStopWords(lists ...[]string) StopWords {
+	sw := StopWords{}
+	for _, list := range lists {
+		for _, w := range list {
+			if w = textnorm.Fold(strings.TrimSpace(w)); w != "" {
+				sw[w] = struct{}{}
+			}
+		}
+	}
+	return sw
+}
+
+// Has reports whether the folded word is a stop word.
+func (sw StopWords) Has(word string) bool {
+	_, ok := sw[word]
+	return ok
+}
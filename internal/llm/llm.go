@@ -0,0 +1,117 @@
+// Package llm is a minimal client for OpenAI-compatible chat completion
+// endpoints, used by the optional answer-synthesis features.
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/config"
+)
+
+// Message is one chat message.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Client completes a chat conversation.
+type Client interface {
+	Complete(ctx context.Context, messages []Message) (string, error)
+}
+
+// HTTPClient calls POST <endpoint>/chat/completions.
+type HTTPClient struct {
+	endpoint  string
+	model     string
+	apiKey    string
+	maxTokens int
+	http      *http.Client
+}
+
+// New returns a client for cfg, or nil when no endpoint is configured. The
+// API key is read from the environment variable named by cfg.APIKeyEnv.
+func New(cfg config.LLMConfig) *HTTPClient {
+	if strings.TrimSpace(cfg.Endpoint) == "" {
+		return nil
+	}
+	var key string
+	if cfg.APIKeyEnv != "" {
+		key = os.Getenv(cfg.APIKeyEnv)
+	}
+	return &HTTPClient{
+		endpoint:  strings.TrimRight(cfg.Endpoint, "/"),
+		model:     cfg.Model,
+		apiKey:    key,
+		maxTokens: cfg.MaxTokens,
+		http:      &http.Client{Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second},
+	}
+}
+
+type chatRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Temperature float64   `json:"temperature"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message Message `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Complete returns the first choice's message content.
+func (c *HTTPClient) Complete(ctx context.Context, messages []Message) (string, error) {
+	body, err := json.Marshal(chatRequest{Model: c.model, Messages: messages, MaxTokens: c.maxTokens})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("build llm request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("llm request: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("read llm response: %w", err)
+	}
+
+	var out chatResponse
+	if err := json.Unmarshal(raw, &out); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("llm request: %s", resp.Status)
+		}
+		return "", fmt.Errorf("decode llm response: %w", err)
+	}
+	if out.Error != nil {
+		return "", fmt.Errorf("llm request: %s: %s", resp.Status, out.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("llm request: %s", resp.Status)
+	}
+	if len(out.Choices) == 0 {
+		return "", errors.New("llm response has no choices")
+	}
+	return strings.TrimSpace(out.Choices[0].Message.Content), nil
+}
@@ -0,0 +1,42 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/xiy/memory-mcp/internal/config"
+)
+
+func TestHTTPClient_Complete(t *testing.T) {
+	t.Setenv("TEST_LLM_KEY", "secret")
+	var got chatRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, `{"error":{"message":"bad request"}}`, http.StatusBadRequest)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":" 48 hours [m-1] "}}]}`))
+	}))
+	defer srv.Close()
+
+	c := New(config.LLMConfig{Endpoint: srv.URL + "/v1/", Model: "small", APIKeyEnv: "TEST_LLM_KEY", TimeoutSeconds: 5, MaxTokens: 64})
+	answer, err := c.Complete(context.Background(), []Message{{Role: "user", Content: "ttl?"}})
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if answer != "48 hours [m-1]" || got.Model != "small" || got.MaxTokens != 64 || len(got.Messages) != 1 {
+		t.Fatalf("unexpected exchange: answer=%q request=%+v", answer, got)
+	}
+
+	bad := New(config.LLMConfig{Endpoint: srv.URL, TimeoutSeconds: 5})
+	if _, err := bad.Complete(context.Background(), nil); err == nil {
+		t.Fatal("expected error response to fail")
+	}
+	if New(config.LLMConfig{}) != nil {
+		t.Fatal("expected nil client without an endpoint")
+	}
+}
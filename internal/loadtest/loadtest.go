@@ -0,0 +1,460 @@
+// Package loadtest drives a running memory-mcp server with concurrent tool
+// calls and reports latency percentiles and error rates per tool.
+package loadtest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/bench"
+)
+
+// Tool names accepted in a mix, keyed by their short form.
+var mixTools = map[string]string{
+	"write":  "memory_write",
+	"search": "memory_search",
+	"pack":   "memory_get_context_pack",
+}
+
+// Dialer opens one client connection to the target server. Each worker
+// dials its own connection, mirroring one agent per MCP session.
+type Dialer func(ctx context.Context) (io.ReadWriteCloser, error)
+
+// Options control a load-test run.
+type Options struct {
+	Dial Dialer
+	// Concurrency is the number of workers, each with its own connection.
+	Concurrency int
+	// Requests caps the total number of tool calls; zero means run for
+	// Duration instead.
+	Requests int
+	Duration time.Duration
+	// Mix weights tool calls by short name (write, search, pack).
+	Mix       map[string]int
+	Namespace string
+	Seed      int64
+}
+
+// ToolReport summarizes calls to one tool.
+type ToolReport struct {
+	Tool    string        `json:"tool"`
+	Calls   int           `json:"calls"`
+	Errors  int           `json:"errors"`
+	Latency bench.Latency `json:"latency"`
+}
+
+// ErrorRate is the fraction of calls that failed.
+func (t ToolReport) ErrorRate() float64 {
+	if t.Calls == 0 {
+		return 0
+	}
+	return float64(t.Errors) / float64(t.Calls)
+}
+
+// Report is the result of Run.
+type Report struct {
+	Concurrency int           `json:"concurrency"`
+	Elapsed     time.Duration `json:"elapsed_ns"`
+	Tools       []ToolReport  `json:"tools"`
+	Total       ToolReport    `json:"total"`
+}
+
+// ParseMix parses a tool mix such as "write=1,search=3,pack=1".
+func ParseMix(s string) (map[string]int, error) {
+	mix := map[string]int{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, weight, ok := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if !ok {
+			return nil, fmt.Errorf("invalid mix entry %q (expected tool=weight)", part)
+		}
+		if _, known := mixTools[name]; !known {
+			return nil, fmt.Errorf("unknown mix tool %q (expected write, search or pack)", name)
+		}
+		w, err := strconv.Atoi(strings.TrimSpace(weight))
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight for %s: %q", name, weight)
+		}
+		mix[name] = w
+	}
+	return mix, nil
+}
+
+// StdioDialer launches argv once per connection and speaks MCP over its
+// stdin/stdout. The subprocess exits when the connection is closed.
+func StdioDialer(argv []string) Dialer {
+	return func(ctx context.Context) (io.ReadWriteCloser, error) {
+		if len(argv) == 0 {
+			return nil, errors.New("empty server command")
+		}
+		cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+		stderr := &bytes.Buffer{}
+		cmd.Stderr = stderr
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("start %s: %w", argv[0], err)
+		}
+		return &stdioConn{Reader: stdout, stdin: stdin, cmd: cmd, stderr: stderr}, nil
+	}
+}
+
+type stdioConn struct {
+	io.Reader
+	stdin  io.WriteCloser
+	cmd    *exec.Cmd
+	stderr *bytes.Buffer
+}
+
+func (c *stdioConn) Write(p []byte) (int, error) { return c.stdin.Write(p) }
+
+// Close ends the session and waits for the subprocess. A failed exit is
+// reported with the last line the server logged.
+func (c *stdioConn) Close() error {
+	_ = c.stdin.Close()
+	err := c.cmd.Wait()
+	if err == nil {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSpace(c.stderr.String()), "\n")
+	if last := lines[len(lines)-1]; last != "" {
+		return fmt.Errorf("%w: %s", err, last)
+	}
+	return err
+}
+
+// SocketDialer connects to addr, either "unix:<path>", an absolute socket
+// path, or a TCP "host:port".
+func SocketDialer(addr string) Dialer {
+	network := "tcp"
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		network, addr = "unix", path
+	} else if strings.HasPrefix(addr, "/") {
+		network = "unix"
+	}
+	return func(ctx context.Context) (io.ReadWriteCloser, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, addr)
+	}
+}
+
+// Run starts the workers, waits for them to finish and aggregates their
+// samples. Tool-level failures (isError results, JSON-RPC errors) count
+// towards error rates; a broken connection stops its worker and is returned
+// alongside the partial report.
+func Run(ctx context.Context, opts Options) (Report, error) {
+	if opts.Dial == nil {
+		return Report{}, errors.New("no dialer configured")
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.Requests <= 0 && opts.Duration <= 0 {
+		opts.Duration = 10 * time.Second
+	}
+	if len(opts.Mix) == 0 {
+		opts.Mix = map[string]int{"write": 1, "search": 3, "pack": 1}
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = "loadtest/repo/main/load"
+	}
+	if opts.Seed == 0 {
+		opts.Seed = 1
+	}
+	picker, err := newPicker(opts.Mix)
+	if err != nil {
+		return Report{}, err
+	}
+
+	runCtx := ctx
+	if opts.Requests <= 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	var (
+		issued  atomic.Int64
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		samples = map[string]*toolSamples{}
+		errs    []error
+	)
+	started := time.Now()
+	for w := 0; w < opts.Concurrency; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			local, err := runWorker(runCtx, opts, picker, rand.New(rand.NewSource(opts.Seed+int64(w))), w, func() bool {
+				return opts.Requests <= 0 || issued.Add(1) <= int64(opts.Requests)
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			for tool, s := range local {
+				agg := samples[tool]
+				if agg == nil {
+					agg = &toolSamples{}
+					samples[tool] = agg
+				}
+				agg.latencies = append(agg.latencies, s.latencies...)
+				agg.errors += s.errors
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("worker %d: %w", w, err))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	rep := Report{Concurrency: opts.Concurrency, Elapsed: time.Since(started)}
+	var all []time.Duration
+	for tool, s := range samples {
+		all = append(all, s.latencies...)
+		rep.Tools = append(rep.Tools, ToolReport{
+			Tool:    tool,
+			Calls:   len(s.latencies),
+			Errors:  s.errors,
+			Latency: bench.Summarize(s.latencies),
+		})
+		rep.Total.Errors += s.errors
+	}
+	sort.Slice(rep.Tools, func(i, j int) bool { return rep.Tools[i].Tool < rep.Tools[j].Tool })
+	rep.Total.Tool = "total"
+	rep.Total.Calls = len(all)
+	rep.Total.Latency = bench.Summarize(all)
+	return rep, errors.Join(errs...)
+}
+
+type toolSamples struct {
+	latencies []time.Duration
+	errors    int
+}
+
+func runWorker(ctx context.Context, opts Options, pick *picker, rng *rand.Rand, worker int, next func() bool) (map[string]*toolSamples, error) {
+	out := map[string]*toolSamples{}
+	rwc, err := opts.Dial(ctx)
+	if err != nil {
+		return out, err
+	}
+	c := newClient(rwc)
+	err = c.drive(ctx, opts, pick, rng, worker, next, out)
+	if cerr := c.Close(); err != nil && cerr != nil {
+		err = fmt.Errorf("%w (%v)", err, cerr)
+	}
+	return out, err
+}
+
+func (c *client) drive(ctx context.Context, opts Options, pick *picker, rng *rand.Rand, worker int, next func() bool, out map[string]*toolSamples) error {
+	if err := c.initialize(); err != nil {
+		return err
+	}
+	for i := 0; ctx.Err() == nil && next(); i++ {
+		tool := pick.pick(rng)
+		args := toolArguments(tool, opts.Namespace, rng, worker, i)
+		start := time.Now()
+		toolErr, err := c.callTool(tool, args)
+		elapsed := time.Since(start)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		s := out[tool]
+		if s == nil {
+			s = &toolSamples{}
+			out[tool] = s
+		}
+		s.latencies = append(s.latencies, elapsed)
+		if toolErr {
+			s.errors++
+		}
+	}
+	return nil
+}
+
+func toolArguments(tool, namespace string, rng *rand.Rand, worker, i int) map[string]any {
+	switch tool {
+	case "memory_write":
+		return map[string]any{
+			"namespace":  namespace,
+			"content":    bench.SyntheticContent(rng, worker*1_000_000+i),
+			"importance": 1 + rng.Intn(5),
+		}
+	case "memory_get_context_pack":
+		return map[string]any{"namespace": namespace, "query": bench.SyntheticQuery(rng), "token_budget": 512}
+	default:
+		return map[string]any{"namespace": namespace, "query": bench.SyntheticQuery(rng)}
+	}
+}
+
+// picker chooses tools in proportion to their mix weights.
+type picker struct {
+	tools   []string
+	cumul   []int
+	totalWt int
+}
+
+func newPicker(mix map[string]int) (*picker, error) {
+	names := make([]string, 0, len(mix))
+	for name := range mix {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	p := &picker{}
+	for _, name := range names {
+		tool, ok := mixTools[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown mix tool %q", name)
+		}
+		if mix[name] <= 0 {
+			continue
+		}
+		p.totalWt += mix[name]
+		p.tools = append(p.tools, tool)
+		p.cumul = append(p.cumul, p.totalWt)
+	}
+	if p.totalWt == 0 {
+		return nil, errors.New("tool mix has no positive weights")
+	}
+	return p, nil
+}
+
+func (p *picker) pick(rng *rand.Rand) string {
+	n := rng.Intn(p.totalWt)
+	idx := sort.SearchInts(p.cumul, n+1)
+	return p.tools[idx]
+}
+
+// client is a minimal sequential JSON-line MCP client.
+type client struct {
+	rwc    io.ReadWriteCloser
+	r      *bufio.Reader
+	nextID int
+}
+
+func newClient(rwc io.ReadWriteCloser) *client {
+	return &client{rwc: rwc, r: bufio.NewReader(rwc)}
+}
+
+func (c *client) Close() error { return c.rwc.Close() }
+
+func (c *client) initialize() error {
+	if _, err := c.call("initialize", map[string]any{
+		"protocolVersion": "2024-11-05",
+		"clientInfo":      map[string]any{"name": "memory-mcp-loadtest", "version": "0.1.0"},
+	}); err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+	return c.notify("notifications/initialized")
+}
+
+// callTool reports whether the server returned a tool or protocol error;
+// err is only set when the connection itself failed.
+func (c *client) callTool(name string, args map[string]any) (bool, error) {
+	resp, err := c.call("tools/call", map[string]any{"name": name, "arguments": args})
+	if err != nil {
+		return false, err
+	}
+	if resp.Error != nil {
+		return true, nil
+	}
+	var result struct {
+		IsError bool `json:"isError"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return true, nil
+	}
+	return result.IsError, nil
+}
+
+type rpcResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *client) call(method string, params any) (rpcResponse, error) {
+	c.nextID++
+	if err := c.send(map[string]any{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params}); err != nil {
+		return rpcResponse{}, err
+	}
+	line, err := c.r.ReadBytes('\n')
+	if err != nil {
+		return rpcResponse{}, fmt.Errorf("read response: %w", err)
+	}
+	var resp rpcResponse
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return rpcResponse{}, fmt.Errorf("decode response: %w", err)
+	}
+	if string(resp.ID) != strconv.Itoa(c.nextID) {
+		return rpcResponse{}, fmt.Errorf("response id %s does not match request %d", resp.ID, c.nextID)
+	}
+	return resp, nil
+}
+
+func (c *client) notify(method string) error {
+	return c.send(map[string]any{"jsonrpc": "2.0", "method": method})
+}
+
+func (c *client) send(msg map[string]any) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = c.rwc.Write(append(b, '\n'))
+	return err
+}
+
+// Write renders the report as an aligned table.
+func (r Report) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "tool\tcalls\terrors\terror %\tp50\tp95\tp99")
+	for _, t := range append(r.Tools, r.Total) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f\t%s\t%s\t%s\n",
+			t.Tool, t.Calls, t.Errors, 100*t.ErrorRate(),
+			round(t.Latency.P50), round(t.Latency.P95), round(t.Latency.P99),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	rate := 0.0
+	if r.Elapsed > 0 {
+		rate = float64(r.Total.Calls) / r.Elapsed.Seconds()
+	}
+	_, err := fmt.Fprintf(w, "\n%d workers, %s elapsed, %.0f calls/s\n", r.Concurrency, r.Elapsed.Round(time.Millisecond), rate)
+	return err
+}
+
+func round(d time.Duration) string {
+	return d.Round(time.Microsecond).String()
+}
@@ -0,0 +1,79 @@
+package loadtest
+
+import (
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/mcp"
+	"github.com/xiy/memory-mcp/internal/memory"
+	"github.com/xiy/memory-mcp/internal/store"
+)
+
+func TestParseMix(t *testing.T) {
+	t.Parallel()
+	mix, err := ParseMix("write=1, search=3,pack=0")
+	if err != nil {
+		t.Fatalf("ParseMix() error = %v", err)
+	}
+	if mix["write"] != 1 || mix["search"] != 3 || mix["pack"] != 0 {
+		t.Fatalf("unexpected mix %v", mix)
+	}
+	for _, bad := range []string{"promote=1", "write", "search=-1"} {
+		if _, err := ParseMix(bad); err == nil {
+			t.Fatalf("ParseMix(%q) expected error", bad)
+		}
+	}
+}
+
+func TestRun_InProcessServer(t *testing.T) {
+	t.Parallel()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	ctx := context.Background()
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "load.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	svc, err := memory.NewService(st, config.Default(), logger)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	srv := mcp.NewServer(svc, logger, nil)
+
+	dial := func(ctx context.Context) (io.ReadWriteCloser, error) {
+		client, server := net.Pipe()
+		go func() {
+			_ = srv.Serve(ctx, server, server)
+			server.Close()
+		}()
+		return client, nil
+	}
+
+	rep, err := Run(ctx, Options{
+		Dial:        dial,
+		Concurrency: 3,
+		Requests:    60,
+		Mix:         map[string]int{"write": 1, "search": 1, "pack": 1},
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if rep.Total.Calls != 60 {
+		t.Fatalf("expected 60 calls, got %d", rep.Total.Calls)
+	}
+	if rep.Total.Errors != 0 {
+		t.Fatalf("expected no tool errors, got %+v", rep.Tools)
+	}
+	if len(rep.Tools) != 3 {
+		t.Fatalf("expected all three tools exercised, got %+v", rep.Tools)
+	}
+	if rep.Total.Latency.P99 < rep.Total.Latency.P50 {
+		t.Fatalf("p99 below p50: %+v", rep.Total.Latency)
+	}
+}
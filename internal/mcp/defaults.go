@@ -0,0 +1,93 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// WithDefaultNamespace fills in namespace for tools that require one when
+// a call leaves it out or empty.
+func WithDefaultNamespace(namespace string) ServerOption {
+	return func(s *Server) {
+		s.namespace = namespace
+	}
+}
+
+// applyDefaults fills in arguments a call left out or empty: the default
+// namespace for tools that require one, and source_agent from the
+// client's name. The note describes a namespace substitution for the tool
+// response.
+func (s *Server) applyDefaults(ctx context.Context, tool string, args json.RawMessage) (json.RawMessage, string, error) {
+	setNamespace := s.namespace != "" && requiresNamespace(tool)
+	agent := s.clientName(ctx)
+	setAgent := agent != "" && hasArgument(tool, "source_agent")
+	if !setNamespace && !setAgent {
+		return args, "", nil
+	}
+	in := map[string]any{}
+	if len(args) > 0 && string(args) != "null" {
+		if err := json.Unmarshal(args, &in); err != nil {
+			return nil, "", fmt.Errorf("invalid %s arguments: %w", tool, err)
+		}
+	}
+	changed, note := false, ""
+	if setNamespace && isBlank(in["namespace"]) {
+		in["namespace"] = s.namespace
+		changed = true
+		note = fmt.Sprintf("namespace was not given; used the server's default_namespace %q", s.namespace)
+	}
+	if setAgent && isBlank(in["source_agent"]) {
+		in["source_agent"] = agent
+		changed = true
+	}
+	if !changed {
+		return args, "", nil
+	}
+	out, err := json.Marshal(in)
+	if err != nil {
+		return nil, "", err
+	}
+	return out, note, nil
+}
+
+func isBlank(v any) bool {
+	str, _ := v.(string)
+	return strings.TrimSpace(str) == ""
+}
+
+func hasArgument(tool, name string) bool {
+	for _, def := range toolDefinitions() {
+		if def.Name == tool {
+			props, _ := def.InputSchema["properties"].(map[string]any)
+			_, ok := props[name]
+			return ok
+		}
+	}
+	return false
+}
+
+func requiresNamespace(tool string) bool {
+	for _, def := range toolDefinitions() {
+		if def.Name == tool {
+			required, _ := def.InputSchema["required"].([]string)
+			return slices.Contains(required, "namespace")
+		}
+	}
+	return false
+}
+
+// optionalNamespace drops namespace from def's required arguments and
+// documents the default, so clients let agents omit it.
+func optionalNamespace(def ToolDefinition, namespace string) {
+	required, _ := def.InputSchema["required"].([]string)
+	if !slices.Contains(required, "namespace") {
+		return
+	}
+	def.InputSchema["required"] = slices.DeleteFunc(slices.Clone(required), func(r string) bool { return r == "namespace" })
+	if props, ok := def.InputSchema["properties"].(map[string]any); ok {
+		props["namespace"] = propString(fmt.Sprintf("Namespace key (defaults to %s).", namespace))
+	}
+}
@@ -0,0 +1,32 @@
+package mcp
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/xiy/memory-mcp/internal/config"
+)
+
+// RenderInstructions executes cfg.Instructions with the server name,
+// namespace pattern and exposed tool names.
+func RenderInstructions(cfg config.Config) (string, error) {
+	tmpl, err := template.New("instructions").Funcs(template.FuncMap{"tool": cfg.Tools.ToolName}).Parse(cfg.Instructions)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, struct {
+		ServerName       string
+		NamespacePattern string
+	}{cfg.ServerName, cfg.NamespacePattern}); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// WithInstructions sets the instructions returned from initialize.
+func WithInstructions(text string) ServerOption {
+	return func(s *Server) {
+		s.instructions = text
+	}
+}
@@ -0,0 +1,144 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// maxPendingPings bounds unanswered pings remembered for round-trip times.
+const maxPendingPings = 16
+
+// KeepaliveStats describes server-initiated pings and how the client
+// answers them.
+type KeepaliveStats struct {
+	IntervalSeconds float64   `json:"interval_seconds"`
+	Sent            uint64    `json:"sent"`
+	Answered        uint64    `json:"answered"`
+	Outstanding     int       `json:"outstanding"`
+	LastRTTMS       int64     `json:"last_rtt_ms"`
+	LastAnsweredAt  time.Time `json:"last_answered_at"`
+	// Unresponsive is set once two consecutive pings go unanswered and
+	// cleared by the next answer.
+	Unresponsive bool `json:"unresponsive"`
+}
+
+type keepaliveState struct {
+	next         uint64
+	sent         uint64
+	answered     uint64
+	pending      map[string]time.Time
+	lastRTT      time.Duration
+	lastAnswered time.Time
+	unresponsive bool
+}
+
+type pingRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      string `json:"id"`
+	Method  string `json:"method"`
+}
+
+// WithKeepalive sends a ping request to the client after every interval
+// without client requests, so clients that treat a silent stdio session
+// as hung keep it alive. Zero disables pings.
+func WithKeepalive(interval time.Duration) ServerOption {
+	return func(s *Server) {
+		s.keepalive = max(interval, 0)
+	}
+}
+
+func (s *Server) runKeepalive(ctx context.Context, w *wire) {
+	ticker := time.NewTicker(s.keepalive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if now.Sub(time.Unix(0, s.lastRequest.Load())) < s.keepalive {
+				continue
+			}
+			id := s.notePing(now)
+			sent, err := w.writeInClientMode(pingRequest{JSONRPC: jsonRPCVersion, ID: id, Method: "ping"})
+			if err != nil {
+				s.logger.Warn("keepalive ping failed", "error", err)
+				return
+			}
+			if !sent {
+				s.forgetPing(id)
+			}
+		}
+	}
+}
+
+func (s *Server) notePing(now time.Time) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := &s.pings
+	if k.pending == nil {
+		k.pending = map[string]time.Time{}
+	}
+	k.next++
+	k.sent++
+	id := fmt.Sprintf("memory-mcp-ping-%d", k.next)
+	k.pending[id] = now
+	if len(k.pending) > maxPendingPings {
+		delete(k.pending, fmt.Sprintf("memory-mcp-ping-%d", k.next-maxPendingPings))
+	}
+	if len(k.pending) >= 3 && !k.unresponsive {
+		// Two earlier pings are still unanswered.
+		k.unresponsive = true
+		s.logger.Warn("client is not answering keepalive pings", "client", s.client.Name, "unanswered", len(k.pending)-1)
+	}
+	return id
+}
+
+func (s *Server) forgetPing(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pings.pending, id)
+	s.pings.sent--
+}
+
+func (s *Server) handlePong(rawID json.RawMessage) {
+	var id string
+	if err := json.Unmarshal(rawID, &id); err != nil {
+		return
+	}
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := &s.pings
+	sentAt, ok := k.pending[id]
+	if !ok {
+		return
+	}
+	delete(k.pending, id)
+	k.answered++
+	k.lastRTT = now.Sub(sentAt)
+	k.lastAnswered = now.UTC()
+	k.unresponsive = false
+}
+
+// keepaliveStats must be called with s.mu held.
+func (s *Server) keepaliveStats(now time.Time) KeepaliveStats {
+	k := s.pings
+	outstanding := 0
+	for _, sentAt := range k.pending {
+		// The newest ping may simply not be answered yet.
+		if now.Sub(sentAt) >= s.keepalive {
+			outstanding++
+		}
+	}
+	return KeepaliveStats{
+		IntervalSeconds: s.keepalive.Seconds(),
+		Sent:            k.sent,
+		Answered:        k.answered,
+		Outstanding:     outstanding,
+		LastRTTMS:       k.lastRTT.Milliseconds(),
+		LastAnsweredAt:  k.lastAnswered,
+		Unresponsive:    k.unresponsive,
+	}
+}
@@ -0,0 +1,89 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SocketAddr splits addr into a network and address for net.Listen and
+// net.Dial: "unix:<path>" or an absolute path is a unix socket, anything
+// else a TCP "host:port".
+func SocketAddr(addr string) (network, address string) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		return "unix", path
+	}
+	if strings.HasPrefix(addr, "/") {
+		return "unix", addr
+	}
+	return "tcp", addr
+}
+
+// Listen opens addr (see SocketAddr) for ServeListener. A unix socket is
+// only readable by the current user, and one left behind by a server that
+// did not shut down cleanly is replaced.
+func Listen(addr string) (net.Listener, error) {
+	network, address := SocketAddr(addr)
+	if network == "unix" {
+		if err := os.MkdirAll(filepath.Dir(address), 0o700); err != nil {
+			return nil, fmt.Errorf("create socket dir: %w", err)
+		}
+		if _, err := os.Stat(address); err == nil {
+			if c, err := net.DialTimeout("unix", address, time.Second); err == nil {
+				c.Close()
+				return nil, fmt.Errorf("%s is in use by another server", address)
+			}
+			if err := os.Remove(address); err != nil {
+				return nil, fmt.Errorf("remove stale socket: %w", err)
+			}
+		}
+	}
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if network == "unix" {
+		if err := os.Chmod(address, 0o600); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
+
+// ServeListener runs a Serve session for every connection accepted on ln,
+// so several clients share one server, store and cache. It returns when
+// ctx is done, after closing ln and the open connections.
+func (s *Server) ServeListener(ctx context.Context, ln net.Listener) error {
+	var wg sync.WaitGroup
+	stop := context.AfterFunc(ctx, func() { ln.Close() })
+	defer stop()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				wg.Wait()
+				return ctx.Err()
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		wg.Go(func() {
+			defer conn.Close()
+			closeConn := context.AfterFunc(ctx, func() { conn.Close() })
+			defer closeConn()
+			s.logger.Debug("socket session opened", "remote", conn.RemoteAddr())
+			if err := s.Serve(ctx, conn, conn); err != nil && ctx.Err() == nil {
+				s.logger.Warn("socket session ended", "error", err)
+			}
+		})
+	}
+}
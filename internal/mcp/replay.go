@@ -0,0 +1,65 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+)
+
+// maxLoggedParams bounds the params stored with a request log; larger
+// calls are logged without params and cannot be replayed.
+const maxLoggedParams = 64 << 10
+
+// secretKey matches argument names whose values are never logged, such as
+// api_key or access_token but not token_budget.
+var secretKey = regexp.MustCompile(`(?i)(^|[_-])(secret|token|password|passwd|api_?key|authorization|credentials?)$`)
+
+// sanitizeParams returns tools/call params as JSON for the request log,
+// with secret-looking arguments redacted, or "" when they are not worth
+// keeping.
+func sanitizeParams(method string, params json.RawMessage) string {
+	if method != "tools/call" || len(params) == 0 || len(params) > maxLoggedParams {
+		return ""
+	}
+	var v any
+	if err := json.Unmarshal(params, &v); err != nil {
+		return ""
+	}
+	out, err := json.Marshal(redact(v))
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+func redact(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			if secretKey.MatchString(k) {
+				t[k] = "[redacted]"
+				continue
+			}
+			t[k] = redact(val)
+		}
+	case []any:
+		for i, val := range t {
+			t[i] = redact(val)
+		}
+	}
+	return v
+}
+
+// WithClient presets the client identity, as if sent in initialize. Used
+// to replay a logged call as the client that made it.
+func WithClient(info ClientInfo) ServerOption {
+	return func(s *Server) {
+		s.client = info
+	}
+}
+
+// CallTool executes tools/call params outside a session, applying the same
+// name resolution, tool filters and argument defaults as Serve.
+func (s *Server) CallTool(ctx context.Context, params json.RawMessage) (map[string]any, error) {
+	return s.handleToolCall(ctx, params)
+}
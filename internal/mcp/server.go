@@ -8,46 +8,120 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 	"unicode"
 
 	"github.com/charmbracelet/log"
 
+	"github.com/xiy/memory-mcp/internal/auth"
+	"github.com/xiy/memory-mcp/internal/cache"
 	"github.com/xiy/memory-mcp/internal/memory"
 	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/internal/transcript"
 	"github.com/xiy/memory-mcp/pkg/types"
 )
 
 const jsonRPCVersion = "2.0"
 
+// ServerVersion is the version reported in initialize and on traces.
+const ServerVersion = "0.1.0"
+
 // Server handles MCP JSON-RPC messages over stdio.
 type Server struct {
 	svc    *memory.Service
 	logger *log.Logger
 	sink   RequestLogSink
 
-	requests uint64
-	errors   uint64
+	requests     uint64
+	errors       uint64
+	startedAt    time.Time
+	lastRequest  atomic.Int64
+	keepalive    time.Duration
+	transport    string
+	instructions string
+	disabled     map[string]bool
+	exposed      map[string]string
+	canonical    map[string]string
+	namespace    string
+	recorder     *transcript.Writer
+	auth         *auth.Authenticator
+	apiKey       string
+
+	mu       sync.Mutex
+	client   ClientInfo
+	calls    map[uint64]ToolCall
+	nextCall uint64
+	pings    keepaliveState
+}
+
+// ClientInfo identifies the connected MCP client as sent in initialize.
+type ClientInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// ToolCall is a tool call that is still executing.
+type ToolCall struct {
+	Tool      string    `json:"tool"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// Snapshot is a point-in-time view of a running server's in-memory state.
+type Snapshot struct {
+	PID         int            `json:"pid"`
+	StartedAt   time.Time      `json:"started_at"`
+	Requests    uint64         `json:"requests"`
+	Errors      uint64         `json:"errors"`
+	SearchCache cache.Stats    `json:"search_cache"`
+	PackCache   cache.Stats    `json:"pack_cache"`
+	Client      ClientInfo     `json:"client"`
+	InFlight    []ToolCall     `json:"in_flight"`
+	Keepalive   KeepaliveStats `json:"keepalive"`
+	TS          time.Time      `json:"ts"`
 }
 
+// ServerOption customizes a Server.
+type ServerOption func(*Server)
+
 // RequestLogSink receives summarized MCP request events.
 type RequestLogSink interface {
 	InsertMCPRequestLog(ctx context.Context, rec store.MCPRequestLog) error
 }
 
 // NewServer creates an MCP server.
-func NewServer(svc *memory.Service, logger *log.Logger, sink RequestLogSink) *Server {
-	return &Server{svc: svc, logger: logger, sink: sink}
+func NewServer(svc *memory.Service, logger *log.Logger, sink RequestLogSink, opts ...ServerOption) *Server {
+	s := &Server{svc: svc, logger: logger, sink: sink, startedAt: time.Now().UTC(), calls: map[uint64]ToolCall{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // Serve starts MCP handling over the provided streams.
 func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
 	br := bufio.NewReader(in)
-	bw := bufio.NewWriter(out)
-	defer bw.Flush()
+	w := &wire{bw: bufio.NewWriter(out), rec: s.recorder}
+	var sess session
+	ctx = context.WithValue(ctx, sessionKey{}, &sess)
+	defer w.flush()
+	if s.keepalive > 0 {
+		kctx, stop := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			s.runKeepalive(kctx, w)
+		}()
+		defer func() {
+			stop()
+			<-done
+		}()
+	}
 
 	for {
 		select {
@@ -56,13 +130,33 @@ func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
 		default:
 		}
 
-		payload, mode, err := readMessage(br)
+		payload, mode, err := s.readNext(br, &sess)
+		var ferr *frameError
+		if errors.As(err, &ferr) {
+			s.logger.Warn("rejected client message", "error", err, "session_continues", ferr.skipped)
+			s.recordRequest(ctx, request{Method: "parse_error"}, response{
+				Error: &rpcError{Code: -32700, Message: "parse error", Data: err.Error()},
+			}, 0)
+			if werr := w.write(errorResponse(nil, -32700, "parse error", err.Error()), mode); werr != nil {
+				return werr
+			}
+			if ferr.skipped {
+				continue
+			}
+			return err
+		}
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				return nil
 			}
 			return err
 		}
+		w.setMode(mode)
+		if s.recorder != nil {
+			if err := s.recorder.Record(transcript.Client, payload); err != nil {
+				return fmt.Errorf("record transcript: %w", err)
+			}
+		}
 
 		var req request
 		if err := json.Unmarshal(payload, &req); err != nil {
@@ -75,11 +169,17 @@ func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
 				},
 			}, 0)
 			resp := errorResponse(nil, -32700, "parse error", err.Error())
-			if werr := writeFramedMessage(bw, resp); werr != nil {
+			if werr := w.write(resp, wireModeFramed); werr != nil {
 				return werr
 			}
 			continue
 		}
+		if req.Method == "" && len(req.ID) > 0 {
+			// A client's reply to one of our keepalive pings.
+			s.handlePong(req.ID)
+			continue
+		}
+		s.lastRequest.Store(time.Now().UnixNano())
 
 		started := time.Now()
 		resp, shouldRespond := s.handle(ctx, req)
@@ -87,12 +187,66 @@ func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
 		if !shouldRespond {
 			continue
 		}
-		if err := writeMessage(bw, resp, mode); err != nil {
+		if err := w.write(resp, mode); err != nil {
 			return err
 		}
 	}
 }
 
+// wire serializes writes from the request loop and the keepalive pinger.
+type wire struct {
+	mu    sync.Mutex
+	bw    *bufio.Writer
+	mode  wireMode
+	ready bool
+	rec   *transcript.Writer
+}
+
+// setMode records the framing the client uses; server-initiated messages
+// are only sent once it is known.
+func (w *wire) setMode(mode wireMode) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.mode, w.ready = mode, true
+}
+
+func (w *wire) write(msg any, mode wireMode) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.send(msg, mode)
+}
+
+// send must be called with w.mu held.
+func (w *wire) send(msg any, mode wireMode) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if w.rec != nil {
+		if err := w.rec.Record(transcript.Server, payload); err != nil {
+			return fmt.Errorf("record transcript: %w", err)
+		}
+	}
+	return writePayload(w.bw, payload, mode)
+}
+
+// writeInClientMode writes msg in the client's framing and reports false
+// when no message has been read yet.
+func (w *wire) writeInClientMode(msg any) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.ready {
+		return false, nil
+	}
+	return true, w.send(msg, w.mode)
+}
+
+func (w *wire) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_ = w.bw.Flush()
+}
+
 type wireMode int
 
 const (
@@ -100,6 +254,87 @@ const (
 	wireModeJSONLine
 )
 
+// Transport modes accepted by WithTransportMode.
+const (
+	TransportAuto   = "auto"
+	TransportFramed = "framed"
+	TransportJSONL  = "jsonl"
+)
+
+func (m wireMode) String() string {
+	if m == wireModeJSONLine {
+		return TransportJSONL
+	}
+	return TransportFramed
+}
+
+// WithTransportMode pins the stdio framing to TransportFramed or
+// TransportJSONL instead of detecting it for every message.
+func WithTransportMode(mode string) ServerOption {
+	return func(s *Server) {
+		s.transport = mode
+	}
+}
+
+// WithAuth restricts tool calls to the namespaces granted by a. key is
+// used by sessions that send no key of their own, such as a stdio server
+// started with the client's key in its environment.
+func WithAuth(a *auth.Authenticator, key string) ServerOption {
+	return func(s *Server) { s.auth, s.apiKey = a, key }
+}
+
+// WithRecorder appends every message read or written by Serve to rec, so
+// the session can be replayed as a golden transcript.
+func WithRecorder(rec *transcript.Writer) ServerOption {
+	return func(s *Server) {
+		s.recorder = rec
+	}
+}
+
+// session tracks one connection's framing so changes are logged once.
+type session struct {
+	mode   wireMode
+	seen   bool
+	warned bool
+	// client is what this session sent in initialize. Sessions on a
+	// socket share the Server, so tool defaults and request logs use it
+	// rather than the server-wide client.
+	client ClientInfo
+	// apiKey is the key sent in initialize, if any.
+	apiKey string
+}
+
+type sessionKey struct{}
+
+// readNext reads one message in the pinned or detected framing and logs
+// the mode the first time and whenever it changes.
+func (s *Server) readNext(r *bufio.Reader, sess *session) ([]byte, wireMode, error) {
+	mode, err := detectWireMode(r)
+	if err != nil {
+		return nil, wireModeFramed, err
+	}
+	transport := s.transport
+	switch transport {
+	case TransportFramed, TransportJSONL:
+		pinned := wireModeFramed
+		if transport == TransportJSONL {
+			pinned = wireModeJSONLine
+		}
+		if mode != pinned && !sess.warned {
+			sess.warned = true
+			s.logger.Warn("client message does not match transport_mode", "transport_mode", transport, "detected", mode)
+		}
+		mode = pinned
+	default:
+		transport = TransportAuto
+	}
+	if !sess.seen || sess.mode != mode {
+		sess.seen, sess.mode = true, mode
+		s.logger.Info("client wire mode", "mode", mode, "transport_mode", transport)
+	}
+	return readMessageAs(r, mode)
+}
+
 type request struct {
 	JSONRPC string          `json:"jsonrpc"`
 	ID      json.RawMessage `json:"id,omitempty"`
@@ -120,7 +355,7 @@ type rpcError struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
-func (s *Server) handle(ctx context.Context, req request) (response, bool) {
+func (s *Server) handle(ctx context.Context, req request) (resp response, reply bool) {
 	atomic.AddUint64(&s.requests, 1)
 
 	hasID := len(req.ID) > 0
@@ -129,18 +364,34 @@ func (s *Server) handle(ctx context.Context, req request) (response, bool) {
 	if req.Method == "notifications/initialized" {
 		return response{}, false
 	}
+	ctx, span := s.startRequestSpan(ctx, req)
+	defer func() { s.endRequestSpan(ctx, span, resp) }()
 
 	switch req.Method {
 	case "initialize":
 		var p struct {
-			ProtocolVersion string `json:"protocolVersion"`
+			ProtocolVersion string     `json:"protocolVersion"`
+			ClientInfo      ClientInfo `json:"clientInfo"`
+			Meta            struct {
+				APIKey string `json:"memory-mcp/api_key"`
+			} `json:"_meta"`
 		}
 		_ = json.Unmarshal(req.Params, &p)
+		s.mu.Lock()
+		s.client = p.ClientInfo
+		s.mu.Unlock()
+		if sess, ok := ctx.Value(sessionKey{}).(*session); ok {
+			sess.client = p.ClientInfo
+			sess.apiKey = p.Meta.APIKey
+		}
+		if p.ClientInfo.Name != "" {
+			s.logger.Info("client connected", "client", p.ClientInfo.Name, "version", p.ClientInfo.Version)
+		}
 		pv := p.ProtocolVersion
 		if strings.TrimSpace(pv) == "" {
 			pv = "2024-11-05"
 		}
-		return response{JSONRPC: jsonRPCVersion, ID: id, Result: map[string]any{
+		result := map[string]any{
 			"protocolVersion": pv,
 			"capabilities": map[string]any{
 				"tools": map[string]any{
@@ -149,16 +400,21 @@ func (s *Server) handle(ctx context.Context, req request) (response, bool) {
 			},
 			"serverInfo": map[string]any{
 				"name":    "memory-mcp",
-				"version": "0.1.0",
+				"version": ServerVersion,
 			},
-		}}, hasID
+		}
+		if s.instructions != "" {
+			result["instructions"] = s.instructions
+		}
+		return response{JSONRPC: jsonRPCVersion, ID: id, Result: result}, hasID
 	case "ping":
 		return response{JSONRPC: jsonRPCVersion, ID: id, Result: map[string]any{}}, hasID
 	case "tools/list":
-		defs := toolDefinitions()
-		return response{JSONRPC: jsonRPCVersion, ID: id, Result: map[string]any{"tools": defs}}, hasID
+		return response{JSONRPC: jsonRPCVersion, ID: id, Result: map[string]any{"tools": s.tools()}}, hasID
 	case "tools/call":
+		done := s.trackCall(s.loggedToolName(req.Method, req.Params))
 		res, err := s.handleToolCall(ctx, req.Params)
+		done()
 		if err != nil {
 			atomic.AddUint64(&s.errors, 1)
 			return response{JSONRPC: jsonRPCVersion, ID: id, Result: map[string]any{
@@ -179,13 +435,17 @@ func (s *Server) recordRequest(ctx context.Context, req request, resp response,
 	if s.sink == nil {
 		return
 	}
+	client := s.clientInfo(ctx)
 	rec := store.MCPRequestLog{
-		Method:     strings.TrimSpace(req.Method),
-		ToolName:   toolNameFromParams(req.Method, req.Params),
-		Success:    responseSuccessful(resp),
-		ErrorText:  responseErrorText(resp),
-		DurationMS: duration.Milliseconds(),
-		CreatedAt:  time.Now().UTC(),
+		Method:        strings.TrimSpace(req.Method),
+		ToolName:      s.loggedToolName(req.Method, req.Params),
+		Success:       responseSuccessful(resp),
+		ErrorText:     responseErrorText(resp),
+		DurationMS:    duration.Milliseconds(),
+		ClientName:    client.Name,
+		ClientVersion: client.Version,
+		Params:        sanitizeParams(req.Method, req.Params),
+		CreatedAt:     time.Now().UTC(),
 	}
 	if strings.TrimSpace(rec.Method) == "" {
 		rec.Method = "unknown"
@@ -195,6 +455,16 @@ func (s *Server) recordRequest(ctx context.Context, req request, resp response,
 	}
 }
 
+// loggedToolName returns the tool a tools/call names, resolving prefixes
+// and aliases so logs and snapshots use the built-in names.
+func (s *Server) loggedToolName(method string, params json.RawMessage) string {
+	name := toolNameFromParams(method, params)
+	if tool, ok := s.resolveTool(name); ok {
+		return tool
+	}
+	return name
+}
+
 func toolNameFromParams(method string, params json.RawMessage) string {
 	if method != "tools/call" || len(params) == 0 {
 		return ""
@@ -255,11 +525,41 @@ func (s *Server) handleToolCall(ctx context.Context, params json.RawMessage) (ma
 	if err := json.Unmarshal(params, &p); err != nil {
 		return nil, fmt.Errorf("invalid tools/call params: %w", err)
 	}
+	name, ok := s.resolveTool(p.Name)
+	if !ok {
+		return nil, fmt.Errorf("unknown tool %q", p.Name)
+	}
+	if s.disabled[name] {
+		return nil, fmt.Errorf("tool %q is disabled on this server (see tools.allow and tools.deny in its config)", p.Name)
+	}
 
-	switch p.Name {
+	caller := memory.Caller{Tool: name, Client: s.clientName(ctx)}
+	if s.auth != nil {
+		grant, err := s.auth.Resolve(s.apiKeyOf(ctx), caller.Client)
+		if err != nil {
+			return nil, err
+		}
+		caller.Grant = grant
+	}
+
+	args, note, err := s.applyDefaults(ctx, name, p.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	ctx = memory.WithCaller(ctx, caller)
+	res, err := s.callTool(ctx, name, args)
+	if err != nil || note == "" {
+		return res, err
+	}
+	res["content"] = append(res["content"].([]map[string]any), map[string]any{"type": "text", "text": note})
+	return res, nil
+}
+
+func (s *Server) callTool(ctx context.Context, name string, args json.RawMessage) (map[string]any, error) {
+	switch name {
 	case "memory_write":
 		var in types.WriteInput
-		if err := json.Unmarshal(p.Arguments, &in); err != nil {
+		if err := json.Unmarshal(args, &in); err != nil {
 			return nil, fmt.Errorf("invalid memory_write arguments: %w", err)
 		}
 		rec, err := s.svc.Write(ctx, in)
@@ -269,17 +569,36 @@ func (s *Server) handleToolCall(ctx context.Context, params json.RawMessage) (ma
 		return toolSuccess(rec)
 	case "memory_search":
 		var in types.SearchInput
-		if err := json.Unmarshal(p.Arguments, &in); err != nil {
+		if err := json.Unmarshal(args, &in); err != nil {
 			return nil, fmt.Errorf("invalid memory_search arguments: %w", err)
 		}
 		items, err := s.svc.Search(ctx, in)
 		if err != nil {
 			return nil, err
 		}
+		if len(items) == 0 {
+			suggestions, err := s.svc.Suggest(ctx, in)
+			if err != nil {
+				s.logger.Warn("search suggestions failed", "namespace", in.Namespace, "error", err)
+			}
+			if len(suggestions) > 0 {
+				return searchMissResult(items, suggestions)
+			}
+		}
 		return toolSuccess(items)
+	case "memory_list":
+		var in types.ListInput
+		if err := json.Unmarshal(args, &in); err != nil {
+			return nil, fmt.Errorf("invalid memory_list arguments: %w", err)
+		}
+		res, err := s.svc.List(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return toolSuccess(res)
 	case "memory_get_context_pack":
 		var in types.ContextPackInput
-		if err := json.Unmarshal(p.Arguments, &in); err != nil {
+		if err := json.Unmarshal(args, &in); err != nil {
 			return nil, fmt.Errorf("invalid memory_get_context_pack arguments: %w", err)
 		}
 		pack, err := s.svc.ContextPack(ctx, in)
@@ -287,9 +606,29 @@ func (s *Server) handleToolCall(ctx context.Context, params json.RawMessage) (ma
 			return nil, err
 		}
 		return toolSuccess(pack)
+	case "memory_ask":
+		var in types.AskInput
+		if err := json.Unmarshal(args, &in); err != nil {
+			return nil, fmt.Errorf("invalid memory_ask arguments: %w", err)
+		}
+		res, err := s.svc.Ask(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return toolSuccess(res)
+	case "memory_cluster":
+		var in types.ClusterInput
+		if err := json.Unmarshal(args, &in); err != nil {
+			return nil, fmt.Errorf("invalid memory_cluster arguments: %w", err)
+		}
+		res, err := s.svc.Cluster(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return toolSuccess(res)
 	case "memory_promote":
 		var in types.PromoteInput
-		if err := json.Unmarshal(p.Arguments, &in); err != nil {
+		if err := json.Unmarshal(args, &in); err != nil {
 			return nil, fmt.Errorf("invalid memory_promote arguments: %w", err)
 		}
 		rec, err := s.svc.Promote(ctx, in)
@@ -297,8 +636,98 @@ func (s *Server) handleToolCall(ctx context.Context, params json.RawMessage) (ma
 			return nil, err
 		}
 		return toolSuccess(rec)
+	case "memory_demote":
+		var in types.DemoteInput
+		if err := json.Unmarshal(args, &in); err != nil {
+			return nil, fmt.Errorf("invalid memory_demote arguments: %w", err)
+		}
+		rec, err := s.svc.Demote(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return toolSuccess(rec)
+	case "memory_append":
+		var in types.AppendInput
+		if err := json.Unmarshal(args, &in); err != nil {
+			return nil, fmt.Errorf("invalid memory_append arguments: %w", err)
+		}
+		rec, err := s.svc.Append(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return toolSuccess(rec)
+	case "memory_merge":
+		var in types.MergeInput
+		if err := json.Unmarshal(args, &in); err != nil {
+			return nil, fmt.Errorf("invalid memory_merge arguments: %w", err)
+		}
+		res, err := s.svc.Merge(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return toolSuccess(res)
+	case "memory_facts":
+		var in types.FactsInput
+		if err := json.Unmarshal(args, &in); err != nil {
+			return nil, fmt.Errorf("invalid memory_facts arguments: %w", err)
+		}
+		res, err := s.svc.Facts(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return toolSuccess(res)
+	case "memory_feedback":
+		var in types.FeedbackInput
+		if err := json.Unmarshal(args, &in); err != nil {
+			return nil, fmt.Errorf("invalid memory_feedback arguments: %w", err)
+		}
+		res, err := s.svc.Feedback(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return toolSuccess(res)
+	case "memory_delete":
+		var in types.DeleteInput
+		if err := json.Unmarshal(args, &in); err != nil {
+			return nil, fmt.Errorf("invalid memory_delete arguments: %w", err)
+		}
+		res, err := s.svc.Delete(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return toolSuccess(res)
+	case "memory_link":
+		var in types.LinkInput
+		if err := json.Unmarshal(args, &in); err != nil {
+			return nil, fmt.Errorf("invalid memory_link arguments: %w", err)
+		}
+		res, err := s.svc.Link(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return toolSuccess(res)
+	case "memory_forget":
+		var in types.ForgetInput
+		if err := json.Unmarshal(args, &in); err != nil {
+			return nil, fmt.Errorf("invalid memory_forget arguments: %w", err)
+		}
+		res, err := s.svc.Forget(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return toolSuccess(res)
+	case "memory_purge":
+		var in types.PurgeInput
+		if err := json.Unmarshal(args, &in); err != nil {
+			return nil, fmt.Errorf("invalid memory_purge arguments: %w", err)
+		}
+		res, err := s.svc.Purge(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return toolSuccess(res)
 	default:
-		return nil, fmt.Errorf("unknown tool %q", p.Name)
+		return nil, fmt.Errorf("unknown tool %q", name)
 	}
 }
 
@@ -314,6 +743,23 @@ func toolSuccess(v any) (map[string]any, error) {
 	}, nil
 }
 
+// searchMissResult is an empty memory_search result carrying corrected
+// queries, as a "suggestions" field and as text for clients that only show
+// content.
+func searchMissResult(items []types.SearchResult, suggestions []string) (map[string]any, error) {
+	res, err := toolSuccess(items)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(map[string]any{"suggestions": suggestions})
+	if err != nil {
+		return nil, err
+	}
+	res["content"] = append(res["content"].([]map[string]any), map[string]any{"type": "text", "text": string(b)})
+	res["suggestions"] = suggestions
+	return res, nil
+}
+
 func errorResponse(id interface{}, code int, msg string, data interface{}) response {
 	return response{
 		JSONRPC: jsonRPCVersion,
@@ -337,27 +783,20 @@ func decodeID(raw json.RawMessage) interface{} {
 	return v
 }
 
-func writeFramedMessage(w *bufio.Writer, msg response) error {
+func writeFramedMessage(w *bufio.Writer, msg any) error {
+	return writeMessage(w, msg, wireModeFramed)
+}
+
+func writeMessage(w *bufio.Writer, msg any, mode wireMode) error {
 	payload, err := json.Marshal(msg)
 	if err != nil {
 		return err
 	}
-	header := fmt.Sprintf("Content-Length: %d\r\n\r\n", len(payload))
-	if _, err := w.WriteString(header); err != nil {
-		return err
-	}
-	if _, err := w.Write(payload); err != nil {
-		return err
-	}
-	return w.Flush()
+	return writePayload(w, payload, mode)
 }
 
-func writeMessage(w *bufio.Writer, msg response, mode wireMode) error {
+func writePayload(w *bufio.Writer, payload []byte, mode wireMode) error {
 	if mode == wireModeJSONLine {
-		payload, err := json.Marshal(msg)
-		if err != nil {
-			return err
-		}
 		if _, err := w.Write(payload); err != nil {
 			return err
 		}
@@ -366,7 +805,14 @@ func writeMessage(w *bufio.Writer, msg response, mode wireMode) error {
 		}
 		return w.Flush()
 	}
-	return writeFramedMessage(w, msg)
+	header := fmt.Sprintf("Content-Length: %d\r\n\r\n", len(payload))
+	if _, err := w.WriteString(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return err
+	}
+	return w.Flush()
 }
 
 func readMessage(r *bufio.Reader) ([]byte, wireMode, error) {
@@ -374,6 +820,10 @@ func readMessage(r *bufio.Reader) ([]byte, wireMode, error) {
 	if err != nil {
 		return nil, wireModeFramed, err
 	}
+	return readMessageAs(r, mode)
+}
+
+func readMessageAs(r *bufio.Reader, mode wireMode) ([]byte, wireMode, error) {
 	if mode == wireModeJSONLine {
 		return readJSONLineMessage(r)
 	}
@@ -404,46 +854,129 @@ func detectWireMode(r *bufio.Reader) (wireMode, error) {
 	return wireModeJSONLine, nil
 }
 
-func readJSONLineMessage(r *bufio.Reader) ([]byte, wireMode, error) {
-	line, err := r.ReadBytes('\n')
-	if err != nil && !errors.Is(err, io.EOF) {
-		return nil, wireModeJSONLine, err
+// Wire limits. A client exceeding them gets a parse error; the session
+// survives only when the offending message could be skipped whole.
+const (
+	maxHeaderLine  = 1 << 10
+	maxHeaders     = 16
+	maxMessageSize = 16 << 20
+)
+
+var (
+	errHeaderTooLong    = errors.New("header line too long")
+	errTooManyHeaders   = errors.New("too many headers")
+	errBadContentLength = errors.New("missing or invalid Content-Length")
+	errMessageTooLarge  = errors.New("message too large")
+)
+
+// frameError is a message that breaks the wire limits or header syntax.
+type frameError struct {
+	err    error
+	detail string
+	// skipped is set when the message was read past, so the stream is
+	// still aligned on the next message.
+	skipped bool
+}
+
+func (e *frameError) Error() string {
+	if e.detail == "" {
+		return e.err.Error()
 	}
-	line = bytes.TrimSpace(line)
-	if len(line) == 0 {
+	return e.err.Error() + ": " + e.detail
+}
+
+func (e *frameError) Unwrap() error { return e.err }
+
+func readJSONLineMessage(r *bufio.Reader) ([]byte, wireMode, error) {
+	for {
+		line, err := readLine(r, maxMessageSize, true)
+		if errors.Is(err, errMessageTooLarge) {
+			return nil, wireModeJSONLine, &frameError{err: errMessageTooLarge, detail: fmt.Sprintf("line exceeds %d bytes", maxMessageSize), skipped: true}
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, wireModeJSONLine, err
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			return line, wireModeJSONLine, nil
+		}
 		if errors.Is(err, io.EOF) {
 			return nil, wireModeJSONLine, io.EOF
 		}
-		return readJSONLineMessage(r)
 	}
-	return line, wireModeJSONLine, nil
 }
 
-func readFramedMessage(r *bufio.Reader) ([]byte, error) {
-	contentLength := 0
+// readLine reads through the next newline, failing with
+// errMessageTooLarge once the line exceeds limit bytes. With skip the rest
+// of an overlong line is read and dropped; otherwise it is left unread.
+func readLine(r *bufio.Reader, limit int, skip bool) ([]byte, error) {
+	var line []byte
+	over := false
 	for {
-		line, err := r.ReadString('\n')
+		chunk, err := r.ReadSlice('\n')
+		if !over && len(line)+len(chunk) > limit {
+			if !skip {
+				return nil, errMessageTooLarge
+			}
+			over, line = true, nil
+		}
+		if !over {
+			line = append(line, chunk...)
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if over {
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			return nil, errMessageTooLarge
+		}
+		return line, err
+	}
+}
+
+func readFramedMessage(r *bufio.Reader) ([]byte, error) {
+	contentLength := -1
+	for headers := 0; ; headers++ {
+		if headers == maxHeaders {
+			return nil, &frameError{err: errTooManyHeaders, detail: fmt.Sprintf("more than %d", maxHeaders)}
+		}
+		raw, err := readLine(r, maxHeaderLine, false)
+		if errors.Is(err, errMessageTooLarge) {
+			return nil, &frameError{err: errHeaderTooLong, detail: fmt.Sprintf("exceeds %d bytes", maxHeaderLine)}
+		}
 		if err != nil {
+			if errors.Is(err, io.EOF) && len(raw) > 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
 			return nil, err
 		}
-		line = strings.TrimRight(line, "\r\n")
+		line := strings.TrimRight(string(raw), "\r\n")
 		if line == "" {
 			break
 		}
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
 			continue
 		}
-		if strings.EqualFold(strings.TrimSpace(parts[0]), "Content-Length") {
-			n, err := strconv.Atoi(strings.TrimSpace(parts[1]))
-			if err != nil {
-				return nil, fmt.Errorf("invalid Content-Length: %w", err)
-			}
-			contentLength = n
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n <= 0 {
+			return nil, &frameError{err: errBadContentLength, detail: fmt.Sprintf("%q", strings.TrimSpace(value))}
+		}
+		if contentLength >= 0 && n != contentLength {
+			return nil, &frameError{err: errBadContentLength, detail: fmt.Sprintf("conflicting values %d and %d", contentLength, n)}
 		}
+		contentLength = n
+	}
+	if contentLength < 0 {
+		return nil, &frameError{err: errBadContentLength, detail: "header not found"}
 	}
-	if contentLength <= 0 {
-		return nil, fmt.Errorf("missing or invalid Content-Length")
+	if contentLength > maxMessageSize {
+		if _, err := io.CopyN(io.Discard, r, int64(contentLength)); err != nil {
+			return nil, &frameError{err: errMessageTooLarge, detail: fmt.Sprintf("Content-Length %d exceeds %d bytes", contentLength, maxMessageSize)}
+		}
+		return nil, &frameError{err: errMessageTooLarge, detail: fmt.Sprintf("Content-Length %d exceeds %d bytes", contentLength, maxMessageSize), skipped: true}
 	}
 
 	buf := make([]byte, contentLength)
@@ -453,11 +986,63 @@ func readFramedMessage(r *bufio.Reader) ([]byte, error) {
 	return buf, nil
 }
 
-// Snapshot returns server counters for dashboards.
-func (s *Server) Snapshot() map[string]any {
-	return map[string]any{
-		"requests": atomic.LoadUint64(&s.requests),
-		"errors":   atomic.LoadUint64(&s.errors),
-		"ts":       time.Now().UTC(),
+// clientInfo returns the client of the session ctx belongs to, or the
+// server's client outside a session, as for CallTool.
+func (s *Server) clientInfo(ctx context.Context) ClientInfo {
+	if sess, ok := ctx.Value(sessionKey{}).(*session); ok {
+		return sess.client
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client
+}
+
+func (s *Server) clientName(ctx context.Context) string {
+	return strings.TrimSpace(s.clientInfo(ctx).Name)
+}
+
+// apiKeyOf returns the API key the session ctx belongs to sent in
+// initialize, or the server's own key from WithAuth.
+func (s *Server) apiKeyOf(ctx context.Context) string {
+	if sess, ok := ctx.Value(sessionKey{}).(*session); ok && sess.apiKey != "" {
+		return sess.apiKey
+	}
+	return s.apiKey
+}
+
+// trackCall records a tool call as in flight until the returned func runs.
+func (s *Server) trackCall(tool string) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextCall++
+	id := s.nextCall
+	s.calls[id] = ToolCall{Tool: tool, StartedAt: time.Now().UTC()}
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.calls, id)
+	}
+}
+
+// Snapshot returns server counters, the last client to connect and in-flight
+// tool calls for dashboards. It is safe to call while Serve runs.
+func (s *Server) Snapshot() Snapshot {
+	snap := Snapshot{
+		PID:         os.Getpid(),
+		StartedAt:   s.startedAt,
+		Requests:    atomic.LoadUint64(&s.requests),
+		Errors:      atomic.LoadUint64(&s.errors),
+		SearchCache: s.svc.SearchCacheStats(),
+		PackCache:   s.svc.PackCacheStats(),
+		TS:          time.Now().UTC(),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap.Client = s.client
+	snap.Keepalive = s.keepaliveStats(snap.TS)
+	for _, c := range s.calls {
+		snap.InFlight = append(snap.InFlight, c)
 	}
+	sort.Slice(snap.InFlight, func(i, j int) bool { return snap.InFlight[i].StartedAt.Before(snap.InFlight[j].StartedAt) })
+	return snap
 }
@@ -4,13 +4,23 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/charmbracelet/log"
 
+	"github.com/xiy/memory-mcp/internal/auth"
 	"github.com/xiy/memory-mcp/internal/config"
 	"github.com/xiy/memory-mcp/internal/memory"
 	"github.com/xiy/memory-mcp/internal/store"
@@ -22,10 +32,18 @@ type fakeStore struct{}
 func (fakeStore) InsertMemory(_ context.Context, rec types.MemoryRecord) (types.MemoryRecord, error) {
 	return rec, nil
 }
-func (fakeStore) SearchCandidates(_ context.Context, _, _, _ string, _ int, _ time.Time) ([]store.Candidate, error) {
+func (fakeStore) InsertMemories(_ context.Context, recs []types.MemoryRecord) ([]types.MemoryRecord, error) {
+	return recs, nil
+}
+func (fakeStore) SearchCandidates(_ context.Context, _ store.SearchQuery) ([]store.Candidate, error) {
 	return nil, nil
 }
-func (fakeStore) Promote(_ context.Context, _ string, _ time.Time) error    { return nil }
+func (fakeStore) Promote(_ context.Context, _ string, _ time.Time) error   { return nil }
+func (fakeStore) Demote(_ context.Context, _ string, _, _ time.Time) error { return nil }
+func (fakeStore) UpdateMemory(_ context.Context, id string, fn func(*types.MemoryRecord) error) (types.MemoryRecord, error) {
+	rec := types.MemoryRecord{ID: id, Namespace: "org/repo/task", Scope: "long"}
+	return rec, fn(&rec)
+}
 func (fakeStore) ExpireShort(_ context.Context, _ time.Time) (int64, error) { return 0, nil }
 func (fakeStore) Stats(_ context.Context, _ time.Time) (store.Stats, error) {
 	return store.Stats{}, nil
@@ -33,6 +51,22 @@ func (fakeStore) Stats(_ context.Context, _ time.Time) (store.Stats, error) {
 func (fakeStore) GetMemory(_ context.Context, id string) (types.MemoryRecord, error) {
 	return types.MemoryRecord{ID: id, Namespace: "org/repo/task", Scope: "long"}, nil
 }
+func (fakeStore) ListMemories(_ context.Context, _ store.ListFilter) ([]types.MemoryRecord, error) {
+	return nil, nil
+}
+func (fakeStore) CountBy(_ context.Context, _ string, _ store.ListFilter) ([]store.GroupCount, error) {
+	return nil, nil
+}
+func (fakeStore) DeleteMemories(_ context.Context, ids []string) (int64, error) {
+	return int64(len(ids)), nil
+}
+func (fakeStore) DeleteByFilter(_ context.Context, _ store.ListFilter) (int64, error) {
+	return 0, nil
+}
+func (fakeStore) ExportAll(_ context.Context, _ func(types.MemoryRecord) error) error { return nil }
+func (fakeStore) ImportBatch(_ context.Context, recs []types.MemoryRecord) ([]types.MemoryRecord, []string, error) {
+	return recs, nil, nil
+}
 func (fakeStore) Close() error { return nil }
 
 type captureSink struct {
@@ -182,3 +216,509 @@ func TestServe_LogsRequestEvents(t *testing.T) {
 		t.Fatalf("expected non-empty error text")
 	}
 }
+
+func TestSnapshot_RecordsClientInfo(t *testing.T) {
+	t.Parallel()
+	svc, err := memory.NewService(fakeStore{}, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	srv := NewServer(svc, log.NewWithOptions(io.Discard, log.Options{}), nil)
+
+	srv.handle(context.Background(), request{
+		JSONRPC: "2.0",
+		ID:      json.RawMessage(`1`),
+		Method:  "initialize",
+		Params:  json.RawMessage(`{"protocolVersion":"2025-03-26","clientInfo":{"name":"claude-code","version":"1.0.3"}}`),
+	})
+	done := srv.trackCall("memory_search")
+	snap := srv.Snapshot()
+	done()
+
+	if snap.Client != (ClientInfo{Name: "claude-code", Version: "1.0.3"}) || snap.Requests != 1 {
+		t.Fatalf("unexpected snapshot %+v", snap)
+	}
+	if len(snap.InFlight) != 1 || snap.InFlight[0].Tool != "memory_search" {
+		t.Fatalf("expected one in-flight memory_search, got %+v", snap.InFlight)
+	}
+	if got := srv.Snapshot().InFlight; len(got) != 0 {
+		t.Fatalf("expected finished call to be cleared, got %+v", got)
+	}
+}
+
+func TestServe_KeepalivePingsIdleClient(t *testing.T) {
+	t.Parallel()
+	svc, err := memory.NewService(fakeStore{}, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	srv := NewServer(svc, log.NewWithOptions(io.Discard, log.Options{}), nil, WithKeepalive(20*time.Millisecond))
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(context.Background(), inR, outW) }()
+	lines := bufio.NewScanner(outR)
+
+	if _, err := io.WriteString(inW, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`+"\n"); err != nil {
+		t.Fatalf("write initialize: %v", err)
+	}
+	var ping struct {
+		ID     string `json:"id"`
+		Method string `json:"method"`
+	}
+	for lines.Scan() {
+		if err := json.Unmarshal(lines.Bytes(), &ping); err == nil && ping.Method == "ping" {
+			break
+		}
+	}
+	if ping.ID == "" {
+		t.Fatalf("expected a ping request, scan error = %v", lines.Err())
+	}
+	if _, err := io.WriteString(inW, `{"jsonrpc":"2.0","id":"`+ping.ID+`","result":{}}`+"\n"); err != nil {
+		t.Fatalf("write pong: %v", err)
+	}
+	go func() {
+		for lines.Scan() {
+		}
+	}()
+
+	var ka KeepaliveStats
+	for range 100 {
+		if ka = srv.Snapshot().Keepalive; ka.Answered > 0 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if ka.Answered == 0 || ka.Sent < ka.Answered || ka.LastAnsweredAt.IsZero() || ka.IntervalSeconds != 0.02 {
+		t.Fatalf("unexpected keepalive stats %+v", ka)
+	}
+
+	inW.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	outW.Close()
+}
+
+func TestReadNext_PinnedTransportLogsMode(t *testing.T) {
+	t.Parallel()
+	svc, err := memory.NewService(fakeStore{}, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	var logs bytes.Buffer
+	srv := NewServer(svc, log.NewWithOptions(&logs, log.Options{}), nil, WithTransportMode(TransportJSONL))
+
+	br := bufio.NewReader(bytes.NewBufferString("{\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"ping\"}\n{\"jsonrpc\":\"2.0\",\"id\":2,\"method\":\"ping\"}\n"))
+	var sess session
+	for range 2 {
+		payload, mode, err := srv.readNext(br, &sess)
+		if err != nil {
+			t.Fatalf("readNext() error = %v", err)
+		}
+		if mode != wireModeJSONLine || !bytes.Contains(payload, []byte(`"ping"`)) {
+			t.Fatalf("readNext() = %q, %v", payload, mode)
+		}
+	}
+	if got := strings.Count(logs.String(), "client wire mode"); got != 1 || !strings.Contains(logs.String(), "transport_mode=jsonl") {
+		t.Fatalf("expected one mode log line, got %q", logs.String())
+	}
+}
+
+func TestHandle_InitializeReturnsInstructions(t *testing.T) {
+	t.Parallel()
+	cfg := config.Default()
+	svc, err := memory.NewService(fakeStore{}, cfg, log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	cfg.Instructions = "Use namespaces matching {{.NamespacePattern}} on {{.ServerName}}."
+	text, err := RenderInstructions(cfg)
+	if err != nil {
+		t.Fatalf("RenderInstructions() error = %v", err)
+	}
+	srv := NewServer(svc, log.NewWithOptions(io.Discard, log.Options{}), nil, WithInstructions(text))
+
+	resp, _ := srv.handle(context.Background(), request{JSONRPC: "2.0", ID: json.RawMessage(`1`), Method: "initialize"})
+	got, _ := resp.Result.(map[string]any)["instructions"].(string)
+	if want := "Use namespaces matching " + cfg.NamespacePattern + " on memory-mcp."; got != want {
+		t.Fatalf("instructions = %q, want %q", got, want)
+	}
+
+	bare := NewServer(svc, log.NewWithOptions(io.Discard, log.Options{}), nil)
+	resp, _ = bare.handle(context.Background(), request{JSONRPC: "2.0", ID: json.RawMessage(`1`), Method: "initialize"})
+	if _, ok := resp.Result.(map[string]any)["instructions"]; ok {
+		t.Fatal("expected no instructions field when none are configured")
+	}
+}
+
+func TestWithTools_HidesAndRejectsDisabledTools(t *testing.T) {
+	t.Parallel()
+	svc, err := memory.NewService(fakeStore{}, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	tools := config.ToolsConfig{Allow: []string{"memory_search", "memory_write"}, Deny: []string{"memory_write"}}
+	if err := CheckToolsConfig(tools); err != nil {
+		t.Fatalf("CheckToolsConfig() error = %v", err)
+	}
+	if err := CheckToolsConfig(config.ToolsConfig{Deny: []string{"memory_wirte"}}); err == nil {
+		t.Fatal("expected unknown tool name to be rejected")
+	}
+	srv := NewServer(svc, log.NewWithOptions(io.Discard, log.Options{}), nil, WithTools(tools))
+
+	resp, _ := srv.handle(context.Background(), request{JSONRPC: "2.0", ID: json.RawMessage(`1`), Method: "tools/list"})
+	defs := resp.Result.(map[string]any)["tools"].([]ToolDefinition)
+	if len(defs) != 1 || defs[0].Name != "memory_search" {
+		t.Fatalf("expected only memory_search, got %+v", defs)
+	}
+
+	resp, _ = srv.handle(context.Background(), request{JSONRPC: "2.0", ID: json.RawMessage(`2`), Method: "tools/call",
+		Params: json.RawMessage(`{"name":"memory_write","arguments":{"namespace":"org/repo/task","content":"x"}}`)})
+	if responseSuccessful(resp) || !strings.Contains(responseErrorText(resp), "disabled") {
+		t.Fatalf("expected disabled tool error, got %+v", resp)
+	}
+}
+
+func TestWithTools_PrefixAndAliases(t *testing.T) {
+	t.Parallel()
+	svc, err := memory.NewService(fakeStore{}, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	tools := config.ToolsConfig{Prefix: "shm_", Aliases: map[string]string{"memory_get_context_pack": "shm_context"}}
+	if err := CheckToolsConfig(tools); err != nil {
+		t.Fatalf("CheckToolsConfig() error = %v", err)
+	}
+	if err := CheckToolsConfig(config.ToolsConfig{Aliases: map[string]string{"memory_ask": "memory_search"}}); err == nil {
+		t.Fatal("expected clashing alias to be rejected")
+	}
+	sink := &captureSink{}
+	srv := NewServer(svc, log.NewWithOptions(io.Discard, log.Options{}), sink, WithTools(tools))
+
+	resp, _ := srv.handle(context.Background(), request{JSONRPC: "2.0", ID: json.RawMessage(`1`), Method: "tools/list"})
+	names := map[string]bool{}
+	for _, def := range resp.Result.(map[string]any)["tools"].([]ToolDefinition) {
+		names[def.Name] = true
+	}
+	if !names["shm_memory_write"] || !names["shm_context"] || names["memory_write"] || names["shm_memory_get_context_pack"] {
+		t.Fatalf("unexpected exposed names %v", names)
+	}
+
+	call := func(name string) response {
+		req := request{JSONRPC: "2.0", ID: json.RawMessage(`2`), Method: "tools/call",
+			Params: json.RawMessage(`{"name":"` + name + `","arguments":{"namespace":"org/repo/task","query":"auth"}}`)}
+		resp, _ := srv.handle(context.Background(), req)
+		srv.recordRequest(context.Background(), req, resp, 0)
+		return resp
+	}
+	if resp := call("shm_memory_search"); !responseSuccessful(resp) {
+		t.Fatalf("shm_memory_search failed: %s", responseErrorText(resp))
+	}
+	if resp := call("memory_search"); responseSuccessful(resp) {
+		t.Fatal("expected the unprefixed name to be unknown")
+	}
+	if sink.rows[0].ToolName != "memory_search" {
+		t.Fatalf("expected request log to record the built-in name, got %q", sink.rows[0].ToolName)
+	}
+
+	cfg := config.Default()
+	cfg.Tools = tools
+	text, err := RenderInstructions(cfg)
+	if err != nil || !strings.Contains(text, "shm_context") || strings.Contains(text, "call memory_") {
+		t.Fatalf("expected instructions to use exposed names, got %q, %v", text, err)
+	}
+}
+
+func TestWithDefaultNamespace_FillsMissingNamespace(t *testing.T) {
+	t.Parallel()
+	svc, err := memory.NewService(fakeStore{}, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	srv := NewServer(svc, log.NewWithOptions(io.Discard, log.Options{}), nil, WithDefaultNamespace("acme/ghost/main"))
+
+	resp, _ := srv.handle(context.Background(), request{JSONRPC: "2.0", ID: json.RawMessage(`1`), Method: "tools/list"})
+	for _, def := range resp.Result.(map[string]any)["tools"].([]ToolDefinition) {
+		if def.Name == "memory_write" && slices.Contains(def.InputSchema["required"].([]string), "namespace") {
+			t.Fatal("expected namespace to be optional with a default")
+		}
+	}
+
+	res, err := srv.handleToolCall(context.Background(), json.RawMessage(`{"name":"memory_write","arguments":{"content":"note"}}`))
+	if err != nil {
+		t.Fatalf("handleToolCall() error = %v", err)
+	}
+	rec := res["structuredContent"].(types.MemoryRecord)
+	content := res["content"].([]map[string]any)
+	if rec.Namespace != "acme/ghost/main" || len(content) != 2 || !strings.Contains(content[1]["text"].(string), "default_namespace") {
+		t.Fatalf("expected defaulted namespace and a note, got %+v / %+v", rec, content)
+	}
+
+	res, err = srv.handleToolCall(context.Background(), json.RawMessage(`{"name":"memory_write","arguments":{"namespace":"org/repo/task","content":"note"}}`))
+	if err != nil || res["structuredContent"].(types.MemoryRecord).Namespace != "org/repo/task" || len(res["content"].([]map[string]any)) != 1 {
+		t.Fatalf("expected explicit namespace to be kept without a note, got %+v, %v", res, err)
+	}
+}
+
+func TestApplyDefaults_SourceAgentFromClientInfo(t *testing.T) {
+	t.Parallel()
+	svc, err := memory.NewService(fakeStore{}, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	sink := &captureSink{}
+	srv := NewServer(svc, log.NewWithOptions(io.Discard, log.Options{}), sink)
+	init := request{JSONRPC: "2.0", ID: json.RawMessage(`1`), Method: "initialize",
+		Params: json.RawMessage(`{"clientInfo":{"name":"gemini-cli","version":"0.9"}}`)}
+	resp, _ := srv.handle(context.Background(), init)
+	srv.recordRequest(context.Background(), init, resp, 0)
+
+	res, err := srv.handleToolCall(context.Background(), json.RawMessage(`{"name":"memory_write","arguments":{"namespace":"org/repo/task","content":"note"}}`))
+	if err != nil {
+		t.Fatalf("handleToolCall() error = %v", err)
+	}
+	if got := res["structuredContent"].(types.MemoryRecord).SourceAgent; got != "gemini-cli" {
+		t.Fatalf("SourceAgent = %q, want gemini-cli", got)
+	}
+	res, err = srv.handleToolCall(context.Background(), json.RawMessage(`{"name":"memory_write","arguments":{"namespace":"org/repo/task","content":"note","source_agent":"reviewer"}}`))
+	if err != nil || res["structuredContent"].(types.MemoryRecord).SourceAgent != "reviewer" {
+		t.Fatalf("expected explicit source_agent to be kept, got %+v, %v", res, err)
+	}
+	if len(sink.rows) != 1 || sink.rows[0].ClientName != "gemini-cli" || sink.rows[0].ClientVersion != "0.9" {
+		t.Fatalf("expected request log to carry client info, got %+v", sink.rows)
+	}
+}
+
+func TestSanitizeParams_RedactsSecretsAndSkipsOtherMethods(t *testing.T) {
+	t.Parallel()
+	params := json.RawMessage(`{"name":"memory_write","arguments":{"content":"x","token_budget":900,"metadata":{"api_key":"sk-1","nested":[{"access_token":"t"}]}}}`)
+	got := sanitizeParams("tools/call", params)
+	if strings.Contains(got, "sk-1") || strings.Contains(got, `"t"`) || !strings.Contains(got, `"token_budget":900`) {
+		t.Fatalf("sanitizeParams() = %s", got)
+	}
+	if got := sanitizeParams("initialize", json.RawMessage(`{"clientInfo":{"name":"codex"}}`)); got != "" {
+		t.Fatalf("expected non tools/call params to be dropped, got %s", got)
+	}
+}
+
+func TestCallTool_ReplaysAsPresetClient(t *testing.T) {
+	t.Parallel()
+	svc, err := memory.NewService(fakeStore{}, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	srv := NewServer(svc, log.NewWithOptions(io.Discard, log.Options{}), nil, WithClient(ClientInfo{Name: "codex", Version: "1.2"}))
+	res, err := srv.CallTool(context.Background(), json.RawMessage(`{"name":"memory_write","arguments":{"namespace":"org/repo/task","content":"note"}}`))
+	if err != nil {
+		t.Fatalf("CallTool() error = %v", err)
+	}
+	if got := res["structuredContent"].(types.MemoryRecord).SourceAgent; got != "codex" {
+		t.Fatalf("SourceAgent = %q, want codex", got)
+	}
+}
+
+func TestReadFramedMessage_Limits(t *testing.T) {
+	t.Parallel()
+	ping := `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+	cases := []struct {
+		name    string
+		raw     string
+		want    error
+		skipped bool
+	}{
+		{"header too long", "Content-Length: 5\r\nX-Pad: " + strings.Repeat("a", maxHeaderLine) + "\r\n\r\n", errHeaderTooLong, false},
+		{"too many headers", strings.Repeat("X-Pad: a\r\n", maxHeaders) + "Content-Length: 5\r\n\r\n", errTooManyHeaders, false},
+		{"missing length", "X-Pad: a\r\n\r\n" + ping, errBadContentLength, false},
+		{"negative length", "Content-Length: -4\r\n\r\n" + ping, errBadContentLength, false},
+		{"overflowing length", "Content-Length: 99999999999999999999999\r\n\r\n" + ping, errBadContentLength, false},
+		{"conflicting lengths", "Content-Length: 5\r\nContent-Length: 6\r\n\r\n" + ping, errBadContentLength, false},
+		{"payload too large", fmt.Sprintf("Content-Length: %d\r\n\r\n", maxMessageSize+1) + strings.Repeat(" ", maxMessageSize+1), errMessageTooLarge, true},
+	}
+	for _, tc := range cases {
+		_, err := readFramedMessage(bufio.NewReader(strings.NewReader(tc.raw)))
+		var ferr *frameError
+		if !errors.As(err, &ferr) || !errors.Is(err, tc.want) || ferr.skipped != tc.skipped {
+			t.Fatalf("%s: readFramedMessage() error = %v, want %v (skipped %v)", tc.name, err, tc.want, tc.skipped)
+		}
+	}
+}
+
+func TestServe_SkipsOversizedMessageAndContinues(t *testing.T) {
+	t.Parallel()
+	svc, err := memory.NewService(fakeStore{}, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	sink := &captureSink{}
+	srv := NewServer(svc, log.NewWithOptions(io.Discard, log.Options{}), sink)
+
+	in := `{"jsonrpc":"2.0","id":1,"method":"ping","params":{"pad":"` + strings.Repeat("a", maxMessageSize) + "\"}}\n" +
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}` + "\n"
+	var out bytes.Buffer
+	if err := srv.Serve(context.Background(), strings.NewReader(in), &out); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"code":-32700`) || !strings.Contains(lines[0], "message too large") || !strings.Contains(lines[1], `"id":2`) {
+		t.Fatalf("unexpected responses %q", out.String())
+	}
+	if len(sink.rows) != 2 || sink.rows[0].Method != "parse_error" {
+		t.Fatalf("expected the rejected message to be logged, got %+v", sink.rows)
+	}
+}
+
+func FuzzReadMessage(f *testing.F) {
+	f.Add([]byte("Content-Length: 2\r\n\r\n{}"))
+	f.Add([]byte("content-length: 40\r\nContent-Type: application/json\r\n\r\n{\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"ping\"}"))
+	f.Add([]byte("{\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"ping\"}\n\n{}\n"))
+	f.Add([]byte("Content-Length: -1\r\n\r\n"))
+	f.Add([]byte("Content-Length: 3\r\nContent-Length: 3\r\n\r\nabc"))
+	f.Add([]byte("Content-Length: 18446744073709551616\r\n\r\n"))
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		br := bufio.NewReader(bytes.NewReader(raw))
+		for range 8 {
+			payload, mode, err := readMessage(br)
+			if err != nil {
+				var ferr *frameError
+				if errors.As(err, &ferr) && ferr.skipped {
+					continue
+				}
+				return
+			}
+			if len(payload) == 0 || len(payload) > maxMessageSize {
+				t.Fatalf("readMessage() returned %d bytes in %v mode", len(payload), mode)
+			}
+		}
+	})
+}
+
+func TestServeListener_SessionsKeepTheirOwnClient(t *testing.T) {
+	t.Parallel()
+	svc, err := memory.NewService(fakeStore{}, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	srv := NewServer(svc, log.NewWithOptions(io.Discard, log.Options{}), nil)
+	// Unix socket paths are limited to about 100 bytes.
+	dir, err := os.MkdirTemp("", "mcp")
+	if err != nil {
+		t.Fatalf("MkdirTemp() error = %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	addr := "unix:" + filepath.Join(dir, "s.sock")
+	ln, err := Listen(addr)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.ServeListener(ctx, ln) }()
+
+	type client struct {
+		conn net.Conn
+		r    *bufio.Reader
+	}
+	call := func(c client, msg string) map[string]any {
+		t.Helper()
+		if _, err := io.WriteString(c.conn, msg+"\n"); err != nil {
+			t.Fatalf("write error = %v", err)
+		}
+		line, err := c.r.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("read error = %v", err)
+		}
+		var resp map[string]any
+		if err := json.Unmarshal(line, &resp); err != nil {
+			t.Fatalf("invalid response %q: %v", line, err)
+		}
+		return resp
+	}
+	var clients []client
+	for _, name := range []string{"codex", "claude-code"} {
+		network, address := SocketAddr(addr)
+		conn, err := net.Dial(network, address)
+		if err != nil {
+			t.Fatalf("Dial() error = %v", err)
+		}
+		defer conn.Close()
+		c := client{conn, bufio.NewReader(conn)}
+		call(c, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"clientInfo":{"name":"`+name+`"}}}`)
+		clients = append(clients, c)
+	}
+	for i, want := range []string{"codex", "claude-code"} {
+		resp := call(clients[i], `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"memory_write","arguments":{"namespace":"org/repo/task","content":"note"}}}`)
+		got, _ := resp["result"].(map[string]any)["structuredContent"].(map[string]any)["source_agent"].(string)
+		if got != want {
+			t.Fatalf("session %d source_agent = %q, want %q", i, got, want)
+		}
+	}
+
+	if _, err := Listen(addr); err == nil {
+		t.Fatal("Listen() on a socket in use error = nil")
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("ServeListener() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestWithAuth_ResolvesSessionKey(t *testing.T) {
+	t.Parallel()
+	svc, err := memory.NewService(fakeStore{}, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	sum := sha256.Sum256([]byte("s3cret"))
+	authn, err := auth.New(config.AuthConfig{Enabled: true, Principals: []config.PrincipalConfig{
+		{Name: "ci", KeySHA256: hex.EncodeToString(sum[:]), Namespaces: []string{"org/repo"}},
+	}})
+	if err != nil {
+		t.Fatalf("auth.New() error = %v", err)
+	}
+	session := func(srv *Server, initParams string) []string {
+		t.Helper()
+		in := bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":` + initParams + "}\n" +
+			`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"memory_write","arguments":{"namespace":"org/repo/task","content":"x"}}}` + "\n" +
+			`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"memory_write","arguments":{"namespace":"org/other/task","content":"x"}}}` + "\n")
+		var out bytes.Buffer
+		if err := srv.Serve(context.Background(), in, &out); err != nil {
+			t.Fatalf("Serve() error = %v", err)
+		}
+		var errs []string
+		for line := range bytes.Lines(bytes.TrimSpace(out.Bytes())) {
+			var resp struct {
+				Result struct {
+					IsError bool `json:"isError"`
+					Content []struct {
+						Text string `json:"text"`
+					} `json:"content"`
+				} `json:"result"`
+			}
+			if err := json.Unmarshal(line, &resp); err != nil {
+				t.Fatalf("invalid response %q: %v", line, err)
+			}
+			if resp.Result.IsError {
+				errs = append(errs, resp.Result.Content[0].Text)
+			} else if len(resp.Result.Content) > 0 {
+				errs = append(errs, "")
+			}
+		}
+		return errs
+	}
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+
+	got := session(NewServer(svc, logger, nil, WithAuth(authn, "")), `{"clientInfo":{"name":"codex"},"_meta":{"memory-mcp/api_key":"s3cret"}}`)
+	if len(got) != 2 || got[0] != "" || !strings.Contains(got[1], "access denied") {
+		t.Fatalf("keyed session errors = %q, want the second write denied", got)
+	}
+	got = session(NewServer(svc, logger, nil, WithAuth(authn, "")), `{"clientInfo":{"name":"codex"}}`)
+	if len(got) != 2 || !strings.Contains(got[0], "authentication required") {
+		t.Fatalf("keyless session errors = %q, want authentication required", got)
+	}
+	got = session(NewServer(svc, logger, nil, WithAuth(authn, "s3cret")), `{"clientInfo":{"name":"codex"}}`)
+	if len(got) != 2 || got[0] != "" || !strings.Contains(got[1], "access denied") {
+		t.Fatalf("session with the server key errors = %q, want the second write denied", got)
+	}
+}
@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/xiy/memory-mcp/internal/config"
+)
+
+// toolNamePattern is the set of names MCP clients accept for tools.
+var toolNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// CheckToolsConfig reports tool names in cfg that the server does not
+// define, and prefixes or aliases that produce invalid or clashing names.
+func CheckToolsConfig(cfg config.ToolsConfig) error {
+	known := map[string]bool{}
+	for _, def := range toolDefinitions() {
+		known[def.Name] = true
+	}
+	aliased := make([]string, 0, len(cfg.Aliases))
+	for name := range cfg.Aliases {
+		aliased = append(aliased, name)
+	}
+	for _, list := range []struct {
+		key   string
+		names []string
+	}{{"tools.allow", cfg.Allow}, {"tools.deny", cfg.Deny}, {"tools.aliases", aliased}} {
+		for _, name := range list.names {
+			if !known[name] {
+				return fmt.Errorf("%s: unknown tool %q", list.key, name)
+			}
+		}
+	}
+	exposed := map[string]string{}
+	for _, def := range toolDefinitions() {
+		name := cfg.ToolName(def.Name)
+		if !toolNamePattern.MatchString(name) {
+			return fmt.Errorf("tool %s would be exposed as %q; names must be 1-64 letters, digits, _ or -", def.Name, name)
+		}
+		if other, ok := exposed[name]; ok {
+			return fmt.Errorf("tools %s and %s would both be exposed as %q", other, def.Name, name)
+		}
+		exposed[name] = def.Name
+	}
+	return nil
+}
+
+// WithTools hides tools not in cfg.Allow (when set) or in cfg.Deny from
+// tools/list and rejects calls to them, and exposes tools under their
+// prefixed or aliased names. Check cfg with CheckToolsConfig first.
+func WithTools(cfg config.ToolsConfig) ServerOption {
+	return func(s *Server) {
+		s.exposed = map[string]string{}
+		s.canonical = map[string]string{}
+		for _, def := range toolDefinitions() {
+			name := cfg.ToolName(def.Name)
+			s.exposed[def.Name] = name
+			s.canonical[name] = def.Name
+		}
+		s.disabled = map[string]bool{}
+		allowed := map[string]bool{}
+		for _, name := range cfg.Allow {
+			allowed[name] = true
+		}
+		for _, def := range toolDefinitions() {
+			if len(cfg.Allow) > 0 && !allowed[def.Name] {
+				s.disabled[def.Name] = true
+			}
+		}
+		for _, name := range cfg.Deny {
+			s.disabled[name] = true
+		}
+	}
+}
+
+// tools returns the definitions of the enabled tools under their exposed
+// names.
+func (s *Server) tools() []ToolDefinition {
+	defs := toolDefinitions()
+	out := defs[:0]
+	for _, def := range defs {
+		if s.disabled[def.Name] {
+			continue
+		}
+		if name, ok := s.exposed[def.Name]; ok {
+			def.Name = name
+		}
+		if s.namespace != "" {
+			optionalNamespace(def, s.namespace)
+		}
+		out = append(out, def)
+	}
+	return out
+}
+
+// resolveTool maps an exposed tool name back to the tool it names.
+func (s *Server) resolveTool(name string) (string, bool) {
+	if s.canonical == nil {
+		return name, true
+	}
+	tool, ok := s.canonical[name]
+	return tool, ok
+}
@@ -1,5 +1,7 @@
 package mcp
 
+import "github.com/xiy/memory-mcp/internal/config"
+
 // ToolDefinition models MCP tool metadata.
 type ToolDefinition struct {
 	Name        string         `json:"name"`
@@ -11,15 +13,16 @@ func toolDefinitions() []ToolDefinition {
 	return []ToolDefinition{
 		{
 			Name:        "memory_write",
-			Description: "Store a new short-term or long-term memory entry.",
+			Description: "Store a new short-term, long-term, project or global memory entry.",
 			InputSchema: jsonSchema(map[string]any{
 				"namespace":    propString("Namespace key (e.g. org/repo/branch/task)."),
-				"scope":        propStringEnum("Memory scope.", []string{"short", "long"}),
+				"scope":        propStringEnum("Memory scope: short expires, long stays in the namespace, project is shared by the project's namespaces and global by all.", config.Scopes),
 				"content":      propString("Primary memory content."),
 				"summary":      propString("Optional summary."),
 				"importance":   propNumber("Importance 1-5."),
 				"source_agent": propString("Agent identifier."),
 				"ttl_seconds":  propNumber("Optional TTL in seconds for short-term memory."),
+				"external_key": propString("Optional client identifier, unique per namespace. Writing an existing key updates that memory in place."),
 				"metadata": map[string]any{
 					"type": "object",
 				},
@@ -27,35 +30,180 @@ func toolDefinitions() []ToolDefinition {
 		},
 		{
 			Name:        "memory_search",
-			Description: "Search memory by lexical relevance + recency + importance.",
+			Description: "Search memory by lexical relevance + recency + importance. When nothing matches, the result may carry suggestions: corrected queries worth retrying.",
 			InputSchema: jsonSchema(map[string]any{
-				"namespace":        propString("Namespace key."),
-				"query":            propString("Search query."),
-				"scope":            propStringEnum("Optional scope filter.", []string{"short", "long"}),
-				"k":                propNumber("Maximum results."),
-				"include_metadata": propBoolean("Whether to include metadata in results."),
+				"namespace":           propString("Namespace key."),
+				"query":               propString("Search query."),
+				"scope":               propStringEnum("Optional scope filter.", config.Scopes),
+				"k":                   propNumber("Maximum results."),
+				"include_metadata":    propBoolean("Whether to include metadata in results."),
+				"filter":              propString(`Optional metadata filter, e.g. metadata.priority >= 2 and metadata.files contains "auth.go". Operators: = != < <= > >= contains.`),
+				"source_agent":        propString("Searching agent identifier, used for per-agent ranking."),
+				"written_by":          propString("Only return memories whose source_agent is this agent."),
+				"explain":             propBoolean("Include a per-term score breakdown with each result."),
+				"include_ancestors":   propBoolean("Also search parent namespaces (org/repo for org/repo/branch/task); each level away lowers the score."),
+				"include_descendants": propBoolean("Also search the namespaces below this one; each level away lowers the score."),
+				"collapse_superseded": propBoolean("Drop memories that another memory supersedes (memory_link or memory_merge)."),
+				"advanced_query":      propBoolean("Treat query as an SQLite FTS5 expression: AND, OR, NOT, NEAR(a b, 5), prefix*, \"phrases\" and column filters (search_text:, meta_text:). SQLite store only."),
 			}, []string{"namespace", "query"}),
 		},
+		{
+			Name:        "memory_list",
+			Description: "Browse memories page by page without a query, newest first by default. Pass next_cursor from the result as cursor to get the next page.",
+			InputSchema: jsonSchema(map[string]any{
+				"namespace":      propString("Namespace prefix; matches the namespace and everything below it. Empty lists all namespaces."),
+				"scope":          propStringEnum("Optional scope filter.", config.Scopes),
+				"min_importance": propNumber("Lowest importance to include (1-5)."),
+				"max_importance": propNumber("Highest importance to include (1-5)."),
+				"created_after":  propString("RFC 3339 time; only memories created at or after it."),
+				"created_before": propString("RFC 3339 time; only memories created before it."),
+				"written_by":     propString("Only list memories whose source_agent is this agent."),
+				"sort":           propStringEnum("Order of the listing.", []string{"created_desc", "created_asc", "importance_desc"}),
+				"limit":          propNumber("Page size (default 50, max 500)."),
+				"cursor":         propString("next_cursor of the previous page."),
+			}, []string{}),
+		},
 		{
 			Name:        "memory_get_context_pack",
 			Description: "Return a compact, deduplicated context pack under a token budget.",
 			InputSchema: jsonSchema(map[string]any{
-				"namespace":    propString("Namespace key."),
-				"query":        propString("Query for retrieving context."),
-				"token_budget": propNumber("Maximum estimated tokens."),
-				"scope":        propStringEnum("Optional scope filter.", []string{"short", "long"}),
-				"k":            propNumber("Maximum candidate items to evaluate."),
+				"namespace":           propString("Namespace key."),
+				"query":               propString("Query for retrieving context."),
+				"token_budget":        propNumber("Maximum estimated tokens."),
+				"scope":               propStringEnum("Optional scope filter.", config.Scopes),
+				"k":                   propNumber("Maximum candidate items to evaluate."),
+				"source_agent":        propString("Requesting agent identifier, used for per-agent ranking."),
+				"include_ancestors":   propBoolean("Also search parent namespaces; each level away lowers the score."),
+				"include_descendants": propBoolean("Also search the namespaces below this one; each level away lowers the score."),
+				"format":              propString("Shape of text: markdown (bullets, the default), xml, json, plain or a template from context_pack.templates."),
+				"sections":            propBoolean("Group the pack into durable knowledge, recent working notes and possibly stale memories, each with its own share of the token budget."),
 			}, []string{"namespace", "query", "token_budget"}),
 		},
+		{
+			Name:        "memory_ask",
+			Description: "Answer a natural-language question from memory. Returns a synthesized answer citing memory IDs when an LLM is configured, and always the supporting snippets.",
+			InputSchema: jsonSchema(map[string]any{
+				"namespace": propString("Namespace key."),
+				"question":  propString("Question to answer."),
+				"scope":     propStringEnum("Optional scope filter.", config.Scopes),
+				"k":         propNumber("Maximum memories to consult (default 8)."),
+			}, []string{"namespace", "question"}),
+		},
+		{
+			Name:        "memory_cluster",
+			Description: "Group a namespace's memories into labeled topics with a representative memory each, to see and prune what has accumulated.",
+			InputSchema: jsonSchema(map[string]any{
+				"namespace": propString("Namespace key."),
+				"scope":     propStringEnum("Optional scope filter.", config.Scopes),
+				"k":         propNumber("Number of clusters (default chosen from the memory count, max 50)."),
+			}, []string{"namespace"}),
+		},
 		{
 			Name:        "memory_promote",
-			Description: "Promote a memory entry to long-term memory.",
+			Description: "Promote a memory entry to a wider scope: long-term, project or global.",
 			InputSchema: jsonSchema(map[string]any{
 				"memory_id":    propString("Memory ID to promote."),
-				"target_scope": propStringEnum("Target scope.", []string{"long"}),
-				"reason":       propString("Optional reason for promotion."),
+				"target_scope": propStringEnum("Target scope (default long).", config.Scopes[1:]),
+				"reason":       propString("Optional reason for promotion, kept in the promotion audit trail."),
+				"source_agent": propString("Optional agent requesting the promotion."),
+			}, []string{"memory_id"}),
+		},
+		{
+			Name:        "memory_demote",
+			Description: "Move a long-term, project or global memory back to short-term scope with a fresh TTL, e.g. when a durable decision was reversed.",
+			InputSchema: jsonSchema(map[string]any{
+				"memory_id":    propString("Memory ID to demote."),
+				"ttl_seconds":  propNumber("Optional TTL in seconds (default default_short_ttl_hours)."),
+				"source_agent": propString("Optional agent requesting the demotion."),
 			}, []string{"memory_id"}),
 		},
+		{
+			Name:        "memory_append",
+			Description: "Append a timestamped entry to an existing memory, e.g. a running debugging log, instead of writing a sibling memory.",
+			InputSchema: jsonSchema(map[string]any{
+				"memory_id":      propString("Memory ID to append to."),
+				"content":        propString("Entry to append."),
+				"delimiter":      propString("Separator placed before the entry (default a blank line)."),
+				"omit_timestamp": propBoolean("Do not prefix the entry with the current time."),
+				"summary":        propString("Optional replacement summary."),
+			}, []string{"memory_id", "content"}),
+		},
+		{
+			Name:        "memory_merge",
+			Description: "Merge several memories into one consolidated record (joined content, highest importance, union of tags and metadata) and mark the originals as superseded.",
+			InputSchema: jsonSchema(map[string]any{
+				"memory_ids":       propStringArray("IDs of the memories to merge, in content order."),
+				"namespace":        propString("Namespace of the merged memory; defaults to the originals' namespace."),
+				"summary":          propString("Optional summary for the merged memory."),
+				"source_agent":     propString("Agent identifier."),
+				"expire_originals": propBoolean("Expire the originals so they drop out of search."),
+			}, []string{"memory_ids"}),
+		},
+		{
+			Name:        "memory_link",
+			Description: "Record how one memory relates to another, e.g. that a new decision supersedes the old one. Context packs then prefer the newest memory of a supersession chain.",
+			InputSchema: jsonSchema(map[string]any{
+				"from_id":      propString("ID of the newer or dependent memory."),
+				"to_id":        propString("ID of the memory it relates to."),
+				"relation":     propStringEnum("How from_id relates to to_id.", []string{"supersedes", "refines", "relates_to", "derived_from"}),
+				"source_agent": propString("Agent identifier."),
+			}, []string{"from_id", "to_id", "relation"}),
+		},
+		{
+			Name:        "memory_facts",
+			Description: "Look up subject-predicate-object facts extracted from memories (e.g. subject \"default ttl\" -> \"48h\"). Requires facts.enabled.",
+			InputSchema: jsonSchema(map[string]any{
+				"namespace": propString("Namespace key."),
+				"subject":   propString("Exact subject, case-insensitive."),
+				"predicate": propString("Exact predicate such as =, is, uses or depends on."),
+				"object":    propString("Substring of the object."),
+				"limit":     propNumber("Maximum facts (default 50)."),
+			}, []string{"namespace"}),
+		},
+		{
+			Name:        "memory_feedback",
+			Description: "Report that a memory returned for a query was useful or irrelevant. Feedback adjusts future search ranking.",
+			InputSchema: jsonSchema(map[string]any{
+				"memory_id":    propString("Memory ID the rating applies to."),
+				"query":        propString("Query the memory was returned for."),
+				"rating":       propStringEnum("Whether the memory helped.", []string{"useful", "irrelevant"}),
+				"source_agent": propString("Agent identifier."),
+			}, []string{"memory_id", "query", "rating"}),
+		},
+		{
+			Name:        "memory_delete",
+			Description: "Delete memory entries by ID. Use dry_run to preview what would be removed.",
+			InputSchema: jsonSchema(map[string]any{
+				"memory_ids": propStringArray("Memory IDs to delete."),
+				"dry_run":    propBoolean("Report affected memories without deleting them."),
+			}, []string{"memory_ids"}),
+		},
+		{
+			Name:        "memory_forget",
+			Description: "Delete every memory matching all the given filters, e.g. the short-term memories of a finished task. Requires namespace or metadata. Returns the count removed; use dry_run to list matches first.",
+			InputSchema: jsonSchema(map[string]any{
+				"namespace":           propString("Namespace to forget memories in."),
+				"include_descendants": propBoolean("Also forget memories in namespaces below namespace."),
+				"scope":               propStringEnum("Optional scope filter.", config.Scopes),
+				"older_than_seconds":  propNumber("Only memories created at least this many seconds ago."),
+				"max_importance":      propNumber("Only memories with at most this importance (1-5)."),
+				"metadata": map[string]any{
+					"type":        "object",
+					"description": "Top-level metadata keys and the values they must equal, compared as text.",
+				},
+				"dry_run": propBoolean("Report matching memories without deleting them."),
+			}, []string{}),
+		},
+		{
+			Name:        "memory_purge",
+			Description: "Delete every memory in any namespace whose metadata key equals value, e.g. user_id=123 for a data-deletion request, or blank them with redact. Reports IDs and namespaces only. Use dry_run to preview.",
+			InputSchema: jsonSchema(map[string]any{
+				"key":     propString("Top-level metadata key, e.g. user_id."),
+				"value":   propString("Value to match, compared as text."),
+				"redact":  propBoolean("Replace content and summary with [redacted] and drop the key instead of deleting."),
+				"dry_run": propBoolean("Report affected memories without changing them."),
+			}, []string{"key", "value"}),
+		},
 	}
 }
 
@@ -82,3 +230,7 @@ func propNumber(description string) map[string]any {
 func propBoolean(description string) map[string]any {
 	return map[string]any{"type": "boolean", "description": description}
 }
+
+func propStringArray(description string) map[string]any {
+	return map[string]any{"type": "array", "description": description, "items": map[string]any{"type": "string"}}
+}
@@ -0,0 +1,45 @@
+package mcp
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/xiy/memory-mcp/internal/mcp")
+
+// startRequestSpan starts the server span of one JSON-RPC request, named
+// after its method and, for tools/call, the tool, e.g.
+// "tools/call memory_search".
+func (s *Server) startRequestSpan(ctx context.Context, req request) (context.Context, trace.Span) {
+	name := req.Method
+	attrs := []attribute.KeyValue{semconv.McpMethodNameKey.String(req.Method)}
+	if len(req.ID) > 0 {
+		attrs = append(attrs, semconv.JSONRPCRequestID(string(req.ID)))
+	}
+	if tool := s.loggedToolName(req.Method, req.Params); tool != "" {
+		name += " " + tool
+		attrs = append(attrs, semconv.GenAIToolName(tool))
+	}
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+}
+
+// endRequestSpan records the session's client and resp's outcome on span
+// and ends it.
+func (s *Server) endRequestSpan(ctx context.Context, span trace.Span, resp response) {
+	if client := s.clientName(ctx); client != "" {
+		span.SetAttributes(clientNameKey.String(client))
+	}
+	if !responseSuccessful(resp) {
+		span.SetStatus(codes.Error, responseErrorText(resp))
+	}
+	span.End()
+}
+
+// clientNameKey carries clientInfo.name, which semantic conventions do not
+// cover yet.
+const clientNameKey = attribute.Key("mcp.client.name")
@@ -0,0 +1,92 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/clock"
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/memory"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/internal/transcript"
+)
+
+var update = flag.Bool("update", false, "rewrite golden transcripts in testdata/transcripts from the current server")
+
+// TestGoldenTranscripts replays each session in testdata/transcripts
+// against a fresh server and requires byte-identical responses. After an
+// intended change, regenerate them with
+//
+//	go test ./internal/mcp -run TestGoldenTranscripts -update
+//
+// and review the diff. Sessions recorded with `serve --record` can be added
+// once their ids and timestamps come from the fixed clock used here.
+func TestGoldenTranscripts(t *testing.T) {
+	t.Parallel()
+	paths, err := filepath.Glob(filepath.Join("testdata", "transcripts", "*.txt"))
+	if err != nil {
+		t.Fatalf("Glob() error = %v", err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no golden transcripts found")
+	}
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			t.Parallel()
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			want, err := transcript.Parse(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			got, err := transcript.Replay(ctx, goldenServer(t).Serve, want)
+			if err != nil {
+				t.Fatalf("Replay() error = %v", err)
+			}
+			if *update {
+				var buf bytes.Buffer
+				if _, err := got.WriteTo(&buf); err != nil {
+					t.Fatalf("WriteTo() error = %v", err)
+				}
+				if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+					t.Fatalf("WriteFile() error = %v", err)
+				}
+				return
+			}
+			if err := transcript.Diff(want, got); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// goldenServer returns a server whose responses depend only on the
+// requests: an empty database, content-addressed ids and a fixed clock.
+func goldenServer(t *testing.T) *Server {
+	t.Helper()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(context.Background(), filepath.Join(t.TempDir(), "golden.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	cfg := config.Default()
+	cfg.IDFormat = "content"
+	svc, err := memory.NewService(st, cfg, logger, memory.WithClock(clock.NewManual(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	return NewServer(svc, logger, nil, WithTransportMode(TransportJSONL), WithTools(cfg.Tools))
+}
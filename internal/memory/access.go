@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// accessFlushInterval is how often TrackAccess writes pending accesses.
+	accessFlushInterval = 10 * time.Second
+	// accessBatchSize pending memories wake TrackAccess without waiting for
+	// the interval.
+	accessBatchSize = 200
+)
+
+// accessLog collects retrievals until they are written in one batch, so
+// searches never wait on the store.
+type accessLog struct {
+	mu      sync.Mutex
+	pending map[string]int
+	// flushMu keeps one batch in flight at a time.
+	flushMu sync.Mutex
+	// full wakes TrackAccess when a batch fills up. It holds one signal, so
+	// noteAccess never blocks and never starts a flush of its own that could
+	// outlive the final FlushAccess.
+	full chan struct{}
+}
+
+// noteAccess queues a retrieval of each of ids. A full batch is handed to
+// TrackAccess; without it running, the batch waits for FlushAccess.
+func (s *Service) noteAccess(ids ...string) {
+	if s.accessRecorder == nil || s.cfg.ReadOnly || len(ids) == 0 {
+		return
+	}
+	a := &s.accesses
+	a.mu.Lock()
+	if a.pending == nil {
+		a.pending = map[string]int{}
+	}
+	for _, id := range ids {
+		a.pending[id]++
+	}
+	full := len(a.pending) >= accessBatchSize
+	a.mu.Unlock()
+	if full {
+		select {
+		case a.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// FlushAccess writes the queued retrievals to the store. A failed batch is
+// logged and dropped: access counts are a ranking hint, not a record.
+func (s *Service) FlushAccess(ctx context.Context) {
+	if s.accessRecorder == nil {
+		return
+	}
+	a := &s.accesses
+	a.flushMu.Lock()
+	defer a.flushMu.Unlock()
+	a.mu.Lock()
+	batch := a.pending
+	a.pending = nil
+	a.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+	if err := s.accessRecorder.RecordAccess(ctx, batch, s.now()); err != nil {
+		s.logger.Warn("recording memory access failed", "memories", len(batch), "error", err)
+	}
+}
+
+// TrackAccess flushes queued retrievals every accessFlushInterval, and as
+// soon as a batch fills up, until ctx is done. Callers flush what is left
+// with FlushAccess before closing the store.
+func (s *Service) TrackAccess(ctx context.Context) {
+	ticker := time.NewTicker(accessFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.FlushAccess(ctx)
+		case <-s.accesses.full:
+			s.FlushAccess(ctx)
+		}
+	}
+}
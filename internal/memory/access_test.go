@@ -0,0 +1,227 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/clock"
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestSearchAndContextPack_RecordAccess(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "access.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewManual(now)
+	svc, err := NewService(st, config.Default(), logger, WithClock(clk))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	hit, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/main/task", Scope: "long", Content: "deploy runs from the release branch"})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	miss, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/main/task", Scope: "long", Content: "lint with golangci"})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	clk.Advance(time.Hour)
+	if _, err := svc.Search(ctx, types.SearchInput{Namespace: "org/repo/main/task", Query: "deploy"}); err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	pack, err := svc.ContextPack(ctx, types.ContextPackInput{Namespace: "org/repo/main/task", Query: "deploy release"})
+	if err != nil || len(pack.MemoryIDs) != 1 {
+		t.Fatalf("ContextPack() = %+v, %v, want one memory", pack, err)
+	}
+	if rec, _ := st.GetMemory(ctx, hit.ID); rec.AccessCount != 0 {
+		t.Fatalf("AccessCount before flush = %d, want 0", rec.AccessCount)
+	}
+
+	svc.FlushAccess(ctx)
+	rec, err := st.GetMemory(ctx, hit.ID)
+	if err != nil {
+		t.Fatalf("GetMemory() error = %v", err)
+	}
+	if rec.AccessCount != 2 || !rec.LastAccessedAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("accessed memory count = %d, last access = %v, want 2 at %v", rec.AccessCount, rec.LastAccessedAt, now.Add(time.Hour))
+	}
+	if rec, _ := st.GetMemory(ctx, miss.ID); rec.AccessCount != 0 || !rec.LastAccessedAt.Equal(now) {
+		t.Fatalf("unreturned memory count = %d, last access = %v, want untouched", rec.AccessCount, rec.LastAccessedAt)
+	}
+}
+
+func TestSearch_RanksFrequentlyUsedMemoriesHigher(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "frequency.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc, err := NewService(st, config.Default(), logger, WithClock(clock.NewManual(now)))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	var ids []string
+	for _, content := range []string{"deploy from the release branch", "deploy with the release script"} {
+		rec, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/main/task", Scope: "long", Content: content})
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		ids = append(ids, rec.ID)
+	}
+	if err := st.RecordAccess(ctx, map[string]int{ids[1]: 10}, now); err != nil {
+		t.Fatalf("RecordAccess() error = %v", err)
+	}
+
+	results, err := svc.Search(ctx, types.SearchInput{Namespace: "org/repo/main/task", Query: "deploy release", Explain: true})
+	if err != nil || len(results) != 2 {
+		t.Fatalf("Search() = %v, %v, want both memories", results, err)
+	}
+	if results[0].Record.ID != ids[1] {
+		t.Fatalf("Search() ranked %s first, want the frequently used %s", results[0].Record.ID, ids[1])
+	}
+	want := config.Default().Ranking.FrequencyWeight * 10.0 / 15.0
+	if got := results[0].Explanation.Frequency; math.Abs(got-want) > 1e-9 {
+		t.Fatalf("Explanation.Frequency = %v, want %v", got, want)
+	}
+	if results[1].FrequencyScore != 0 {
+		t.Fatalf("unused memory FrequencyScore = %v, want 0", results[1].FrequencyScore)
+	}
+}
+
+func TestAutoPromote_PromotesFrequentlyUsedMemories(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "access.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	clk := clock.NewManual(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	cfg := config.Default()
+	cfg.AutoPromote = config.AutoPromoteConfig{Enabled: true, IntervalMinutes: 60, MinAccessCount: 2, MinImportance: 3, MinAgeHours: 1}
+	svc, err := NewService(st, cfg, logger, WithClock(clk))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	write := func(content string, importance int) types.MemoryRecord {
+		t.Helper()
+		rec, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/main/task", Content: content, Importance: importance})
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		return rec
+	}
+	hot := write("deploy runs from the release branch", 4)
+	minor := write("deploy logs are noisy", 2)
+	unused := write("lint with golangci", 4)
+
+	for range 2 {
+		if _, err := svc.Search(ctx, types.SearchInput{Namespace: "org/repo/main/task", Query: "deploy"}); err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+	}
+	svc.FlushAccess(ctx)
+	if n, err := svc.AutoPromote(ctx); err != nil || n != 0 {
+		t.Fatalf("AutoPromote() of young memories = %d, %v, want 0", n, err)
+	}
+
+	clk.Advance(2 * time.Hour)
+	if n, err := svc.AutoPromote(ctx); err != nil || n != 1 {
+		t.Fatalf("AutoPromote() = %d, %v, want 1", n, err)
+	}
+	rec, err := st.GetMemory(ctx, hot.ID)
+	if err != nil {
+		t.Fatalf("GetMemory() error = %v", err)
+	}
+	decision, _ := rec.Metadata[MetaAutoPromoted].(map[string]any)
+	if rec.Scope != "long" || rec.ExpiresAt != nil || decision == nil || decision["access_count"] != float64(2) {
+		t.Fatalf("promoted memory = %+v, want long-term with the decision in metadata", rec)
+	}
+	for _, id := range []string{minor.ID, unused.ID} {
+		if rec, _ := st.GetMemory(ctx, id); rec.Scope != "short" {
+			t.Fatalf("memory %s scope = %q, want short", id, rec.Scope)
+		}
+	}
+	promotions, err := st.RecentPromotions(ctx, 10)
+	if err != nil || len(promotions) != 1 || promotions[0].MemoryID != hot.ID || promotions[0].SourceAgent != autoPromoteAgent {
+		t.Fatalf("RecentPromotions() = %+v, %v, want the auto-promotion", promotions, err)
+	}
+	if n, err := svc.AutoPromote(ctx); err != nil || n != 0 {
+		t.Fatalf("second AutoPromote() = %d, %v, want 0", n, err)
+	}
+}
+
+// accessCounter hands each RecordAccess batch to batches.
+type accessCounter struct {
+	*store.MemoryStore
+	batches chan map[string]int
+}
+
+func (a *accessCounter) RecordAccess(_ context.Context, counts map[string]int, _ time.Time) error {
+	a.batches <- counts
+	return nil
+}
+
+func TestTrackAccess_FlushesAFullBatchInTheLoop(t *testing.T) {
+	t.Parallel()
+	st := &accessCounter{MemoryStore: store.NewMemoryStore(), batches: make(chan map[string]int, 4)}
+	svc, err := NewService(st, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	ids := make([]string, accessBatchSize)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("mem-%d", i)
+	}
+
+	// Without TrackAccess a full batch waits for the final flush.
+	svc.noteAccess(ids...)
+	select {
+	case batch := <-st.batches:
+		t.Fatalf("noteAccess() flushed %d memories on its own, want it left for FlushAccess", len(batch))
+	case <-time.After(50 * time.Millisecond):
+	}
+	svc.FlushAccess(context.Background())
+	if batch := <-st.batches; len(batch) != accessBatchSize {
+		t.Fatalf("FlushAccess() recorded %d memories, want %d", len(batch), accessBatchSize)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		svc.TrackAccess(ctx)
+		close(done)
+	}()
+	svc.noteAccess(ids...)
+	select {
+	case batch := <-st.batches:
+		if len(batch) != accessBatchSize {
+			t.Fatalf("TrackAccess() recorded %d memories, want %d", len(batch), accessBatchSize)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("TrackAccess() did not flush a full batch before the interval")
+	}
+	cancel()
+	<-done
+}
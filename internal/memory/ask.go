@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/xiy/memory-mcp/internal/llm"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+const (
+	defaultAskK     = 8
+	askSnippetRunes = 400
+	askSystemPrompt = `You answer questions using only the numbered memories provided. Be direct and brief. Cite every memory you rely on by its ID in square brackets, e.g. [abc123]. If the memories do not contain the answer, say so.`
+	askUserPrompt   = "Memories:\n%s\nQuestion: %s"
+)
+
+var citationRE = regexp.MustCompile(`\[([^\[\]]+)\]`)
+
+// Ask retrieves memories relevant to a question. With an LLM configured it
+// also synthesizes an answer that cites memory IDs; otherwise, or when the
+// LLM fails, the result carries only the supporting snippets.
+func (s *Service) Ask(ctx context.Context, in types.AskInput) (_ types.AskResult, err error) {
+	ctx, span := startSpan(ctx, "memory.Ask", namespaceAttr(in.Namespace))
+	defer func() { endSpan(span, err) }()
+	question := strings.TrimSpace(in.Question)
+	if question == "" {
+		return types.AskResult{}, errors.New("question must not be empty")
+	}
+	k := in.K
+	if k <= 0 {
+		k = defaultAskK
+	}
+	results, err := s.Search(ctx, types.SearchInput{Namespace: in.Namespace, Query: question, Scope: in.Scope, K: k})
+	if err != nil {
+		return types.AskResult{}, err
+	}
+
+	res := types.AskResult{Question: question, Citations: []string{}, Sources: make([]types.AskSource, 0, len(results))}
+	for _, r := range results {
+		res.Sources = append(res.Sources, types.AskSource{
+			ID:      r.Record.ID,
+			Snippet: truncate(strings.TrimSpace(r.Record.Content), askSnippetRunes),
+			Score:   r.Score,
+		})
+	}
+	if s.llm == nil || len(res.Sources) == 0 {
+		return res, nil
+	}
+
+	var b strings.Builder
+	for _, src := range res.Sources {
+		fmt.Fprintf(&b, "[%s] %s\n", src.ID, src.Snippet)
+	}
+	answer, err := s.llm.Complete(ctx, []llm.Message{
+		{Role: "system", Content: askSystemPrompt},
+		{Role: "user", Content: fmt.Sprintf(askUserPrompt, b.String(), question)},
+	})
+	if err != nil {
+		s.logger.Warn("llm answer synthesis failed", "error", err)
+		res.LLMError = err.Error()
+		return res, nil
+	}
+	res.Answer = answer
+	res.Synthesized = true
+	res.Citations = citedIDs(answer, res.Sources)
+	return res, nil
+}
+
+// citedIDs returns the source IDs referenced in answer, in order of first
+// mention. Bracketed text that is not a source ID is ignored.
+func citedIDs(answer string, sources []types.AskSource) []string {
+	known := make(map[string]struct{}, len(sources))
+	for _, src := range sources {
+		known[src.ID] = struct{}{}
+	}
+	out := []string{}
+	seen := map[string]struct{}{}
+	for _, m := range citationRE.FindAllStringSubmatch(answer, -1) {
+		for _, id := range strings.Split(m[1], ",") {
+			id = strings.TrimSpace(id)
+			if _, ok := known[id]; !ok {
+				continue
+			}
+			if _, dup := seen[id]; !dup {
+				seen[id] = struct{}{}
+				out = append(out, id)
+			}
+		}
+	}
+	return out
+}
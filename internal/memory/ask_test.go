@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/llm"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+type fakeLLM struct {
+	answer string
+	err    error
+	prompt []llm.Message
+}
+
+func (f *fakeLLM) Complete(_ context.Context, messages []llm.Message) (string, error) {
+	f.prompt = messages
+	return f.answer, f.err
+}
+
+func askStore() *fakeStore {
+	return &fakeStore{search: []store.Candidate{
+		{Record: types.MemoryRecord{ID: "m-ttl", Namespace: "org/repo/task", Content: "Default TTL is 48h for short-term memories.", Importance: 3}, LexicalScore: 0.9},
+		{Record: types.MemoryRecord{ID: "m-other", Namespace: "org/repo/task", Content: "Unrelated deployment note.", Importance: 3}, LexicalScore: 0.2},
+	}}
+}
+
+func TestAsk_WithoutLLMReturnsSnippets(t *testing.T) {
+	t.Parallel()
+	svc, err := NewService(askStore(), config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	res, err := svc.Ask(context.Background(), types.AskInput{Namespace: "org/repo/task", Question: "what is the default ttl?"})
+	if err != nil {
+		t.Fatalf("Ask() error = %v", err)
+	}
+	if res.Synthesized || res.Answer != "" || len(res.Sources) != 2 || res.Sources[0].ID != "m-ttl" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if _, err := svc.Ask(context.Background(), types.AskInput{Namespace: "org/repo/task"}); err == nil {
+		t.Fatal("expected empty question to fail")
+	}
+}
+
+func TestAsk_SynthesizesAnswerWithCitations(t *testing.T) {
+	t.Parallel()
+	model := &fakeLLM{answer: "Short-term memories live 48h [m-ttl] (see also [m-ttl, m-bogus])."}
+	svc, err := NewService(askStore(), config.Default(), log.NewWithOptions(io.Discard, log.Options{}), WithLLM(model))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	res, err := svc.Ask(context.Background(), types.AskInput{Namespace: "org/repo/task", Question: "what is the default ttl?"})
+	if err != nil {
+		t.Fatalf("Ask() error = %v", err)
+	}
+	if !res.Synthesized || res.Answer != model.answer || !reflect.DeepEqual(res.Citations, []string{"m-ttl"}) {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if len(model.prompt) != 2 || !strings.Contains(model.prompt[1].Content, "[m-ttl] Default TTL is 48h") {
+		t.Fatalf("expected memories in prompt, got %+v", model.prompt)
+	}
+
+	model.err = errors.New("upstream unavailable")
+	res, err = svc.Ask(context.Background(), types.AskInput{Namespace: "org/repo/task", Question: "what is the default ttl?"})
+	if err != nil {
+		t.Fatalf("Ask() error = %v", err)
+	}
+	if res.Synthesized || res.LLMError == "" || len(res.Sources) != 2 {
+		t.Fatalf("expected snippet fallback on LLM failure, got %+v", res)
+	}
+}
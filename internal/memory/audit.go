@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"strings"
+
+	"github.com/xiy/memory-mcp/internal/auth"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// Caller identifies who is calling the service, for the audit log.
+type Caller struct {
+	// Tool is the MCP tool or job making the call, e.g. memory_write.
+	Tool string
+	// Client is the MCP client name, the actor when a call names no agent.
+	Client string
+	// Grant limits the namespaces the caller may use; nil is unrestricted.
+	Grant *auth.Grant
+}
+
+type callerKey struct{}
+
+// WithCaller returns ctx carrying c.
+func WithCaller(ctx context.Context, c Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, c)
+}
+
+func callerOf(ctx context.Context) Caller {
+	c, _ := ctx.Value(callerKey{}).(Caller)
+	return c
+}
+
+// auditEntry describes a change from before to after; a write passes a
+// zero before and a deletion a zero after. agent is the actor when set.
+func (s *Service) auditEntry(ctx context.Context, action, agent string, before, after types.MemoryRecord) store.AuditEntry {
+	c := callerOf(ctx)
+	rec := after
+	if rec.ID == "" {
+		rec = before
+	}
+	actor := strings.TrimSpace(agent)
+	if actor == "" {
+		actor = c.Client
+	}
+	return store.AuditEntry{
+		Action:    action,
+		MemoryID:  rec.ID,
+		Namespace: rec.Namespace,
+		Actor:     actor,
+		Tool:      c.Tool,
+		Before:    before.Summary,
+		After:     after.Summary,
+		At:        s.now(),
+	}
+}
+
+// recordAudit appends entries to the audit log. Failures are logged and
+// never fail the change, which has already happened.
+func (s *Service) recordAudit(ctx context.Context, entries ...store.AuditEntry) {
+	if s.audit == nil || len(entries) == 0 {
+		return
+	}
+	if err := s.audit.RecordAudit(ctx, entries...); err != nil {
+		s.logger.Warn("recording audit log failed", "entries", len(entries), "error", err)
+	}
+}
+
+// auditDeleted records action for each of recs, which are gone.
+func (s *Service) auditDeleted(ctx context.Context, action string, recs []types.MemoryRecord) {
+	if s.audit == nil {
+		return
+	}
+	entries := make([]store.AuditEntry, 0, len(recs))
+	for _, rec := range recs {
+		entries = append(entries, s.auditEntry(ctx, action, "", rec, types.MemoryRecord{}))
+	}
+	s.recordAudit(ctx, entries...)
+}
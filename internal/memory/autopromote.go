@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// MetaAutoPromoted holds why the auto-promotion job promoted a memory:
+// when, and its access count, importance and age in hours at the time.
+const (
+	MetaAutoPromoted = "auto_promoted"
+	autoPromoteAgent = "memory-mcp/auto-promote"
+)
+
+// errNotEligible aborts the update of a memory that changed after it was
+// listed and no longer qualifies.
+var errNotEligible = errors.New("no longer eligible for auto-promotion")
+
+// AutoPromote promotes the short-term memories that cross every
+// auto_promote threshold to long-term and returns how many it promoted.
+// Each keeps the decision under MetaAutoPromoted and is recorded in the
+// promotion and audit trails like a memory_promote. It is a no-op in
+// read-only mode.
+func (s *Service) AutoPromote(ctx context.Context) (_ int, err error) {
+	ctx, span := startSpan(ctx, "memory.AutoPromote")
+	defer func() { endSpan(span, err) }()
+	if s.cfg.ReadOnly {
+		return 0, nil
+	}
+	if callerOf(ctx).Tool == "" {
+		ctx = WithCaller(ctx, Caller{Tool: "auto-promote"})
+	}
+	now := s.now()
+	cfg := s.cfg.AutoPromote
+	minAge := time.Duration(cfg.MinAgeHours) * time.Hour
+	recs, err := s.store.ListMemories(ctx, store.ListFilter{
+		Scope:         "short",
+		MinImportance: cfg.MinImportance,
+		CreatedBefore: now.Add(-minAge),
+		Now:           now,
+	})
+	if err != nil {
+		return 0, err
+	}
+	promoted := 0
+	for _, prior := range recs {
+		if !s.autoPromotable(prior, now) {
+			continue
+		}
+		reason := fmt.Sprintf("auto: access_count %d, importance %d, age %dh", prior.AccessCount, prior.Importance, int(now.Sub(prior.CreatedAt).Hours()))
+		rec, err := s.store.UpdateMemory(ctx, prior.ID, func(rec *types.MemoryRecord) error {
+			if !s.autoPromotable(*rec, now) {
+				return errNotEligible
+			}
+			if rec.Metadata == nil {
+				rec.Metadata = map[string]any{}
+			}
+			rec.Metadata[MetaAutoPromoted] = map[string]any{
+				"at":           now.UTC().Format(time.RFC3339),
+				"access_count": rec.AccessCount,
+				"importance":   rec.Importance,
+				"age_hours":    int(now.Sub(rec.CreatedAt).Hours()),
+			}
+			rec.Scope = "long"
+			rec.ExpiresAt = nil
+			rec.PromotedAt = &now
+			return nil
+		})
+		if errors.Is(err, errNotEligible) {
+			continue
+		}
+		if err != nil {
+			s.logger.Warn("auto-promotion failed", "memory_id", prior.ID, "error", err)
+			continue
+		}
+		s.invalidate(rec.Namespace)
+		s.recordPromotion(ctx, rec, prior.Scope, reason, autoPromoteAgent, now)
+		s.recordAudit(ctx, s.auditEntry(ctx, store.AuditPromote, autoPromoteAgent, prior, rec))
+		promoted++
+	}
+	return promoted, nil
+}
+
+// autoPromotable reports whether short-term rec crosses every auto_promote
+// threshold at now and has not expired yet.
+func (s *Service) autoPromotable(rec types.MemoryRecord, now time.Time) bool {
+	cfg := s.cfg.AutoPromote
+	return rec.Scope == "short" &&
+		(rec.ExpiresAt == nil || rec.ExpiresAt.After(now)) &&
+		rec.AccessCount >= cfg.MinAccessCount &&
+		rec.Importance >= cfg.MinImportance &&
+		now.Sub(rec.CreatedAt) >= time.Duration(cfg.MinAgeHours)*time.Hour
+}
@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xiy/memory-mcp/internal/cluster"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// clusterLimit bounds the memories considered, newest first.
+const clusterLimit = 2000
+
+// Cluster groups the namespace's live memories into lexical topics, each
+// labelled with its heaviest terms and represented by the memory closest to
+// its centre.
+func (s *Service) Cluster(ctx context.Context, in types.ClusterInput) (_ types.ClusterResult, err error) {
+	ctx, span := startSpan(ctx, "memory.Cluster", namespaceAttr(in.Namespace))
+	defer func() { endSpan(span, err) }()
+	if err := s.validateNamespace(in.Namespace); err != nil {
+		return types.ClusterResult{}, err
+	}
+	if err := checkNamespace(ctx, in.Namespace, false); err != nil {
+		return types.ClusterResult{}, err
+	}
+	in.Scope = strings.TrimSpace(strings.ToLower(in.Scope))
+	if in.Scope != "" && !validScope(in.Scope) {
+		return types.ClusterResult{}, fmt.Errorf("invalid scope %q", in.Scope)
+	}
+	if in.K < 0 || in.K > 50 {
+		return types.ClusterResult{}, errors.New("k must be between 0 and 50")
+	}
+	recs, err := s.store.ListMemories(ctx, store.ListFilter{
+		NamespacePrefix: in.Namespace,
+		Scope:           in.Scope,
+		Now:             s.now(),
+		Limit:           clusterLimit,
+	})
+	if err != nil {
+		return types.ClusterResult{}, err
+	}
+
+	byID := make(map[string]types.MemoryRecord, len(recs))
+	docs := make([]cluster.Doc, 0, len(recs))
+	for _, rec := range recs {
+		if rec.Namespace != in.Namespace {
+			continue
+		}
+		byID[rec.ID] = rec
+		docs = append(docs, cluster.Doc{ID: rec.ID, Text: rec.Summary + "\n" + rec.Content})
+	}
+
+	res := types.ClusterResult{Namespace: in.Namespace, Memories: len(docs), Clusters: []types.Cluster{}}
+	for _, g := range cluster.Run(docs, in.K) {
+		rep := byID[g.Representative]
+		summary := strings.TrimSpace(rep.Summary)
+		if summary == "" {
+			summary = autoSummary(rep.Content)
+		}
+		res.Clusters = append(res.Clusters, types.Cluster{
+			Label:            g.Label(),
+			Terms:            g.Terms,
+			Size:             len(g.Members),
+			RepresentativeID: rep.ID,
+			Summary:          summary,
+			MemoryIDs:        g.Members,
+		})
+	}
+	return res, nil
+}
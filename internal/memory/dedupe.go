@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/dedupe"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// DefaultDuplicateThreshold is the word-set similarity at which two
+// memories count as near duplicates.
+const DefaultDuplicateThreshold = 0.8
+
+// ErrDuplicate is returned by Write when write_dedupe.policy is reject and
+// the namespace already holds a near-identical memory.
+var ErrDuplicate = errors.New("duplicate memory")
+
+// writeDedupeCandidates is how many of the namespace's best search
+// matches a write is compared against.
+const writeDedupeCandidates = 10
+
+// FindDuplicates reports exact and near-duplicate memories within each
+// namespace, or only within namespace when it is set. Memories already
+// superseded by a merge are ignored. Each group can be passed to Merge.
+func (s *Service) FindDuplicates(ctx context.Context, namespace string, threshold float64) ([]types.DuplicateGroup, error) {
+	if threshold <= 0 || threshold > 1 {
+		return nil, errors.New("threshold must be in (0, 1]")
+	}
+	now := s.now()
+	var namespaces []string
+	if namespace != "" {
+		if err := s.validateNamespace(namespace); err != nil {
+			return nil, err
+		}
+		namespaces = []string{namespace}
+	} else {
+		groups, err := s.store.CountBy(ctx, store.GroupNamespace, store.ListFilter{Now: now})
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			namespaces = append(namespaces, g.Key)
+		}
+	}
+
+	out := []types.DuplicateGroup{}
+	for _, ns := range namespaces {
+		recs, err := s.store.ListMemories(ctx, store.ListFilter{Namespace: ns, Now: now, Sort: store.SortCreatedAsc})
+		if err != nil {
+			return nil, err
+		}
+		byID := map[string]types.MemoryRecord{}
+		var docs []dedupe.Doc
+		for _, rec := range recs {
+			if _, merged := rec.Metadata[MetaSupersededBy]; merged {
+				continue
+			}
+			byID[rec.ID] = rec
+			docs = append(docs, dedupe.Doc{ID: rec.ID, Text: rec.Content})
+		}
+		for _, g := range dedupe.Find(docs, threshold) {
+			first := byID[g.IDs[0]]
+			summary := strings.TrimSpace(first.Summary)
+			if summary == "" {
+				summary = autoSummary(first.Content)
+			}
+			out = append(out, types.DuplicateGroup{
+				Namespace:  ns,
+				MemoryIDs:  g.IDs,
+				Exact:      g.Exact,
+				Similarity: g.Similarity,
+				Summary:    summary,
+			})
+		}
+	}
+	return out, nil
+}
+
+// findDuplicate returns the live memory in in.Namespace that in.Content
+// duplicates most closely under write_dedupe, with its similarity, or nil.
+// Candidates are the namespace's best lexical matches for the content and,
+// with semantic_threshold set, its nearest embeddings. Memories superseded
+// by a merge are ignored.
+func (s *Service) findDuplicate(ctx context.Context, in types.WriteInput, now time.Time) (*types.MemoryRecord, float64, error) {
+	cfg := s.cfg.WriteDedupe
+	cands, err := s.store.SearchCandidates(ctx, store.SearchQuery{
+		Namespace: in.Namespace,
+		Query:     in.Content,
+		Limit:     writeDedupeCandidates,
+		Now:       now,
+		StopWords: s.stopWords,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	var (
+		best    *types.MemoryRecord
+		bestSim float64
+	)
+	consider := func(rec types.MemoryRecord, sim, threshold float64) {
+		if _, merged := rec.Metadata[MetaSupersededBy]; merged || sim < threshold || sim <= bestSim {
+			return
+		}
+		best, bestSim = &rec, sim
+	}
+	for _, c := range cands {
+		consider(c.Record, dedupe.Similarity(in.Content, c.Record.Content), cfg.Threshold)
+	}
+	if cfg.SemanticThreshold > 0 {
+		sims := s.semanticMatches(ctx, store.VectorQuery{Now: now, Limit: writeDedupeCandidates}, []string{in.Namespace}, in.Content)
+		for _, id := range slices.Sorted(maps.Keys(sims)) {
+			if sims[id] < cfg.SemanticThreshold || sims[id] <= bestSim {
+				continue
+			}
+			rec, err := s.store.GetMemory(ctx, id)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return nil, 0, err
+			}
+			consider(rec, sims[id], cfg.SemanticThreshold)
+		}
+	}
+	return best, bestSim, nil
+}
+
+// mergeInto folds a duplicate write into memory id: content it does not
+// already hold is appended, importance and scope only rise, and tags and
+// metadata are combined, the stored values winning on conflicting keys.
+// A memory whose scope has a TTL gets a fresh one.
+func (s *Service) mergeInto(ctx context.Context, id string, in types.WriteInput, now time.Time) (types.MemoryRecord, error) {
+	var before types.MemoryRecord
+	rec, err := s.store.UpdateMemory(ctx, id, func(rec *types.MemoryRecord) error {
+		before = *rec
+		merged := mergeRecords([]types.MemoryRecord{*rec, {Content: in.Content, Importance: in.Importance, Scope: in.Scope, Metadata: in.Metadata}})
+		for _, k := range []string{MetaMergedFrom, MetaSupersededBy} {
+			if v, ok := rec.Metadata[k]; ok {
+				merged.Metadata[k] = v
+			}
+		}
+		switch {
+		case strings.TrimSpace(in.Summary) != "":
+			rec.Summary = strings.TrimSpace(in.Summary)
+		case merged.Content != rec.Content:
+			rec.Summary = autoSummary(merged.Content)
+		}
+		rec.Content = merged.Content
+		rec.Importance = merged.Importance
+		rec.Metadata = merged.Metadata
+		if merged.Scope != rec.Scope {
+			rec.Scope = merged.Scope
+			rec.PromotedAt = &now
+		}
+		rec.ExpiresAt = s.scopeExpiry(types.WriteInput{Scope: rec.Scope, TTLSeconds: in.TTLSeconds}, now)
+		rec.LastAccessedAt = now
+		return nil
+	})
+	if err != nil {
+		return types.MemoryRecord{}, err
+	}
+	s.rewritten(ctx, before, rec, in.SourceAgent, now)
+	return rec, nil
+}
@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"errors"
+
+	"github.com/xiy/memory-mcp/internal/facts"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// ErrFactsDisabled is returned by Facts when extraction is not enabled.
+var ErrFactsDisabled = errors.New("fact extraction is disabled; set facts.enabled with the sqlite store")
+
+// extractFacts records the facts in rec's content. Extraction is best
+// effort: failures are logged and never fail the write that triggered it.
+func (s *Service) extractFacts(ctx context.Context, rec types.MemoryRecord) {
+	if s.factStore == nil {
+		return
+	}
+	triples := facts.Extract(rec.Content)
+	out := make([]store.Fact, 0, len(triples))
+	for _, t := range triples {
+		out = append(out, store.Fact{Subject: t.Subject, Predicate: t.Predicate, Object: t.Object})
+	}
+	if err := s.factStore.ReplaceFacts(ctx, rec.ID, rec.Namespace, out, s.now()); err != nil {
+		s.logger.Warn("fact extraction failed", "memory_id", rec.ID, "error", err)
+	}
+}
+
+// Facts looks up extracted facts in a namespace.
+func (s *Service) Facts(ctx context.Context, in types.FactsInput) (_ []store.Fact, err error) {
+	ctx, span := startSpan(ctx, "memory.Facts", namespaceAttr(in.Namespace))
+	defer func() { endSpan(span, err) }()
+	if s.factStore == nil {
+		return nil, ErrFactsDisabled
+	}
+	if err := s.validateNamespace(in.Namespace); err != nil {
+		return nil, err
+	}
+	if err := checkNamespace(ctx, in.Namespace, false); err != nil {
+		return nil, err
+	}
+	limit := in.Limit
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	out, err := s.factStore.QueryFacts(ctx, store.FactQuery{
+		Namespace: in.Namespace,
+		Subject:   facts.NormalizeTerm(in.Subject),
+		Predicate: facts.NormalizeTerm(in.Predicate),
+		Object:    in.Object,
+		Now:       s.now(),
+		Limit:     limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out == nil {
+		out = []store.Fact{}
+	}
+	return out, nil
+}
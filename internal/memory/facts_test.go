@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestFacts_ExtractedOnWriteAndAppend(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "facts.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Facts.Enabled = true
+	svc, err := NewService(st, cfg, logger)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	rec, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "Default TTL = 48h. The worker uses SQLite."})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	got, err := svc.Facts(ctx, types.FactsInput{Namespace: "org/repo/task", Subject: "Default TTL"})
+	if err != nil {
+		t.Fatalf("Facts() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Object != "48h" || got[0].MemoryID != rec.ID {
+		t.Fatalf("unexpected facts %+v", got)
+	}
+
+	if _, err := svc.Append(ctx, types.AppendInput{MemoryID: rec.ID, Content: "Cache size: 1024", OmitTimestamp: true}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	got, err = svc.Facts(ctx, types.FactsInput{Namespace: "org/repo/task"})
+	if err != nil {
+		t.Fatalf("Facts() error = %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected facts to be re-extracted after append, got %+v", got)
+	}
+	got, err = svc.Facts(ctx, types.FactsInput{Namespace: "org/repo/task", Predicate: "uses", Object: "sqlite"})
+	if err != nil || len(got) != 1 || got[0].Subject != "the worker" {
+		t.Fatalf("unexpected predicate/object lookup %+v, %v", got, err)
+	}
+
+	if _, err := svc.Delete(ctx, types.DeleteInput{MemoryIDs: []string{rec.ID}}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	got, err = svc.Facts(ctx, types.FactsInput{Namespace: "org/repo/task"})
+	if err != nil || len(got) != 0 {
+		t.Fatalf("expected facts to go with their memory, got %+v, %v", got, err)
+	}
+}
+
+func TestFacts_DisabledByDefault(t *testing.T) {
+	t.Parallel()
+	svc, err := NewService(&fakeStore{}, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	if _, err := svc.Facts(context.Background(), types.FactsInput{Namespace: "org/repo/task"}); !errors.Is(err, ErrFactsDisabled) {
+		t.Fatalf("expected ErrFactsDisabled, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// Feedback ratings accepted by Feedback.
+const (
+	RatingUseful     = "useful"
+	RatingIrrelevant = "irrelevant"
+)
+
+// ErrFeedbackUnsupported is returned by Feedback when the store does not
+// keep relevance feedback.
+var ErrFeedbackUnsupported = errors.New("feedback requires the sqlite store")
+
+// Feedback records that a memory was useful or irrelevant for a query.
+// Later searches add ranking.feedback_weight times the memory's learned
+// score, weighting reports for the same query more heavily.
+func (s *Service) Feedback(ctx context.Context, in types.FeedbackInput) (_ types.FeedbackResult, err error) {
+	ctx, span := startSpan(ctx, "memory.Feedback", idAttr(in.MemoryID))
+	defer func() { endSpan(span, err) }()
+	if s.cfg.ReadOnly {
+		return types.FeedbackResult{}, ErrReadOnly
+	}
+	if s.feedback == nil {
+		return types.FeedbackResult{}, ErrFeedbackUnsupported
+	}
+	if strings.TrimSpace(in.MemoryID) == "" {
+		return types.FeedbackResult{}, errors.New("memory_id is required")
+	}
+	in.Rating = strings.ToLower(strings.TrimSpace(in.Rating))
+	var signal int
+	switch in.Rating {
+	case RatingUseful:
+		signal = 1
+	case RatingIrrelevant:
+		signal = -1
+	default:
+		return types.FeedbackResult{}, fmt.Errorf("invalid rating %q (expected %s or %s)", in.Rating, RatingUseful, RatingIrrelevant)
+	}
+	rec, err := s.store.GetMemory(ctx, in.MemoryID)
+	if err == nil {
+		err = checkRecord(ctx, rec, true)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.FeedbackResult{}, fmt.Errorf("memory %s not found", in.MemoryID)
+		}
+		return types.FeedbackResult{}, err
+	}
+
+	query := normalize(in.Query)
+	err = s.feedback.RecordFeedback(ctx, store.Feedback{
+		MemoryID:    rec.ID,
+		Namespace:   rec.Namespace,
+		Query:       query,
+		Signal:      signal,
+		SourceAgent: strings.TrimSpace(in.SourceAgent),
+		CreatedAt:   s.now(),
+	})
+	if err != nil {
+		return types.FeedbackResult{}, err
+	}
+	// Feedback reranks later searches, so packs built before it are stale.
+	s.packs.invalidate(rec.Namespace)
+	scores, err := s.feedback.FeedbackScores(ctx, query, []string{rec.ID})
+	if err != nil {
+		return types.FeedbackResult{}, err
+	}
+	return types.FeedbackResult{MemoryID: rec.ID, Rating: in.Rating, Score: scores[rec.ID]}, nil
+}
+
+// applyFeedback adds the learned feedback term to results. It is best
+// effort: a failed lookup is logged and leaves the scores unchanged.
+func (s *Service) applyFeedback(ctx context.Context, query string, results []types.SearchResult) {
+	if s.feedback == nil || s.cfg.Ranking.FeedbackWeight == 0 || len(results) == 0 {
+		return
+	}
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.Record.ID
+	}
+	scores, err := s.feedback.FeedbackScores(ctx, normalize(query), ids)
+	if err != nil {
+		s.logger.Warn("feedback lookup failed", "error", err)
+		return
+	}
+	for i := range results {
+		fb := scores[results[i].Record.ID]
+		results[i].FeedbackScore = fb
+		results[i].Score += s.cfg.Ranking.FeedbackWeight * fb
+		if e := results[i].Explanation; e != nil {
+			e.Feedback = s.cfg.Ranking.FeedbackWeight * fb
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package memory
+
+import (
+	"context"
+	"io"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/clock"
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestFeedback_AdjustsRankingAndStats(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "feedback.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc, err := NewService(st, config.Default(), logger, WithClock(clock.NewManual(now)))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	first, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "deploy checklist for staging", Importance: 4})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	second, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "deploy checklist for production", Importance: 3})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	search := func() []types.SearchResult {
+		t.Helper()
+		res, err := svc.Search(ctx, types.SearchInput{Namespace: "org/repo/task", Query: "deploy checklist"})
+		if err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+		if len(res) != 2 {
+			t.Fatalf("expected 2 results, got %d", len(res))
+		}
+		return res
+	}
+	if res := search(); res[0].Record.ID != first.ID {
+		t.Fatalf("expected %s first before feedback, got %s", first.ID, res[0].Record.ID)
+	}
+
+	for range 3 {
+		if _, err := svc.Feedback(ctx, types.FeedbackInput{MemoryID: second.ID, Query: "Deploy  checklist", Rating: "useful"}); err != nil {
+			t.Fatalf("Feedback() error = %v", err)
+		}
+	}
+	got, err := svc.Feedback(ctx, types.FeedbackInput{MemoryID: first.ID, Query: "deploy checklist", Rating: "irrelevant"})
+	if err != nil {
+		t.Fatalf("Feedback() error = %v", err)
+	}
+	if got.Score >= 0 {
+		t.Fatalf("expected a negative score after an irrelevant rating, got %+v", got)
+	}
+	res := search()
+	if res[0].Record.ID != second.ID || res[0].FeedbackScore <= 0 || res[1].FeedbackScore >= 0 {
+		t.Fatalf("expected feedback to reorder results, got %+v", res)
+	}
+
+	stats, err := st.Stats(ctx, now)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.Useful != 3 || stats.Irrelevant != 1 {
+		t.Fatalf("unexpected feedback stats %+v", stats)
+	}
+	if _, err := svc.Feedback(ctx, types.FeedbackInput{MemoryID: first.ID, Query: "x", Rating: "meh"}); err == nil {
+		t.Fatal("expected invalid rating to fail")
+	}
+}
+
+func TestSearch_PersonalizesByAgentAndExplains(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "agents.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Ranking.Agents = map[string]map[string]float64{"Claude": {"decision": 0.2}}
+	svc, err := NewService(st, cfg, logger, WithClock(clock.NewManual(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	code, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "auth retry lives in client.go", Importance: 4,
+		Metadata: map[string]any{"tags": []any{"code"}}})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	decision, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "auth retry was capped at three attempts", Importance: 3,
+		Metadata: map[string]any{"tags": []any{"decision"}}})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	search := func(agent string) []types.SearchResult {
+		t.Helper()
+		res, err := svc.Search(ctx, types.SearchInput{Namespace: "org/repo/task", Query: "auth retry", SourceAgent: agent, Explain: true})
+		if err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+		return res
+	}
+
+	if res := search(""); res[0].Record.ID != code.ID || res[0].Explanation.Agent != 0 {
+		t.Fatalf("expected unpersonalized order to favour %s, got %+v", code.ID, res)
+	}
+	res := search("claude")
+	if res[0].Record.ID != decision.ID || res[0].Explanation.AgentTags["decision"] != 0.2 {
+		t.Fatalf("expected claude's decision boost to win, got %+v", res[0])
+	}
+	e := res[0].Explanation
+	if sum := e.Lexical + e.Recency + e.Importance + e.Feedback + e.Agent; math.Abs(sum-res[0].Score) > 1e-9 {
+		t.Fatalf("explanation terms sum to %v, score is %v", sum, res[0].Score)
+	}
+
+	if _, err := svc.Feedback(ctx, types.FeedbackInput{MemoryID: code.ID, Query: "where is the client", Rating: "useful", SourceAgent: "codex"}); err != nil {
+		t.Fatalf("Feedback() error = %v", err)
+	}
+	for _, r := range search("Codex") {
+		if r.Record.ID == code.ID && r.Explanation.AgentTags["code"] <= 0 {
+			t.Fatalf("expected codex to learn an affinity for code memories, got %+v", r.Explanation)
+		}
+	}
+}
+
+func TestSearch_FiltersAndBoostsByWritingAgent(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "authors.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Ranking.OwnAgentBoost = 0.5
+	svc, err := NewService(st, cfg, logger, WithClock(clock.NewManual(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	codex, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo", Content: "deploy retries twice before paging", Importance: 5, SourceAgent: "codex"})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	claude, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo", Content: "deploy window closes at five", Importance: 2, SourceAgent: "claude"})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	res, err := svc.Search(ctx, types.SearchInput{Namespace: "org/repo", Query: "deploy", WrittenBy: "claude"})
+	if err != nil || len(res) != 1 || res[0].Record.ID != claude.ID {
+		t.Fatalf("Search(written_by claude) = %+v, %v, want only %s", res, err, claude.ID)
+	}
+	list, err := svc.List(ctx, types.ListInput{Namespace: "org", WrittenBy: "codex"})
+	if err != nil || len(list.Memories) != 1 || list.Memories[0].ID != codex.ID {
+		t.Fatalf("List(written_by codex) = %+v, %v, want only %s", list, err, codex.ID)
+	}
+
+	res, err = svc.Search(ctx, types.SearchInput{Namespace: "org/repo", Query: "deploy", SourceAgent: "Claude", Explain: true})
+	if err != nil || len(res) != 2 {
+		t.Fatalf("Search() = %+v, %v", res, err)
+	}
+	if res[0].Record.ID != claude.ID || res[0].Explanation.OwnAgent != 0.5 || res[1].Explanation.OwnAgent != 0 {
+		t.Fatalf("expected claude's own memory boosted first, got %+v then %+v", res[0].Explanation, res[1].Explanation)
+	}
+
+}
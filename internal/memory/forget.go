@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// Forget deletes every memory matching in's filters, expired or not. The
+// matches are listed first only for dry runs and the audit log; the delete
+// itself is one store call. Dry runs are allowed in read-only mode.
+func (s *Service) Forget(ctx context.Context, in types.ForgetInput) (_ types.ForgetResult, err error) {
+	ctx, span := startSpan(ctx, "memory.Forget", namespaceAttr(in.Namespace))
+	defer func() { endSpan(span, err) }()
+	f, err := s.forgetFilter(in)
+	if err != nil {
+		return types.ForgetResult{}, err
+	}
+	if s.cfg.ReadOnly && !in.DryRun {
+		return types.ForgetResult{}, ErrReadOnly
+	}
+	if err := checkNamespace(ctx, cmp.Or(f.Namespace, f.NamespacePrefix), !in.DryRun); err != nil {
+		return types.ForgetResult{}, err
+	}
+
+	res := types.ForgetResult{DryRun: in.DryRun}
+	var recs []types.MemoryRecord
+	if in.DryRun || s.audit != nil {
+		f.Sort = store.SortCreatedAsc
+		if recs, err = s.store.ListMemories(ctx, f); err != nil {
+			return types.ForgetResult{}, err
+		}
+	}
+	if in.DryRun {
+		res.Count = int64(len(recs))
+		res.Memories = make([]types.PurgedMemory, 0, len(recs))
+		for _, rec := range recs {
+			res.Memories = append(res.Memories, types.PurgedMemory{ID: rec.ID, Namespace: rec.Namespace, Scope: rec.Scope, CreatedAt: rec.CreatedAt})
+		}
+		return res, nil
+	}
+
+	res.Count, err = s.store.DeleteByFilter(ctx, f)
+	if res.Count > 0 {
+		s.invalidateAll()
+		s.auditDeleted(ctx, store.AuditDelete, recs)
+	}
+	return res, err
+}
+
+// forgetFilter validates in and translates it into a store filter.
+func (s *Service) forgetFilter(in types.ForgetInput) (store.ListFilter, error) {
+	in.Namespace = strings.Trim(strings.TrimSpace(in.Namespace), "/")
+	if in.Namespace == "" && len(in.Metadata) == 0 {
+		return store.ListFilter{}, errors.New("namespace or metadata is required")
+	}
+	f := store.ListFilter{IncludeExpired: true, MaxImportance: in.MaxImportance}
+	if in.Namespace != "" {
+		if err := s.validateNamespace(in.Namespace); err != nil {
+			return store.ListFilter{}, err
+		}
+		if in.IncludeDescendants {
+			f.NamespacePrefix = in.Namespace
+		} else {
+			f.Namespace = in.Namespace
+		}
+	}
+	f.Scope = strings.TrimSpace(strings.ToLower(in.Scope))
+	if f.Scope != "" && !validScope(f.Scope) {
+		return store.ListFilter{}, fmt.Errorf("invalid scope %q", in.Scope)
+	}
+	if in.OlderThanSeconds < 0 {
+		return store.ListFilter{}, errors.New("older_than_seconds must be >= 0")
+	}
+	if in.OlderThanSeconds > 0 {
+		f.CreatedBefore = s.now().Add(-time.Duration(in.OlderThanSeconds) * time.Second)
+	}
+	if in.MaxImportance < 0 || in.MaxImportance > 5 {
+		return store.ListFilter{}, errors.New("max_importance must be between 0 and 5")
+	}
+	for key, value := range in.Metadata {
+		if !store.ValidMetadataColumnKey(key) {
+			return store.ListFilter{}, fmt.Errorf("invalid metadata key %q", key)
+		}
+		if f.Metadata == nil {
+			f.Metadata = map[string]string{}
+		}
+		f.Metadata[key] = value
+	}
+	return f, nil
+}
@@ -0,0 +1,30 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// checkNamespace returns an auth.ErrForbidden error unless the caller's
+// grant covers namespace, and writes to it when write is set. An empty
+// namespace means every namespace.
+func checkNamespace(ctx context.Context, namespace string, write bool) error {
+	return callerOf(ctx).Grant.Check(namespace, write)
+}
+
+// checkRecord is checkNamespace for a stored record. A record outside the
+// caller's namespaces is reported as sql.ErrNoRows, so IDs cannot be used
+// to probe other namespaces.
+func checkRecord(ctx context.Context, rec types.MemoryRecord, write bool) error {
+	if !visible(ctx, rec) {
+		return sql.ErrNoRows
+	}
+	return checkNamespace(ctx, rec.Namespace, write)
+}
+
+// visible reports whether the caller's grant covers rec's namespace.
+func visible(ctx context.Context, rec types.MemoryRecord) bool {
+	return callerOf(ctx).Grant.Allows(rec.Namespace)
+}
@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/auth"
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestGrant_LimitsServiceToNamespaces(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "grant.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	svc, err := NewService(st, config.Default(), logger)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	write := func(ctx context.Context, ns string) (types.MemoryRecord, error) {
+		return svc.Write(ctx, types.WriteInput{Namespace: ns, Scope: "long", Content: "deploy window is 9-11 in " + ns})
+	}
+	parent, err := write(ctx, "acme/api")
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	other, err := write(ctx, "acme/web/main")
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	ci := WithCaller(ctx, Caller{Tool: "memory_write", Grant: &auth.Grant{Principal: "ci", Namespaces: []string{"acme/api/main"}}})
+	own, err := write(ci, "acme/api/main/task")
+	if err != nil {
+		t.Fatalf("Write() inside the grant error = %v", err)
+	}
+	if _, err := write(ci, "acme/web/main"); !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("Write() outside the grant error = %v, want ErrForbidden", err)
+	}
+	if _, err := svc.Search(ci, types.SearchInput{Namespace: "acme/web/main", Query: "deploy"}); !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("Search() outside the grant error = %v, want ErrForbidden", err)
+	}
+	// The ungranted ancestor acme/api is skipped, not an error.
+	results, err := svc.Search(ci, types.SearchInput{Namespace: "acme/api/main/task", Query: "deploy", IncludeAncestors: true})
+	if err != nil || len(results) != 1 || results[0].Record.ID != own.ID {
+		t.Fatalf("Search(include_ancestors) = %+v, %v, want only %s", results, err, own.ID)
+	}
+	if _, err := svc.List(ci, types.ListInput{}); !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("List() of every namespace error = %v, want ErrForbidden", err)
+	}
+	if _, err := svc.Purge(ci, types.PurgeInput{Key: "ticket", Value: "OPS-1", DryRun: true}); !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("Purge() error = %v, want ErrForbidden", err)
+	}
+
+	// Memories outside the grant look missing when named by ID.
+	if _, err := svc.Promote(ci, types.PromoteInput{MemoryID: other.ID}); err == nil || errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("Promote() outside the grant error = %v, want not found", err)
+	}
+	if _, err := svc.Append(ci, types.AppendInput{MemoryID: parent.ID, Content: "more"}); err == nil {
+		t.Fatal("Append() outside the grant error = nil")
+	}
+	res, err := svc.Delete(ci, types.DeleteInput{MemoryIDs: []string{other.ID, own.ID}, DryRun: true})
+	if err != nil || !slices.Equal(res.Missing, []string{other.ID}) || res.Count != 1 {
+		t.Fatalf("Delete(dry_run) = %+v, %v, want %s missing", res, err, other.ID)
+	}
+
+	viewer := WithCaller(ctx, Caller{Grant: &auth.Grant{Principal: "viewer", Namespaces: []string{auth.Wildcard}, ReadOnly: true}})
+	if _, err := write(viewer, "acme/api"); !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("Write() by a read-only grant error = %v, want ErrForbidden", err)
+	}
+	if _, err := svc.Delete(viewer, types.DeleteInput{MemoryIDs: []string{own.ID}}); !errors.Is(err, auth.ErrForbidden) {
+		t.Fatalf("Delete() by a read-only grant error = %v, want ErrForbidden", err)
+	}
+	if got, err := svc.List(viewer, types.ListInput{}); err != nil || len(got.Memories) != 3 {
+		t.Fatalf("List() by a wildcard grant = %d memories, %v, want 3", len(got.Memories), err)
+	}
+}
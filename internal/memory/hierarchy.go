@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// maxDescendantNamespaces bounds the namespaces below the searched one that
+// include_descendants searches, those with the most memories first.
+const maxDescendantNamespaces = 20
+
+// searchLevels returns the namespaces a search covers with their distance
+// in levels from in.Namespace, which is at 0. Ancestors are the namespace's
+// parents up to its first segment that the caller may use; descendants are
+// the namespaces below it that hold live memories.
+func (s *Service) searchLevels(ctx context.Context, in types.SearchInput, now time.Time) (map[string]int, error) {
+	levels := map[string]int{in.Namespace: 0}
+	if in.IncludeAncestors {
+		parent := strings.TrimRight(in.Namespace, "/")
+		for d := 1; ; d++ {
+			i := strings.LastIndex(parent, "/")
+			if i <= 0 {
+				break
+			}
+			parent = parent[:i]
+			if callerOf(ctx).Grant.Allows(parent) {
+				levels[parent] = d
+			}
+		}
+	}
+	if in.IncludeDescendants {
+		groups, err := s.store.CountBy(ctx, store.GroupNamespace, store.ListFilter{NamespacePrefix: in.Namespace, Scope: in.Scope, Now: now})
+		if err != nil {
+			return nil, err
+		}
+		base := strings.Count(strings.Trim(in.Namespace, "/"), "/")
+		added := 0
+		for _, g := range groups {
+			if _, ok := levels[g.Key]; ok || added == maxDescendantNamespaces {
+				continue
+			}
+			levels[g.Key] = strings.Count(g.Key, "/") - base
+			added++
+		}
+	}
+	return levels, nil
+}
+
+// maxSharedNamespaces bounds the namespaces searched for project or for
+// global memories, those with the most memories of the scope first.
+const maxSharedNamespaces = 20
+
+// sharedLevel is a namespace searched only for memories of one scope.
+type sharedLevel struct {
+	namespace, scope string
+}
+
+// sharedLevels returns the namespaces outside levels holding project
+// memories of in.Namespace's project, then those holding global memories,
+// that the caller may read. A scope filter skips the other scope.
+func (s *Service) sharedLevels(ctx context.Context, in types.SearchInput, levels map[string]int, now time.Time) ([]sharedLevel, error) {
+	var out []sharedLevel
+	for _, scope := range []string{"project", "global"} {
+		if in.Scope != "" && in.Scope != scope {
+			continue
+		}
+		f := store.ListFilter{Scope: scope, Now: now}
+		if scope == "project" {
+			f.NamespacePrefix = s.projectNamespace(in.Namespace)
+		}
+		groups, err := s.store.CountBy(ctx, store.GroupNamespace, f)
+		if err != nil {
+			return nil, err
+		}
+		added := 0
+		for _, g := range groups {
+			if _, ok := levels[g.Key]; ok || added == maxSharedNamespaces || !callerOf(ctx).Grant.Allows(g.Key) {
+				continue
+			}
+			out = append(out, sharedLevel{namespace: g.Key, scope: scope})
+			added++
+		}
+	}
+	return out, nil
+}
+
+// levelCandidates collects the store's candidates for q in every namespace
+// of levels, nearest level first, then for the scope of every shared
+// level. Each namespace is searched and cached on its own, so writes
+// invalidate exactly the searches they affect.
+func (s *Service) levelCandidates(ctx context.Context, q store.SearchQuery, filter string, levels map[string]int, shared []sharedLevel) ([]store.Candidate, error) {
+	if len(levels) == 1 && len(shared) == 0 {
+		return s.searches.candidates(ctx, s.store, q, filter)
+	}
+	var out []store.Candidate
+	for _, ns := range sortedLevels(levels) {
+		q.Namespace = ns
+		cands, err := s.searches.candidates(ctx, s.store, q, filter)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, cands...)
+	}
+	for _, l := range shared {
+		q.Namespace, q.Scope = l.namespace, l.scope
+		cands, err := s.searches.candidates(ctx, s.store, q, filter)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, cands...)
+	}
+	return out, nil
+}
+
+// sortedLevels returns the namespaces of levels nearest first, then by name.
+func sortedLevels(levels map[string]int) []string {
+	return slices.SortedFunc(maps.Keys(levels), func(a, b string) int {
+		return cmp.Or(cmp.Compare(levels[a], levels[b]), cmp.Compare(a, b))
+	})
+}
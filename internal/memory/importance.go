@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/xiy/memory-mcp/internal/llm"
+	"github.com/xiy/memory-mcp/internal/textnorm"
+)
+
+// MetaImportanceAuto is set on memories whose importance was assigned by
+// the importance_scoring scorer rather than the writer: "heuristic" or
+// "llm".
+const MetaImportanceAuto = "importance_auto"
+
+const importancePrompt = `You rate how important a note in an engineering team's shared memory is to agents working on the same code later. 5 is a durable decision, rule or hazard everyone must know; 3 is useful context; 1 is a passing or scratch note. Reply with a single digit from 1 to 5 and nothing else.`
+
+// importanceRaising are words of decisions, rules and hazards, which are
+// worth more than a plain note; importanceLowering are words of scratch
+// and in-progress notes.
+var (
+	importanceRaising = map[string]struct{}{
+		"decision": {}, "decided": {}, "decide": {}, "always": {}, "never": {},
+		"must": {}, "convention": {}, "policy": {}, "rule": {}, "required": {},
+		"important": {}, "critical": {}, "security": {}, "breaking": {},
+		"gotcha": {}, "warning": {}, "avoid": {}, "don't": {}, "dont": {},
+	}
+	importanceLowering = map[string]struct{}{
+		"todo": {}, "wip": {}, "temp": {}, "temporary": {}, "tmp": {},
+		"scratch": {}, "maybe": {}, "fyi": {}, "trying": {}, "draft": {},
+	}
+)
+
+// heuristicImportance scores text from 1 to 5 starting at 3: one point
+// for a decision or rule word and another for two different ones, minus
+// one for a scratch word, and one point either way for a note under 40
+// or over 600 characters.
+func heuristicImportance(text string) int {
+	raising := map[string]struct{}{}
+	lowering := false
+	for _, w := range strings.FieldsFunc(textnorm.Fold(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	}) {
+		w = strings.Trim(w, "'")
+		if _, ok := importanceRaising[w]; ok {
+			raising[w] = struct{}{}
+		}
+		if _, ok := importanceLowering[w]; ok {
+			lowering = true
+		}
+	}
+	score := 3 + min(len(raising), 2)
+	if lowering {
+		score--
+	}
+	switch n := utf8.RuneCountInString(strings.TrimSpace(text)); {
+	case n < 40:
+		score--
+	case n > 600:
+		score++
+	}
+	return max(1, min(score, 5))
+}
+
+// autoImportance scores a write that gave no importance and names the
+// scorer that did. With importance_scoring llm the model is asked first
+// and the heuristic used when it fails or replies with no digit.
+func (s *Service) autoImportance(ctx context.Context, content, summary string) (int, string) {
+	text := strings.TrimSpace(summary + "\n" + content)
+	if s.cfg.ImportanceScoring == "llm" && s.llm != nil {
+		reply, err := s.llm.Complete(ctx, []llm.Message{
+			{Role: "system", Content: importancePrompt},
+			{Role: "user", Content: truncate(text, 2000)},
+		})
+		if err == nil {
+			if i := strings.IndexAny(reply, "12345"); i >= 0 {
+				return int(reply[i] - '0'), "llm"
+			}
+		}
+		s.logger.Warn("llm importance scoring failed; using heuristic", "error", err, "reply", truncate(reply, 80))
+	}
+	return heuristicImportance(text), "heuristic"
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestHeuristicImportance(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		text string
+		want int
+	}{
+		{"Deploys go through the canary stage before production.", 3},
+		{"Decision: we always squash-merge feature branches into main.", 5},
+		{"Never run migrations by hand against the production database.", 4},
+		{"todo: look at flaky test", 1},
+		{"WIP: trying a different cache key layout for the search results.", 2},
+		{"Don't bump the driver; it breaks the nightly build on arm64.", 4},
+		{strings.Repeat("The ingestion pipeline reads batches from the queue. ", 15), 4},
+	}
+	for _, tc := range cases {
+		if got := heuristicImportance(tc.text); got != tc.want {
+			t.Fatalf("heuristicImportance(%q) = %d, want %d", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestWrite_AutoScoresImportance(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	cfg := config.Default()
+	cfg.ImportanceScoring = "llm"
+	st := &fakeStore{}
+	model := &fakeLLM{answer: "5"}
+	svc, err := NewService(st, cfg, logger, WithLLM(model))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	meta := map[string]any{"tags": []any{"ci"}}
+	rec, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "Release builds are signed in CI.", Metadata: meta})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if rec.Importance != 5 || rec.Metadata[MetaImportanceAuto] != "llm" || rec.Metadata["tags"] == nil {
+		t.Fatalf("Write() = %+v, want importance 5 scored by llm", rec)
+	}
+	if _, ok := meta[MetaImportanceAuto]; ok {
+		t.Fatal("Write() modified the caller's metadata")
+	}
+
+	model.err = errors.New("upstream unavailable")
+	rec, err = svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "todo: check cache"})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if rec.Importance != 1 || rec.Metadata[MetaImportanceAuto] != "heuristic" {
+		t.Fatalf("Write() = %+v, want the heuristic when the llm fails", rec)
+	}
+
+	rec, err = svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "todo: check cache", Importance: 4})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if rec.Importance != 4 || rec.Metadata != nil {
+		t.Fatalf("Write() = %+v, want the given importance untouched", rec)
+	}
+}
@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+// listCursor is the decoded form of ListResult.NextCursor. It records the
+// sort it was issued for, so a cursor cannot silently skip memories when
+// reused with another order.
+type listCursor struct {
+	Sort       string    `json:"s"`
+	CreatedAt  time.Time `json:"c"`
+	Importance int       `json:"i"`
+	ID         string    `json:"id"`
+}
+
+// List returns a page of live memories matching in, and a cursor for the
+// next page when more may follow.
+func (s *Service) List(ctx context.Context, in types.ListInput) (_ types.ListResult, err error) {
+	ctx, span := startSpan(ctx, "memory.List", namespaceAttr(in.Namespace))
+	defer func() { endSpan(span, err) }()
+	in.Scope = strings.TrimSpace(strings.ToLower(in.Scope))
+	if in.Scope != "" && !validScope(in.Scope) {
+		return types.ListResult{}, fmt.Errorf("invalid scope %q", in.Scope)
+	}
+	if in.MinImportance < 0 || in.MinImportance > 5 || in.MaxImportance < 0 || in.MaxImportance > 5 {
+		return types.ListResult{}, errors.New("min_importance and max_importance must be between 1 and 5")
+	}
+	if in.Sort == "" {
+		in.Sort = store.SortCreatedDesc
+	}
+	if in.Limit <= 0 {
+		in.Limit = defaultListLimit
+	}
+	in.Limit = min(in.Limit, maxListLimit)
+
+	f := store.ListFilter{
+		NamespacePrefix: strings.TrimSpace(in.Namespace),
+		Scope:           in.Scope,
+		MinImportance:   in.MinImportance,
+		MaxImportance:   in.MaxImportance,
+		CreatedAfter:    in.CreatedAfter,
+		CreatedBefore:   in.CreatedBefore,
+		SourceAgent:     strings.TrimSpace(in.WrittenBy),
+		Now:             s.now(),
+		Sort:            in.Sort,
+		// One more than a page tells whether another page follows.
+		Limit: in.Limit + 1,
+	}
+	if in.Cursor != "" {
+		c, err := decodeListCursor(in.Cursor)
+		if err != nil {
+			return types.ListResult{}, err
+		}
+		if c.Sort != in.Sort {
+			return types.ListResult{}, fmt.Errorf("cursor was issued for sort %q, not %q", c.Sort, in.Sort)
+		}
+		f.After = &store.ListCursor{CreatedAt: c.CreatedAt, Importance: c.Importance, ID: c.ID}
+	}
+	if err := checkNamespace(ctx, f.NamespacePrefix, false); err != nil {
+		return types.ListResult{}, err
+	}
+	recs, err := s.store.ListMemories(ctx, f)
+	if err != nil {
+		return types.ListResult{}, err
+	}
+
+	res := types.ListResult{Memories: recs}
+	if res.Memories == nil {
+		res.Memories = []types.MemoryRecord{}
+	}
+	if len(recs) > in.Limit {
+		res.Memories = recs[:in.Limit]
+		last := res.Memories[in.Limit-1]
+		res.NextCursor = encodeListCursor(listCursor{Sort: in.Sort, CreatedAt: last.CreatedAt, Importance: last.Importance, ID: last.ID})
+	}
+	return res, nil
+}
+
+func encodeListCursor(c listCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeListCursor(s string) (listCursor, error) {
+	var c listCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(b, &c)
+	}
+	if err != nil || c.ID == "" {
+		return listCursor{}, errors.New("invalid cursor")
+	}
+	return c, nil
+}
@@ -0,0 +1,212 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// Metadata keys linking a merged record and the originals it replaces.
+const (
+	MetaMergedFrom   = "merged_from"
+	MetaSupersededBy = "superseded_by"
+)
+
+// mergeDelimiter separates the originals' content in a merged record.
+const mergeDelimiter = "\n\n---\n\n"
+
+// Merge writes one record consolidating in.MemoryIDs: their content joined
+// in the given order with identical contents kept once, the highest importance, and the union of their tags
+// and metadata (earlier records win on conflicting keys). The merged record
+// is long-term if any original is. Originals are marked superseded_by the
+// new ID and, with ExpireOriginals, expire immediately, long-term ones
+// becoming short-term so the next TTL sweep deletes them.
+func (s *Service) Merge(ctx context.Context, in types.MergeInput) (_ types.MergeResult, err error) {
+	ctx, span := startSpan(ctx, "memory.Merge", attribute.StringSlice("memory.ids", in.MemoryIDs))
+	defer func() { endSpan(span, err) }()
+	if s.cfg.ReadOnly {
+		return types.MergeResult{}, ErrReadOnly
+	}
+	ids := make([]string, 0, len(in.MemoryIDs))
+	seen := map[string]struct{}{}
+	for _, id := range in.MemoryIDs {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) < 2 {
+		return types.MergeResult{}, errors.New("memory_ids must name at least two memories")
+	}
+	summary, err := s.cleanText("summary", in.Summary)
+	if err != nil {
+		return types.MergeResult{}, err
+	}
+	in.Summary = summary
+
+	originals := make([]types.MemoryRecord, 0, len(ids))
+	for _, id := range ids {
+		rec, err := s.store.GetMemory(ctx, id)
+		if err == nil {
+			err = checkRecord(ctx, rec, true)
+		}
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return types.MergeResult{}, fmt.Errorf("memory %s not found", id)
+			}
+			return types.MergeResult{}, err
+		}
+		originals = append(originals, rec)
+	}
+
+	namespace := strings.TrimSpace(in.Namespace)
+	if namespace == "" {
+		namespace = originals[0].Namespace
+		for _, rec := range originals[1:] {
+			if rec.Namespace != namespace {
+				return types.MergeResult{}, errors.New("memories span several namespaces; set namespace for the merged record")
+			}
+		}
+	}
+	if err := s.validateNamespace(namespace); err != nil {
+		return types.MergeResult{}, err
+	}
+	if err := checkNamespace(ctx, namespace, true); err != nil {
+		return types.MergeResult{}, err
+	}
+
+	now := s.now()
+	merged := mergeRecords(originals)
+	merged.Namespace = namespace
+	merged.Metadata[MetaMergedFrom] = ids
+	merged.SourceAgent = strings.TrimSpace(in.SourceAgent)
+	if merged.SourceAgent == "" {
+		merged.SourceAgent = commonAgent(originals)
+	}
+	merged.Summary = strings.TrimSpace(in.Summary)
+	if merged.Summary == "" {
+		merged.Summary = autoSummary(merged.Content)
+	}
+	merged.ExpiresAt = s.scopeExpiry(types.WriteInput{Scope: merged.Scope}, now)
+	merged.ID = s.ids.New(namespace, merged.Content, now)
+	merged.CreatedAt = now
+	merged.LastAccessedAt = now
+
+	stored, err := s.store.InsertMemory(ctx, merged)
+	if err != nil {
+		return types.MergeResult{}, err
+	}
+	s.extractFacts(ctx, stored)
+	s.embed(ctx, stored)
+
+	res := types.MergeResult{Merged: stored, Superseded: ids, Expired: in.ExpireOriginals}
+	entries := []store.AuditEntry{s.auditEntry(ctx, store.AuditWrite, stored.SourceAgent, types.MemoryRecord{}, stored)}
+	defer func() { s.recordAudit(ctx, entries...) }()
+	for i, id := range ids {
+		updated, err := s.store.UpdateMemory(ctx, id, func(rec *types.MemoryRecord) error {
+			if rec.Metadata == nil {
+				rec.Metadata = map[string]any{}
+			}
+			rec.Metadata[MetaSupersededBy] = stored.ID
+			if in.ExpireOriginals {
+				// Expiry never removes long-term records, so they are
+				// demoted to short-term for the TTL sweep to delete.
+				if rec.Scope == "long" {
+					rec.Scope = "short"
+				}
+				rec.ExpiresAt = &now
+			}
+			return nil
+		})
+		if err != nil {
+			s.invalidateAll()
+			return res, fmt.Errorf("mark %s superseded: %w", id, err)
+		}
+		entries = append(entries, s.auditEntry(ctx, store.AuditUpdate, in.SourceAgent, originals[i], updated))
+	}
+	s.invalidateRecords(append(slices.Clip(originals), stored)...)
+	return res, nil
+}
+
+// mergeRecords combines content, importance, scope and metadata of recs.
+func mergeRecords(recs []types.MemoryRecord) types.MemoryRecord {
+	out := types.MemoryRecord{Scope: "short", Metadata: map[string]any{}}
+	var (
+		parts []string
+		tags  []any
+	)
+	seenTags := map[string]struct{}{}
+	seenContent := map[string]struct{}{}
+	for _, rec := range recs {
+		if key := normalize(rec.Content); key != "" {
+			if _, dup := seenContent[key]; !dup {
+				seenContent[key] = struct{}{}
+				parts = append(parts, strings.TrimSpace(rec.Content))
+			}
+		}
+		out.Importance = max(out.Importance, rec.Importance)
+		if widerScope(rec.Scope, out.Scope) {
+			out.Scope = rec.Scope
+		}
+		for k, v := range rec.Metadata {
+			switch k {
+			case "tags":
+				for _, tag := range tagList(v) {
+					if _, ok := seenTags[tag]; !ok {
+						seenTags[tag] = struct{}{}
+						tags = append(tags, tag)
+					}
+				}
+			case MetaMergedFrom, MetaSupersededBy:
+			default:
+				if _, ok := out.Metadata[k]; !ok {
+					out.Metadata[k] = v
+				}
+			}
+		}
+	}
+	if len(tags) > 0 {
+		out.Metadata["tags"] = tags
+	}
+	out.Content = strings.Join(parts, mergeDelimiter)
+	return out
+}
+
+func tagList(v any) []string {
+	var out []string
+	switch v := v.(type) {
+	case []any:
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				out = append(out, s)
+			}
+		}
+	case []string:
+		out = v
+	case string:
+		out = []string{v}
+	}
+	return out
+}
+
+func commonAgent(recs []types.MemoryRecord) string {
+	agent := recs[0].SourceAgent
+	for _, rec := range recs[1:] {
+		if rec.SourceAgent != agent {
+			return ""
+		}
+	}
+	return agent
+}
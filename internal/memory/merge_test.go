@@ -0,0 +1,195 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/clock"
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestMerge_ConsolidatesAndSupersedesOriginals(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "merge.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc, err := NewService(st, config.Default(), logger, WithClock(clock.NewManual(now)))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	a, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Scope: "short", Content: "retry deploys twice", Importance: 2,
+		SourceAgent: "codex", Metadata: map[string]any{"tags": []any{"deploy"}, "ticket": "OPS-1"}})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	b, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Scope: "long", Content: "deploy window is 9-11", Importance: 4,
+		SourceAgent: "codex", Metadata: map[string]any{"tags": []any{"deploy", "schedule"}, "ticket": "OPS-2"}})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	res, err := svc.Merge(ctx, types.MergeInput{MemoryIDs: []string{a.ID, b.ID, a.ID}, ExpireOriginals: true})
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	m := res.Merged
+	if m.Content != "retry deploys twice"+mergeDelimiter+"deploy window is 9-11" || m.Importance != 4 || m.Scope != "long" || m.SourceAgent != "codex" {
+		t.Fatalf("unexpected merged record %+v", m)
+	}
+	if !reflect.DeepEqual(res.Superseded, []string{a.ID, b.ID}) {
+		t.Fatalf("unexpected superseded ids %v", res.Superseded)
+	}
+
+	stored, err := st.GetMemory(ctx, m.ID)
+	if err != nil {
+		t.Fatalf("GetMemory() error = %v", err)
+	}
+	if got := stored.Metadata["tags"]; !reflect.DeepEqual(got, []any{"deploy", "schedule"}) {
+		t.Fatalf("expected tag union, got %v", got)
+	}
+	if stored.Metadata["ticket"] != "OPS-1" || !reflect.DeepEqual(stored.Metadata[MetaMergedFrom], []any{a.ID, b.ID}) {
+		t.Fatalf("unexpected merged metadata %v", stored.Metadata)
+	}
+	for _, id := range []string{a.ID, b.ID} {
+		orig, err := st.GetMemory(ctx, id)
+		if err != nil {
+			t.Fatalf("GetMemory(%s) error = %v", id, err)
+		}
+		if orig.Metadata[MetaSupersededBy] != m.ID || orig.ExpiresAt == nil || !orig.ExpiresAt.Equal(now) {
+			t.Fatalf("expected %s to be superseded and expired, got %+v", id, orig)
+		}
+	}
+
+	results, err := svc.Search(ctx, types.SearchInput{Namespace: "org/repo/task", Query: "deploy"})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Record.ID != m.ID {
+		t.Fatalf("expected only the merged memory in search, got %v", results)
+	}
+
+	if _, err := svc.Merge(ctx, types.MergeInput{MemoryIDs: []string{a.ID}}); err == nil {
+		t.Fatal("expected merging a single memory to fail")
+	}
+	other, err := svc.Write(ctx, types.WriteInput{Namespace: "org/other/task", Content: "unrelated"})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := svc.Merge(ctx, types.MergeInput{MemoryIDs: []string{a.ID, other.ID}}); err == nil {
+		t.Fatal("expected cross-namespace merge without namespace to fail")
+	}
+
+	swept, err := svc.ExpireShort(ctx)
+	if err != nil || swept != 2 {
+		t.Fatalf("ExpireShort() after merge = %d, %v, want both originals", swept, err)
+	}
+	for _, id := range []string{a.ID, b.ID} {
+		if _, err := st.GetMemory(ctx, id); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("GetMemory(%s) after the sweep error = %v, want sql.ErrNoRows", id, err)
+		}
+	}
+	if _, err := st.GetMemory(ctx, m.ID); err != nil {
+		t.Fatalf("GetMemory(merged) after the sweep error = %v", err)
+	}
+}
+
+func TestFindDuplicates_GroupsPerNamespaceAndSkipsSuperseded(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "dupes.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	clk := clock.NewManual(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	svc, err := NewService(st, config.Default(), logger, WithClock(clk))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	write := func(ns, content string) string {
+		t.Helper()
+		clk.Advance(time.Second)
+		rec, err := svc.Write(ctx, types.WriteInput{Namespace: ns, Scope: "long", Content: content})
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		return rec.ID
+	}
+	a := write("org/repo/a", "Use pnpm for installs")
+	b := write("org/repo/a", "use pnpm  for installs")
+	write("org/repo/b", "Use pnpm for installs")
+
+	groups, err := svc.FindDuplicates(ctx, "", DefaultDuplicateThreshold)
+	if err != nil {
+		t.Fatalf("FindDuplicates() error = %v", err)
+	}
+	if len(groups) != 1 || !reflect.DeepEqual(groups[0].MemoryIDs, []string{a, b}) || !groups[0].Exact || groups[0].Namespace != "org/repo/a" {
+		t.Fatalf("unexpected groups %+v", groups)
+	}
+
+	res, err := svc.Merge(ctx, types.MergeInput{MemoryIDs: groups[0].MemoryIDs, ExpireOriginals: true})
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if res.Merged.Content != "Use pnpm for installs" {
+		t.Fatalf("expected exact duplicates to merge into one copy, got %q", res.Merged.Content)
+	}
+	if groups, err := svc.FindDuplicates(ctx, "org/repo/a", DefaultDuplicateThreshold); err != nil || len(groups) != 0 {
+		t.Fatalf("FindDuplicates() after merge = %+v, %v", groups, err)
+	}
+}
+
+// listRecorder records the filters of ListMemories calls.
+type listRecorder struct {
+	*store.MemoryStore
+	filters []store.ListFilter
+}
+
+func (l *listRecorder) ListMemories(ctx context.Context, f store.ListFilter) ([]types.MemoryRecord, error) {
+	l.filters = append(l.filters, f)
+	return l.MemoryStore.ListMemories(ctx, f)
+}
+
+func TestFindDuplicates_ListsOnlyEachNamespace(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	st := &listRecorder{MemoryStore: store.NewMemoryStore()}
+	svc, err := NewService(st, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	for _, ns := range []string{"org/repo", "org/repo/task"} {
+		if _, err := svc.Write(ctx, types.WriteInput{Namespace: ns, Scope: "long", Content: "Use pnpm for installs"}); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	st.filters = nil
+	if groups, err := svc.FindDuplicates(ctx, "", DefaultDuplicateThreshold); err != nil || len(groups) != 0 {
+		t.Fatalf("FindDuplicates() = %+v, %v, want no cross-namespace groups", groups, err)
+	}
+	if len(st.filters) != 2 {
+		t.Fatalf("FindDuplicates() listed %d times, want once per namespace", len(st.filters))
+	}
+	for _, f := range st.filters {
+		if f.Namespace == "" || f.NamespacePrefix != "" {
+			t.Fatalf("FindDuplicates() listed with %+v, want only the exact namespace", f)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/auth"
+	"github.com/xiy/memory-mcp/internal/cache"
+	"github.com/xiy/memory-mcp/internal/clock"
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// packKey holds every context pack argument that changes the pack, after
+// defaults are applied, and the caller's grant, which decides the
+// ancestors and shared namespaces a pack may draw on.
+type packKey struct {
+	namespace   string
+	query       string
+	budget      int
+	scope       string
+	k           int
+	sourceAgent string
+	format      string
+	ancestors   bool
+	descendants bool
+	sections    bool
+	grant       *auth.Grant
+}
+
+// packEntry is a cached pack and the namespaces its search covered. A pack
+// built with include_descendants also covers every namespace under prefix,
+// including ones that held no memories when it was built.
+type packEntry struct {
+	pack       types.ContextPack
+	namespaces []string
+	prefix     string
+}
+
+// packCache memoizes whole context packs, so an agent asking for the same
+// pack again within the TTL skips search, dedup and rendering. Unlike the
+// search cache it serves the ranking as of when the pack was built. Like
+// it, a generation counter stops a pack that raced a write from being
+// cached.
+type packCache struct {
+	lru *cache.LRU[packKey, packEntry]
+	gen atomic.Uint64
+}
+
+// newPackCache returns nil when the cache is disabled; all methods accept a
+// nil receiver.
+func newPackCache(cfg config.CacheConfig, clk clock.Clock) *packCache {
+	if cfg.PackEntries <= 0 {
+		return nil
+	}
+	ttl := time.Duration(cfg.PackTTLSeconds) * time.Second
+	return &packCache{lru: cache.New[packKey, packEntry](cfg.PackEntries, ttl, clk)}
+}
+
+func (c *packCache) get(key packKey) (types.ContextPack, bool) {
+	if c == nil {
+		return types.ContextPack{}, false
+	}
+	e, ok := c.lru.Get(key)
+	return e.pack, ok
+}
+
+// generation is read before building a pack and handed back to put.
+func (c *packCache) generation() uint64 {
+	if c == nil {
+		return 0
+	}
+	return c.gen.Load()
+}
+
+// put caches pack unless a write invalidated the cache since gen. namespaces
+// are the ones the pack's search covered.
+func (c *packCache) put(key packKey, gen uint64, pack types.ContextPack, namespaces []string) {
+	if c == nil || c.gen.Load() != gen {
+		return
+	}
+	e := packEntry{pack: pack, namespaces: namespaces}
+	if key.descendants {
+		e.prefix = strings.Trim(key.namespace, "/")
+	}
+	c.lru.Put(key, e)
+}
+
+// invalidate drops cached packs whose search covered any of namespaces, or
+// whose descendants include one of them.
+func (c *packCache) invalidate(namespaces ...string) {
+	if c == nil || len(namespaces) == 0 {
+		return
+	}
+	c.gen.Add(1)
+	set := make(map[string]struct{}, len(namespaces))
+	for _, ns := range namespaces {
+		set[ns] = struct{}{}
+	}
+	c.lru.DeleteEntryFunc(func(_ packKey, e packEntry) bool {
+		if e.prefix != "" && slices.ContainsFunc(namespaces, func(ns string) bool {
+			ns = strings.Trim(ns, "/")
+			return ns == e.prefix || strings.HasPrefix(ns, e.prefix+"/")
+		}) {
+			return true
+		}
+		return slices.ContainsFunc(e.namespaces, func(ns string) bool {
+			_, ok := set[ns]
+			return ok
+		})
+	})
+}
+
+func (c *packCache) invalidateAll() {
+	if c == nil {
+		return
+	}
+	c.gen.Add(1)
+	c.lru.Purge()
+}
+
+func (c *packCache) stats() cache.Stats {
+	if c == nil {
+		return cache.Stats{}
+	}
+	return c.lru.Stats()
+}
@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/config"
+)
+
+// PackData is what a context pack template renders.
+type PackData struct {
+	Namespace string
+	Query     string
+	Memories  []PackMemory
+	// Sections is set for sectioned packs: the sections holding memories,
+	// in order. Memories then lists them section by section.
+	Sections []PackSection
+}
+
+// PackSection is one labeled group of a sectioned context pack.
+type PackSection struct {
+	Name     string       `json:"name" xml:"name,attr"`
+	Title    string       `json:"title" xml:"title,attr"`
+	Memories []PackMemory `json:"memories" xml:"memory"`
+}
+
+// PackMemory is one memory of a context pack. Text is its summary, or its
+// content when it has none, cut to 300 characters.
+type PackMemory struct {
+	ID          string    `json:"id" xml:"id,attr"`
+	Namespace   string    `json:"namespace" xml:"namespace,attr"`
+	Scope       string    `json:"scope" xml:"scope,attr"`
+	Importance  int       `json:"importance" xml:"importance,attr"`
+	SourceAgent string    `json:"source_agent,omitempty" xml:"source_agent,attr,omitempty"`
+	CreatedAt   time.Time `json:"created_at" xml:"-"`
+	Text        string    `json:"text" xml:",chardata"`
+}
+
+// packFormatter renders a context pack in one format.
+type packFormatter func(PackData) (string, error)
+
+var builtinPackFormats = map[string]packFormatter{
+	"markdown": func(d PackData) (string, error) {
+		return renderLines(d, "## %s", func(m PackMemory) string { return fmt.Sprintf("- [%s] %s", m.ID, m.Text) }), nil
+	},
+	"plain": func(d PackData) (string, error) {
+		return renderLines(d, "%s:", func(m PackMemory) string { return m.Text }), nil
+	},
+	"json": func(d PackData) (string, error) {
+		var v any = d.Memories
+		switch {
+		case d.Sections != nil:
+			v = d.Sections
+		case d.Memories == nil:
+			v = []PackMemory{}
+		}
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"xml": func(d PackData) (string, error) {
+		doc := struct {
+			XMLName   xml.Name      `xml:"memories"`
+			Namespace string        `xml:"namespace,attr"`
+			Sections  []PackSection `xml:"section"`
+			Memories  []PackMemory  `xml:"memory"`
+		}{Namespace: d.Namespace, Sections: d.Sections, Memories: d.Memories}
+		if d.Sections != nil {
+			doc.Memories = nil
+		}
+		b, err := xml.MarshalIndent(doc, "", "  ")
+		return string(b), err
+	},
+}
+
+// renderLines renders a line per memory and, for sectioned packs, a
+// heading made by heading from each section's title.
+func renderLines(d PackData, heading string, line func(PackMemory) string) string {
+	if d.Sections == nil {
+		lines := make([]string, len(d.Memories))
+		for i, m := range d.Memories {
+			lines[i] = line(m)
+		}
+		return strings.Join(lines, "\n")
+	}
+	blocks := make([]string, len(d.Sections))
+	for i, sec := range d.Sections {
+		lines := []string{fmt.Sprintf(heading, sec.Title)}
+		for _, m := range sec.Memories {
+			lines = append(lines, line(m))
+		}
+		blocks[i] = strings.Join(lines, "\n")
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+// newPackFormats returns the built-in formats with cfg's templates added,
+// replacing built-ins of the same name.
+func newPackFormats(cfg config.ContextPackConfig) (map[string]packFormatter, error) {
+	formats := make(map[string]packFormatter, len(builtinPackFormats)+len(cfg.Templates))
+	for name, f := range builtinPackFormats {
+		formats[name] = f
+	}
+	for name, text := range cfg.Templates {
+		tmpl, err := config.ParseContextPackTemplate(name, text)
+		if err != nil {
+			return nil, err
+		}
+		formats[name] = func(d PackData) (string, error) {
+			var b strings.Builder
+			if err := tmpl.Execute(&b, d); err != nil {
+				return "", fmt.Errorf("context pack template %s: %w", name, err)
+			}
+			return strings.TrimSpace(b.String()), nil
+		}
+	}
+	return formats, nil
+}
+
+// packFormat returns the formatter called name, or the configured default
+// when name is empty.
+func (s *Service) packFormat(name string) (string, packFormatter, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = s.cfg.ContextPack.Format
+	}
+	if f, ok := s.packFormats[name]; ok {
+		return name, f, nil
+	}
+	names := make([]string, 0, len(s.packFormats))
+	for n := range s.packFormats {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	return "", nil, fmt.Errorf("unknown format %q (expected one of %s)", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// packSectionTitles label the sections of config.ContextPackSections.
+var packSectionTitles = map[string]string{
+	"durable": "Durable knowledge",
+	"recent":  "Recent working notes",
+	"stale":   "Possibly stale",
+}
+
+// packSections fills the sections of a sectioned context pack, each
+// within its share of the token budget.
+type packSections struct {
+	cfg      config.ContextPackSectionsConfig
+	now      time.Time
+	memories map[string][]PackMemory
+	budgets  map[string]int
+}
+
+func newPackSections(cfg config.ContextPackSectionsConfig, budget int, now time.Time) *packSections {
+	var total float64
+	for _, share := range cfg.Budget {
+		total += share
+	}
+	p := &packSections{cfg: cfg, now: now, memories: map[string][]PackMemory{}, budgets: map[string]int{}}
+	for name, share := range cfg.Budget {
+		p.budgets[name] = int(float64(budget) * share / total)
+	}
+	return p
+}
+
+// sectionOf returns the section rec belongs in: short-term memories are
+// recent working notes, and long-term ones older than stale_after_days are
+// possibly stale.
+func (p *packSections) sectionOf(rec types.MemoryRecord) string {
+	switch {
+	case rec.Scope == "short":
+		return "recent"
+	case p.now.Sub(rec.CreatedAt) > time.Duration(p.cfg.StaleAfterDays)*24*time.Hour:
+		return "stale"
+	default:
+		return "durable"
+	}
+}
+
+// data returns base with the filled sections, plus m in section when m is
+// set.
+func (p *packSections) data(base PackData, section string, m *PackMemory) PackData {
+	base.Memories, base.Sections = nil, []PackSection{}
+	for _, name := range config.ContextPackSections {
+		mems := p.memories[name]
+		if m != nil && name == section {
+			mems = append(mems[:len(mems):len(mems)], *m)
+		}
+		if len(mems) == 0 {
+			continue
+		}
+		base.Sections = append(base.Sections, PackSection{Name: name, Title: packSectionTitles[name], Memories: mems})
+		base.Memories = append(base.Memories, mems...)
+	}
+	return base
+}
+
+// only returns base holding nothing but section, with m added, to price
+// the section against its budget.
+func (p *packSections) only(base PackData, section string, m PackMemory) PackData {
+	mems := append(p.memories[section][:len(p.memories[section]):len(p.memories[section])], m)
+	base.Memories = mems
+	base.Sections = []PackSection{{Name: section, Title: packSectionTitles[section], Memories: mems}}
+	return base
+}
@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"strings"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// applyAgentRanking adds the searching agent's per-tag adjustments to
+// results: the fixed ranking.agents boosts for each tag a memory carries,
+// plus ranking.agent_weight times the agent's learned affinity for those
+// tags, averaged over the memory's tags so heavily tagged memories are not
+// favoured, plus ranking.own_agent_boost for memories the agent wrote
+// itself. Affinity lookup is best effort.
+func (s *Service) applyAgentRanking(ctx context.Context, agent string, results []types.SearchResult) {
+	agent = strings.ToLower(strings.TrimSpace(agent))
+	if agent == "" || len(results) == 0 {
+		return
+	}
+	boosts := s.agentBoosts[agent]
+	var affinity map[string]float64
+	if s.feedback != nil && s.cfg.Ranking.AgentWeight > 0 {
+		var err error
+		affinity, err = s.feedback.AgentTagAffinity(ctx, agent)
+		if err != nil {
+			s.logger.Warn("agent affinity lookup failed", "agent", agent, "error", err)
+		}
+	}
+	own := s.cfg.Ranking.OwnAgentBoost
+	if len(boosts) == 0 && len(affinity) == 0 && own == 0 {
+		return
+	}
+
+	for i := range results {
+		tags := tagList(results[i].Record.Metadata["tags"])
+		var total float64
+		perTag := map[string]float64{}
+		for _, tag := range tags {
+			adj := boosts[tag] + s.cfg.Ranking.AgentWeight*affinity[tag]/float64(len(tags))
+			if adj == 0 {
+				continue
+			}
+			perTag[tag] = adj
+			total += adj
+		}
+		var ownAdj float64
+		if own != 0 && strings.EqualFold(strings.TrimSpace(results[i].Record.SourceAgent), agent) {
+			ownAdj = own
+			total += own
+		}
+		results[i].Score += total
+		if e := results[i].Explanation; e != nil {
+			e.SourceAgent = agent
+			e.Agent = total
+			e.OwnAgent = ownAdj
+			if len(perTag) > 0 {
+				e.AgentTags = perTag
+			}
+		}
+	}
+}
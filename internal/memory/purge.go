@@ -0,0 +1,207 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// MetaRedactedAt is set on memories blanked by a purge with redact.
+const MetaRedactedAt = "redacted_at"
+
+// RedactedText replaces the content and summary of redacted memories.
+const RedactedText = "[redacted]"
+
+// Purge deletes or redacts every memory, across namespaces, whose metadata
+// key equals value. Audit entries already recorded for those memories lose
+// their summaries, logged requests that carried them lose their params, and
+// the purge itself is audited without them. Dry runs are allowed in
+// read-only mode.
+func (s *Service) Purge(ctx context.Context, in types.PurgeInput) (_ types.PurgeResult, err error) {
+	ctx, span := startSpan(ctx, "memory.Purge", attribute.String("memory.metadata_key", in.Key))
+	defer func() { endSpan(span, err) }()
+	in.Key = strings.TrimSpace(in.Key)
+	if in.Key == "" || in.Value == "" {
+		return types.PurgeResult{}, errors.New("key and value are required")
+	}
+	if !store.ValidMetadataColumnKey(in.Key) {
+		return types.PurgeResult{}, fmt.Errorf("invalid metadata key %q", in.Key)
+	}
+	if s.cfg.ReadOnly && !in.DryRun {
+		return types.PurgeResult{}, ErrReadOnly
+	}
+	// A purge spans every namespace.
+	if err := checkNamespace(ctx, "", !in.DryRun); err != nil {
+		return types.PurgeResult{}, err
+	}
+	recs, err := s.store.ListMemories(ctx, store.ListFilter{
+		Metadata:       map[string]string{in.Key: in.Value},
+		IncludeExpired: true,
+		Sort:           store.SortCreatedAsc,
+	})
+	if err != nil {
+		return types.PurgeResult{}, err
+	}
+
+	res := types.PurgeResult{DryRun: in.DryRun, Mode: "delete", Memories: make([]types.PurgedMemory, 0, len(recs))}
+	if in.Redact {
+		res.Mode = "redact"
+	}
+	ids := make([]string, 0, len(recs))
+	for _, rec := range recs {
+		ids = append(ids, rec.ID)
+		res.Memories = append(res.Memories, types.PurgedMemory{ID: rec.ID, Namespace: rec.Namespace, Scope: rec.Scope, CreatedAt: rec.CreatedAt})
+	}
+	if in.DryRun || len(recs) == 0 {
+		res.Count = int64(len(recs))
+		return res, nil
+	}
+
+	if in.Redact {
+		now := s.now()
+		for _, rec := range recs {
+			redacted, err := s.store.UpdateMemory(ctx, rec.ID, func(r *types.MemoryRecord) error {
+				r.Content = RedactedText
+				r.Summary = RedactedText
+				metadata := make(map[string]any, len(r.Metadata))
+				for k, v := range r.Metadata {
+					if k != in.Key {
+						metadata[k] = v
+					}
+				}
+				metadata[MetaRedactedAt] = now.Format(time.RFC3339)
+				r.Metadata = metadata
+				return nil
+			})
+			if err != nil {
+				s.invalidate(namespacesOf(recs)...)
+				return res, fmt.Errorf("redact %s: %w", rec.ID, err)
+			}
+			s.extractFacts(ctx, redacted)
+			res.Count++
+		}
+	} else {
+		if res.Count, err = s.store.DeleteMemories(ctx, ids); err != nil {
+			s.invalidate(namespacesOf(recs)...)
+			return res, err
+		}
+	}
+	s.invalidate(namespacesOf(recs)...)
+
+	if res.Requests, err = s.scrubRequestLogs(ctx, in, recs); err != nil {
+		s.logger.Warn("clearing request log params failed", "memories", len(ids), "error", err)
+	}
+	if s.audit != nil {
+		if err := s.audit.RedactAudit(ctx, ids); err != nil {
+			s.logger.Warn("redacting audit log failed", "memories", len(ids), "error", err)
+		}
+		entries := make([]store.AuditEntry, 0, len(recs))
+		for _, rec := range recs {
+			entries = append(entries, s.auditEntry(ctx, store.AuditPurge, "", types.MemoryRecord{ID: rec.ID, Namespace: rec.Namespace}, types.MemoryRecord{}))
+		}
+		s.recordAudit(ctx, entries...)
+	}
+	return res, nil
+}
+
+// requestLogPage is how many logged requests scrubRequestLogs reads at once.
+const requestLogPage = 500
+
+// scrubRequestLogs clears the params of logged requests that mention any
+// of recs: an argument named in.Key equal to in.Value, or a string holding
+// a memory's ID or content. It returns how many were cleared.
+func (s *Service) scrubRequestLogs(ctx context.Context, in types.PurgeInput, recs []types.MemoryRecord) (int64, error) {
+	if s.requestLog == nil {
+		return 0, nil
+	}
+	ids := make(map[string]struct{}, len(recs))
+	var contents []string
+	for _, rec := range recs {
+		ids[rec.ID] = struct{}{}
+		if c := strings.TrimSpace(rec.Content); c != "" {
+			contents = append(contents, c)
+		}
+	}
+	var (
+		cleared []int64
+		after   int64
+	)
+	for {
+		rows, err := s.requestLog.MCPRequestLogsAfter(ctx, after, requestLogPage)
+		if err != nil {
+			return 0, err
+		}
+		for _, row := range rows {
+			after = row.ID
+			if row.Params == "" {
+				continue
+			}
+			var params any
+			if err := json.Unmarshal([]byte(row.Params), &params); err != nil || mentionsPurged(params, in.Key, in.Value, ids, contents) {
+				cleared = append(cleared, row.ID)
+			}
+		}
+		if len(rows) < requestLogPage {
+			break
+		}
+	}
+	if err := s.requestLog.ClearMCPRequestParams(ctx, cleared); err != nil {
+		return 0, err
+	}
+	return int64(len(cleared)), nil
+}
+
+// mentionsPurged walks decoded JSON params for the purged key and value,
+// or a string holding a purged memory's ID or content.
+func mentionsPurged(v any, key, value string, ids map[string]struct{}, contents []string) bool {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			if k == key && scalarString(val) == value {
+				return true
+			}
+			if mentionsPurged(val, key, value, ids, contents) {
+				return true
+			}
+		}
+	case []any:
+		for _, val := range t {
+			if mentionsPurged(val, key, value, ids, contents) {
+				return true
+			}
+		}
+	case string:
+		if _, ok := ids[t]; ok {
+			return true
+		}
+		for _, c := range contents {
+			if strings.Contains(t, c) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// scalarString formats a decoded JSON scalar the way metadata filters
+// compare it, so 123 and "123" both match "123".
+func scalarString(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	}
+	return ""
+}
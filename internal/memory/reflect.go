@@ -0,0 +1,194 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/llm"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// Reflection memories carry this tag and list their sources under
+// MetaReflectedFrom.
+const (
+	ReflectionTag     = "reflection"
+	MetaReflectedFrom = "reflected_from"
+	reflectionAgent   = "memory-mcp/reflect"
+	// reflectionInputs bounds the memories shown to the LLM per namespace.
+	reflectionInputs = 50
+)
+
+// ErrNoLLM is returned by features that need llm.endpoint.
+var ErrNoLLM = errors.New("no LLM configured; set llm.endpoint")
+
+const reflectSystemPrompt = `You review an engineering team's shared memory and write higher-level insights: recurring preferences, decisions, conventions or problems that individual notes only hint at (e.g. "the team consistently prefers X"). Only state what several memories support. Reply with a JSON array of at most %d objects {"insight": string, "sources": [memory IDs]} and nothing else. Reply [] if nothing stands out.`
+
+type reflectInsight struct {
+	Insight string   `json:"insight"`
+	Sources []string `json:"sources"`
+}
+
+// ReflectAll runs Reflect for the configured namespaces, or for every
+// namespace with memories inside the window, and returns the number of
+// insights written. Errors in one namespace are logged and skipped.
+func (s *Service) ReflectAll(ctx context.Context) (int, error) {
+	if s.cfg.ReadOnly {
+		return 0, nil
+	}
+	if s.llm == nil {
+		return 0, ErrNoLLM
+	}
+	namespaces := s.cfg.Reflection.Namespaces
+	if len(namespaces) == 0 {
+		groups, err := s.store.CountBy(ctx, store.GroupNamespace, store.ListFilter{
+			CreatedAfter: s.now().Add(-s.reflectionWindow()),
+			Now:          s.now(),
+		})
+		if err != nil {
+			return 0, err
+		}
+		for _, g := range groups {
+			namespaces = append(namespaces, g.Key)
+		}
+	}
+	total := 0
+	for _, ns := range namespaces {
+		recs, err := s.Reflect(ctx, ns)
+		if err != nil {
+			s.logger.Warn("reflection failed", "namespace", ns, "error", err)
+			continue
+		}
+		total += len(recs)
+	}
+	return total, nil
+}
+
+// Reflect asks the LLM for insights about the namespace's memories written
+// since the later of the window start and its last reflection, and stores
+// each as a long-term memory tagged ReflectionTag. Namespaces with fewer
+// than reflection.min_memories new memories are skipped.
+func (s *Service) Reflect(ctx context.Context, namespace string) (_ []types.MemoryRecord, err error) {
+	ctx, span := startSpan(ctx, "memory.Reflect", namespaceAttr(namespace))
+	defer func() { endSpan(span, err) }()
+	if s.cfg.ReadOnly {
+		return nil, ErrReadOnly
+	}
+	if s.llm == nil {
+		return nil, ErrNoLLM
+	}
+	if err := s.validateNamespace(namespace); err != nil {
+		return nil, err
+	}
+	now := s.now()
+	since := now.Add(-s.reflectionWindow())
+	last, err := s.store.ListMemories(ctx, store.ListFilter{Namespace: namespace, Tags: []string{ReflectionTag}, Now: now, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(last) > 0 && last[0].CreatedAt.After(since) {
+		since = last[0].CreatedAt
+	}
+
+	all, err := s.store.ListMemories(ctx, store.ListFilter{Namespace: namespace, CreatedAfter: since, Now: now})
+	if err != nil {
+		return nil, err
+	}
+	inputs := make([]types.MemoryRecord, 0, len(all))
+	for _, rec := range all {
+		if !isReflection(rec) && rec.CreatedAt.After(since) {
+			inputs = append(inputs, rec)
+		}
+	}
+	if len(inputs) < s.cfg.Reflection.MinMemories || len(inputs) == 0 {
+		return nil, nil
+	}
+	if len(inputs) > reflectionInputs {
+		inputs = inputs[:reflectionInputs]
+	}
+
+	maxInsights := max(s.cfg.Reflection.MaxInsights, 1)
+	var b strings.Builder
+	known := make(map[string]struct{}, len(inputs))
+	for _, rec := range inputs {
+		known[rec.ID] = struct{}{}
+		fmt.Fprintf(&b, "[%s] (%s) %s\n", rec.ID, rec.CreatedAt.Format(time.DateOnly), truncate(strings.TrimSpace(rec.Content), 500))
+	}
+	reply, err := s.llm.Complete(ctx, []llm.Message{
+		{Role: "system", Content: fmt.Sprintf(reflectSystemPrompt, maxInsights)},
+		{Role: "user", Content: "Namespace: " + namespace + "\nMemories:\n" + b.String()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	insights, err := parseInsights(reply)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []types.MemoryRecord
+	for _, in := range insights {
+		if len(out) == maxInsights {
+			break
+		}
+		text := strings.TrimSpace(in.Insight)
+		var sources []any
+		for _, id := range in.Sources {
+			if _, ok := known[id]; ok {
+				sources = append(sources, id)
+			}
+		}
+		if text == "" || len(sources) == 0 {
+			continue
+		}
+		rec, err := s.Write(ctx, types.WriteInput{
+			Namespace:   namespace,
+			Scope:       "long",
+			Content:     text,
+			Importance:  4,
+			SourceAgent: reflectionAgent,
+			Metadata:    map[string]any{"tags": []any{ReflectionTag}, MetaReflectedFrom: sources},
+		})
+		if err != nil {
+			return out, err
+		}
+		out = append(out, rec)
+	}
+	if len(out) > 0 {
+		s.logger.Info("reflection wrote insights", "namespace", namespace, "count", len(out), "inputs", len(inputs))
+	}
+	return out, nil
+}
+
+func (s *Service) reflectionWindow() time.Duration {
+	return time.Duration(s.cfg.Reflection.WindowHours) * time.Hour
+}
+
+func isReflection(rec types.MemoryRecord) bool {
+	for _, tag := range tagList(rec.Metadata["tags"]) {
+		if tag == ReflectionTag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseInsights decodes the LLM reply, tolerating a Markdown code fence
+// around the JSON array.
+func parseInsights(reply string) ([]reflectInsight, error) {
+	reply = strings.TrimSpace(reply)
+	if i := strings.Index(reply, "["); i >= 0 {
+		if j := strings.LastIndex(reply, "]"); j > i {
+			reply = reply[i : j+1]
+		}
+	}
+	var out []reflectInsight
+	if err := json.Unmarshal([]byte(reply), &out); err != nil {
+		return nil, fmt.Errorf("decode reflection reply: %w", err)
+	}
+	return out, nil
+}
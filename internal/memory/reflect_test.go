@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/clock"
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestReflect_WritesTaggedInsightsLinkedToSources(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "reflect.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	clk := clock.NewManual(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	cfg := config.Default()
+	cfg.Reflection.MinMemories = 3
+	cfg.Reflection.MaxInsights = 1
+	model := &fakeLLM{}
+	svc, err := NewService(st, cfg, logger, WithClock(clk), WithLLM(model))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	var ids []string
+	for i := range 3 {
+		rec, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo", Content: fmt.Sprintf("chose table-driven tests for package %d", i)})
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		ids = append(ids, rec.ID)
+	}
+	clk.Advance(time.Minute)
+
+	model.answer = "```json\n" + `[{"insight": "The team consistently prefers table-driven tests.", "sources": ["` + ids[0] + `", "` + ids[2] + `", "bogus"]},
+		{"insight": "Second insight over the limit.", "sources": ["` + ids[1] + `"]}]` + "\n```"
+	got, err := svc.Reflect(ctx, "org/repo")
+	if err != nil {
+		t.Fatalf("Reflect() error = %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected 1 insight, got %+v", got)
+	}
+	in := got[0]
+	if in.Scope != "long" || in.Content != "The team consistently prefers table-driven tests." {
+		t.Fatalf("unexpected insight %+v", in)
+	}
+	if !reflect.DeepEqual(in.Metadata[MetaReflectedFrom], []any{ids[0], ids[2]}) || !isReflection(in) {
+		t.Fatalf("unexpected insight metadata %v", in.Metadata)
+	}
+	if prompt := model.prompt[1].Content; !strings.Contains(prompt, ids[1]) {
+		t.Fatalf("prompt does not list source memories: %q", prompt)
+	}
+
+	// Nothing new since the last reflection: the LLM is not consulted again.
+	model.prompt = nil
+	if got, err := svc.Reflect(ctx, "org/repo"); err != nil || len(got) != 0 || model.prompt != nil {
+		t.Fatalf("Reflect() = %v, %v, prompt %v; want a skipped run", got, err, model.prompt)
+	}
+}
+
+func TestReflect_IgnoresReflectionsOfChildNamespaces(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "reflect.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	clk := clock.NewManual(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	cfg := config.Default()
+	cfg.Reflection.MinMemories = 2
+	model := &fakeLLM{}
+	svc, err := NewService(st, cfg, logger, WithClock(clk), WithLLM(model))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	write := func(ns string) []string {
+		t.Helper()
+		var ids []string
+		for i := range 2 {
+			rec, err := svc.Write(ctx, types.WriteInput{Namespace: ns, Content: fmt.Sprintf("%s uses table-driven tests in package %d", ns, i)})
+			if err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			ids = append(ids, rec.ID)
+		}
+		clk.Advance(time.Minute)
+		return ids
+	}
+	write("org/repo")
+	child := write("org/repo/task")
+
+	model.answer = `[{"insight": "The task prefers table-driven tests.", "sources": ["` + child[0] + `"]}]`
+	if got, err := svc.Reflect(ctx, "org/repo/task"); err != nil || len(got) != 1 {
+		t.Fatalf("Reflect(child) = %+v, %v, want one insight", got, err)
+	}
+	clk.Advance(time.Minute)
+
+	model.prompt = nil
+	if _, err := svc.Reflect(ctx, "org/repo"); err != nil || model.prompt == nil {
+		t.Fatalf("Reflect(parent) error = %v, prompted %v; want the child's reflection not to count as the parent's", err, model.prompt != nil)
+	}
+}
+
+func TestReflect_RequiresLLM(t *testing.T) {
+	t.Parallel()
+	svc, err := NewService(&fakeStore{}, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	if _, err := svc.Reflect(context.Background(), "org/repo"); !errors.Is(err, ErrNoLLM) {
+		t.Fatalf("Reflect() error = %v, want ErrNoLLM", err)
+	}
+}
@@ -0,0 +1,210 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// ErrRelationsUnsupported is returned by Link when the store does not keep
+// links between memories.
+var ErrRelationsUnsupported = errors.New("memory relations require the sqlite store")
+
+// maxChain bounds how far a supersession chain is followed.
+const maxChain = 20
+
+// Link records that in.FromID relates to in.ToID. A memory cannot link to
+// itself, and a supersedes link that would close a cycle is refused.
+func (s *Service) Link(ctx context.Context, in types.LinkInput) (_ types.MemoryRelation, err error) {
+	ctx, span := startSpan(ctx, "memory.Link", idAttr(in.FromID), attribute.String("memory.to_id", in.ToID))
+	defer func() { endSpan(span, err) }()
+	if s.cfg.ReadOnly {
+		return types.MemoryRelation{}, ErrReadOnly
+	}
+	if s.relations == nil {
+		return types.MemoryRelation{}, ErrRelationsUnsupported
+	}
+	in.FromID, in.ToID = strings.TrimSpace(in.FromID), strings.TrimSpace(in.ToID)
+	if in.FromID == "" || in.ToID == "" {
+		return types.MemoryRelation{}, errors.New("from_id and to_id are required")
+	}
+	if in.FromID == in.ToID {
+		return types.MemoryRelation{}, errors.New("a memory cannot be linked to itself")
+	}
+	kind := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(in.Relation)), "-", "_")
+	switch kind {
+	case store.RelationSupersedes, store.RelationRefines, store.RelationRelatesTo, store.RelationDerivedFrom:
+	default:
+		return types.MemoryRelation{}, fmt.Errorf("invalid relation %q (expected supersedes, refines, relates_to or derived_from)", in.Relation)
+	}
+	var recs [2]types.MemoryRecord
+	for i, id := range []string{in.FromID, in.ToID} {
+		rec, err := s.store.GetMemory(ctx, id)
+		if err == nil {
+			err = checkRecord(ctx, rec, true)
+		}
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return types.MemoryRelation{}, fmt.Errorf("memory %s not found", id)
+			}
+			return types.MemoryRelation{}, err
+		}
+		recs[i] = rec
+	}
+	if kind == store.RelationSupersedes {
+		cycle, err := s.supersedesTransitively(ctx, in.ToID, in.FromID)
+		if err != nil {
+			return types.MemoryRelation{}, err
+		}
+		if cycle {
+			return types.MemoryRelation{}, fmt.Errorf("memory %s already supersedes %s", in.ToID, in.FromID)
+		}
+	}
+
+	rel := store.Relation{
+		FromID:      in.FromID,
+		ToID:        in.ToID,
+		Kind:        kind,
+		Namespace:   recs[0].Namespace,
+		SourceAgent: strings.TrimSpace(in.SourceAgent),
+		CreatedAt:   s.now(),
+	}
+	if err := s.relations.LinkMemories(ctx, rel); err != nil {
+		return types.MemoryRelation{}, err
+	}
+	s.invalidate(recs[0].Namespace, recs[1].Namespace)
+	return types.MemoryRelation{FromID: rel.FromID, ToID: rel.ToID, Relation: rel.Kind, SourceAgent: rel.SourceAgent, CreatedAt: rel.CreatedAt}, nil
+}
+
+// supersedesTransitively reports whether from supersedes to directly or
+// through a chain of supersedes links.
+func (s *Service) supersedesTransitively(ctx context.Context, from, to string) (bool, error) {
+	seen := map[string]bool{from: true}
+	frontier := []string{from}
+	for range maxChain {
+		if len(frontier) == 0 {
+			return false, nil
+		}
+		rels, err := s.relations.RelationsOf(ctx, store.RelationSupersedes, frontier)
+		if err != nil {
+			return false, err
+		}
+		var next []string
+		for _, r := range rels {
+			if !seen[r.FromID] || seen[r.ToID] {
+				continue
+			}
+			if r.ToID == to {
+				return true, nil
+			}
+			seen[r.ToID] = true
+			next = append(next, r.ToID)
+		}
+		frontier = next
+	}
+	return false, nil
+}
+
+// superseded returns which of recs another existing memory supersedes,
+// through a supersedes link or, after memory_merge, superseded_by metadata.
+// Failed lookups are logged and leave the memory in place.
+func (s *Service) superseded(ctx context.Context, recs []types.MemoryRecord) map[string]bool {
+	out := map[string]bool{}
+	ids := make([]string, 0, len(recs))
+	for _, rec := range recs {
+		if _, merged := rec.Metadata[MetaSupersededBy]; merged {
+			out[rec.ID] = true
+		}
+		ids = append(ids, rec.ID)
+	}
+	if s.relations == nil || len(ids) == 0 {
+		return out
+	}
+	rels, err := s.relations.RelationsOf(ctx, store.RelationSupersedes, ids)
+	if err != nil {
+		s.logger.Warn("reading memory relations failed", "error", err)
+		return out
+	}
+	exists := map[string]bool{}
+	for _, r := range rels {
+		if out[r.ToID] || !slices.Contains(ids, r.ToID) {
+			continue
+		}
+		ok, seen := exists[r.FromID]
+		if !seen {
+			next, err := s.store.GetMemory(ctx, r.FromID)
+			ok = err == nil && visible(ctx, next)
+			exists[r.FromID] = ok
+		}
+		if ok {
+			out[r.ToID] = true
+		}
+	}
+	return out
+}
+
+// collapseSuperseded drops the results another memory supersedes.
+func (s *Service) collapseSuperseded(ctx context.Context, results []types.SearchResult) []types.SearchResult {
+	recs := make([]types.MemoryRecord, len(results))
+	for i, r := range results {
+		recs[i] = r.Record
+	}
+	drop := s.superseded(ctx, recs)
+	if len(drop) == 0 {
+		return results
+	}
+	return slices.DeleteFunc(results, func(r types.SearchResult) bool { return drop[r.Record.ID] })
+}
+
+// successor returns the newest existing memory that supersedes rec.
+func (s *Service) successor(ctx context.Context, rec types.MemoryRecord) (types.MemoryRecord, bool) {
+	var candidates []string
+	if id, ok := rec.Metadata[MetaSupersededBy].(string); ok && id != "" {
+		candidates = append(candidates, id)
+	}
+	if s.relations != nil {
+		rels, err := s.relations.RelationsOf(ctx, store.RelationSupersedes, []string{rec.ID})
+		if err != nil {
+			s.logger.Warn("reading memory relations failed", "memory_id", rec.ID, "error", err)
+		}
+		for _, r := range rels {
+			if r.ToID == rec.ID {
+				candidates = append(candidates, r.FromID)
+			}
+		}
+	}
+	var best types.MemoryRecord
+	found := false
+	for _, id := range candidates {
+		next, err := s.store.GetMemory(ctx, id)
+		if err != nil || !visible(ctx, next) {
+			continue
+		}
+		if !found || next.CreatedAt.After(best.CreatedAt) {
+			best, found = next, true
+		}
+	}
+	return best, found
+}
+
+// chainHead follows rec's supersession chain to its newest memory.
+func (s *Service) chainHead(ctx context.Context, rec types.MemoryRecord) types.MemoryRecord {
+	seen := map[string]bool{rec.ID: true}
+	for range maxChain {
+		next, ok := s.successor(ctx, rec)
+		if !ok || seen[next.ID] {
+			break
+		}
+		seen[next.ID] = true
+		rec = next
+	}
+	return rec
+}
@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/clock"
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestLink_SupersessionChain(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "link.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	clk := clock.NewManual(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	svc, err := NewService(st, config.Default(), logger, WithClock(clk))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	var ids []string
+	for _, content := range []string{"deploy window is 9-11", "deploy window is 10-12", "deploy window is 13-15"} {
+		rec, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Scope: "long", Content: content, Importance: 3})
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		ids = append(ids, rec.ID)
+		clk.Advance(time.Minute)
+	}
+	v1, v2, v3 := ids[0], ids[1], ids[2]
+
+	if _, err := svc.Link(ctx, types.LinkInput{FromID: v1, ToID: v1, Relation: "supersedes"}); err == nil {
+		t.Fatal("Link() to itself error = nil")
+	}
+	if _, err := svc.Link(ctx, types.LinkInput{FromID: v2, ToID: v1, Relation: "replaces"}); err == nil {
+		t.Fatal("Link() with an unknown relation error = nil")
+	}
+	if _, err := svc.Link(ctx, types.LinkInput{FromID: v2, ToID: "missing", Relation: "supersedes"}); err == nil {
+		t.Fatal("Link() to a missing memory error = nil")
+	}
+	rel, err := svc.Link(ctx, types.LinkInput{FromID: v2, ToID: v1, Relation: "supersedes", SourceAgent: "codex"})
+	if err != nil || rel.Relation != store.RelationSupersedes || rel.SourceAgent != "codex" {
+		t.Fatalf("Link() = %+v, %v", rel, err)
+	}
+	if _, err := svc.Link(ctx, types.LinkInput{FromID: v3, ToID: v2, Relation: "supersedes"}); err != nil {
+		t.Fatalf("Link() error = %v", err)
+	}
+	if _, err := svc.Link(ctx, types.LinkInput{FromID: v1, ToID: v3, Relation: "supersedes"}); err == nil {
+		t.Fatal("Link() closing a supersedes cycle error = nil")
+	}
+	if _, err := svc.Link(ctx, types.LinkInput{FromID: v1, ToID: v3, Relation: "relates-to"}); err != nil {
+		t.Fatalf("Link(relates-to) error = %v", err)
+	}
+
+	results, err := svc.Search(ctx, types.SearchInput{Namespace: "org/repo/task", Query: "deploy window", K: 10})
+	if err != nil || len(results) != 3 {
+		t.Fatalf("Search() = %d results, %v, want 3", len(results), err)
+	}
+	results, err = svc.Search(ctx, types.SearchInput{Namespace: "org/repo/task", Query: "deploy window", K: 10, CollapseSuperseded: true})
+	if err != nil || len(results) != 1 || results[0].Record.ID != v3 {
+		t.Fatalf("Search(collapse_superseded) = %+v, %v, want only %s", results, err, v3)
+	}
+
+	// Only the old window matches, yet the pack carries its newest successor.
+	pack, err := svc.ContextPack(ctx, types.ContextPackInput{Namespace: "org/repo/task", Query: "9-11"})
+	if err != nil {
+		t.Fatalf("ContextPack() error = %v", err)
+	}
+	if !slices.Equal(pack.MemoryIDs, []string{v3}) {
+		t.Fatalf("ContextPack() ids = %v, want [%s]", pack.MemoryIDs, v3)
+	}
+
+	// Once the head is gone the chain ends at the memory it superseded.
+	if _, err := svc.Delete(ctx, types.DeleteInput{MemoryIDs: []string{v3}}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	pack, err = svc.ContextPack(ctx, types.ContextPackInput{Namespace: "org/repo/task", Query: "9-11"})
+	if err != nil {
+		t.Fatalf("ContextPack() error = %v", err)
+	}
+	if !slices.Equal(pack.MemoryIDs, []string{v2}) {
+		t.Fatalf("ContextPack() after delete ids = %v, want [%s]", pack.MemoryIDs, v2)
+	}
+}
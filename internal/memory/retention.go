@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// retentionRule is one long_term_retention entry; a zero duration is no
+// limit.
+type retentionRule struct {
+	namespace string
+	maxAge    time.Duration
+	stale     time.Duration
+}
+
+func newRetentionRules(cfg map[string]config.RetentionConfig) []retentionRule {
+	out := make([]retentionRule, 0, len(cfg))
+	for ns, r := range cfg {
+		out = append(out, retentionRule{
+			namespace: strings.Trim(strings.TrimSpace(ns), "/"),
+			maxAge:    time.Duration(r.MaxAgeDays) * 24 * time.Hour,
+			stale:     time.Duration(r.StaleDays) * 24 * time.Hour,
+		})
+	}
+	// Longest namespace first so the most specific entry wins.
+	sort.Slice(out, func(i, j int) bool {
+		return len(out[i].namespace) > len(out[j].namespace)
+	})
+	return out
+}
+
+// expired reports whether long-term rec is past the rule at now. Age
+// counts from promotion for promoted memories.
+func (r retentionRule) expired(rec types.MemoryRecord, now time.Time) bool {
+	since := rec.CreatedAt
+	if rec.PromotedAt != nil {
+		since = *rec.PromotedAt
+	}
+	if r.maxAge > 0 && now.Sub(since) >= r.maxAge {
+		return true
+	}
+	return r.stale > 0 && now.Sub(rec.LastAccessedAt) >= r.stale
+}
+
+// minAge is the youngest a memory can be and still expire under r.
+func (r retentionRule) minAge() time.Duration {
+	if r.maxAge == 0 || (r.stale > 0 && r.stale < r.maxAge) {
+		return r.stale
+	}
+	return r.maxAge
+}
+
+func (s *Service) retentionFor(namespace string) (retentionRule, bool) {
+	for _, r := range s.retention {
+		if namespace == r.namespace || strings.HasPrefix(namespace, r.namespace+"/") {
+			return r, true
+		}
+	}
+	return retentionRule{}, false
+}
+
+// expireLong deletes the long-term memories past their namespace's
+// long_term_retention rule.
+func (s *Service) expireLong(ctx context.Context, now time.Time) (int64, error) {
+	var ids []string
+	seen := map[string]struct{}{}
+	var expired []types.MemoryRecord
+	for _, r := range s.retention {
+		recs, err := s.store.ListMemories(ctx, store.ListFilter{
+			NamespacePrefix: r.namespace,
+			Scope:           "long",
+			CreatedBefore:   now.Add(-r.minAge()),
+		})
+		if err != nil {
+			return 0, err
+		}
+		for _, rec := range recs {
+			if _, ok := seen[rec.ID]; ok {
+				continue
+			}
+			seen[rec.ID] = struct{}{}
+			if rule, _ := s.retentionFor(rec.Namespace); rule.expired(rec, now) {
+				ids = append(ids, rec.ID)
+				expired = append(expired, rec)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	n, err := s.store.DeleteMemories(ctx, ids)
+	s.invalidate(namespacesOf(expired)...)
+	if err != nil {
+		return n, err
+	}
+	s.auditDeleted(ctx, store.AuditExpire, expired)
+	return n, nil
+}
@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xiy/memory-mcp/internal/textnorm"
+)
+
+// cleanText applies content_sanitization to text an agent sent, so it
+// indexes in FTS and survives JSON round-trips unchanged. In both modes
+// CRLF and CR line endings become LF and the result is NFC-normalized.
+// field names the input in errors.
+func (s *Service) cleanText(field, text string) (string, error) {
+	if strings.Contains(text, "\r") {
+		text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+	}
+	offset, bad := firstUnclean(text)
+	if offset < 0 {
+		return textnorm.Canonical(text), nil
+	}
+	if s.cfg.ContentSanitization == "reject" {
+		return "", fmt.Errorf("%s contains %s at byte %d (content_sanitization is reject)", field, bad, offset)
+	}
+
+	var b strings.Builder
+	b.Grow(len(text))
+	b.WriteString(text[:offset])
+	for i := offset; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b.WriteRune(utf8.RuneError)
+		case isControl(r):
+		default:
+			b.WriteString(text[i : i+size])
+		}
+		i += size
+	}
+	return textnorm.Canonical(b.String()), nil
+}
+
+// firstUnclean returns the byte offset and a description of the first
+// invalid UTF-8 sequence or control character in text, or -1.
+func firstUnclean(text string) (int, string) {
+	for i, r := range text {
+		if r == utf8.RuneError {
+			if _, size := utf8.DecodeRuneInString(text[i:]); size == 1 {
+				return i, "invalid UTF-8"
+			}
+		}
+		if isControl(r) {
+			return i, fmt.Sprintf("control character %U", r)
+		}
+	}
+	return -1, ""
+}
+
+// isControl reports C0 and C1 control characters other than tab and
+// newline.
+func isControl(r rune) bool {
+	return (r < 0x20 && r != '\t' && r != '\n') || (r >= 0x7f && r <= 0x9f)
+}
@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/xiy/memory-mcp/internal/config"
+)
+
+// validScope reports whether scope is one of config.Scopes.
+func validScope(scope string) bool {
+	return slices.Contains(config.Scopes, scope)
+}
+
+// widerScope reports whether scope a reaches further than scope b.
+func widerScope(a, b string) bool {
+	return slices.Index(config.Scopes, a) > slices.Index(config.Scopes, b)
+}
+
+// projectNamespace returns the project ns belongs to: its first
+// scopes.project_depth segments.
+func (s *Service) projectNamespace(ns string) string {
+	parts := strings.Split(strings.Trim(ns, "/"), "/")
+	return strings.Join(parts[:min(len(parts), s.cfg.Scopes.ProjectDepth)], "/")
+}
+
+// promotableFrom returns the scopes memory_promote may move memories
+// into target from.
+func (s *Service) promotableFrom(target string) []string {
+	switch target {
+	case "long":
+		return []string{"short"}
+	case "project":
+		return s.cfg.Scopes.Project.PromoteFrom
+	case "global":
+		return s.cfg.Scopes.Global.PromoteFrom
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/cache"
+	"github.com/xiy/memory-mcp/internal/clock"
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/store"
+)
+
+type searchKey struct {
+	namespace string
+	query     string
+	scope     string
+	limit     int
+	// filter is the raw metadata filter expression.
+	filter      string
+	sourceAgent string
+	advanced    bool
+}
+
+// searchCache memoizes store search candidates. Ranking still runs on every
+// call so recency reflects the current time. A generation counter stops a
+// search that raced a write from caching pre-write results.
+type searchCache struct {
+	lru *cache.LRU[searchKey, []store.Candidate]
+	gen atomic.Uint64
+}
+
+// newSearchCache returns nil when the cache is disabled; all methods accept
+// a nil receiver.
+func newSearchCache(cfg config.CacheConfig, clk clock.Clock) *searchCache {
+	if cfg.SearchEntries <= 0 {
+		return nil
+	}
+	ttl := time.Duration(cfg.SearchTTLSeconds) * time.Second
+	return &searchCache{lru: cache.New[searchKey, []store.Candidate](cfg.SearchEntries, ttl, clk)}
+}
+
+// candidates returns the store's candidates for q. filter is the expression
+// q.Metadata was parsed from and only serves as part of the cache key.
+func (c *searchCache) candidates(ctx context.Context, st store.Store, q store.SearchQuery, filter string) ([]store.Candidate, error) {
+	if c == nil {
+		return st.SearchCandidates(ctx, q)
+	}
+	key := searchKey{
+		namespace:   q.Namespace,
+		query:       strings.TrimSpace(q.Query),
+		scope:       q.Scope,
+		limit:       q.Limit,
+		filter:      strings.TrimSpace(filter),
+		sourceAgent: q.SourceAgent,
+		advanced:    q.Advanced,
+	}
+	if cands, ok := c.lru.Get(key); ok {
+		return cands, nil
+	}
+	gen := c.gen.Load()
+	cands, err := st.SearchCandidates(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if c.gen.Load() == gen {
+		c.lru.Put(key, cands)
+	}
+	return cands, nil
+}
+
+// invalidate drops cached searches for the given namespaces.
+func (c *searchCache) invalidate(namespaces ...string) {
+	if c == nil || len(namespaces) == 0 {
+		return
+	}
+	c.gen.Add(1)
+	set := make(map[string]struct{}, len(namespaces))
+	for _, ns := range namespaces {
+		set[ns] = struct{}{}
+	}
+	c.lru.DeleteFunc(func(k searchKey) bool {
+		_, ok := set[k.namespace]
+		return ok
+	})
+}
+
+func (c *searchCache) invalidateAll() {
+	if c == nil {
+		return
+	}
+	c.gen.Add(1)
+	c.lru.Purge()
+}
+
+func (c *searchCache) stats() cache.Stats {
+	if c == nil {
+		return cache.Stats{}
+	}
+	return c.lru.Stats()
+}
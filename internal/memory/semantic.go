@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+
+	"github.com/xiy/memory-mcp/internal/embeddings"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// embed stores rec's vector. Like fact extraction it is best effort: a
+// memory without a vector is still found lexically, and admin reembed
+// fills in what was missed.
+func (s *Service) embed(ctx context.Context, rec types.MemoryRecord) {
+	if s.embedder == nil {
+		return
+	}
+	vec, name, err := s.embedder.EmbedWith(ctx, embeddings.Text(rec))
+	if err != nil {
+		s.logger.Warn("embedding failed", "memory_id", rec.ID, "error", err)
+		return
+	}
+	if err := s.vectors.PutEmbedding(ctx, rec.ID, embeddings.ModelOf(name), vec, s.now()); err != nil {
+		s.logger.Warn("storing embedding failed", "memory_id", rec.ID, "error", err)
+	}
+}
+
+// semanticMatches embeds query and returns the similarity of the closest
+// memories in each of namespaces within q's scope, keyed by memory ID. It
+// returns nil when semantic ranking is off, the query is empty, or the
+// query vector is not from the store's active model, so searches fall back
+// to lexical ranking.
+func (s *Service) semanticMatches(ctx context.Context, q store.VectorQuery, namespaces []string, query string) map[string]float64 {
+	if s.embedder == nil || query == "" {
+		return nil
+	}
+	vec, name, err := s.embedder.EmbedWith(ctx, query)
+	if err != nil {
+		s.logger.Warn("query embedding failed; ranking lexically", "error", err)
+		return nil
+	}
+	q.Model, q.Vector = embeddings.ModelOf(name), vec
+	out := map[string]float64{}
+	for _, ns := range namespaces {
+		q.Namespace = ns
+		matches, err := s.vectors.NearestEmbeddings(ctx, q)
+		if errors.Is(err, store.ErrEmbeddingMismatch) {
+			s.logger.Debug("query embedded by another model than the store's; ranking lexically", "error", err)
+			return nil
+		}
+		if err != nil {
+			s.logger.Warn("semantic search failed; ranking lexically", "error", err)
+			return nil
+		}
+		for _, m := range matches {
+			out[m.MemoryID] = max(m.Similarity, 0)
+		}
+	}
+	return out
+}
+
+// addSemanticOnly appends the memories similar enough to the query that
+// lexical search missed. They are skipped under a metadata filter, which
+// only the store evaluates.
+func (s *Service) addSemanticOnly(ctx context.Context, cands []store.Candidate, sims map[string]float64, filtered bool) []store.Candidate {
+	if len(sims) == 0 || filtered {
+		return cands
+	}
+	// cands may be shared with the search cache.
+	cands = slices.Clip(cands)
+	seen := make(map[string]bool, len(cands))
+	for _, c := range cands {
+		seen[c.Record.ID] = true
+	}
+	for _, id := range slices.Sorted(maps.Keys(sims)) {
+		if sim := sims[id]; seen[id] || sim < s.cfg.Embeddings.MinSimilarity {
+			continue
+		}
+		rec, err := s.store.GetMemory(ctx, id)
+		if err != nil {
+			continue
+		}
+		cands = append(cands, store.Candidate{Record: rec})
+	}
+	return cands
+}
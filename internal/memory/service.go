@@ -7,102 +7,579 @@ import (
 	"fmt"
 	"math"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
-	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 
+	"github.com/xiy/memory-mcp/internal/cache"
+	"github.com/xiy/memory-mcp/internal/clock"
 	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/embeddings"
+	"github.com/xiy/memory-mcp/internal/ids"
+	"github.com/xiy/memory-mcp/internal/lang"
+	"github.com/xiy/memory-mcp/internal/llm"
 	"github.com/xiy/memory-mcp/internal/store"
 	"github.com/xiy/memory-mcp/pkg/types"
 )
 
+// ErrReadOnly is returned by mutating operations when the server runs in
+// read-only mode.
+var ErrReadOnly = errors.New("memory server is read-only; writes are disabled")
+
 // Service coordinates validation, ranking and retrieval behavior.
 type Service struct {
 	store         store.Store
 	cfg           config.Config
 	namespaceExpr *regexp.Regexp
+	ids           *ids.Generator
+	clock         clock.Clock
+	searches      *searchCache
+	packs         *packCache
 	logger        *log.Logger
+	// factStore is set when fact extraction is enabled and supported.
+	factStore store.FactStore
+	// feedback is set when the store keeps relevance feedback.
+	feedback store.FeedbackStore
+	// agentBoosts is ranking.agents keyed by lower-cased agent.
+	agentBoosts map[string]map[string]float64
+	llm         llm.Client
+	// stopWords are dropped from search queries.
+	stopWords lang.StopWords
+	// misses is set when zero-result searches are recorded.
+	misses store.MissStore
+	// suggester is set when the store can correct missed queries.
+	suggester store.Suggester
+	// sliding lists sliding_ttl_hours, most specific namespace first.
+	sliding []slidingWindow
+	// audit is set when changes are recorded in an audit log.
+	audit store.AuditStore
+	// requestLog is set when the store logs MCP requests with their params.
+	requestLog store.RequestLogScrubber
+	// promotions is set when the store keeps a promotion audit trail.
+	promotions store.PromotionStore
+	// retention lists long_term_retention, most specific namespace first.
+	retention []retentionRule
+	// extender is set when the store can extend expiries in place.
+	extender store.ExpiryExtender
+	// accessRecorder is set when the store counts retrievals; accesses
+	// queues them between flushes.
+	accessRecorder store.AccessRecorder
+	accesses       accessLog
+	// relations is set when the store keeps links between memories.
+	relations store.RelationStore
+	// packFormats are the built-in context pack formats and those of
+	// context_pack.templates.
+	packFormats map[string]packFormatter
+	// embedder and vectors are set when embeddings.providers are
+	// configured and the store keeps vectors.
+	embedder *embeddings.Chain
+	vectors  store.EmbeddingStore
+	// keyMu serializes writes with an external_key so two cannot both
+	// insert the same key.
+	keyMu sync.Mutex
+}
+
+// Option customizes a Service.
+type Option func(*Service)
+
+// WithClock replaces the wall clock used for TTLs, recency and expiry.
+func WithClock(c clock.Clock) Option {
+	return func(s *Service) { s.clock = c }
+}
+
+// WithLLM enables answer synthesis in Ask.
+func WithLLM(c llm.Client) Option {
+	return func(s *Service) { s.llm = c }
 }
 
 // NewService constructs a memory service.
-func NewService(st store.Store, cfg config.Config, logger *log.Logger) (*Service, error) {
+func NewService(st store.Store, cfg config.Config, logger *log.Logger, opts ...Option) (*Service, error) {
 	re, err := regexp.Compile(cfg.NamespacePattern)
 	if err != nil {
 		return nil, fmt.Errorf("compile namespace pattern: %w", err)
 	}
-	return &Service{store: st, cfg: cfg, namespaceExpr: re, logger: logger}, nil
+	gen, err := ids.NewGenerator(cfg.IDFormat, cfg.IDPrefixes)
+	if err != nil {
+		return nil, err
+	}
+	s := &Service{store: st, cfg: cfg, namespaceExpr: re, ids: gen, clock: clock.System{}, logger: logger}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.searches = newSearchCache(cfg.Cache, s.clock)
+	s.packs = newPackCache(cfg.Cache, s.clock)
+	if s.packFormats, err = newPackFormats(cfg.ContextPack); err != nil {
+		return nil, err
+	}
+	s.stopWords = lang.NewStopWords(cfg.StopWords)
+	if cfg.StopWordList == "english" {
+		s.stopWords = lang.NewStopWords(lang.EnglishStopWords, cfg.StopWords)
+	}
+	if fb, ok := st.(store.FeedbackStore); ok {
+		s.feedback = fb
+	}
+	s.sliding = newSlidingWindows(cfg.SlidingTTLHours)
+	s.retention = newRetentionRules(cfg.LongTermRetention)
+	if as, ok := st.(store.AuditStore); ok && cfg.AuditLog {
+		s.audit = as
+	}
+	if rl, ok := st.(store.RequestLogScrubber); ok {
+		s.requestLog = rl
+	}
+	if ps, ok := st.(store.PromotionStore); ok {
+		s.promotions = ps
+	}
+	if ex, ok := st.(store.ExpiryExtender); ok {
+		s.extender = ex
+	}
+	if ar, ok := st.(store.AccessRecorder); ok {
+		s.accessRecorder = ar
+		s.accesses.full = make(chan struct{}, 1)
+	}
+	if rs, ok := st.(store.RelationStore); ok {
+		s.relations = rs
+	}
+	s.agentBoosts = make(map[string]map[string]float64, len(cfg.Ranking.Agents))
+	for agent, boosts := range cfg.Ranking.Agents {
+		s.agentBoosts[strings.ToLower(strings.TrimSpace(agent))] = boosts
+	}
+	if cfg.RecordSearchMisses {
+		if ms, ok := st.(store.MissStore); ok {
+			s.misses = ms
+		}
+	}
+	if sg, ok := st.(store.Suggester); ok && cfg.SearchSuggestions > 0 {
+		s.suggester = sg
+	}
+	if chain := embeddings.New(cfg.Embeddings); chain != nil {
+		if es, ok := st.(store.EmbeddingStore); ok {
+			s.embedder, s.vectors = chain, es
+		} else {
+			logger.Warn("embeddings.providers set but the store does not keep vectors; semantic ranking disabled")
+		}
+	}
+	if cfg.Facts.Enabled {
+		if fs, ok := st.(store.FactStore); ok {
+			s.factStore = fs
+		} else {
+			logger.Warn("facts.enabled set but the store does not keep facts; extraction disabled")
+		}
+	}
+	return s, nil
+}
+
+// SearchCacheStats reports search cache hits, misses and size.
+func (s *Service) SearchCacheStats() cache.Stats {
+	return s.searches.stats()
+}
+
+// PackCacheStats reports context pack cache hits, misses and size.
+func (s *Service) PackCacheStats() cache.Stats {
+	return s.packs.stats()
+}
+
+// invalidate drops the cached searches and context packs that cover any of
+// namespaces.
+func (s *Service) invalidate(namespaces ...string) {
+	s.searches.invalidate(namespaces...)
+	s.packs.invalidate(namespaces...)
+}
+
+// invalidateRecords drops the cached searches and packs covering recs'
+// namespaces. Packs of any namespace may draw on a project or global
+// memory, so one of those drops every cached pack.
+func (s *Service) invalidateRecords(recs ...types.MemoryRecord) {
+	s.invalidate(namespacesOf(recs)...)
+	for _, rec := range recs {
+		if rec.Scope == "project" || rec.Scope == "global" {
+			s.packs.invalidateAll()
+			return
+		}
+	}
+}
+
+func (s *Service) invalidateAll() {
+	s.searches.invalidateAll()
+	s.packs.invalidateAll()
 }
 
 // Write validates and stores a memory record.
-func (s *Service) Write(ctx context.Context, in types.WriteInput) (types.MemoryRecord, error) {
+func (s *Service) Write(ctx context.Context, in types.WriteInput) (_ types.MemoryRecord, err error) {
+	ctx, span := startSpan(ctx, "memory.Write", namespaceAttr(in.Namespace))
+	defer func() { endSpan(span, err) }()
+	if s.cfg.ReadOnly {
+		return types.MemoryRecord{}, ErrReadOnly
+	}
 	if err := s.validateNamespace(in.Namespace); err != nil {
 		return types.MemoryRecord{}, err
 	}
+	if err := checkNamespace(ctx, in.Namespace, true); err != nil {
+		return types.MemoryRecord{}, err
+	}
 	in.Scope = strings.TrimSpace(strings.ToLower(in.Scope))
+	scopeGiven := in.Scope != ""
 	if in.Scope == "" {
 		in.Scope = "short"
 	}
-	if in.Scope != "short" && in.Scope != "long" {
+	if !validScope(in.Scope) {
 		return types.MemoryRecord{}, fmt.Errorf("invalid scope %q", in.Scope)
 	}
+	in.ExternalKey = strings.TrimSpace(in.ExternalKey)
+	if len(in.ExternalKey) > maxExternalKeyLen {
+		return types.MemoryRecord{}, fmt.Errorf("external_key is longer than %d bytes", maxExternalKeyLen)
+	}
+	if in.Content, err = s.cleanText("content", in.Content); err != nil {
+		return types.MemoryRecord{}, err
+	}
+	if in.Summary, err = s.cleanText("summary", in.Summary); err != nil {
+		return types.MemoryRecord{}, err
+	}
 	if strings.TrimSpace(in.Content) == "" {
 		return types.MemoryRecord{}, errors.New("content must not be empty")
 	}
 
-	now := time.Now().UTC()
-	importance := in.Importance
-	if importance < 1 || importance > 5 {
-		importance = 3
-	}
-
+	now := s.now()
 	summary := strings.TrimSpace(in.Summary)
 	if summary == "" {
 		summary = autoSummary(in.Content)
 	}
 
-	var expiresAt *time.Time
-	if in.Scope == "short" {
-		ttlSeconds := in.TTLSeconds
-		if ttlSeconds <= 0 {
-			ttlSeconds = s.cfg.DefaultShortTTLHours * 3600
+	expiresAt := s.scopeExpiry(in, now)
+
+	id := s.ids.New(in.Namespace, in.Content, now)
+	if in.ExternalKey != "" {
+		s.keyMu.Lock()
+		defer s.keyMu.Unlock()
+		existing, err := s.store.ListMemories(ctx, store.ListFilter{
+			NamespacePrefix: in.Namespace,
+			ExternalKey:     in.ExternalKey,
+			IncludeExpired:  true,
+		})
+		if err != nil {
+			return types.MemoryRecord{}, err
+		}
+		for _, rec := range existing {
+			if rec.Namespace == in.Namespace {
+				return s.upsert(ctx, rec.ID, in, scopeGiven, now)
+			}
+		}
+	}
+	if s.ids.ContentAddressed() && in.ExternalKey == "" {
+		// Re-writing identical content returns the stored record.
+		existing, err := s.store.GetMemory(ctx, id)
+		if err == nil {
+			return s.rewriteIdentical(ctx, existing, in, scopeGiven, now)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return types.MemoryRecord{}, err
+		}
+	}
+
+	if s.cfg.WriteDedupe.Policy != "off" && in.ExternalKey == "" {
+		dup, sim, err := s.findDuplicate(ctx, in, now)
+		if err != nil {
+			return types.MemoryRecord{}, err
+		}
+		if dup != nil {
+			switch s.cfg.WriteDedupe.Policy {
+			case "reject":
+				return types.MemoryRecord{}, fmt.Errorf("%w: %s already says this (similarity %.2f)", ErrDuplicate, dup.ID, sim)
+			case "update":
+				return s.upsert(ctx, dup.ID, in, scopeGiven, now)
+			case "merge":
+				return s.mergeInto(ctx, dup.ID, in, now)
+			}
+		}
+	}
+
+	importance := in.Importance
+	metadata := in.Metadata
+	if importance < 1 || importance > 5 {
+		importance = 3
+		if s.cfg.ImportanceScoring != "off" {
+			var scorer string
+			importance, scorer = s.autoImportance(ctx, in.Content, in.Summary)
+			metadata = make(map[string]any, len(in.Metadata)+1)
+			for k, v := range in.Metadata {
+				metadata[k] = v
+			}
+			metadata[MetaImportanceAuto] = scorer
 		}
-		t := now.Add(time.Duration(ttlSeconds) * time.Second)
-		expiresAt = &t
 	}
 
 	rec := types.MemoryRecord{
-		ID:             uuid.NewString(),
+		ID:             id,
 		Namespace:      in.Namespace,
 		Scope:          in.Scope,
 		Content:        in.Content,
 		Summary:        summary,
 		Importance:     importance,
 		SourceAgent:    in.SourceAgent,
-		Metadata:       in.Metadata,
+		Metadata:       metadata,
 		CreatedAt:      now,
 		LastAccessedAt: now,
 		ExpiresAt:      expiresAt,
+		ExternalKey:    in.ExternalKey,
 	}
 
 	stored, err := s.store.InsertMemory(ctx, rec)
 	if err != nil {
+		if s.ids.ContentAddressed() && in.ExternalKey == "" {
+			// A concurrent write of the same content won the insert;
+			// treat this one as rewriting it.
+			if existing, gerr := s.store.GetMemory(ctx, id); gerr == nil {
+				return s.rewriteIdentical(ctx, existing, in, scopeGiven, now)
+			}
+		}
 		return types.MemoryRecord{}, err
 	}
+	s.invalidateRecords(stored)
+	s.extractFacts(ctx, stored)
+	s.embed(ctx, stored)
+	s.recordAudit(ctx, s.auditEntry(ctx, store.AuditWrite, in.SourceAgent, types.MemoryRecord{}, stored))
 
 	return stored, nil
 }
 
-// Search returns ranked memory items.
-func (s *Service) Search(ctx context.Context, in types.SearchInput) ([]types.SearchResult, error) {
-	if err := s.validateNamespace(in.Namespace); err != nil {
+// maxExternalKeyLen bounds external_key, which is indexed.
+const maxExternalKeyLen = 256
+
+// scopeExpiry is the expiry of a write: in.TTLSeconds or the default TTL
+// for short-term memories, the scope's ttl_hours for project and global
+// ones, and nil for long-term ones or a ttl_hours of 0.
+func (s *Service) scopeExpiry(in types.WriteInput, now time.Time) *time.Time {
+	var ttl time.Duration
+	switch in.Scope {
+	case "short":
+		ttl = time.Duration(s.cfg.DefaultShortTTLHours) * time.Hour
+		if in.TTLSeconds > 0 {
+			ttl = time.Duration(in.TTLSeconds) * time.Second
+		}
+	case "project":
+		ttl = time.Duration(s.cfg.Scopes.Project.TTLHours) * time.Hour
+	case "global":
+		ttl = time.Duration(s.cfg.Scopes.Global.TTLHours) * time.Hour
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	t := now.Add(ttl)
+	return &t
+}
+
+// upsert applies a write whose external_key matched memory id. Content
+// and summary are replaced; importance and metadata only when given, and
+// the scope only when given, so re-ingesting cannot demote a promoted
+// memory. A memory whose scope has a TTL gets a fresh one.
+func (s *Service) upsert(ctx context.Context, id string, in types.WriteInput, scopeGiven bool, now time.Time) (types.MemoryRecord, error) {
+	var before types.MemoryRecord
+	rec, err := s.store.UpdateMemory(ctx, id, func(rec *types.MemoryRecord) error {
+		before = *rec
+		rec.Content = in.Content
+		rec.Summary = strings.TrimSpace(in.Summary)
+		if rec.Summary == "" {
+			rec.Summary = autoSummary(in.Content)
+		}
+		if in.Importance >= 1 && in.Importance <= 5 {
+			rec.Importance = in.Importance
+		}
+		if in.Metadata != nil {
+			rec.Metadata = in.Metadata
+		}
+		if in.SourceAgent != "" {
+			rec.SourceAgent = in.SourceAgent
+		}
+		if scopeGiven && in.Scope != rec.Scope {
+			if widerScope(in.Scope, rec.Scope) {
+				rec.PromotedAt = &now
+			}
+			rec.Scope = in.Scope
+		}
+		in.Scope = rec.Scope
+		rec.ExpiresAt = s.scopeExpiry(in, now)
+		rec.LastAccessedAt = now
+		return nil
+	})
+	if err != nil {
+		return types.MemoryRecord{}, err
+	}
+	s.rewritten(ctx, before, rec, in.SourceAgent, now)
+	return rec, nil
+}
+
+// rewriteIdentical applies a content-addressed write of content the store
+// already holds as existing. It is a no-op unless existing has expired but
+// not been swept yet, which gives it a fresh TTL, or the write asked for a
+// wider scope, which promotes it.
+func (s *Service) rewriteIdentical(ctx context.Context, existing types.MemoryRecord, in types.WriteInput, scopeGiven bool, now time.Time) (types.MemoryRecord, error) {
+	expired := existing.ExpiresAt != nil && !existing.ExpiresAt.After(now)
+	widen := scopeGiven && widerScope(in.Scope, existing.Scope)
+	if !expired && !widen {
+		return existing, nil
+	}
+	var before types.MemoryRecord
+	rec, err := s.store.UpdateMemory(ctx, existing.ID, func(rec *types.MemoryRecord) error {
+		before = *rec
+		if widen && widerScope(in.Scope, rec.Scope) {
+			rec.Scope = in.Scope
+			rec.PromotedAt = &now
+		}
+		in.Scope = rec.Scope
+		rec.ExpiresAt = s.scopeExpiry(in, now)
+		rec.LastAccessedAt = now
+		return nil
+	})
+	if err != nil {
+		return types.MemoryRecord{}, err
+	}
+	s.rewritten(ctx, before, rec, in.SourceAgent, now)
+	return rec, nil
+}
+
+// rewritten refreshes what derives from a memory a write changed in place,
+// and records the change.
+func (s *Service) rewritten(ctx context.Context, before, rec types.MemoryRecord, agent string, now time.Time) {
+	s.invalidateRecords(before, rec)
+	s.extractFacts(ctx, rec)
+	s.embed(ctx, rec)
+	if widerScope(rec.Scope, before.Scope) {
+		s.recordPromotion(ctx, rec, before.Scope, "", agent, now)
+	}
+	s.recordAudit(ctx, s.auditEntry(ctx, store.AuditUpdate, agent, before, rec))
+}
+
+// Import stores pre-built records in one batch, keeping their timestamps.
+// Missing IDs, summaries and timestamps are filled in as Write would, and
+// records without an expiry get their scope's TTL from CreatedAt. Under
+// content-addressed IDs, content already stored or repeated in recs is
+// stored once.
+func (s *Service) Import(ctx context.Context, recs []types.MemoryRecord) (_ []types.MemoryRecord, err error) {
+	ctx, span := startSpan(ctx, "memory.Import", attribute.Int("memory.count", len(recs)))
+	defer func() { endSpan(span, err) }()
+	if s.cfg.ReadOnly {
+		return nil, ErrReadOnly
+	}
+	out, err := s.prepareImport(recs, 0)
+	if err != nil {
+		return nil, err
+	}
+	var stored []types.MemoryRecord
+	if s.ids.ContentAddressed() {
+		// Content already stored keeps its record, as it would for Write.
+		stored, _, err = s.store.ImportBatch(ctx, out)
+	} else {
+		stored, err = s.store.InsertMemories(ctx, out)
+	}
+	if err != nil {
 		return nil, err
 	}
+	s.imported(ctx, stored)
+	return stored, nil
+}
+
+// prepareImport validates recs and fills in what Write would. Errors
+// number records from first.
+func (s *Service) prepareImport(recs []types.MemoryRecord, first int) ([]types.MemoryRecord, error) {
+	now := s.now()
+	out := make([]types.MemoryRecord, 0, len(recs))
+	generated := map[string]bool{}
+	for i, rec := range recs {
+		if err := s.validateNamespace(rec.Namespace); err != nil {
+			return nil, fmt.Errorf("record %d: %w", first+i, err)
+		}
+		rec.Scope = strings.TrimSpace(strings.ToLower(rec.Scope))
+		if rec.Scope == "" {
+			rec.Scope = "short"
+		}
+		if !validScope(rec.Scope) {
+			return nil, fmt.Errorf("record %d: invalid scope %q", first+i, rec.Scope)
+		}
+		var err error
+		if rec.Content, err = s.cleanText("content", rec.Content); err != nil {
+			return nil, fmt.Errorf("record %d: %w", first+i, err)
+		}
+		if rec.Summary, err = s.cleanText("summary", rec.Summary); err != nil {
+			return nil, fmt.Errorf("record %d: %w", first+i, err)
+		}
+		if strings.TrimSpace(rec.Content) == "" {
+			return nil, fmt.Errorf("record %d: content must not be empty", first+i)
+		}
+		if rec.Importance < 1 || rec.Importance > 5 {
+			rec.Importance = 3
+		}
+		if strings.TrimSpace(rec.Summary) == "" {
+			rec.Summary = autoSummary(rec.Content)
+		}
+		if rec.CreatedAt.IsZero() {
+			rec.CreatedAt = now
+		}
+		if rec.LastAccessedAt.IsZero() {
+			rec.LastAccessedAt = rec.CreatedAt
+		}
+		switch {
+		case rec.Scope == "long":
+			rec.ExpiresAt = nil
+		case rec.ExpiresAt == nil:
+			rec.ExpiresAt = s.scopeExpiry(types.WriteInput{Scope: rec.Scope}, rec.CreatedAt)
+		}
+		if rec.ID == "" {
+			rec.ID = s.ids.New(rec.Namespace, rec.Content, rec.CreatedAt)
+			// Content-addressed IDs repeat for identical content in a
+			// namespace; only the first such record is kept.
+			if generated[rec.ID] {
+				continue
+			}
+			generated[rec.ID] = true
+		}
+		out = append(out, rec)
+	}
+	return out, nil
+}
+
+// imported updates caches, facts and the audit log for stored records.
+func (s *Service) imported(ctx context.Context, stored []types.MemoryRecord) {
+	s.invalidateRecords(stored...)
+	entries := make([]store.AuditEntry, 0, len(stored))
+	for _, rec := range stored {
+		s.extractFacts(ctx, rec)
+		entries = append(entries, s.auditEntry(ctx, store.AuditWrite, rec.SourceAgent, types.MemoryRecord{}, rec))
+	}
+	s.recordAudit(ctx, entries...)
+}
+
+// Search returns ranked memory items and queues them as accessed.
+func (s *Service) Search(ctx context.Context, in types.SearchInput) (_ []types.SearchResult, err error) {
+	ctx, span := startSpan(ctx, "memory.Search", namespaceAttr(in.Namespace))
+	defer func() { endSpan(span, err) }()
+	results, _, err := s.search(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.Record.ID
+	}
+	s.noteAccess(ids...)
+	return results, nil
+}
+
+// search ranks memories for in without counting the results as accessed.
+// It also returns the namespaces it searched.
+func (s *Service) search(ctx context.Context, in types.SearchInput) ([]types.SearchResult, []string, error) {
+	if err := s.validateNamespace(in.Namespace); err != nil {
+		return nil, nil, err
+	}
+	if err := checkNamespace(ctx, in.Namespace, false); err != nil {
+		return nil, nil, err
+	}
 	in.Scope = strings.TrimSpace(strings.ToLower(in.Scope))
-	if in.Scope != "" && in.Scope != "short" && in.Scope != "long" {
-		return nil, fmt.Errorf("invalid scope %q", in.Scope)
+	if in.Scope != "" && !validScope(in.Scope) {
+		return nil, nil, fmt.Errorf("invalid scope %q", in.Scope)
 	}
 	if in.K <= 0 {
 		in.K = s.cfg.DefaultSearchK
@@ -111,25 +588,97 @@ func (s *Service) Search(ctx context.Context, in types.SearchInput) ([]types.Sea
 		in.K = 100
 	}
 
-	now := time.Now().UTC()
-	cands, err := s.store.SearchCandidates(ctx, in.Namespace, in.Query, in.Scope, in.K*3, now)
+	conds, err := store.ParseMetadataFilter(in.Filter)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	now := s.now()
+	levels, err := s.searchLevels(ctx, in, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	shared, err := s.sharedLevels(ctx, in, levels, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	writtenBy := strings.TrimSpace(in.WrittenBy)
+	cands, err := s.levelCandidates(ctx, store.SearchQuery{
+		Namespace:   in.Namespace,
+		Query:       in.Query,
+		Scope:       in.Scope,
+		Limit:       in.K * 3,
+		Now:         now,
+		Metadata:    conds,
+		SourceAgent: writtenBy,
+		StopWords:   s.stopWords,
+		Advanced:    in.AdvancedQuery,
+	}, in.Filter, levels, shared)
+	if err != nil {
+		return nil, nil, err
+	}
+	// An advanced query is an FTS5 expression, not text worth embedding.
+	var sims map[string]float64
+	if !in.AdvancedQuery {
+		sims = s.semanticMatches(ctx, store.VectorQuery{
+			Scope:       in.Scope,
+			Now:         now,
+			SourceAgent: writtenBy,
+			Limit:       in.K * 3,
+		}, sortedLevels(levels), strings.TrimSpace(in.Query))
+	}
+	cands = s.addSemanticOnly(ctx, cands, sims, len(conds) > 0)
+	// The relevance term is lexical, or split with semantic similarity
+	// when the query could be embedded.
+	w := s.cfg.Ranking
+	lexWeight, semWeight := w.LexicalWeight, 0.0
+	if sims != nil {
+		semWeight = w.LexicalWeight * s.cfg.Embeddings.SemanticWeight
+		lexWeight -= semWeight
 	}
 
 	results := make([]types.SearchResult, 0, len(cands))
 	for _, c := range cands {
-		recency := recencyScore(now, c.Record.CreatedAt)
+		recency := recencyScore(now, c.Record.CreatedAt, w.RecencyHalfLifeDays)
 		importance := float64(c.Record.Importance) / 5.0
-		score := (0.60 * c.LexicalScore) + (0.25 * recency) + (0.15 * importance)
-		results = append(results, types.SearchResult{
+		semantic := sims[c.Record.ID]
+		frequency := frequencyScore(c.Record.AccessCount)
+		score := (lexWeight * c.LexicalScore) + (semWeight * semantic) + (w.RecencyWeight * recency) +
+			(w.ImportanceWeight * importance) + (w.FrequencyWeight * frequency)
+		// Memories from other levels of the hierarchy decay per level.
+		var decay float64
+		if d := levels[c.Record.Namespace]; d > 0 {
+			decay = score * (math.Pow(s.cfg.Ranking.NamespaceDecay, float64(d)) - 1)
+			score += decay
+		}
+		res := types.SearchResult{
 			Record:          c.Record,
 			Score:           score,
 			LexicalScore:    c.LexicalScore,
+			SemanticScore:   semantic,
 			RecencyScore:    recency,
 			ImportanceScore: importance,
-		})
+			FrequencyScore:  frequency,
+			Snippet:         c.Snippet,
+			MatchOffsets:    c.MatchOffsets,
+		}
+		if in.Explain {
+			res.Explanation = &types.ScoreExplanation{
+				Lexical:    lexWeight * c.LexicalScore,
+				Semantic:   semWeight * semantic,
+				Recency:    w.RecencyWeight * recency,
+				Importance: w.ImportanceWeight * importance,
+				Frequency:  w.FrequencyWeight * frequency,
+				Namespace:  decay,
+			}
+		}
+		results = append(results, res)
+	}
+	if in.CollapseSuperseded {
+		results = s.collapseSuperseded(ctx, results)
 	}
+	s.applyFeedback(ctx, in.Query, results)
+	s.applyAgentRanking(ctx, in.SourceAgent, results)
 
 	sort.SliceStable(results, func(i, j int) bool {
 		return results[i].Score > results[j].Score
@@ -138,17 +687,29 @@ func (s *Service) Search(ctx context.Context, in types.SearchInput) ([]types.Sea
 	if len(results) > in.K {
 		results = results[:in.K]
 	}
+	if len(results) == 0 {
+		s.recordMiss(ctx, in, now)
+	}
+	s.slideExpiry(ctx, results, now)
 
 	if !in.IncludeMetadata {
 		for i := range results {
 			results[i].Record.Metadata = nil
 		}
 	}
-	return results, nil
+	searched := sortedLevels(levels)
+	for _, l := range shared {
+		searched = append(searched, l.namespace)
+	}
+	return results, searched, nil
 }
 
-// ContextPack builds a compact context block bounded by token budget.
-func (s *Service) ContextPack(ctx context.Context, in types.ContextPackInput) (types.ContextPack, error) {
+// ContextPack builds a compact context block bounded by token budget. An
+// identical request is answered from the pack cache until a write touches
+// a namespace the pack searched or drew a memory from, or its TTL passes.
+func (s *Service) ContextPack(ctx context.Context, in types.ContextPackInput) (_ types.ContextPack, err error) {
+	ctx, span := startSpan(ctx, "memory.ContextPack", namespaceAttr(in.Namespace))
+	defer func() { endSpan(span, err) }()
 	if in.TokenBudget <= 0 {
 		in.TokenBudget = 512
 	}
@@ -158,24 +719,78 @@ func (s *Service) ContextPack(ctx context.Context, in types.ContextPackInput) (t
 	if in.K > 50 {
 		in.K = 50
 	}
+	format, render, err := s.packFormat(in.Format)
+	if err != nil {
+		return types.ContextPack{}, err
+	}
+	if err := s.validateNamespace(in.Namespace); err != nil {
+		return types.ContextPack{}, err
+	}
+	if err := checkNamespace(ctx, in.Namespace, false); err != nil {
+		return types.ContextPack{}, err
+	}
+	key := packKey{
+		namespace:   in.Namespace,
+		query:       in.Query,
+		budget:      in.TokenBudget,
+		scope:       strings.TrimSpace(strings.ToLower(in.Scope)),
+		k:           in.K,
+		sourceAgent: strings.ToLower(strings.TrimSpace(in.SourceAgent)),
+		format:      format,
+		ancestors:   in.IncludeAncestors,
+		descendants: in.IncludeDescendants,
+		sections:    in.Sections,
+		grant:       callerOf(ctx).Grant,
+	}
+	if pack, ok := s.packs.get(key); ok {
+		s.noteAccess(pack.MemoryIDs...)
+		return pack, nil
+	}
+	gen := s.packs.generation()
 
-	results, err := s.Search(ctx, types.SearchInput{
-		Namespace:       in.Namespace,
-		Query:           in.Query,
-		Scope:           in.Scope,
-		K:               in.K,
-		IncludeMetadata: false,
+	results, searched, err := s.search(ctx, types.SearchInput{
+		Namespace:          in.Namespace,
+		Query:              in.Query,
+		Scope:              in.Scope,
+		K:                  in.K,
+		IncludeMetadata:    false,
+		SourceAgent:        in.SourceAgent,
+		IncludeAncestors:   in.IncludeAncestors,
+		IncludeDescendants: in.IncludeDescendants,
 	})
 	if err != nil {
 		return types.ContextPack{}, err
 	}
 
+	// Prefer the newest memory of a supersession chain over the ones it
+	// replaces.
+	recs := make([]types.MemoryRecord, len(results))
+	for i, r := range results {
+		recs[i] = r.Record
+	}
+	stale := s.superseded(ctx, recs)
+
 	seen := map[string]struct{}{}
-	lines := make([]string, 0, len(results))
-	ids := make([]string, 0, len(results))
-	tokens := 0
+	seenIDs := map[string]struct{}{}
+	data := PackData{Namespace: in.Namespace, Query: in.Query}
+	var sections *packSections
+	if in.Sections || s.cfg.ContextPack.Sections.Enabled {
+		sections = newPackSections(s.cfg.ContextPack.Sections, in.TokenBudget, s.now())
+		data = sections.data(data, "", nil)
+	}
+	packText, err := render(data)
+	if err != nil {
+		return types.ContextPack{}, err
+	}
 
 	for _, r := range results {
+		if stale[r.Record.ID] {
+			r.Record = s.chainHead(ctx, r.Record)
+		}
+		if _, ok := seenIDs[r.Record.ID]; ok {
+			continue
+		}
+		seenIDs[r.Record.ID] = struct{}{}
 		text := strings.TrimSpace(r.Record.Summary)
 		if text == "" {
 			text = strings.TrimSpace(r.Record.Content)
@@ -190,49 +805,377 @@ func (s *Service) ContextPack(ctx context.Context, in types.ContextPackInput) (t
 		}
 		seen[norm] = struct{}{}
 
-		line := fmt.Sprintf("- [%s] %s", r.Record.ID, truncate(text, 300))
-		lineTokens := estimateTokens(line)
-		if tokens+lineTokens > in.TokenBudget {
-			break
+		m := PackMemory{
+			ID:          r.Record.ID,
+			Namespace:   r.Record.Namespace,
+			Scope:       r.Record.Scope,
+			Importance:  r.Record.Importance,
+			SourceAgent: r.Record.SourceAgent,
+			CreatedAt:   r.Record.CreatedAt,
+			Text:        truncate(text, 300),
+		}
+		// The budget covers the whole rendered pack, so re-render it with
+		// each memory added; packs hold at most 50.
+		if sections == nil {
+			next := data
+			next.Memories = append(slices.Clip(data.Memories), m)
+			nextText, err := render(next)
+			if err != nil {
+				return types.ContextPack{}, err
+			}
+			if estimateTokens(nextText) > in.TokenBudget {
+				break
+			}
+			data, packText = next, nextText
+			continue
+		}
+		// A full section skips the memory but leaves room for later ones
+		// in other sections.
+		section := sections.sectionOf(r.Record)
+		sectionText, err := render(sections.only(data, section, m))
+		if err != nil {
+			return types.ContextPack{}, err
+		}
+		if estimateTokens(sectionText) > sections.budgets[section] {
+			continue
+		}
+		next := sections.data(data, section, &m)
+		nextText, err := render(next)
+		if err != nil {
+			return types.ContextPack{}, err
 		}
-		tokens += lineTokens
-		lines = append(lines, line)
-		ids = append(ids, r.Record.ID)
+		if estimateTokens(nextText) > in.TokenBudget {
+			continue
+		}
+		sections.memories[section] = append(sections.memories[section], m)
+		data, packText = next, nextText
 	}
 
+	ids := make([]string, len(data.Memories))
+	for i, m := range data.Memories {
+		ids[i] = m.ID
+	}
+	tokens := 0
+	if packText != "" {
+		tokens = estimateTokens(packText)
+	}
 	pack := types.ContextPack{
-		Text:            strings.Join(lines, "\n"),
+		Text:            packText,
+		Format:          format,
 		EstimatedTokens: tokens,
 		MemoryIDs:       ids,
 	}
+	for _, sec := range data.Sections {
+		secIDs := make([]string, len(sec.Memories))
+		for i, m := range sec.Memories {
+			secIDs[i] = m.ID
+		}
+		pack.Sections = append(pack.Sections, types.ContextPackSection{Name: sec.Name, Title: sec.Title, MemoryIDs: secIDs})
+	}
+	for _, m := range data.Memories {
+		if !slices.Contains(searched, m.Namespace) {
+			searched = append(searched, m.Namespace)
+		}
+	}
+	s.packs.put(key, gen, pack, searched)
+	s.noteAccess(ids...)
 	return pack, nil
 }
 
-// Promote moves a memory to long-term scope.
-func (s *Service) Promote(ctx context.Context, in types.PromoteInput) (types.MemoryRecord, error) {
+// Promote moves a memory to a wider scope, long-term by default.
+func (s *Service) Promote(ctx context.Context, in types.PromoteInput) (_ types.MemoryRecord, err error) {
+	ctx, span := startSpan(ctx, "memory.Promote", idAttr(in.MemoryID))
+	defer func() { endSpan(span, err) }()
+	if s.cfg.ReadOnly {
+		return types.MemoryRecord{}, ErrReadOnly
+	}
 	if strings.TrimSpace(in.MemoryID) == "" {
 		return types.MemoryRecord{}, errors.New("memory_id is required")
 	}
+	in.TargetScope = strings.TrimSpace(strings.ToLower(in.TargetScope))
 	if in.TargetScope == "" {
 		in.TargetScope = "long"
 	}
-	if in.TargetScope != "long" {
-		return types.MemoryRecord{}, errors.New("only target_scope=long is supported")
+	if !validScope(in.TargetScope) || in.TargetScope == "short" {
+		return types.MemoryRecord{}, fmt.Errorf("invalid target_scope %q (expected long, project or global)", in.TargetScope)
+	}
+
+	now := s.now()
+	prior, err := s.store.GetMemory(ctx, in.MemoryID)
+	if err == nil {
+		err = checkRecord(ctx, prior, true)
+	}
+	if err == nil {
+		err = s.promote(ctx, prior, in.TargetScope, now)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.MemoryRecord{}, fmt.Errorf("memory %s not found", in.MemoryID)
+		}
+		return types.MemoryRecord{}, err
+	}
+	rec, err := s.store.GetMemory(ctx, in.MemoryID)
+	if err != nil {
+		s.invalidateAll()
+		return types.MemoryRecord{}, err
+	}
+	s.invalidateRecords(prior, rec)
+	s.recordPromotion(ctx, rec, prior.Scope, strings.TrimSpace(in.Reason), in.SourceAgent, now)
+	s.recordAudit(ctx, s.auditEntry(ctx, store.AuditPromote, in.SourceAgent, prior, rec))
+	return rec, nil
+}
+
+// Demote moves a memory of a wider scope back to short-term scope with a
+// fresh TTL, the inverse of Promote.
+func (s *Service) Demote(ctx context.Context, in types.DemoteInput) (_ types.MemoryRecord, err error) {
+	ctx, span := startSpan(ctx, "memory.Demote", idAttr(in.MemoryID))
+	defer func() { endSpan(span, err) }()
+	if s.cfg.ReadOnly {
+		return types.MemoryRecord{}, ErrReadOnly
+	}
+	if strings.TrimSpace(in.MemoryID) == "" {
+		return types.MemoryRecord{}, errors.New("memory_id is required")
+	}
+	if in.TTLSeconds < 0 {
+		return types.MemoryRecord{}, errors.New("ttl_seconds must be >= 0")
 	}
 
-	now := time.Now().UTC()
-	if err := s.store.Promote(ctx, in.MemoryID, now); err != nil {
+	now := s.now()
+	prior, err := s.store.GetMemory(ctx, in.MemoryID)
+	if err == nil {
+		err = checkRecord(ctx, prior, true)
+	}
+	if err == nil && prior.Scope == "short" {
+		err = fmt.Errorf("memory %s is already short-term", in.MemoryID)
+	}
+	if err == nil {
+		err = s.store.Demote(ctx, in.MemoryID, *s.scopeExpiry(types.WriteInput{Scope: "short", TTLSeconds: in.TTLSeconds}, now), now)
+	}
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return types.MemoryRecord{}, fmt.Errorf("memory %s not found", in.MemoryID)
 		}
 		return types.MemoryRecord{}, err
 	}
-	return s.store.GetMemory(ctx, in.MemoryID)
+	rec, err := s.store.GetMemory(ctx, in.MemoryID)
+	if err != nil {
+		s.invalidateAll()
+		return types.MemoryRecord{}, err
+	}
+	s.invalidateRecords(prior, rec)
+	s.recordAudit(ctx, s.auditEntry(ctx, store.AuditDemote, in.SourceAgent, prior, rec))
+	return rec, nil
 }
 
-// ExpireShort triggers TTL cleanup.
-func (s *Service) ExpireShort(ctx context.Context) (int64, error) {
-	return s.store.ExpireShort(ctx, time.Now().UTC())
+// promote moves prior to scope target, which its scope must be allowed
+// to reach through scopes.<target>.promote_from.
+func (s *Service) promote(ctx context.Context, prior types.MemoryRecord, target string, now time.Time) error {
+	if !slices.Contains(s.promotableFrom(target), prior.Scope) {
+		if target == "long" && prior.Scope == "long" {
+			// Promoting to long-term again only refreshes promoted_at.
+			return s.store.Promote(ctx, prior.ID, now)
+		}
+		return fmt.Errorf("cannot promote %s memory %s to %s", prior.Scope, prior.ID, target)
+	}
+	if target == "long" {
+		return s.store.Promote(ctx, prior.ID, now)
+	}
+	_, err := s.store.UpdateMemory(ctx, prior.ID, func(rec *types.MemoryRecord) error {
+		rec.Scope = target
+		rec.PromotedAt = &now
+		rec.ExpiresAt = s.scopeExpiry(types.WriteInput{Scope: target}, now)
+		rec.LastAccessedAt = now
+		return nil
+	})
+	return err
+}
+
+// recordPromotion adds a promotion of rec to the audit trail. Failures are
+// logged and never fail the promotion, which has already happened.
+func (s *Service) recordPromotion(ctx context.Context, rec types.MemoryRecord, priorScope, reason, agent string, now time.Time) {
+	if s.promotions == nil {
+		return
+	}
+	err := s.promotions.RecordPromotion(ctx, store.Promotion{
+		MemoryID:    rec.ID,
+		Namespace:   rec.Namespace,
+		PriorScope:  priorScope,
+		Reason:      reason,
+		SourceAgent: agent,
+		PromotedAt:  now,
+	})
+	if err != nil {
+		s.logger.Warn("recording promotion failed", "memory_id", rec.ID, "error", err)
+	}
+}
+
+// Append adds an entry to an existing memory's content, stamped with the
+// current time unless OmitTimestamp is set, and refreshes its summary.
+func (s *Service) Append(ctx context.Context, in types.AppendInput) (_ types.MemoryRecord, err error) {
+	ctx, span := startSpan(ctx, "memory.Append", idAttr(in.MemoryID))
+	defer func() { endSpan(span, err) }()
+	if s.cfg.ReadOnly {
+		return types.MemoryRecord{}, ErrReadOnly
+	}
+	if strings.TrimSpace(in.MemoryID) == "" {
+		return types.MemoryRecord{}, errors.New("memory_id is required")
+	}
+	if in.Content, err = s.cleanText("content", in.Content); err != nil {
+		return types.MemoryRecord{}, err
+	}
+	if in.Summary, err = s.cleanText("summary", in.Summary); err != nil {
+		return types.MemoryRecord{}, err
+	}
+	if in.Delimiter, err = s.cleanText("delimiter", in.Delimiter); err != nil {
+		return types.MemoryRecord{}, err
+	}
+	entry := strings.TrimSpace(in.Content)
+	if entry == "" {
+		return types.MemoryRecord{}, errors.New("content must not be empty")
+	}
+	delim := in.Delimiter
+	if delim == "" {
+		delim = "\n\n"
+	}
+
+	now := s.now()
+	if !in.OmitTimestamp {
+		entry = "[" + now.UTC().Format(time.RFC3339) + "] " + entry
+	}
+	var before types.MemoryRecord
+	rec, err := s.store.UpdateMemory(ctx, in.MemoryID, func(rec *types.MemoryRecord) error {
+		if err := checkRecord(ctx, *rec, true); err != nil {
+			return err
+		}
+		before = *rec
+		// A summary that still matches the derived one is refreshed; one
+		// written by hand is kept.
+		derived := rec.Summary == autoSummary(rec.Content)
+		rec.Content = strings.TrimRight(rec.Content, "\n") + delim + entry
+		switch {
+		case strings.TrimSpace(in.Summary) != "":
+			rec.Summary = strings.TrimSpace(in.Summary)
+		case derived:
+			rec.Summary = autoSummary(rec.Content)
+		}
+		rec.LastAccessedAt = now
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return types.MemoryRecord{}, fmt.Errorf("memory %s not found", in.MemoryID)
+		}
+		return types.MemoryRecord{}, err
+	}
+	s.invalidateRecords(before, rec)
+	s.extractFacts(ctx, rec)
+	s.embed(ctx, rec)
+	s.recordAudit(ctx, s.auditEntry(ctx, store.AuditUpdate, "", before, rec))
+	return rec, nil
+}
+
+// Delete removes memories by ID. Dry runs report the matching records without
+// deleting anything and are allowed in read-only mode.
+func (s *Service) Delete(ctx context.Context, in types.DeleteInput) (_ types.DeleteResult, err error) {
+	ctx, span := startSpan(ctx, "memory.Delete", attribute.StringSlice("memory.ids", in.MemoryIDs))
+	defer func() { endSpan(span, err) }()
+	if len(in.MemoryIDs) == 0 {
+		return types.DeleteResult{}, errors.New("memory_ids is required")
+	}
+	if s.cfg.ReadOnly && !in.DryRun {
+		return types.DeleteResult{}, ErrReadOnly
+	}
+
+	res := types.DeleteResult{DryRun: in.DryRun, Memories: []types.MemoryRecord{}}
+	ids := make([]string, 0, len(in.MemoryIDs))
+	seen := map[string]struct{}{}
+	for _, id := range in.MemoryIDs {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		rec, err := s.store.GetMemory(ctx, id)
+		if err == nil {
+			err = checkRecord(ctx, rec, !in.DryRun)
+		}
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				res.Missing = append(res.Missing, id)
+				continue
+			}
+			return types.DeleteResult{}, err
+		}
+		ids = append(ids, id)
+		res.Memories = append(res.Memories, rec)
+	}
+
+	if in.DryRun {
+		res.Count = int64(len(ids))
+		return res, nil
+	}
+	n, err := s.store.DeleteMemories(ctx, ids)
+	s.invalidate(namespacesOf(res.Memories)...)
+	if err != nil {
+		return types.DeleteResult{}, err
+	}
+	s.auditDeleted(ctx, store.AuditDelete, res.Memories)
+	res.Count = n
+	return res, nil
+}
+
+// ExpireShort triggers TTL cleanup: expired short-term memories and
+// project or global ones past their scope's ttl_hours, then long-term
+// ones past long_term_retention. It is a no-op in read-only mode.
+func (s *Service) ExpireShort(ctx context.Context) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "memory.ExpireShort")
+	defer func() { endSpan(span, err) }()
+	if s.cfg.ReadOnly {
+		return 0, nil
+	}
+	now := s.now()
+	if callerOf(ctx).Tool == "" {
+		ctx = WithCaller(ctx, Caller{Tool: "ttl"})
+	}
+	var expired []types.MemoryRecord
+	if s.audit != nil {
+		for _, scope := range []string{"short", "project", "global"} {
+			recs, err := s.store.ListMemories(ctx, store.ListFilter{Scope: scope, ExpiredOnly: true, Now: now})
+			if err != nil {
+				return 0, err
+			}
+			expired = append(expired, recs...)
+		}
+	}
+	n, err := s.store.ExpireShort(ctx, now)
+	if n > 0 {
+		s.invalidateAll()
+		s.auditDeleted(ctx, store.AuditExpire, expired)
+	}
+	if err != nil || len(s.retention) == 0 {
+		return n, err
+	}
+	long, err := s.expireLong(ctx, now)
+	return n + long, err
+}
+
+func namespacesOf(recs []types.MemoryRecord) []string {
+	seen := map[string]struct{}{}
+	out := make([]string, 0, len(recs))
+	for _, rec := range recs {
+		if _, ok := seen[rec.Namespace]; !ok {
+			seen[rec.Namespace] = struct{}{}
+			out = append(out, rec.Namespace)
+		}
+	}
+	return out
+}
+
+func (s *Service) now() time.Time {
+	return s.clock.Now().UTC()
 }
 
 func (s *Service) validateNamespace(namespace string) error {
@@ -265,18 +1208,71 @@ func truncate(s string, limit int) string {
 	return string(r[:limit-3]) + "..."
 }
 
+// Suggest proposes up to search_suggestions corrected queries for a search
+// that found nothing, using words the namespace already has. It returns
+// none when the store cannot suggest or every word is known.
+func (s *Service) Suggest(ctx context.Context, in types.SearchInput) ([]string, error) {
+	if s.suggester == nil || strings.TrimSpace(in.Query) == "" || in.AdvancedQuery {
+		return nil, nil
+	}
+	if err := s.validateNamespace(in.Namespace); err != nil {
+		return nil, err
+	}
+	if err := checkNamespace(ctx, in.Namespace, false); err != nil {
+		return nil, err
+	}
+	return s.suggester.SuggestQueries(ctx, store.SearchQuery{
+		Namespace: in.Namespace,
+		Query:     in.Query,
+		StopWords: s.stopWords,
+	}, s.cfg.SearchSuggestions)
+}
+
+// recordMiss counts a search that found nothing. Filtered and advanced
+// searches are skipped: their misses say more about the filter or the
+// expression than the query. Read-only mode records nothing.
+func (s *Service) recordMiss(ctx context.Context, in types.SearchInput, now time.Time) {
+	query := normalize(in.Query)
+	if s.cfg.ReadOnly || s.misses == nil || query == "" || in.Filter != "" || in.AdvancedQuery {
+		return
+	}
+	err := s.misses.RecordSearchMiss(ctx, store.SearchMiss{
+		Namespace:   in.Namespace,
+		Query:       query,
+		SourceAgent: in.SourceAgent,
+		LastSeen:    now,
+	})
+	if err != nil {
+		s.logger.Warn("record search miss failed", "namespace", in.Namespace, "error", err)
+	}
+}
+
 func normalize(s string) string {
 	s = strings.ToLower(strings.TrimSpace(s))
 	s = strings.Join(strings.Fields(s), " ")
 	return truncate(s, 180)
 }
 
-func recencyScore(now, t time.Time) float64 {
+// recencyScore halves for every halfLifeDays since t.
+func recencyScore(now, t time.Time, halfLifeDays float64) float64 {
 	days := now.Sub(t).Hours() / 24.0
 	if days <= 0 {
 		return 1.0
 	}
-	return math.Exp(-days / 14.0)
+	return math.Exp2(-days / halfLifeDays)
+}
+
+// frequencyHalfCount is the access count that earns half the frequency
+// term.
+const frequencyHalfCount = 5
+
+// frequencyScore maps an access count to [0, 1), rising quickly for the
+// first retrievals and saturating for memories used all the time.
+func frequencyScore(accesses int) float64 {
+	if accesses <= 0 {
+		return 0
+	}
+	return float64(accesses) / float64(accesses+frequencyHalfCount)
 }
 
 // EstimateTokens is a rough approximation for prompt budgeting.
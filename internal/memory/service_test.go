@@ -2,12 +2,21 @@ package memory
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"io"
+	"math"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/charmbracelet/log"
 
+	"github.com/xiy/memory-mcp/internal/clock"
 	"github.com/xiy/memory-mcp/internal/config"
 	"github.com/xiy/memory-mcp/internal/store"
 	"github.com/xiy/memory-mcp/pkg/types"
@@ -16,16 +25,36 @@ import (
 type fakeStore struct {
 	inserted []types.MemoryRecord
 	search   []store.Candidate
+	deleted  []string
 }
 
 func (f *fakeStore) InsertMemory(_ context.Context, rec types.MemoryRecord) (types.MemoryRecord, error) {
 	f.inserted = append(f.inserted, rec)
 	return rec, nil
 }
-func (f *fakeStore) SearchCandidates(_ context.Context, _, _, _ string, _ int, _ time.Time) ([]store.Candidate, error) {
+func (f *fakeStore) InsertMemories(_ context.Context, recs []types.MemoryRecord) ([]types.MemoryRecord, error) {
+	f.inserted = append(f.inserted, recs...)
+	return recs, nil
+}
+func (f *fakeStore) SearchCandidates(_ context.Context, _ store.SearchQuery) ([]store.Candidate, error) {
 	return f.search, nil
 }
-func (f *fakeStore) Promote(_ context.Context, _ string, _ time.Time) error    { return nil }
+func (f *fakeStore) Promote(_ context.Context, _ string, _ time.Time) error   { return nil }
+func (f *fakeStore) Demote(_ context.Context, _ string, _, _ time.Time) error { return nil }
+func (f *fakeStore) UpdateMemory(_ context.Context, id string, fn func(*types.MemoryRecord) error) (types.MemoryRecord, error) {
+	for i := range f.inserted {
+		if f.inserted[i].ID != id {
+			continue
+		}
+		rec := f.inserted[i]
+		if err := fn(&rec); err != nil {
+			return types.MemoryRecord{}, err
+		}
+		f.inserted[i] = rec
+		return rec, nil
+	}
+	return types.MemoryRecord{}, sql.ErrNoRows
+}
 func (f *fakeStore) ExpireShort(_ context.Context, _ time.Time) (int64, error) { return 0, nil }
 func (f *fakeStore) Stats(_ context.Context, _ time.Time) (store.Stats, error) {
 	return store.Stats{}, nil
@@ -36,6 +65,31 @@ func (f *fakeStore) GetMemory(_ context.Context, _ string) (types.MemoryRecord,
 	}
 	return f.inserted[0], nil
 }
+func (f *fakeStore) ListMemories(_ context.Context, _ store.ListFilter) ([]types.MemoryRecord, error) {
+	return nil, nil
+}
+func (f *fakeStore) CountBy(_ context.Context, _ string, _ store.ListFilter) ([]store.GroupCount, error) {
+	return nil, nil
+}
+func (f *fakeStore) DeleteMemories(_ context.Context, ids []string) (int64, error) {
+	f.deleted = append(f.deleted, ids...)
+	return int64(len(ids)), nil
+}
+func (f *fakeStore) DeleteByFilter(_ context.Context, _ store.ListFilter) (int64, error) {
+	return 0, nil
+}
+func (f *fakeStore) ExportAll(_ context.Context, fn func(types.MemoryRecord) error) error {
+	for _, rec := range f.inserted {
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (f *fakeStore) ImportBatch(_ context.Context, recs []types.MemoryRecord) ([]types.MemoryRecord, []string, error) {
+	f.inserted = append(f.inserted, recs...)
+	return recs, nil, nil
+}
 func (f *fakeStore) Close() error { return nil }
 
 func TestWrite_ValidatesNamespace(t *testing.T) {
@@ -83,3 +137,1541 @@ func TestContextPack_RespectsTokenBudget(t *testing.T) {
 		t.Fatalf("expected token budget <= 5, got %d", pack.EstimatedTokens)
 	}
 }
+
+func TestContextPack_Formats(t *testing.T) {
+	t.Parallel()
+	now := time.Now().UTC()
+	st := &fakeStore{search: []store.Candidate{
+		{Record: types.MemoryRecord{ID: "a", Namespace: "org/repo", Scope: "long", Summary: "use <pnpm> & not npm", CreatedAt: now, Importance: 5}, LexicalScore: 0.9},
+	}}
+	cfg := config.Default()
+	cfg.ContextPack.Templates = map[string]string{"claude": `{{range .Memories}}<note id="{{.ID}}">{{xml .Text}}</note>{{end}}`}
+	svc, err := NewService(st, cfg, log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	for format, want := range map[string]string{
+		"":       "- [a] use <pnpm> & not npm",
+		"plain":  "use <pnpm> & not npm",
+		"json":   `[{"id":"a","namespace":"org/repo","scope":"long","importance":5,"created_at":"` + now.Format(time.RFC3339Nano) + `","text":"use \u003cpnpm\u003e \u0026 not npm"}]`,
+		"XML":    "<memories namespace=\"org/repo\">\n  <memory id=\"a\" namespace=\"org/repo\" scope=\"long\" importance=\"5\">use &lt;pnpm&gt; &amp; not npm</memory>\n</memories>",
+		"claude": `<note id="a">use &lt;pnpm&gt; &amp; not npm</note>`,
+	} {
+		pack, err := svc.ContextPack(context.Background(), types.ContextPackInput{Namespace: "org/repo", Query: "pnpm", TokenBudget: 200, Format: format})
+		if err != nil {
+			t.Fatalf("ContextPack(%q) error = %v", format, err)
+		}
+		if pack.Text != want || len(pack.MemoryIDs) != 1 || pack.EstimatedTokens != estimateTokens(want) {
+			t.Fatalf("ContextPack(%q) = %+v, want text %q", format, pack, want)
+		}
+	}
+	if _, err := svc.ContextPack(context.Background(), types.ContextPackInput{Namespace: "org/repo", Query: "pnpm", TokenBudget: 200, Format: "yaml"}); err == nil || !strings.Contains(err.Error(), "claude") {
+		t.Fatalf("ContextPack(yaml) error = %v, want the known formats listed", err)
+	}
+	pack, err := svc.ContextPack(context.Background(), types.ContextPackInput{Namespace: "org/repo", Query: "pnpm", TokenBudget: 20, Format: "xml"})
+	if err != nil || len(pack.MemoryIDs) != 0 || pack.EstimatedTokens > 20 {
+		t.Fatalf("ContextPack(xml, 20 tokens) = %+v, %v, want the memory left out by the wrapper's cost", pack, err)
+	}
+}
+
+func TestReadOnly_RejectsMutations(t *testing.T) {
+	t.Parallel()
+	cfg := config.Default()
+	cfg.ReadOnly = true
+	st := &fakeStore{}
+	svc, err := NewService(st, cfg, log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	_, err = svc.Write(context.Background(), types.WriteInput{Namespace: "org/repo/task", Content: "hello"})
+	if !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly from Write, got %v", err)
+	}
+	_, err = svc.Promote(context.Background(), types.PromoteInput{MemoryID: "m-1"})
+	if !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly from Promote, got %v", err)
+	}
+	_, err = svc.Append(context.Background(), types.AppendInput{MemoryID: "m-1", Content: "more"})
+	if !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly from Append, got %v", err)
+	}
+	if len(st.inserted) != 0 {
+		t.Fatalf("expected no inserts in read-only mode, got %d", len(st.inserted))
+	}
+}
+
+func TestDelete_DryRunLeavesStoreUntouched(t *testing.T) {
+	t.Parallel()
+	st := &fakeStore{inserted: []types.MemoryRecord{{ID: "m-1", Namespace: "org/repo/task"}}}
+	svc, err := NewService(st, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	res, err := svc.Delete(context.Background(), types.DeleteInput{MemoryIDs: []string{"m-1"}, DryRun: true})
+	if err != nil {
+		t.Fatalf("Delete(dry run) error = %v", err)
+	}
+	if !res.DryRun || res.Count != 1 || len(res.Memories) != 1 {
+		t.Fatalf("unexpected dry-run result %+v", res)
+	}
+	if len(st.deleted) != 0 {
+		t.Fatalf("expected no deletes during dry run, got %v", st.deleted)
+	}
+
+	res, err = svc.Delete(context.Background(), types.DeleteInput{MemoryIDs: []string{"m-1"}})
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if res.DryRun || res.Count != 1 || len(st.deleted) != 1 {
+		t.Fatalf("expected one deletion, got result %+v deleted %v", res, st.deleted)
+	}
+}
+
+func TestWrite_UsesInjectedClockForTTL(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 2, 17, 10, 0, 0, 0, time.UTC)
+	clk := clock.NewManual(now)
+	st := &fakeStore{}
+	cfg := config.Default()
+	svc, err := NewService(st, cfg, log.NewWithOptions(io.Discard, log.Options{}), WithClock(clk))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	rec, err := svc.Write(context.Background(), types.WriteInput{Namespace: "org/repo/task", Content: "note"})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if !rec.CreatedAt.Equal(now) {
+		t.Fatalf("expected created_at %s, got %s", now, rec.CreatedAt)
+	}
+	want := now.Add(time.Duration(cfg.DefaultShortTTLHours) * time.Hour)
+	if rec.ExpiresAt == nil || !rec.ExpiresAt.Equal(want) {
+		t.Fatalf("expected expires_at %s, got %v", want, rec.ExpiresAt)
+	}
+}
+
+func TestImport_KeepsTimestampsAndFillsDefaults(t *testing.T) {
+	t.Parallel()
+	st := &fakeStore{}
+	cfg := config.Default()
+	svc, err := NewService(st, cfg, log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	created := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	recs, err := svc.Import(context.Background(), []types.MemoryRecord{
+		{Namespace: "org/repo/task", Content: "old short note", CreatedAt: created},
+		{Namespace: "org/repo/task", Scope: "long", Content: "old decision", Importance: 9, CreatedAt: created},
+	})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(st.inserted) != 2 || recs[0].ID == "" || recs[0].ID == recs[1].ID {
+		t.Fatalf("expected two records with fresh IDs, got %+v", recs)
+	}
+	wantExpiry := created.Add(time.Duration(cfg.DefaultShortTTLHours) * time.Hour)
+	if recs[0].ExpiresAt == nil || !recs[0].ExpiresAt.Equal(wantExpiry) {
+		t.Fatalf("expected TTL from created_at, got %v", recs[0].ExpiresAt)
+	}
+	if !recs[1].CreatedAt.Equal(created) || recs[1].ExpiresAt != nil || recs[1].Importance != 3 {
+		t.Fatalf("unexpected long-term record %+v", recs[1])
+	}
+
+	if _, err := svc.Import(context.Background(), []types.MemoryRecord{{Namespace: "bad", Content: "x"}}); err == nil {
+		t.Fatal("expected invalid namespace to fail the import")
+	}
+}
+
+type countingStore struct {
+	fakeStore
+	searches int
+}
+
+func (c *countingStore) SearchCandidates(ctx context.Context, q store.SearchQuery) ([]store.Candidate, error) {
+	c.searches++
+	return c.fakeStore.SearchCandidates(ctx, q)
+}
+
+func TestSearch_CachesUntilNamespaceWrite(t *testing.T) {
+	t.Parallel()
+	st := &countingStore{}
+	svc, err := NewService(st, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	ctx := context.Background()
+	in := types.SearchInput{Namespace: "org/repo/task", Query: "deploy"}
+
+	for i := 0; i < 3; i++ {
+		if _, err := svc.Search(ctx, in); err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+	}
+	if st.searches != 1 {
+		t.Fatalf("expected repeated searches to hit the cache, store saw %d", st.searches)
+	}
+
+	if _, err := svc.Write(ctx, types.WriteInput{Namespace: "org/other/task", Content: "unrelated"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := svc.Search(ctx, in); err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if st.searches != 1 {
+		t.Fatalf("expected write to another namespace to keep the cache, store saw %d", st.searches)
+	}
+
+	if _, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "deploy notes"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := svc.Search(ctx, in); err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if st.searches != 2 {
+		t.Fatalf("expected write to the namespace to invalidate, store saw %d", st.searches)
+	}
+	if stats := svc.SearchCacheStats(); stats.Hits != 3 || stats.Misses != 2 {
+		t.Fatalf("unexpected cache stats %+v", stats)
+	}
+}
+
+func TestContextPack_CachesUntilNamespaceWrite(t *testing.T) {
+	t.Parallel()
+	st := &countingStore{}
+	cfg := config.Default()
+	cfg.Cache.SearchEntries = 0
+	svc, err := NewService(st, cfg, log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	ctx := context.Background()
+	if _, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "deploy notes"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	in := types.ContextPackInput{Namespace: "org/repo/task", Query: "deploy", TokenBudget: 256}
+
+	first, err := svc.ContextPack(ctx, in)
+	if err != nil {
+		t.Fatalf("ContextPack() error = %v", err)
+	}
+	again, err := svc.ContextPack(ctx, in)
+	if err != nil || again.Text != first.Text || st.searches != 1 {
+		t.Fatalf("expected the repeated pack from the cache, store saw %d searches, err %v", st.searches, err)
+	}
+
+	in.TokenBudget = 128
+	if _, err := svc.ContextPack(ctx, in); err != nil {
+		t.Fatalf("ContextPack() error = %v", err)
+	}
+	if st.searches != 2 {
+		t.Fatalf("expected another budget to build a new pack, store saw %d", st.searches)
+	}
+
+	if _, err := svc.Write(ctx, types.WriteInput{Namespace: "org/other/task", Content: "unrelated"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := svc.ContextPack(ctx, in); err != nil {
+		t.Fatalf("ContextPack() error = %v", err)
+	}
+	if st.searches != 2 {
+		t.Fatalf("expected write to another namespace to keep the pack, store saw %d", st.searches)
+	}
+
+	if _, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "deploy window is 9-11"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := svc.ContextPack(ctx, in); err != nil {
+		t.Fatalf("ContextPack() error = %v", err)
+	}
+	if st.searches != 3 {
+		t.Fatalf("expected write to the namespace to invalidate, store saw %d", st.searches)
+	}
+	if stats := svc.PackCacheStats(); stats.Hits != 2 || stats.Misses != 3 {
+		t.Fatalf("unexpected pack cache stats %+v", stats)
+	}
+}
+
+func TestContextPack_SharedScopeWriteDropsEveryPack(t *testing.T) {
+	t.Parallel()
+	st := &countingStore{}
+	cfg := config.Default()
+	cfg.Cache.SearchEntries = 0
+	svc, err := NewService(st, cfg, log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	ctx := context.Background()
+	in := types.ContextPackInput{Namespace: "org/repo/task", Query: "deploy", TokenBudget: 256}
+	pack := func() {
+		t.Helper()
+		if _, err := svc.ContextPack(ctx, in); err != nil {
+			t.Fatalf("ContextPack() error = %v", err)
+		}
+	}
+
+	pack()
+	searches := st.searches
+	if _, err := svc.Write(ctx, types.WriteInput{Namespace: "acme/handbook", Scope: "global", Content: "deploy freezes start friday"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	pack()
+	if st.searches == searches {
+		t.Fatal("expected a global memory written elsewhere to drop the cached pack")
+	}
+
+	searches = st.searches
+	pack()
+	if _, err := svc.Write(ctx, types.WriteInput{Namespace: "acme/handbook", Scope: "long", Content: "deploy notes"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	pack()
+	if st.searches != searches {
+		t.Fatalf("expected a long-term memory elsewhere to keep the cached pack, store saw %d more searches", st.searches-searches)
+	}
+}
+
+func TestContextPack_DescendantPackDropsOnWriteBelowIt(t *testing.T) {
+	t.Parallel()
+	st := &countingStore{}
+	cfg := config.Default()
+	cfg.Cache.SearchEntries = 0
+	svc, err := NewService(st, cfg, log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	ctx := context.Background()
+	in := types.ContextPackInput{Namespace: "org/repo", Query: "deploy", TokenBudget: 256, IncludeDescendants: true}
+	pack := func() {
+		t.Helper()
+		if _, err := svc.ContextPack(ctx, in); err != nil {
+			t.Fatalf("ContextPack() error = %v", err)
+		}
+	}
+
+	pack()
+	searches := st.searches
+	if _, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repository", Content: "deploy notes"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	pack()
+	if st.searches != searches {
+		t.Fatalf("expected a write to a sibling namespace to keep the pack, store saw %d more searches", st.searches-searches)
+	}
+	if _, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/new-task", Content: "deploy window is 9-11"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	pack()
+	if st.searches == searches {
+		t.Fatal("expected a write to a new namespace below the pack's to drop it")
+	}
+}
+
+func TestSearch_ParsesMetadataFilterIntoQuery(t *testing.T) {
+	t.Parallel()
+	st := &recordingStore{}
+	svc, err := NewService(st, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	ctx := context.Background()
+
+	in := types.SearchInput{Namespace: "org/repo/task", Query: "deploy", Filter: `metadata.priority >= 2`}
+	if _, err := svc.Search(ctx, in); err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(st.last.Metadata) != 1 || st.last.Metadata[0].Op != store.OpGe {
+		t.Fatalf("expected parsed filter in store query, got %+v", st.last.Metadata)
+	}
+
+	in.Filter = `metadata.priority >= 3`
+	if _, err := svc.Search(ctx, in); err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if st.searches != 2 {
+		t.Fatalf("expected a different filter to miss the cache, store saw %d", st.searches)
+	}
+
+	in.Filter = `priority >= 2`
+	if _, err := svc.Search(ctx, in); err == nil {
+		t.Fatal("expected malformed filter to fail")
+	}
+}
+
+type recordingStore struct {
+	fakeStore
+	searches int
+	last     store.SearchQuery
+}
+
+func (r *recordingStore) SearchCandidates(ctx context.Context, q store.SearchQuery) ([]store.Candidate, error) {
+	r.searches++
+	r.last = q
+	return r.fakeStore.SearchCandidates(ctx, q)
+}
+
+func TestAppend_StampsEntryAndRefreshesDerivedSummary(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	st := &fakeStore{inserted: []types.MemoryRecord{
+		{ID: "m-log", Namespace: "org/repo/task", Scope: "short", Content: "debugging timeline", Summary: "debugging timeline"},
+		{ID: "m-hand", Namespace: "org/repo/task", Scope: "long", Content: "notes", Summary: "hand written"},
+	}}
+	svc, err := NewService(st, config.Default(), log.NewWithOptions(io.Discard, log.Options{}), WithClock(clock.NewManual(now)))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	ctx := context.Background()
+
+	rec, err := svc.Append(ctx, types.AppendInput{MemoryID: "m-log", Content: "reproduced with race detector"})
+	if err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	want := "debugging timeline\n\n[2026-03-01T09:30:00Z] reproduced with race detector"
+	if rec.Content != want || rec.Summary != autoSummary(want) || !rec.LastAccessedAt.Equal(now) {
+		t.Fatalf("unexpected appended record %+v", rec)
+	}
+
+	rec, err = svc.Append(ctx, types.AppendInput{MemoryID: "m-hand", Content: "- item", Delimiter: "\n", OmitTimestamp: true})
+	if err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if rec.Content != "notes\n- item" || rec.Summary != "hand written" {
+		t.Fatalf("expected hand-written summary to survive, got %+v", rec)
+	}
+
+	if _, err := svc.Append(ctx, types.AppendInput{MemoryID: "missing", Content: "x"}); err == nil {
+		t.Fatal("expected append to a missing memory to fail")
+	}
+	if _, err := svc.Append(ctx, types.AppendInput{MemoryID: "m-log", Content: "  "}); err == nil {
+		t.Fatal("expected empty content to fail")
+	}
+}
+
+func TestWrite_SanitizesContent(t *testing.T) {
+	t.Parallel()
+	svc, err := NewService(&fakeStore{}, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	rec, err := svc.Write(context.Background(), types.WriteInput{
+		Namespace: "org/repo/task",
+		Content:   "build\x00 log\r\nexit \x1b[31mred\x1b[0m \xff\xfe done\u0085\ttab",
+		Summary:   "line one\rline two\x07",
+	})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if want := "build log\nexit [31mred[0m \uFFFD\uFFFD done\ttab"; rec.Content != want {
+		t.Fatalf("Content = %q, want %q", rec.Content, want)
+	}
+	if rec.Summary != "line one\nline two" {
+		t.Fatalf("Summary = %q", rec.Summary)
+	}
+	if _, err := svc.Write(context.Background(), types.WriteInput{Namespace: "org/repo/task", Content: "\x00\x01"}); err == nil {
+		t.Fatal("expected content of only control characters to be rejected as empty")
+	}
+}
+
+func TestWrite_RejectsUncleanContentInRejectMode(t *testing.T) {
+	t.Parallel()
+	cfg := config.Default()
+	cfg.ContentSanitization = "reject"
+	svc, err := NewService(&fakeStore{}, cfg, log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	ctx := context.Background()
+	_, err = svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "ok \xc3("})
+	if err == nil || !strings.Contains(err.Error(), "invalid UTF-8 at byte 3") {
+		t.Fatalf("Write() error = %v, want invalid UTF-8 at byte 3", err)
+	}
+	_, err = svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "bell\a"})
+	if err == nil || !strings.Contains(err.Error(), "U+0007") {
+		t.Fatalf("Write() error = %v, want control character U+0007", err)
+	}
+	rec, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "crlf\r\nis fine"})
+	if err != nil || rec.Content != "crlf\nis fine" {
+		t.Fatalf("Write() = %q, %v", rec.Content, err)
+	}
+}
+
+func TestSearch_DropsStopWords(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	for _, opts := range [][]store.Option{nil, {store.WithoutFTS()}} {
+		st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "stop.db"), logger, opts...)
+		if err != nil {
+			t.Fatalf("OpenSQLite() error = %v", err)
+		}
+		cfg := config.Default()
+		cfg.StopWords = []string{"procedure"}
+		svc, err := NewService(st, cfg, logger)
+		if err != nil {
+			t.Fatalf("NewService() error = %v", err)
+		}
+		rec, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "Deployment process: tag, build, canary, promote."})
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		res, err := svc.Search(ctx, types.SearchInput{Namespace: "org/repo/task", Query: "What is the deployment process procedure?"})
+		if err != nil || len(res) != 1 || res[0].Record.ID != rec.ID {
+			t.Fatalf("Search() = %+v, %v, want %s", res, err, rec.ID)
+		}
+		st.Close()
+	}
+}
+
+func TestSearch_RecordsMisses(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "misses.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	clk := clock.NewManual(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	svc, err := NewService(st, config.Default(), logger, WithClock(clk))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	if _, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "deploy checklist"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	search := func(ns, query, filter string) {
+		t.Helper()
+		if _, err := svc.Search(ctx, types.SearchInput{Namespace: ns, Query: query, Filter: filter, SourceAgent: "codex"}); err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+	}
+	search("org/repo/task", "deploy checklist", "")
+	search("org/repo/task", "Rollback  Plan", "")
+	clk.Advance(time.Minute)
+	search("org/repo/task", "rollback plan", "")
+	search("org/repo/task", "oncall", `metadata.team = "infra"`)
+	search("org/repo/other", "oncall", "")
+
+	misses, err := st.SearchMisses(ctx, "org/repo/task", 10)
+	if err != nil {
+		t.Fatalf("SearchMisses() error = %v", err)
+	}
+	if len(misses) != 1 {
+		t.Fatalf("SearchMisses() = %+v, want only the unfiltered miss", misses)
+	}
+	m := misses[0]
+	if m.Query != "rollback plan" || m.Count != 2 || m.SourceAgent != "codex" || !m.LastSeen.Equal(clk.Now()) || !m.FirstSeen.Before(m.LastSeen) {
+		t.Fatalf("SearchMisses()[0] = %+v", m)
+	}
+	if all, err := st.SearchMisses(ctx, "", 10); err != nil || len(all) != 2 {
+		t.Fatalf("SearchMisses(all) = %+v, %v, want 2", all, err)
+	}
+}
+
+func TestSearch_ReadOnlyRecordsNoMisses(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "misses.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	cfg := config.Default()
+	cfg.ReadOnly = true
+	svc, err := NewService(st, cfg, logger)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	if res, err := svc.Search(ctx, types.SearchInput{Namespace: "org/repo/task", Query: "rollback plan"}); err != nil || len(res) != 0 {
+		t.Fatalf("Search() = %+v, %v, want no results", res, err)
+	}
+	if misses, err := st.SearchMisses(ctx, "", 10); err != nil || len(misses) != 0 {
+		t.Fatalf("SearchMisses() = %+v, %v, want nothing recorded in read-only mode", misses, err)
+	}
+}
+
+func TestWrite_ContentAddressedRevivesAndWidens(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "content.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	cfg := config.Default()
+	cfg.IDFormat = "content"
+	clk := clock.NewManual(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	svc, err := NewService(st, cfg, logger, WithClock(clk))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	write := func(content, scope string) types.MemoryRecord {
+		t.Helper()
+		rec, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo", Content: content, Scope: scope})
+		if err != nil {
+			t.Fatalf("Write(%q, %q) error = %v", content, scope, err)
+		}
+		return rec
+	}
+
+	first := write("deploy checklist", "short")
+	if again := write("deploy checklist", ""); again.ID != first.ID || again.Scope != "short" {
+		t.Fatalf("re-write without a scope = %+v, want the stored short-term record", again)
+	}
+	if long := write("deploy checklist", "long"); long.ID != first.ID || long.Scope != "long" || long.ExpiresAt != nil {
+		t.Fatalf("re-write as long = %+v, want the record promoted to long-term", long)
+	}
+
+	scratch := write("rollback scratch note", "short")
+	clk.Advance(time.Duration(cfg.DefaultShortTTLHours+1) * time.Hour)
+	revived := write("rollback scratch note", "short")
+	if revived.ID != scratch.ID || revived.ExpiresAt == nil || !revived.ExpiresAt.After(clk.Now()) {
+		t.Fatalf("re-write of an expired memory = %+v, want a fresh expiry", revived)
+	}
+	if res, err := svc.Search(ctx, types.SearchInput{Namespace: "org/repo", Query: "rollback"}); err != nil || len(res) != 1 {
+		t.Fatalf("Search() = %+v, %v, want the revived memory", res, err)
+	}
+
+	batch := []types.MemoryRecord{
+		{Namespace: "org/repo", Content: "imported twice"},
+		{Namespace: "org/repo", Content: "imported twice"},
+		{Namespace: "org/repo", Content: "deploy checklist"},
+	}
+	stored, err := svc.Import(ctx, batch)
+	if err != nil || len(stored) != 1 || stored[0].Content != "imported twice" {
+		t.Fatalf("Import() = %+v, %v, want the repeated and already stored content kept once", stored, err)
+	}
+}
+
+// lookupBarrier holds the first n GetMemory calls until all n are made, so
+// concurrent writers all miss before any of them inserts.
+type lookupBarrier struct {
+	*store.MemoryStore
+	wg      sync.WaitGroup
+	pending atomic.Int32
+}
+
+func (b *lookupBarrier) GetMemory(ctx context.Context, id string) (types.MemoryRecord, error) {
+	if b.pending.Add(-1) >= 0 {
+		rec, err := b.MemoryStore.GetMemory(ctx, id)
+		b.wg.Done()
+		b.wg.Wait()
+		return rec, err
+	}
+	return b.MemoryStore.GetMemory(ctx, id)
+}
+
+func TestWrite_ContentAddressedConcurrentWritesShareOneRecord(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	cfg := config.Default()
+	cfg.IDFormat = "content"
+	const writers = 4
+	st := &lookupBarrier{MemoryStore: store.NewMemoryStore()}
+	st.wg.Add(writers)
+	st.pending.Store(writers)
+	svc, err := NewService(st, cfg, log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	var (
+		wg   sync.WaitGroup
+		ids  = make([]string, writers)
+		errs = make([]error, writers)
+	)
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo", Scope: "long", Content: "same note from every agent"})
+			ids[i], errs[i] = rec.ID, err
+		}()
+	}
+	wg.Wait()
+	for i := range writers {
+		if errs[i] != nil || ids[i] != ids[0] {
+			t.Fatalf("Write() %d = %q, %v, want %q and no error", i, ids[i], errs[i], ids[0])
+		}
+	}
+}
+
+func TestWrite_UpsertsByExternalKey(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	openers := map[string]func(dir string) (store.Store, error){
+		"sqlite": func(dir string) (store.Store, error) {
+			return store.OpenSQLite(ctx, filepath.Join(dir, "m.db"), logger)
+		},
+		"markdown": func(dir string) (store.Store, error) { return store.OpenMarkdown(ctx, dir, false, logger) },
+	}
+	for name, open := range openers {
+		st, err := open(t.TempDir())
+		if err != nil {
+			t.Fatalf("%s: open error = %v", name, err)
+		}
+		clk := clock.NewManual(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+		svc, err := NewService(st, config.Default(), logger, WithClock(clk))
+		if err != nil {
+			t.Fatalf("%s: NewService() error = %v", name, err)
+		}
+		first, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Scope: "long", Content: "Runbook v1", Importance: 5, ExternalKey: "runbook/deploy"})
+		if err != nil {
+			t.Fatalf("%s: Write() error = %v", name, err)
+		}
+		clk.Advance(time.Hour)
+		second, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "Runbook v2", ExternalKey: "runbook/deploy"})
+		if err != nil {
+			t.Fatalf("%s: Write() update error = %v", name, err)
+		}
+		if second.ID != first.ID || second.Content != "Runbook v2" || second.Summary != "Runbook v2" {
+			t.Fatalf("%s: upsert = %+v, want %s updated in place", name, second, first.ID)
+		}
+		if second.Scope != "long" || second.ExpiresAt != nil || second.Importance != 5 || !second.CreatedAt.Equal(first.CreatedAt) {
+			t.Fatalf("%s: upsert changed fields it was not given: %+v", name, second)
+		}
+		other, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/other", Content: "Other runbook", ExternalKey: "runbook/deploy"})
+		if err != nil || other.ID == first.ID {
+			t.Fatalf("%s: Write() in another namespace = %+v, %v, want a new memory", name, other, err)
+		}
+		recs, err := st.ListMemories(ctx, store.ListFilter{NamespacePrefix: "org/repo/task"})
+		if err != nil || len(recs) != 1 || recs[0].ExternalKey != "runbook/deploy" {
+			t.Fatalf("%s: ListMemories() = %+v, %v, want one keyed memory", name, recs, err)
+		}
+		st.Close()
+	}
+}
+
+func TestSearch_SlidesShortTermExpiry(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	openers := map[string]func(dir string) (store.Store, error){
+		"sqlite": func(dir string) (store.Store, error) {
+			return store.OpenSQLite(ctx, filepath.Join(dir, "m.db"), logger)
+		},
+		"markdown": func(dir string) (store.Store, error) { return store.OpenMarkdown(ctx, dir, false, logger) },
+	}
+	for name, open := range openers {
+		st, err := open(t.TempDir())
+		if err != nil {
+			t.Fatalf("%s: open error = %v", name, err)
+		}
+		cfg := config.Default()
+		cfg.DefaultShortTTLHours = 2
+		cfg.SlidingTTLHours = map[string]int{"org/repo": 24, "org/repo/quiet": 1}
+		clk := clock.NewManual(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+		svc, err := NewService(st, cfg, logger, WithClock(clk))
+		if err != nil {
+			t.Fatalf("%s: NewService() error = %v", name, err)
+		}
+		active, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "Canary rollout is paused"})
+		if err != nil {
+			t.Fatalf("%s: Write() error = %v", name, err)
+		}
+		idle, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "Lunch order for friday"})
+		if err != nil {
+			t.Fatalf("%s: Write() error = %v", name, err)
+		}
+		quiet, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/quiet", Content: "Canary dashboards moved"})
+		if err != nil {
+			t.Fatalf("%s: Write() error = %v", name, err)
+		}
+
+		clk.Advance(time.Hour)
+		wantActive := clk.Now().Add(24 * time.Hour)
+		results, err := svc.Search(ctx, types.SearchInput{Namespace: "org/repo/task", Query: "canary"})
+		if err != nil || len(results) != 1 || !results[0].Record.ExpiresAt.Equal(wantActive) {
+			t.Fatalf("%s: Search() = %+v, %v, want %s expiring %v", name, results, err, active.ID, wantActive)
+		}
+		if _, err := svc.Search(ctx, types.SearchInput{Namespace: "org/repo/quiet", Query: "canary"}); err != nil {
+			t.Fatalf("%s: Search() error = %v", name, err)
+		}
+		for id, want := range map[string]time.Time{active.ID: wantActive, idle.ID: *idle.ExpiresAt, quiet.ID: *quiet.ExpiresAt} {
+			got, err := st.GetMemory(ctx, id)
+			if err != nil {
+				t.Fatalf("%s: GetMemory(%s) error = %v", name, id, err)
+			}
+			if !got.ExpiresAt.Equal(want) {
+				t.Fatalf("%s: %s expires %v, want %v", name, got.Content, got.ExpiresAt, want)
+			}
+		}
+		st.Close()
+	}
+}
+
+func TestExpireShort_AppliesLongTermRetention(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "m.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	cfg := config.Default()
+	cfg.DefaultShortTTLHours = 400 * 24
+	cfg.LongTermRetention = map[string]config.RetentionConfig{
+		"org/repo":      {MaxAgeDays: 365},
+		"org/repo/hot":  {StaleDays: 30},
+		"org/repo/keep": {MaxAgeDays: 3650},
+	}
+	clk := clock.NewManual(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	svc, err := NewService(st, cfg, logger, WithClock(clk))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	write := func(ns, content, scope string) types.MemoryRecord {
+		t.Helper()
+		rec, err := svc.Write(ctx, types.WriteInput{Namespace: ns, Scope: scope, Content: content})
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		return rec
+	}
+	old := write("org/repo/task", "Old decision", "long")
+	kept := write("org/repo/keep", "Archived decision", "long")
+	hot := write("org/repo/hot", "Hot path note", "long")
+	promoted := write("org/repo/task", "Promoted later", "short")
+	other := write("org/other/task", "Unmanaged decision", "long")
+
+	clk.Advance(300 * 24 * time.Hour)
+	if _, err := svc.Promote(ctx, types.PromoteInput{MemoryID: promoted.ID}); err != nil {
+		t.Fatalf("Promote() error = %v", err)
+	}
+	clk.Advance(100 * 24 * time.Hour)
+	n, err := svc.ExpireShort(ctx)
+	if err != nil {
+		t.Fatalf("ExpireShort() error = %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("ExpireShort() = %d, want 2", n)
+	}
+	for _, id := range []string{old.ID, hot.ID} {
+		if _, err := st.GetMemory(ctx, id); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("GetMemory(%s) error = %v, want it expired", id, err)
+		}
+	}
+	for _, id := range []string{kept.ID, promoted.ID, other.ID} {
+		if _, err := st.GetMemory(ctx, id); err != nil {
+			t.Fatalf("GetMemory(%s) error = %v, want it kept", id, err)
+		}
+	}
+}
+
+func TestPromote_RecordsAuditTrail(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "m.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	svc, err := NewService(st, config.Default(), logger)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	rec, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "Use squash merges"})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := svc.Promote(ctx, types.PromoteInput{MemoryID: rec.ID, Reason: " agreed in review ", SourceAgent: "codex"}); err != nil {
+		t.Fatalf("Promote() error = %v", err)
+	}
+	keyed, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "Runbook v1", ExternalKey: "runbook"})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Scope: "long", Content: "Runbook v2", ExternalKey: "runbook", SourceAgent: "ingest"}); err != nil {
+		t.Fatalf("Write() upsert error = %v", err)
+	}
+	if _, err := svc.Promote(ctx, types.PromoteInput{MemoryID: "missing"}); err == nil {
+		t.Fatal("Promote() of a missing memory succeeded")
+	}
+
+	got, err := st.RecentPromotions(ctx, 10)
+	if err != nil {
+		t.Fatalf("RecentPromotions() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("RecentPromotions() = %+v, want 2", got)
+	}
+	if got[0].MemoryID != keyed.ID || got[0].PriorScope != "short" || got[0].SourceAgent != "ingest" {
+		t.Fatalf("upsert promotion = %+v", got[0])
+	}
+	if got[1].MemoryID != rec.ID || got[1].PriorScope != "short" || got[1].Reason != "agreed in review" || got[1].SourceAgent != "codex" || got[1].Namespace != "org/repo/task" {
+		t.Fatalf("promotion = %+v", got[1])
+	}
+}
+
+func TestDemote_MovesBackToShortTerm(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "m.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	clk := clock.NewManual(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	svc, err := NewService(st, config.Default(), logger, WithClock(clk))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	rec, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "Use squash merges"})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := svc.Demote(ctx, types.DemoteInput{MemoryID: rec.ID}); err == nil {
+		t.Fatal("Demote() of a short-term memory succeeded")
+	}
+	if _, err := svc.Promote(ctx, types.PromoteInput{MemoryID: rec.ID}); err != nil {
+		t.Fatalf("Promote() error = %v", err)
+	}
+
+	clk.Advance(time.Hour)
+	got, err := svc.Demote(ctx, types.DemoteInput{MemoryID: rec.ID, TTLSeconds: 600, SourceAgent: "codex"})
+	if err != nil {
+		t.Fatalf("Demote() error = %v", err)
+	}
+	if got.Scope != "short" || got.PromotedAt != nil || got.ExpiresAt == nil || !got.ExpiresAt.Equal(clk.Now().Add(10*time.Minute)) {
+		t.Fatalf("Demote() = %+v, want short-term expiring in 10 minutes", got)
+	}
+	entries, err := st.AuditLog(ctx, store.AuditFilter{MemoryID: rec.ID})
+	if err != nil || len(entries) == 0 || entries[0].Action != store.AuditDemote || entries[0].Actor != "codex" {
+		t.Fatalf("AuditLog() = %+v, %v, want a demote entry first", entries, err)
+	}
+	if _, err := svc.Demote(ctx, types.DemoteInput{MemoryID: "missing"}); err == nil {
+		t.Fatal("Demote() of a missing memory succeeded")
+	}
+
+	clk.Advance(11 * time.Minute)
+	if n, err := svc.ExpireShort(ctx); err != nil || n != 1 {
+		t.Fatalf("ExpireShort() = %d, %v, want the demoted memory expired", n, err)
+	}
+}
+
+func TestService_RecordsAuditLog(t *testing.T) {
+	t.Parallel()
+	ctx := WithCaller(context.Background(), Caller{Tool: "memory_write", Client: "claude-code"})
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "m.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	clk := clock.NewManual(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	svc, err := NewService(st, config.Default(), logger, WithClock(clk))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	kept, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "Deploys need approval", SourceAgent: "codex"})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := svc.Append(ctx, types.AppendInput{MemoryID: kept.ID, Content: "from two reviewers", Summary: "Deploys need two approvals"}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	gone, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Content: "Scratch note", TTLSeconds: 60})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	clk.Advance(time.Minute)
+	if _, err := svc.ExpireShort(context.Background()); err != nil {
+		t.Fatalf("ExpireShort() error = %v", err)
+	}
+	if _, err := svc.Delete(ctx, types.DeleteInput{MemoryIDs: []string{kept.ID}}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	got, err := st.AuditLog(ctx, store.AuditFilter{NamespacePrefix: "org/repo"})
+	if err != nil {
+		t.Fatalf("AuditLog() error = %v", err)
+	}
+	want := []store.AuditEntry{
+		{Action: store.AuditDelete, MemoryID: kept.ID, Actor: "claude-code", Tool: "memory_write", Before: "Deploys need two approvals"},
+		{Action: store.AuditExpire, MemoryID: gone.ID, Tool: "ttl", Before: "Scratch note"},
+		{Action: store.AuditWrite, MemoryID: gone.ID, Actor: "claude-code", Tool: "memory_write", After: "Scratch note"},
+		{Action: store.AuditUpdate, MemoryID: kept.ID, Actor: "claude-code", Tool: "memory_write", Before: "Deploys need approval", After: "Deploys need two approvals"},
+		{Action: store.AuditWrite, MemoryID: kept.ID, Actor: "codex", Tool: "memory_write", After: "Deploys need approval"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("AuditLog() = %+v, want %d entries", got, len(want))
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.Action != w.Action || g.MemoryID != w.MemoryID || g.Actor != w.Actor || g.Tool != w.Tool || g.Before != w.Before || g.After != w.After || g.Namespace != "org/repo/task" {
+			t.Fatalf("AuditLog()[%d] = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestPurge_DeletesOrRedactsByMetadata(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "m.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	svc, err := NewService(st, config.Default(), logger)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	write := func(ns, content string, meta map[string]any) types.MemoryRecord {
+		t.Helper()
+		rec, err := svc.Write(ctx, types.WriteInput{Namespace: ns, Scope: "long", Content: content, Metadata: meta})
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		return rec
+	}
+	a := write("org/repo/task", "Alice prefers email", map[string]any{"user_id": 123})
+	b := write("org/other/task", "Alice is on call", map[string]any{"user_id": "123", "team": "ops"})
+	c := write("org/repo/task", "Bob prefers chat", map[string]any{"user_id": 456})
+
+	preview, err := svc.Purge(ctx, types.PurgeInput{Key: "user_id", Value: "123", DryRun: true})
+	if err != nil || preview.Count != 2 || len(preview.Memories) != 2 || preview.Memories[0].ID != a.ID {
+		t.Fatalf("Purge() dry run = %+v, %v, want %s and %s", preview, err, a.ID, b.ID)
+	}
+	if _, err := st.GetMemory(ctx, a.ID); err != nil {
+		t.Fatalf("dry run removed %s: %v", a.ID, err)
+	}
+
+	redacted, err := svc.Purge(ctx, types.PurgeInput{Key: "user_id", Value: "123", Redact: true})
+	if err != nil || redacted.Count != 2 || redacted.Mode != "redact" {
+		t.Fatalf("Purge() redact = %+v, %v", redacted, err)
+	}
+	got, err := st.GetMemory(ctx, b.ID)
+	if err != nil {
+		t.Fatalf("GetMemory() error = %v", err)
+	}
+	if got.Content != RedactedText || got.Summary != RedactedText || got.Metadata["user_id"] != nil || got.Metadata["team"] != "ops" || got.Metadata[MetaRedactedAt] == nil {
+		t.Fatalf("redacted memory = %+v", got)
+	}
+	if results, err := svc.Search(ctx, types.SearchInput{Namespace: "org/repo/task", Query: "Alice"}); err != nil || len(results) != 0 {
+		t.Fatalf("Search() after redaction = %+v, %v, want nothing", results, err)
+	}
+	entries, err := st.AuditLog(ctx, store.AuditFilter{MemoryID: a.ID})
+	if err != nil {
+		t.Fatalf("AuditLog() error = %v", err)
+	}
+	for _, e := range entries {
+		if e.Before != "" || e.After != "" {
+			t.Fatalf("audit entry kept a summary after purge: %+v", e)
+		}
+	}
+	if len(entries) != 2 || entries[0].Action != store.AuditPurge {
+		t.Fatalf("AuditLog() = %+v, want write then purge", entries)
+	}
+
+	deleted, err := svc.Purge(ctx, types.PurgeInput{Key: "user_id", Value: "456"})
+	if err != nil || deleted.Count != 1 {
+		t.Fatalf("Purge() delete = %+v, %v", deleted, err)
+	}
+	if _, err := st.GetMemory(ctx, c.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetMemory(%s) error = %v, want it deleted", c.ID, err)
+	}
+	if _, err := svc.Purge(ctx, types.PurgeInput{Key: "user-id", Value: "1"}); err == nil {
+		t.Fatal("Purge() accepted an invalid metadata key")
+	}
+}
+
+func TestForget_DeletesByFilter(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "m.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	clk := clock.NewManual(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	svc, err := NewService(st, config.Default(), logger, WithClock(clk))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	write := func(ns, scope, content string) types.MemoryRecord {
+		t.Helper()
+		rec, err := svc.Write(ctx, types.WriteInput{Namespace: ns, Scope: scope, Content: content})
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		return rec
+	}
+	old := write("org/repo/task", "short", "scratch: try the flaky test again")
+	clk.Advance(time.Minute)
+	child := write("org/repo/task/sub", "short", "scratch: bisect the failure")
+	kept := write("org/repo/task", "long", "the flaky test needs a fixed seed")
+	clk.Advance(2 * time.Hour)
+	recent := write("org/repo/task", "short", "scratch: rerun CI")
+
+	if _, err := svc.Forget(ctx, types.ForgetInput{Scope: "short"}); err == nil {
+		t.Fatal("Forget() without namespace or metadata error = nil")
+	}
+	preview, err := svc.Forget(ctx, types.ForgetInput{Namespace: "org/repo/task", IncludeDescendants: true, Scope: "short", OlderThanSeconds: 3600, DryRun: true})
+	if err != nil || preview.Count != 2 || len(preview.Memories) != 2 || preview.Memories[0].ID != old.ID || preview.Memories[1].ID != child.ID {
+		t.Fatalf("Forget() dry run = %+v, %v, want %s and %s", preview, err, old.ID, child.ID)
+	}
+	if _, err := st.GetMemory(ctx, old.ID); err != nil {
+		t.Fatalf("dry run removed %s: %v", old.ID, err)
+	}
+
+	res, err := svc.Forget(ctx, types.ForgetInput{Namespace: "org/repo/task", Scope: "short", OlderThanSeconds: 3600})
+	if err != nil || res.Count != 1 || res.Memories != nil {
+		t.Fatalf("Forget() = %+v, %v, want 1 removed", res, err)
+	}
+	for id, want := range map[string]bool{old.ID: false, child.ID: true, kept.ID: true, recent.ID: true} {
+		if _, err := st.GetMemory(ctx, id); (err == nil) != want {
+			t.Fatalf("GetMemory(%s) error = %v, want present %v", id, err, want)
+		}
+	}
+}
+
+func TestSearch_BlendsSemanticSimilarity(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "semantic.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	cfg := config.Default()
+	cfg.Embeddings.Providers = []config.EmbeddingProviderConfig{{Kind: "hash", Model: "hash-256"}}
+	svc, err := NewService(st, cfg, logger)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	var k8s types.MemoryRecord
+	for _, content := range []string{"kubernetes cluster upgrade notes", "lunch menu for friday"} {
+		rec, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Scope: "long", Content: content})
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if k8s.ID == "" {
+			k8s = rec
+		}
+	}
+	if missing, err := st.MemoriesMissingEmbedding(ctx, "hash-256", 10); err != nil || len(missing) != 0 {
+		t.Fatalf("MemoriesMissingEmbedding() = %d, %v, want every write embedded", len(missing), err)
+	}
+
+	res, err := svc.Search(ctx, types.SearchInput{Namespace: "org/repo/task", Query: "kubrnetes clustr", Explain: true})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(res) != 1 || res[0].Record.ID != k8s.ID {
+		t.Fatalf("Search(typo) = %+v, want only the kubernetes memory", res)
+	}
+	if r := res[0]; r.LexicalScore != 0 || r.SemanticScore < cfg.Embeddings.MinSimilarity || r.Explanation.Semantic == 0 {
+		t.Fatalf("Search(typo) scores = lexical %v semantic %v, want a semantic-only match", r.LexicalScore, r.SemanticScore)
+	}
+
+	res, err = svc.Search(ctx, types.SearchInput{Namespace: "org/repo/task", Query: "kubernetes upgrade"})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(res) == 0 || res[0].Record.ID != k8s.ID || res[0].LexicalScore == 0 || res[0].SemanticScore == 0 {
+		t.Fatalf("Search() = %+v, want the kubernetes memory ranked by both terms", res)
+	}
+}
+
+func TestList_PagesWithCursor(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "m.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	clk := clock.NewManual(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	svc, err := NewService(st, config.Default(), logger, WithClock(clk))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	var ids []string
+	for i, ns := range []string{"org/repo/a", "org/repo/b", "org/other/a", "org/repo/a", "org/repo/b"} {
+		rec, err := svc.Write(ctx, types.WriteInput{Namespace: ns, Scope: "long", Content: "note", Importance: i + 1})
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		ids = append(ids, rec.ID)
+		clk.Advance(time.Minute)
+	}
+
+	var got []string
+	in := types.ListInput{Namespace: "org/repo", Limit: 2}
+	for page := 0; ; page++ {
+		res, err := svc.List(ctx, in)
+		if err != nil {
+			t.Fatalf("List() page %d error = %v", page, err)
+		}
+		for _, rec := range res.Memories {
+			got = append(got, rec.ID)
+		}
+		if res.NextCursor == "" {
+			break
+		}
+		if page == 0 {
+			// A memory written between pages belongs before the cursor.
+			if _, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/a", Scope: "long", Content: "later"}); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+		}
+		in.Cursor = res.NextCursor
+	}
+	if want := []string{ids[4], ids[3], ids[1], ids[0]}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("List() pages = %v, want %v", got, want)
+	}
+
+	res, err := svc.List(ctx, types.ListInput{MinImportance: 2, MaxImportance: 4, Sort: store.SortImportanceDesc})
+	if err != nil || len(res.Memories) != 4 || res.Memories[0].ID != ids[3] || res.NextCursor != "" {
+		t.Fatalf("List(importance 2-4) = %+v, %v, want %s first of 4", res, err, ids[3])
+	}
+	if _, err := svc.List(ctx, types.ListInput{Sort: store.SortCreatedAsc, Cursor: in.Cursor}); err == nil {
+		t.Fatal("List() with a cursor from another sort error = nil")
+	}
+	if _, err := svc.List(ctx, types.ListInput{Cursor: "not-a-cursor"}); err == nil {
+		t.Fatal("List() with an invalid cursor error = nil")
+	}
+}
+
+func TestSearch_TraversesNamespaceHierarchy(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "m.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	svc, err := NewService(st, config.Default(), logger)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	byNamespace := map[string]string{}
+	for _, ns := range []string{"org/repo", "org/repo/main/task", "org/repo/main/task/sub", "org/other/main/task"} {
+		rec, err := svc.Write(ctx, types.WriteInput{Namespace: ns, Scope: "long", Content: "flaky deploy pipeline"})
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		byNamespace[rec.ID] = ns
+	}
+	namespaces := func(results []types.SearchResult) []string {
+		var out []string
+		for _, r := range results {
+			out = append(out, byNamespace[r.Record.ID])
+		}
+		return out
+	}
+
+	results, err := svc.Search(ctx, types.SearchInput{Namespace: "org/repo/main/task", Query: "deploy"})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Search() = %v, %v, want the exact namespace only", namespaces(results), err)
+	}
+
+	results, err = svc.Search(ctx, types.SearchInput{Namespace: "org/repo/main/task", Query: "deploy", IncludeAncestors: true, IncludeDescendants: true, Explain: true})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	want := []string{"org/repo/main/task", "org/repo/main/task/sub", "org/repo"}
+	if got := namespaces(results); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("Search(ancestors, descendants) = %v, want %v", got, want)
+	}
+	decay := config.Default().Ranking.NamespaceDecay
+	if got, want := results[2].Score/results[0].Score, decay*decay; math.Abs(got-want) > 1e-9 {
+		t.Fatalf("two levels up scored %.4f of the exact match, want %.4f", got, want)
+	}
+	if e := results[1].Explanation; e == nil || e.Namespace >= 0 {
+		t.Fatalf("descendant explanation = %+v, want a negative namespace term", e)
+	}
+
+	pack, err := svc.ContextPack(ctx, types.ContextPackInput{Namespace: "org/repo/main/task/sub", Query: "deploy", TokenBudget: 200, IncludeAncestors: true})
+	if err != nil || len(pack.MemoryIDs) != 1 {
+		t.Fatalf("ContextPack(ancestors) = %+v, %v, want the deduplicated note once", pack, err)
+	}
+}
+
+func TestScopes_ProjectAndGlobal(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "m.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	clk := clock.NewManual(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	cfg := config.Default()
+	cfg.Scopes.Global.TTLHours = 24
+	svc, err := NewService(st, cfg, logger, WithClock(clk))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	project, err := svc.Write(ctx, types.WriteInput{Namespace: "acme/api/main/task", Scope: "project", Content: "deploys go through the release train"})
+	if err != nil {
+		t.Fatalf("Write(project) error = %v", err)
+	}
+	if project.ExpiresAt != nil {
+		t.Fatalf("project memory expires at %v, want no expiry", project.ExpiresAt)
+	}
+	global, err := svc.Write(ctx, types.WriteInput{Namespace: "acme/web/main/task", Scope: "global", Content: "deploys need a changelog entry"})
+	if err != nil {
+		t.Fatalf("Write(global) error = %v", err)
+	}
+	if global.ExpiresAt == nil || !global.ExpiresAt.Equal(clk.Now().Add(24*time.Hour)) {
+		t.Fatalf("global memory expires at %v, want after scopes.global.ttl_hours", global.ExpiresAt)
+	}
+	if _, err := svc.Write(ctx, types.WriteInput{Namespace: "acme/api/main/task", Scope: "team", Content: "x"}); err == nil {
+		t.Fatal("Write() with an unknown scope succeeded")
+	}
+
+	ids := func(ns string) []string {
+		results, err := svc.Search(ctx, types.SearchInput{Namespace: ns, Query: "deploys"})
+		if err != nil {
+			t.Fatalf("Search(%s) error = %v", ns, err)
+		}
+		var out []string
+		for _, r := range results {
+			out = append(out, r.Record.ID)
+		}
+		slices.Sort(out)
+		return out
+	}
+	both := []string{project.ID, global.ID}
+	slices.Sort(both)
+	if got := ids("acme/api/feature/other"); !slices.Equal(got, both) {
+		t.Fatalf("Search() from the same project = %v, want %v", got, both)
+	}
+	if got := ids("other/repo/main/task"); !slices.Equal(got, []string{global.ID}) {
+		t.Fatalf("Search() from another project = %v, want only the global memory", got)
+	}
+
+	short, err := svc.Write(ctx, types.WriteInput{Namespace: "acme/api/main/task", Content: "the release train leaves on Tuesdays"})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	promoted, err := svc.Promote(ctx, types.PromoteInput{MemoryID: short.ID, TargetScope: "project"})
+	if err != nil {
+		t.Fatalf("Promote(project) error = %v", err)
+	}
+	if promoted.Scope != "project" || promoted.ExpiresAt != nil || promoted.PromotedAt == nil {
+		t.Fatalf("Promote(project) = %+v", promoted)
+	}
+	if _, err := svc.Promote(ctx, types.PromoteInput{MemoryID: short.ID, TargetScope: "long"}); err == nil {
+		t.Fatal("Promote() from project down to long succeeded")
+	}
+	if _, err := svc.Promote(ctx, types.PromoteInput{MemoryID: short.ID, TargetScope: "short"}); err == nil {
+		t.Fatal("Promote() to short succeeded")
+	}
+
+	clk.Advance(25 * time.Hour)
+	if n, err := svc.ExpireShort(ctx); err != nil || n != 1 {
+		t.Fatalf("ExpireShort() = %d, %v, want the global memory expired", n, err)
+	}
+	if _, err := st.GetMemory(ctx, project.ID); err != nil {
+		t.Fatalf("GetMemory(project) error = %v, want it kept", err)
+	}
+}
+
+func TestImportJSONL_RemapsAndSkipsKnownIDs(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	open := func(name string) *Service {
+		st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), name), logger)
+		if err != nil {
+			t.Fatalf("OpenSQLite() error = %v", err)
+		}
+		t.Cleanup(func() { st.Close() })
+		svc, err := NewService(st, config.Default(), logger)
+		if err != nil {
+			t.Fatalf("NewService() error = %v", err)
+		}
+		return svc
+	}
+	src, dst := open("src.db"), open("dst.db")
+	var want []types.MemoryRecord
+	for _, ns := range []string{"org/repo/task", "org/repo", "org/other"} {
+		rec, err := src.Write(ctx, types.WriteInput{Namespace: ns, Scope: "long", Content: "note in " + ns})
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		want = append(want, rec)
+	}
+
+	var dump strings.Builder
+	exp, err := src.ExportJSONL(ctx, &dump, "org/repo")
+	if err != nil || exp.Exported != 2 {
+		t.Fatalf("ExportJSONL(org/repo) = %+v, %v, want 2 memories", exp, err)
+	}
+	opts := ImportOptions{Remaps: []NamespaceRemap{{From: "org/repo", To: "acme/api"}}}
+	res, err := dst.ImportJSONL(ctx, strings.NewReader(dump.String()), opts)
+	if err != nil || res.Read != 2 || res.Imported != 2 || len(res.Skipped) != 0 {
+		t.Fatalf("ImportJSONL() = %+v, %v, want 2 imported", res, err)
+	}
+	got, err := dst.store.GetMemory(ctx, want[0].ID)
+	if err != nil || got.Namespace != "acme/api/task" || !got.CreatedAt.Equal(want[0].CreatedAt) {
+		t.Fatalf("imported %+v, %v, want %s moved to acme/api/task with its timestamps", got, err, want[0].ID)
+	}
+
+	res, err = dst.ImportJSONL(ctx, strings.NewReader(dump.String()), opts)
+	if err != nil || res.Imported != 0 || len(res.Skipped) != 2 {
+		t.Fatalf("ImportJSONL(again) = %+v, %v, want both skipped", res, err)
+	}
+	if _, err := dst.ImportJSONL(ctx, strings.NewReader(`{"namespace": "org/repo/task", "content": ""}`), ImportOptions{DryRun: true}); err == nil || !strings.Contains(err.Error(), "record 1") {
+		t.Fatalf("ImportJSONL(empty content) error = %v, want record 1 rejected", err)
+	}
+	if _, err := ParseNamespaceRemap("org/repo"); err == nil {
+		t.Fatal("ParseNamespaceRemap() without = error = nil")
+	}
+}
+
+func TestWrite_DedupesByPolicy(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	open := func(policy string) *Service {
+		st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), policy+".db"), logger)
+		if err != nil {
+			t.Fatalf("OpenSQLite() error = %v", err)
+		}
+		t.Cleanup(func() { st.Close() })
+		cfg := config.Default()
+		cfg.WriteDedupe.Policy = policy
+		svc, err := NewService(st, cfg, logger)
+		if err != nil {
+			t.Fatalf("NewService() error = %v", err)
+		}
+		return svc
+	}
+	const ns = "org/repo/task"
+	first := types.WriteInput{Namespace: ns, Scope: "short", Content: "The staging deploy uses blue green rollout.", Importance: 2,
+		Metadata: map[string]any{"tags": []any{"deploy"}}}
+	again := types.WriteInput{Namespace: ns, Scope: "long", Content: "the staging deploy uses blue-green rollout", Importance: 4,
+		Metadata: map[string]any{"tags": []any{"staging"}}}
+
+	svc := open("reject")
+	orig, err := svc.Write(ctx, first)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := svc.Write(ctx, again); !errors.Is(err, ErrDuplicate) || !strings.Contains(err.Error(), orig.ID) {
+		t.Fatalf("Write(duplicate) error = %v, want ErrDuplicate naming %s", err, orig.ID)
+	}
+	if _, err := svc.Write(ctx, types.WriteInput{Namespace: ns, Content: "Production deploys need two approvals."}); err != nil {
+		t.Fatalf("Write(distinct) error = %v", err)
+	}
+
+	svc = open("update")
+	orig, _ = svc.Write(ctx, first)
+	got, err := svc.Write(ctx, again)
+	if err != nil || got.ID != orig.ID || got.Content != again.Content || got.Scope != "long" {
+		t.Fatalf("Write(duplicate) = %+v, %v, want %s updated in place", got, err, orig.ID)
+	}
+
+	svc = open("merge")
+	orig, _ = svc.Write(ctx, first)
+	got, err = svc.Write(ctx, again)
+	if err != nil || got.ID != orig.ID {
+		t.Fatalf("Write(duplicate) = %+v, %v, want merged into %s", got, err, orig.ID)
+	}
+	if got.Importance != 4 || got.Scope != "long" || got.ExpiresAt != nil || !strings.Contains(got.Content, first.Content) || !strings.Contains(got.Content, again.Content) {
+		t.Fatalf("merged record = %+v, want both contents, importance 4 and long scope", got)
+	}
+	if tags := tagList(got.Metadata["tags"]); strings.Join(tags, ",") != "deploy,staging" {
+		t.Fatalf("merged tags = %v, want deploy,staging", tags)
+	}
+}
+
+func TestContextPack_Sections(t *testing.T) {
+	t.Parallel()
+	now := time.Now().UTC()
+	old := now.AddDate(-1, 0, 0)
+	st := &fakeStore{search: []store.Candidate{
+		{Record: types.MemoryRecord{ID: "note", Scope: "short", Summary: "retry the flaky login test", CreatedAt: now, Importance: 3}, LexicalScore: 0.9},
+		{Record: types.MemoryRecord{ID: "old", Scope: "long", Summary: "deploys go through jenkins", CreatedAt: old, Importance: 3}, LexicalScore: 0.8},
+		{Record: types.MemoryRecord{ID: "rule", Scope: "long", Summary: "never commit secrets", CreatedAt: now, Importance: 5}, LexicalScore: 0.7},
+		{Record: types.MemoryRecord{ID: "note2", Scope: "short", Summary: "a much longer working note that will not fit in what is left of the recent share", CreatedAt: now, Importance: 3}, LexicalScore: 0.6},
+	}}
+	svc, err := NewService(st, config.Default(), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	pack, err := svc.ContextPack(context.Background(), types.ContextPackInput{Namespace: "org/repo", Query: "x", TokenBudget: 80, Sections: true})
+	if err != nil {
+		t.Fatalf("ContextPack() error = %v", err)
+	}
+	want := "## Durable knowledge\n- [rule] never commit secrets\n\n## Recent working notes\n- [note] retry the flaky login test\n\n## Possibly stale\n- [old] deploys go through jenkins"
+	if pack.Text != want {
+		t.Fatalf("ContextPack().Text = %q, want %q", pack.Text, want)
+	}
+	if !slices.Equal(pack.MemoryIDs, []string{"rule", "note", "old"}) || len(pack.Sections) != 3 || pack.Sections[1].Name != "recent" || !slices.Equal(pack.Sections[1].MemoryIDs, []string{"note"}) {
+		t.Fatalf("ContextPack() = %+v, want note2 left out for the recent share", pack)
+	}
+
+	pack, err = svc.ContextPack(context.Background(), types.ContextPackInput{Namespace: "org/repo", Query: "x", TokenBudget: 400, Sections: true, Format: "json"})
+	if err != nil || !strings.HasPrefix(pack.Text, `[{"name":"durable","title":"Durable knowledge","memories":[{"id":"rule"`) || len(pack.Sections) != 3 {
+		t.Fatalf("ContextPack(json) = %+v, %v", pack, err)
+	}
+}
+
+func TestPurge_ClearsLoggedRequestParams(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "m.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	svc, err := NewService(st, config.Default(), logger)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	rec, err := svc.Write(ctx, types.WriteInput{Namespace: "org/repo/task", Scope: "long", Content: "Alice prefers email", Metadata: map[string]any{"user_id": 123}})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	logged := []string{
+		`{"name":"memory_write","arguments":{"namespace":"org/repo/task","content":"Alice prefers email","metadata":{"user_id":123}}}`,
+		`{"name":"memory_get","arguments":{"ids":["` + rec.ID + `"]}}`,
+		`{"name":"memory_append","arguments":{"id":"m-other","content":"Note: Alice prefers email, not chat"}}`,
+		`{"name":"memory_search","arguments":{"namespace":"org/repo/task","query":"deploy"}}`,
+	}
+	for _, params := range logged {
+		if err := st.InsertMCPRequestLog(ctx, store.MCPRequestLog{Method: "tools/call", Success: true, Params: params}); err != nil {
+			t.Fatalf("InsertMCPRequestLog() error = %v", err)
+		}
+	}
+
+	res, err := svc.Purge(ctx, types.PurgeInput{Key: "user_id", Value: "123"})
+	if err != nil || res.Count != 1 || res.Requests != 3 {
+		t.Fatalf("Purge() = %+v, %v, want 1 memory and 3 requests", res, err)
+	}
+	rows, err := st.MCPRequestLogsAfter(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("MCPRequestLogsAfter() error = %v", err)
+	}
+	if len(rows) != len(logged) {
+		t.Fatalf("MCPRequestLogsAfter() = %d rows, want %d", len(rows), len(logged))
+	}
+	for i, row := range rows[:3] {
+		if row.Params != "" {
+			t.Fatalf("request %d kept params %q after purge", i, row.Params)
+		}
+	}
+	if rows[3].Params != logged[3] {
+		t.Fatalf("unrelated request params = %q, want them kept", rows[3].Params)
+	}
+}
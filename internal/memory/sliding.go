@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// slidingWindow is one sliding_ttl_hours entry.
+type slidingWindow struct {
+	namespace string
+	window    time.Duration
+}
+
+func newSlidingWindows(hours map[string]int) []slidingWindow {
+	out := make([]slidingWindow, 0, len(hours))
+	for ns, h := range hours {
+		out = append(out, slidingWindow{namespace: strings.Trim(strings.TrimSpace(ns), "/"), window: time.Duration(h) * time.Hour})
+	}
+	// Longest namespace first so the most specific entry wins.
+	sort.Slice(out, func(i, j int) bool {
+		return len(out[i].namespace) > len(out[j].namespace)
+	})
+	return out
+}
+
+// slidingTTL returns the sliding window of namespace, or 0 when retrieving
+// its memories leaves their expiry alone.
+func (s *Service) slidingTTL(namespace string) time.Duration {
+	for _, w := range s.sliding {
+		if namespace == w.namespace || strings.HasPrefix(namespace, w.namespace+"/") {
+			return w.window
+		}
+	}
+	return 0
+}
+
+// slideExpiry keeps retrieved short-term memories alive for at least their
+// namespace's sliding window from now. The returned records are updated
+// too; failures are logged and never fail the search.
+func (s *Service) slideExpiry(ctx context.Context, results []types.SearchResult, now time.Time) {
+	if s.cfg.ReadOnly || len(s.sliding) == 0 {
+		return
+	}
+	for i := range results {
+		rec := &results[i].Record
+		window := s.slidingTTL(rec.Namespace)
+		if rec.Scope != "short" || rec.ExpiresAt == nil || window == 0 {
+			continue
+		}
+		until := now.Add(window)
+		if !until.After(*rec.ExpiresAt) {
+			continue
+		}
+		var err error
+		if s.extender != nil {
+			err = s.extender.ExtendExpiry(ctx, rec.ID, until, now)
+		} else {
+			_, err = s.store.UpdateMemory(ctx, rec.ID, func(r *types.MemoryRecord) error {
+				if r.Scope == "short" {
+					r.ExpiresAt = &until
+					r.LastAccessedAt = now
+				}
+				return nil
+			})
+		}
+		if err != nil {
+			s.logger.Warn("sliding ttl extension failed", "memory_id", rec.ID, "error", err)
+			continue
+		}
+		rec.ExpiresAt = &until
+		rec.LastAccessedAt = now
+	}
+}
@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/xiy/memory-mcp/internal/memory")
+
+// startSpan starts the span of a Service operation, e.g. "memory.Write",
+// tagged with the calling tool.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if tool := callerOf(ctx).Tool; tool != "" {
+		attrs = append(attrs, attribute.String("memory.caller.tool", tool))
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan marks span failed when err is set and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func namespaceAttr(ns string) attribute.KeyValue {
+	return attribute.String("memory.namespace", ns)
+}
+
+func idAttr(id string) attribute.KeyValue {
+	return attribute.String("memory.id", id)
+}
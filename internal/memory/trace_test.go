@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// TestService_RecordsSpans installs the global tracer provider, so it must
+// stay the only test in the package that does.
+func TestService_RecordsSpans(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "trace.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	svc, err := NewService(st, config.Default(), logger)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	ctx = WithCaller(ctx, Caller{Tool: "memory_write"})
+	if _, err := svc.Write(ctx, types.WriteInput{Namespace: "acme/api", Scope: "long", Content: "deploy window is 9-11"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := svc.Write(ctx, types.WriteInput{Namespace: "acme/api", Scope: "bogus", Content: "x"}); err == nil {
+		t.Fatal("Write(bogus scope) error = nil")
+	}
+
+	var writes []sdktrace.ReadOnlySpan
+	var sqlChild bool
+	for _, span := range rec.Ended() {
+		if span.Name() == "memory.Write" {
+			writes = append(writes, span)
+		}
+	}
+	if len(writes) != 2 {
+		t.Fatalf("recorded %d memory.Write spans, want 2", len(writes))
+	}
+	for _, span := range rec.Ended() {
+		if span.Parent().SpanID() == writes[0].SpanContext().SpanID() && span.Name() != "memory.Write" {
+			sqlChild = true
+		}
+	}
+	if !sqlChild {
+		t.Fatal("memory.Write has no child SQL spans")
+	}
+	attrs := map[string]string{}
+	for _, kv := range writes[0].Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs["memory.namespace"] != "acme/api" || attrs["memory.caller.tool"] != "memory_write" {
+		t.Fatalf("memory.Write attributes = %v", attrs)
+	}
+	if writes[0].Status().Code == codes.Error || writes[1].Status().Code != codes.Error {
+		t.Fatalf("memory.Write statuses = %v, %v, want ok then error", writes[0].Status(), writes[1].Status())
+	}
+}
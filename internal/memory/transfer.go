@@ -0,0 +1,150 @@
+package memory
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// importBatchSize is the number of records ImportJSONL stores per batch,
+// each in one transaction.
+const importBatchSize = 500
+
+// NamespaceRemap moves imported memories from namespace From, and the
+// namespaces below it, to To.
+type NamespaceRemap struct {
+	From string
+	To   string
+}
+
+// ParseNamespaceRemap parses a "from=to" remap.
+func ParseNamespaceRemap(s string) (NamespaceRemap, error) {
+	from, to, ok := strings.Cut(s, "=")
+	from, to = strings.Trim(strings.TrimSpace(from), "/"), strings.Trim(strings.TrimSpace(to), "/")
+	if !ok || from == "" || to == "" {
+		return NamespaceRemap{}, fmt.Errorf("invalid namespace remap %q (expected from=to)", s)
+	}
+	return NamespaceRemap{From: from, To: to}, nil
+}
+
+// remapNamespace returns ns moved by the first remap whose From is ns or one of its
+// ancestors.
+func remapNamespace(ns string, remaps []NamespaceRemap) string {
+	for _, r := range remaps {
+		if ns == r.From {
+			return r.To
+		}
+		if rest, ok := strings.CutPrefix(ns, r.From+"/"); ok {
+			return r.To + "/" + rest
+		}
+	}
+	return ns
+}
+
+// ExportResult reports what ExportJSONL wrote.
+type ExportResult struct {
+	Exported int `json:"exported"`
+}
+
+// ExportJSONL writes every memory under namespacePrefix, or every memory
+// when it is empty, to w as one JSON record per line, oldest first.
+// Expired short-term memories are included with their expiry.
+func (s *Service) ExportJSONL(ctx context.Context, w io.Writer, namespacePrefix string) (ExportResult, error) {
+	prefix := strings.Trim(strings.TrimSpace(namespacePrefix), "/")
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	var res ExportResult
+	err := s.store.ExportAll(ctx, func(rec types.MemoryRecord) error {
+		if prefix != "" && rec.Namespace != prefix && !strings.HasPrefix(rec.Namespace, prefix+"/") {
+			return nil
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+		res.Exported++
+		return nil
+	})
+	if err != nil {
+		return res, err
+	}
+	return res, bw.Flush()
+}
+
+// ImportOptions tune ImportJSONL.
+type ImportOptions struct {
+	// Remaps are tried in order; the first that matches a record's
+	// namespace moves it.
+	Remaps []NamespaceRemap
+	// DryRun validates the input without storing anything.
+	DryRun bool
+}
+
+// ImportResult reports what ImportJSONL stored. Skipped lists the IDs
+// already present, which are left untouched.
+type ImportResult struct {
+	DryRun   bool     `json:"dry_run"`
+	Read     int      `json:"read"`
+	Imported int      `json:"imported"`
+	Skipped  []string `json:"skipped"`
+}
+
+// ImportJSONL restores memories written by ExportJSONL, keeping their IDs
+// and timestamps. Records whose IDs are already stored are skipped, so
+// importing the same file twice, or merging two databases that share
+// history, does not duplicate memories. Batches already stored stay
+// stored when a later record fails.
+func (s *Service) ImportJSONL(ctx context.Context, r io.Reader, opts ImportOptions) (ImportResult, error) {
+	res := ImportResult{DryRun: opts.DryRun, Skipped: []string{}}
+	if s.cfg.ReadOnly && !opts.DryRun {
+		return res, ErrReadOnly
+	}
+	dec := json.NewDecoder(bufio.NewReader(r))
+	batch := make([]types.MemoryRecord, 0, importBatchSize)
+	flush := func() error {
+		recs, err := s.prepareImport(batch, res.Read-len(batch)+1)
+		if err != nil {
+			return err
+		}
+		batch = batch[:0]
+		if opts.DryRun {
+			return nil
+		}
+		stored, skipped, err := s.store.ImportBatch(ctx, recs)
+		if err != nil {
+			return err
+		}
+		s.imported(ctx, stored)
+		res.Imported += len(stored)
+		res.Skipped = append(res.Skipped, skipped...)
+		return nil
+	}
+	for {
+		var rec types.MemoryRecord
+		if err := dec.Decode(&rec); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return res, fmt.Errorf("record %d: %w", res.Read+1, err)
+		}
+		res.Read++
+		rec.Namespace = remapNamespace(rec.Namespace, opts.Remaps)
+		batch = append(batch, rec)
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return res, err
+			}
+		}
+	}
+	if len(batch) > 0 {
+		if err := flush(); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
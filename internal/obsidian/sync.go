@@ -0,0 +1,266 @@
+package obsidian
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// Source lists memories to mirror into the vault.
+type Source interface {
+	ListByScope(ctx context.Context, scope string) ([]types.MemoryRecord, error)
+}
+
+// Options control where notes are written.
+type Options struct {
+	// VaultDir is the root of the Obsidian vault.
+	VaultDir string
+	// Folder is the vault-relative folder owned by the sync. Notes in it that
+	// no longer correspond to a mirrored memory are removed.
+	Folder string
+}
+
+// Result summarizes one sync pass.
+type Result struct {
+	Written   int `json:"written"`
+	Unchanged int `json:"unchanged"`
+	Removed   int `json:"removed"`
+}
+
+// mirroredScopes are the scopes whose memories outlive a task and so get a
+// note.
+var mirroredScopes = []string{"long", "project", "global"}
+
+type noteFrontmatter struct {
+	ID        string `yaml:"memory_id"`
+	Namespace string `yaml:"namespace"`
+	// Scope is left out for long-term memories, so their notes stay as
+	// they were before wider scopes existed.
+	Scope       string   `yaml:"scope,omitempty"`
+	Importance  int      `yaml:"importance"`
+	SourceAgent string   `yaml:"source_agent,omitempty"`
+	Created     string   `yaml:"created"`
+	Promoted    string   `yaml:"promoted,omitempty"`
+	Tags        []string `yaml:"tags"`
+	Aliases     []string `yaml:"aliases,omitempty"`
+}
+
+// Sync mirrors every long-term, project and global memory into
+// <vault>/<folder>/<namespace>/<id>.md.
+// The sync is one-way: edits made in the vault are overwritten on the next pass.
+func Sync(ctx context.Context, src Source, opts Options) (Result, error) {
+	var res Result
+	if strings.TrimSpace(opts.VaultDir) == "" {
+		return res, errors.New("obsidian vault directory is required")
+	}
+	if strings.TrimSpace(opts.Folder) == "" {
+		opts.Folder = "memory-mcp"
+	}
+	root := filepath.Join(opts.VaultDir, filepath.FromSlash(opts.Folder))
+
+	var recs []types.MemoryRecord
+	for _, scope := range mirroredScopes {
+		scoped, err := src.ListByScope(ctx, scope)
+		if err != nil {
+			return res, err
+		}
+		recs = append(recs, scoped...)
+	}
+
+	keep := make(map[string]struct{}, len(recs))
+	for _, rec := range recs {
+		path, ok := notePath(root, rec)
+		if !ok {
+			continue
+		}
+		keep[path] = struct{}{}
+
+		body, err := renderNote(rec)
+		if err != nil {
+			return res, err
+		}
+		if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, body) {
+			res.Unchanged++
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return res, fmt.Errorf("mkdir note dir: %w", err)
+		}
+		if err := os.WriteFile(path, body, 0o644); err != nil {
+			return res, fmt.Errorf("write note: %w", err)
+		}
+		res.Written++
+	}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".md" {
+			return nil
+		}
+		if _, ok := keep[path]; ok {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("remove stale note: %w", err)
+		}
+		res.Removed++
+		return nil
+	})
+	return res, err
+}
+
+// Start runs Sync every interval until ctx is cancelled.
+func Start(ctx context.Context, logger *log.Logger, interval time.Duration, src Source, opts Options) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			res, err := Sync(ctx, src, opts)
+			if err != nil {
+				logger.Warn("obsidian sync failed", "error", err)
+				continue
+			}
+			if res.Written > 0 || res.Removed > 0 {
+				logger.Info("obsidian sync updated vault", "written", res.Written, "removed", res.Removed)
+			}
+		}
+	}
+}
+
+func notePath(root string, rec types.MemoryRecord) (string, bool) {
+	for _, seg := range strings.Split(rec.Namespace, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return "", false
+		}
+	}
+	if rec.ID == "" || strings.ContainsAny(rec.ID, `/\`) {
+		return "", false
+	}
+	return filepath.Join(root, filepath.FromSlash(rec.Namespace), rec.ID+".md"), true
+}
+
+func renderNote(rec types.MemoryRecord) ([]byte, error) {
+	fm := noteFrontmatter{
+		ID:          rec.ID,
+		Namespace:   rec.Namespace,
+		Importance:  rec.Importance,
+		SourceAgent: rec.SourceAgent,
+		Created:     rec.CreatedAt.UTC().Format(time.RFC3339),
+		Tags:        noteTags(rec),
+	}
+	if rec.Scope != "long" {
+		fm.Scope = rec.Scope
+	}
+	if rec.PromotedAt != nil {
+		fm.Promoted = rec.PromotedAt.UTC().Format(time.RFC3339)
+	}
+	if s := strings.TrimSpace(rec.Summary); s != "" {
+		fm.Aliases = []string{s}
+	}
+	header, err := yaml.Marshal(fm)
+	if err != nil {
+		return nil, fmt.Errorf("marshal note frontmatter: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	buf.Write(header)
+	buf.WriteString("---\n\n")
+	if s := strings.TrimSpace(rec.Summary); s != "" {
+		fmt.Fprintf(&buf, "# %s\n\n", s)
+	}
+	buf.WriteString(strings.TrimSpace(rec.Content))
+	buf.WriteString("\n")
+
+	if links := noteLinks(rec); len(links) > 0 {
+		buf.WriteString("\n## Links\n\n")
+		for _, id := range links {
+			fmt.Fprintf(&buf, "- [[%s]]\n", id)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// noteTags converts the namespace and metadata tags into Obsidian tags,
+// which may not contain whitespace.
+func noteTags(rec types.MemoryRecord) []string {
+	tags := []string{"memory-mcp", "ns/" + rec.Namespace}
+	seen := map[string]struct{}{}
+	for _, t := range tags {
+		seen[t] = struct{}{}
+	}
+	for _, raw := range stringList(rec.Metadata["tags"]) {
+		t := strings.Join(strings.Fields(strings.TrimPrefix(raw, "#")), "-")
+		if t == "" {
+			continue
+		}
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		tags = append(tags, t)
+	}
+	return tags
+}
+
+// noteLinks collects memory IDs referenced from metadata; notes are named by
+// ID, so wikilinks resolve to the mirrored note.
+func noteLinks(rec types.MemoryRecord) []string {
+	seen := map[string]struct{}{}
+	var out []string
+	for _, key := range []string{"links", "related"} {
+		for _, id := range stringList(rec.Metadata[key]) {
+			id = strings.TrimSpace(id)
+			if id == "" || id == rec.ID {
+				continue
+			}
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			out = append(out, id)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func stringList(v any) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	case []any:
+		out := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,107 @@
+package obsidian
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+type staticSource []types.MemoryRecord
+
+func (s staticSource) ListByScope(_ context.Context, scope string) ([]types.MemoryRecord, error) {
+	var out []types.MemoryRecord
+	for _, rec := range s {
+		if rec.Scope == scope {
+			out = append(out, rec)
+		}
+	}
+	return out, nil
+}
+
+func TestSync_WritesNotesAndRemovesStale(t *testing.T) {
+	t.Parallel()
+	vault := t.TempDir()
+	now := time.Date(2026, 2, 17, 10, 0, 0, 0, time.UTC)
+	src := staticSource{
+		{
+			ID:         "m-1",
+			Namespace:  "org/repo/task",
+			Scope:      "long",
+			Content:    "Use WAL mode.",
+			Summary:    "WAL decision",
+			Importance: 5,
+			Metadata:   map[string]any{"tags": []any{"decision", "sqlite storage"}, "related": []any{"m-2"}},
+			CreatedAt:  now,
+		},
+		{ID: "m-short", Namespace: "org/repo/task", Scope: "short", Content: "scratch", CreatedAt: now},
+	}
+
+	stale := filepath.Join(vault, "memory-mcp", "org", "old", "gone.md")
+	if err := os.MkdirAll(filepath.Dir(stale), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stale, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Sync(context.Background(), src, Options{VaultDir: vault})
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if res.Written != 1 || res.Removed != 1 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+
+	note, err := os.ReadFile(filepath.Join(vault, "memory-mcp", "org", "repo", "task", "m-1.md"))
+	if err != nil {
+		t.Fatalf("expected note for long-term memory: %v", err)
+	}
+	for _, want := range []string{"ns/org/repo/task", "- decision", "- sqlite-storage", "[[m-2]]", "# WAL decision"} {
+		if !strings.Contains(string(note), want) {
+			t.Fatalf("expected note to contain %q:\n%s", want, note)
+		}
+	}
+
+	res, err = Sync(context.Background(), src, Options{VaultDir: vault})
+	if err != nil {
+		t.Fatalf("Sync(second) error = %v", err)
+	}
+	if res.Written != 0 || res.Unchanged != 1 {
+		t.Fatalf("expected idempotent second pass, got %+v", res)
+	}
+}
+
+func TestSync_MirrorsProjectAndGlobalMemories(t *testing.T) {
+	t.Parallel()
+	vault := t.TempDir()
+	now := time.Date(2026, 2, 17, 10, 0, 0, 0, time.UTC)
+	src := staticSource{
+		{ID: "m-project", Namespace: "org/repo", Scope: "project", Content: "Releases are cut on Tuesdays.", CreatedAt: now},
+		{ID: "m-global", Namespace: "org", Scope: "global", Content: "Never force-push main.", CreatedAt: now},
+	}
+
+	res, err := Sync(context.Background(), src, Options{VaultDir: vault})
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if res.Written != 2 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	note, err := os.ReadFile(filepath.Join(vault, "memory-mcp", "org", "repo", "m-project.md"))
+	if err != nil {
+		t.Fatalf("expected note for project-scoped memory: %v", err)
+	}
+	for _, want := range []string{"scope: project", "Releases are cut on Tuesdays."} {
+		if !strings.Contains(string(note), want) {
+			t.Fatalf("expected note to contain %q:\n%s", want, note)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(vault, "memory-mcp", "org", "m-global.md")); err != nil {
+		t.Fatalf("expected note for global memory: %v", err)
+	}
+}
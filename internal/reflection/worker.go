@@ -0,0 +1,35 @@
+package reflection
+
+import (
+	"context"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// Reflector represents the insight generation needed by the worker.
+type Reflector interface {
+	ReflectAll(ctx context.Context) (int, error)
+}
+
+// Start launches a periodic reflection worker.
+func Start(ctx context.Context, logger *log.Logger, interval time.Duration, reflector Reflector) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := reflector.ReflectAll(ctx)
+			if err != nil {
+				logger.Warn("reflection failed", "error", err)
+				continue
+			}
+			if n > 0 {
+				logger.Info("reflection wrote insights", "count", n)
+			}
+		}
+	}
+}
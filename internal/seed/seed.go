@@ -0,0 +1,249 @@
+// Package seed fills a store with realistic fake memories for demos and for
+// exercising ranking changes without waiting on real agent traffic.
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/memory"
+	"github.com/xiy/memory-mcp/internal/store"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// DefaultNamespaces are used when Options.Namespaces is empty.
+var DefaultNamespaces = []string{
+	"acme/api/main/build-loop",
+	"acme/web/main/ui-refresh",
+	"acme/infra/main/release",
+}
+
+// Options control a seeding run.
+type Options struct {
+	Count      int
+	Namespaces []string
+	// MaxAge bounds how far back long-term memories are dated. Short-term
+	// memories always fall inside the configured TTL so they stay live.
+	MaxAge time.Duration
+	Seed   int64
+	// Now anchors generated timestamps; the wall clock is used when zero.
+	Now time.Time
+}
+
+// Result counts what Run wrote.
+type Result struct {
+	Written     int            `json:"written"`
+	Promoted    int            `json:"promoted"`
+	ByNamespace map[string]int `json:"by_namespace"`
+}
+
+// plan is one memory to write, dated before it is written.
+type plan struct {
+	at         time.Time
+	promoteAt  time.Time
+	namespace  string
+	scope      string
+	importance int
+	content    string
+	tags       []string
+	agent      string
+}
+
+// Run generates opts.Count memories and stores them in a single batch
+// through the memory service, so IDs, summaries and TTLs follow the same
+// rules as agent writes. Roughly a quarter are long-term from the start and
+// another sixth are short-term memories that were later promoted.
+func Run(ctx context.Context, st store.Store, cfg config.Config, logger *log.Logger, opts Options) (Result, error) {
+	if opts.Count <= 0 {
+		return Result{}, errors.New("count must be > 0")
+	}
+	if len(opts.Namespaces) == 0 {
+		opts.Namespaces = DefaultNamespaces
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = 30 * 24 * time.Hour
+	}
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	svc, err := memory.NewService(st, cfg, logger)
+	if err != nil {
+		return Result{}, err
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	ttl := time.Duration(cfg.DefaultShortTTLHours) * time.Hour
+	plans := make([]plan, opts.Count)
+	for i := range plans {
+		plans[i] = newPlan(rng, opts, ttl)
+	}
+	// Oldest first so time-ordered IDs match creation order.
+	sort.Slice(plans, func(i, j int) bool { return plans[i].at.Before(plans[j].at) })
+
+	recs := make([]types.MemoryRecord, 0, len(plans))
+	for _, p := range plans {
+		rec := types.MemoryRecord{
+			Namespace:      p.namespace,
+			Scope:          p.scope,
+			Content:        p.content,
+			Importance:     p.importance,
+			SourceAgent:    p.agent,
+			Metadata:       map[string]any{"tags": p.tags, "seeded": true},
+			CreatedAt:      p.at.UTC(),
+			LastAccessedAt: p.at.UTC(),
+		}
+		if !p.promoteAt.IsZero() {
+			promoted := p.promoteAt.UTC()
+			rec.Scope = "long"
+			rec.PromotedAt = &promoted
+			rec.LastAccessedAt = promoted
+		}
+		recs = append(recs, rec)
+	}
+	stored, err := svc.Import(ctx, recs)
+	if err != nil {
+		return Result{}, fmt.Errorf("write seed memories: %w", err)
+	}
+	// Count what was stored: content-addressed IDs keep one record per
+	// distinct content.
+	res := Result{Written: len(stored), ByNamespace: map[string]int{}}
+	for _, rec := range stored {
+		if rec.PromotedAt != nil {
+			res.Promoted++
+		}
+		res.ByNamespace[rec.Namespace]++
+	}
+	return res, nil
+}
+
+func newPlan(rng *rand.Rand, opts Options, ttl time.Duration) plan {
+	k := kinds[rng.Intn(len(kinds))]
+	p := plan{
+		namespace:  opts.Namespaces[rng.Intn(len(opts.Namespaces))],
+		importance: k.importance[rng.Intn(len(k.importance))],
+		content:    k.render(rng),
+		tags:       []string{k.tag, pick(rng, components).tag},
+		agent:      pick(rng, agents),
+	}
+
+	switch r := rng.Float64(); {
+	case r < 0.25:
+		p.scope = "long"
+		p.at = opts.Now.Add(-skewedAge(rng, opts.MaxAge))
+	case r < 0.40:
+		p.scope = "short"
+		p.at = opts.Now.Add(-skewedAge(rng, opts.MaxAge))
+		// Promoted somewhere between a few minutes and a day after writing.
+		delay := time.Duration(5+rng.Intn(24*60)) * time.Minute
+		p.promoteAt = minTime(p.at.Add(delay), opts.Now)
+	default:
+		p.scope = "short"
+		if ttl > 0 {
+			p.at = opts.Now.Add(-time.Duration(rng.Int63n(int64(ttl))))
+		} else {
+			p.at = opts.Now
+		}
+	}
+	return p
+}
+
+// skewedAge favours recent timestamps: most memories are from the last few
+// days with a long tail back to maxAge.
+func skewedAge(rng *rand.Rand, maxAge time.Duration) time.Duration {
+	f := rng.Float64()
+	return time.Duration(f * f * float64(maxAge))
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func pick[T any](rng *rand.Rand, xs []T) T {
+	return xs[rng.Intn(len(xs))]
+}
+
+type component struct {
+	name string
+	tag  string
+}
+
+var components = []component{
+	{"the SQLite store", "storage"},
+	{"the TTL worker", "ttl"},
+	{"the context pack builder", "context-pack"},
+	{"the auth middleware", "auth"},
+	{"the CI pipeline", "ci"},
+	{"the release script", "release"},
+	{"the admin dashboard", "admin"},
+	{"the search ranking", "ranking"},
+}
+
+var agents = []string{"codex", "claude", "gemini"}
+
+var (
+	actions = []string{
+		"enable WAL mode", "retry with exponential backoff", "pin the Go toolchain",
+		"cache search results for 30s", "batch inserts in a transaction",
+		"log request IDs on every error", "split the handler into smaller functions",
+		"move config parsing behind an interface",
+	}
+	reasons = []string{
+		"p95 latency doubled under load", "agents kept rewriting the same notes",
+		"a flaky test was hiding a race", "reviewers asked for reproducible builds",
+		"the old approach leaked file handles", "on-call could not trace failures",
+	}
+	failures = []string{
+		"timeout after 30s", "database is locked", "nil pointer dereference in the handler",
+		"token budget exceeded", "stale cache served after deploy", "panic on empty namespace",
+	}
+	fixes = []string{
+		"adding a busy timeout", "guarding the nil case", "invalidating the cache on write",
+		"trimming low-importance items first", "validating input at the boundary",
+	}
+)
+
+type kind struct {
+	tag        string
+	importance []int
+	render     func(*rand.Rand) string
+}
+
+var kinds = []kind{
+	{"decision", []int{3, 4, 4, 5}, func(rng *rand.Rand) string {
+		return fmt.Sprintf("Decided to %s in %s because %s.", pick(rng, actions), pick(rng, components).name, pick(rng, reasons))
+	}},
+	{"bug", []int{2, 3, 3, 4}, func(rng *rand.Rand) string {
+		return fmt.Sprintf("%s hit %q; fixed by %s.", capitalize(pick(rng, components).name), pick(rng, failures), pick(rng, fixes))
+	}},
+	{"convention", []int{3, 4, 5}, func(rng *rand.Rand) string {
+		return fmt.Sprintf("Convention: always %s when touching %s.", pick(rng, actions), pick(rng, components).name)
+	}},
+	{"todo", []int{1, 2, 2, 3}, func(rng *rand.Rand) string {
+		return fmt.Sprintf("TODO: %s in %s before the next release.", pick(rng, actions), pick(rng, components).name)
+	}},
+	{"observation", []int{1, 2, 3}, func(rng *rand.Rand) string {
+		return fmt.Sprintf("Noticed %s after touching %s; %s.", pick(rng, failures), pick(rng, components).name, pick(rng, reasons))
+	}},
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
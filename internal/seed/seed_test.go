@@ -0,0 +1,87 @@
+package seed
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/config"
+	"github.com/xiy/memory-mcp/internal/store"
+)
+
+func TestRun_SeedsVariedMemories(t *testing.T) {
+	t.Parallel()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	ctx := context.Background()
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "seed.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	res, err := Run(ctx, st, config.Default(), logger, Options{Count: 200, Seed: 7, Now: now})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if res.Written != 200 || res.Promoted == 0 || len(res.ByNamespace) != len(DefaultNamespaces) {
+		t.Fatalf("unexpected result %+v", res)
+	}
+
+	long, err := st.ListByScope(ctx, "long")
+	if err != nil {
+		t.Fatalf("ListByScope() error = %v", err)
+	}
+	if len(long) == 0 || len(long) == 200 {
+		t.Fatalf("expected a mix of scopes, got %d long", len(long))
+	}
+	oldest := long[0].CreatedAt
+	if !oldest.Before(now.Add(-48 * time.Hour)) {
+		t.Fatalf("expected backdated long-term memories, oldest is %s", oldest)
+	}
+
+	stats, err := st.Stats(ctx, now)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.Expired != 0 {
+		t.Fatalf("expected seeded short-term memories to be live, %d expired", stats.Expired)
+	}
+}
+
+func TestRun_CountsOnlyStoredMemoriesWithContentAddressedIDs(t *testing.T) {
+	t.Parallel()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	ctx := context.Background()
+	st, err := store.OpenSQLite(ctx, filepath.Join(t.TempDir(), "seed.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+
+	cfg := config.Default()
+	cfg.IDFormat = "content"
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	res, err := Run(ctx, st, cfg, logger, Options{Count: 2000, Seed: 7, Now: now})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	stats, err := st.Stats(ctx, now)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if res.Written >= 2000 || int64(res.Written) != stats.Total {
+		t.Fatalf("Run() wrote %d, store holds %d; want repeated content stored and counted once", res.Written, stats.Total)
+	}
+	sum := 0
+	for _, n := range res.ByNamespace {
+		sum += n
+	}
+	if sum != res.Written {
+		t.Fatalf("ByNamespace sums to %d, want %d", sum, res.Written)
+	}
+}
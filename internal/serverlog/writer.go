@@ -0,0 +1,161 @@
+// Package serverlog persists the server's log events so the admin dashboard
+// can show them, including from a later session.
+package serverlog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/store"
+)
+
+const (
+	// queueSize bounds events waiting to be stored. Events logged while the
+	// queue is full are dropped from the table but still reach the output.
+	queueSize = 256
+	batchSize = 64
+)
+
+// Sink stores batches of log events.
+type Sink interface {
+	InsertServerLogs(ctx context.Context, logs []store.ServerLog) error
+}
+
+// Writer passes log output through to another writer and queues INFO and
+// higher lines in charmbracelet/log's text format for a Sink. Events are
+// stored from a separate goroutine because the store logs while holding
+// its write connection.
+type Writer struct {
+	out    io.Writer
+	prefix string
+	queue  chan store.ServerLog
+	done   chan struct{}
+
+	mu      sync.Mutex
+	closed  bool
+	started bool
+}
+
+// New returns a Writer that forwards to out. prefix is the logger's
+// prefix, which is left out of stored messages. Events queue until Start.
+func New(out io.Writer, prefix string) *Writer {
+	return &Writer{
+		out:    out,
+		prefix: prefix,
+		queue:  make(chan store.ServerLog, queueSize),
+		done:   make(chan struct{}),
+	}
+}
+
+// Write implements io.Writer.
+func (w *Writer) Write(p []byte) (int, error) {
+	n, err := w.out.Write(p)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return n, err
+	}
+	now := time.Now().UTC()
+	for _, line := range strings.Split(string(p), "\n") {
+		entry, ok := parseLine(line, w.prefix)
+		if !ok {
+			continue
+		}
+		entry.CreatedAt = now
+		select {
+		case w.queue <- entry:
+		default:
+		}
+	}
+	return n, err
+}
+
+// Start begins storing queued and future events in sink. It may be called
+// at most once.
+func (w *Writer) Start(sink Sink) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.started || w.closed {
+		return
+	}
+	w.started = true
+	go w.run(sink)
+}
+
+// Close stops queueing and, once started, waits for queued events to be
+// stored.
+func (w *Writer) Close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.queue)
+	started := w.started
+	w.mu.Unlock()
+	if started {
+		<-w.done
+	}
+}
+
+func (w *Writer) run(sink Sink) {
+	defer close(w.done)
+	for entry := range w.queue {
+		batch := []store.ServerLog{entry}
+	drain:
+		for len(batch) < batchSize {
+			select {
+			case next, ok := <-w.queue:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, next)
+			default:
+				break drain
+			}
+		}
+		if err := sink.InsertServerLogs(context.Background(), batch); err != nil {
+			fmt.Fprintf(w.out, "serverlog: %v\n", err)
+		}
+	}
+}
+
+var levels = map[string]string{
+	"INFO": "info",
+	"WARN": "warn",
+	"ERRO": "error",
+	"FATA": "fatal",
+}
+
+// parseLine extracts the level and message from a line such as
+// "WARN memory-mcp: fts query failed error=...", stripping the logger
+// prefix. A leading timestamp is skipped. DEBUG and unrecognized lines are
+// dropped.
+func parseLine(line, prefix string) (store.ServerLog, bool) {
+	rest := strings.TrimSpace(line)
+	level := ""
+	for range 3 {
+		var token string
+		token, rest, _ = strings.Cut(rest, " ")
+		if l, ok := levels[token]; ok {
+			level = l
+			break
+		}
+	}
+	if level == "" {
+		return store.ServerLog{}, false
+	}
+	rest = strings.TrimSpace(rest)
+	if prefix != "" {
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, prefix+":"))
+	}
+	if rest == "" {
+		return store.ServerLog{}, false
+	}
+	return store.ServerLog{Level: level, Message: rest}, true
+}
@@ -0,0 +1,74 @@
+package serverlog
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/store"
+)
+
+type fakeSink struct {
+	mu   sync.Mutex
+	logs []store.ServerLog
+}
+
+func (f *fakeSink) InsertServerLogs(_ context.Context, logs []store.ServerLog) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.logs = append(f.logs, logs...)
+	return nil
+}
+
+func TestWriter_StoresInfoAndAboveAfterStart(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	w := New(&out, "memory-mcp")
+	logger := log.NewWithOptions(w, log.Options{Prefix: "memory-mcp", Level: log.DebugLevel})
+
+	logger.Warn("FTS5 disabled; falling back to LIKE queries", "error", "no such module: fts5")
+	logger.Debug("noisy detail")
+	sink := &fakeSink{}
+	w.Start(sink)
+	logger.Info("ttl cleanup removed expired short memories", "count", 3)
+	w.Close()
+	logger.Error("after close")
+
+	if !strings.Contains(out.String(), "noisy detail") || !strings.Contains(out.String(), "after close") {
+		t.Fatalf("output not passed through: %q", out.String())
+	}
+	if len(sink.logs) != 2 {
+		t.Fatalf("stored %d events, want 2: %+v", len(sink.logs), sink.logs)
+	}
+	if got := sink.logs[0]; got.Level != "warn" || got.Message != `FTS5 disabled; falling back to LIKE queries error="no such module: fts5"` || got.CreatedAt.IsZero() {
+		t.Fatalf("first event = %+v", got)
+	}
+	if got := sink.logs[1]; got.Level != "info" || got.Message != "ttl cleanup removed expired short memories count=3" {
+		t.Fatalf("second event = %+v", got)
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		line, level, msg string
+	}{
+		{"ERRO memory-mcp: boom n=3", "error", "boom n=3"},
+		{"INFO hello: world", "info", "hello: world"},
+		{"2026/10/15 10:00:00 WARN memory-mcp: slow", "warn", "slow"},
+		{"DEBU memory-mcp: detail", "", ""},
+		{"not a log line", "", ""},
+	}
+	for _, tc := range cases {
+		got, ok := parseLine(tc.line, "memory-mcp")
+		if ok != (tc.level != "") || got.Level != tc.level || got.Message != tc.msg {
+			t.Fatalf("parseLine(%q) = %+v, %v", tc.line, got, ok)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+// Package service installs `memory-mcp serve` as a per-user background
+// service, a launchd agent on macOS or a systemd user unit on Linux, that
+// listens on a socket so agent CLIs share one server.
+package service
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const (
+	// Label names the launchd agent.
+	Label = "io.github.xiy.memory-mcp"
+	// Unit names the systemd user unit.
+	Unit = "memory-mcp.service"
+)
+
+// Options describe the service to install.
+type Options struct {
+	// GOOS selects launchd ("darwin") or systemd ("linux").
+	GOOS string
+	// Home is the user's home directory.
+	Home string
+	// UID is the user's ID, for launchctl's gui/<uid> domain.
+	UID int
+	// Binary is the absolute path of the memory-mcp executable.
+	Binary     string
+	ConfigPath string
+	Listen     string
+	// LogPath receives the server's stderr under launchd; systemd sends
+	// it to the journal.
+	LogPath string
+}
+
+// Command captures an executable command.
+type Command struct {
+	Name string
+	Args []string
+}
+
+func (c Command) String() string {
+	return c.Name + " " + strings.Join(c.Args, " ")
+}
+
+// Runner executes system commands.
+type Runner interface {
+	Run(name string, args ...string) error
+}
+
+// Result reports what Install or Uninstall did.
+type Result struct {
+	UnitPath string   `json:"unit_path"`
+	Listen   string   `json:"listen,omitempty"`
+	Commands []string `json:"commands"`
+}
+
+// UnitPath returns where the launchd plist or systemd unit is installed.
+func UnitPath(opts Options) (string, error) {
+	switch opts.GOOS {
+	case "darwin":
+		return filepath.Join(opts.Home, "Library", "LaunchAgents", Label+".plist"), nil
+	case "linux":
+		dir := os.Getenv("XDG_CONFIG_HOME")
+		if dir == "" {
+			dir = filepath.Join(opts.Home, ".config")
+		}
+		return filepath.Join(dir, "systemd", "user", Unit), nil
+	default:
+		return "", fmt.Errorf("service install is not supported on %s (expected darwin or linux)", opts.GOOS)
+	}
+}
+
+// Render returns the launchd plist or systemd unit for opts.
+func Render(opts Options) ([]byte, error) {
+	if opts.Binary == "" || opts.ConfigPath == "" || opts.Listen == "" {
+		return nil, errors.New("binary, config path and listen address are required")
+	}
+	data := struct {
+		Options
+		Label string
+		Args  []string
+	}{opts, Label, []string{opts.Binary, "serve", "--config", opts.ConfigPath, "--listen", opts.Listen}}
+	tmpl := systemdUnit
+	if opts.GOOS == "darwin" {
+		tmpl = launchdPlist
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var funcs = template.FuncMap{
+	"xml": func(s string) (string, error) {
+		var b strings.Builder
+		err := xml.EscapeText(&b, []byte(s))
+		return b.String(), err
+	},
+	"exec": func(args []string) string {
+		quoted := make([]string, len(args))
+		for i, a := range args {
+			quoted[i] = strings.ReplaceAll(strconv.Quote(a), "%", "%%")
+		}
+		return strings.Join(quoted, " ")
+	},
+}
+
+var launchdPlist = template.Must(template.New("plist").Funcs(funcs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>{{.Label}}</string>
+	<key>ProgramArguments</key>
+	<array>
+{{- range .Args}}
+		<string>{{xml .}}</string>
+{{- end}}
+	</array>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<true/>
+{{- if .LogPath}}
+	<key>StandardErrorPath</key>
+	<string>{{xml .LogPath}}</string>
+{{- end}}
+</dict>
+</plist>
+`))
+
+var systemdUnit = template.Must(template.New("unit").Funcs(funcs).Parse(`[Unit]
+Description=memory-mcp shared memory server
+
+[Service]
+ExecStart={{exec .Args}}
+Restart=on-failure
+RestartSec=2
+
+[Install]
+WantedBy=default.target
+`))
+
+// InstallCommands load the installed unit, replacing a running instance.
+func InstallCommands(opts Options, unitPath string) []Command {
+	if opts.GOOS == "darwin" {
+		return []Command{
+			{Name: "launchctl", Args: []string{"bootout", launchdTarget(opts)}},
+			{Name: "launchctl", Args: []string{"bootstrap", launchdDomain(opts), unitPath}},
+		}
+	}
+	return []Command{
+		{Name: "systemctl", Args: []string{"--user", "daemon-reload"}},
+		{Name: "systemctl", Args: []string{"--user", "enable", Unit}},
+		{Name: "systemctl", Args: []string{"--user", "restart", Unit}},
+	}
+}
+
+// UninstallCommands stop the service before its unit is removed.
+func UninstallCommands(opts Options) []Command {
+	if opts.GOOS == "darwin" {
+		return []Command{{Name: "launchctl", Args: []string{"bootout", launchdTarget(opts)}}}
+	}
+	return []Command{{Name: "systemctl", Args: []string{"--user", "disable", "--now", Unit}}}
+}
+
+// StatusCommand prints the service manager's view of the service.
+func StatusCommand(opts Options) Command {
+	if opts.GOOS == "darwin" {
+		return Command{Name: "launchctl", Args: []string{"print", launchdTarget(opts)}}
+	}
+	return Command{Name: "systemctl", Args: []string{"--user", "status", "--no-pager", Unit}}
+}
+
+func launchdDomain(opts Options) string { return "gui/" + strconv.Itoa(opts.UID) }
+
+func launchdTarget(opts Options) string { return launchdDomain(opts) + "/" + Label }
+
+// Install writes the unit for opts and loads it. With dryRun the unit is
+// neither written nor loaded and Result lists the planned commands.
+func Install(opts Options, runner Runner, dryRun bool) (Result, error) {
+	path, err := UnitPath(opts)
+	if err != nil {
+		return Result{}, err
+	}
+	unit, err := Render(opts)
+	if err != nil {
+		return Result{}, err
+	}
+	res := Result{UnitPath: path, Listen: opts.Listen, Commands: []string{}}
+	cmds := InstallCommands(opts, path)
+	if dryRun {
+		for _, c := range cmds {
+			res.Commands = append(res.Commands, c.String())
+		}
+		return res, nil
+	}
+	if opts.LogPath != "" {
+		if err := os.MkdirAll(filepath.Dir(opts.LogPath), 0o755); err != nil {
+			return res, err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return res, err
+	}
+	if err := os.WriteFile(path, unit, 0o644); err != nil {
+		return res, fmt.Errorf("write %s: %w", path, err)
+	}
+	for i, c := range cmds {
+		res.Commands = append(res.Commands, c.String())
+		if err := runner.Run(c.Name, c.Args...); err != nil {
+			// bootout fails when the agent was not loaded yet.
+			if opts.GOOS == "darwin" && i == 0 {
+				continue
+			}
+			return res, fmt.Errorf("run %q: %w", c.String(), err)
+		}
+	}
+	return res, nil
+}
+
+// Uninstall stops the service and removes its unit. A service that is not
+// installed is not an error.
+func Uninstall(opts Options, runner Runner, dryRun bool) (Result, error) {
+	path, err := UnitPath(opts)
+	if err != nil {
+		return Result{}, err
+	}
+	res := Result{UnitPath: path, Commands: []string{}}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return res, nil
+	}
+	cmds := UninstallCommands(opts)
+	for _, c := range cmds {
+		res.Commands = append(res.Commands, c.String())
+		if dryRun {
+			continue
+		}
+		// Stopping fails when the service is not running; removing the
+		// unit is what matters.
+		_ = runner.Run(c.Name, c.Args...)
+	}
+	if dryRun {
+		return res, nil
+	}
+	if err := os.Remove(path); err != nil {
+		return res, err
+	}
+	if opts.GOOS == "linux" {
+		reload := Command{Name: "systemctl", Args: []string{"--user", "daemon-reload"}}
+		res.Commands = append(res.Commands, reload.String())
+		if err := runner.Run(reload.Name, reload.Args...); err != nil {
+			return res, fmt.Errorf("run %q: %w", reload.String(), err)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type recordRunner struct {
+	cmds []string
+}
+
+func (r *recordRunner) Run(name string, args ...string) error {
+	r.cmds = append(r.cmds, name+" "+strings.Join(args, " "))
+	return nil
+}
+
+func TestRender_QuotesArguments(t *testing.T) {
+	t.Parallel()
+	opts := Options{GOOS: "linux", Binary: "/opt/memory mcp/memory-mcp", ConfigPath: "/home/a/100%.yaml", Listen: "unix:/home/a/m.sock"}
+	unit, err := Render(opts)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	want := `ExecStart="/opt/memory mcp/memory-mcp" "serve" "--config" "/home/a/100%%.yaml" "--listen" "unix:/home/a/m.sock"`
+	if !strings.Contains(string(unit), want+"\n") {
+		t.Fatalf("systemd unit missing %s:\n%s", want, unit)
+	}
+
+	opts.GOOS = "darwin"
+	opts.ConfigPath = "/Users/a/a&b.yaml"
+	opts.LogPath = "/Users/a/.memory-mcp/service.log"
+	plist, err := Render(opts)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	for _, want := range []string{"<string>/Users/a/a&amp;b.yaml</string>", "<string>" + Label + "</string>", "<key>StandardErrorPath</key>"} {
+		if !strings.Contains(string(plist), want) {
+			t.Fatalf("plist missing %s:\n%s", want, plist)
+		}
+	}
+
+	if _, err := Render(Options{GOOS: "linux", Binary: "/bin/memory-mcp"}); err == nil {
+		t.Fatal("Render() without config and listen error = nil")
+	}
+}
+
+func TestInstallAndUninstall_Systemd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", "")
+	opts := Options{GOOS: "linux", Home: home, Binary: "/bin/memory-mcp", ConfigPath: "/etc/m.yaml", Listen: "unix:/tmp/m.sock"}
+
+	runner := &recordRunner{}
+	res, err := Install(opts, runner, true)
+	if err != nil {
+		t.Fatalf("Install(dry run) error = %v", err)
+	}
+	if len(runner.cmds) != 0 || len(res.Commands) != 3 {
+		t.Fatalf("dry run ran %v and planned %v, want nothing run and 3 planned", runner.cmds, res.Commands)
+	}
+	if _, err := os.Stat(res.UnitPath); !os.IsNotExist(err) {
+		t.Fatalf("dry run wrote %s", res.UnitPath)
+	}
+
+	res, err = Install(opts, runner, false)
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if want := filepath.Join(home, ".config", "systemd", "user", Unit); res.UnitPath != want {
+		t.Fatalf("UnitPath = %s, want %s", res.UnitPath, want)
+	}
+	if _, err := os.Stat(res.UnitPath); err != nil {
+		t.Fatalf("unit not written: %v", err)
+	}
+	if runner.cmds[len(runner.cmds)-1] != "systemctl --user restart "+Unit {
+		t.Fatalf("install ran %v, want a restart last", runner.cmds)
+	}
+
+	runner.cmds = nil
+	if _, err := Uninstall(opts, runner, false); err != nil {
+		t.Fatalf("Uninstall() error = %v", err)
+	}
+	if _, err := os.Stat(res.UnitPath); !os.IsNotExist(err) {
+		t.Fatalf("unit still present after uninstall: %v", err)
+	}
+	if len(runner.cmds) != 2 || runner.cmds[1] != "systemctl --user daemon-reload" {
+		t.Fatalf("uninstall ran %v", runner.cmds)
+	}
+	res, err = Uninstall(opts, runner, false)
+	if err != nil || len(res.Commands) != 0 {
+		t.Fatalf("Uninstall() of a missing service = %+v, %v, want no commands", res, err)
+	}
+}
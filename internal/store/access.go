@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// AccessRecorder is implemented by stores that can count retrievals
+// without rewriting the rest of a memory.
+type AccessRecorder interface {
+	// RecordAccess adds counts[id] to each memory's access count and sets
+	// its last access to at. Missing memories are skipped.
+	RecordAccess(ctx context.Context, counts map[string]int, at time.Time) error
+}
+
+// RecordAccess updates access_count and last_accessed_at only, so neither
+// the FTS index nor embeddings are touched.
+func (s *SQLiteStore) RecordAccess(ctx context.Context, counts map[string]int, at time.Time) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	ids := slices.Sorted(maps.Keys(counts))
+	stamp := at.UTC().Format(time.RFC3339Nano)
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, `UPDATE memories
+SET access_count = access_count + ?, last_accessed_at = ?
+WHERE id = ?`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, id := range ids {
+			if _, err := stmt.ExecContext(ctx, counts[id], stamp, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	s.uncacheRecords(ids...)
+	if err != nil {
+		return fmt.Errorf("record memory access: %w", err)
+	}
+	return nil
+}
+
+// RecordAccess implements AccessRecorder. Every shard is updated, since a
+// shard skips the IDs it does not hold.
+func (s *ShardedStore) RecordAccess(ctx context.Context, counts map[string]int, at time.Time) error {
+	for _, st := range s.stores() {
+		if err := st.RecordAccess(ctx, counts, at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Dimensions accepted by CountBy.
+const (
+	GroupNamespace = "namespace"
+	GroupScope     = "scope"
+	GroupAgent     = "agent"
+	// GroupDay buckets by the UTC calendar day of created_at (YYYY-MM-DD).
+	GroupDay = "day"
+)
+
+// GroupCount is one bucket of a CountBy result.
+type GroupCount struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// groupColumn maps a dimension to the SQL expression it groups by.
+func groupColumn(dim string) (string, error) {
+	switch dim {
+	case GroupNamespace:
+		return "namespace", nil
+	case GroupScope:
+		return "scope", nil
+	case GroupAgent:
+		return "source_agent", nil
+	case GroupDay:
+		// created_at is stored as UTC RFC 3339, so the first ten characters
+		// are the UTC date.
+		return "substr(created_at, 1, 10)", nil
+	default:
+		return "", fmt.Errorf("invalid group %q (expected %s, %s, %s or %s)", dim, GroupNamespace, GroupScope, GroupAgent, GroupDay)
+	}
+}
+
+// CountBy counts memories matching f grouped by dim. Day buckets are
+// returned oldest first; other dimensions largest first, ties by key. The
+// filter's Sort, Limit and Offset are ignored.
+func (s *SQLiteStore) CountBy(ctx context.Context, dim string, f ListFilter) ([]GroupCount, error) {
+	col, err := groupColumn(dim)
+	if err != nil {
+		return nil, err
+	}
+	where, args := listWhere(f, s.metaCols)
+	q := "SELECT " + col + " AS k, count(*) AS n FROM memories"
+	if len(where) > 0 {
+		q += "\nWHERE " + strings.Join(where, "\n  AND ")
+	}
+	q += "\nGROUP BY k"
+	if dim == GroupDay {
+		q += "\nORDER BY k ASC"
+	} else {
+		q += "\nORDER BY n DESC, k ASC"
+	}
+
+	rows, err := s.reader.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("count memories by %s: %w", dim, err)
+	}
+	defer rows.Close()
+
+	var groups []GroupCount
+	for rows.Next() {
+		var g GroupCount
+		if err := rows.Scan(&g.Key, &g.Count); err != nil {
+			return nil, fmt.Errorf("scan group count: %w", err)
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// CountBy counts memories matching f grouped by dim, with the same ordering
+// as the SQLite store.
+func (s *MarkdownStore) CountBy(_ context.Context, dim string, f ListFilter) ([]GroupCount, error) {
+	return s.index.countBy(dim, f)
+}
+
+// countBy groups the records matching f by dim for the index-backed stores.
+func (ix *memIndex) countBy(dim string, f ListFilter) ([]GroupCount, error) {
+	if _, err := groupColumn(dim); err != nil {
+		return nil, err
+	}
+	f.Sort, f.Limit, f.Offset = "", 0, 0
+
+	counts := map[string]int64{}
+	for _, rec := range ix.list(f) {
+		var key string
+		switch dim {
+		case GroupNamespace:
+			key = rec.Namespace
+		case GroupScope:
+			key = rec.Scope
+		case GroupAgent:
+			key = rec.SourceAgent
+		case GroupDay:
+			key = rec.CreatedAt.UTC().Format(time.DateOnly)
+		}
+		counts[key]++
+	}
+
+	return groupCounts(dim, counts), nil
+}
+
+// groupCounts turns per-key counts into CountBy's ordering: day buckets
+// oldest first, other dimensions largest first with ties by key.
+func groupCounts(dim string, counts map[string]int64) []GroupCount {
+	groups := make([]GroupCount, 0, len(counts))
+	for k, n := range counts {
+		groups = append(groups, GroupCount{Key: k, Count: n})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if dim != GroupDay && groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	return groups
+}
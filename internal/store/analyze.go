@@ -0,0 +1,68 @@
+package store
+
+import (
+	"strings"
+
+	"github.com/xiy/memory-mcp/internal/lang"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// setLanguage records the dominant language of rec's text. Stores call it
+// on every write so the analyzer follows edits.
+func setLanguage(rec *types.MemoryRecord) {
+	rec.Language = lang.Detect(rec.Content + "\n" + rec.Summary)
+}
+
+// analyzedText is the text searches match a memory by: folded content and
+// summary, stemmed when the memory is English, with CJK runs as n-grams
+// of size n (none when n < 2).
+func analyzedText(language, content, summary string, n int) string {
+	text := searchText(content, summary)
+	if language == lang.English {
+		text = stemText(text)
+	}
+	return ngramText(text, n)
+}
+
+// stemText replaces every a-z word in s with its stem.
+func stemText(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	word := -1
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) && (s[i] >= 'a' && s[i] <= 'z' || s[i] >= '0' && s[i] <= '9' || s[i] >= 0x80) {
+			if word < 0 {
+				word = i
+			}
+			continue
+		}
+		if word >= 0 {
+			b.WriteString(lang.Stem(s[word:i]))
+			word = -1
+		}
+		if i < len(s) {
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// stemTerms lets each query term also match its stem, as English memories
+// are indexed by stems while others keep whole words. A term with a
+// different stem becomes "term|stem"; termForms splits it again.
+func stemTerms(terms []string) []string {
+	out := make([]string, len(terms))
+	for i, term := range terms {
+		out[i] = term
+		if stem := lang.Stem(term); stem != term {
+			out[i] = term + "|" + stem
+		}
+	}
+	return out
+}
+
+// termForms returns the alternatives of a query term from stemTerms; any
+// of them may match.
+func termForms(term string) []string {
+	return strings.Split(term, "|")
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/internal/lang"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestBuildFTSMatchQuery_StemAlternatives(t *testing.T) {
+	t.Parallel()
+	got := buildFTSMatchQuery(stemTerms([]string{"deploying", "api", "東*"}))
+	want := `("deploying" OR "deploi") AND "api" AND "東"*`
+	if got != want {
+		t.Fatalf("buildFTSMatchQuery() = %s, want %s", got, want)
+	}
+}
+
+func TestStores_AnalyzeByDetectedLanguage(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	now := time.Now().UTC()
+	open := map[string]func(dir string) (Store, error){
+		"fts": func(dir string) (Store, error) { return OpenSQLite(ctx, filepath.Join(dir, "m.db"), logger) },
+		"like": func(dir string) (Store, error) {
+			return OpenSQLite(ctx, filepath.Join(dir, "m.db"), logger, WithoutFTS())
+		},
+		"markdown": func(dir string) (Store, error) {
+			return OpenMarkdown(ctx, dir, false, logger)
+		},
+	}
+	for name, openStore := range open {
+		st, err := openStore(t.TempDir())
+		if err != nil {
+			t.Fatalf("%s: open error = %v", name, err)
+		}
+		recs, err := st.InsertMemories(ctx, []types.MemoryRecord{
+			{ID: "m-en", Namespace: "org/repo/task", Scope: "long", Content: "The payment services were deployed after the migrations ran.", CreatedAt: now, LastAccessedAt: now},
+			{ID: "m-ja", Namespace: "org/repo/task", Scope: "long", Content: "決済サービスのデプロイは移行の後に行う", CreatedAt: now, LastAccessedAt: now},
+			{ID: "m-fr", Namespace: "org/repo/task", Scope: "long", Content: "Les services de paiement sont déployés après la migration.", CreatedAt: now, LastAccessedAt: now},
+		})
+		if err != nil {
+			t.Fatalf("%s: InsertMemories() error = %v", name, err)
+		}
+		if recs[0].Language != "en" || recs[1].Language != "ja" || recs[2].Language != "fr" {
+			t.Fatalf("%s: languages = %q %q %q, want en ja fr", name, recs[0].Language, recs[1].Language, recs[2].Language)
+		}
+		for query, want := range map[string][]string{
+			"deploying service": {"m-en"},
+			"migration":         {"m-en", "m-fr"},
+			"services":          {"m-en", "m-fr"},
+			"デプロイ":              {"m-ja"},
+		} {
+			cands, err := st.SearchCandidates(ctx, SearchQuery{Namespace: "org/repo/task", Query: query, Limit: 10, Now: now})
+			if err != nil {
+				t.Fatalf("%s: SearchCandidates(%q) error = %v", name, query, err)
+			}
+			got := map[string]bool{}
+			for _, c := range cands {
+				got[c.Record.ID] = true
+			}
+			if len(got) != len(want) {
+				t.Fatalf("%s: SearchCandidates(%q) = %v, want %v", name, query, got, want)
+			}
+			for _, id := range want {
+				if !got[id] {
+					t.Fatalf("%s: SearchCandidates(%q) = %v, want %v", name, query, got, want)
+				}
+			}
+		}
+		rec, err := st.UpdateMemory(ctx, "m-fr", func(rec *types.MemoryRecord) error {
+			rec.Content = "Payment services are deployed after the migration."
+			return nil
+		})
+		if err != nil || rec.Language != "en" {
+			t.Fatalf("%s: UpdateMemory() = %q, %v, want language en", name, rec.Language, err)
+		}
+		if got, err := st.GetMemory(ctx, "m-fr"); err != nil || got.Language != "en" {
+			t.Fatalf("%s: GetMemory() language = %q, %v, want en", name, got.Language, err)
+		}
+		st.Close()
+	}
+}
+
+func TestTokenizeQueryTerms_DropsStopWords(t *testing.T) {
+	t.Parallel()
+	stop := lang.NewStopWords(lang.EnglishStopWords, []string{"Please"})
+	if got := tokenizeQueryTerms("What is the deployment process, please?", stop); !slices.Equal(got, []string{"deployment", "process"}) {
+		t.Fatalf("tokenizeQueryTerms() = %q", got)
+	}
+	if got := tokenizeQueryTerms("the who", stop); !slices.Equal(got, []string{"the", "who"}) {
+		t.Fatalf("tokenizeQueryTerms() of only stop words = %q, want them kept", got)
+	}
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"strings"
+	"unicode"
+)
+
+// isCJK reports characters of scripts written without spaces between
+// words. The FTS tokenizer would index a whole run of them as one token.
+func isCJK(r rune) bool {
+	// U+30FC, the prolonged sound mark in katakana words, is Common script.
+	return r == 'ー' || unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// ngramText rewrites every CJK run in s as space-separated n-grams for
+// indexing; other text is unchanged. n < 2 disables it.
+func ngramText(s string, n int) string {
+	if n < 2 || !strings.ContainsFunc(s, isCJK) {
+		return s
+	}
+	var b strings.Builder
+	var run []rune
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		b.WriteByte(' ')
+		b.WriteString(strings.Join(runGrams(run, n), " "))
+		b.WriteByte(' ')
+		run = run[:0]
+	}
+	for _, r := range s {
+		if isCJK(r) {
+			run = append(run, r)
+			continue
+		}
+		flush()
+		b.WriteRune(r)
+	}
+	flush()
+	return b.String()
+}
+
+// runGrams returns the n-gram starting at every rune of run, shortening
+// towards its end, so any substring shorter than n is a prefix of a gram.
+func runGrams(run []rune, n int) []string {
+	out := make([]string, 0, len(run))
+	for i := range run {
+		out = append(out, string(run[i:min(i+n, len(run))]))
+	}
+	return out
+}
+
+// ngramTerms rewrites query terms to match ngramText: a CJK run of at
+// least n runes becomes its full n-grams, and a shorter one a prefix term
+// marked with a trailing "*".
+func ngramTerms(terms []string, n int) []string {
+	if n < 2 {
+		return terms
+	}
+	out := make([]string, 0, len(terms))
+	seen := map[string]struct{}{}
+	add := func(term string) {
+		if _, ok := seen[term]; ok || term == "" {
+			return
+		}
+		seen[term] = struct{}{}
+		out = append(out, term)
+	}
+	for _, term := range terms {
+		if !strings.ContainsFunc(term, isCJK) {
+			add(term)
+			continue
+		}
+		var other strings.Builder
+		var run []rune
+		flush := func() {
+			add(other.String())
+			other.Reset()
+			if len(run) == 0 {
+				return
+			}
+			if len(run) < n {
+				add(string(run) + "*")
+			}
+			for i := 0; i+n <= len(run); i++ {
+				add(string(run[i : i+n]))
+			}
+			run = run[:0]
+		}
+		for _, r := range term {
+			if isCJK(r) {
+				if other.Len() > 0 {
+					add(other.String())
+					other.Reset()
+				}
+				run = append(run, r)
+				continue
+			}
+			if len(run) > 0 {
+				flush()
+			}
+			other.WriteRune(r)
+		}
+		flush()
+	}
+	return out
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestNgramText(t *testing.T) {
+	t.Parallel()
+	if got := ngramText("api 東京都 sync", 2); got != "api  東京 京都 都  sync" {
+		t.Fatalf("ngramText() = %q", got)
+	}
+	if got := ngramText("東京都", 0); got != "東京都" {
+		t.Fatalf("ngramText() with n=0 = %q", got)
+	}
+	if got := ngramText("plain text", 2); got != "plain text" {
+		t.Fatalf("ngramText() without CJK = %q", got)
+	}
+}
+
+func TestNgramTerms(t *testing.T) {
+	t.Parallel()
+	got := ngramTerms([]string{"データベース", "api東", "sync"}, 2)
+	want := []string{"デー", "ータ", "タベ", "ベー", "ース", "api", "東*", "sync"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("ngramTerms() = %q, want %q", got, want)
+	}
+}
+
+func TestSQLiteStore_SearchesCJKInsideRuns(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	now := time.Now().UTC()
+	for _, opts := range [][]Option{nil, {WithCJKNgram(3)}, {WithoutFTS()}} {
+		st, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "cjk.db"), logger, opts...)
+		if err != nil {
+			t.Fatalf("OpenSQLite() error = %v", err)
+		}
+		if _, err := st.InsertMemories(ctx, []types.MemoryRecord{
+			{ID: "m-ja", Namespace: "org/repo/task", Scope: "long", Content: "本番データベースの接続先は東京リージョンです", CreatedAt: now, LastAccessedAt: now},
+			{ID: "m-zh", Namespace: "org/repo/task", Scope: "long", Content: "部署前必须运行数据库迁移脚本", CreatedAt: now, LastAccessedAt: now},
+		}); err != nil {
+			t.Fatalf("InsertMemories() error = %v", err)
+		}
+		for query, want := range map[string]string{
+			"データベース": "m-ja",
+			"東京":     "m-ja",
+			"ｿﾞｰﾝ":   "",
+			"数据库 迁移": "m-zh",
+			"迁":      "m-zh",
+		} {
+			cands, err := st.SearchCandidates(ctx, SearchQuery{Namespace: "org/repo/task", Query: query, Limit: 10, Now: now})
+			if err != nil {
+				t.Fatalf("SearchCandidates(%q) error = %v", query, err)
+			}
+			var got string
+			if len(cands) == 1 {
+				got = cands[0].Record.ID
+			}
+			if len(cands) > 1 || got != want {
+				t.Fatalf("SearchCandidates(%q) with %d options = %+v, want %q", query, len(opts), cands, want)
+			}
+		}
+		st.Close()
+	}
+}
+
+func TestSQLiteStore_ReindexesWhenCJKNgramChanges(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	dbPath := filepath.Join(t.TempDir(), "reindex.db")
+	st, err := OpenSQLite(ctx, dbPath, logger, WithCJKNgram(0))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	now := time.Now().UTC()
+	if _, err := st.InsertMemory(ctx, types.MemoryRecord{ID: "m-ja", Namespace: "org/repo/task", Scope: "long", Content: "東京リージョン", CreatedAt: now, LastAccessedAt: now}); err != nil {
+		t.Fatalf("InsertMemory() error = %v", err)
+	}
+	if !st.ftsEnabled {
+		st.Close()
+		t.Skip("FTS5 unavailable")
+	}
+	match := func(st *SQLiteStore) int {
+		cands, err := st.searchFTS(ctx, SearchQuery{Namespace: "org/repo/task", Limit: 10, Now: now}, buildFTSMatchQuery(ngramTerms([]string{"リージョン"}, st.cjkNgram)))
+		if err != nil {
+			t.Fatalf("searchFTS() error = %v", err)
+		}
+		return len(cands)
+	}
+	if n := match(st); n != 0 {
+		t.Fatalf("expected whole-run index to miss an inner word, got %d matches", n)
+	}
+	st.Close()
+
+	st, err = OpenSQLite(ctx, dbPath, logger, WithCJKNgram(2))
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer st.Close()
+	if n := match(st); n != 1 {
+		t.Fatalf("expected reindexed bigrams to match, got %d matches", n)
+	}
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DeleteByFilter removes the matching rows in one statement; the FTS
+// trigger drops their index entries.
+func (s *SQLiteStore) DeleteByFilter(ctx context.Context, f ListFilter) (int64, error) {
+	f.Sort, f.After, f.Limit, f.Offset = "", nil, 0, 0
+	if err := f.validate(); err != nil {
+		return 0, err
+	}
+	where, args := listWhere(f, s.metaCols)
+	q := `DELETE FROM memories`
+	if len(where) > 0 {
+		q += "\nWHERE " + strings.Join(where, "\n  AND ")
+	}
+	rows, err := s.db.QueryContext(ctx, q+"\nRETURNING id", args...)
+	if err != nil {
+		return 0, fmt.Errorf("delete memories: %w", err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return 0, fmt.Errorf("delete memories: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	s.uncacheRecords(ids...)
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("delete memories: %w", err)
+	}
+	return int64(len(ids)), nil
+}
+
+// DeleteByFilter removes the files of the memories matching f in one
+// commit.
+func (s *MarkdownStore) DeleteByFilter(ctx context.Context, f ListFilter) (int64, error) {
+	f.Sort, f.After, f.Limit, f.Offset = "", nil, 0, 0
+	if err := f.validate(); err != nil {
+		return 0, err
+	}
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	recs := s.index.list(f)
+	if len(recs) == 0 {
+		return 0, nil
+	}
+	ids := make([]string, len(recs))
+	for i, rec := range recs {
+		ids[i] = rec.ID
+	}
+	return s.remove(ctx, ids, fmt.Sprintf("memory: forget %d memories", len(ids)))
+}
+
+// DeleteByFilter applies f to every shard.
+func (s *ShardedStore) DeleteByFilter(ctx context.Context, f ListFilter) (int64, error) {
+	var total int64
+	for _, st := range s.stores() {
+		n, err := st.DeleteByFilter(ctx, f)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestDeleteByFilter_AcrossDrivers(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	recs := []types.MemoryRecord{
+		{ID: "d-1", Namespace: "acme/api/task", Scope: "short", Content: "expired note", Importance: 2, CreatedAt: now.Add(-48 * time.Hour), ExpiresAt: &past},
+		{ID: "d-2", Namespace: "acme/api/task", Scope: "short", Content: "fresh note", Importance: 2, CreatedAt: now},
+		{ID: "d-3", Namespace: "acme/api/task", Scope: "long", Content: "decision", Importance: 5, CreatedAt: now.Add(-48 * time.Hour)},
+		{ID: "d-4", Namespace: "acme/api/task/sub", Scope: "short", Content: "child note", Importance: 2, CreatedAt: now.Add(-48 * time.Hour)},
+		{ID: "d-5", Namespace: "beta/app", Scope: "short", Content: "other", Importance: 1, CreatedAt: now.Add(-48 * time.Hour), Metadata: map[string]any{"task": "t-1"}},
+	}
+	for i := range recs {
+		recs[i].LastAccessedAt = recs[i].CreatedAt
+	}
+
+	sqliteStore, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "delete.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer sqliteStore.Close()
+	sharded, err := OpenSharded(ctx, filepath.Join(t.TempDir(), "main.db"), map[string]string{"beta": filepath.Join(t.TempDir(), "beta.db")}, logger)
+	if err != nil {
+		t.Fatalf("OpenSharded() error = %v", err)
+	}
+	defer sharded.Close()
+	mdStore, err := OpenMarkdown(ctx, t.TempDir(), false, logger)
+	if err != nil {
+		t.Fatalf("OpenMarkdown() error = %v", err)
+	}
+
+	for _, st := range []Store{sqliteStore, sharded, mdStore, NewMemoryStore()} {
+		if _, _, err := st.ImportBatch(ctx, recs); err != nil {
+			t.Fatalf("%T.ImportBatch() error = %v", st, err)
+		}
+		// Old, unimportant short-term memories of the task itself, expired
+		// or not.
+		n, err := st.DeleteByFilter(ctx, ListFilter{Namespace: "acme/api/task", Scope: "short", CreatedBefore: now.Add(-time.Hour), MaxImportance: 3, IncludeExpired: true, Now: now})
+		if err != nil || n != 1 {
+			t.Fatalf("%T.DeleteByFilter(task) = %d, %v, want 1", st, n, err)
+		}
+		n, err = st.DeleteByFilter(ctx, ListFilter{Metadata: map[string]string{"task": "t-1"}, IncludeExpired: true, Now: now})
+		if err != nil || n != 1 {
+			t.Fatalf("%T.DeleteByFilter(metadata) = %d, %v, want 1", st, n, err)
+		}
+
+		var left []string
+		if err := st.ExportAll(ctx, func(rec types.MemoryRecord) error {
+			left = append(left, rec.ID)
+			return nil
+		}); err != nil {
+			t.Fatalf("%T.ExportAll() error = %v", st, err)
+		}
+		slices.Sort(left)
+		if want := []string{"d-2", "d-3", "d-4"}; !slices.Equal(left, want) {
+			t.Fatalf("%T left %v, want %v", st, left, want)
+		}
+		if _, err := st.GetMemory(ctx, "d-1"); err == nil {
+			t.Fatalf("%T.GetMemory() of a forgotten memory error = nil", st)
+		}
+	}
+}
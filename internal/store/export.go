@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// ExportAll calls fn with every memory, expired ones included, oldest
+// first. It stops at the first error fn returns.
+func (s *SQLiteStore) ExportAll(ctx context.Context, fn func(types.MemoryRecord) error) error {
+	rows, err := s.reader.QueryContext(ctx, `SELECT id, namespace, scope, content, summary, importance, source_agent,
+       metadata_json, created_at, last_accessed_at, expires_at, promoted_at, language, external_key, access_count
+FROM memories
+ORDER BY created_at ASC, id ASC`)
+	if err != nil {
+		return fmt.Errorf("export memories: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		rec, err := scanMemoryRow(rows)
+		if err != nil {
+			return fmt.Errorf("scan memory: %w", err)
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ImportBatch inserts the records whose IDs the store does not hold yet in
+// one transaction, and returns them with the IDs it skipped.
+func (s *SQLiteStore) ImportBatch(ctx context.Context, recs []types.MemoryRecord) ([]types.MemoryRecord, []string, error) {
+	var (
+		stored  []types.MemoryRecord
+		skipped []string
+	)
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		stored, skipped = nil, nil
+		for _, rec := range recs {
+			var one int
+			err := tx.QueryRowContext(ctx, `SELECT 1 FROM memories WHERE id = ?`, rec.ID).Scan(&one)
+			if err == nil {
+				skipped = append(skipped, rec.ID)
+				continue
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("memory %s: %w", rec.ID, err)
+			}
+			setLanguage(&rec)
+			if err := s.insertMemoryTx(ctx, tx, rec); err != nil {
+				return fmt.Errorf("memory %s: %w", rec.ID, err)
+			}
+			stored = append(stored, rec)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, rec := range stored {
+		s.uncacheRecords(rec.ID)
+	}
+	return stored, skipped, nil
+}
+
+// ExportAll calls fn with every memory, expired ones included, oldest
+// first.
+func (s *MarkdownStore) ExportAll(_ context.Context, fn func(types.MemoryRecord) error) error {
+	for _, rec := range s.index.list(ListFilter{IncludeExpired: true, Sort: SortCreatedAsc}) {
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportBatch writes the records whose IDs the store does not hold yet,
+// and returns them with the IDs it skipped, including repeats within recs.
+func (s *MarkdownStore) ImportBatch(ctx context.Context, recs []types.MemoryRecord) ([]types.MemoryRecord, []string, error) {
+	var (
+		fresh   []types.MemoryRecord
+		skipped []string
+	)
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	seen := make(map[string]bool, len(recs))
+	for _, rec := range recs {
+		if _, ok := s.index.get(rec.ID); ok || seen[rec.ID] {
+			skipped = append(skipped, rec.ID)
+			continue
+		}
+		seen[rec.ID] = true
+		fresh = append(fresh, rec)
+	}
+	stored, err := s.insertMemories(ctx, fresh)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stored, skipped, nil
+}
+
+// ExportAll exports every store in turn, so records are oldest first
+// within each store.
+func (s *ShardedStore) ExportAll(ctx context.Context, fn func(types.MemoryRecord) error) error {
+	for _, st := range s.stores() {
+		if err := st.ExportAll(ctx, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportBatch skips records whose IDs any store holds, since a remapped
+// namespace may route an existing ID to another shard, and inserts the
+// rest into their shards.
+func (s *ShardedStore) ImportBatch(ctx context.Context, recs []types.MemoryRecord) ([]types.MemoryRecord, []string, error) {
+	var (
+		fresh   []types.MemoryRecord
+		skipped []string
+	)
+	seen := make(map[string]bool, len(recs))
+	for _, rec := range recs {
+		_, err := s.GetMemory(ctx, rec.ID)
+		if err == nil || seen[rec.ID] {
+			skipped = append(skipped, rec.ID)
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, err
+		}
+		seen[rec.ID] = true
+		fresh = append(fresh, rec)
+	}
+	if len(fresh) == 0 {
+		return nil, skipped, nil
+	}
+	stored, err := s.InsertMemories(ctx, fresh)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stored, skipped, nil
+}
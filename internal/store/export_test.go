@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestExportAllAndImportBatch_RoundTripAcrossDrivers(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	recs := []types.MemoryRecord{
+		{ID: "x-2", Namespace: "acme/api", Scope: "long", Content: "second", CreatedAt: now.Add(-time.Hour)},
+		{ID: "x-1", Namespace: "beta/app", Scope: "short", Content: "first", CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: &past},
+	}
+	for i := range recs {
+		recs[i].LastAccessedAt = recs[i].CreatedAt
+	}
+
+	sqliteStore, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "export.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer sqliteStore.Close()
+	sharded, err := OpenSharded(ctx, filepath.Join(t.TempDir(), "main.db"), map[string]string{"beta": filepath.Join(t.TempDir(), "beta.db")}, logger)
+	if err != nil {
+		t.Fatalf("OpenSharded() error = %v", err)
+	}
+	defer sharded.Close()
+	mdStore, err := OpenMarkdown(ctx, t.TempDir(), false, logger)
+	if err != nil {
+		t.Fatalf("OpenMarkdown() error = %v", err)
+	}
+
+	for _, st := range []Store{sqliteStore, sharded, mdStore, NewMemoryStore()} {
+		stored, skipped, err := st.ImportBatch(ctx, recs[:1])
+		if err != nil || len(stored) != 1 || len(skipped) != 0 {
+			t.Fatalf("%T.ImportBatch() = %d stored, %v skipped, %v", st, len(stored), skipped, err)
+		}
+		stored, skipped, err = st.ImportBatch(ctx, append(recs, recs[1]))
+		if err != nil || len(stored) != 1 || stored[0].ID != "x-1" || !reflect.DeepEqual(skipped, []string{"x-2", "x-1"}) {
+			t.Fatalf("%T.ImportBatch(again) = %v stored, %v skipped, %v; want x-1 stored and the repeats skipped", st, stored, skipped, err)
+		}
+
+		var ids []string
+		if err := st.ExportAll(ctx, func(rec types.MemoryRecord) error {
+			ids = append(ids, rec.ID)
+			return nil
+		}); err != nil {
+			t.Fatalf("%T.ExportAll() error = %v", st, err)
+		}
+		want := []string{"x-1", "x-2"}
+		if st == Store(sharded) {
+			// Each shard exports oldest first in turn, the primary first.
+			want = []string{"x-2", "x-1"}
+		}
+		if !reflect.DeepEqual(ids, want) {
+			t.Fatalf("%T.ExportAll() = %v, want %v including the expired memory", st, ids, want)
+		}
+	}
+}
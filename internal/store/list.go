@@ -0,0 +1,370 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// Sort orders accepted by ListFilter.Sort.
+const (
+	SortCreatedDesc    = "created_desc"
+	SortCreatedAsc     = "created_asc"
+	SortImportanceDesc = "importance_desc"
+)
+
+// ListFilter selects memories for ListMemories. Zero values do not filter.
+type ListFilter struct {
+	// Namespace matches this namespace only.
+	Namespace string
+	// NamespacePrefix matches the namespace itself and its descendants on
+	// segment boundaries: "acme/api" matches "acme/api/main" but not
+	// "acme/apix".
+	NamespacePrefix string
+	Scope           string
+	SourceAgent     string
+	// ExternalKey matches the memory written with this external key.
+	ExternalKey string
+	// Tags must all be present in metadata "tags".
+	Tags []string
+	// Metadata requires each top-level metadata key to equal the value,
+	// compared as text. Keys configured as metadata columns use an index.
+	Metadata      map[string]string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// MinImportance and MaxImportance bound importance inclusively; 0
+	// leaves that end open.
+	MinImportance int
+	MaxImportance int
+	// ExpiredOnly lists only memories whose TTL has elapsed at Now, which
+	// the TTL worker would remove next.
+	ExpiredOnly bool
+	// IncludeExpired keeps short-term memories whose TTL has elapsed but
+	// that the TTL worker has not yet removed. Expiry is judged at Now.
+	IncludeExpired bool
+	Now            time.Time
+	Sort           string
+	// After continues a listing strictly after this position in Sort
+	// order, so pages stay stable while memories are written.
+	After *ListCursor
+	// Limit <= 0 returns every match.
+	Limit  int
+	Offset int
+}
+
+// ListCursor is the position of a listed memory: the sort keys of the last
+// memory on a page.
+type ListCursor struct {
+	CreatedAt  time.Time
+	Importance int
+	ID         string
+}
+
+// follows reports whether rec sorts strictly after c in order sort.
+func (c ListCursor) follows(rec types.MemoryRecord, sort string) bool {
+	switch sort {
+	case SortCreatedAsc:
+		return cmp.Or(rec.CreatedAt.Compare(c.CreatedAt), cmp.Compare(rec.ID, c.ID)) > 0
+	case SortImportanceDesc:
+		return cmp.Or(cmp.Compare(c.Importance, rec.Importance), c.CreatedAt.Compare(rec.CreatedAt), cmp.Compare(c.ID, rec.ID)) > 0
+	default:
+		return cmp.Or(c.CreatedAt.Compare(rec.CreatedAt), cmp.Compare(c.ID, rec.ID)) > 0
+	}
+}
+
+func (f ListFilter) validate() error {
+	switch f.Sort {
+	case "", SortCreatedDesc, SortCreatedAsc, SortImportanceDesc:
+	default:
+		return fmt.Errorf("invalid sort %q (expected %s, %s or %s)", f.Sort, SortCreatedDesc, SortCreatedAsc, SortImportanceDesc)
+	}
+	if f.Offset < 0 {
+		return fmt.Errorf("offset must be >= 0")
+	}
+	if f.MinImportance < 0 || f.MaxImportance < 0 || (f.MaxImportance > 0 && f.MinImportance > f.MaxImportance) {
+		return fmt.Errorf("invalid importance range %d-%d", f.MinImportance, f.MaxImportance)
+	}
+	for key := range f.Metadata {
+		if !ValidMetadataColumnKey(key) {
+			return fmt.Errorf("invalid metadata key %q", key)
+		}
+	}
+	return nil
+}
+
+// ListMemories returns memories matching f.
+func (s *SQLiteStore) ListMemories(ctx context.Context, f ListFilter) ([]types.MemoryRecord, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	q, args := listMemoriesQuery(f, s.metaCols)
+	rows, err := s.reader.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list memories: %w", err)
+	}
+	defer rows.Close()
+
+	var items []types.MemoryRecord
+	for rows.Next() {
+		rec, err := scanMemoryRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan memory: %w", err)
+		}
+		items = append(items, rec)
+	}
+	return items, rows.Err()
+}
+
+func listMemoriesQuery(f ListFilter, metaCols map[string]struct{}) (string, []any) {
+	where, args := listWhere(f, metaCols)
+
+	q := `SELECT id, namespace, scope, content, summary, importance, source_agent,
+       metadata_json, created_at, last_accessed_at, expires_at, promoted_at, language, external_key, access_count
+FROM memories`
+	if len(where) > 0 {
+		q += "\nWHERE " + strings.Join(where, "\n  AND ")
+	}
+	switch f.Sort {
+	case SortCreatedAsc:
+		q += "\nORDER BY created_at ASC, id ASC"
+	case SortImportanceDesc:
+		q += "\nORDER BY importance DESC, created_at DESC, id DESC"
+	default:
+		q += "\nORDER BY created_at DESC, id DESC"
+	}
+	switch {
+	case f.Limit > 0:
+		q += "\nLIMIT ? OFFSET ?"
+		args = append(args, f.Limit, f.Offset)
+	case f.Offset > 0:
+		q += "\nLIMIT -1 OFFSET ?"
+		args = append(args, f.Offset)
+	}
+	return q, args
+}
+
+// listWhere translates the filtering fields of f into WHERE clauses.
+// metaCols lists metadata keys that have a generated column.
+func listWhere(f ListFilter, metaCols map[string]struct{}) ([]string, []any) {
+	var (
+		where []string
+		args  []any
+	)
+	if f.Namespace != "" {
+		where = append(where, "namespace = ?")
+		args = append(args, f.Namespace)
+	}
+	if ns := strings.Trim(f.NamespacePrefix, "/"); ns != "" {
+		where = append(where, `(namespace = ? OR namespace LIKE ? ESCAPE '\')`)
+		args = append(args, ns, escapeLike(ns)+"/%")
+	}
+	if f.Scope != "" {
+		where = append(where, "scope = ?")
+		args = append(args, f.Scope)
+	}
+	if f.SourceAgent != "" {
+		where = append(where, "source_agent = ?")
+		args = append(args, f.SourceAgent)
+	}
+	if f.ExternalKey != "" {
+		where = append(where, "external_key = ?")
+		args = append(args, f.ExternalKey)
+	}
+	for _, tag := range f.Tags {
+		where = append(where, `EXISTS (SELECT 1 FROM json_each(metadata_json, '$.tags') WHERE value = ?)`)
+		args = append(args, tag)
+	}
+	for _, key := range sortedKeys(f.Metadata) {
+		if _, ok := metaCols[key]; ok {
+			where = append(where, metaColumnPrefix+key+" = ?")
+		} else {
+			where = append(where, metadataExtract(key)+" = ?")
+		}
+		args = append(args, f.Metadata[key])
+	}
+	if !f.CreatedAfter.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, f.CreatedAfter.UTC().Format(time.RFC3339Nano))
+	}
+	if !f.CreatedBefore.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, f.CreatedBefore.UTC().Format(time.RFC3339Nano))
+	}
+	if f.MinImportance > 0 {
+		where = append(where, "importance >= ?")
+		args = append(args, f.MinImportance)
+	}
+	if f.MaxImportance > 0 {
+		where = append(where, "importance <= ?")
+		args = append(args, f.MaxImportance)
+	}
+	if c := f.After; c != nil {
+		created := c.CreatedAt.UTC().Format(time.RFC3339Nano)
+		switch f.Sort {
+		case SortCreatedAsc:
+			where = append(where, "(created_at > ? OR (created_at = ? AND id > ?))")
+			args = append(args, created, created, c.ID)
+		case SortImportanceDesc:
+			where = append(where, "(importance < ? OR (importance = ? AND (created_at < ? OR (created_at = ? AND id < ?))))")
+			args = append(args, c.Importance, c.Importance, created, created, c.ID)
+		default:
+			where = append(where, "(created_at < ? OR (created_at = ? AND id < ?))")
+			args = append(args, created, created, c.ID)
+		}
+	}
+	switch {
+	case f.ExpiredOnly:
+		where = append(where, "expires_at IS NOT NULL AND expires_at <= ?")
+		args = append(args, listNow(f).UTC().Format(time.RFC3339Nano))
+	case !f.IncludeExpired:
+		where = append(where, "(expires_at IS NULL OR expires_at > ?)")
+		args = append(args, listNow(f).UTC().Format(time.RFC3339Nano))
+	}
+	return where, args
+}
+
+func listNow(f ListFilter) time.Time {
+	if f.Now.IsZero() {
+		return time.Now()
+	}
+	return f.Now
+}
+
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
+
+// list applies f to the in-memory index with the same semantics as the
+// SQLite query.
+func (ix *memIndex) list(f ListFilter) []types.MemoryRecord {
+	ns := strings.Trim(f.NamespacePrefix, "/")
+	now := listNow(f)
+
+	ix.mu.RLock()
+	var items []types.MemoryRecord
+	for _, rec := range ix.records {
+		if f.Namespace != "" && rec.Namespace != f.Namespace {
+			continue
+		}
+		if ns != "" && rec.Namespace != ns && !strings.HasPrefix(rec.Namespace, ns+"/") {
+			continue
+		}
+		if f.Scope != "" && rec.Scope != f.Scope {
+			continue
+		}
+		if f.SourceAgent != "" && rec.SourceAgent != f.SourceAgent {
+			continue
+		}
+		if f.ExternalKey != "" && rec.ExternalKey != f.ExternalKey {
+			continue
+		}
+		if !hasAllTags(rec, f.Tags) || !matchesMetadata(rec, f.Metadata) {
+			continue
+		}
+		if !f.CreatedAfter.IsZero() && rec.CreatedAt.Before(f.CreatedAfter) {
+			continue
+		}
+		if !f.CreatedBefore.IsZero() && !rec.CreatedAt.Before(f.CreatedBefore) {
+			continue
+		}
+		if (f.MinImportance > 0 && rec.Importance < f.MinImportance) || (f.MaxImportance > 0 && rec.Importance > f.MaxImportance) {
+			continue
+		}
+		if f.After != nil && !f.After.follows(rec, f.Sort) {
+			continue
+		}
+		if expired := isExpired(rec, now); (f.ExpiredOnly && !expired) || (!f.IncludeExpired && !f.ExpiredOnly && expired) {
+			continue
+		}
+		items = append(items, cloneRecord(rec))
+	}
+	ix.mu.RUnlock()
+
+	switch f.Sort {
+	case SortCreatedAsc:
+		sort.Slice(items, func(i, j int) bool {
+			if items[i].CreatedAt.Equal(items[j].CreatedAt) {
+				return items[i].ID < items[j].ID
+			}
+			return items[i].CreatedAt.Before(items[j].CreatedAt)
+		})
+	case SortImportanceDesc:
+		sortNewestFirst(items)
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Importance > items[j].Importance })
+	default:
+		sortNewestFirst(items)
+	}
+
+	if f.Offset >= len(items) {
+		return nil
+	}
+	items = items[f.Offset:]
+	if f.Limit > 0 && len(items) > f.Limit {
+		items = items[:f.Limit]
+	}
+	return items
+}
+
+func hasAllTags(rec types.MemoryRecord, tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	have := map[string]struct{}{}
+	switch raw := rec.Metadata["tags"].(type) {
+	case []any:
+		for _, v := range raw {
+			if s, ok := v.(string); ok {
+				have[s] = struct{}{}
+			}
+		}
+	case []string:
+		for _, s := range raw {
+			have[s] = struct{}{}
+		}
+	}
+	for _, tag := range tags {
+		if _, ok := have[tag]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesMetadata mirrors the SQL text comparison of metadata values.
+func matchesMetadata(rec types.MemoryRecord, want map[string]string) bool {
+	for key, value := range want {
+		v, ok := rec.Metadata[key]
+		if !ok {
+			return false
+		}
+		switch v := v.(type) {
+		case string:
+			if v != value {
+				return false
+			}
+		case float64, int, int64, json.Number:
+			if fmt.Sprint(v) != value {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
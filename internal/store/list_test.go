@@ -0,0 +1,234 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestListMemories_FiltersConsistentlyAcrossDrivers(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+
+	recs := []types.MemoryRecord{
+		{ID: "m-1", Namespace: "acme/api/main", Scope: "long", Importance: 2, SourceAgent: "codex",
+			Metadata: map[string]any{"tags": []any{"decision", "storage"}}, CreatedAt: now.Add(-72 * time.Hour)},
+		{ID: "m-2", Namespace: "acme/api/feature", Scope: "short", Importance: 5, SourceAgent: "claude",
+			Metadata: map[string]any{"tags": []any{"decision"}, "ticket": "OPS-7", "priority": float64(2)}, CreatedAt: now.Add(-48 * time.Hour)},
+		{ID: "m-3", Namespace: "acme/apix/main", Scope: "long", Importance: 4, SourceAgent: "codex",
+			Metadata: map[string]any{"tags": []any{"storage"}}, CreatedAt: now.Add(-24 * time.Hour)},
+		{ID: "m-4", Namespace: "acme/api/main", Scope: "short", Importance: 3, SourceAgent: "codex",
+			CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: &past},
+	}
+	for i := range recs {
+		recs[i].Content = "note " + recs[i].ID
+		recs[i].LastAccessedAt = recs[i].CreatedAt
+	}
+
+	sqliteStore, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "list.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer sqliteStore.Close()
+	indexedStore, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "list-indexed.db"), logger, WithMetadataColumns("ticket"))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer indexedStore.Close()
+	mdStore, err := OpenMarkdown(ctx, t.TempDir(), false, logger)
+	if err != nil {
+		t.Fatalf("OpenMarkdown() error = %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		filter ListFilter
+		want   []string
+	}{
+		{"all live newest first", ListFilter{}, []string{"m-3", "m-2", "m-1"}},
+		{"namespace prefix on segment boundary", ListFilter{NamespacePrefix: "acme/api"}, []string{"m-2", "m-1"}},
+		{"scope and agent", ListFilter{Scope: "long", SourceAgent: "codex"}, []string{"m-3", "m-1"}},
+		{"all tags required", ListFilter{Tags: []string{"decision", "storage"}}, []string{"m-1"}},
+		{"metadata equality", ListFilter{Metadata: map[string]string{"ticket": "OPS-7", "priority": "2"}}, []string{"m-2"}},
+		{"date range", ListFilter{CreatedAfter: now.Add(-50 * time.Hour), CreatedBefore: now.Add(-24 * time.Hour)}, []string{"m-2"}},
+		{"include expired oldest first", ListFilter{IncludeExpired: true, Sort: SortCreatedAsc}, []string{"m-1", "m-2", "m-3", "m-4"}},
+		{"importance with paging", ListFilter{Sort: SortImportanceDesc, Limit: 2, Offset: 1}, []string{"m-3", "m-1"}},
+		{"importance range", ListFilter{MinImportance: 3, MaxImportance: 4, IncludeExpired: true}, []string{"m-4", "m-3"}},
+		{"after cursor", ListFilter{After: &ListCursor{CreatedAt: now.Add(-48 * time.Hour), ID: "m-2"}}, []string{"m-1"}},
+		{"after cursor oldest first", ListFilter{Sort: SortCreatedAsc, After: &ListCursor{CreatedAt: now.Add(-72 * time.Hour), ID: "m-1"}, Limit: 1}, []string{"m-2"}},
+		{"after cursor by importance", ListFilter{Sort: SortImportanceDesc, After: &ListCursor{CreatedAt: now.Add(-24 * time.Hour), Importance: 4, ID: "m-3"}}, []string{"m-1"}},
+	}
+
+	for _, st := range []Store{sqliteStore, indexedStore, mdStore, NewMemoryStore()} {
+		if _, err := st.InsertMemories(ctx, recs); err != nil {
+			t.Fatalf("%T.InsertMemories() error = %v", st, err)
+		}
+		for _, tc := range cases {
+			tc.filter.Now = now
+			got, err := st.ListMemories(ctx, tc.filter)
+			if err != nil {
+				t.Fatalf("%T %s: ListMemories() error = %v", st, tc.name, err)
+			}
+			ids := []string{}
+			for _, rec := range got {
+				ids = append(ids, rec.ID)
+			}
+			if !reflect.DeepEqual(ids, tc.want) {
+				t.Fatalf("%T %s: got %v, want %v", st, tc.name, ids, tc.want)
+			}
+		}
+		if _, err := st.ListMemories(ctx, ListFilter{Sort: "random"}); err == nil {
+			t.Fatalf("%T: expected invalid sort to fail", st)
+		}
+		if _, err := st.ListMemories(ctx, ListFilter{MinImportance: 4, MaxImportance: 2}); err == nil {
+			t.Fatalf("%T: expected an empty importance range to fail", st)
+		}
+	}
+}
+
+func TestCountBy_GroupsConsistentlyAcrossDrivers(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+
+	recs := []types.MemoryRecord{
+		{ID: "g-1", Namespace: "acme/api", Scope: "long", SourceAgent: "codex", CreatedAt: now.Add(-49 * time.Hour)},
+		{ID: "g-2", Namespace: "acme/api", Scope: "short", SourceAgent: "claude", CreatedAt: now.Add(-25 * time.Hour)},
+		{ID: "g-3", Namespace: "acme/web", Scope: "long", SourceAgent: "codex", CreatedAt: now.Add(-26 * time.Hour)},
+		{ID: "g-4", Namespace: "acme/web", Scope: "short", SourceAgent: "codex", CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: &past},
+		{ID: "g-5", Namespace: "beta/app", Scope: "long", SourceAgent: "codex", CreatedAt: now.Add(-3 * time.Hour)},
+	}
+	for i := range recs {
+		recs[i].Content = "note " + recs[i].ID
+		recs[i].LastAccessedAt = recs[i].CreatedAt
+	}
+
+	sqliteStore, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "count.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer sqliteStore.Close()
+	mdStore, err := OpenMarkdown(ctx, t.TempDir(), false, logger)
+	if err != nil {
+		t.Fatalf("OpenMarkdown() error = %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		dim    string
+		filter ListFilter
+		want   []GroupCount
+	}{
+		{"namespace", GroupNamespace, ListFilter{}, []GroupCount{{"acme/api", 2}, {"acme/web", 1}, {"beta/app", 1}}},
+		{"scope with expired", GroupScope, ListFilter{IncludeExpired: true}, []GroupCount{{"long", 3}, {"short", 2}}},
+		{"agent under prefix", GroupAgent, ListFilter{NamespacePrefix: "acme"}, []GroupCount{{"codex", 2}, {"claude", 1}}},
+		{"day", GroupDay, ListFilter{}, []GroupCount{{"2026-02-27", 1}, {"2026-02-28", 2}, {"2026-03-01", 1}}},
+	}
+
+	for _, st := range []Store{sqliteStore, mdStore} {
+		if _, err := st.InsertMemories(ctx, recs); err != nil {
+			t.Fatalf("%T.InsertMemories() error = %v", st, err)
+		}
+		for _, tc := range cases {
+			tc.filter.Now = now
+			got, err := st.CountBy(ctx, tc.dim, tc.filter)
+			if err != nil {
+				t.Fatalf("%T %s: CountBy() error = %v", st, tc.name, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("%T %s: got %v, want %v", st, tc.name, got, tc.want)
+			}
+		}
+		if _, err := st.CountBy(ctx, "hour", ListFilter{}); err == nil {
+			t.Fatalf("%T: expected invalid group to fail", st)
+		}
+		stats, err := st.Stats(ctx, now)
+		if err != nil {
+			t.Fatalf("%T.Stats() error = %v", st, err)
+		}
+		want := Stats{Total: 5, Short: 2, Long: 3, Expired: 1, Agents: []GroupCount{{"codex", 4}, {"claude", 1}}}
+		if !reflect.DeepEqual(stats, want) {
+			t.Fatalf("%T.Stats() = %+v, want %+v", st, stats, want)
+		}
+	}
+}
+
+func TestUpdateMemory_ReindexesAndKeepsIdentity(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	sqliteStore, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "update.db"), logger, WithRecordCache(8, time.Minute))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer sqliteStore.Close()
+	mdStore, err := OpenMarkdown(ctx, t.TempDir(), false, logger)
+	if err != nil {
+		t.Fatalf("OpenMarkdown() error = %v", err)
+	}
+
+	for _, st := range []Store{sqliteStore, mdStore} {
+		rec := types.MemoryRecord{ID: "u-1", Namespace: "org/repo/task", Scope: "long", Content: "initial note",
+			CreatedAt: now.Add(-time.Hour), LastAccessedAt: now.Add(-time.Hour)}
+		if _, err := st.InsertMemory(ctx, rec); err != nil {
+			t.Fatalf("%T.InsertMemory() error = %v", st, err)
+		}
+		if _, err := st.GetMemory(ctx, "u-1"); err != nil {
+			t.Fatalf("%T.GetMemory() error = %v", st, err)
+		}
+
+		got, err := st.UpdateMemory(ctx, "u-1", func(r *types.MemoryRecord) error {
+			r.Content += " then flamegraph"
+			r.Namespace = "org/other/task"
+			r.LastAccessedAt = now
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%T.UpdateMemory() error = %v", st, err)
+		}
+		if got.Namespace != "org/repo/task" || got.Content != "initial note then flamegraph" {
+			t.Fatalf("%T: unexpected updated record %+v", st, got)
+		}
+		stored, err := st.GetMemory(ctx, "u-1")
+		if err != nil || stored.Content != got.Content || !stored.LastAccessedAt.Equal(now) {
+			t.Fatalf("%T: expected update to be visible, got %+v, %v", st, stored, err)
+		}
+		cands, err := st.SearchCandidates(ctx, SearchQuery{Namespace: "org/repo/task", Query: "flamegraph", Now: now})
+		if err != nil || len(cands) != 1 {
+			t.Fatalf("%T: expected appended text to be searchable, got %v, %v", st, cands, err)
+		}
+
+		errAbort := errors.New("abort")
+		if _, err := st.UpdateMemory(ctx, "u-1", func(r *types.MemoryRecord) error {
+			r.Content = "discarded"
+			return errAbort
+		}); !errors.Is(err, errAbort) {
+			t.Fatalf("%T: expected fn error, got %v", st, err)
+		}
+		if stored, _ := st.GetMemory(ctx, "u-1"); stored.Content == "discarded" {
+			t.Fatalf("%T: aborted update was saved", st)
+		}
+		if _, err := st.UpdateMemory(ctx, "missing", func(*types.MemoryRecord) error { return nil }); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("%T: expected sql.ErrNoRows, got %v", st, err)
+		}
+	}
+	if err := sqliteStore.CheckFTS(ctx); err != nil {
+		t.Fatalf("CheckFTS() error = %v", err)
+	}
+}
@@ -0,0 +1,475 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+const frontmatterDelim = "---"
+
+// MarkdownStore persists each memory as a Markdown file with YAML
+// frontmatter under <dir>/<namespace>/<id>.md. Search is served from an
+// in-memory index built at open time.
+type MarkdownStore struct {
+	dir       string
+	gitCommit bool
+	logger    *log.Logger
+	index     *memIndex
+
+	// wmu serializes file writes and git commits.
+	wmu sync.Mutex
+}
+
+// markdownFrontmatter is the on-disk header of one memory file.
+type markdownFrontmatter struct {
+	ID             string         `yaml:"id"`
+	Namespace      string         `yaml:"namespace"`
+	Scope          string         `yaml:"scope"`
+	Summary        string         `yaml:"summary,omitempty"`
+	Importance     int            `yaml:"importance"`
+	SourceAgent    string         `yaml:"source_agent,omitempty"`
+	Metadata       map[string]any `yaml:"metadata,omitempty"`
+	CreatedAt      time.Time      `yaml:"created_at"`
+	LastAccessedAt time.Time      `yaml:"last_accessed_at"`
+	ExpiresAt      *time.Time     `yaml:"expires_at,omitempty"`
+	PromotedAt     *time.Time     `yaml:"promoted_at,omitempty"`
+	ExternalKey    string         `yaml:"external_key,omitempty"`
+	AccessCount    int            `yaml:"access_count,omitempty"`
+}
+
+// OpenMarkdown loads every memory file under dir into memory. When gitCommit
+// is set and dir is inside a git work tree, each mutation is committed.
+func OpenMarkdown(ctx context.Context, dir string, gitCommit bool, logger *log.Logger) (*MarkdownStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("mkdir markdown dir: %w", err)
+	}
+
+	s := &MarkdownStore{dir: dir, logger: logger, index: newMemIndex()}
+	if gitCommit {
+		if s.insideGitWorkTree(ctx) {
+			s.gitCommit = true
+		} else {
+			logger.Warn("store.git_commit set but directory is not a git work tree; commits disabled", "dir", dir)
+		}
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *MarkdownStore) load() error {
+	return filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".md" {
+			return nil
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read memory file: %w", err)
+		}
+		rec, err := decodeMarkdownMemory(b)
+		if err != nil {
+			s.logger.Warn("skipping unreadable memory file", "path", path, "error", err)
+			return nil
+		}
+		setLanguage(&rec)
+		s.index.put(rec)
+		return nil
+	})
+}
+
+func (s *MarkdownStore) InsertMemory(ctx context.Context, rec types.MemoryRecord) (types.MemoryRecord, error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	if _, ok := s.index.get(rec.ID); ok {
+		return rec, fmt.Errorf("insert memory: id %s already exists", rec.ID)
+	}
+	setLanguage(&rec)
+	if err := s.persist(ctx, rec, "write"); err != nil {
+		return rec, err
+	}
+	s.index.put(rec)
+	return rec, nil
+}
+
+func (s *MarkdownStore) SearchCandidates(_ context.Context, q SearchQuery) ([]Candidate, error) {
+	if q.Advanced {
+		return nil, ErrAdvancedQueryUnsupported
+	}
+	return s.index.search(q), nil
+}
+
+func (s *MarkdownStore) Promote(ctx context.Context, id string, now time.Time) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	rec, ok := s.index.get(id)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	now = now.UTC()
+	rec.Scope = "long"
+	rec.ExpiresAt = nil
+	rec.PromotedAt = &now
+	rec.LastAccessedAt = now
+	if err := s.persist(ctx, rec, "promote"); err != nil {
+		return err
+	}
+	s.index.put(rec)
+	return nil
+}
+
+func (s *MarkdownStore) Demote(ctx context.Context, id string, expiresAt, now time.Time) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	rec, ok := s.index.get(id)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	expiresAt = expiresAt.UTC()
+	rec.Scope = "short"
+	rec.ExpiresAt = &expiresAt
+	rec.PromotedAt = nil
+	rec.LastAccessedAt = now.UTC()
+	if err := s.persist(ctx, rec, "demote"); err != nil {
+		return err
+	}
+	s.index.put(rec)
+	return nil
+}
+
+// UpdateMemory applies fn to id's record and rewrites its file while
+// holding the write lock. ID, namespace and created_at cannot be changed.
+func (s *MarkdownStore) UpdateMemory(ctx context.Context, id string, fn func(*types.MemoryRecord) error) (types.MemoryRecord, error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	cur, ok := s.index.get(id)
+	if !ok {
+		return types.MemoryRecord{}, sql.ErrNoRows
+	}
+	rec := cloneRecord(cur)
+	if err := fn(&rec); err != nil {
+		return types.MemoryRecord{}, err
+	}
+	rec.ID, rec.Namespace, rec.CreatedAt = cur.ID, cur.Namespace, cur.CreatedAt
+	setLanguage(&rec)
+	if _, err := s.writeFile(rec); err != nil {
+		return types.MemoryRecord{}, err
+	}
+	s.commit(ctx, fmt.Sprintf("memory: update %s (%s)", rec.ID, rec.Namespace))
+	s.index.put(rec)
+	return cloneRecord(rec), nil
+}
+
+// ExpireShort picks the expired records under the write lock, so a record
+// promoted or revived meanwhile is not removed.
+func (s *MarkdownStore) ExpireShort(ctx context.Context, now time.Time) (int64, error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	ids := s.index.expiredShort(now)
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return s.remove(ctx, ids, fmt.Sprintf("memory: expire %d memories", len(ids)))
+}
+
+// DeleteMemories removes the files of the given IDs.
+func (s *MarkdownStore) DeleteMemories(ctx context.Context, ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	return s.remove(ctx, ids, fmt.Sprintf("memory: delete %d memories", len(ids)))
+}
+
+// remove deletes the files of ids and commits. Callers must hold wmu from
+// picking ids until remove returns.
+func (s *MarkdownStore) remove(ctx context.Context, ids []string, message string) (int64, error) {
+	var n int64
+	for _, id := range ids {
+		rec, ok := s.index.get(id)
+		if !ok {
+			continue
+		}
+		path, err := s.recordPath(rec)
+		if err != nil {
+			return n, err
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return n, fmt.Errorf("remove memory file: %w", err)
+		}
+		s.index.remove(id)
+		n++
+	}
+	if n > 0 {
+		s.commit(ctx, message)
+	}
+	return n, nil
+}
+
+func (s *MarkdownStore) Stats(_ context.Context, now time.Time) (Stats, error) {
+	return s.index.stats(now), nil
+}
+
+func (s *MarkdownStore) GetMemory(_ context.Context, id string) (types.MemoryRecord, error) {
+	rec, ok := s.index.get(id)
+	if !ok {
+		return types.MemoryRecord{}, sql.ErrNoRows
+	}
+	return rec, nil
+}
+
+// ListMemories returns memories matching f.
+func (s *MarkdownStore) ListMemories(_ context.Context, f ListFilter) ([]types.MemoryRecord, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	return s.index.list(f), nil
+}
+
+// ListByScope returns every memory in scope, oldest first.
+func (s *MarkdownStore) ListByScope(_ context.Context, scope string) ([]types.MemoryRecord, error) {
+	return s.index.byScope(scope), nil
+}
+
+func (s *MarkdownStore) Close() error {
+	return nil
+}
+
+// InsertMemories writes all records and commits them together. Files
+// already written are removed again if a later record fails.
+func (s *MarkdownStore) InsertMemories(ctx context.Context, recs []types.MemoryRecord) ([]types.MemoryRecord, error) {
+	if len(recs) == 0 {
+		return recs, nil
+	}
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	return s.insertMemories(ctx, recs)
+}
+
+// insertMemories is InsertMemories for callers that hold wmu.
+func (s *MarkdownStore) insertMemories(ctx context.Context, recs []types.MemoryRecord) ([]types.MemoryRecord, error) {
+	if len(recs) == 0 {
+		return recs, nil
+	}
+	seen := make(map[string]struct{}, len(recs))
+	for i := range recs {
+		setLanguage(&recs[i])
+	}
+	for _, rec := range recs {
+		if _, ok := s.index.get(rec.ID); ok {
+			return nil, fmt.Errorf("insert memory: id %s already exists", rec.ID)
+		}
+		if _, ok := seen[rec.ID]; ok {
+			return nil, fmt.Errorf("insert memory: duplicate id %s in batch", rec.ID)
+		}
+		seen[rec.ID] = struct{}{}
+	}
+
+	var written []string
+	for _, rec := range recs {
+		path, err := s.writeFile(rec)
+		if err != nil {
+			for _, p := range written {
+				_ = os.Remove(p)
+			}
+			return nil, err
+		}
+		written = append(written, path)
+	}
+	for _, rec := range recs {
+		s.index.put(rec)
+	}
+	s.commit(ctx, fmt.Sprintf("memory: write %d memories", len(recs)))
+	return recs, nil
+}
+
+// persist writes rec's file and commits it. Callers must hold wmu from
+// the index lookup the write is based on until the index is updated, so a
+// concurrent update or delete is neither lost nor undone.
+func (s *MarkdownStore) persist(ctx context.Context, rec types.MemoryRecord, action string) error {
+	if _, err := s.writeFile(rec); err != nil {
+		return err
+	}
+	s.commit(ctx, fmt.Sprintf("memory: %s %s (%s)", action, rec.ID, rec.Namespace))
+	return nil
+}
+
+// writeFile atomically replaces the record's file and returns its path.
+// Callers must hold wmu.
+func (s *MarkdownStore) writeFile(rec types.MemoryRecord) (string, error) {
+	path, err := s.recordPath(rec)
+	if err != nil {
+		return "", err
+	}
+	b, err := encodeMarkdownMemory(rec)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("mkdir namespace dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return "", fmt.Errorf("write memory file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("rename memory file: %w", err)
+	}
+	return path, nil
+}
+
+// recordPath maps a record to its file, refusing namespaces that would
+// escape the store directory.
+func (s *MarkdownStore) recordPath(rec types.MemoryRecord) (string, error) {
+	for _, seg := range strings.Split(rec.Namespace, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return "", fmt.Errorf("namespace %q cannot be mapped to a directory", rec.Namespace)
+		}
+	}
+	if rec.ID == "" || strings.ContainsAny(rec.ID, `/\`) {
+		return "", fmt.Errorf("memory id %q cannot be mapped to a file name", rec.ID)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(rec.Namespace), rec.ID+".md"), nil
+}
+
+func (s *MarkdownStore) insideGitWorkTree(ctx context.Context) bool {
+	out, err := exec.CommandContext(ctx, "git", "-C", s.dir, "rev-parse", "--is-inside-work-tree").Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// commit stages and commits the store directory. Failures are logged and
+// never fail the mutation itself. Callers must hold wmu.
+func (s *MarkdownStore) commit(ctx context.Context, message string) {
+	if !s.gitCommit {
+		return
+	}
+	if out, err := exec.CommandContext(ctx, "git", "-C", s.dir, "add", "-A", ".").CombinedOutput(); err != nil {
+		s.logger.Warn("git add failed", "error", err, "output", strings.TrimSpace(string(out)))
+		return
+	}
+	out, err := exec.CommandContext(ctx, "git", "-C", s.dir, "commit", "--quiet", "-m", message, "--", ".").CombinedOutput()
+	if err != nil && !strings.Contains(string(out), "nothing to commit") {
+		s.logger.Warn("git commit failed", "error", err, "output", strings.TrimSpace(string(out)))
+	}
+}
+
+func encodeMarkdownMemory(rec types.MemoryRecord) ([]byte, error) {
+	fm := markdownFrontmatter{
+		ID:             rec.ID,
+		Namespace:      rec.Namespace,
+		Scope:          rec.Scope,
+		Summary:        rec.Summary,
+		Importance:     rec.Importance,
+		SourceAgent:    rec.SourceAgent,
+		Metadata:       rec.Metadata,
+		CreatedAt:      rec.CreatedAt.UTC(),
+		LastAccessedAt: rec.LastAccessedAt.UTC(),
+		ExpiresAt:      utcPtr(rec.ExpiresAt),
+		PromotedAt:     utcPtr(rec.PromotedAt),
+		ExternalKey:    rec.ExternalKey,
+		AccessCount:    rec.AccessCount,
+	}
+	header, err := yaml.Marshal(fm)
+	if err != nil {
+		return nil, fmt.Errorf("marshal frontmatter: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(frontmatterDelim + "\n")
+	buf.Write(header)
+	buf.WriteString(frontmatterDelim + "\n")
+	buf.WriteString(rec.Content)
+	if !strings.HasSuffix(rec.Content, "\n") {
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeMarkdownMemory(b []byte) (types.MemoryRecord, error) {
+	text := strings.ReplaceAll(string(b), "\r\n", "\n")
+	if !strings.HasPrefix(text, frontmatterDelim+"\n") {
+		return types.MemoryRecord{}, errors.New("missing frontmatter")
+	}
+	rest := text[len(frontmatterDelim)+1:]
+	end := strings.Index(rest, "\n"+frontmatterDelim+"\n")
+	if end < 0 {
+		return types.MemoryRecord{}, errors.New("unterminated frontmatter")
+	}
+
+	var fm markdownFrontmatter
+	if err := yaml.Unmarshal([]byte(rest[:end]), &fm); err != nil {
+		return types.MemoryRecord{}, fmt.Errorf("parse frontmatter: %w", err)
+	}
+	if fm.ID == "" || fm.Namespace == "" {
+		return types.MemoryRecord{}, errors.New("frontmatter requires id and namespace")
+	}
+
+	content := strings.TrimSuffix(rest[end+len(frontmatterDelim)+2:], "\n")
+	return types.MemoryRecord{
+		ID:             fm.ID,
+		Namespace:      fm.Namespace,
+		Scope:          fm.Scope,
+		Content:        content,
+		Summary:        fm.Summary,
+		Importance:     fm.Importance,
+		SourceAgent:    fm.SourceAgent,
+		Metadata:       normalizeMetadata(fm.Metadata),
+		CreatedAt:      fm.CreatedAt.UTC(),
+		LastAccessedAt: fm.LastAccessedAt.UTC(),
+		ExpiresAt:      utcPtr(fm.ExpiresAt),
+		PromotedAt:     utcPtr(fm.PromotedAt),
+		ExternalKey:    fm.ExternalKey,
+		AccessCount:    fm.AccessCount,
+	}, nil
+}
+
+// normalizeMetadata round-trips YAML-decoded metadata through JSON so value
+// types match what the SQLite store returns (float64 numbers, []any lists).
+func normalizeMetadata(meta map[string]any) map[string]any {
+	if len(meta) == 0 {
+		return map[string]any{}
+	}
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return meta
+	}
+	out := map[string]any{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return meta
+	}
+	return out
+}
+
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
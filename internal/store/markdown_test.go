@@ -0,0 +1,173 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestMarkdownStore_PersistsAndReloads(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	dir := t.TempDir()
+
+	st, err := OpenMarkdown(ctx, dir, false, logger)
+	if err != nil {
+		t.Fatalf("OpenMarkdown() error = %v", err)
+	}
+
+	now := time.Date(2026, 2, 17, 10, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	recs := []types.MemoryRecord{
+		{
+			ID:             "m-long",
+			Namespace:      "org/repo/task",
+			Scope:          "long",
+			Content:        "use WAL mode for the shared database\nsecond line",
+			Summary:        "WAL mode decision",
+			Importance:     5,
+			Metadata:       map[string]any{"ticket": "MEM-1", "priority": 2},
+			CreatedAt:      now,
+			LastAccessedAt: now,
+		},
+		{
+			ID:             "m-expired",
+			Namespace:      "org/repo/task",
+			Scope:          "short",
+			Content:        "stale scratch note",
+			Importance:     2,
+			CreatedAt:      now,
+			LastAccessedAt: now,
+			ExpiresAt:      &past,
+		},
+	}
+	for _, rec := range recs {
+		if _, err := st.InsertMemory(ctx, rec); err != nil {
+			t.Fatalf("InsertMemory(%s) error = %v", rec.ID, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "org", "repo", "task", "m-long.md")); err != nil {
+		t.Fatalf("expected memory file on disk: %v", err)
+	}
+
+	reopened, err := OpenMarkdown(ctx, dir, false, logger)
+	if err != nil {
+		t.Fatalf("OpenMarkdown(reopen) error = %v", err)
+	}
+	got, err := reopened.GetMemory(ctx, "m-long")
+	if err != nil {
+		t.Fatalf("GetMemory() error = %v", err)
+	}
+	if got.Content != recs[0].Content || got.Summary != recs[0].Summary {
+		t.Fatalf("round-trip mismatch: %+v", got)
+	}
+	if got.Metadata["priority"] != float64(2) {
+		t.Fatalf("expected JSON-normalized metadata, got %#v", got.Metadata["priority"])
+	}
+
+	cands, err := reopened.SearchCandidates(ctx, SearchQuery{Namespace: "org/repo/task", Query: "wal database", Limit: 10, Now: now})
+	if err != nil {
+		t.Fatalf("SearchCandidates() error = %v", err)
+	}
+	if len(cands) != 1 || cands[0].Record.ID != "m-long" {
+		t.Fatalf("expected m-long match, got %+v", cands)
+	}
+
+	n, err := reopened.ExpireShort(ctx, now)
+	if err != nil {
+		t.Fatalf("ExpireShort() error = %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 expired memory, got %d", n)
+	}
+	if _, err := reopened.GetMemory(ctx, "m-expired"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected expired memory to be gone, got err=%v", err)
+	}
+}
+
+func TestMarkdownStore_PromoteNeverResurrectsADeletedMemory(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	dir := t.TempDir()
+	st, err := OpenMarkdown(ctx, dir, false, log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("OpenMarkdown() error = %v", err)
+	}
+	now := time.Date(2026, 2, 17, 10, 0, 0, 0, time.UTC)
+	rec := types.MemoryRecord{ID: "m-1", Namespace: "org/repo", Scope: "short", Content: "note", CreatedAt: now, LastAccessedAt: now}
+	if _, err := st.InsertMemory(ctx, rec); err != nil {
+		t.Fatalf("InsertMemory() error = %v", err)
+	}
+
+	// Hold the write lock as a delete would, let Promote start, then
+	// delete the memory before releasing it.
+	st.wmu.Lock()
+	done := make(chan error, 1)
+	go func() { done <- st.Promote(ctx, rec.ID, now) }()
+	time.Sleep(20 * time.Millisecond)
+	path, err := st.recordPath(rec)
+	if err != nil {
+		t.Fatalf("recordPath() error = %v", err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	st.index.remove(rec.ID)
+	st.wmu.Unlock()
+
+	if err := <-done; !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("Promote() of a memory deleted meanwhile error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the file to stay deleted, stat error = %v", err)
+	}
+}
+
+func TestMarkdownStore_ExpireShortSparesAMemoryPromotedMeanwhile(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	st, err := OpenMarkdown(ctx, t.TempDir(), false, log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("OpenMarkdown() error = %v", err)
+	}
+	now := time.Date(2026, 2, 17, 10, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Minute)
+	rec := types.MemoryRecord{ID: "m-1", Namespace: "org/repo", Scope: "short", Content: "note", CreatedAt: now, LastAccessedAt: now, ExpiresAt: &expired}
+	if _, err := st.InsertMemory(ctx, rec); err != nil {
+		t.Fatalf("InsertMemory() error = %v", err)
+	}
+
+	// Hold the write lock as a promotion would, let ExpireShort start,
+	// then promote the memory before releasing it.
+	st.wmu.Lock()
+	done := make(chan int64, 1)
+	go func() {
+		n, _ := st.ExpireShort(ctx, now)
+		done <- n
+	}()
+	time.Sleep(20 * time.Millisecond)
+	promoted := rec
+	promoted.Scope, promoted.ExpiresAt = "long", nil
+	if err := st.persist(ctx, promoted, "promote"); err != nil {
+		t.Fatalf("persist() error = %v", err)
+	}
+	st.index.put(promoted)
+	st.wmu.Unlock()
+
+	if n := <-done; n != 0 {
+		t.Fatalf("ExpireShort() removed %d memories, want the promoted one kept", n)
+	}
+	if got, err := st.GetMemory(ctx, rec.ID); err != nil || got.Scope != "long" {
+		t.Fatalf("GetMemory() = %+v, %v, want the promoted memory", got, err)
+	}
+}
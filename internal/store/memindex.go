@@ -0,0 +1,193 @@
+package store
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xiy/memory-mcp/internal/textnorm"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// memIndex is an in-process record index shared by file-backed stores.
+// Search mirrors the LIKE fallback of the SQLite store: every query term
+// must appear in the content or summary.
+type memIndex struct {
+	mu      sync.RWMutex
+	records map[string]types.MemoryRecord
+}
+
+func newMemIndex() *memIndex {
+	return &memIndex{records: map[string]types.MemoryRecord{}}
+}
+
+func (ix *memIndex) put(rec types.MemoryRecord) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.records[rec.ID] = cloneRecord(rec)
+}
+
+func (ix *memIndex) get(id string) (types.MemoryRecord, bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	rec, ok := ix.records[id]
+	if !ok {
+		return types.MemoryRecord{}, false
+	}
+	return cloneRecord(rec), true
+}
+
+func (ix *memIndex) remove(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	delete(ix.records, id)
+}
+
+func (ix *memIndex) search(q SearchQuery) []Candidate {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	query := strings.TrimSpace(q.Query)
+	terms := stemTerms(tokenizeQueryTerms(query, q.StopWords))
+
+	ix.mu.RLock()
+	matches := make([]types.MemoryRecord, 0, limit)
+	for _, rec := range ix.records {
+		if rec.Namespace != q.Namespace || isExpired(rec, q.Now) {
+			continue
+		}
+		if q.Scope != "" && rec.Scope != q.Scope {
+			continue
+		}
+		if q.SourceAgent != "" && rec.SourceAgent != q.SourceAgent {
+			continue
+		}
+		if !matchesTerms(rec, query, terms) || !matchesConds(rec, q.Metadata) {
+			continue
+		}
+		matches = append(matches, cloneRecord(rec))
+	}
+	ix.mu.RUnlock()
+
+	sortNewestFirst(matches)
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	lex := 0.4
+	if query == "" {
+		lex = 0.25
+	}
+	items := make([]Candidate, 0, len(matches))
+	for _, rec := range matches {
+		items = append(items, Candidate{Record: rec, LexicalScore: lex})
+	}
+	return withHighlights(items, termMatcher(terms))
+}
+
+func (ix *memIndex) byScope(scope string) []types.MemoryRecord {
+	ix.mu.RLock()
+	items := make([]types.MemoryRecord, 0, len(ix.records))
+	for _, rec := range ix.records {
+		if rec.Scope == scope {
+			items = append(items, cloneRecord(rec))
+		}
+	}
+	ix.mu.RUnlock()
+
+	sortNewestFirst(items)
+	slices.Reverse(items)
+	return items
+}
+
+// expiredShort returns the IDs of records whose expiry has passed, except
+// long-term ones.
+func (ix *memIndex) expiredShort(now time.Time) []string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	var ids []string
+	for id, rec := range ix.records {
+		if rec.Scope != "long" && rec.ExpiresAt != nil && !rec.ExpiresAt.After(now) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (ix *memIndex) stats(now time.Time) Stats {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	var st Stats
+	agents := map[string]int64{}
+	for _, rec := range ix.records {
+		st.Total++
+		agents[rec.SourceAgent]++
+		switch rec.Scope {
+		case "short":
+			st.Short++
+		case "long":
+			st.Long++
+		case "project":
+			st.Project++
+		case "global":
+			st.Global++
+		}
+		if rec.ExpiresAt != nil && !rec.ExpiresAt.After(now) {
+			st.Expired++
+		}
+	}
+	if len(agents) > 0 {
+		st.Agents = groupCounts(GroupAgent, agents)
+	}
+	return st
+}
+
+func matchesTerms(rec types.MemoryRecord, query string, terms []string) bool {
+	if query == "" {
+		return true
+	}
+	if len(terms) == 0 {
+		return strings.Contains(searchText(rec.Content, rec.Summary), textnorm.Fold(query))
+	}
+	haystack := analyzedText(rec.Language, rec.Content, rec.Summary, 0)
+	for _, term := range terms {
+		if !slices.ContainsFunc(termForms(term), func(form string) bool {
+			return strings.Contains(haystack, form)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesConds(rec types.MemoryRecord, conds []MetadataCond) bool {
+	for _, c := range conds {
+		if !c.Match(rec.Metadata) {
+			return false
+		}
+	}
+	return true
+}
+
+func isExpired(rec types.MemoryRecord, now time.Time) bool {
+	return rec.ExpiresAt != nil && !rec.ExpiresAt.After(now)
+}
+
+func sortNewestFirst(recs []types.MemoryRecord) {
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].CreatedAt.Equal(recs[j].CreatedAt) {
+			return recs[i].ID > recs[j].ID
+		}
+		return recs[i].CreatedAt.After(recs[j].CreatedAt)
+	})
+}
+
+func cloneRecord(rec types.MemoryRecord) types.MemoryRecord {
+	rec.Metadata = maps.Clone(rec.Metadata)
+	return rec
+}
@@ -0,0 +1,220 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// MemoryStore keeps memories in process only, so everything is lost when
+// the process exits. It serves tests and ephemeral runs with the same
+// search and listing semantics as the markdown store.
+type MemoryStore struct {
+	index *memIndex
+
+	// wmu serializes mutations so read-modify-write updates are atomic.
+	wmu sync.Mutex
+}
+
+// NewMemoryStore returns an empty in-process store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{index: newMemIndex()}
+}
+
+func (s *MemoryStore) InsertMemory(_ context.Context, rec types.MemoryRecord) (types.MemoryRecord, error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	if _, ok := s.index.get(rec.ID); ok {
+		return rec, fmt.Errorf("insert memory: id %s already exists", rec.ID)
+	}
+	setLanguage(&rec)
+	s.index.put(rec)
+	return rec, nil
+}
+
+// InsertMemories stores all records or, when any ID is taken, none.
+func (s *MemoryStore) InsertMemories(_ context.Context, recs []types.MemoryRecord) ([]types.MemoryRecord, error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	seen := make(map[string]struct{}, len(recs))
+	for i := range recs {
+		if _, ok := s.index.get(recs[i].ID); ok {
+			return nil, fmt.Errorf("insert memory: id %s already exists", recs[i].ID)
+		}
+		if _, ok := seen[recs[i].ID]; ok {
+			return nil, fmt.Errorf("insert memory: duplicate id %s in batch", recs[i].ID)
+		}
+		seen[recs[i].ID] = struct{}{}
+		setLanguage(&recs[i])
+	}
+	for _, rec := range recs {
+		s.index.put(rec)
+	}
+	return recs, nil
+}
+
+func (s *MemoryStore) SearchCandidates(_ context.Context, q SearchQuery) ([]Candidate, error) {
+	if q.Advanced {
+		return nil, ErrAdvancedQueryUnsupported
+	}
+	return s.index.search(q), nil
+}
+
+func (s *MemoryStore) Promote(_ context.Context, id string, now time.Time) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	rec, ok := s.index.get(id)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	now = now.UTC()
+	rec.Scope = "long"
+	rec.ExpiresAt = nil
+	rec.PromotedAt = &now
+	rec.LastAccessedAt = now
+	s.index.put(rec)
+	return nil
+}
+
+func (s *MemoryStore) Demote(_ context.Context, id string, expiresAt, now time.Time) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	rec, ok := s.index.get(id)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	expiresAt = expiresAt.UTC()
+	rec.Scope = "short"
+	rec.ExpiresAt = &expiresAt
+	rec.PromotedAt = nil
+	rec.LastAccessedAt = now.UTC()
+	s.index.put(rec)
+	return nil
+}
+
+// UpdateMemory applies fn to id's record. ID, namespace and created_at
+// cannot be changed.
+func (s *MemoryStore) UpdateMemory(_ context.Context, id string, fn func(*types.MemoryRecord) error) (types.MemoryRecord, error) {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	cur, ok := s.index.get(id)
+	if !ok {
+		return types.MemoryRecord{}, sql.ErrNoRows
+	}
+	rec := cloneRecord(cur)
+	if err := fn(&rec); err != nil {
+		return types.MemoryRecord{}, err
+	}
+	rec.ID, rec.Namespace, rec.CreatedAt = cur.ID, cur.Namespace, cur.CreatedAt
+	setLanguage(&rec)
+	s.index.put(rec)
+	return cloneRecord(rec), nil
+}
+
+func (s *MemoryStore) ExpireShort(_ context.Context, now time.Time) (int64, error) {
+	return s.remove(s.index.expiredShort(now)), nil
+}
+
+func (s *MemoryStore) Stats(_ context.Context, now time.Time) (Stats, error) {
+	return s.index.stats(now), nil
+}
+
+func (s *MemoryStore) GetMemory(_ context.Context, id string) (types.MemoryRecord, error) {
+	rec, ok := s.index.get(id)
+	if !ok {
+		return types.MemoryRecord{}, sql.ErrNoRows
+	}
+	return rec, nil
+}
+
+// ListMemories returns memories matching f.
+func (s *MemoryStore) ListMemories(_ context.Context, f ListFilter) ([]types.MemoryRecord, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	return s.index.list(f), nil
+}
+
+// ListByScope returns every memory in scope, oldest first.
+func (s *MemoryStore) ListByScope(_ context.Context, scope string) ([]types.MemoryRecord, error) {
+	return s.index.byScope(scope), nil
+}
+
+// CountBy counts memories matching f grouped by dim, with the same ordering
+// as the SQLite store.
+func (s *MemoryStore) CountBy(_ context.Context, dim string, f ListFilter) ([]GroupCount, error) {
+	return s.index.countBy(dim, f)
+}
+
+func (s *MemoryStore) DeleteMemories(_ context.Context, ids []string) (int64, error) {
+	return s.remove(ids), nil
+}
+
+// DeleteByFilter removes the memories matching f.
+func (s *MemoryStore) DeleteByFilter(_ context.Context, f ListFilter) (int64, error) {
+	f.Sort, f.After, f.Limit, f.Offset = "", nil, 0, 0
+	if err := f.validate(); err != nil {
+		return 0, err
+	}
+	recs := s.index.list(f)
+	ids := make([]string, len(recs))
+	for i, rec := range recs {
+		ids[i] = rec.ID
+	}
+	return s.remove(ids), nil
+}
+
+func (s *MemoryStore) remove(ids []string) int64 {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	var n int64
+	for _, id := range ids {
+		if _, ok := s.index.get(id); ok {
+			s.index.remove(id)
+			n++
+		}
+	}
+	return n
+}
+
+// ExportAll calls fn with every memory, expired ones included, oldest
+// first.
+func (s *MemoryStore) ExportAll(_ context.Context, fn func(types.MemoryRecord) error) error {
+	for _, rec := range s.index.list(ListFilter{IncludeExpired: true, Sort: SortCreatedAsc}) {
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportBatch stores the records whose IDs the store does not hold yet,
+// and returns them with the IDs it skipped, including repeats within recs.
+func (s *MemoryStore) ImportBatch(ctx context.Context, recs []types.MemoryRecord) ([]types.MemoryRecord, []string, error) {
+	var (
+		fresh   []types.MemoryRecord
+		skipped []string
+	)
+	seen := make(map[string]bool, len(recs))
+	for _, rec := range recs {
+		if _, ok := s.index.get(rec.ID); ok || seen[rec.ID] {
+			skipped = append(skipped, rec.ID)
+			continue
+		}
+		seen[rec.ID] = true
+		fresh = append(fresh, rec)
+	}
+	stored, err := s.InsertMemories(ctx, fresh)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stored, skipped, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,287 @@
+package store
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Operators accepted in metadata filter expressions.
+const (
+	OpEq       = "="
+	OpNe       = "!="
+	OpLt       = "<"
+	OpLe       = "<="
+	OpGt       = ">"
+	OpGe       = ">="
+	OpContains = "contains"
+)
+
+// MetadataCond is one parsed condition of a metadata filter, e.g.
+// `metadata.priority >= 2`. Value is a string, float64 or bool.
+type MetadataCond struct {
+	Path  []string
+	Op    string
+	Value any
+}
+
+// ParseMetadataFilter parses conditions of the form
+//
+//	metadata.<key>[.<key>...] <op> <value>
+//
+// joined by "and". Operators are =, !=, <, <=, >, >= and contains; values
+// are numbers, true/false or double-quoted strings. contains matches an
+// element of a list or a substring of a string.
+func ParseMetadataFilter(expr string) ([]MetadataCond, error) {
+	toks, err := lexFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	var conds []MetadataCond
+	for i := 0; i < len(toks); {
+		if len(conds) > 0 {
+			if !strings.EqualFold(toks[i].text, "and") || toks[i].quoted {
+				return nil, fmt.Errorf("filter: expected \"and\" before %q", toks[i].text)
+			}
+			i++
+		}
+		if i+3 > len(toks) {
+			return nil, fmt.Errorf("filter: incomplete condition")
+		}
+		cond, err := parseCond(toks[i], toks[i+1], toks[i+2])
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, cond)
+		i += 3
+	}
+	return conds, nil
+}
+
+func parseCond(field, op, value filterToken) (MetadataCond, error) {
+	path, ok := strings.CutPrefix(field.text, "metadata.")
+	if field.quoted || !ok {
+		return MetadataCond{}, fmt.Errorf("filter: %q must start with metadata.", field.text)
+	}
+	cond := MetadataCond{Path: strings.Split(path, ".")}
+	for _, seg := range cond.Path {
+		if !ValidMetadataColumnKey(seg) {
+			return MetadataCond{}, fmt.Errorf("filter: invalid metadata path %q", path)
+		}
+	}
+
+	switch o := strings.ToLower(op.text); o {
+	case OpEq, OpNe, OpLt, OpLe, OpGt, OpGe, OpContains:
+		cond.Op = o
+	case "==":
+		cond.Op = OpEq
+	default:
+		return MetadataCond{}, fmt.Errorf("filter: unknown operator %q", op.text)
+	}
+
+	switch {
+	case value.quoted:
+		cond.Value = value.text
+	case value.text == "true":
+		cond.Value = true
+	case value.text == "false":
+		cond.Value = false
+	default:
+		n, err := strconv.ParseFloat(value.text, 64)
+		if err != nil {
+			return MetadataCond{}, fmt.Errorf("filter: value %q must be a number, true, false or a quoted string", value.text)
+		}
+		cond.Value = n
+	}
+	if _, isBool := cond.Value.(bool); isBool && cond.Op != OpEq && cond.Op != OpNe {
+		return MetadataCond{}, fmt.Errorf("filter: %s does not apply to booleans", cond.Op)
+	}
+	return cond, nil
+}
+
+type filterToken struct {
+	text   string
+	quoted bool
+}
+
+func lexFilter(expr string) ([]filterToken, error) {
+	var toks []filterToken
+	rs := []rune(expr)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			var b strings.Builder
+			i++
+			for ; i < len(rs) && rs[i] != '"'; i++ {
+				if rs[i] == '\\' && i+1 < len(rs) {
+					i++
+				}
+				b.WriteRune(rs[i])
+			}
+			if i >= len(rs) {
+				return nil, fmt.Errorf("filter: unterminated string")
+			}
+			i++
+			toks = append(toks, filterToken{text: b.String(), quoted: true})
+		case strings.ContainsRune("=!<>", r):
+			j := i + 1
+			if j < len(rs) && rs[j] == '=' {
+				j++
+			}
+			toks = append(toks, filterToken{text: string(rs[i:j])})
+			i = j
+		default:
+			j := i
+			for j < len(rs) && !unicode.IsSpace(rs[j]) && !strings.ContainsRune("=!<>\"", rs[j]) {
+				j++
+			}
+			toks = append(toks, filterToken{text: string(rs[i:j])})
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+// metadataCondSQL translates c into a condition over metadata_json. String
+// equality on a key with a generated column uses the column's index.
+func metadataCondSQL(c MetadataCond, metaCols map[string]struct{}) (string, []any) {
+	jsonPath := "$." + strings.Join(c.Path, ".")
+	arg := c.Value
+	if b, ok := arg.(bool); ok {
+		// json_extract returns JSON booleans as 1/0.
+		arg = 0
+		if b {
+			arg = 1
+		}
+	}
+
+	if c.Op == OpContains {
+		return `(EXISTS (SELECT 1 FROM json_each(metadata_json, ?) WHERE value = ?)
+    OR (json_type(metadata_json, ?) = 'text' AND instr(json_extract(metadata_json, ?), ?) > 0))`,
+			[]any{jsonPath, arg, jsonPath, jsonPath, fmt.Sprint(arg)}
+	}
+	if s, ok := c.Value.(string); ok && c.Op == OpEq && len(c.Path) == 1 {
+		if _, indexed := metaCols[c.Path[0]]; indexed {
+			return "(" + metaColumnPrefix + c.Path[0] + " = ? AND json_type(metadata_json, ?) = 'text')", []any{s, jsonPath}
+		}
+	}
+	// The type guard keeps SQLite from ordering numbers before text, so a
+	// value of another type never matches, as in Match.
+	var types string
+	switch c.Value.(type) {
+	case float64:
+		types = "'integer', 'real'"
+	case string:
+		types = "'text'"
+	default:
+		types = "'true', 'false'"
+	}
+	return "(json_type(metadata_json, ?) IN (" + types + ") AND json_extract(metadata_json, ?) " + c.Op + " ?)",
+		[]any{jsonPath, jsonPath, arg}
+}
+
+// Match evaluates c against meta with the same semantics as the SQL form:
+// a missing key or a value of another type matches nothing.
+func (c MetadataCond) Match(meta map[string]any) bool {
+	var v any = meta
+	for _, seg := range c.Path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return false
+		}
+		if v, ok = m[seg]; !ok {
+			return false
+		}
+	}
+
+	if c.Op == OpContains {
+		switch v := v.(type) {
+		case []any:
+			for _, e := range v {
+				if sameValue(e, c.Value) {
+					return true
+				}
+			}
+		case []string:
+			for _, e := range v {
+				if sameValue(e, c.Value) {
+					return true
+				}
+			}
+		case string:
+			return strings.Contains(v, fmt.Sprint(c.Value))
+		}
+		return false
+	}
+
+	cmp, ok := compareValues(v, c.Value)
+	if !ok {
+		return false
+	}
+	switch c.Op {
+	case OpEq:
+		return cmp == 0
+	case OpNe:
+		return cmp != 0
+	case OpLt:
+		return cmp < 0
+	case OpLe:
+		return cmp <= 0
+	case OpGt:
+		return cmp > 0
+	case OpGe:
+		return cmp >= 0
+	}
+	return false
+}
+
+func sameValue(a, b any) bool {
+	cmp, ok := compareValues(a, b)
+	return ok && cmp == 0
+}
+
+// compareValues orders a against b when both are numbers, strings or
+// booleans of the same kind.
+func compareValues(a, b any) (int, bool) {
+	switch b := b.(type) {
+	case float64:
+		var f float64
+		switch a := a.(type) {
+		case float64:
+			f = a
+		case int:
+			f = float64(a)
+		case int64:
+			f = float64(a)
+		default:
+			return 0, false
+		}
+		switch {
+		case f < b:
+			return -1, true
+		case f > b:
+			return 1, true
+		}
+		return 0, true
+	case string:
+		s, ok := a.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(s, b), true
+	case bool:
+		x, ok := a.(bool)
+		if !ok {
+			return 0, false
+		}
+		if x == b {
+			return 0, true
+		}
+		return 1, true
+	}
+	return 0, false
+}
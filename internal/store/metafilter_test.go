@@ -0,0 +1,124 @@
+package store
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestParseMetadataFilter(t *testing.T) {
+	t.Parallel()
+	conds, err := ParseMetadataFilter(`metadata.priority >= 2 AND metadata.files contains "auth.go" and metadata.review.done = true`)
+	if err != nil {
+		t.Fatalf("ParseMetadataFilter() error = %v", err)
+	}
+	want := []MetadataCond{
+		{Path: []string{"priority"}, Op: OpGe, Value: 2.0},
+		{Path: []string{"files"}, Op: OpContains, Value: "auth.go"},
+		{Path: []string{"review", "done"}, Op: OpEq, Value: true},
+	}
+	if !reflect.DeepEqual(conds, want) {
+		t.Fatalf("got %#v, want %#v", conds, want)
+	}
+
+	if conds, err := ParseMetadataFilter("  "); err != nil || len(conds) != 0 {
+		t.Fatalf("expected empty filter to parse to nothing, got %v, %v", conds, err)
+	}
+	for _, bad := range []string{
+		`priority >= 2`,
+		`metadata.priority ~ 2`,
+		`metadata.priority >= high`,
+		`metadata.owner = "dana`,
+		`metadata.priority >= 2 metadata.owner = "dana"`,
+		`metadata.done > true`,
+		`metadata.bad-key = 1`,
+		`metadata.priority >=`,
+	} {
+		if _, err := ParseMetadataFilter(bad); err == nil {
+			t.Errorf("ParseMetadataFilter(%q) expected error", bad)
+		}
+	}
+}
+
+func TestSearchCandidates_MetadataFilterConsistentAcrossDrivers(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	recs := []types.MemoryRecord{
+		{ID: "f-1", Metadata: map[string]any{"priority": float64(3), "files": []any{"auth.go", "session.go"}, "owner": "dana"}},
+		{ID: "f-2", Metadata: map[string]any{"priority": float64(1), "files": []any{"main.go"}, "owner": "lee", "review": map[string]any{"done": true}}},
+		{ID: "f-3", Metadata: map[string]any{"priority": "high", "files": "auth.go,db.go", "owner": "dana"}},
+		{ID: "f-4"},
+	}
+	for i := range recs {
+		recs[i].Namespace = "org/repo/task"
+		recs[i].Scope = "long"
+		recs[i].Content = "release checklist " + recs[i].ID
+		recs[i].CreatedAt = now.Add(-time.Duration(i+1) * time.Hour)
+		recs[i].LastAccessedAt = recs[i].CreatedAt
+	}
+
+	sqliteStore, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "filter.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer sqliteStore.Close()
+	indexedStore, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "filter-indexed.db"), logger, WithMetadataColumns("owner"))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer indexedStore.Close()
+	mdStore, err := OpenMarkdown(ctx, t.TempDir(), false, logger)
+	if err != nil {
+		t.Fatalf("OpenMarkdown() error = %v", err)
+	}
+
+	cases := []struct {
+		filter string
+		want   []string
+	}{
+		{`metadata.priority >= 2`, []string{"f-1"}},
+		{`metadata.priority != 3`, []string{"f-2"}},
+		{`metadata.files contains "auth.go"`, []string{"f-1", "f-3"}},
+		{`metadata.owner = "dana" and metadata.priority < 5`, []string{"f-1"}},
+		{`metadata.owner = "dana"`, []string{"f-1", "f-3"}},
+		{`metadata.review.done = true`, []string{"f-2"}},
+		{`metadata.missing = 1`, []string{}},
+	}
+
+	for _, st := range []Store{sqliteStore, indexedStore, mdStore, NewMemoryStore()} {
+		if _, err := st.InsertMemories(ctx, recs); err != nil {
+			t.Fatalf("%T.InsertMemories() error = %v", st, err)
+		}
+		for _, query := range []string{"", "checklist"} {
+			for _, tc := range cases {
+				conds, err := ParseMetadataFilter(tc.filter)
+				if err != nil {
+					t.Fatalf("ParseMetadataFilter(%q) error = %v", tc.filter, err)
+				}
+				cands, err := st.SearchCandidates(ctx, SearchQuery{Namespace: "org/repo/task", Query: query, Limit: 10, Now: now, Metadata: conds})
+				if err != nil {
+					t.Fatalf("%T %q: SearchCandidates() error = %v", st, tc.filter, err)
+				}
+				ids := []string{}
+				for _, c := range cands {
+					ids = append(ids, c.Record.ID)
+				}
+				sort.Strings(ids)
+				if !reflect.DeepEqual(ids, tc.want) {
+					t.Fatalf("%T query=%q %q: got %v, want %v", st, query, tc.filter, ids, tc.want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/charmbracelet/log"
+)
+
+func TestMigrate_AppliesInOrderAndRollsBackFailures(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "m.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	embedded, err := loadMigrations(migrationFiles)
+	if err != nil {
+		t.Fatalf("loadMigrations() error = %v", err)
+	}
+	base := embedded[len(embedded)-1].version
+	if v, err := st.SchemaVersion(ctx); err != nil || v != base {
+		t.Fatalf("SchemaVersion() of a new database = %d, %v, want %d", v, err, base)
+	}
+
+	fsys := fstest.MapFS{
+		"migrations/0100_links.sql":  {Data: []byte("CREATE TABLE links (a TEXT);\nCREATE INDEX idx_links_a ON links(a);")},
+		"migrations/0101_broken.sql": {Data: []byte("CREATE TABLE broken (a TEXT);\nALTER TABLE nowhere ADD COLUMN b TEXT;")},
+	}
+	extra, err := loadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("loadMigrations() error = %v", err)
+	}
+	err = st.migrate(ctx, append(embedded, extra...))
+	if err == nil || !strings.Contains(err.Error(), "migration 101 (broken)") {
+		t.Fatalf("migrate() error = %v, want migration 101 to fail", err)
+	}
+	if v, _ := st.SchemaVersion(ctx); v != 100 {
+		t.Fatalf("SchemaVersion() = %d, want 100", v)
+	}
+	var n int
+	if err := st.db.QueryRowContext(ctx, `SELECT count(*) FROM sqlite_master WHERE name IN ('links', 'idx_links_a', 'broken')`).Scan(&n); err != nil || n != 2 {
+		t.Fatalf("tables and indexes after a failed migration = %d, %v, want links and its index only", n, err)
+	}
+
+	if err := st.migrate(ctx, embedded); err == nil || !strings.Contains(err.Error(), "newer than this release") {
+		t.Fatalf("migrate() of a newer database error = %v, want a refusal", err)
+	}
+}
+
+func TestLoadMigrations_RejectsBadNames(t *testing.T) {
+	t.Parallel()
+	for name, fsys := range map[string]fstest.MapFS{
+		"no version": {"migrations/links.sql": {}},
+		"duplicate":  {"migrations/0002_a.sql": {}, "migrations/2_b.sql": {}},
+	} {
+		if _, err := loadMigrations(fsys); err == nil {
+			t.Fatalf("loadMigrations(%s) error = nil", name)
+		}
+	}
+}
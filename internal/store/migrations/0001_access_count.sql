@@ -0,0 +1,2 @@
+-- Times the memory was returned by a search or context pack.
+ALTER TABLE memories ADD COLUMN access_count INTEGER NOT NULL DEFAULT 0;
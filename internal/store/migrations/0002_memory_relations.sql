@@ -0,0 +1,16 @@
+-- Directed links between memories: from_id <relation> to_id, e.g. a new
+-- decision that supersedes an old one.
+CREATE TABLE memory_relations (
+  from_id TEXT NOT NULL,
+  to_id TEXT NOT NULL,
+  relation TEXT NOT NULL CHECK(relation IN ('supersedes', 'refines', 'relates_to', 'derived_from')),
+  source_agent TEXT NOT NULL DEFAULT '',
+  created_at TEXT NOT NULL,
+  PRIMARY KEY (from_id, to_id, relation)
+);
+
+CREATE INDEX idx_memory_relations_to ON memory_relations(to_id);
+
+CREATE TRIGGER memories_relations_ad AFTER DELETE ON memories BEGIN
+  DELETE FROM memory_relations WHERE from_id = old.id OR to_id = old.id;
+END;
@@ -0,0 +1,44 @@
+-- Allows the project and global scopes. SQLite cannot change a CHECK
+-- constraint in place, so memories is rebuilt, keeping rowids for the FTS
+-- index. Its indexes and the relations trigger go with the old table and
+-- are recreated here; the other triggers and the md_* metadata columns are
+-- recreated at open.
+CREATE TABLE memories_new (
+  id TEXT PRIMARY KEY,
+  namespace TEXT NOT NULL,
+  scope TEXT NOT NULL CHECK(scope IN ('short', 'long', 'project', 'global')),
+  content TEXT NOT NULL,
+  summary TEXT NOT NULL DEFAULT '',
+  importance INTEGER NOT NULL DEFAULT 3,
+  source_agent TEXT NOT NULL DEFAULT '',
+  metadata_json TEXT NOT NULL DEFAULT '{}',
+  created_at TEXT NOT NULL,
+  last_accessed_at TEXT NOT NULL,
+  expires_at TEXT,
+  promoted_at TEXT,
+  meta_text TEXT NOT NULL DEFAULT '',
+  search_text TEXT NOT NULL DEFAULT '',
+  language TEXT NOT NULL DEFAULT '',
+  external_key TEXT NOT NULL DEFAULT '',
+  access_count INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT INTO memories_new (rowid, id, namespace, scope, content, summary, importance, source_agent, metadata_json,
+  created_at, last_accessed_at, expires_at, promoted_at, meta_text, search_text, language, external_key, access_count)
+SELECT rowid, id, namespace, scope, content, summary, importance, source_agent, metadata_json,
+  created_at, last_accessed_at, expires_at, promoted_at, meta_text, search_text, language, external_key, access_count
+FROM memories;
+
+DROP TABLE memories;
+ALTER TABLE memories_new RENAME TO memories;
+
+CREATE INDEX idx_memories_ns_scope_created ON memories(namespace, scope, created_at DESC, expires_at);
+CREATE INDEX idx_memories_ns_created ON memories(namespace, created_at DESC, expires_at);
+CREATE INDEX idx_memories_expires_at ON memories(expires_at);
+CREATE INDEX idx_memories_created_at ON memories(created_at DESC);
+-- Finds the namespaces holding project or global memories.
+CREATE INDEX idx_memories_scope_ns ON memories(scope, namespace);
+
+CREATE TRIGGER memories_relations_ad AFTER DELETE ON memories BEGIN
+  DELETE FROM memory_relations WHERE from_id = old.id OR to_id = old.id;
+END;
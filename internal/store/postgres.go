@@ -0,0 +1,915 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/xiy/memory-mcp/internal/clock"
+	"github.com/xiy/memory-mcp/internal/textnorm"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+//go:embed postgres_schema.sql
+var postgresSchemaSQL string
+
+// postgresSchemaLock is the advisory lock key that serializes schema setup
+// between processes opening the same database.
+const postgresSchemaLock = 0x6d656d6f7279
+
+const pgMemoryColumns = `id, namespace, scope, content, summary, importance, source_agent,
+       metadata::text, created_at, last_accessed_at, expires_at, promoted_at, language, external_key, access_count`
+
+// PostgresStore is a PostgreSQL-backed memory store, so agents on several
+// hosts can share one database. Search uses a tsvector index over the same
+// folded and stemmed text the SQLite store indexes, with the same LIKE
+// fallback. There is no record cache, since other hosts write the same
+// rows. Timestamps keep microseconds.
+type PostgresStore struct {
+	db          *sql.DB
+	logger      *log.Logger
+	clock       clock.Clock
+	maxConns    int
+	ftsMetaKeys []string
+	cjkNgram    int
+}
+
+// PostgresOption customizes a PostgresStore.
+type PostgresOption func(*PostgresStore)
+
+// WithPostgresMaxConns caps the connection pool (10 by default).
+func WithPostgresMaxConns(n int) PostgresOption {
+	return func(s *PostgresStore) { s.maxConns = n }
+}
+
+// WithPostgresAnalyzer sets the metadata keys indexed with content and the
+// CJK n-gram size, as WithFTSMetadataKeys and WithCJKNgram do for SQLite.
+// Changing them affects memories written afterwards only.
+func WithPostgresAnalyzer(ftsMetaKeys []string, cjkNgram int) PostgresOption {
+	return func(s *PostgresStore) { s.ftsMetaKeys, s.cjkNgram = ftsMetaKeys, cjkNgram }
+}
+
+// WithPostgresClock replaces the wall clock used for store-assigned
+// timestamps.
+func WithPostgresClock(c clock.Clock) PostgresOption {
+	return func(s *PostgresStore) { s.clock = c }
+}
+
+// OpenPostgres connects to dsn, a postgres:// URL or key=value connection
+// string, and creates the schema if needed.
+func OpenPostgres(ctx context.Context, dsn string, logger *log.Logger, opts ...PostgresOption) (*PostgresStore, error) {
+	s := &PostgresStore{logger: logger, clock: clock.System{}, maxConns: 10, cjkNgram: 2}
+	for _, opt := range opts {
+		opt(s)
+	}
+	db, err := openTraced("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open postgres: %w", err)
+	}
+	db.SetMaxOpenConns(s.maxConns)
+	db.SetMaxIdleConns(s.maxConns)
+	s.db = db
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("connect postgres: %w", err)
+	}
+	if err := s.init(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *PostgresStore) init(ctx context.Context) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, postgresSchemaLock); err != nil {
+			return fmt.Errorf("lock postgres schema: %w", err)
+		}
+		for _, stmt := range splitSQLStatements(stripSQLComments(postgresSchemaSQL)) {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("apply postgres schema: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// stripSQLComments drops -- comments, which may contain semicolons.
+func stripSQLComments(q string) string {
+	lines := strings.Split(q, "\n")
+	for i, line := range lines {
+		if before, _, found := strings.Cut(line, "--"); found {
+			lines[i] = before
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// withTx runs fn in a transaction, committing on success and rolling back
+// on error.
+func (s *PostgresStore) withTx(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) query(ctx context.Context, q string, args ...any) (*sql.Rows, error) {
+	return s.db.QueryContext(ctx, pgRebind(q), args...)
+}
+
+func (s *PostgresStore) exec(ctx context.Context, q string, args ...any) (sql.Result, error) {
+	return s.db.ExecContext(ctx, pgRebind(q), args...)
+}
+
+func (s *PostgresStore) InsertMemory(ctx context.Context, rec types.MemoryRecord) (types.MemoryRecord, error) {
+	setLanguage(&rec)
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := s.insertMemoryTx(ctx, tx, rec, false)
+		return err
+	})
+	return rec, err
+}
+
+// InsertMemories stores all records in one transaction; either every record
+// is inserted or none is.
+func (s *PostgresStore) InsertMemories(ctx context.Context, recs []types.MemoryRecord) ([]types.MemoryRecord, error) {
+	if len(recs) == 0 {
+		return recs, nil
+	}
+	for i := range recs {
+		setLanguage(&recs[i])
+	}
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		for _, rec := range recs {
+			if _, err := s.insertMemoryTx(ctx, tx, rec, false); err != nil {
+				return fmt.Errorf("memory %s: %w", rec.ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recs, nil
+}
+
+// insertMemoryTx inserts rec and reports whether it did; with skipExisting
+// a taken ID is skipped instead of failing.
+func (s *PostgresStore) insertMemoryTx(ctx context.Context, tx *sql.Tx, rec types.MemoryRecord, skipExisting bool) (bool, error) {
+	meta := rec.Metadata
+	if meta == nil {
+		meta = map[string]any{}
+	}
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return false, fmt.Errorf("marshal metadata: %w", err)
+	}
+	q := `INSERT INTO memories (
+		id, namespace, scope, content, summary, importance, source_agent, metadata,
+		created_at, last_accessed_at, expires_at, promoted_at, meta_text, search_text, language, external_key,
+		access_count
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?::jsonb, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	if skipExisting {
+		q += " ON CONFLICT (id) DO NOTHING"
+	}
+	res, err := tx.ExecContext(ctx, pgRebind(q),
+		rec.ID,
+		rec.Namespace,
+		rec.Scope,
+		rec.Content,
+		rec.Summary,
+		rec.Importance,
+		rec.SourceAgent,
+		string(metaJSON),
+		rec.CreatedAt.UTC(),
+		rec.LastAccessedAt.UTC(),
+		pgNullTime(rec.ExpiresAt),
+		pgNullTime(rec.PromotedAt),
+		ngramText(metadataText(meta, s.ftsMetaKeys), s.cjkNgram),
+		analyzedText(rec.Language, rec.Content, rec.Summary, s.cjkNgram),
+		rec.Language,
+		rec.ExternalKey,
+		rec.AccessCount,
+	)
+	if err != nil {
+		return false, fmt.Errorf("insert memory: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("insert rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (s *PostgresStore) SearchCandidates(ctx context.Context, q SearchQuery) ([]Candidate, error) {
+	if q.Advanced {
+		return nil, ErrAdvancedQueryUnsupported
+	}
+	if q.Limit <= 0 {
+		q.Limit = 10
+	}
+	q.Query = strings.TrimSpace(q.Query)
+	terms := stemTerms(ngramTerms(tokenizeQueryTerms(q.Query, q.StopWords), s.cjkNgram))
+
+	if tsq := pgTSQuery(terms); tsq != "" {
+		rows, err := s.searchFTS(ctx, q, tsq)
+		if err == nil && len(rows) > 0 {
+			return withHighlights(rows, termMatcher(terms)), nil
+		}
+		if err != nil {
+			s.logger.Warn("fts query failed; fallback to LIKE", "error", err)
+		}
+	}
+	rows, err := s.searchLIKE(ctx, q, terms)
+	if err != nil {
+		return nil, err
+	}
+	return withHighlights(rows, termMatcher(terms)), nil
+}
+
+func (s *PostgresStore) searchFTS(ctx context.Context, q SearchQuery, tsq string) ([]Candidate, error) {
+	base := `
+SELECT ` + pgMemoryColumns + `, ts_rank(search_tsv, tsq, 32) AS rank
+FROM memories, to_tsquery('simple', ?) AS tsq
+WHERE search_tsv @@ tsq
+  AND namespace = ?
+  AND (expires_at IS NULL OR expires_at > ?)
+`
+	args := []any{tsq, q.Namespace, q.Now.UTC()}
+	base, args = pgSearchFilters(base, args, q)
+	base += "ORDER BY rank DESC, created_at DESC LIMIT ?"
+	args = append(args, q.Limit)
+
+	rows, err := s.query(ctx, base, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]Candidate, 0, q.Limit)
+	for rows.Next() {
+		var rank float64
+		rec, err := scanPGMemory(rows, &rank)
+		if err != nil {
+			return nil, err
+		}
+		// rank is normalized into [0, 1); lifting it above 0.5 keeps FTS
+		// matches ahead of the LIKE fallback's flat 0.4, as with SQLite.
+		items = append(items, Candidate{Record: rec, LexicalScore: 0.5 + 0.5*rank})
+	}
+	return items, rows.Err()
+}
+
+func (s *PostgresStore) searchLIKE(ctx context.Context, q SearchQuery, terms []string) ([]Candidate, error) {
+	base := `
+SELECT ` + pgMemoryColumns + `
+FROM memories
+WHERE namespace = ?
+  AND (expires_at IS NULL OR expires_at > ?)
+`
+	args := []any{q.Namespace, q.Now.UTC()}
+	if len(terms) > 0 {
+		for _, term := range terms {
+			var conds []string
+			for _, form := range termForms(term) {
+				conds = append(conds, `search_text LIKE ? ESCAPE '\' OR meta_text LIKE ? ESCAPE '\'`)
+				needle := "%" + escapeLike(strings.TrimSuffix(form, "*")) + "%"
+				args = append(args, needle, needle)
+			}
+			base += " AND (" + strings.Join(conds, " OR ") + ")\n"
+		}
+	} else if q.Query != "" {
+		base += ` AND (search_text LIKE ? ESCAPE '\' OR meta_text LIKE ? ESCAPE '\')` + "\n"
+		needle := "%" + escapeLike(textnorm.Fold(q.Query)) + "%"
+		args = append(args, needle, needle)
+	}
+	base, args = pgSearchFilters(base, args, q)
+	base += "ORDER BY created_at DESC LIMIT ?"
+	args = append(args, q.Limit)
+
+	rows, err := s.query(ctx, base, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search like: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]Candidate, 0, q.Limit)
+	for rows.Next() {
+		rec, err := scanPGMemory(rows)
+		if err != nil {
+			return nil, err
+		}
+		lex := 0.4
+		if q.Query == "" {
+			lex = 0.25
+		}
+		items = append(items, Candidate{Record: rec, LexicalScore: lex})
+	}
+	return items, rows.Err()
+}
+
+// pgSearchFilters appends the scope, source agent and metadata conditions
+// of q.
+func pgSearchFilters(base string, args []any, q SearchQuery) (string, []any) {
+	if q.Scope != "" {
+		base += " AND scope = ?\n"
+		args = append(args, q.Scope)
+	}
+	if q.SourceAgent != "" {
+		base += " AND source_agent = ?\n"
+		args = append(args, q.SourceAgent)
+	}
+	for _, c := range q.Metadata {
+		cond, condArgs := pgMetadataCondSQL(c)
+		base += " AND " + cond + "\n"
+		args = append(args, condArgs...)
+	}
+	return base, args
+}
+
+func (s *PostgresStore) Promote(ctx context.Context, id string, now time.Time) error {
+	res, err := s.exec(ctx, `UPDATE memories
+SET scope = 'long', expires_at = NULL, promoted_at = ?, last_accessed_at = ?
+WHERE id = ?`, now.UTC(), now.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("promote memory: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("promote rows affected: %w", err)
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s *PostgresStore) Demote(ctx context.Context, id string, expiresAt, now time.Time) error {
+	res, err := s.exec(ctx, `UPDATE memories
+SET scope = 'short', expires_at = ?, promoted_at = NULL, last_accessed_at = ?
+WHERE id = ?`, expiresAt.UTC(), now.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("demote memory: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("demote rows affected: %w", err)
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UpdateMemory loads id with a row lock, applies fn and saves the result in
+// one transaction, so concurrent updates from any host cannot lose each
+// other's changes. ID, namespace and created_at cannot be changed.
+func (s *PostgresStore) UpdateMemory(ctx context.Context, id string, fn func(*types.MemoryRecord) error) (types.MemoryRecord, error) {
+	var rec types.MemoryRecord
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, `SELECT `+pgMemoryColumns+` FROM memories WHERE id = $1 FOR UPDATE`, id)
+		cur, err := scanPGMemory(row)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			return fmt.Errorf("load memory: %w", err)
+		}
+		rec = cur
+		if err := fn(&rec); err != nil {
+			return err
+		}
+		rec.ID, rec.Namespace, rec.CreatedAt = cur.ID, cur.Namespace, cur.CreatedAt
+		setLanguage(&rec)
+
+		meta := rec.Metadata
+		if meta == nil {
+			meta = map[string]any{}
+		}
+		metaJSON, err := json.Marshal(meta)
+		if err != nil {
+			return fmt.Errorf("marshal metadata: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, pgRebind(`UPDATE memories
+SET scope = ?, content = ?, summary = ?, importance = ?, source_agent = ?, metadata = ?::jsonb,
+    last_accessed_at = ?, expires_at = ?, promoted_at = ?, meta_text = ?, search_text = ?, language = ?,
+    external_key = ?
+WHERE id = ?`),
+			rec.Scope,
+			rec.Content,
+			rec.Summary,
+			rec.Importance,
+			rec.SourceAgent,
+			string(metaJSON),
+			rec.LastAccessedAt.UTC(),
+			pgNullTime(rec.ExpiresAt),
+			pgNullTime(rec.PromotedAt),
+			ngramText(metadataText(meta, s.ftsMetaKeys), s.cjkNgram),
+			analyzedText(rec.Language, rec.Content, rec.Summary, s.cjkNgram),
+			rec.Language,
+			rec.ExternalKey,
+			rec.ID,
+		)
+		if err != nil {
+			return fmt.Errorf("update memory: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return types.MemoryRecord{}, err
+	}
+	return rec, nil
+}
+
+func (s *PostgresStore) ExpireShort(ctx context.Context, now time.Time) (int64, error) {
+	res, err := s.exec(ctx, `DELETE FROM memories WHERE scope <> 'long' AND expires_at IS NOT NULL AND expires_at <= ?`, now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("expire short memories: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func (s *PostgresStore) Stats(ctx context.Context, now time.Time) (Stats, error) {
+	var st Stats
+	err := s.db.QueryRowContext(ctx, `SELECT count(*),
+       count(*) FILTER (WHERE scope = 'short'),
+       count(*) FILTER (WHERE scope = 'long'),
+       count(*) FILTER (WHERE scope = 'project'),
+       count(*) FILTER (WHERE scope = 'global'),
+       count(*) FILTER (WHERE expires_at IS NOT NULL AND expires_at <= $1)
+FROM memories`, now.UTC()).Scan(&st.Total, &st.Short, &st.Long, &st.Project, &st.Global, &st.Expired)
+	if err != nil {
+		return st, err
+	}
+	st.Agents, err = s.CountBy(ctx, GroupAgent, ListFilter{IncludeExpired: true, Now: now})
+	return st, err
+}
+
+func (s *PostgresStore) GetMemory(ctx context.Context, id string) (types.MemoryRecord, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+pgMemoryColumns+` FROM memories WHERE id = $1`, id)
+	rec, err := scanPGMemory(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return rec, err
+		}
+		return rec, fmt.Errorf("get memory: %w", err)
+	}
+	return rec, nil
+}
+
+// ListMemories returns memories matching f.
+func (s *PostgresStore) ListMemories(ctx context.Context, f ListFilter) ([]types.MemoryRecord, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	where, args := pgListWhere(f)
+	q := `SELECT ` + pgMemoryColumns + ` FROM memories`
+	if len(where) > 0 {
+		q += "\nWHERE " + strings.Join(where, "\n  AND ")
+	}
+	switch f.Sort {
+	case SortCreatedAsc:
+		q += "\nORDER BY created_at ASC, id COLLATE \"C\" ASC"
+	case SortImportanceDesc:
+		q += "\nORDER BY importance DESC, created_at DESC, id COLLATE \"C\" DESC"
+	default:
+		q += "\nORDER BY created_at DESC, id COLLATE \"C\" DESC"
+	}
+	if f.Limit > 0 {
+		q += "\nLIMIT ?"
+		args = append(args, f.Limit)
+	}
+	if f.Offset > 0 {
+		q += "\nOFFSET ?"
+		args = append(args, f.Offset)
+	}
+	return s.queryMemories(ctx, "list memories", q, args...)
+}
+
+// ListByScope returns every memory in scope, oldest first.
+func (s *PostgresStore) ListByScope(ctx context.Context, scope string) ([]types.MemoryRecord, error) {
+	return s.queryMemories(ctx, "list memories by scope", `SELECT `+pgMemoryColumns+` FROM memories WHERE scope = ?
+ORDER BY created_at ASC, id COLLATE "C" ASC`, scope)
+}
+
+func (s *PostgresStore) queryMemories(ctx context.Context, what, q string, args ...any) ([]types.MemoryRecord, error) {
+	rows, err := s.query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", what, err)
+	}
+	defer rows.Close()
+
+	var items []types.MemoryRecord
+	for rows.Next() {
+		rec, err := scanPGMemory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan memory: %w", err)
+		}
+		items = append(items, rec)
+	}
+	return items, rows.Err()
+}
+
+// CountBy counts memories matching f grouped by dim, with the same ordering
+// as the SQLite store.
+func (s *PostgresStore) CountBy(ctx context.Context, dim string, f ListFilter) ([]GroupCount, error) {
+	col, err := pgGroupColumn(dim)
+	if err != nil {
+		return nil, err
+	}
+	where, args := pgListWhere(f)
+	q := "SELECT " + col + " AS k, count(*) AS n FROM memories"
+	if len(where) > 0 {
+		q += "\nWHERE " + strings.Join(where, "\n  AND ")
+	}
+	q += "\nGROUP BY k"
+	if dim == GroupDay {
+		q += "\nORDER BY k ASC"
+	} else {
+		q += "\nORDER BY n DESC, k ASC"
+	}
+
+	rows, err := s.query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("count memories by %s: %w", dim, err)
+	}
+	defer rows.Close()
+
+	var groups []GroupCount
+	for rows.Next() {
+		var g GroupCount
+		if err := rows.Scan(&g.Key, &g.Count); err != nil {
+			return nil, fmt.Errorf("scan group count: %w", err)
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+func (s *PostgresStore) DeleteMemories(ctx context.Context, ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	res, err := s.exec(ctx, `DELETE FROM memories WHERE id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("delete memories: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// DeleteByFilter removes the matching rows in one statement.
+func (s *PostgresStore) DeleteByFilter(ctx context.Context, f ListFilter) (int64, error) {
+	f.Sort, f.After, f.Limit, f.Offset = "", nil, 0, 0
+	if err := f.validate(); err != nil {
+		return 0, err
+	}
+	where, args := pgListWhere(f)
+	q := `DELETE FROM memories`
+	if len(where) > 0 {
+		q += "\nWHERE " + strings.Join(where, "\n  AND ")
+	}
+	res, err := s.exec(ctx, q, args...)
+	if err != nil {
+		return 0, fmt.Errorf("delete memories: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// ExportAll calls fn with every memory, expired ones included, oldest
+// first. It stops at the first error fn returns.
+func (s *PostgresStore) ExportAll(ctx context.Context, fn func(types.MemoryRecord) error) error {
+	rows, err := s.query(ctx, `SELECT `+pgMemoryColumns+` FROM memories ORDER BY created_at ASC, id COLLATE "C" ASC`)
+	if err != nil {
+		return fmt.Errorf("export memories: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		rec, err := scanPGMemory(rows)
+		if err != nil {
+			return fmt.Errorf("scan memory: %w", err)
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ImportBatch inserts the records whose IDs the store does not hold yet in
+// one transaction, and returns them with the IDs it skipped, including
+// repeats within recs.
+func (s *PostgresStore) ImportBatch(ctx context.Context, recs []types.MemoryRecord) ([]types.MemoryRecord, []string, error) {
+	var (
+		stored  []types.MemoryRecord
+		skipped []string
+	)
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		stored, skipped = nil, nil
+		for _, rec := range recs {
+			setLanguage(&rec)
+			inserted, err := s.insertMemoryTx(ctx, tx, rec, true)
+			if err != nil {
+				return fmt.Errorf("memory %s: %w", rec.ID, err)
+			}
+			if !inserted {
+				skipped = append(skipped, rec.ID)
+				continue
+			}
+			stored = append(stored, rec)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return stored, skipped, nil
+}
+
+// RecordAccess implements AccessRecorder.
+func (s *PostgresStore) RecordAccess(ctx context.Context, counts map[string]int, at time.Time) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, `UPDATE memories
+SET access_count = access_count + $1, last_accessed_at = $2
+WHERE id = $3`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		// A fixed order keeps concurrent flushes from deadlocking.
+		for _, id := range slices.Sorted(maps.Keys(counts)) {
+			if _, err := stmt.ExecContext(ctx, counts[id], at.UTC(), id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("record memory access: %w", err)
+	}
+	return nil
+}
+
+// ExtendExpiry implements ExpiryExtender.
+func (s *PostgresStore) ExtendExpiry(ctx context.Context, id string, expiresAt, now time.Time) error {
+	_, err := s.exec(ctx, `UPDATE memories SET expires_at = ?, last_accessed_at = ? WHERE id = ? AND scope = 'short'`, expiresAt.UTC(), now.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("extend memory expiry: %w", err)
+	}
+	return nil
+}
+
+// InsertMCPRequestLog stores one request event for admin observability.
+func (s *PostgresStore) InsertMCPRequestLog(ctx context.Context, rec MCPRequestLog) error {
+	ts := rec.CreatedAt.UTC()
+	if rec.CreatedAt.IsZero() {
+		ts = s.clock.Now().UTC()
+	}
+	_, err := s.exec(ctx, `INSERT INTO mcp_requests (
+		method, tool_name, success, error_text, duration_ms, client_name, client_version, params, created_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		strings.TrimSpace(rec.Method),
+		strings.TrimSpace(rec.ToolName),
+		rec.Success,
+		strings.TrimSpace(rec.ErrorText),
+		rec.DurationMS,
+		strings.TrimSpace(rec.ClientName),
+		strings.TrimSpace(rec.ClientVersion),
+		rec.Params,
+		ts,
+	)
+	if err != nil {
+		return fmt.Errorf("insert mcp request log: %w", err)
+	}
+	return nil
+}
+
+// RecentMCPRequestLogs returns most recent request events in newest-first order.
+func (s *PostgresStore) RecentMCPRequestLogs(ctx context.Context, limit int) ([]MCPRequestLog, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	rows, err := s.query(ctx, `SELECT `+mcpRequestColumns+`
+FROM mcp_requests
+ORDER BY created_at DESC, id DESC
+LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list mcp request logs: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]MCPRequestLog, 0, limit)
+	for rows.Next() {
+		row, err := scanPGRequestLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, row)
+	}
+	return items, rows.Err()
+}
+
+// MCPRequestLogsAfter returns up to limit requests logged after the one
+// with ID afterID, oldest first.
+func (s *PostgresStore) MCPRequestLogsAfter(ctx context.Context, afterID int64, limit int) ([]MCPRequestLog, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.query(ctx, `SELECT `+mcpRequestColumns+`
+FROM mcp_requests
+WHERE id > ?
+ORDER BY id
+LIMIT ?`, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("tail mcp request logs: %w", err)
+	}
+	defer rows.Close()
+
+	var items []MCPRequestLog
+	for rows.Next() {
+		row, err := scanPGRequestLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, row)
+	}
+	return items, rows.Err()
+}
+
+// ClearMCPRequestParams blanks the params of the given requests.
+func (s *PostgresStore) ClearMCPRequestParams(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	if _, err := s.exec(ctx, `UPDATE mcp_requests SET params = '' WHERE id IN (`+placeholders+`)`, args...); err != nil {
+		return fmt.Errorf("clear mcp request params: %w", err)
+	}
+	return nil
+}
+
+// GetMCPRequestLog returns one logged request, or sql.ErrNoRows.
+func (s *PostgresStore) GetMCPRequestLog(ctx context.Context, id int64) (MCPRequestLog, error) {
+	return scanPGRequestLog(s.db.QueryRowContext(ctx, `SELECT `+mcpRequestColumns+` FROM mcp_requests WHERE id = $1`, id))
+}
+
+// RequestsByClient counts logged requests and failures per client name,
+// busiest first.
+func (s *PostgresStore) RequestsByClient(ctx context.Context, limit int) ([]ClientRequests, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	rows, err := s.query(ctx, `SELECT client_name, count(*), count(*) FILTER (WHERE NOT success), max(created_at)
+FROM mcp_requests
+GROUP BY client_name
+ORDER BY count(*) DESC, client_name COLLATE "C"
+LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("count requests by client: %w", err)
+	}
+	defer rows.Close()
+
+	var out []ClientRequests
+	for rows.Next() {
+		var row ClientRequests
+		if err := rows.Scan(&row.Client, &row.Requests, &row.Errors, &row.LastSeen); err != nil {
+			return nil, fmt.Errorf("scan client requests: %w", err)
+		}
+		row.LastSeen = row.LastSeen.UTC()
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// RecentMemories returns compact memory rows in newest-first order.
+func (s *PostgresStore) RecentMemories(ctx context.Context, limit int) ([]RecentMemory, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	rows, err := s.query(ctx, `SELECT id, namespace, scope, CASE WHEN btrim(summary) = '' THEN content ELSE summary END, importance, created_at
+FROM memories
+ORDER BY created_at DESC
+LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list recent memories: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]RecentMemory, 0, limit)
+	for rows.Next() {
+		var row RecentMemory
+		if err := rows.Scan(&row.ID, &row.Namespace, &row.Scope, &row.Summary, &row.Importance, &row.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan recent memory: %w", err)
+		}
+		row.CreatedAt = row.CreatedAt.UTC()
+		items = append(items, row)
+	}
+	return items, rows.Err()
+}
+
+func (s *PostgresStore) Close() error {
+	return s.db.Close()
+}
+
+// scanPGMemory scans the pgMemoryColumns of one row, followed by extra.
+func scanPGMemory(sc scanner, extra ...any) (types.MemoryRecord, error) {
+	var (
+		rec                 types.MemoryRecord
+		metadataJSON        string
+		expiresAt, promoted sql.NullTime
+	)
+	dest := append([]any{
+		&rec.ID,
+		&rec.Namespace,
+		&rec.Scope,
+		&rec.Content,
+		&rec.Summary,
+		&rec.Importance,
+		&rec.SourceAgent,
+		&metadataJSON,
+		&rec.CreatedAt,
+		&rec.LastAccessedAt,
+		&expiresAt,
+		&promoted,
+		&rec.Language,
+		&rec.ExternalKey,
+		&rec.AccessCount,
+	}, extra...)
+	if err := sc.Scan(dest...); err != nil {
+		return rec, err
+	}
+	if err := json.Unmarshal([]byte(metadataJSON), &rec.Metadata); err != nil {
+		rec.Metadata = map[string]any{}
+	}
+	rec.CreatedAt = rec.CreatedAt.UTC()
+	rec.LastAccessedAt = rec.LastAccessedAt.UTC()
+	if expiresAt.Valid {
+		t := expiresAt.Time.UTC()
+		rec.ExpiresAt = &t
+	}
+	if promoted.Valid {
+		t := promoted.Time.UTC()
+		rec.PromotedAt = &t
+	}
+	return rec, nil
+}
+
+func scanPGRequestLog(row scanner) (MCPRequestLog, error) {
+	var rec MCPRequestLog
+	if err := row.Scan(
+		&rec.ID,
+		&rec.Method,
+		&rec.ToolName,
+		&rec.Success,
+		&rec.ErrorText,
+		&rec.DurationMS,
+		&rec.ClientName,
+		&rec.ClientVersion,
+		&rec.Params,
+		&rec.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return MCPRequestLog{}, err
+		}
+		return MCPRequestLog{}, fmt.Errorf("scan mcp request log: %w", err)
+	}
+	rec.CreatedAt = rec.CreatedAt.UTC()
+	return rec, nil
+}
+
+func pgNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
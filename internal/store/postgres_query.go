@@ -0,0 +1,169 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pgRebind numbers the ? placeholders of q as $1, $2, ... so queries can be
+// assembled the same way as the SQLite ones. q must not contain a literal
+// question mark.
+func pgRebind(q string) string {
+	var b strings.Builder
+	b.Grow(len(q) + 8)
+	n := 0
+	for _, r := range q {
+		if r == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// pgTSQuery builds a to_tsquery('simple', ...) expression from terms: the
+// forms of one term are alternatives and every term must match, like
+// buildFTSMatchQuery. A trailing "*" makes a prefix match.
+func pgTSQuery(terms []string) string {
+	parts := make([]string, 0, len(terms))
+	for _, term := range terms {
+		var alts []string
+		for _, form := range termForms(term) {
+			prefix, isPrefix := strings.CutSuffix(form, "*")
+			if prefix == "" {
+				continue
+			}
+			alt := "'" + strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(prefix) + "'"
+			if isPrefix {
+				alt += ":*"
+			}
+			alts = append(alts, alt)
+		}
+		switch len(alts) {
+		case 0:
+		case 1:
+			parts = append(parts, alts[0])
+		default:
+			parts = append(parts, "("+strings.Join(alts, " | ")+")")
+		}
+	}
+	return strings.Join(parts, " & ")
+}
+
+// pgListWhere translates the filtering fields of f into WHERE clauses with
+// the semantics of listWhere.
+func pgListWhere(f ListFilter) ([]string, []any) {
+	var (
+		where []string
+		args  []any
+	)
+	if f.Namespace != "" {
+		where = append(where, "namespace = ?")
+		args = append(args, f.Namespace)
+	}
+	if ns := strings.Trim(f.NamespacePrefix, "/"); ns != "" {
+		where = append(where, `(namespace = ? OR namespace LIKE ? ESCAPE '\')`)
+		args = append(args, ns, escapeLike(ns)+"/%")
+	}
+	if f.Scope != "" {
+		where = append(where, "scope = ?")
+		args = append(args, f.Scope)
+	}
+	if f.SourceAgent != "" {
+		where = append(where, "source_agent = ?")
+		args = append(args, f.SourceAgent)
+	}
+	if f.ExternalKey != "" {
+		where = append(where, "external_key = ?")
+		args = append(args, f.ExternalKey)
+	}
+	for _, tag := range f.Tags {
+		b, _ := json.Marshal([]string{tag})
+		where = append(where, "metadata -> 'tags' @> ?::jsonb")
+		args = append(args, string(b))
+	}
+	for _, key := range sortedKeys(f.Metadata) {
+		where = append(where, "metadata ->> ?::text = ?")
+		args = append(args, key, f.Metadata[key])
+	}
+	if !f.CreatedAfter.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, f.CreatedAfter.UTC())
+	}
+	if !f.CreatedBefore.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, f.CreatedBefore.UTC())
+	}
+	if f.MinImportance > 0 {
+		where = append(where, "importance >= ?")
+		args = append(args, f.MinImportance)
+	}
+	if f.MaxImportance > 0 {
+		where = append(where, "importance <= ?")
+		args = append(args, f.MaxImportance)
+	}
+	if c := f.After; c != nil {
+		created := c.CreatedAt.UTC()
+		switch f.Sort {
+		case SortCreatedAsc:
+			where = append(where, `(created_at, id COLLATE "C") > (?, ?)`)
+			args = append(args, created, c.ID)
+		case SortImportanceDesc:
+			where = append(where, `(importance < ? OR (importance = ? AND (created_at, id COLLATE "C") < (?, ?)))`)
+			args = append(args, c.Importance, c.Importance, created, c.ID)
+		default:
+			where = append(where, `(created_at, id COLLATE "C") < (?, ?)`)
+			args = append(args, created, c.ID)
+		}
+	}
+	switch {
+	case f.ExpiredOnly:
+		where = append(where, "expires_at IS NOT NULL AND expires_at <= ?")
+		args = append(args, listNow(f).UTC())
+	case !f.IncludeExpired:
+		where = append(where, "(expires_at IS NULL OR expires_at > ?)")
+		args = append(args, listNow(f).UTC())
+	}
+	return where, args
+}
+
+// pgMetadataCondSQL translates c into a condition over the metadata
+// column with the semantics of Match: a missing key or a value of another
+// JSON type matches nothing, and strings compare bytewise.
+func pgMetadataCondSQL(c MetadataCond) (string, []any) {
+	// Path segments are validated identifiers, so the array literal needs
+	// no quoting.
+	path := "{" + strings.Join(c.Path, ",") + "}"
+	if c.Op == OpContains {
+		elem, _ := json.Marshal([]any{c.Value})
+		return `((jsonb_typeof(metadata #> ?::text[]) = 'array' AND metadata #> ?::text[] @> ?::jsonb)
+    OR (jsonb_typeof(metadata #> ?::text[]) = 'string' AND strpos(metadata #>> ?::text[], ?) > 0))`,
+			[]any{path, path, string(elem), path, path, fmt.Sprint(c.Value)}
+	}
+	switch v := c.Value.(type) {
+	case float64:
+		return "(jsonb_typeof(metadata #> ?::text[]) = 'number' AND (metadata #>> ?::text[])::float8 " + c.Op + " ?)", []any{path, path, v}
+	case string:
+		return "(jsonb_typeof(metadata #> ?::text[]) = 'string' AND (metadata #>> ?::text[]) COLLATE \"C\" " + c.Op + " ?)", []any{path, path, v}
+	default:
+		return "(jsonb_typeof(metadata #> ?::text[]) = 'boolean' AND (metadata #>> ?::text[])::boolean " + c.Op + " ?)", []any{path, path, v}
+	}
+}
+
+// pgGroupColumn maps a CountBy dimension to the expression it groups by.
+func pgGroupColumn(dim string) (string, error) {
+	col, err := groupColumn(dim)
+	if err != nil {
+		return "", err
+	}
+	if dim == GroupDay {
+		col = "to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')"
+	}
+	// Keys order bytewise, as in SQLite.
+	return "(" + col + `) COLLATE "C"`, nil
+}
@@ -0,0 +1,72 @@
+-- Schema of the postgres store. Every process applies it at open time under
+-- an advisory lock, so statements must stay idempotent.
+CREATE TABLE IF NOT EXISTS memories (
+  id TEXT PRIMARY KEY,
+  namespace TEXT NOT NULL,
+  scope TEXT NOT NULL CONSTRAINT memories_scope_check CHECK (scope IN ('short', 'long', 'project', 'global')),
+  content TEXT NOT NULL,
+  summary TEXT NOT NULL DEFAULT '',
+  importance INTEGER NOT NULL DEFAULT 3,
+  source_agent TEXT NOT NULL DEFAULT '',
+  metadata JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL,
+  last_accessed_at TIMESTAMPTZ NOT NULL,
+  expires_at TIMESTAMPTZ,
+  promoted_at TIMESTAMPTZ,
+  search_text TEXT NOT NULL DEFAULT '',
+  meta_text TEXT NOT NULL DEFAULT '',
+  language TEXT NOT NULL DEFAULT '',
+  external_key TEXT NOT NULL DEFAULT '',
+  access_count INTEGER NOT NULL DEFAULT 0,
+  -- search_text and meta_text are already folded and stemmed, so the simple
+  -- configuration only splits them. Punctuation is blanked first so that
+  -- "auth.go" indexes "auth" and "go" rather than one file-name token.
+  search_tsv TSVECTOR GENERATED ALWAYS AS (
+    to_tsvector('simple', regexp_replace(search_text || ' ' || meta_text, '[^[:alnum:]]+', ' ', 'g'))
+  ) STORED
+);
+
+-- Tables created before the project and global scopes only allow short and
+-- long. NOT VALID skips rechecking existing rows on every open.
+ALTER TABLE memories DROP CONSTRAINT IF EXISTS memories_scope_check;
+ALTER TABLE memories ADD CONSTRAINT memories_scope_check CHECK (scope IN ('short', 'long', 'project', 'global')) NOT VALID;
+
+CREATE INDEX IF NOT EXISTS idx_memories_ns_scope_created ON memories (namespace, scope, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_memories_ns_created ON memories (namespace, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_memories_expires_at ON memories (expires_at) WHERE expires_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_memories_scope_ns ON memories (scope, namespace);
+CREATE INDEX IF NOT EXISTS idx_memories_created_at ON memories (created_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_memories_external_key ON memories (namespace, external_key) WHERE external_key <> '';
+CREATE INDEX IF NOT EXISTS idx_memories_search_tsv ON memories USING GIN (search_tsv);
+CREATE INDEX IF NOT EXISTS idx_memories_metadata ON memories USING GIN (metadata jsonb_path_ops);
+
+CREATE TABLE IF NOT EXISTS mcp_requests (
+  id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  method TEXT NOT NULL,
+  tool_name TEXT NOT NULL DEFAULT '',
+  success BOOLEAN NOT NULL,
+  error_text TEXT NOT NULL DEFAULT '',
+  duration_ms BIGINT NOT NULL DEFAULT 0,
+  client_name TEXT NOT NULL DEFAULT '',
+  client_version TEXT NOT NULL DEFAULT '',
+  params TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_mcp_requests_created_at ON mcp_requests (created_at DESC);
+
+-- Every change to a memory, as in the SQLite audit_log. Rows outlive their
+-- memory.
+CREATE TABLE IF NOT EXISTS audit_log (
+  id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  action TEXT NOT NULL,
+  memory_id TEXT NOT NULL,
+  namespace TEXT NOT NULL,
+  actor TEXT NOT NULL DEFAULT '',
+  tool TEXT NOT NULL DEFAULT '',
+  before_summary TEXT NOT NULL DEFAULT '',
+  after_summary TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_memory ON audit_log (memory_id);
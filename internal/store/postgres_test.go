@@ -0,0 +1,219 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestPgRebind(t *testing.T) {
+	t.Parallel()
+	got := pgRebind("SELECT 1 FROM memories WHERE id = ? AND scope IN (?, ?)")
+	if want := "SELECT 1 FROM memories WHERE id = $1 AND scope IN ($2, $3)"; got != want {
+		t.Fatalf("pgRebind() = %q, want %q", got, want)
+	}
+}
+
+func TestPgTSQuery(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		terms []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"wal"}, "'wal'"},
+		{[]string{"deploys|deploy", "window"}, "('deploys' | 'deploy') & 'window'"},
+		{[]string{"東京*", "o'brien"}, "'東京':* & 'o''brien'"},
+	}
+	for _, tc := range cases {
+		if got := pgTSQuery(tc.terms); got != tc.want {
+			t.Fatalf("pgTSQuery(%q) = %q, want %q", tc.terms, got, tc.want)
+		}
+	}
+}
+
+// openTestPostgres connects to MEMORY_MCP_TEST_POSTGRES_DSN, which must
+// point at a throwaway database: its memories, request logs and audit log
+// are truncated.
+func openTestPostgres(t *testing.T) *PostgresStore {
+	t.Helper()
+	dsn := os.Getenv("MEMORY_MCP_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("MEMORY_MCP_TEST_POSTGRES_DSN not set")
+	}
+	ctx := context.Background()
+	st, err := OpenPostgres(ctx, dsn, log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("OpenPostgres() error = %v", err)
+	}
+	t.Cleanup(func() { st.Close() })
+	if _, err := st.db.ExecContext(ctx, `TRUNCATE memories, mcp_requests, audit_log`); err != nil {
+		t.Fatalf("truncate error = %v", err)
+	}
+	return st
+}
+
+func TestPostgresStore_Parity(t *testing.T) {
+	st := openTestPostgres(t)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	recs := []types.MemoryRecord{
+		{ID: "p-1", Namespace: "acme/api", Scope: "long", Content: "Deploys retry twice; see auth.go", Importance: 4, SourceAgent: "codex",
+			Metadata: map[string]any{"tags": []any{"deploy"}, "priority": 2, "files": []any{"auth.go"}}, CreatedAt: now.Add(-2 * time.Hour)},
+		{ID: "p-2", Namespace: "acme/api", Scope: "short", Content: "deploy window is 9-11", Importance: 2, SourceAgent: "claude",
+			Metadata: map[string]any{"ticket": "OPS-7"}, CreatedAt: now.Add(-time.Hour)},
+		{ID: "p-3", Namespace: "acme/api/sub", Scope: "short", Content: "expired scratch note", Importance: 1, CreatedAt: now, ExpiresAt: &past},
+	}
+	for i := range recs {
+		recs[i].LastAccessedAt = recs[i].CreatedAt
+	}
+	if _, err := st.InsertMemories(ctx, recs); err != nil {
+		t.Fatalf("InsertMemories() error = %v", err)
+	}
+	if _, err := st.InsertMemory(ctx, recs[0]); err == nil {
+		t.Fatal("InsertMemory() of a taken ID error = nil")
+	}
+
+	cands, err := st.SearchCandidates(ctx, SearchQuery{Namespace: "acme/api", Query: "deploying", Now: now})
+	if err != nil || len(cands) != 2 {
+		t.Fatalf("SearchCandidates(stemmed) = %+v, %v, want 2", cands, err)
+	}
+	cands, err = st.SearchCandidates(ctx, SearchQuery{Namespace: "acme/api", Query: "auth", Now: now})
+	if err != nil || len(cands) != 1 || cands[0].Record.ID != "p-1" || cands[0].LexicalScore <= 0.4 {
+		t.Fatalf("SearchCandidates(auth) = %+v, %v, want p-1 from FTS", cands, err)
+	}
+	conds, err := ParseMetadataFilter(`metadata.priority >= 2 and metadata.files contains "auth.go"`)
+	if err != nil {
+		t.Fatalf("ParseMetadataFilter() error = %v", err)
+	}
+	cands, err = st.SearchCandidates(ctx, SearchQuery{Namespace: "acme/api", Query: "deploy", Now: now, Metadata: conds})
+	if err != nil || len(cands) != 1 || cands[0].Record.ID != "p-1" {
+		t.Fatalf("SearchCandidates(metadata) = %+v, %v, want p-1", cands, err)
+	}
+
+	list := func(f ListFilter) []string {
+		t.Helper()
+		f.Now = now
+		got, err := st.ListMemories(ctx, f)
+		if err != nil {
+			t.Fatalf("ListMemories(%+v) error = %v", f, err)
+		}
+		ids := []string{}
+		for _, rec := range got {
+			ids = append(ids, rec.ID)
+		}
+		return ids
+	}
+	for _, tc := range []struct {
+		filter ListFilter
+		want   []string
+	}{
+		{ListFilter{}, []string{"p-2", "p-1"}},
+		{ListFilter{NamespacePrefix: "acme/api", IncludeExpired: true, Sort: SortCreatedAsc}, []string{"p-1", "p-2", "p-3"}},
+		{ListFilter{Tags: []string{"deploy"}}, []string{"p-1"}},
+		{ListFilter{Metadata: map[string]string{"priority": "2"}}, []string{"p-1"}},
+		{ListFilter{ExpiredOnly: true}, []string{"p-3"}},
+		{ListFilter{After: &ListCursor{CreatedAt: now.Add(-time.Hour), ID: "p-2"}}, []string{"p-1"}},
+	} {
+		if got := list(tc.filter); !slices.Equal(got, tc.want) {
+			t.Fatalf("ListMemories(%+v) = %v, want %v", tc.filter, got, tc.want)
+		}
+	}
+
+	groups, err := st.CountBy(ctx, GroupAgent, ListFilter{IncludeExpired: true, Now: now})
+	if err != nil || len(groups) != 3 || groups[0].Count != 1 || groups[0].Key != "" {
+		t.Fatalf("CountBy(agent) = %+v, %v", groups, err)
+	}
+
+	rec, err := st.UpdateMemory(ctx, "p-2", func(r *types.MemoryRecord) error {
+		r.Content = "deploy window is 10-12"
+		return nil
+	})
+	if err != nil || rec.Content != "deploy window is 10-12" {
+		t.Fatalf("UpdateMemory() = %+v, %v", rec, err)
+	}
+	if _, err := st.UpdateMemory(ctx, "missing", func(*types.MemoryRecord) error { return nil }); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("UpdateMemory(missing) error = %v, want sql.ErrNoRows", err)
+	}
+	if err := st.RecordAccess(ctx, map[string]int{"p-1": 3}, now); err != nil {
+		t.Fatalf("RecordAccess() error = %v", err)
+	}
+	if got, err := st.GetMemory(ctx, "p-1"); err != nil || got.AccessCount != 3 || !got.LastAccessedAt.Equal(now) {
+		t.Fatalf("GetMemory() = %+v, %v", got, err)
+	}
+
+	stored, skipped, err := st.ImportBatch(ctx, []types.MemoryRecord{recs[0], {ID: "p-4", Namespace: "beta", Scope: "long", Content: "imported", Importance: 3, CreatedAt: now, LastAccessedAt: now}})
+	if err != nil || len(stored) != 1 || !slices.Equal(skipped, []string{"p-1"}) {
+		t.Fatalf("ImportBatch() = %d stored, %v skipped, %v", len(stored), skipped, err)
+	}
+
+	stats, err := st.Stats(ctx, now)
+	if err != nil || stats.Total != 4 || stats.Short != 2 || stats.Expired != 1 {
+		t.Fatalf("Stats() = %+v, %v", stats, err)
+	}
+	if n, err := st.ExpireShort(ctx, now); err != nil || n != 1 {
+		t.Fatalf("ExpireShort() = %d, %v, want 1", n, err)
+	}
+	if n, err := st.DeleteByFilter(ctx, ListFilter{Namespace: "beta", IncludeExpired: true, Now: now}); err != nil || n != 1 {
+		t.Fatalf("DeleteByFilter() = %d, %v, want 1", n, err)
+	}
+
+	if err := st.InsertMCPRequestLog(ctx, MCPRequestLog{Method: "tools/call", ToolName: "memory_search", Success: true, ClientName: "codex", CreatedAt: now}); err != nil {
+		t.Fatalf("InsertMCPRequestLog() error = %v", err)
+	}
+	logs, err := st.RecentMCPRequestLogs(ctx, 5)
+	if err != nil || len(logs) != 1 || !logs[0].Success || logs[0].ToolName != "memory_search" {
+		t.Fatalf("RecentMCPRequestLogs() = %+v, %v", logs, err)
+	}
+	if got, err := st.GetMCPRequestLog(ctx, logs[0].ID); err != nil || got.ClientName != "codex" {
+		t.Fatalf("GetMCPRequestLog() = %+v, %v", got, err)
+	}
+	if tail, err := st.MCPRequestLogsAfter(ctx, 0, 10); err != nil || len(tail) != 1 || tail[0].ID != logs[0].ID {
+		t.Fatalf("MCPRequestLogsAfter() = %+v, %v", tail, err)
+	}
+	if tail, err := st.MCPRequestLogsAfter(ctx, logs[0].ID, 10); err != nil || len(tail) != 0 {
+		t.Fatalf("MCPRequestLogsAfter(last) = %+v, %v, want none", tail, err)
+	}
+	recent, err := st.RecentMemories(ctx, 5)
+	if err != nil || len(recent) != 2 || recent[0].ID != "p-2" {
+		t.Fatalf("RecentMemories() = %+v, %v", recent, err)
+	}
+}
+
+func TestPostgresStore_AuditLog(t *testing.T) {
+	st := openTestPostgres(t)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	err := st.RecordAudit(ctx,
+		AuditEntry{Action: AuditWrite, MemoryID: "p-1", Namespace: "acme/api", Actor: "codex", Tool: "memory_write", After: "deploys retry", At: now},
+		AuditEntry{Action: AuditUpdate, MemoryID: "p-1", Namespace: "acme/api", Actor: "claude", Tool: "memory_append", Before: "deploys retry", After: "deploys retry twice", At: now.Add(time.Minute)},
+		AuditEntry{Action: AuditWrite, MemoryID: "p-2", Namespace: "acme/apix", Actor: "codex", Tool: "memory_write", After: "other", At: now},
+	)
+	if err != nil {
+		t.Fatalf("RecordAudit() error = %v", err)
+	}
+	got, err := st.AuditLog(ctx, AuditFilter{NamespacePrefix: "acme/api"})
+	if err != nil || len(got) != 2 || got[0].Action != AuditUpdate || got[0].Actor != "claude" || !got[0].At.Equal(now.Add(time.Minute)) {
+		t.Fatalf("AuditLog(namespace) = %+v, %v, want the two acme/api entries newest first", got, err)
+	}
+	if got, err := st.AuditLog(ctx, AuditFilter{Actor: "codex", Action: AuditWrite}); err != nil || len(got) != 2 {
+		t.Fatalf("AuditLog(actor, action) = %+v, %v, want 2", got, err)
+	}
+	if err := st.RedactAudit(ctx, []string{"p-1"}); err != nil {
+		t.Fatalf("RedactAudit() error = %v", err)
+	}
+	got, err = st.AuditLog(ctx, AuditFilter{MemoryID: "p-1"})
+	if err != nil || len(got) != 2 || got[0].Before != "" || got[0].After != "" || got[0].Actor != "claude" {
+		t.Fatalf("AuditLog() after redaction = %+v, %v", got, err)
+	}
+}
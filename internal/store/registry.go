@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/charmbracelet/log"
+)
+
+// Backend holds the settings a store.driver backend is opened with. Each
+// backend reads the fields it understands and ignores the rest.
+type Backend struct {
+	// Path is the SQLite database file.
+	Path string
+	// Shards maps namespace prefixes to further SQLite databases.
+	Shards map[string]string
+	// Dir and GitCommit configure the markdown backend.
+	Dir       string
+	GitCommit bool
+	// Options customize SQLite-backed stores.
+	Options []Option
+	// DSN and PostgresOptions configure the postgres backend.
+	DSN             string
+	PostgresOptions []PostgresOption
+}
+
+// OpenFunc opens a registered backend.
+type OpenFunc func(ctx context.Context, b Backend, logger *log.Logger) (Store, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]OpenFunc{}
+)
+
+func init() {
+	Register("sqlite", func(ctx context.Context, b Backend, logger *log.Logger) (Store, error) {
+		if len(b.Shards) > 0 {
+			return OpenSharded(ctx, b.Path, b.Shards, logger, b.Options...)
+		}
+		return OpenSQLite(ctx, b.Path, logger, b.Options...)
+	})
+	Register("markdown", func(ctx context.Context, b Backend, logger *log.Logger) (Store, error) {
+		return OpenMarkdown(ctx, b.Dir, b.GitCommit, logger)
+	})
+	Register("postgres", func(ctx context.Context, b Backend, logger *log.Logger) (Store, error) {
+		return OpenPostgres(ctx, b.DSN, logger, b.PostgresOptions...)
+	})
+	Register("memory", func(context.Context, Backend, *log.Logger) (Store, error) {
+		return NewMemoryStore(), nil
+	})
+}
+
+// Register makes a backend available as store.driver name. It panics when
+// name is already registered, like database/sql.Register.
+func Register(name string, open OpenFunc) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if _, dup := drivers[name]; dup {
+		panic("store: Register called twice for driver " + name)
+	}
+	drivers[name] = open
+}
+
+// Drivers returns the registered backend names, sorted.
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	return slices.Sorted(maps.Keys(drivers))
+}
+
+// Open opens the backend registered as driver.
+func Open(ctx context.Context, driver string, b Backend, logger *log.Logger) (Store, error) {
+	driversMu.RLock()
+	open, ok := drivers[driver]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown store driver %q (registered: %v)", driver, Drivers())
+	}
+	return open(ctx, b, logger)
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestOpen_SelectsRegisteredBackend(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+
+	for _, name := range []string{"markdown", "memory", "sqlite"} {
+		if !slices.Contains(Drivers(), name) {
+			t.Fatalf("Drivers() = %v, missing %s", Drivers(), name)
+		}
+	}
+	if _, err := Open(ctx, "cassandra", Backend{}, logger); err == nil {
+		t.Fatal("Open() of an unknown driver error = nil")
+	}
+
+	b := Backend{Path: filepath.Join(t.TempDir(), "open.db"), Dir: t.TempDir()}
+	for driver, want := range map[string]string{"sqlite": "*store.SQLiteStore", "markdown": "*store.MarkdownStore", "memory": "*store.MemoryStore"} {
+		st, err := Open(ctx, driver, b, logger)
+		if err != nil {
+			t.Fatalf("Open(%s) error = %v", driver, err)
+		}
+		defer st.Close()
+		if got := fmt.Sprintf("%T", st); got != want {
+			t.Fatalf("Open(%s) = %s, want %s", driver, got, want)
+		}
+	}
+}
+
+func TestMemoryStore_SearchUpdateExpire(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	st := NewMemoryStore()
+
+	recs := []types.MemoryRecord{
+		{ID: "m-1", Namespace: "org/repo", Scope: "long", Content: "use WAL mode", Importance: 4, CreatedAt: now, LastAccessedAt: now},
+		{ID: "m-2", Namespace: "org/repo", Scope: "short", Content: "scratch WAL note", Importance: 2, CreatedAt: now, LastAccessedAt: now, ExpiresAt: &past},
+	}
+	if _, err := st.InsertMemories(ctx, recs); err != nil {
+		t.Fatalf("InsertMemories() error = %v", err)
+	}
+	if _, err := st.InsertMemory(ctx, recs[0]); err == nil {
+		t.Fatal("InsertMemory() of a taken ID error = nil")
+	}
+
+	cands, err := st.SearchCandidates(ctx, SearchQuery{Namespace: "org/repo", Query: "wal", Limit: 10, Now: now})
+	if err != nil || len(cands) != 1 || cands[0].Record.ID != "m-1" {
+		t.Fatalf("SearchCandidates() = %+v, %v, want only m-1", cands, err)
+	}
+
+	rec, err := st.UpdateMemory(ctx, "m-1", func(r *types.MemoryRecord) error {
+		r.Content = "use WAL mode with synchronous=NORMAL"
+		r.Namespace = "elsewhere"
+		return nil
+	})
+	if err != nil || rec.Namespace != "org/repo" || rec.Content != "use WAL mode with synchronous=NORMAL" {
+		t.Fatalf("UpdateMemory() = %+v, %v", rec, err)
+	}
+
+	if n, err := st.ExpireShort(ctx, now); err != nil || n != 1 {
+		t.Fatalf("ExpireShort() = %d, %v, want 1", n, err)
+	}
+	stats, err := st.Stats(ctx, now)
+	if err != nil || stats.Total != 1 || stats.Long != 1 {
+		t.Fatalf("Stats() = %+v, %v", stats, err)
+	}
+}
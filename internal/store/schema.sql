@@ -1,3 +1,5 @@
+-- Baseline schema, replayed on every open. Do not change it: later schema
+-- changes are versioned files in migrations/ (see migrate.go).
 PRAGMA journal_mode=WAL;
 PRAGMA synchronous=NORMAL;
 
@@ -13,13 +15,128 @@ CREATE TABLE IF NOT EXISTS memories (
   created_at TEXT NOT NULL,
   last_accessed_at TEXT NOT NULL,
   expires_at TEXT,
-  promoted_at TEXT
+  promoted_at TEXT,
+  -- Flattened values of the configured metadata keys, indexed by FTS.
+  meta_text TEXT NOT NULL DEFAULT '',
+  -- Content and summary folded by textnorm.Fold, indexed by FTS.
+  search_text TEXT NOT NULL DEFAULT '',
+  -- Dominant language of content and summary, picks the analyzer.
+  language TEXT NOT NULL DEFAULT '',
+  -- Client-supplied key, unique per namespace when set (see
+  -- idx_memories_external_key in sqlite.go).
+  external_key TEXT NOT NULL DEFAULT ''
 );
 
-CREATE INDEX IF NOT EXISTS idx_memories_namespace_scope ON memories(namespace, scope);
+CREATE TABLE IF NOT EXISTS store_settings (
+  key TEXT PRIMARY KEY,
+  value TEXT NOT NULL
+);
+
+-- Ranking and list queries filter by namespace (and usually scope), skip
+-- expired rows and read newest first. expires_at rides along so the expiry
+-- check is answered from the index before touching the row.
+DROP INDEX IF EXISTS idx_memories_namespace_scope;
+CREATE INDEX IF NOT EXISTS idx_memories_ns_scope_created ON memories(namespace, scope, created_at DESC, expires_at);
+CREATE INDEX IF NOT EXISTS idx_memories_ns_created ON memories(namespace, created_at DESC, expires_at);
 CREATE INDEX IF NOT EXISTS idx_memories_expires_at ON memories(expires_at);
 CREATE INDEX IF NOT EXISTS idx_memories_created_at ON memories(created_at DESC);
 
+-- Statements extracted from memory content by internal/facts. The
+-- memories_facts_ad trigger (sqlite_facts.go) removes them with their memory.
+CREATE TABLE IF NOT EXISTS facts (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  memory_id TEXT NOT NULL,
+  namespace TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  predicate TEXT NOT NULL,
+  object TEXT NOT NULL,
+  created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_facts_ns_subject ON facts(namespace, subject, predicate);
+CREATE INDEX IF NOT EXISTS idx_facts_memory ON facts(memory_id);
+
+-- Relevance reports from memory_feedback. signal is 1 (useful) or -1
+-- (irrelevant) and query is stored normalized. The memories_feedback_ad
+-- trigger (sqlite_feedback.go) removes rows with their memory.
+CREATE TABLE IF NOT EXISTS feedback (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  memory_id TEXT NOT NULL,
+  namespace TEXT NOT NULL,
+  query TEXT NOT NULL DEFAULT '',
+  signal INTEGER NOT NULL,
+  source_agent TEXT NOT NULL DEFAULT '',
+  created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_feedback_memory ON feedback(memory_id, query);
+
+-- Searches that returned nothing, one row per namespace and normalized
+-- query. count and last_seen grow with every repeat.
+CREATE TABLE IF NOT EXISTS search_misses (
+  namespace TEXT NOT NULL,
+  query TEXT NOT NULL,
+  count INTEGER NOT NULL DEFAULT 1,
+  source_agent TEXT NOT NULL DEFAULT '',
+  first_seen TEXT NOT NULL,
+  last_seen TEXT NOT NULL,
+  PRIMARY KEY (namespace, query)
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_misses_count ON search_misses(count DESC, last_seen DESC);
+
+-- Audit trail of promotions to long-term scope, with the scope the memory
+-- had before and the requester's reason. Rows outlive their memory.
+CREATE TABLE IF NOT EXISTS promotions (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  memory_id TEXT NOT NULL,
+  namespace TEXT NOT NULL,
+  prior_scope TEXT NOT NULL,
+  reason TEXT NOT NULL DEFAULT '',
+  source_agent TEXT NOT NULL DEFAULT '',
+  promoted_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_promotions_memory ON promotions(memory_id);
+
+-- Every change to a memory: action is write, update, promote, delete or
+-- expire. actor is the source agent or MCP client, tool the MCP tool or
+-- job that made the change. Rows outlive their memory.
+CREATE TABLE IF NOT EXISTS audit_log (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  action TEXT NOT NULL,
+  memory_id TEXT NOT NULL,
+  namespace TEXT NOT NULL,
+  actor TEXT NOT NULL DEFAULT '',
+  tool TEXT NOT NULL DEFAULT '',
+  before_summary TEXT NOT NULL DEFAULT '',
+  after_summary TEXT NOT NULL DEFAULT '',
+  created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_memory ON audit_log(memory_id);
+
+-- Embedding vectors, one per memory and model. embedding_models records
+-- each model's dimension and the active model is kept in store_settings.
+-- The memories_embeddings_ad trigger (sqlite_embeddings.go) removes vectors
+-- with their memory.
+CREATE TABLE IF NOT EXISTS memory_embeddings (
+  memory_id TEXT NOT NULL,
+  model TEXT NOT NULL,
+  dim INTEGER NOT NULL,
+  vector BLOB NOT NULL,
+  created_at TEXT NOT NULL,
+  PRIMARY KEY (memory_id, model)
+);
+
+CREATE INDEX IF NOT EXISTS idx_memory_embeddings_model ON memory_embeddings(model);
+
+CREATE TABLE IF NOT EXISTS embedding_models (
+  model TEXT PRIMARY KEY,
+  dim INTEGER NOT NULL,
+  created_at TEXT NOT NULL
+);
+
 CREATE TABLE IF NOT EXISTS mcp_requests (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
   method TEXT NOT NULL,
@@ -27,14 +144,19 @@ CREATE TABLE IF NOT EXISTS mcp_requests (
   success INTEGER NOT NULL,
   error_text TEXT NOT NULL DEFAULT '',
   duration_ms INTEGER NOT NULL DEFAULT 0,
+  client_name TEXT NOT NULL DEFAULT '',
+  client_version TEXT NOT NULL DEFAULT '',
+  params TEXT NOT NULL DEFAULT '',
   created_at TEXT NOT NULL
 );
 
 CREATE INDEX IF NOT EXISTS idx_mcp_requests_created_at ON mcp_requests(created_at DESC);
 
--- FTS5 table for lexical retrieval. We keep a separate id column for joins.
-CREATE VIRTUAL TABLE IF NOT EXISTS memories_fts USING fts5(
-  id UNINDEXED,
-  content,
-  summary
+CREATE TABLE IF NOT EXISTS server_logs (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  level TEXT NOT NULL,
+  message TEXT NOT NULL,
+  created_at TEXT NOT NULL
 );
+
+CREATE INDEX IF NOT EXISTS idx_server_logs_created_at ON server_logs(created_at DESC);
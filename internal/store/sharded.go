@@ -0,0 +1,635 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// Shard is one database file of a ShardedStore. It holds every namespace
+// under Prefix that no longer prefix claims.
+type Shard struct {
+	Prefix string
+	Store  *SQLiteStore
+}
+
+// ShardedStore spreads memories over several SQLite files by namespace
+// prefix so large deployments do not share one writer and one file. The
+// primary store holds every namespace no shard claims, plus the request,
+// server, audit, promotion and search miss logs, which are not kept per
+// namespace.
+//
+// Writes and searches touch only the shard of their namespace. Lookups by
+// ID try each store in turn, and listings, counts and stats are merged
+// across the stores they cover. A batch insert spanning several shards is
+// atomic within each shard but not across them.
+type ShardedStore struct {
+	primary *SQLiteStore
+	// shards is ordered longest prefix first.
+	shards []Shard
+}
+
+// NewShardedStore routes namespaces under each shard's prefix to that
+// shard and the rest to primary. It takes ownership of every store.
+func NewShardedStore(primary *SQLiteStore, shards []Shard) *ShardedStore {
+	s := &ShardedStore{primary: primary}
+	for _, sh := range shards {
+		sh.Prefix = strings.Trim(sh.Prefix, "/")
+		s.shards = append(s.shards, sh)
+	}
+	slices.SortFunc(s.shards, func(a, b Shard) int {
+		return cmp.Or(cmp.Compare(len(b.Prefix), len(a.Prefix)), cmp.Compare(a.Prefix, b.Prefix))
+	})
+	return s
+}
+
+// OpenSharded opens the primary database at dbPath and one database per
+// entry of shards, which maps namespace prefixes to file paths. Every
+// database is opened with opts.
+func OpenSharded(ctx context.Context, dbPath string, shards map[string]string, logger *log.Logger, opts ...Option) (*ShardedStore, error) {
+	primary, err := OpenSQLite(ctx, dbPath, logger, opts...)
+	if err != nil {
+		return nil, err
+	}
+	var opened []Shard
+	for prefix, path := range shards {
+		st, err := OpenSQLite(ctx, path, logger.With("shard", prefix), opts...)
+		if err != nil {
+			for _, sh := range opened {
+				sh.Store.Close()
+			}
+			primary.Close()
+			return nil, fmt.Errorf("open shard %s: %w", prefix, err)
+		}
+		opened = append(opened, Shard{Prefix: prefix, Store: st})
+	}
+	return NewShardedStore(primary, opened), nil
+}
+
+// Primary returns the store for unsharded namespaces and the logs.
+func (s *ShardedStore) Primary() *SQLiteStore {
+	return s.primary
+}
+
+// Stores returns the primary followed by every shard's store, longest
+// prefix first.
+func (s *ShardedStore) Stores() []*SQLiteStore {
+	return s.stores()
+}
+
+// storeFor returns the store holding namespace.
+func (s *ShardedStore) storeFor(namespace string) *SQLiteStore {
+	namespace = strings.Trim(namespace, "/")
+	for _, sh := range s.shards {
+		if namespace == sh.Prefix || strings.HasPrefix(namespace, sh.Prefix+"/") {
+			return sh.Store
+		}
+	}
+	return s.primary
+}
+
+// stores returns the primary followed by every shard.
+func (s *ShardedStore) stores() []*SQLiteStore {
+	out := []*SQLiteStore{s.primary}
+	for _, sh := range s.shards {
+		out = append(out, sh.Store)
+	}
+	return out
+}
+
+// storesUnder returns the stores that can hold namespaces under prefix:
+// the one prefix itself routes to and every shard beneath it.
+func (s *ShardedStore) storesUnder(prefix string) []*SQLiteStore {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return s.stores()
+	}
+	out := []*SQLiteStore{s.storeFor(prefix)}
+	for _, sh := range s.shards {
+		if sh.Store != out[0] && strings.HasPrefix(sh.Prefix, prefix+"/") {
+			out = append(out, sh.Store)
+		}
+	}
+	return out
+}
+
+// owner returns the store holding memory id, or the primary when none
+// does so that callers get its not-found error.
+func (s *ShardedStore) owner(ctx context.Context, id string) (*SQLiteStore, error) {
+	for _, st := range s.stores() {
+		_, err := st.GetMemory(ctx, id)
+		if err == nil {
+			return st, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+	return s.primary, nil
+}
+
+func (s *ShardedStore) InsertMemory(ctx context.Context, rec types.MemoryRecord) (types.MemoryRecord, error) {
+	return s.storeFor(rec.Namespace).InsertMemory(ctx, rec)
+}
+
+// InsertMemories inserts each shard's records in one transaction, in the
+// order the shards first appear in recs, and returns them in recs order.
+func (s *ShardedStore) InsertMemories(ctx context.Context, recs []types.MemoryRecord) ([]types.MemoryRecord, error) {
+	var (
+		order []*SQLiteStore
+		index = map[*SQLiteStore][]int{}
+	)
+	for i, rec := range recs {
+		st := s.storeFor(rec.Namespace)
+		if _, ok := index[st]; !ok {
+			order = append(order, st)
+		}
+		index[st] = append(index[st], i)
+	}
+	if len(order) == 1 {
+		return order[0].InsertMemories(ctx, recs)
+	}
+	out := make([]types.MemoryRecord, len(recs))
+	for _, st := range order {
+		batch := make([]types.MemoryRecord, 0, len(index[st]))
+		for _, i := range index[st] {
+			batch = append(batch, recs[i])
+		}
+		got, err := st.InsertMemories(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range index[st] {
+			out[i] = got[j]
+		}
+	}
+	return out, nil
+}
+
+func (s *ShardedStore) SearchCandidates(ctx context.Context, q SearchQuery) ([]Candidate, error) {
+	return s.storeFor(q.Namespace).SearchCandidates(ctx, q)
+}
+
+func (s *ShardedStore) Promote(ctx context.Context, id string, now time.Time) error {
+	st, err := s.owner(ctx, id)
+	if err != nil {
+		return err
+	}
+	return st.Promote(ctx, id, now)
+}
+
+func (s *ShardedStore) Demote(ctx context.Context, id string, expiresAt, now time.Time) error {
+	st, err := s.owner(ctx, id)
+	if err != nil {
+		return err
+	}
+	return st.Demote(ctx, id, expiresAt, now)
+}
+
+func (s *ShardedStore) UpdateMemory(ctx context.Context, id string, fn func(*types.MemoryRecord) error) (types.MemoryRecord, error) {
+	st, err := s.owner(ctx, id)
+	if err != nil {
+		return types.MemoryRecord{}, err
+	}
+	return st.UpdateMemory(ctx, id, fn)
+}
+
+// ExtendExpiry implements ExpiryExtender.
+func (s *ShardedStore) ExtendExpiry(ctx context.Context, id string, expiresAt, now time.Time) error {
+	st, err := s.owner(ctx, id)
+	if err != nil {
+		return err
+	}
+	return st.ExtendExpiry(ctx, id, expiresAt, now)
+}
+
+func (s *ShardedStore) ExpireShort(ctx context.Context, now time.Time) (int64, error) {
+	var total int64
+	for _, st := range s.stores() {
+		n, err := st.ExpireShort(ctx, now)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// Stats adds up the counters of every store.
+func (s *ShardedStore) Stats(ctx context.Context, now time.Time) (Stats, error) {
+	var total Stats
+	agents := map[string]int64{}
+	for _, st := range s.stores() {
+		one, err := st.Stats(ctx, now)
+		if err != nil {
+			return total, err
+		}
+		total.Total += one.Total
+		total.Short += one.Short
+		total.Long += one.Long
+		total.Project += one.Project
+		total.Global += one.Global
+		total.Expired += one.Expired
+		total.Useful += one.Useful
+		total.Irrelevant += one.Irrelevant
+		for _, g := range one.Agents {
+			agents[g.Key] += g.Count
+		}
+	}
+	if len(agents) > 0 {
+		total.Agents = groupCounts(GroupAgent, agents)
+	}
+	return total, nil
+}
+
+func (s *ShardedStore) GetMemory(ctx context.Context, id string) (types.MemoryRecord, error) {
+	var err error
+	for _, st := range s.stores() {
+		var rec types.MemoryRecord
+		if rec, err = st.GetMemory(ctx, id); !errors.Is(err, sql.ErrNoRows) {
+			return rec, err
+		}
+	}
+	return types.MemoryRecord{}, err
+}
+
+// ListMemories asks every store that can hold f's namespaces for its first
+// Offset+Limit matches and merges them in f's sort order.
+func (s *ShardedStore) ListMemories(ctx context.Context, f ListFilter) ([]types.MemoryRecord, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	targets := s.storesUnder(f.NamespacePrefix)
+	if len(targets) == 1 {
+		return targets[0].ListMemories(ctx, f)
+	}
+	each := f
+	each.Offset = 0
+	if f.Limit > 0 {
+		each.Limit = f.Offset + f.Limit
+	}
+	var out []types.MemoryRecord
+	for _, st := range targets {
+		recs, err := st.ListMemories(ctx, each)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, recs...)
+	}
+	slices.SortStableFunc(out, func(a, b types.MemoryRecord) int {
+		switch f.Sort {
+		case SortCreatedAsc:
+			return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+		case SortImportanceDesc:
+			return cmp.Or(cmp.Compare(b.Importance, a.Importance), b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.ID, a.ID))
+		default:
+			return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.ID, a.ID))
+		}
+	})
+	out = out[min(f.Offset, len(out)):]
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out, nil
+}
+
+// CountBy adds up the groups of every store that can hold f's namespaces.
+func (s *ShardedStore) CountBy(ctx context.Context, dim string, f ListFilter) ([]GroupCount, error) {
+	targets := s.storesUnder(f.NamespacePrefix)
+	if len(targets) == 1 {
+		return targets[0].CountBy(ctx, dim, f)
+	}
+	counts := map[string]int64{}
+	for _, st := range targets {
+		groups, err := st.CountBy(ctx, dim, f)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			counts[g.Key] += g.Count
+		}
+	}
+	return groupCounts(dim, counts), nil
+}
+
+func (s *ShardedStore) DeleteMemories(ctx context.Context, ids []string) (int64, error) {
+	var total int64
+	for _, st := range s.stores() {
+		n, err := st.DeleteMemories(ctx, ids)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// Close closes every store.
+func (s *ShardedStore) Close() error {
+	var errs []error
+	for _, st := range s.stores() {
+		errs = append(errs, st.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// RecordFeedback implements FeedbackStore.
+func (s *ShardedStore) RecordFeedback(ctx context.Context, f Feedback) error {
+	return s.storeFor(f.Namespace).RecordFeedback(ctx, f)
+}
+
+// FeedbackScores implements FeedbackStore.
+func (s *ShardedStore) FeedbackScores(ctx context.Context, query string, ids []string) (map[string]float64, error) {
+	out := map[string]float64{}
+	for _, st := range s.stores() {
+		scores, err := st.FeedbackScores(ctx, query, ids)
+		if err != nil {
+			return nil, err
+		}
+		for id, v := range scores {
+			out[id] = v
+		}
+	}
+	return out, nil
+}
+
+// AgentTagAffinity implements FeedbackStore. A tag rated in several
+// shards gets the mean of their affinities.
+func (s *ShardedStore) AgentTagAffinity(ctx context.Context, agent string) (map[string]float64, error) {
+	sums, counts := map[string]float64{}, map[string]float64{}
+	for _, st := range s.stores() {
+		aff, err := st.AgentTagAffinity(ctx, agent)
+		if err != nil {
+			return nil, err
+		}
+		for tag, v := range aff {
+			sums[tag] += v
+			counts[tag]++
+		}
+	}
+	for tag := range sums {
+		sums[tag] /= counts[tag]
+	}
+	return sums, nil
+}
+
+// SuggestQueries implements Suggester.
+func (s *ShardedStore) SuggestQueries(ctx context.Context, q SearchQuery, limit int) ([]string, error) {
+	return s.storeFor(q.Namespace).SuggestQueries(ctx, q, limit)
+}
+
+// ReplaceFacts implements FactStore.
+func (s *ShardedStore) ReplaceFacts(ctx context.Context, memoryID, namespace string, facts []Fact, now time.Time) error {
+	return s.storeFor(namespace).ReplaceFacts(ctx, memoryID, namespace, facts, now)
+}
+
+// QueryFacts implements FactStore.
+func (s *ShardedStore) QueryFacts(ctx context.Context, q FactQuery) ([]Fact, error) {
+	return s.storeFor(q.Namespace).QueryFacts(ctx, q)
+}
+
+// ActiveEmbeddingModel implements EmbeddingStore with the first store
+// that has an active model; each store activates its own.
+func (s *ShardedStore) ActiveEmbeddingModel(ctx context.Context) (EmbeddingModel, bool, error) {
+	for _, st := range s.stores() {
+		m, ok, err := st.ActiveEmbeddingModel(ctx)
+		if err != nil || ok {
+			return m, ok, err
+		}
+	}
+	return EmbeddingModel{}, false, nil
+}
+
+// PutEmbedding implements EmbeddingStore.
+func (s *ShardedStore) PutEmbedding(ctx context.Context, memoryID, model string, vec []float32, now time.Time) error {
+	st, err := s.owner(ctx, memoryID)
+	if err != nil {
+		return err
+	}
+	return st.PutEmbedding(ctx, memoryID, model, vec, now)
+}
+
+// MemoriesMissingEmbedding implements EmbeddingStore, taking the primary's
+// memories first.
+func (s *ShardedStore) MemoriesMissingEmbedding(ctx context.Context, model string, limit int) ([]types.MemoryRecord, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	var out []types.MemoryRecord
+	for _, st := range s.stores() {
+		recs, err := st.MemoriesMissingEmbedding(ctx, model, limit-len(out))
+		if err != nil {
+			return out, err
+		}
+		if out = append(out, recs...); len(out) >= limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+// ActivateEmbeddingModel implements EmbeddingStore. Stores without vectors
+// for model, such as an empty shard, are left alone.
+func (s *ShardedStore) ActivateEmbeddingModel(ctx context.Context, model string) error {
+	activated := false
+	for _, st := range s.stores() {
+		ok, err := st.hasEmbeddingModel(ctx, model)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := st.ActivateEmbeddingModel(ctx, model); err != nil {
+			return err
+		}
+		activated = true
+	}
+	if !activated {
+		return fmt.Errorf("embedding model %q has no vectors", model)
+	}
+	return nil
+}
+
+// NearestEmbeddings implements EmbeddingStore.
+func (s *ShardedStore) NearestEmbeddings(ctx context.Context, q VectorQuery) ([]VectorMatch, error) {
+	return s.storeFor(q.Namespace).NearestEmbeddings(ctx, q)
+}
+
+// RecordAudit implements AuditStore.
+func (s *ShardedStore) RecordAudit(ctx context.Context, entries ...AuditEntry) error {
+	return s.primary.RecordAudit(ctx, entries...)
+}
+
+// AuditLog implements AuditStore.
+func (s *ShardedStore) AuditLog(ctx context.Context, f AuditFilter) ([]AuditEntry, error) {
+	return s.primary.AuditLog(ctx, f)
+}
+
+// RedactAudit implements AuditStore.
+func (s *ShardedStore) RedactAudit(ctx context.Context, memoryIDs []string) error {
+	return s.primary.RedactAudit(ctx, memoryIDs)
+}
+
+// RecordPromotion implements PromotionStore.
+func (s *ShardedStore) RecordPromotion(ctx context.Context, p Promotion) error {
+	return s.primary.RecordPromotion(ctx, p)
+}
+
+// RecentPromotions implements PromotionStore.
+func (s *ShardedStore) RecentPromotions(ctx context.Context, limit int) ([]Promotion, error) {
+	return s.primary.RecentPromotions(ctx, limit)
+}
+
+// RecordSearchMiss implements MissStore.
+func (s *ShardedStore) RecordSearchMiss(ctx context.Context, m SearchMiss) error {
+	return s.primary.RecordSearchMiss(ctx, m)
+}
+
+// SearchMisses implements MissStore.
+func (s *ShardedStore) SearchMisses(ctx context.Context, namespace string, limit int) ([]SearchMiss, error) {
+	return s.primary.SearchMisses(ctx, namespace, limit)
+}
+
+// InsertMCPRequestLog records a request in the primary store.
+func (s *ShardedStore) InsertMCPRequestLog(ctx context.Context, rec MCPRequestLog) error {
+	return s.primary.InsertMCPRequestLog(ctx, rec)
+}
+
+// RecentMCPRequestLogs returns the newest requests from the primary store.
+func (s *ShardedStore) RecentMCPRequestLogs(ctx context.Context, limit int) ([]MCPRequestLog, error) {
+	return s.primary.RecentMCPRequestLogs(ctx, limit)
+}
+
+// MCPRequestLogsAfter tails the requests of the primary store.
+func (s *ShardedStore) MCPRequestLogsAfter(ctx context.Context, afterID int64, limit int) ([]MCPRequestLog, error) {
+	return s.primary.MCPRequestLogsAfter(ctx, afterID, limit)
+}
+
+// ClearMCPRequestParams clears request params in the primary store.
+func (s *ShardedStore) ClearMCPRequestParams(ctx context.Context, ids []int64) error {
+	return s.primary.ClearMCPRequestParams(ctx, ids)
+}
+
+// GetMCPRequestLog returns one logged request from the primary store.
+func (s *ShardedStore) GetMCPRequestLog(ctx context.Context, id int64) (MCPRequestLog, error) {
+	return s.primary.GetMCPRequestLog(ctx, id)
+}
+
+// RequestsByClient summarizes requests from the primary store.
+func (s *ShardedStore) RequestsByClient(ctx context.Context, limit int) ([]ClientRequests, error) {
+	return s.primary.RequestsByClient(ctx, limit)
+}
+
+// InsertServerLogs records log events in the primary store.
+func (s *ShardedStore) InsertServerLogs(ctx context.Context, logs []ServerLog) error {
+	return s.primary.InsertServerLogs(ctx, logs)
+}
+
+// RecentServerLogs returns the newest log events from the primary store.
+func (s *ShardedStore) RecentServerLogs(ctx context.Context, limit int) ([]ServerLog, error) {
+	return s.primary.RecentServerLogs(ctx, limit)
+}
+
+// ListByScope returns every memory in scope across all stores, oldest
+// first.
+func (s *ShardedStore) ListByScope(ctx context.Context, scope string) ([]types.MemoryRecord, error) {
+	var out []types.MemoryRecord
+	for _, st := range s.stores() {
+		recs, err := st.ListByScope(ctx, scope)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, recs...)
+	}
+	slices.SortStableFunc(out, func(a, b types.MemoryRecord) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return out, nil
+}
+
+// RecentMemories merges the newest memories of every store.
+func (s *ShardedStore) RecentMemories(ctx context.Context, limit int) ([]RecentMemory, error) {
+	var out []RecentMemory
+	for _, st := range s.stores() {
+		recs, err := st.RecentMemories(ctx, limit)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, recs...)
+	}
+	slices.SortStableFunc(out, func(a, b RecentMemory) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.ID, a.ID))
+	})
+	if limit <= 0 {
+		limit = 20
+	}
+	return out[:min(limit, len(out))], nil
+}
+
+// CheckFTS checks the FTS index of every store, stopping at the first
+// mismatch.
+func (s *ShardedStore) CheckFTS(ctx context.Context) error {
+	if err := s.primary.CheckFTS(ctx); err != nil {
+		return err
+	}
+	for _, sh := range s.shards {
+		if err := sh.Store.CheckFTS(ctx); err != nil {
+			return fmt.Errorf("shard %s: %w", sh.Prefix, err)
+		}
+	}
+	return nil
+}
+
+// RebuildFTS regenerates the FTS index of every store.
+func (s *ShardedStore) RebuildFTS(ctx context.Context) error {
+	for _, st := range s.stores() {
+		if err := st.RebuildFTS(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IndexHealth adds up the index counts and sizes of every store. FTS is
+// reported on only when every store uses it, and LastReindex is the
+// oldest store's.
+func (s *ShardedStore) IndexHealth(ctx context.Context) (IndexHealth, error) {
+	var total IndexHealth
+	sizes := map[string]int64{}
+	for i, st := range s.stores() {
+		h, err := st.IndexHealth(ctx)
+		if err != nil {
+			return total, err
+		}
+		if i == 0 {
+			total.FTSEnabled, total.LastReindex = h.FTSEnabled, h.LastReindex
+		}
+		total.FTSEnabled = total.FTSEnabled && h.FTSEnabled
+		total.FTSForcedOff = total.FTSForcedOff || h.FTSForcedOff
+		if h.LastReindex.Before(total.LastReindex) {
+			total.LastReindex = h.LastReindex
+		}
+		total.Memories += h.Memories
+		total.FTSRows += h.FTSRows
+		for _, o := range h.Sizes {
+			sizes[o.Name] += o.Bytes
+		}
+	}
+	for name, n := range sizes {
+		total.Sizes = append(total.Sizes, ObjectSize{Name: name, Bytes: n})
+	}
+	slices.SortFunc(total.Sizes, func(a, b ObjectSize) int {
+		return cmp.Or(cmp.Compare(b.Bytes, a.Bytes), cmp.Compare(a.Name, b.Name))
+	})
+	return total, nil
+}
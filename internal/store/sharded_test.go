@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestShardedStore_RoutesAndMergesByNamespace(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	dir := t.TempDir()
+	st, err := OpenSharded(ctx, filepath.Join(dir, "main.db"), map[string]string{
+		"acme/api":     filepath.Join(dir, "api.db"),
+		"acme/api/v2/": filepath.Join(dir, "v2.db"),
+	}, logger)
+	if err != nil {
+		t.Fatalf("OpenSharded() error = %v", err)
+	}
+	defer st.Close()
+
+	now := time.Now().UTC()
+	var recs []types.MemoryRecord
+	for i, ns := range []string{"acme/web/main", "acme/api/main", "acme/api/v2/main", "acme/apix/main"} {
+		recs = append(recs, types.MemoryRecord{
+			ID: "m-" + ns, Namespace: ns, Scope: "long", Content: "deploy notes for " + ns, Importance: 3,
+			CreatedAt: now.Add(time.Duration(i) * time.Minute), LastAccessedAt: now,
+		})
+	}
+	if _, err := st.InsertMemories(ctx, recs); err != nil {
+		t.Fatalf("InsertMemories() error = %v", err)
+	}
+
+	for _, c := range []struct {
+		store *SQLiteStore
+		id    string
+	}{
+		{st.storeFor("acme/api/main"), "m-acme/api/main"},
+		{st.storeFor("acme/api/v2/main"), "m-acme/api/v2/main"},
+		{st.Primary(), "m-acme/apix/main"},
+	} {
+		if _, err := c.store.GetMemory(ctx, c.id); err != nil {
+			t.Fatalf("GetMemory(%s) on its shard error = %v", c.id, err)
+		}
+	}
+	if _, err := st.Primary().GetMemory(ctx, "m-acme/api/main"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("primary GetMemory(sharded id) error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := st.GetMemory(ctx, "m-acme/api/v2/main"); err != nil {
+		t.Fatalf("GetMemory() error = %v", err)
+	}
+
+	got, err := st.ListMemories(ctx, ListFilter{NamespacePrefix: "acme/api", Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatalf("ListMemories() error = %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "m-acme/api/main" {
+		t.Fatalf("ListMemories(acme/api, offset 1) = %+v, want m-acme/api/main", got)
+	}
+	all, err := st.ListMemories(ctx, ListFilter{Sort: SortCreatedAsc})
+	if err != nil {
+		t.Fatalf("ListMemories() error = %v", err)
+	}
+	if len(all) != 4 || all[0].ID != "m-acme/web/main" || all[3].ID != "m-acme/apix/main" {
+		t.Fatalf("ListMemories(all) = %d records, want 4 oldest first", len(all))
+	}
+	counts, err := st.CountBy(ctx, GroupScope, ListFilter{NamespacePrefix: "acme"})
+	if err != nil {
+		t.Fatalf("CountBy() error = %v", err)
+	}
+	if len(counts) != 1 || counts[0].Count != 4 {
+		t.Fatalf("CountBy(scope) = %+v, want 4 long", counts)
+	}
+
+	if err := st.Promote(ctx, "m-acme/api/main", now); err != nil {
+		t.Fatalf("Promote() error = %v", err)
+	}
+	if err := st.Promote(ctx, "m-missing", now); err == nil {
+		t.Fatal("Promote(missing) error = nil")
+	}
+	n, err := st.DeleteMemories(ctx, []string{"m-acme/api/main", "m-acme/web/main"})
+	if err != nil || n != 2 {
+		t.Fatalf("DeleteMemories() = %d, %v, want 2", n, err)
+	}
+	stats, err := st.Stats(ctx, now)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.Total != 2 || stats.Long != 2 {
+		t.Fatalf("Stats() = %+v, want 2 long memories", stats)
+	}
+	if want := []GroupCount{{"", 2}}; !reflect.DeepEqual(stats.Agents, want) {
+		t.Fatalf("Stats().Agents = %v, want the shards' counts merged into %v", stats.Agents, want)
+	}
+}
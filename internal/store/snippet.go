@@ -0,0 +1,194 @@
+package store
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/xiy/memory-mcp/internal/lang"
+	"github.com/xiy/memory-mcp/internal/textnorm"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+const (
+	// snippetWords is how many words of a memory a snippet shows.
+	snippetWords = 24
+	// snippetLead is how many words a snippet shows before its first match.
+	snippetLead = 4
+)
+
+// ftsMarkOpen and ftsMarkClose are the control characters highlight()
+// wraps the matched tokens of search_text in.
+const (
+	ftsMarkOpen  = "\x02"
+	ftsMarkClose = "\x03"
+)
+
+// textWord is a word of a memory's content or summary. start and end are
+// rune offsets, byteStart and byteEnd byte offsets, both end-exclusive.
+type textWord struct {
+	text               string
+	start, end         int
+	byteStart, byteEnd int
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// splitWords splits s into its runs of letters and digits.
+func splitWords(s string) []textWord {
+	var words []textWord
+	start, startRune, n := -1, 0, 0
+	for i, r := range s {
+		if isWordRune(r) {
+			if start < 0 {
+				start, startRune = i, n
+			}
+		} else if start >= 0 {
+			words = append(words, textWord{s[start:i], startRune, n, start, i})
+			start = -1
+		}
+		n++
+	}
+	if start >= 0 {
+		words = append(words, textWord{s[start:], startRune, n, start, len(s)})
+	}
+	return words
+}
+
+// analyzedWord is word as search_text holds it: folded, and stemmed for
+// English memories.
+func analyzedWord(language, word string) string {
+	word = textnorm.Fold(word)
+	if language == lang.English {
+		word = stemText(word)
+	}
+	return word
+}
+
+// withHighlights sets the snippet and match offsets of every candidate
+// from the words of its memory that match.
+func withHighlights(items []Candidate, match func(analyzed string) bool) []Candidate {
+	for i := range items {
+		items[i].Snippet, items[i].MatchOffsets = highlight(items[i].Record, match)
+	}
+	return items
+}
+
+// highlight finds the words of rec's content, or of its summary when none
+// in the content do, for whose analyzed form match reports true. FTS5's
+// own snippet() would quote search_text, which is folded and stemmed, so
+// the snippet is cut from the original text instead.
+func highlight(rec types.MemoryRecord, match func(analyzed string) bool) (string, []types.MatchOffset) {
+	for _, field := range [...]struct{ name, text string }{{"content", rec.Content}, {"summary", rec.Summary}} {
+		words := splitWords(field.text)
+		var hits []int
+		for i, w := range words {
+			if match(analyzedWord(rec.Language, w.text)) {
+				hits = append(hits, i)
+			}
+		}
+		if len(hits) == 0 {
+			continue
+		}
+		offsets := make([]types.MatchOffset, len(hits))
+		for i, h := range hits {
+			offsets[i] = types.MatchOffset{Field: field.name, Start: words[h].start, End: words[h].end}
+		}
+		return snippet(field.text, words, hits), offsets
+	}
+	return "", nil
+}
+
+// snippet returns up to snippetWords words of text, starting shortly
+// before the hit that begins the window holding the most hits, with the
+// hit words wrapped in [ and ] and whitespace collapsed.
+func snippet(text string, words []textWord, hits []int) string {
+	first, best := 0, 0
+	for _, h := range hits {
+		start := max(0, h-snippetLead)
+		n := 0
+		for _, o := range hits {
+			if o >= start && o < start+snippetWords {
+				n++
+			}
+		}
+		if n > best {
+			first, best = start, n
+		}
+	}
+	last := min(len(words), first+snippetWords) - 1
+
+	var b strings.Builder
+	pos, end := 0, len(text)
+	if first > 0 {
+		b.WriteString("… ")
+		pos = words[first].byteStart
+	}
+	if last < len(words)-1 {
+		end = words[last].byteEnd
+	}
+	for i := first; i <= last; i++ {
+		w := words[i]
+		if !slices.Contains(hits, i) {
+			continue
+		}
+		b.WriteString(text[pos:w.byteStart])
+		b.WriteString("[" + w.text + "]")
+		pos = w.byteEnd
+	}
+	b.WriteString(text[pos:end])
+	if last < len(words)-1 {
+		b.WriteString(" …")
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// termMatcher matches analyzed words the way the LIKE fallback matches
+// search_text: by containing any form of any query term.
+func termMatcher(terms []string) func(string) bool {
+	var forms []string
+	for _, term := range terms {
+		for _, form := range termForms(term) {
+			if form = strings.TrimSuffix(form, "*"); form != "" {
+				forms = append(forms, form)
+			}
+		}
+	}
+	return func(word string) bool {
+		return slices.ContainsFunc(forms, func(form string) bool { return strings.Contains(word, form) })
+	}
+}
+
+// highlightMatcher matches analyzed words against the tokens highlight()
+// marked in search_text. CJK runs are indexed as n-grams, so a run
+// matches when it contains a marked gram.
+func highlightMatcher(highlighted string) func(string) bool {
+	marked := map[string]bool{}
+	for {
+		_, rest, ok := strings.Cut(highlighted, ftsMarkOpen)
+		if !ok {
+			break
+		}
+		span, after, _ := strings.Cut(rest, ftsMarkClose)
+		for _, w := range splitWords(span) {
+			marked[w.text] = true
+		}
+		highlighted = after
+	}
+	return func(word string) bool {
+		if marked[word] {
+			return true
+		}
+		if !strings.ContainsFunc(word, isCJK) {
+			return false
+		}
+		for gram := range marked {
+			if strings.Contains(word, gram) {
+				return true
+			}
+		}
+		return false
+	}
+}
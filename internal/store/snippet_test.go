@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestSnippet_WindowsAroundMostMatches(t *testing.T) {
+	t.Parallel()
+	text := "alpha " + strings.Repeat("filler ", 30) + "beta gamma beta " + strings.Repeat("tail ", 30)
+	rec := types.MemoryRecord{Content: text}
+	got, offsets := highlight(rec, func(w string) bool { return w == "alpha" || w == "beta" })
+	if len(offsets) != 3 || offsets[0] != (types.MatchOffset{Field: "content", Start: 0, End: 5}) {
+		t.Fatalf("highlight() offsets = %+v", offsets)
+	}
+	want := "… filler filler filler filler [beta] gamma [beta] tail"
+	if !strings.HasPrefix(got, want) || !strings.HasSuffix(got, " …") {
+		t.Fatalf("highlight() snippet = %q, want prefix %q", got, want)
+	}
+	if n := len(strings.Fields(got)); n != snippetWords+2 {
+		t.Fatalf("highlight() snippet has %d fields, want %d words and two ellipses", n, snippetWords)
+	}
+
+	rec = types.MemoryRecord{Content: "nothing here", Summary: "Café menu"}
+	got, offsets = highlight(rec, func(w string) bool { return w == "café" })
+	if got != "[Café] menu" || len(offsets) != 1 || offsets[0] != (types.MatchOffset{Field: "summary", Start: 0, End: 4}) {
+		t.Fatalf("highlight(summary) = %q, %+v", got, offsets)
+	}
+}
+
+func TestSearchCandidates_Snippets(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	now := time.Now().UTC()
+	recs := []types.MemoryRecord{
+		{ID: "m-deploy", Namespace: "org/repo/task", Scope: "long", Content: "Deploying to staging failed twice; retry the deploy after the migration.", CreatedAt: now, LastAccessedAt: now},
+		{ID: "m-other", Namespace: "org/repo/task", Scope: "long", Content: "Lint runs before tests.", CreatedAt: now, LastAccessedAt: now},
+	}
+	stores := map[string]Store{"memory": NewMemoryStore()}
+	for name, opts := range map[string][]Option{"fts": nil, "like": {WithoutFTS()}} {
+		st, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), name+".db"), logger, opts...)
+		if err != nil {
+			t.Fatalf("OpenSQLite() error = %v", err)
+		}
+		defer st.Close()
+		stores[name] = st
+	}
+	for name, st := range stores {
+		if _, err := st.InsertMemories(ctx, recs); err != nil {
+			t.Fatalf("%s: InsertMemories() error = %v", name, err)
+		}
+		cands, err := st.SearchCandidates(ctx, SearchQuery{Namespace: "org/repo/task", Query: "deploying", Limit: 10, Now: now})
+		if err != nil || len(cands) != 1 {
+			t.Fatalf("%s: SearchCandidates() = %+v, %v, want m-deploy", name, cands, err)
+		}
+		want := "[Deploying] to staging failed twice; retry the [deploy] after the migration."
+		if got := cands[0].Snippet; got != want {
+			t.Fatalf("%s: Snippet = %q, want %q", name, got, want)
+		}
+		if got := cands[0].MatchOffsets; len(got) != 2 || got[1] != (types.MatchOffset{Field: "content", Start: 45, End: 51}) {
+			t.Fatalf("%s: MatchOffsets = %+v", name, got)
+		}
+	}
+
+	cands, err := stores["fts"].SearchCandidates(ctx, SearchQuery{Namespace: "org/repo/task", Query: "stag* OR lint", Advanced: true, Limit: 10, Now: now})
+	if err != nil || len(cands) != 2 {
+		t.Fatalf("SearchCandidates(advanced) = %+v, %v", cands, err)
+	}
+	for _, c := range cands {
+		if !strings.Contains(c.Snippet, "[staging]") && !strings.Contains(c.Snippet, "[Lint]") {
+			t.Fatalf("SearchCandidates(advanced) snippet = %q, want the prefix or OR term marked", c.Snippet)
+		}
+	}
+}
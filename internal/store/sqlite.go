@@ -10,13 +10,19 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 
 	"github.com/charmbracelet/log"
 	_ "modernc.org/sqlite"
 
+	"github.com/xiy/memory-mcp/internal/cache"
+	"github.com/xiy/memory-mcp/internal/clock"
+	"github.com/xiy/memory-mcp/internal/lang"
+	"github.com/xiy/memory-mcp/internal/textnorm"
 	"github.com/xiy/memory-mcp/pkg/types"
 )
 
@@ -27,25 +33,84 @@ var schemaSQL string
 type Candidate struct {
 	Record       types.MemoryRecord
 	LexicalScore float64
+	// Snippet and MatchOffsets show which words of the memory matched the
+	// query; see types.SearchResult.
+	Snippet      string
+	MatchOffsets []types.MatchOffset
+}
+
+// SearchQuery selects search candidates within one namespace.
+type SearchQuery struct {
+	Namespace string
+	Query     string
+	// Scope is "short", "long", "project", "global" or "" for all.
+	Scope string
+	// Limit <= 0 means 10.
+	Limit int
+	// Now decides which short-term memories have expired.
+	Now time.Time
+	// Metadata conditions must all hold; see ParseMetadataFilter.
+	Metadata []MetadataCond
+	// SourceAgent, when set, keeps only memories written by that agent.
+	SourceAgent string
+	// StopWords are dropped from the query terms unless nothing else is
+	// left.
+	StopWords lang.StopWords
+	// Advanced passes Query to FTS5 as a match expression, with its
+	// NEAR, prefix*, OR, NOT and column filters, instead of ANDing its
+	// terms. Stores without FTS5 return ErrAdvancedQueryUnsupported.
+	Advanced bool
 }
 
 // Stats summarizes database counters for admin dashboards.
 type Stats struct {
-	Total   int64
-	Short   int64
-	Long    int64
-	Expired int64
+	Total   int64 `json:"total"`
+	Short   int64 `json:"short"`
+	Long    int64 `json:"long"`
+	Project int64 `json:"project"`
+	Global  int64 `json:"global"`
+	Expired int64 `json:"expired"`
+	// Useful and Irrelevant count memory_feedback reports (SQLite only).
+	Useful     int64 `json:"useful"`
+	Irrelevant int64 `json:"irrelevant"`
+	// Agents counts memories, expired included, per source_agent, most
+	// first.
+	Agents []GroupCount `json:"agents,omitempty"`
+}
+
+// RequestLogScrubber is implemented by stores that keep the MCP request
+// log, so a purge can clear logged params that carry purged data.
+type RequestLogScrubber interface {
+	MCPRequestLogsAfter(ctx context.Context, afterID int64, limit int) ([]MCPRequestLog, error)
+	// ClearMCPRequestParams blanks the params of the given requests, which
+	// can then no longer be replayed.
+	ClearMCPRequestParams(ctx context.Context, ids []int64) error
 }
 
 // MCPRequestLog captures one incoming MCP request handled by the server.
 type MCPRequestLog struct {
-	ID         int64
-	Method     string
-	ToolName   string
-	Success    bool
-	ErrorText  string
-	DurationMS int64
-	CreatedAt  time.Time
+	ID         int64  `json:"id"`
+	Method     string `json:"method"`
+	ToolName   string `json:"tool_name,omitempty"`
+	Success    bool   `json:"success"`
+	ErrorText  string `json:"error_text,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	// ClientName and ClientVersion come from the session's initialize
+	// clientInfo.
+	ClientName    string `json:"client_name,omitempty"`
+	ClientVersion string `json:"client_version,omitempty"`
+	// Params is the sanitized JSON params of a tools/call, kept for
+	// admin replay.
+	Params    string    `json:"params,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ClientRequests summarizes the requests of one MCP client.
+type ClientRequests struct {
+	Client   string
+	Requests int64
+	Errors   int64
+	LastSeen time.Time
 }
 
 // RecentMemory is a compact summary row for admin dashboards.
@@ -61,59 +126,274 @@ type RecentMemory struct {
 // Store represents persistence operations used by memory service.
 type Store interface {
 	InsertMemory(ctx context.Context, rec types.MemoryRecord) (types.MemoryRecord, error)
-	SearchCandidates(ctx context.Context, namespace, query, scope string, limit int, now time.Time) ([]Candidate, error)
+	InsertMemories(ctx context.Context, recs []types.MemoryRecord) ([]types.MemoryRecord, error)
+	SearchCandidates(ctx context.Context, q SearchQuery) ([]Candidate, error)
 	Promote(ctx context.Context, id string, now time.Time) error
+	// Demote moves id back to short-term scope, expiring at expiresAt, and
+	// clears its promoted_at.
+	Demote(ctx context.Context, id string, expiresAt, now time.Time) error
+	UpdateMemory(ctx context.Context, id string, fn func(*types.MemoryRecord) error) (types.MemoryRecord, error)
 	ExpireShort(ctx context.Context, now time.Time) (int64, error)
 	Stats(ctx context.Context, now time.Time) (Stats, error)
 	GetMemory(ctx context.Context, id string) (types.MemoryRecord, error)
+	ListMemories(ctx context.Context, f ListFilter) ([]types.MemoryRecord, error)
+	CountBy(ctx context.Context, dim string, f ListFilter) ([]GroupCount, error)
+	DeleteMemories(ctx context.Context, ids []string) (int64, error)
+	// DeleteByFilter removes every memory matching f and returns how many
+	// were removed. Sort, cursor and paging fields of f are ignored.
+	DeleteByFilter(ctx context.Context, f ListFilter) (int64, error)
+	// ExportAll and ImportBatch back up and restore memories; ImportBatch
+	// skips records whose IDs are already stored.
+	ExportAll(ctx context.Context, fn func(types.MemoryRecord) error) error
+	ImportBatch(ctx context.Context, recs []types.MemoryRecord) ([]types.MemoryRecord, []string, error)
 	Close() error
 }
 
 // SQLiteStore is a SQLite-backed memory store.
+//
+// Writes go through db, which holds a single connection by default so
+// SQLite's one writer never contends with itself. Reads use reader, a pool
+// of read-only connections that WAL lets proceed alongside the writer; when
+// the pool is disabled reader is db. Every connection waits up to
+// busyTimeout for a lock held by another process instead of failing.
 type SQLiteStore struct {
-	db         *sql.DB
-	logger     *log.Logger
-	ftsEnabled bool
+	db          *sql.DB
+	reader      *sql.DB
+	readConns   int
+	writeConns  int
+	busyTimeout time.Duration
+	records     *cache.LRU[string, types.MemoryRecord]
+	// recordCap and recordTTL are set by WithRecordCache; the cache is built
+	// once every option has run so it shares the store's clock.
+	recordCap int
+	recordTTL time.Duration
+	// recordMu orders caching a read record against dropping entries, and
+	// recordGen counts the drops, so a read that raced a write is not
+	// cached.
+	recordMu    sync.Mutex
+	recordGen   uint64
+	logger      *log.Logger
+	clock       clock.Clock
+	ftsEnabled  bool
+	ftsOff      bool
+	ftsMetaKeys []string
+	cjkNgram    int
+	metaColKeys []string
+	// metaCols holds the metadata keys backed by generated columns.
+	metaCols map[string]struct{}
+	// readOnly and immutable are set by WithReadOnly.
+	readOnly, immutable bool
+}
+
+// Option customizes a SQLiteStore.
+type Option func(*SQLiteStore)
+
+// WithClock replaces the wall clock used for store-assigned timestamps.
+func WithClock(c clock.Clock) Option {
+	return func(s *SQLiteStore) { s.clock = c }
+}
+
+// WithoutFTS forces the LIKE search path even when FTS5 is available. The FTS
+// index is still maintained so other handles on the same database can use it.
+func WithoutFTS() Option {
+	return func(s *SQLiteStore) { s.ftsOff = true }
+}
+
+// WithFTSMetadataKeys indexes the values of the given top-level metadata
+// keys alongside content and summary, so searches match e.g. ticket IDs or
+// file names kept only in metadata. Changing the keys re-derives the indexed
+// text for every row on the next open.
+func WithFTSMetadataKeys(keys ...string) Option {
+	return func(s *SQLiteStore) { s.ftsMetaKeys = keys }
+}
+
+// WithCJKNgram indexes runs of Chinese, Japanese and Korean characters as
+// overlapping n-grams of n runes (2 by default) so words inside them can be
+// searched; 0 indexes each run as a single token. Changing n re-indexes
+// every memory on open.
+func WithCJKNgram(n int) Option {
+	return func(s *SQLiteStore) { s.cjkNgram = n }
+}
+
+// WithReadConns sizes the read-only connection pool. n <= 0 serves reads
+// from the write connection.
+func WithReadConns(n int) Option {
+	return func(s *SQLiteStore) { s.readConns = n }
+}
+
+// WithWriteConns sizes the write connection pool (1 by default). With more
+// than one, transactions start with BEGIN IMMEDIATE so they queue for
+// SQLite's write lock within the busy timeout instead of failing when two
+// try to upgrade a read lock at once.
+func WithWriteConns(n int) Option {
+	return func(s *SQLiteStore) { s.writeConns = max(n, 1) }
+}
+
+// WithBusyTimeout sets how long a connection waits for a lock held by
+// another connection or process, such as a running serve while an admin
+// command writes (5s by default). 0 fails at once with SQLITE_BUSY.
+func WithBusyTimeout(d time.Duration) Option {
+	return func(s *SQLiteStore) { s.busyTimeout = max(d, 0) }
+}
+
+// dsn returns the data source name for path with the connection settings
+// every connection needs, appended to the URI query extra.
+func (s *SQLiteStore) dsn(path, extra string) string {
+	q := fmt.Sprintf("_pragma=busy_timeout(%d)", s.busyTimeout.Milliseconds())
+	if extra != "" {
+		q = extra + "&" + q
+	}
+	return path + "?" + q
+}
+
+// WithRecordCache keeps up to n recently read records in memory for up to
+// ttl so repeated GetMemory calls skip SQLite. Every mutation through this
+// store drops the affected entries; the TTL bounds how long a change made
+// by another process goes unseen. n <= 0 disables the cache.
+func WithRecordCache(n int, ttl time.Duration) Option {
+	return func(s *SQLiteStore) { s.recordCap, s.recordTTL = n, ttl }
 }
 
 // OpenSQLite opens and initializes the SQLite store.
-func OpenSQLite(ctx context.Context, dbPath string, logger *log.Logger) (*SQLiteStore, error) {
+func OpenSQLite(ctx context.Context, dbPath string, logger *log.Logger, opts ...Option) (*SQLiteStore, error) {
+	s := &SQLiteStore{logger: logger, clock: clock.System{}, cjkNgram: 2, writeConns: 1, busyTimeout: 5 * time.Second}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.recordCap > 0 {
+		s.records = cache.New[string, types.MemoryRecord](s.recordCap, s.recordTTL, s.clock)
+	}
+	if s.readOnly {
+		if err := s.openReadOnly(ctx, dbPath); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+
 	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
 		return nil, fmt.Errorf("mkdir db dir: %w", err)
 	}
 
-	db, err := sql.Open("sqlite", dbPath)
+	// WAL lets the readers, and other processes, read while a write is in
+	// progress.
+	params := "_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)"
+	if s.writeConns > 1 {
+		params += "&_txlock=immediate"
+	}
+	db, err := openTraced("sqlite", s.dsn(dbPath, params))
 	if err != nil {
 		return nil, fmt.Errorf("open sqlite: %w", err)
 	}
 
-	db.SetMaxOpenConns(1)
-	db.SetMaxIdleConns(1)
+	db.SetMaxOpenConns(s.writeConns)
+	db.SetMaxIdleConns(s.writeConns)
+	s.db, s.reader = db, db
 
-	s := &SQLiteStore{db: db, logger: logger}
 	if err := s.init(ctx); err != nil {
 		_ = db.Close()
 		return nil, err
 	}
+	if s.readConns > 0 {
+		if err := s.openReader(ctx, dbPath); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
 	return s, nil
 }
 
+// openReader opens the read-only pool once the schema exists.
+func (s *SQLiteStore) openReader(ctx context.Context, dbPath string) error {
+	reader, err := openTraced("sqlite", s.dsn("file:"+dbPath, "mode=ro"))
+	if err != nil {
+		return fmt.Errorf("open sqlite reader: %w", err)
+	}
+	reader.SetMaxOpenConns(s.readConns)
+	reader.SetMaxIdleConns(s.readConns)
+	if err := reader.PingContext(ctx); err != nil {
+		_ = reader.Close()
+		return fmt.Errorf("open sqlite reader: %w", err)
+	}
+	s.reader = reader
+	return nil
+}
+
 func (s *SQLiteStore) init(ctx context.Context) error {
 	for _, stmt := range splitSQLStatements(schemaSQL) {
 		if strings.TrimSpace(stmt) == "" {
 			continue
 		}
 		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
-			if strings.Contains(strings.ToLower(stmt), "virtual table") {
-				s.logger.Warn("FTS5 disabled; falling back to LIKE queries", "error", err)
-				s.ftsEnabled = false
-				continue
-			}
 			return fmt.Errorf("run schema stmt: %w", err)
 		}
 	}
 
-	s.ftsEnabled = s.hasFTSTable(ctx)
+	for _, col := range []string{"client_name", "client_version", "params"} {
+		if err := s.ensureColumn(ctx, "mcp_requests", col, "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+	}
+	for _, col := range []string{"meta_text", "search_text", "language", "external_key"} {
+		if err := s.ensureColumn(ctx, "memories", col, "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+	}
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return err
+	}
+	if err := s.migrate(ctx, migrations); err != nil {
+		return err
+	}
+	// Created here rather than in schema.sql, which runs before older
+	// databases gain the column.
+	if _, err := s.db.ExecContext(ctx, `CREATE UNIQUE INDEX IF NOT EXISTS idx_memories_external_key
+ON memories(namespace, external_key) WHERE external_key != ''`); err != nil {
+		return fmt.Errorf("create external key index: %w", err)
+	}
+	backfilled, err := s.backfillSearchText(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.ensureMetadataColumns(ctx); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, factsTriggerSQL); err != nil {
+		return fmt.Errorf("create facts trigger: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, feedbackTriggerSQL); err != nil {
+		return fmt.Errorf("create feedback trigger: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, embeddingsTriggerSQL); err != nil {
+		return fmt.Errorf("create embeddings trigger: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, embeddingsUpdateTriggerSQL); err != nil {
+		return fmt.Errorf("create embeddings trigger: %w", err)
+	}
+
+	enabled, err := s.ensureFTS(ctx, backfilled > 0)
+	if err != nil {
+		return err
+	}
+	s.ftsEnabled = enabled
+	return s.syncMetaText(ctx)
+}
+
+// ensureColumn adds a column that CREATE TABLE IF NOT EXISTS cannot add to
+// databases created by older versions.
+func (s *SQLiteStore) ensureColumn(ctx context.Context, table, column, decl string) error {
+	var n int
+	err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&n)
+	if err != nil {
+		return fmt.Errorf("inspect %s columns: %w", table, err)
+	}
+	if n > 0 {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, decl)); err != nil {
+		return fmt.Errorf("add %s.%s: %w", table, column, err)
+	}
 	return nil
 }
 
@@ -130,23 +410,51 @@ func splitSQLStatements(s string) []string {
 	return out
 }
 
-func (s *SQLiteStore) hasFTSTable(ctx context.Context) bool {
-	const q = `SELECT count(*) FROM sqlite_master WHERE type='table' AND name='memories_fts'`
-	var n int
-	if err := s.db.QueryRowContext(ctx, q).Scan(&n); err != nil {
-		return false
+func (s *SQLiteStore) InsertMemory(ctx context.Context, rec types.MemoryRecord) (types.MemoryRecord, error) {
+	setLanguage(&rec)
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		return s.insertMemoryTx(ctx, tx, rec)
+	})
+	// Drop rather than cache the written record so the next read sees it
+	// exactly as SQLite stored it.
+	s.uncacheRecords(rec.ID)
+	return rec, err
+}
+
+// InsertMemories stores all records in one transaction; either every record
+// is inserted and indexed or none is.
+func (s *SQLiteStore) InsertMemories(ctx context.Context, recs []types.MemoryRecord) ([]types.MemoryRecord, error) {
+	if len(recs) == 0 {
+		return recs, nil
 	}
-	return n > 0
+	for i := range recs {
+		setLanguage(&recs[i])
+	}
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		for _, rec := range recs {
+			if err := s.insertMemoryTx(ctx, tx, rec); err != nil {
+				return fmt.Errorf("memory %s: %w", rec.ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range recs {
+		s.uncacheRecords(rec.ID)
+	}
+	return recs, nil
 }
 
-func (s *SQLiteStore) InsertMemory(ctx context.Context, rec types.MemoryRecord) (types.MemoryRecord, error) {
+func (s *SQLiteStore) insertMemoryTx(ctx context.Context, tx *sql.Tx, rec types.MemoryRecord) error {
 	meta := rec.Metadata
 	if meta == nil {
 		meta = map[string]any{}
 	}
 	metaJSON, err := json.Marshal(meta)
 	if err != nil {
-		return rec, fmt.Errorf("marshal metadata: %w", err)
+		return fmt.Errorf("marshal metadata: %w", err)
 	}
 
 	expiresAt := sql.NullString{}
@@ -160,9 +468,10 @@ func (s *SQLiteStore) InsertMemory(ctx context.Context, rec types.MemoryRecord)
 
 	const q = `INSERT INTO memories (
 		id, namespace, scope, content, summary, importance, source_agent, metadata_json,
-		created_at, last_accessed_at, expires_at, promoted_at
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err = s.db.ExecContext(ctx, q,
+		created_at, last_accessed_at, expires_at, promoted_at, meta_text, search_text, language, external_key,
+		access_count
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = tx.ExecContext(ctx, q,
 		rec.ID,
 		rec.Namespace,
 		rec.Scope,
@@ -175,134 +484,183 @@ func (s *SQLiteStore) InsertMemory(ctx context.Context, rec types.MemoryRecord)
 		rec.LastAccessedAt.UTC().Format(time.RFC3339Nano),
 		expiresAt,
 		promotedAt,
+		s.indexedMetaText(meta),
+		s.indexedText(rec.Language, rec.Content, rec.Summary),
+		rec.Language,
+		rec.ExternalKey,
+		rec.AccessCount,
 	)
 	if err != nil {
-		return rec, fmt.Errorf("insert memory: %w", err)
+		return fmt.Errorf("insert memory: %w", err)
 	}
+	return nil
+}
 
-	if s.ftsEnabled {
-		if _, err := s.db.ExecContext(ctx,
-			`INSERT INTO memories_fts(id, content, summary) VALUES (?, ?, ?)`,
-			rec.ID, rec.Content, rec.Summary,
-		); err != nil {
-			s.logger.Warn("fts insert failed; continuing", "error", err)
-		}
+// withTx runs fn in a write transaction, committing on success and rolling
+// back on error.
+func (s *SQLiteStore) withTx(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
 	}
-
-	return rec, nil
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
 }
 
-func (s *SQLiteStore) SearchCandidates(ctx context.Context, namespace, query, scope string, limit int, now time.Time) ([]Candidate, error) {
-	if limit <= 0 {
-		limit = 10
+func (s *SQLiteStore) SearchCandidates(ctx context.Context, q SearchQuery) ([]Candidate, error) {
+	if q.Limit <= 0 {
+		q.Limit = 10
+	}
+	q.Query = strings.TrimSpace(q.Query)
+	if q.Advanced {
+		return s.searchAdvanced(ctx, q)
 	}
-	query = strings.TrimSpace(query)
-	terms := tokenizeQueryTerms(query)
+	terms := stemTerms(ngramTerms(tokenizeQueryTerms(q.Query, q.StopWords), s.cjkNgram))
 
-	if len(terms) > 0 && s.ftsEnabled {
-		ftsQuery := buildFTSMatchQuery(terms)
-		rows, err := s.searchFTS(ctx, namespace, ftsQuery, scope, limit, now)
+	if len(terms) > 0 && s.ftsEnabled && !s.ftsOff {
+		rows, err := s.searchFTS(ctx, q, buildFTSMatchQuery(terms))
 		if err == nil && len(rows) > 0 {
 			return rows, nil
 		}
 		if err == nil && len(rows) == 0 {
 			// Fallback to LIKE for edge cases where FTS tokenization misses expected matches.
-			return s.searchLIKE(ctx, namespace, query, terms, scope, limit, now)
+			return s.searchLIKE(ctx, q, terms)
 		}
 		s.logger.Warn("fts query failed; fallback to LIKE", "error", err)
 	}
 
-	return s.searchLIKE(ctx, namespace, query, terms, scope, limit, now)
+	return s.searchLIKE(ctx, q, terms)
 }
 
-func (s *SQLiteStore) searchFTS(ctx context.Context, namespace, query, scope string, limit int, now time.Time) ([]Candidate, error) {
+func (s *SQLiteStore) searchFTS(ctx context.Context, q SearchQuery, match string) ([]Candidate, error) {
 	base := `
 SELECT m.id, m.namespace, m.scope, m.content, m.summary, m.importance, m.source_agent,
-       m.metadata_json, m.created_at, m.last_accessed_at, m.expires_at, m.promoted_at,
-       bm25(memories_fts) AS bm
+       m.metadata_json, m.created_at, m.last_accessed_at, m.expires_at, m.promoted_at, m.language, m.external_key, m.access_count,
+       bm25(memories_fts) AS bm, highlight(memories_fts, 0, char(2), char(3)) AS hl
 FROM memories_fts
-JOIN memories m ON m.id = memories_fts.id
+JOIN memories m ON m.rowid = memories_fts.rowid
 WHERE memories_fts MATCH ?
   AND m.namespace = ?
   AND (m.expires_at IS NULL OR m.expires_at > ?)
 `
-	args := []any{query, namespace, now.UTC().Format(time.RFC3339Nano)}
-	if scope != "" {
+	args := []any{match, q.Namespace, q.Now.UTC().Format(time.RFC3339Nano)}
+	if q.Scope != "" {
 		base += " AND m.scope = ?\n"
-		args = append(args, scope)
+		args = append(args, q.Scope)
+	}
+	if q.SourceAgent != "" {
+		base += " AND m.source_agent = ?\n"
+		args = append(args, q.SourceAgent)
+	}
+	for _, c := range q.Metadata {
+		cond, condArgs := metadataCondSQL(c, s.metaCols)
+		base += " AND " + cond + "\n"
+		args = append(args, condArgs...)
 	}
 	base += "ORDER BY bm ASC LIMIT ?"
-	args = append(args, limit)
+	args = append(args, q.Limit)
 
-	rows, err := s.db.QueryContext(ctx, base, args...)
+	rows, err := s.reader.QueryContext(ctx, base, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	items := make([]Candidate, 0, limit)
+	items := make([]Candidate, 0, q.Limit)
 	for rows.Next() {
-		rec, bm, err := scanCandidateRow(rows)
+		rec, bm, hl, err := scanCandidateRow(rows)
 		if err != nil {
 			return nil, err
 		}
 		lex := 1.0 / (1.0 + math.Abs(bm))
-		items = append(items, Candidate{Record: rec, LexicalScore: lex})
+		snippet, offsets := highlight(rec, highlightMatcher(hl))
+		items = append(items, Candidate{Record: rec, LexicalScore: lex, Snippet: snippet, MatchOffsets: offsets})
 	}
 	return items, rows.Err()
 }
 
-func (s *SQLiteStore) searchLIKE(ctx context.Context, namespace, query string, terms []string, scope string, limit int, now time.Time) ([]Candidate, error) {
-	base := `
-SELECT id, namespace, scope, content, summary, importance, source_agent,
-       metadata_json, created_at, last_accessed_at, expires_at, promoted_at
-FROM memories
-WHERE namespace = ?
-  AND (expires_at IS NULL OR expires_at > ?)
-`
-	args := []any{namespace, now.UTC().Format(time.RFC3339Nano)}
-	if scope != "" {
-		base += " AND scope = ?\n"
-		args = append(args, scope)
-	}
-	if len(terms) > 0 {
-		for _, term := range terms {
-			base += " AND (content LIKE ? OR summary LIKE ?)\n"
-			needle := "%" + term + "%"
-			args = append(args, needle, needle)
-		}
-	} else if query != "" {
-		// If query had no extractable tokens (e.g. only punctuation), keep best-effort behavior.
-		base += " AND (content LIKE ? OR summary LIKE ?)\n"
-		needle := "%" + query + "%"
-		args = append(args, needle, needle)
-	}
-	base += " ORDER BY created_at DESC LIMIT ?"
-	args = append(args, limit)
-
-	rows, err := s.db.QueryContext(ctx, base, args...)
+func (s *SQLiteStore) searchLIKE(ctx context.Context, q SearchQuery, terms []string) ([]Candidate, error) {
+	base, args := likeSearchQuery(q, terms, s.metaCols)
+	rows, err := s.reader.QueryContext(ctx, base, args...)
 	if err != nil {
 		return nil, fmt.Errorf("search like: %w", err)
 	}
 	defer rows.Close()
 
-	items := make([]Candidate, 0, limit)
+	items := make([]Candidate, 0, q.Limit)
 	for rows.Next() {
 		rec, err := scanMemoryRow(rows)
 		if err != nil {
 			return nil, err
 		}
 		lex := 0.4
-		if query == "" {
+		if q.Query == "" {
 			lex = 0.25
 		}
 		items = append(items, Candidate{Record: rec, LexicalScore: lex})
 	}
-	return items, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return withHighlights(items, termMatcher(terms)), nil
+}
+
+// likeSearchQuery builds the LIKE fallback query. Its filter and ORDER BY
+// match idx_memories_ns_scope_created / idx_memories_ns_created so SQLite
+// walks the index newest-first instead of sorting the namespace.
+func likeSearchQuery(q SearchQuery, terms []string, metaCols map[string]struct{}) (string, []any) {
+	base := `
+SELECT id, namespace, scope, content, summary, importance, source_agent,
+       metadata_json, created_at, last_accessed_at, expires_at, promoted_at, language, external_key, access_count
+FROM memories
+WHERE namespace = ?
+  AND (expires_at IS NULL OR expires_at > ?)
+`
+	args := []any{q.Namespace, q.Now.UTC().Format(time.RFC3339Nano)}
+	if q.Scope != "" {
+		base += " AND scope = ?\n"
+		args = append(args, q.Scope)
+	}
+	if q.SourceAgent != "" {
+		base += " AND source_agent = ?\n"
+		args = append(args, q.SourceAgent)
+	}
+	if len(terms) > 0 {
+		for _, term := range terms {
+			var conds []string
+			for _, form := range termForms(term) {
+				conds = append(conds, "search_text LIKE ? OR meta_text LIKE ?")
+				needle := "%" + strings.TrimSuffix(form, "*") + "%"
+				args = append(args, needle, needle)
+			}
+			base += " AND (" + strings.Join(conds, " OR ") + ")\n"
+		}
+	} else if q.Query != "" {
+		// If query had no extractable tokens (e.g. only punctuation), keep best-effort behavior.
+		base += " AND (search_text LIKE ? OR meta_text LIKE ?)\n"
+		needle := "%" + textnorm.Fold(q.Query) + "%"
+		args = append(args, needle, needle)
+	}
+	for _, c := range q.Metadata {
+		cond, condArgs := metadataCondSQL(c, metaCols)
+		base += " AND " + cond + "\n"
+		args = append(args, condArgs...)
+	}
+	base += " ORDER BY created_at DESC LIMIT ?"
+	args = append(args, q.Limit)
+	return base, args
 }
 
-func tokenizeQueryTerms(query string) []string {
-	query = strings.TrimSpace(query)
+// tokenizeQueryTerms splits a query into folded, de-duplicated words,
+// leaving out stop words unless the query has nothing else.
+func tokenizeQueryTerms(query string, stop lang.StopWords) []string {
+	query = textnorm.Fold(strings.TrimSpace(query))
 	if query == "" {
 		return nil
 	}
@@ -331,7 +689,11 @@ func tokenizeQueryTerms(query string) []string {
 		flush()
 	}
 	flush()
-	return terms
+	kept := slices.DeleteFunc(slices.Clone(terms), stop.Has)
+	if len(kept) == 0 {
+		return terms
+	}
+	return kept
 }
 
 func buildFTSMatchQuery(terms []string) string {
@@ -340,8 +702,22 @@ func buildFTSMatchQuery(terms []string) string {
 	}
 	parts := make([]string, 0, len(terms))
 	for _, term := range terms {
-		escaped := strings.ReplaceAll(term, `"`, `""`)
-		parts = append(parts, `"`+escaped+`"`)
+		forms := termForms(term)
+		alts := make([]string, 0, len(forms))
+		for _, form := range forms {
+			// A trailing "*" from ngramTerms makes a prefix query.
+			prefix, isPrefix := strings.CutSuffix(form, "*")
+			alt := `"` + strings.ReplaceAll(prefix, `"`, `""`) + `"`
+			if isPrefix {
+				alt += "*"
+			}
+			alts = append(alts, alt)
+		}
+		part := alts[0]
+		if len(alts) > 1 {
+			part = "(" + strings.Join(alts, " OR ") + ")"
+		}
+		parts = append(parts, part)
 	}
 	return strings.Join(parts, " AND ")
 }
@@ -351,6 +727,7 @@ func (s *SQLiteStore) Promote(ctx context.Context, id string, now time.Time) err
 SET scope = 'long', expires_at = NULL, promoted_at = ?, last_accessed_at = ?
 WHERE id = ?`
 	res, err := s.db.ExecContext(ctx, q, now.UTC().Format(time.RFC3339Nano), now.UTC().Format(time.RFC3339Nano), id)
+	s.uncacheRecords(id)
 	if err != nil {
 		return fmt.Errorf("promote memory: %w", err)
 	}
@@ -364,8 +741,106 @@ WHERE id = ?`
 	return nil
 }
 
+func (s *SQLiteStore) Demote(ctx context.Context, id string, expiresAt, now time.Time) error {
+	const q = `UPDATE memories
+SET scope = 'short', expires_at = ?, promoted_at = NULL, last_accessed_at = ?
+WHERE id = ?`
+	res, err := s.db.ExecContext(ctx, q, expiresAt.UTC().Format(time.RFC3339Nano), now.UTC().Format(time.RFC3339Nano), id)
+	s.uncacheRecords(id)
+	if err != nil {
+		return fmt.Errorf("demote memory: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("demote rows affected: %w", err)
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UpdateMemory loads id, applies fn and saves the result in one write
+// transaction, so concurrent updates of the same record cannot lose each
+// other's changes. ID, namespace and created_at cannot be changed. An error
+// from fn aborts the update; a missing id returns sql.ErrNoRows.
+func (s *SQLiteStore) UpdateMemory(ctx context.Context, id string, fn func(*types.MemoryRecord) error) (types.MemoryRecord, error) {
+	var rec types.MemoryRecord
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, `SELECT id, namespace, scope, content, summary, importance, source_agent,
+       metadata_json, created_at, last_accessed_at, expires_at, promoted_at, language, external_key, access_count
+FROM memories WHERE id = ?`, id)
+		cur, err := scanMemoryRow(row)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			return fmt.Errorf("load memory: %w", err)
+		}
+		rec = cur
+		if err := fn(&rec); err != nil {
+			return err
+		}
+		rec.ID, rec.Namespace, rec.CreatedAt = cur.ID, cur.Namespace, cur.CreatedAt
+		setLanguage(&rec)
+		return s.updateMemoryTx(ctx, tx, rec)
+	})
+	s.uncacheRecords(id)
+	if err != nil {
+		return types.MemoryRecord{}, err
+	}
+	return rec, nil
+}
+
+func (s *SQLiteStore) updateMemoryTx(ctx context.Context, tx *sql.Tx, rec types.MemoryRecord) error {
+	meta := rec.Metadata
+	if meta == nil {
+		meta = map[string]any{}
+	}
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("marshal metadata: %w", err)
+	}
+	expiresAt := sql.NullString{}
+	if rec.ExpiresAt != nil {
+		expiresAt = sql.NullString{String: rec.ExpiresAt.UTC().Format(time.RFC3339Nano), Valid: true}
+	}
+	promotedAt := sql.NullString{}
+	if rec.PromotedAt != nil {
+		promotedAt = sql.NullString{String: rec.PromotedAt.UTC().Format(time.RFC3339Nano), Valid: true}
+	}
+
+	const q = `UPDATE memories
+SET scope = ?, content = ?, summary = ?, importance = ?, source_agent = ?, metadata_json = ?,
+    last_accessed_at = ?, expires_at = ?, promoted_at = ?, meta_text = ?, search_text = ?, language = ?,
+    external_key = ?
+WHERE id = ?`
+	_, err = tx.ExecContext(ctx, q,
+		rec.Scope,
+		rec.Content,
+		rec.Summary,
+		rec.Importance,
+		rec.SourceAgent,
+		string(metaJSON),
+		rec.LastAccessedAt.UTC().Format(time.RFC3339Nano),
+		expiresAt,
+		promotedAt,
+		s.indexedMetaText(meta),
+		s.indexedText(rec.Language, rec.Content, rec.Summary),
+		rec.Language,
+		rec.ExternalKey,
+		rec.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update memory: %w", err)
+	}
+	return nil
+}
+
+// ExpireShort deletes the memories whose expiry has passed, except
+// long-term ones, which only long_term_retention removes.
 func (s *SQLiteStore) ExpireShort(ctx context.Context, now time.Time) (int64, error) {
-	const q = `DELETE FROM memories WHERE scope = 'short' AND expires_at IS NOT NULL AND expires_at <= ?`
+	const q = `DELETE FROM memories WHERE scope <> 'long' AND expires_at IS NOT NULL AND expires_at <= ?`
 	res, err := s.db.ExecContext(ctx, q, now.UTC().Format(time.RFC3339Nano))
 	if err != nil {
 		return 0, fmt.Errorf("expire short memories: %w", err)
@@ -374,47 +849,75 @@ func (s *SQLiteStore) ExpireShort(ctx context.Context, now time.Time) (int64, er
 	if err != nil {
 		return 0, fmt.Errorf("expire rows affected: %w", err)
 	}
-	if s.ftsEnabled && n > 0 {
-		_, _ = s.db.ExecContext(ctx, `DELETE FROM memories_fts WHERE id NOT IN (SELECT id FROM memories)`)
+	if n > 0 {
+		s.purgeRecords()
 	}
 	return n, nil
 }
 
-func (s *SQLiteStore) Stats(ctx context.Context, now time.Time) (Stats, error) {
-	var st Stats
-	if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM memories`).Scan(&st.Total); err != nil {
-		return st, err
+// DeleteMemories removes the given IDs; the FTS trigger drops their index
+// entries.
+func (s *SQLiteStore) DeleteMemories(ctx context.Context, ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
 	}
-	if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM memories WHERE scope = 'short'`).Scan(&st.Short); err != nil {
-		return st, err
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
 	}
-	if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM memories WHERE scope = 'long'`).Scan(&st.Long); err != nil {
-		return st, err
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM memories WHERE id IN (`+placeholders+`)`, args...)
+	s.uncacheRecords(ids...)
+	if err != nil {
+		return 0, fmt.Errorf("delete memories: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("delete rows affected: %w", err)
 	}
-	if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM memories WHERE expires_at IS NOT NULL AND expires_at <= ?`, now.UTC().Format(time.RFC3339Nano)).Scan(&st.Expired); err != nil {
+	return n, nil
+}
+
+func (s *SQLiteStore) Stats(ctx context.Context, now time.Time) (Stats, error) {
+	var st Stats
+	err := s.reader.QueryRowContext(ctx, `SELECT count(*),
+       coalesce(sum(scope = 'short'), 0),
+       coalesce(sum(scope = 'long'), 0),
+       coalesce(sum(scope = 'project'), 0),
+       coalesce(sum(scope = 'global'), 0),
+       coalesce(sum(expires_at IS NOT NULL AND expires_at <= ?), 0),
+       (SELECT count(*) FROM feedback WHERE signal > 0),
+       (SELECT count(*) FROM feedback WHERE signal < 0)
+FROM memories`, now.UTC().Format(time.RFC3339Nano)).Scan(&st.Total, &st.Short, &st.Long, &st.Project, &st.Global, &st.Expired, &st.Useful, &st.Irrelevant)
+	if err != nil {
 		return st, err
 	}
-	return st, nil
+	st.Agents, err = s.CountBy(ctx, GroupAgent, ListFilter{IncludeExpired: true, Now: now})
+	return st, err
 }
 
 // InsertMCPRequestLog stores one request event for admin observability.
 func (s *SQLiteStore) InsertMCPRequestLog(ctx context.Context, rec MCPRequestLog) error {
 	ts := rec.CreatedAt.UTC()
 	if ts.IsZero() {
-		ts = time.Now().UTC()
+		ts = s.clock.Now().UTC()
 	}
 	success := 0
 	if rec.Success {
 		success = 1
 	}
 	_, err := s.db.ExecContext(ctx, `INSERT INTO mcp_requests (
-		method, tool_name, success, error_text, duration_ms, created_at
-	) VALUES (?, ?, ?, ?, ?, ?)`,
+		method, tool_name, success, error_text, duration_ms, client_name, client_version, params, created_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		strings.TrimSpace(rec.Method),
 		strings.TrimSpace(rec.ToolName),
 		success,
 		strings.TrimSpace(rec.ErrorText),
 		rec.DurationMS,
+		strings.TrimSpace(rec.ClientName),
+		strings.TrimSpace(rec.ClientVersion),
+		rec.Params,
 		ts.Format(time.RFC3339Nano),
 	)
 	if err != nil {
@@ -423,12 +926,14 @@ func (s *SQLiteStore) InsertMCPRequestLog(ctx context.Context, rec MCPRequestLog
 	return nil
 }
 
+const mcpRequestColumns = `id, method, tool_name, success, error_text, duration_ms, client_name, client_version, params, created_at`
+
 // RecentMCPRequestLogs returns most recent request events in newest-first order.
 func (s *SQLiteStore) RecentMCPRequestLogs(ctx context.Context, limit int) ([]MCPRequestLog, error) {
 	if limit <= 0 {
 		limit = 20
 	}
-	rows, err := s.db.QueryContext(ctx, `SELECT id, method, tool_name, success, error_text, duration_ms, created_at
+	rows, err := s.reader.QueryContext(ctx, `SELECT `+mcpRequestColumns+`
 FROM mcp_requests
 ORDER BY created_at DESC
 LIMIT ?`, limit)
@@ -439,37 +944,134 @@ LIMIT ?`, limit)
 
 	items := make([]MCPRequestLog, 0, limit)
 	for rows.Next() {
-		var (
-			row            MCPRequestLog
-			successAsInt   int
-			createdAtValue string
-		)
-		if err := rows.Scan(
-			&row.ID,
-			&row.Method,
-			&row.ToolName,
-			&successAsInt,
-			&row.ErrorText,
-			&row.DurationMS,
-			&createdAtValue,
-		); err != nil {
-			return nil, fmt.Errorf("scan mcp request log: %w", err)
+		row, err := scanMCPRequestLog(rows)
+		if err != nil {
+			return nil, err
 		}
-		row.Success = successAsInt == 1
-		if ts, err := time.Parse(time.RFC3339Nano, createdAtValue); err == nil {
-			row.CreatedAt = ts
+		items = append(items, row)
+	}
+	return items, rows.Err()
+}
+
+// MCPRequestLogsAfter returns up to limit requests logged after the one
+// with ID afterID, oldest first, so a caller can tail the log by passing
+// the last ID it has seen.
+func (s *SQLiteStore) MCPRequestLogsAfter(ctx context.Context, afterID int64, limit int) ([]MCPRequestLog, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.reader.QueryContext(ctx, `SELECT `+mcpRequestColumns+`
+FROM mcp_requests
+WHERE id > ?
+ORDER BY id
+LIMIT ?`, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("tail mcp request logs: %w", err)
+	}
+	defer rows.Close()
+
+	var items []MCPRequestLog
+	for rows.Next() {
+		row, err := scanMCPRequestLog(rows)
+		if err != nil {
+			return nil, err
 		}
 		items = append(items, row)
 	}
 	return items, rows.Err()
 }
 
+// ClearMCPRequestParams blanks the params of the given requests.
+func (s *SQLiteStore) ClearMCPRequestParams(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	if _, err := s.db.ExecContext(ctx, `UPDATE mcp_requests SET params = '' WHERE id IN (`+placeholders+`)`, args...); err != nil {
+		return fmt.Errorf("clear mcp request params: %w", err)
+	}
+	return nil
+}
+
+// GetMCPRequestLog returns one logged request, or sql.ErrNoRows.
+func (s *SQLiteStore) GetMCPRequestLog(ctx context.Context, id int64) (MCPRequestLog, error) {
+	return scanMCPRequestLog(s.reader.QueryRowContext(ctx, `SELECT `+mcpRequestColumns+` FROM mcp_requests WHERE id = ?`, id))
+}
+
+func scanMCPRequestLog(row scanner) (MCPRequestLog, error) {
+	var (
+		rec            MCPRequestLog
+		successAsInt   int
+		createdAtValue string
+	)
+	if err := row.Scan(
+		&rec.ID,
+		&rec.Method,
+		&rec.ToolName,
+		&successAsInt,
+		&rec.ErrorText,
+		&rec.DurationMS,
+		&rec.ClientName,
+		&rec.ClientVersion,
+		&rec.Params,
+		&createdAtValue,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return MCPRequestLog{}, err
+		}
+		return MCPRequestLog{}, fmt.Errorf("scan mcp request log: %w", err)
+	}
+	rec.Success = successAsInt == 1
+	if ts, err := time.Parse(time.RFC3339Nano, createdAtValue); err == nil {
+		rec.CreatedAt = ts
+	}
+	return rec, nil
+}
+
+// RequestsByClient counts logged requests and failures per client name,
+// busiest first. Requests from sessions without clientInfo are grouped
+// under "".
+func (s *SQLiteStore) RequestsByClient(ctx context.Context, limit int) ([]ClientRequests, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	rows, err := s.reader.QueryContext(ctx, `SELECT client_name, count(*), coalesce(sum(success = 0), 0), max(created_at)
+FROM mcp_requests
+GROUP BY client_name
+ORDER BY count(*) DESC, client_name
+LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("count requests by client: %w", err)
+	}
+	defer rows.Close()
+
+	var out []ClientRequests
+	for rows.Next() {
+		var (
+			row      ClientRequests
+			lastSeen string
+		)
+		if err := rows.Scan(&row.Client, &row.Requests, &row.Errors, &lastSeen); err != nil {
+			return nil, fmt.Errorf("scan client requests: %w", err)
+		}
+		if ts, err := time.Parse(time.RFC3339Nano, lastSeen); err == nil {
+			row.LastSeen = ts
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
 // RecentMemories returns compact memory rows in newest-first order.
 func (s *SQLiteStore) RecentMemories(ctx context.Context, limit int) ([]RecentMemory, error) {
 	if limit <= 0 {
 		limit = 20
 	}
-	rows, err := s.db.QueryContext(ctx, `SELECT id, namespace, scope, summary, content, importance, created_at
+	rows, err := s.reader.QueryContext(ctx, `SELECT id, namespace, scope, summary, content, importance, created_at
 FROM memories
 ORDER BY created_at DESC
 LIMIT ?`, limit)
@@ -507,11 +1109,39 @@ LIMIT ?`, limit)
 	return items, rows.Err()
 }
 
+// ListByScope returns every memory in scope, oldest first.
+func (s *SQLiteStore) ListByScope(ctx context.Context, scope string) ([]types.MemoryRecord, error) {
+	rows, err := s.reader.QueryContext(ctx, `SELECT id, namespace, scope, content, summary, importance, source_agent,
+       metadata_json, created_at, last_accessed_at, expires_at, promoted_at, language, external_key, access_count
+FROM memories WHERE scope = ?
+ORDER BY created_at ASC, id ASC`, scope)
+	if err != nil {
+		return nil, fmt.Errorf("list memories by scope: %w", err)
+	}
+	defer rows.Close()
+
+	var items []types.MemoryRecord
+	for rows.Next() {
+		rec, err := scanMemoryRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan memory: %w", err)
+		}
+		items = append(items, rec)
+	}
+	return items, rows.Err()
+}
+
 func (s *SQLiteStore) GetMemory(ctx context.Context, id string) (types.MemoryRecord, error) {
 	const q = `SELECT id, namespace, scope, content, summary, importance, source_agent,
-       metadata_json, created_at, last_accessed_at, expires_at, promoted_at
+       metadata_json, created_at, last_accessed_at, expires_at, promoted_at, language, external_key, access_count
 FROM memories WHERE id = ? LIMIT 1`
-	row := s.db.QueryRowContext(ctx, q, id)
+	if s.records != nil {
+		if rec, ok := s.records.Get(id); ok {
+			return cloneRecord(rec), nil
+		}
+	}
+	gen := s.recordGeneration()
+	row := s.reader.QueryRowContext(ctx, q, id)
 	rec, err := scanMemoryRow(row)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -519,10 +1149,68 @@ FROM memories WHERE id = ? LIMIT 1`
 		}
 		return rec, fmt.Errorf("get memory: %w", err)
 	}
+	s.cacheRecord(rec, gen)
 	return rec, nil
 }
 
+// RecordCacheStats reports record cache hits, misses and size.
+func (s *SQLiteStore) RecordCacheStats() cache.Stats {
+	if s.records == nil {
+		return cache.Stats{}
+	}
+	return s.records.Stats()
+}
+
+// recordGeneration is read before a record is loaded and handed back to
+// cacheRecord.
+func (s *SQLiteStore) recordGeneration() uint64 {
+	s.recordMu.Lock()
+	defer s.recordMu.Unlock()
+	return s.recordGen
+}
+
+// cacheRecord caches rec unless entries were dropped since gen, in which
+// case rec may predate a committed change.
+func (s *SQLiteStore) cacheRecord(rec types.MemoryRecord, gen uint64) {
+	if s.records == nil {
+		return
+	}
+	s.recordMu.Lock()
+	defer s.recordMu.Unlock()
+	if s.recordGen == gen {
+		s.records.Put(rec.ID, cloneRecord(rec))
+	}
+}
+
+func (s *SQLiteStore) uncacheRecords(ids ...string) {
+	if s.records == nil {
+		return
+	}
+	s.recordMu.Lock()
+	defer s.recordMu.Unlock()
+	s.recordGen++
+	for _, id := range ids {
+		s.records.Delete(id)
+	}
+}
+
+func (s *SQLiteStore) purgeRecords() {
+	if s.records == nil {
+		return
+	}
+	s.recordMu.Lock()
+	defer s.recordMu.Unlock()
+	s.recordGen++
+	s.records.Purge()
+}
+
 func (s *SQLiteStore) Close() error {
+	if s.reader != s.db {
+		if err := s.reader.Close(); err != nil {
+			_ = s.db.Close()
+			return err
+		}
+	}
 	return s.db.Close()
 }
 
@@ -530,14 +1218,16 @@ type scanner interface {
 	Scan(dest ...any) error
 }
 
-func scanCandidateRow(sc scanner) (types.MemoryRecord, float64, error) {
+func scanCandidateRow(sc scanner) (types.MemoryRecord, float64, string, error) {
 	rec, err := scanBaseMemory(sc, true)
 	if err != nil {
-		return types.MemoryRecord{}, 0, err
+		return types.MemoryRecord{}, 0, "", err
 	}
 	bm := rec.Metadata["_bm25"].(float64)
+	hl := rec.Metadata["_highlight"].(string)
 	delete(rec.Metadata, "_bm25")
-	return rec, bm, nil
+	delete(rec.Metadata, "_highlight")
+	return rec, bm, hl, nil
 }
 
 func scanMemoryRow(sc scanner) (types.MemoryRecord, error) {
@@ -552,6 +1242,7 @@ func scanBaseMemory(sc scanner, withBM25 bool) (types.MemoryRecord, error) {
 	var expiresAt, promotedAt sql.NullString
 	if withBM25 {
 		var bm float64
+		var hl sql.NullString
 		err := sc.Scan(
 			&rec.ID,
 			&rec.Namespace,
@@ -565,12 +1256,16 @@ func scanBaseMemory(sc scanner, withBM25 bool) (types.MemoryRecord, error) {
 			&lastAccessedAt,
 			&expiresAt,
 			&promotedAt,
+			&rec.Language,
+			&rec.ExternalKey,
+			&rec.AccessCount,
 			&bm,
+			&hl,
 		)
 		if err != nil {
 			return rec, err
 		}
-		rec.Metadata = map[string]any{"_bm25": bm}
+		rec.Metadata = map[string]any{"_bm25": bm, "_highlight": hl.String}
 	} else {
 		err := sc.Scan(
 			&rec.ID,
@@ -585,6 +1280,9 @@ func scanBaseMemory(sc scanner, withBM25 bool) (types.MemoryRecord, error) {
 			&lastAccessedAt,
 			&expiresAt,
 			&promotedAt,
+			&rec.Language,
+			&rec.ExternalKey,
+			&rec.AccessCount,
 		)
 		if err != nil {
 			return rec, err
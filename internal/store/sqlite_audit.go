@@ -0,0 +1,144 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Audit actions.
+const (
+	AuditWrite   = "write"
+	AuditUpdate  = "update"
+	AuditPromote = "promote"
+	AuditDemote  = "demote"
+	AuditDelete  = "delete"
+	AuditExpire  = "expire"
+	// AuditPurge is a deletion or redaction by metadata, recorded without
+	// summaries.
+	AuditPurge = "purge"
+)
+
+// AuditEntry is one change to a memory. Before and After are the memory's
+// summary around the change; a write has no Before and a deletion no After.
+type AuditEntry struct {
+	Action    string    `json:"action"`
+	MemoryID  string    `json:"memory_id"`
+	Namespace string    `json:"namespace"`
+	Actor     string    `json:"actor"`
+	Tool      string    `json:"tool"`
+	Before    string    `json:"before"`
+	After     string    `json:"after"`
+	At        time.Time `json:"at"`
+}
+
+// AuditFilter narrows AuditLog. Empty fields match everything.
+type AuditFilter struct {
+	// NamespacePrefix matches the namespace and its descendants.
+	NamespacePrefix string
+	MemoryID        string
+	Action          string
+	Actor           string
+	// Limit <= 0 means 50.
+	Limit int
+}
+
+// AuditStore is implemented by stores that keep a mutation audit log.
+type AuditStore interface {
+	RecordAudit(ctx context.Context, entries ...AuditEntry) error
+	// AuditLog lists entries newest first.
+	AuditLog(ctx context.Context, f AuditFilter) ([]AuditEntry, error)
+	// RedactAudit blanks the summaries recorded for memoryIDs.
+	RedactAudit(ctx context.Context, memoryIDs []string) error
+}
+
+// RecordAudit appends entries to the audit_log table in one transaction.
+func (s *SQLiteStore) RecordAudit(ctx context.Context, entries ...AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, `INSERT INTO audit_log (action, memory_id, namespace, actor, tool, before_summary, after_summary, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			return fmt.Errorf("prepare audit insert: %w", err)
+		}
+		defer stmt.Close()
+		for _, e := range entries {
+			if _, err := stmt.ExecContext(ctx, e.Action, e.MemoryID, e.Namespace, e.Actor, e.Tool, e.Before, e.After, e.At.UTC().Format(time.RFC3339Nano)); err != nil {
+				return fmt.Errorf("record audit entry: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// AuditLog reads the audit_log table newest first.
+func (s *SQLiteStore) AuditLog(ctx context.Context, f AuditFilter) ([]AuditEntry, error) {
+	if f.Limit <= 0 {
+		f.Limit = 50
+	}
+	var (
+		where []string
+		args  []any
+	)
+	if ns := strings.Trim(f.NamespacePrefix, "/"); ns != "" {
+		where = append(where, `(namespace = ? OR namespace LIKE ? ESCAPE '\')`)
+		args = append(args, ns, escapeLike(ns)+"/%")
+	}
+	if f.MemoryID != "" {
+		where = append(where, "memory_id = ?")
+		args = append(args, f.MemoryID)
+	}
+	if f.Action != "" {
+		where = append(where, "action = ?")
+		args = append(args, f.Action)
+	}
+	if f.Actor != "" {
+		where = append(where, "actor = ?")
+		args = append(args, f.Actor)
+	}
+	q := `SELECT action, memory_id, namespace, actor, tool, before_summary, after_summary, created_at FROM audit_log`
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+	q += " ORDER BY id DESC LIMIT ?"
+	rows, err := s.reader.QueryContext(ctx, q, append(args, f.Limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("query audit log: %w", err)
+	}
+	defer rows.Close()
+	var out []AuditEntry
+	for rows.Next() {
+		var (
+			e  AuditEntry
+			at string
+		)
+		if err := rows.Scan(&e.Action, &e.MemoryID, &e.Namespace, &e.Actor, &e.Tool, &e.Before, &e.After, &at); err != nil {
+			return nil, fmt.Errorf("scan audit entry: %w", err)
+		}
+		e.At, _ = time.Parse(time.RFC3339Nano, at)
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// RedactAudit clears before_summary and after_summary of the memories'
+// entries, keeping who changed them and when.
+func (s *SQLiteStore) RedactAudit(ctx context.Context, memoryIDs []string) error {
+	if len(memoryIDs) == 0 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(memoryIDs)), ",")
+	args := make([]any, 0, len(memoryIDs))
+	for _, id := range memoryIDs {
+		args = append(args, id)
+	}
+	if _, err := s.db.ExecContext(ctx, `UPDATE audit_log SET before_summary = '', after_summary = ''
+WHERE memory_id IN (`+placeholders+`)`, args...); err != nil {
+		return fmt.Errorf("redact audit log: %w", err)
+	}
+	return nil
+}
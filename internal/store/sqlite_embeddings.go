@@ -0,0 +1,280 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+const (
+	embeddingsTriggerSQL = `CREATE TRIGGER IF NOT EXISTS memories_embeddings_ad AFTER DELETE ON memories BEGIN
+  DELETE FROM memory_embeddings WHERE memory_id = old.id;
+END`
+	// Vectors of rewritten content, e.g. after a redaction, are dropped so
+	// they are re-embedded instead of describing text that is gone.
+	embeddingsUpdateTriggerSQL = `CREATE TRIGGER IF NOT EXISTS memories_embeddings_au AFTER UPDATE OF content ON memories
+WHEN new.content != old.content BEGIN
+  DELETE FROM memory_embeddings WHERE memory_id = old.id;
+END`
+	embeddingModelSetting = "embedding_model"
+)
+
+// ErrEmbeddingMismatch is returned when a vector's model or dimension does
+// not match the registry, so incompatible vectors are never compared.
+var ErrEmbeddingMismatch = errors.New("embedding model mismatch")
+
+// EmbeddingModel is a registered embedding model and its vector dimension.
+type EmbeddingModel struct {
+	Name string `json:"name"`
+	Dim  int    `json:"dim"`
+}
+
+// VectorQuery ranks a namespace's memories by cosine similarity to Vector,
+// which must come from the active model. Expired memories are skipped.
+type VectorQuery struct {
+	Namespace string
+	Scope     string
+	Model     string
+	Vector    []float32
+	Now       time.Time
+	// SourceAgent, when set, keeps only memories written by that agent.
+	SourceAgent string
+	// Limit <= 0 means 10.
+	Limit int
+}
+
+// VectorMatch is a memory and its cosine similarity to the query vector.
+type VectorMatch struct {
+	MemoryID   string
+	Similarity float64
+}
+
+// EmbeddingStore is implemented by stores that keep embedding vectors.
+type EmbeddingStore interface {
+	// ActiveEmbeddingModel reports the model searches compare against;
+	// ok is false until a model has been activated.
+	ActiveEmbeddingModel(ctx context.Context) (m EmbeddingModel, ok bool, err error)
+	// PutEmbedding stores memoryID's vector for model, registering the
+	// model's dimension on first use. The first model stored while none is
+	// active becomes active.
+	PutEmbedding(ctx context.Context, memoryID, model string, vec []float32, now time.Time) error
+	// MemoriesMissingEmbedding returns up to limit memories without a
+	// vector for model, oldest first.
+	MemoriesMissingEmbedding(ctx context.Context, model string, limit int) ([]types.MemoryRecord, error)
+	// ActivateEmbeddingModel makes model active and drops vectors of every
+	// other model.
+	ActivateEmbeddingModel(ctx context.Context, model string) error
+	NearestEmbeddings(ctx context.Context, q VectorQuery) ([]VectorMatch, error)
+}
+
+// ActiveEmbeddingModel reports the active model and its dimension.
+func (s *SQLiteStore) ActiveEmbeddingModel(ctx context.Context) (EmbeddingModel, bool, error) {
+	var m EmbeddingModel
+	err := s.reader.QueryRowContext(ctx, `SELECT m.model, m.dim FROM store_settings s
+JOIN embedding_models m ON m.model = s.value
+WHERE s.key = ?`, embeddingModelSetting).Scan(&m.Name, &m.Dim)
+	if errors.Is(err, sql.ErrNoRows) {
+		return EmbeddingModel{}, false, nil
+	}
+	if err != nil {
+		return EmbeddingModel{}, false, fmt.Errorf("read active embedding model: %w", err)
+	}
+	return m, true, nil
+}
+
+// PutEmbedding stores a vector, rejecting one whose dimension differs from
+// the model's registered dimension.
+func (s *SQLiteStore) PutEmbedding(ctx context.Context, memoryID, model string, vec []float32, now time.Time) error {
+	if model == "" || len(vec) == 0 {
+		return errors.New("embedding needs a model and a vector")
+	}
+	ts := now.UTC().Format(time.RFC3339Nano)
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		var dim int
+		err := tx.QueryRowContext(ctx, `SELECT dim FROM embedding_models WHERE model = ?`, model).Scan(&dim)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			if _, err := tx.ExecContext(ctx, `INSERT INTO embedding_models (model, dim, created_at) VALUES (?, ?, ?)`, model, len(vec), ts); err != nil {
+				return fmt.Errorf("register embedding model: %w", err)
+			}
+			if _, err := tx.ExecContext(ctx, `INSERT INTO store_settings (key, value) VALUES (?, ?)
+ON CONFLICT(key) DO NOTHING`, embeddingModelSetting, model); err != nil {
+				return fmt.Errorf("activate embedding model: %w", err)
+			}
+		case err != nil:
+			return fmt.Errorf("read embedding model: %w", err)
+		case dim != len(vec):
+			return fmt.Errorf("%w: %s vectors have %d dimensions, got %d", ErrEmbeddingMismatch, model, dim, len(vec))
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO memory_embeddings (memory_id, model, dim, vector, created_at)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT(memory_id, model) DO UPDATE SET dim = excluded.dim, vector = excluded.vector, created_at = excluded.created_at`,
+			memoryID, model, len(vec), encodeVector(vec), ts)
+		if err != nil {
+			return fmt.Errorf("store embedding: %w", err)
+		}
+		return nil
+	})
+}
+
+// MemoriesMissingEmbedding lists memories that still need a vector for model.
+func (s *SQLiteStore) MemoriesMissingEmbedding(ctx context.Context, model string, limit int) ([]types.MemoryRecord, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	rows, err := s.reader.QueryContext(ctx, `SELECT id, namespace, scope, content, summary, importance, source_agent,
+       metadata_json, created_at, last_accessed_at, expires_at, promoted_at, language, external_key, access_count
+FROM memories m
+WHERE NOT EXISTS (SELECT 1 FROM memory_embeddings e WHERE e.memory_id = m.id AND e.model = ?)
+ORDER BY created_at ASC, id ASC
+LIMIT ?`, model, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list memories missing embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var items []types.MemoryRecord
+	for rows.Next() {
+		rec, err := scanMemoryRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan memory: %w", err)
+		}
+		items = append(items, rec)
+	}
+	return items, rows.Err()
+}
+
+// ActivateEmbeddingModel switches searches to model, which must already
+// have vectors stored, and deletes the vectors of other models.
+func (s *SQLiteStore) ActivateEmbeddingModel(ctx context.Context, model string) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		var dim int
+		if err := tx.QueryRowContext(ctx, `SELECT dim FROM embedding_models WHERE model = ?`, model).Scan(&dim); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("embedding model %q has no vectors", model)
+			}
+			return fmt.Errorf("read embedding model: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO store_settings (key, value) VALUES (?, ?)
+ON CONFLICT(key) DO UPDATE SET value = excluded.value`, embeddingModelSetting, model); err != nil {
+			return fmt.Errorf("activate embedding model: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM memory_embeddings WHERE model != ?`, model); err != nil {
+			return fmt.Errorf("drop old embeddings: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM embedding_models WHERE model != ?`, model); err != nil {
+			return fmt.Errorf("drop old embedding models: %w", err)
+		}
+		return nil
+	})
+}
+
+// hasEmbeddingModel reports whether model has been registered.
+func (s *SQLiteStore) hasEmbeddingModel(ctx context.Context, model string) (bool, error) {
+	var n int
+	if err := s.reader.QueryRowContext(ctx, `SELECT COUNT(*) FROM embedding_models WHERE model = ?`, model).Scan(&n); err != nil {
+		return false, fmt.Errorf("read embedding model: %w", err)
+	}
+	return n > 0, nil
+}
+
+// NearestEmbeddings scores every vector of the active model in the
+// namespace. It fails with ErrEmbeddingMismatch when the query vector is
+// from another model or has another dimension.
+func (s *SQLiteStore) NearestEmbeddings(ctx context.Context, q VectorQuery) ([]VectorMatch, error) {
+	active, ok, err := s.ActiveEmbeddingModel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
+	if q.Model != active.Name || len(q.Vector) != active.Dim {
+		return nil, fmt.Errorf("%w: store uses %s (%d dimensions), query is %s (%d dimensions)",
+			ErrEmbeddingMismatch, active.Name, active.Dim, q.Model, len(q.Vector))
+	}
+	if q.Limit <= 0 {
+		q.Limit = 10
+	}
+
+	query := `SELECT e.memory_id, e.vector
+FROM memory_embeddings e
+JOIN memories m ON m.id = e.memory_id
+WHERE e.model = ? AND e.dim = ? AND m.namespace = ?
+  AND (m.expires_at IS NULL OR m.expires_at > ?)`
+	args := []any{active.Name, active.Dim, q.Namespace, listNow(ListFilter{Now: q.Now}).UTC().Format(time.RFC3339Nano)}
+	if q.Scope != "" {
+		query += ` AND m.scope = ?`
+		args = append(args, q.Scope)
+	}
+	if q.SourceAgent != "" {
+		query += ` AND m.source_agent = ?`
+		args = append(args, q.SourceAgent)
+	}
+	rows, err := s.reader.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var out []VectorMatch
+	for rows.Next() {
+		var (
+			id  string
+			raw []byte
+		)
+		if err := rows.Scan(&id, &raw); err != nil {
+			return nil, fmt.Errorf("scan embedding: %w", err)
+		}
+		out = append(out, VectorMatch{MemoryID: id, Similarity: Cosine(q.Vector, decodeVector(raw))})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Similarity > out[j].Similarity })
+	if len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out, nil
+}
+
+// Cosine returns the cosine similarity of a and b, or 0 when their lengths
+// differ or either is all zeros.
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+func encodeVector(vec []float32) []byte {
+	buf := make([]byte, 4*len(vec))
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	vec := make([]float32, len(buf)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return vec
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const factsTriggerSQL = `CREATE TRIGGER IF NOT EXISTS memories_facts_ad AFTER DELETE ON memories BEGIN
+  DELETE FROM facts WHERE memory_id = old.id;
+END`
+
+// Fact is a subject–predicate–object statement extracted from a memory.
+type Fact struct {
+	ID        int64     `json:"id"`
+	MemoryID  string    `json:"memory_id"`
+	Namespace string    `json:"namespace"`
+	Subject   string    `json:"subject"`
+	Predicate string    `json:"predicate"`
+	Object    string    `json:"object"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FactQuery selects facts. Subject and Predicate match exactly after
+// normalization by the caller; Object matches case-insensitively as a
+// substring. Facts of expired memories are excluded.
+type FactQuery struct {
+	Namespace string
+	Subject   string
+	Predicate string
+	Object    string
+	Now       time.Time
+	// Limit <= 0 means 50.
+	Limit int
+}
+
+// FactStore is implemented by stores that keep extracted facts.
+type FactStore interface {
+	ReplaceFacts(ctx context.Context, memoryID, namespace string, facts []Fact, now time.Time) error
+	QueryFacts(ctx context.Context, q FactQuery) ([]Fact, error)
+}
+
+// ReplaceFacts swaps the facts recorded for memoryID for facts.
+func (s *SQLiteStore) ReplaceFacts(ctx context.Context, memoryID, namespace string, facts []Fact, now time.Time) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM facts WHERE memory_id = ?`, memoryID); err != nil {
+			return fmt.Errorf("clear facts: %w", err)
+		}
+		for _, f := range facts {
+			_, err := tx.ExecContext(ctx, `INSERT INTO facts (memory_id, namespace, subject, predicate, object, created_at)
+VALUES (?, ?, ?, ?, ?, ?)`, memoryID, namespace, f.Subject, f.Predicate, f.Object, now.UTC().Format(time.RFC3339Nano))
+			if err != nil {
+				return fmt.Errorf("insert fact: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// QueryFacts returns matching facts, newest first.
+func (s *SQLiteStore) QueryFacts(ctx context.Context, q FactQuery) ([]Fact, error) {
+	if q.Limit <= 0 {
+		q.Limit = 50
+	}
+	where := []string{"f.namespace = ?", "(m.expires_at IS NULL OR m.expires_at > ?)"}
+	args := []any{q.Namespace, listNow(ListFilter{Now: q.Now}).UTC().Format(time.RFC3339Nano)}
+	if q.Subject != "" {
+		where = append(where, "f.subject = ?")
+		args = append(args, q.Subject)
+	}
+	if q.Predicate != "" {
+		where = append(where, "f.predicate = ?")
+		args = append(args, q.Predicate)
+	}
+	if q.Object != "" {
+		where = append(where, `lower(f.object) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(strings.ToLower(q.Object))+"%")
+	}
+	args = append(args, q.Limit)
+
+	rows, err := s.reader.QueryContext(ctx, `SELECT f.id, f.memory_id, f.namespace, f.subject, f.predicate, f.object, f.created_at
+FROM facts f
+JOIN memories m ON m.id = f.memory_id
+WHERE `+strings.Join(where, "\n  AND ")+`
+ORDER BY f.created_at DESC, f.id DESC
+LIMIT ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query facts: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Fact
+	for rows.Next() {
+		var (
+			f         Fact
+			createdAt string
+		)
+		if err := rows.Scan(&f.ID, &f.MemoryID, &f.Namespace, &f.Subject, &f.Predicate, &f.Object, &createdAt); err != nil {
+			return nil, fmt.Errorf("scan fact: %w", err)
+		}
+		f.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("parse fact created_at: %w", err)
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
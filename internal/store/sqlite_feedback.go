@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const feedbackTriggerSQL = `CREATE TRIGGER IF NOT EXISTS memories_feedback_ad AFTER DELETE ON memories BEGIN
+  DELETE FROM feedback WHERE memory_id = old.id;
+END`
+
+// Feedback is one report that a memory was useful (Signal 1) or irrelevant
+// (Signal -1) for a query.
+type Feedback struct {
+	MemoryID    string
+	Namespace   string
+	Query       string
+	Signal      int
+	SourceAgent string
+	CreatedAt   time.Time
+}
+
+// FeedbackStore is implemented by stores that keep relevance feedback.
+type FeedbackStore interface {
+	RecordFeedback(ctx context.Context, f Feedback) error
+	// FeedbackScores returns a learned score in (-1, 1) for each of ids
+	// that has feedback. Reports for the same query count double.
+	FeedbackScores(ctx context.Context, query string, ids []string) (map[string]float64, error)
+	// AgentTagAffinity returns, per metadata tag, agent's learned
+	// preference in (-1, 1) from its ratings of memories carrying the tag.
+	AgentTagAffinity(ctx context.Context, agent string) (map[string]float64, error)
+}
+
+// RecordFeedback stores one relevance report.
+func (s *SQLiteStore) RecordFeedback(ctx context.Context, f Feedback) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO feedback (memory_id, namespace, query, signal, source_agent, created_at)
+VALUES (?, ?, ?, ?, ?, ?)`, f.MemoryID, f.Namespace, f.Query, f.Signal, f.SourceAgent, f.CreatedAt.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("insert feedback: %w", err)
+	}
+	return nil
+}
+
+// FeedbackScores computes net / (weight + 2) per memory, where each report
+// adds its signal to net and 1 to weight, doubled when its query matches.
+// The +2 keeps a single report from dominating the ranking.
+func (s *SQLiteStore) FeedbackScores(ctx context.Context, query string, ids []string) (map[string]float64, error) {
+	out := make(map[string]float64, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+	args := []any{query, query}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	rows, err := s.reader.QueryContext(ctx, `SELECT memory_id,
+       sum(signal * (CASE WHEN query = ? THEN 2 ELSE 1 END)),
+       sum(CASE WHEN query = ? THEN 2 ELSE 1 END)
+FROM feedback
+WHERE memory_id IN (`+placeholders+`)
+GROUP BY memory_id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query feedback: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			id          string
+			net, weight float64
+		)
+		if err := rows.Scan(&id, &net, &weight); err != nil {
+			return nil, fmt.Errorf("scan feedback: %w", err)
+		}
+		out[id] = net / (weight + 2)
+	}
+	return out, rows.Err()
+}
+
+// AgentTagAffinity computes net / (count + 2) over agent's ratings of
+// memories carrying each tag. Agents match case-insensitively.
+func (s *SQLiteStore) AgentTagAffinity(ctx context.Context, agent string) (map[string]float64, error) {
+	rows, err := s.reader.QueryContext(ctx, `SELECT t.value, sum(f.signal), count(*)
+FROM feedback f
+JOIN memories m ON m.id = f.memory_id, json_each(m.metadata_json, '$.tags') t
+WHERE lower(f.source_agent) = lower(?) AND t.type = 'text'
+GROUP BY t.value`, agent)
+	if err != nil {
+		return nil, fmt.Errorf("query agent affinity: %w", err)
+	}
+	defer rows.Close()
+	out := map[string]float64{}
+	for rows.Next() {
+		var (
+			tag        string
+			net, count float64
+		)
+		if err := rows.Scan(&tag, &net, &count); err != nil {
+			return nil, fmt.Errorf("scan agent affinity: %w", err)
+		}
+		out[tag] = net / (count + 2)
+	}
+	return out, rows.Err()
+}
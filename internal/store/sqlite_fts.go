@@ -0,0 +1,406 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xiy/memory-mcp/internal/textnorm"
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+// ErrAdvancedQueryUnsupported is returned for SearchQuery.Advanced by
+// stores without an FTS5 index.
+var ErrAdvancedQueryUnsupported = errors.New("advanced_query requires the sqlite store with FTS5")
+
+// ErrQuerySyntax is returned for an advanced query FTS5 cannot parse.
+var ErrQuerySyntax = errors.New("invalid advanced query")
+
+// searchAdvanced runs q.Query as an FTS5 match expression. Malformed
+// expressions fail with ErrQuerySyntax instead of falling back to LIKE.
+func (s *SQLiteStore) searchAdvanced(ctx context.Context, q SearchQuery) ([]Candidate, error) {
+	if !s.ftsEnabled || s.ftsOff {
+		return nil, ErrAdvancedQueryUnsupported
+	}
+	if q.Query == "" {
+		return nil, fmt.Errorf("%w: query is empty", ErrQuerySyntax)
+	}
+	rows, err := s.searchFTS(ctx, q, q.Query)
+	if err != nil {
+		if msg, ok := ftsSyntaxError(err); ok {
+			return nil, fmt.Errorf("%w: %s", ErrQuerySyntax, msg)
+		}
+		return nil, fmt.Errorf("advanced search: %w", err)
+	}
+	return rows, nil
+}
+
+// ftsSyntaxErrors start the messages SQLite gives for match expressions
+// it cannot parse.
+var ftsSyntaxErrors = []string{"fts5:", "unterminated string", "no such column"}
+
+// ftsSyntaxError returns the part of err that explains why FTS5 could not
+// parse a match expression, e.g. `fts5: syntax error near "AND"`.
+func ftsSyntaxError(err error) (string, bool) {
+	msg := err.Error()
+	for _, prefix := range ftsSyntaxErrors {
+		if i := strings.Index(msg, prefix); i >= 0 {
+			return msg[i:], true
+		}
+	}
+	return "", false
+}
+
+// memories_fts is an external-content FTS5 index over memories: it stores
+// only the inverted index and reads search_text and meta_text back from
+// memories by rowid. Both columns hold text already folded by textnorm.Fold,
+// so the tokenizer sees one spelling of every word, and search_text of
+// English memories holds stems. Triggers keep it in step
+// with every insert, update and delete.
+//
+// memories has a TEXT primary key, so its rowid is implicit and VACUUM may
+// renumber it. Opening the store repairs rowids the index lacks or holds in
+// excess; run `admin fts check` to compare the whole index and `admin fts
+// rebuild` to regenerate it.
+const ftsTableSQL = `CREATE VIRTUAL TABLE memories_fts USING fts5(
+  search_text,
+  meta_text,
+  content='memories'
+)`
+
+var ftsTriggerSQL = []string{
+	`CREATE TRIGGER IF NOT EXISTS memories_fts_ai AFTER INSERT ON memories BEGIN
+  INSERT INTO memories_fts(rowid, search_text, meta_text) VALUES (new.rowid, new.search_text, new.meta_text);
+END`,
+	`CREATE TRIGGER IF NOT EXISTS memories_fts_ad AFTER DELETE ON memories BEGIN
+  INSERT INTO memories_fts(memories_fts, rowid, search_text, meta_text) VALUES ('delete', old.rowid, old.search_text, old.meta_text);
+END`,
+	`CREATE TRIGGER IF NOT EXISTS memories_fts_au AFTER UPDATE OF search_text, meta_text ON memories BEGIN
+  INSERT INTO memories_fts(memories_fts, rowid, search_text, meta_text) VALUES ('delete', old.rowid, old.search_text, old.meta_text);
+  INSERT INTO memories_fts(rowid, search_text, meta_text) VALUES (new.rowid, new.search_text, new.meta_text);
+END`,
+}
+
+var ftsTriggerNames = []string{"memories_fts_ai", "memories_fts_ad", "memories_fts_au"}
+
+// ftsMetaKeysSetting records which metadata keys meta_text was derived from.
+const ftsMetaKeysSetting = "fts_metadata_keys"
+
+// ensureFTS creates the FTS index and its triggers, migrating databases
+// that still carry an older table: the standalone one that duplicated
+// content and summary alongside an id column, or an external-content one
+// over the raw content and summary columns. rebuild forces a full reindex
+// of an up-to-date table. It reports whether FTS5 is available.
+func (s *SQLiteStore) ensureFTS(ctx context.Context, rebuild bool) (bool, error) {
+	var ddl string
+	err := s.db.QueryRowContext(ctx, `SELECT sql FROM sqlite_master WHERE type='table' AND name='memories_fts'`).Scan(&ddl)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		ddl = ""
+	case err != nil:
+		return false, fmt.Errorf("inspect fts table: %w", err)
+	}
+
+	if ddl != "" && strings.Contains(ddl, "content='memories'") && strings.Contains(ddl, "search_text") {
+		if err := s.createFTSTriggers(ctx); err != nil {
+			return true, err
+		}
+		if rebuild {
+			s.ftsEnabled = true
+			return true, s.RebuildFTS(ctx)
+		}
+		return true, s.repairFTSDrift(ctx)
+	}
+
+	legacy := ddl != ""
+	err = s.withTx(ctx, func(tx *sql.Tx) error {
+		if legacy {
+			for _, name := range ftsTriggerNames {
+				if _, err := tx.ExecContext(ctx, `DROP TRIGGER IF EXISTS `+name); err != nil {
+					return fmt.Errorf("drop fts trigger: %w", err)
+				}
+			}
+			if _, err := tx.ExecContext(ctx, `DROP TABLE memories_fts`); err != nil {
+				return fmt.Errorf("drop legacy fts table: %w", err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, ftsTableSQL); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO memories_fts(memories_fts) VALUES ('rebuild')`); err != nil {
+			return fmt.Errorf("build fts index: %w", err)
+		}
+		return s.recordReindex(ctx, tx)
+	})
+	if err != nil {
+		if !legacy {
+			// FTS5 is not compiled in; searches fall back to LIKE.
+			s.logger.Warn("FTS5 disabled; falling back to LIKE queries", "error", err)
+			return false, nil
+		}
+		return false, fmt.Errorf("migrate fts table: %w", err)
+	}
+	if legacy {
+		s.logger.Info("migrated memories_fts to an external-content index")
+	}
+	return true, s.createFTSTriggers(ctx)
+}
+
+func (s *SQLiteStore) createFTSTriggers(ctx context.Context) error {
+	for _, stmt := range ftsTriggerSQL {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("create fts trigger: %w", err)
+		}
+	}
+	for _, stmt := range ftsVocabSQL {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("create fts vocabulary table: %w", err)
+		}
+	}
+	return nil
+}
+
+// repairFTSDrift rebuilds the index when a row of memories has no indexed
+// document or an indexed document has no row, e.g. after rows were changed
+// with triggers missing or rowids were renumbered by VACUUM. Comparing
+// rowids, not counts, catches an insert and a delete that both went
+// unindexed. It does not read the indexed text; CheckFTS does.
+func (s *SQLiteStore) repairFTSDrift(ctx context.Context) error {
+	var unindexed, orphaned int64
+	if err := s.db.QueryRowContext(ctx, `SELECT
+  (SELECT count(*) FROM memories WHERE rowid NOT IN (SELECT id FROM memories_fts_docsize)),
+  (SELECT count(*) FROM memories_fts_docsize WHERE id NOT IN (SELECT rowid FROM memories))`).Scan(&unindexed, &orphaned); err != nil {
+		return fmt.Errorf("compare fts documents: %w", err)
+	}
+	if unindexed == 0 && orphaned == 0 {
+		return nil
+	}
+	s.logger.Warn("FTS index out of sync; rebuilding", "unindexed", unindexed, "orphaned", orphaned)
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO memories_fts(memories_fts) VALUES ('rebuild')`); err != nil {
+		return fmt.Errorf("rebuild fts index: %w", err)
+	}
+	return s.recordReindex(ctx, s.db)
+}
+
+// syncMetaText re-derives meta_text for every row when the configured
+// metadata keys differ from those it was last derived from. The update
+// trigger reindexes each changed row.
+func (s *SQLiteStore) syncMetaText(ctx context.Context) error {
+	want := strings.Join(s.ftsMetaKeys, ",")
+	var have string
+	err := s.db.QueryRowContext(ctx, `SELECT value FROM store_settings WHERE key = ?`, ftsMetaKeysSetting).Scan(&have)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// Rows written before meta_text existed have it empty, which matches
+		// an empty key list.
+	case err != nil:
+		return fmt.Errorf("read fts metadata keys: %w", err)
+	}
+	if have == want {
+		return nil
+	}
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `SELECT rowid, metadata_json, meta_text FROM memories`)
+		if err != nil {
+			return fmt.Errorf("scan metadata: %w", err)
+		}
+		type change struct {
+			rowid int64
+			text  string
+		}
+		var changes []change
+		for rows.Next() {
+			var (
+				rowid          int64
+				metaJSON, text string
+			)
+			if err := rows.Scan(&rowid, &metaJSON, &text); err != nil {
+				_ = rows.Close()
+				return fmt.Errorf("scan metadata: %w", err)
+			}
+			var meta map[string]any
+			if err := json.Unmarshal([]byte(metaJSON), &meta); err != nil {
+				_ = rows.Close()
+				return fmt.Errorf("decode metadata: %w", err)
+			}
+			if next := s.indexedMetaText(meta); next != text {
+				changes = append(changes, change{rowid, next})
+			}
+		}
+		_ = rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("scan metadata: %w", err)
+		}
+		for _, c := range changes {
+			if _, err := tx.ExecContext(ctx, `UPDATE memories SET meta_text = ? WHERE rowid = ?`, c.text, c.rowid); err != nil {
+				return fmt.Errorf("update meta_text: %w", err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO store_settings (key, value) VALUES (?, ?)
+ON CONFLICT(key) DO UPDATE SET value = excluded.value`, ftsMetaKeysSetting, want); err != nil {
+			return fmt.Errorf("save fts metadata keys: %w", err)
+		}
+		s.logger.Info("re-derived indexed metadata", "keys", want, "rows", len(changes))
+		return s.recordReindex(ctx, tx)
+	})
+}
+
+// metadataText flattens the values of keys in meta into space-separated
+// text folded by textnorm.Fold. Lists contribute each element; nested
+// objects are skipped.
+func metadataText(meta map[string]any, keys []string) string {
+	var parts []string
+	var add func(v any)
+	add = func(v any) {
+		switch v := v.(type) {
+		case nil, map[string]any:
+		case string:
+			if v = strings.TrimSpace(v); v != "" {
+				parts = append(parts, v)
+			}
+		case []any:
+			for _, e := range v {
+				add(e)
+			}
+		case []string:
+			for _, e := range v {
+				add(e)
+			}
+		default:
+			parts = append(parts, fmt.Sprint(v))
+		}
+	}
+	for _, key := range keys {
+		add(meta[key])
+	}
+	return textnorm.Fold(strings.Join(parts, " "))
+}
+
+// searchText is the folded form of a memory's content and summary that
+// searches match against.
+func searchText(content, summary string) string {
+	return textnorm.Fold(content + "\n" + summary)
+}
+
+// indexedText is the search_text column for a memory.
+func (s *SQLiteStore) indexedText(language, content, summary string) string {
+	return analyzedText(language, content, summary, s.cjkNgram)
+}
+
+// indexedMetaText is the meta_text column for a memory's metadata.
+func (s *SQLiteStore) indexedMetaText(meta map[string]any) string {
+	return ngramText(metadataText(meta, s.ftsMetaKeys), s.cjkNgram)
+}
+
+// ftsCJKNgramSetting records the n-gram size search_text was derived with.
+const ftsCJKNgramSetting = "fts_cjk_ngram"
+
+// ftsAnalyzerSetting records the analyzer version search_text was derived
+// with. Bump ftsAnalyzerVersion when analyzedText changes its output.
+const (
+	ftsAnalyzerSetting = "fts_analyzer"
+	ftsAnalyzerVersion = "1"
+)
+
+// backfillSearchText derives search_text and language, and re-derives
+// meta_text, for rows written before search_text existed or inserted
+// without it, or for every row when the CJK n-gram size or the analyzer
+// changed. It reports how many rows changed; the FTS index must then be
+// rebuilt.
+func (s *SQLiteStore) backfillSearchText(ctx context.Context) (int, error) {
+	settings := [][2]string{
+		{ftsCJKNgramSetting, strconv.Itoa(s.cjkNgram)},
+		{ftsAnalyzerSetting, ftsAnalyzerVersion},
+	}
+	query := `SELECT rowid, content, summary, metadata_json FROM memories WHERE search_text = ''`
+	for _, kv := range settings {
+		var have string
+		err := s.db.QueryRowContext(ctx, `SELECT value FROM store_settings WHERE key = ?`, kv[0]).Scan(&have)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("read %s: %w", kv[0], err)
+		}
+		if have != kv[1] {
+			query = `SELECT rowid, content, summary, metadata_json FROM memories`
+		}
+	}
+
+	var changed int
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query)
+		if err != nil {
+			return fmt.Errorf("scan unindexed memories: %w", err)
+		}
+		type change struct {
+			rowid                int64
+			text, meta, language string
+		}
+		var changes []change
+		for rows.Next() {
+			var (
+				rowid                      int64
+				content, summary, metaJSON string
+			)
+			if err := rows.Scan(&rowid, &content, &summary, &metaJSON); err != nil {
+				_ = rows.Close()
+				return fmt.Errorf("scan unindexed memories: %w", err)
+			}
+			var meta map[string]any
+			if err := json.Unmarshal([]byte(metaJSON), &meta); err != nil {
+				_ = rows.Close()
+				return fmt.Errorf("decode metadata: %w", err)
+			}
+			rec := types.MemoryRecord{Content: content, Summary: summary}
+			setLanguage(&rec)
+			changes = append(changes, change{rowid, s.indexedText(rec.Language, content, summary), s.indexedMetaText(meta), rec.Language})
+		}
+		_ = rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("scan unindexed memories: %w", err)
+		}
+		for _, c := range changes {
+			if _, err := tx.ExecContext(ctx, `UPDATE memories SET search_text = ?, meta_text = ?, language = ? WHERE rowid = ?`, c.text, c.meta, c.language, c.rowid); err != nil {
+				return fmt.Errorf("update search_text: %w", err)
+			}
+		}
+		for _, kv := range settings {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO store_settings (key, value) VALUES (?, ?)
+ON CONFLICT(key) DO UPDATE SET value = excluded.value`, kv[0], kv[1]); err != nil {
+				return fmt.Errorf("save %s: %w", kv[0], err)
+			}
+		}
+		if len(changes) > 0 {
+			s.logger.Info("derived normalized search text", "rows", len(changes), "cjk_ngram", s.cjkNgram)
+		}
+		changed = len(changes)
+		return nil
+	})
+	return changed, err
+}
+
+// CheckFTS runs FTS5's integrity check against the memories table and
+// returns an error describing any mismatch. It reads the whole index.
+func (s *SQLiteStore) CheckFTS(ctx context.Context) error {
+	if !s.ftsEnabled {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO memories_fts(memories_fts, rank) VALUES ('integrity-check', 1)`); err != nil {
+		return fmt.Errorf("fts integrity check: %w", err)
+	}
+	return nil
+}
+
+// RebuildFTS regenerates the FTS index from the memories table.
+func (s *SQLiteStore) RebuildFTS(ctx context.Context) error {
+	if !s.ftsEnabled {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO memories_fts(memories_fts) VALUES ('rebuild')`); err != nil {
+		return fmt.Errorf("rebuild fts index: %w", err)
+	}
+	return s.recordReindex(ctx, s.db)
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ftsLastReindexSetting records when the FTS index was last rebuilt or
+// bulk re-derived.
+const ftsLastReindexSetting = "fts_last_reindex"
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// IndexHealth describes the search index for the admin dashboard.
+type IndexHealth struct {
+	// FTSEnabled is false when FTS5 is unavailable or turned off, in which
+	// case searches use LIKE.
+	FTSEnabled bool `json:"fts_enabled"`
+	// FTSForcedOff is set when FTS5 exists but the store was opened
+	// WithoutFTS.
+	FTSForcedOff bool  `json:"fts_forced_off"`
+	Memories     int64 `json:"memories"`
+	FTSRows      int64 `json:"fts_rows"`
+	// LastReindex is zero until the index has been rebuilt once.
+	LastReindex time.Time `json:"last_reindex"`
+	// Sizes lists the on-disk size of the memories table, its FTS shadow
+	// tables and every index, largest first. It is empty when SQLite lacks
+	// the dbstat table.
+	Sizes []ObjectSize `json:"sizes"`
+}
+
+// InSync reports whether every memory has an FTS entry.
+func (h IndexHealth) InSync() bool {
+	return !h.FTSEnabled || h.FTSRows == h.Memories
+}
+
+// ObjectSize is the page usage of one table or index.
+type ObjectSize struct {
+	Name  string `json:"name"`
+	Bytes int64  `json:"bytes"`
+}
+
+func (s *SQLiteStore) recordReindex(ctx context.Context, db execer) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO store_settings (key, value) VALUES (?, ?)
+ON CONFLICT(key) DO UPDATE SET value = excluded.value`, ftsLastReindexSetting, s.clock.Now().UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("record fts reindex: %w", err)
+	}
+	return nil
+}
+
+// IndexHealth reports FTS availability, coverage and index sizes.
+func (s *SQLiteStore) IndexHealth(ctx context.Context) (IndexHealth, error) {
+	h := IndexHealth{FTSEnabled: s.ftsEnabled && !s.ftsOff, FTSForcedOff: s.ftsEnabled && s.ftsOff}
+	if err := s.reader.QueryRowContext(ctx, `SELECT count(*) FROM memories`).Scan(&h.Memories); err != nil {
+		return h, fmt.Errorf("count memories: %w", err)
+	}
+	if s.ftsEnabled {
+		if err := s.reader.QueryRowContext(ctx, `SELECT count(*) FROM memories_fts_docsize`).Scan(&h.FTSRows); err != nil {
+			return h, fmt.Errorf("count fts documents: %w", err)
+		}
+	}
+
+	var last string
+	err := s.reader.QueryRowContext(ctx, `SELECT value FROM store_settings WHERE key = ?`, ftsLastReindexSetting).Scan(&last)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return h, fmt.Errorf("read last reindex: %w", err)
+	default:
+		if h.LastReindex, err = time.Parse(time.RFC3339Nano, last); err != nil {
+			return h, fmt.Errorf("parse last reindex: %w", err)
+		}
+	}
+
+	rows, err := s.reader.QueryContext(ctx, `SELECT d.name, sum(d.pgsize)
+FROM dbstat d
+JOIN sqlite_master m ON m.name = d.name
+WHERE m.type = 'index' OR d.name = 'memories' OR d.name LIKE 'memories\_fts\_%' ESCAPE '\'
+GROUP BY d.name
+ORDER BY 2 DESC, 1`)
+	if err != nil {
+		// dbstat is an optional SQLite extension.
+		return h, nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var o ObjectSize
+		if err := rows.Scan(&o.Name, &o.Bytes); err != nil {
+			return h, fmt.Errorf("scan index size: %w", err)
+		}
+		h.Sizes = append(h.Sizes, o)
+	}
+	return h, rows.Err()
+}
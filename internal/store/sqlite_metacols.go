@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// metaColumnPrefix names generated columns extracted from metadata_json:
+// key "ticket" becomes md_ticket, indexed by idx_memories_md_ticket.
+const metaColumnPrefix = "md_"
+
+var metaKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidMetadataColumnKey reports whether key can back a generated column.
+func ValidMetadataColumnKey(key string) bool {
+	return metaKeyPattern.MatchString(key)
+}
+
+// WithMetadataColumns materializes the given top-level metadata keys as
+// indexed generated columns so equality filters on them (ListFilter.Metadata)
+// use an index instead of parsing metadata_json for every row. Keys dropped
+// from the list have their columns removed on the next open.
+func WithMetadataColumns(keys ...string) Option {
+	return func(s *SQLiteStore) { s.metaColKeys = keys }
+}
+
+// ensureMetadataColumns adds and drops md_* columns to match the configured
+// keys. Columns are VIRTUAL: values live only in their index.
+func (s *SQLiteStore) ensureMetadataColumns(ctx context.Context) error {
+	want := make(map[string]struct{}, len(s.metaColKeys))
+	for _, key := range s.metaColKeys {
+		if !ValidMetadataColumnKey(key) {
+			return fmt.Errorf("invalid metadata column key %q", key)
+		}
+		want[key] = struct{}{}
+	}
+
+	have, err := s.metadataColumns(ctx)
+	if err != nil {
+		return err
+	}
+
+	for key := range have {
+		if _, ok := want[key]; ok {
+			continue
+		}
+		col := metaColumnPrefix + key
+		if _, err := s.db.ExecContext(ctx, `DROP INDEX IF EXISTS idx_memories_`+col); err != nil {
+			return fmt.Errorf("drop metadata index %s: %w", col, err)
+		}
+		if _, err := s.db.ExecContext(ctx, `ALTER TABLE memories DROP COLUMN `+col); err != nil {
+			return fmt.Errorf("drop metadata column %s: %w", col, err)
+		}
+		s.logger.Info("dropped metadata column", "key", key)
+	}
+
+	for key := range want {
+		col := metaColumnPrefix + key
+		if _, ok := have[key]; !ok {
+			stmt := fmt.Sprintf(`ALTER TABLE memories ADD COLUMN %s TEXT GENERATED ALWAYS AS (%s) VIRTUAL`, col, metadataExtract(key))
+			if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("add metadata column %s: %w", col, err)
+			}
+			s.logger.Info("added metadata column", "key", key)
+		}
+		if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_memories_%s ON memories(%s)`, col, col)); err != nil {
+			return fmt.Errorf("index metadata column %s: %w", col, err)
+		}
+	}
+	s.metaCols = want
+	return nil
+}
+
+// metadataColumns returns the keys that have an md_* column.
+func (s *SQLiteStore) metadataColumns(ctx context.Context) (map[string]struct{}, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT name FROM pragma_table_xinfo('memories') WHERE hidden IN (2, 3)`)
+	if err != nil {
+		return nil, fmt.Errorf("inspect metadata columns: %w", err)
+	}
+	defer rows.Close()
+	have := map[string]struct{}{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("inspect metadata columns: %w", err)
+		}
+		if key, ok := strings.CutPrefix(name, metaColumnPrefix); ok {
+			have[key] = struct{}{}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("inspect metadata columns: %w", err)
+	}
+	return have, nil
+}
+
+// metadataExtract is the SQL expression for a top-level metadata value as
+// text. key must satisfy ValidMetadataColumnKey.
+func metadataExtract(key string) string {
+	return fmt.Sprintf(`CAST(json_extract(metadata_json, '$.%s') AS TEXT)`, key)
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SearchMiss is a query that returned no memories, with how often and
+// when it was seen.
+type SearchMiss struct {
+	Namespace string `json:"namespace"`
+	Query     string `json:"query"`
+	Count     int    `json:"count"`
+	// SourceAgent is the agent of the latest miss.
+	SourceAgent string    `json:"source_agent"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// MissStore is implemented by stores that keep zero-result searches.
+type MissStore interface {
+	// RecordSearchMiss counts one miss of m.Query in m.Namespace at
+	// m.LastSeen.
+	RecordSearchMiss(ctx context.Context, m SearchMiss) error
+	// SearchMisses lists the most frequent misses, newest first among
+	// equals. An empty namespace lists every namespace.
+	SearchMisses(ctx context.Context, namespace string, limit int) ([]SearchMiss, error)
+}
+
+// RecordSearchMiss inserts the miss or bumps its count and last_seen.
+func (s *SQLiteStore) RecordSearchMiss(ctx context.Context, m SearchMiss) error {
+	at := m.LastSeen.UTC().Format(time.RFC3339Nano)
+	_, err := s.db.ExecContext(ctx, `INSERT INTO search_misses (namespace, query, source_agent, first_seen, last_seen)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT(namespace, query) DO UPDATE SET
+  count = count + 1, source_agent = excluded.source_agent, last_seen = excluded.last_seen`,
+		m.Namespace, m.Query, m.SourceAgent, at, at)
+	if err != nil {
+		return fmt.Errorf("record search miss: %w", err)
+	}
+	return nil
+}
+
+// SearchMisses reads the misses table ordered by count.
+func (s *SQLiteStore) SearchMisses(ctx context.Context, namespace string, limit int) ([]SearchMiss, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	rows, err := s.reader.QueryContext(ctx, `SELECT namespace, query, count, source_agent, first_seen, last_seen
+FROM search_misses
+WHERE ? = '' OR namespace = ?
+ORDER BY count DESC, last_seen DESC
+LIMIT ?`, namespace, namespace, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query search misses: %w", err)
+	}
+	defer rows.Close()
+	var out []SearchMiss
+	for rows.Next() {
+		var (
+			m                   SearchMiss
+			firstSeen, lastSeen string
+		)
+		if err := rows.Scan(&m.Namespace, &m.Query, &m.Count, &m.SourceAgent, &firstSeen, &lastSeen); err != nil {
+			return nil, fmt.Errorf("scan search miss: %w", err)
+		}
+		m.FirstSeen, _ = time.Parse(time.RFC3339Nano, firstSeen)
+		m.LastSeen, _ = time.Parse(time.RFC3339Nano, lastSeen)
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
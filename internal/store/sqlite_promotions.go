@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Promotion is one audited move of a memory to long-term scope.
+type Promotion struct {
+	MemoryID    string
+	Namespace   string
+	PriorScope  string
+	Reason      string
+	SourceAgent string
+	PromotedAt  time.Time
+}
+
+// PromotionStore is implemented by stores that keep a promotion audit
+// trail.
+type PromotionStore interface {
+	RecordPromotion(ctx context.Context, p Promotion) error
+	// RecentPromotions lists promotions newest first.
+	RecentPromotions(ctx context.Context, limit int) ([]Promotion, error)
+}
+
+// RecordPromotion appends p to the promotions table.
+func (s *SQLiteStore) RecordPromotion(ctx context.Context, p Promotion) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO promotions (memory_id, namespace, prior_scope, reason, source_agent, promoted_at)
+VALUES (?, ?, ?, ?, ?, ?)`,
+		p.MemoryID, p.Namespace, p.PriorScope, p.Reason, p.SourceAgent, p.PromotedAt.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("record promotion: %w", err)
+	}
+	return nil
+}
+
+// RecentPromotions reads the promotions table newest first.
+func (s *SQLiteStore) RecentPromotions(ctx context.Context, limit int) ([]Promotion, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	rows, err := s.reader.QueryContext(ctx, `SELECT memory_id, namespace, prior_scope, reason, source_agent, promoted_at
+FROM promotions
+ORDER BY id DESC
+LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query promotions: %w", err)
+	}
+	defer rows.Close()
+	var out []Promotion
+	for rows.Next() {
+		var (
+			p  Promotion
+			at string
+		)
+		if err := rows.Scan(&p.MemoryID, &p.Namespace, &p.PriorScope, &p.Reason, &p.SourceAgent, &at); err != nil {
+			return nil, fmt.Errorf("scan promotion: %w", err)
+		}
+		p.PromotedAt, _ = time.Parse(time.RFC3339Nano, at)
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// WithReadOnly opens the database with a read-only URI so the store never
+// takes SQLite's write lock: no schema setup or migration runs, and every
+// write fails. The database must already exist and have been opened
+// read-write by this version. Reads use a pool of WithReadConns
+// connections.
+//
+// immutable additionally tells SQLite the file cannot change while it is
+// open, which skips locking and ignores any write-ahead log. Use it only
+// for files nothing writes to, such as snapshots and replicas that are not
+// being updated; a live database reads as of its last checkpoint, and one
+// that changes underneath an immutable handle returns garbage or errors.
+func WithReadOnly(immutable bool) Option {
+	return func(s *SQLiteStore) { s.readOnly, s.immutable = true, immutable }
+}
+
+// openReadOnly opens dbPath for WithReadOnly and reads which indexes the
+// existing schema has instead of creating them.
+func (s *SQLiteStore) openReadOnly(ctx context.Context, dbPath string) error {
+	if _, err := os.Stat(dbPath); err != nil {
+		return fmt.Errorf("open sqlite read-only: %w", err)
+	}
+	params := "mode=ro"
+	if s.immutable {
+		params += "&immutable=1"
+	}
+	db, err := openTraced("sqlite", s.dsn("file:"+dbPath, params))
+	if err != nil {
+		return fmt.Errorf("open sqlite: %w", err)
+	}
+	conns := max(s.readConns, 1)
+	db.SetMaxOpenConns(conns)
+	db.SetMaxIdleConns(conns)
+	s.db, s.reader = db, db
+	if err := s.inspectSchema(ctx); err != nil {
+		_ = db.Close()
+		return err
+	}
+	return nil
+}
+
+// inspectSchema sets what init would have from the existing schema: whether
+// the FTS index can be searched and which metadata columns exist.
+func (s *SQLiteStore) inspectSchema(ctx context.Context) error {
+	var ddl string
+	err := s.db.QueryRowContext(ctx, `SELECT coalesce((SELECT sql FROM sqlite_master WHERE type='table' AND name='memories_fts'), '')`).Scan(&ddl)
+	if err != nil {
+		return fmt.Errorf("inspect fts table: %w", err)
+	}
+	s.ftsEnabled = strings.Contains(ddl, "content='memories'") && strings.Contains(ddl, "search_text")
+
+	have, err := s.metadataColumns(ctx)
+	if err != nil {
+		return err
+	}
+	s.metaCols = map[string]struct{}{}
+	for _, key := range s.metaColKeys {
+		if _, ok := have[key]; ok {
+			s.metaCols[key] = struct{}{}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Relation kinds accepted by LinkMemories.
+const (
+	RelationSupersedes  = "supersedes"
+	RelationRefines     = "refines"
+	RelationRelatesTo   = "relates_to"
+	RelationDerivedFrom = "derived_from"
+)
+
+// Relation is a directed link: FromID Kind ToID, e.g. a new decision
+// supersedes the one it replaces.
+type Relation struct {
+	FromID string
+	ToID   string
+	Kind   string
+	// Namespace is FromID's namespace, which picks the shard that keeps
+	// the link.
+	Namespace   string
+	SourceAgent string
+	CreatedAt   time.Time
+}
+
+// RelationStore is implemented by stores that keep links between memories.
+// Deleting a memory drops the links kept in its own database; a link
+// across shards can outlive one of its memories, so readers skip links to
+// memories that no longer exist.
+type RelationStore interface {
+	// LinkMemories records r; linking the same pair with the same kind
+	// again keeps the first link.
+	LinkMemories(ctx context.Context, r Relation) error
+	// RelationsOf returns the links of kind from or to any of ids, or of
+	// every kind when kind is empty.
+	RelationsOf(ctx context.Context, kind string, ids []string) ([]Relation, error)
+}
+
+// LinkMemories implements RelationStore.
+func (s *SQLiteStore) LinkMemories(ctx context.Context, r Relation) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO memory_relations (from_id, to_id, relation, source_agent, created_at)
+VALUES (?, ?, ?, ?, ?) ON CONFLICT DO NOTHING`, r.FromID, r.ToID, r.Kind, r.SourceAgent, r.CreatedAt.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("insert relation: %w", err)
+	}
+	return nil
+}
+
+// RelationsOf implements RelationStore.
+func (s *SQLiteStore) RelationsOf(ctx context.Context, kind string, ids []string) ([]Relation, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]any, 0, 2*len(ids)+1)
+	for range 2 {
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+	q := `SELECT from_id, to_id, relation, source_agent, created_at FROM memory_relations
+WHERE (from_id IN (` + placeholders + `) OR to_id IN (` + placeholders + `))`
+	if kind != "" {
+		q += " AND relation = ?"
+		args = append(args, kind)
+	}
+	rows, err := s.reader.QueryContext(ctx, q+" ORDER BY created_at, from_id, to_id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("query relations: %w", err)
+	}
+	defer rows.Close()
+	var out []Relation
+	for rows.Next() {
+		var r Relation
+		var created string
+		if err := rows.Scan(&r.FromID, &r.ToID, &r.Kind, &r.SourceAgent, &created); err != nil {
+			return nil, fmt.Errorf("scan relation: %w", err)
+		}
+		r.CreatedAt, _ = time.Parse(time.RFC3339Nano, created)
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// LinkMemories implements RelationStore in the shard of the linking
+// memory.
+func (s *ShardedStore) LinkMemories(ctx context.Context, r Relation) error {
+	return s.storeFor(r.Namespace).LinkMemories(ctx, r)
+}
+
+// RelationsOf implements RelationStore.
+func (s *ShardedStore) RelationsOf(ctx context.Context, kind string, ids []string) ([]Relation, error) {
+	var out []Relation
+	for _, st := range s.stores() {
+		rels, err := st.RelationsOf(ctx, kind, ids)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rels...)
+	}
+	return out, nil
+}
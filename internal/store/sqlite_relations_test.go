@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestRelations_LinkAndDropOnDelete(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	sqliteStore, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "relations.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer sqliteStore.Close()
+	sharded, err := OpenSharded(ctx, filepath.Join(t.TempDir(), "main.db"), map[string]string{"beta": filepath.Join(t.TempDir(), "beta.db")}, logger)
+	if err != nil {
+		t.Fatalf("OpenSharded() error = %v", err)
+	}
+	defer sharded.Close()
+
+	recs := []types.MemoryRecord{
+		{ID: "r-1", Namespace: "beta/app", Scope: "long", Content: "old decision", Importance: 3, CreatedAt: now, LastAccessedAt: now},
+		{ID: "r-2", Namespace: "beta/app", Scope: "long", Content: "new decision", Importance: 3, CreatedAt: now, LastAccessedAt: now},
+	}
+	for _, st := range []interface {
+		Store
+		RelationStore
+	}{sqliteStore, sharded} {
+		if _, _, err := st.ImportBatch(ctx, recs); err != nil {
+			t.Fatalf("%T.ImportBatch() error = %v", st, err)
+		}
+		for range 2 {
+			if err := st.LinkMemories(ctx, Relation{FromID: "r-2", ToID: "r-1", Kind: RelationSupersedes, Namespace: "beta/app", SourceAgent: "codex", CreatedAt: now}); err != nil {
+				t.Fatalf("%T.LinkMemories() error = %v", st, err)
+			}
+		}
+		if err := st.LinkMemories(ctx, Relation{FromID: "r-1", ToID: "r-2", Kind: "replaces", Namespace: "beta/app", CreatedAt: now}); err == nil {
+			t.Fatalf("%T.LinkMemories() with an unknown kind error = nil", st)
+		}
+
+		rels, err := st.RelationsOf(ctx, RelationSupersedes, []string{"r-1"})
+		if err != nil {
+			t.Fatalf("%T.RelationsOf() error = %v", st, err)
+		}
+		if len(rels) != 1 || rels[0].FromID != "r-2" || rels[0].SourceAgent != "codex" || !rels[0].CreatedAt.Equal(now) {
+			t.Fatalf("%T.RelationsOf() = %+v, want one r-2 supersedes r-1", st, rels)
+		}
+		if rels, _ := st.RelationsOf(ctx, RelationRefines, []string{"r-1"}); len(rels) != 0 {
+			t.Fatalf("%T.RelationsOf(refines) = %+v, want none", st, rels)
+		}
+
+		if _, err := st.DeleteMemories(ctx, []string{"r-2"}); err != nil {
+			t.Fatalf("%T.DeleteMemories() error = %v", st, err)
+		}
+		if rels, err := st.RelationsOf(ctx, "", []string{"r-1", "r-2"}); err != nil || len(rels) != 0 {
+			t.Fatalf("%T.RelationsOf() after delete = %+v, %v, want none", st, rels, err)
+		}
+	}
+}
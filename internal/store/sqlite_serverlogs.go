@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// serverLogRetention is the number of server log rows kept; older rows are
+// pruned as new ones arrive.
+const serverLogRetention = 5000
+
+// ServerLog is one persisted server log event.
+type ServerLog struct {
+	ID        int64
+	Level     string
+	Message   string
+	CreatedAt time.Time
+}
+
+// InsertServerLogs stores a batch of server log events and prunes rows
+// beyond the retention limit.
+func (s *SQLiteStore) InsertServerLogs(ctx context.Context, logs []ServerLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin server log tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, l := range logs {
+		ts := l.CreatedAt.UTC()
+		if l.CreatedAt.IsZero() {
+			ts = s.clock.Now().UTC()
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO server_logs (level, message, created_at) VALUES (?, ?, ?)`,
+			strings.TrimSpace(l.Level), strings.TrimSpace(l.Message), ts.Format(time.RFC3339Nano)); err != nil {
+			return fmt.Errorf("insert server log: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM server_logs WHERE id <= (SELECT max(id) FROM server_logs) - ?`, serverLogRetention); err != nil {
+		return fmt.Errorf("prune server logs: %w", err)
+	}
+	return tx.Commit()
+}
+
+// RecentServerLogs returns the most recent server log events in
+// newest-first order.
+func (s *SQLiteStore) RecentServerLogs(ctx context.Context, limit int) ([]ServerLog, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	rows, err := s.reader.QueryContext(ctx, `SELECT id, level, message, created_at
+FROM server_logs
+ORDER BY created_at DESC, id DESC
+LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list server logs: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]ServerLog, 0, limit)
+	for rows.Next() {
+		var (
+			row            ServerLog
+			createdAtValue string
+		)
+		if err := rows.Scan(&row.ID, &row.Level, &row.Message, &createdAtValue); err != nil {
+			return nil, fmt.Errorf("scan server log: %w", err)
+		}
+		if ts, err := time.Parse(time.RFC3339Nano, createdAtValue); err == nil {
+			row.CreatedAt = ts
+		}
+		items = append(items, row)
+	}
+	return items, rows.Err()
+}
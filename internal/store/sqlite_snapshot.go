@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+
+	"modernc.org/sqlite"
+)
+
+// backupConn is the modernc driver connection's online backup API.
+type backupConn interface {
+	NewBackup(dstURI string) (*sqlite.Backup, error)
+	NewRestore(srcURI string) (*sqlite.Backup, error)
+}
+
+// Backup writes a consistent copy of the database to path with SQLite's
+// online backup API, which copies pages as they are. Unlike VACUUM INTO it
+// keeps the memories rowids that memories_fts refers to, so the copy needs
+// no reindex. path is written through a temporary file and must not exist.
+func (s *SQLiteStore) Backup(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup %s: file exists", path)
+	}
+	tmp := path + ".tmp"
+	_ = os.Remove(tmp)
+	err := runBackup(ctx, s.db, func(c backupConn) (*sqlite.Backup, error) { return c.NewBackup(tmp) })
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("backup database: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the whole database with the SQLite database at path,
+// in one transaction that other connections wait for. Their caches of
+// records and searches are not told; restart servers using the database.
+func (s *SQLiteStore) Restore(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("restore database: %w", err)
+	}
+	err := runBackup(ctx, s.db, func(c backupConn) (*sqlite.Backup, error) { return c.NewRestore("file:" + path + "?mode=ro") })
+	s.purgeRecords()
+	if err != nil {
+		return fmt.Errorf("restore database: %w", err)
+	}
+	return nil
+}
+
+// CopySQLite copies the SQLite database at src to dst, which must not
+// exist, with the online backup API.
+func CopySQLite(ctx context.Context, src, dst string) error {
+	if _, err := os.Stat(src); err != nil {
+		return fmt.Errorf("copy database: %w", err)
+	}
+	db, err := sql.Open("sqlite", "file:"+src+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("open sqlite: %w", err)
+	}
+	defer db.Close()
+	s := &SQLiteStore{db: db}
+	return s.Backup(ctx, dst)
+}
+
+func runBackup(ctx context.Context, db *sql.DB, start func(backupConn) (*sqlite.Backup, error)) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(dc any) error {
+		// Traced connections wrap the driver's; see openTraced.
+		if w, ok := dc.(interface{ Raw() driver.Conn }); ok {
+			dc = w.Raw()
+		}
+		c, ok := dc.(backupConn)
+		if !ok {
+			return errors.New("sqlite driver has no backup API")
+		}
+		b, err := start(c)
+		if err != nil {
+			return err
+		}
+		for more := true; more; {
+			if err := ctx.Err(); err != nil {
+				_ = b.Finish()
+				return err
+			}
+			if more, err = b.Step(256); err != nil {
+				_ = b.Finish()
+				return err
+			}
+		}
+		return b.Finish()
+	})
+}
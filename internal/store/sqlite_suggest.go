@@ -0,0 +1,234 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/xiy/memory-mcp/internal/lang"
+)
+
+// memories_fts_vocab lists every indexed term with its document count, and
+// memories_fts_terms every occurrence with its rowid, which ties a term to
+// a namespace. Both read memories_fts and store nothing themselves.
+var ftsVocabSQL = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS memories_fts_vocab USING fts5vocab(memories_fts, 'row')`,
+	`CREATE VIRTUAL TABLE IF NOT EXISTS memories_fts_terms USING fts5vocab(memories_fts, 'instance')`,
+}
+
+// Suggester is implemented by stores that can propose corrected queries
+// for a search that matched nothing.
+type Suggester interface {
+	// SuggestQueries returns up to limit rewrites of q.Query with each
+	// word unknown to q.Namespace replaced by a close one that is known.
+	SuggestQueries(ctx context.Context, q SearchQuery, limit int) ([]string, error)
+}
+
+// minSuggestLen is the shortest query word that gets corrections and the
+// shortest word suggested; shorter ones have too many neighbours.
+const minSuggestLen = 4
+
+// SuggestQueries looks up query words in the FTS vocabulary. Words the
+// namespace already uses are kept, others are replaced by the vocabulary
+// terms closest in edit distance that occur in the namespace, spelled as
+// in a memory using them. CJK words are kept as typed.
+func (s *SQLiteStore) SuggestQueries(ctx context.Context, q SearchQuery, limit int) ([]string, error) {
+	if !s.ftsEnabled || limit <= 0 {
+		return nil, nil
+	}
+	terms := tokenizeQueryTerms(q.Query, q.StopWords)
+	alts := make([][]string, len(terms))
+	corrected := false
+	for i, term := range terms {
+		if len([]rune(term)) < minSuggestLen || strings.ContainsFunc(term, isCJK) {
+			continue
+		}
+		known, err := s.termInNamespace(ctx, q.Namespace, termForms(stemTerms([]string{term})[0]))
+		if err != nil {
+			return nil, err
+		}
+		if known {
+			continue
+		}
+		if alts[i], err = s.closeTerms(ctx, q.Namespace, term, limit); err != nil {
+			return nil, err
+		}
+		corrected = corrected || len(alts[i]) > 0
+	}
+	if !corrected {
+		return nil, nil
+	}
+
+	var out []string
+	for k := 0; len(out) < limit; k++ {
+		words := make([]string, len(terms))
+		more := false
+		for i, term := range terms {
+			words[i] = term
+			if n := len(alts[i]); n > 0 {
+				words[i] = alts[i][min(k, n-1)]
+				more = more || k < n-1
+			}
+		}
+		if query := strings.Join(words, " "); !slices.Contains(out, query) {
+			out = append(out, query)
+		}
+		if !more {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (s *SQLiteStore) termInNamespace(ctx context.Context, namespace string, forms []string) (bool, error) {
+	for _, form := range forms {
+		var one int
+		err := s.reader.QueryRowContext(ctx, `SELECT EXISTS (
+  SELECT 1 FROM memories_fts_terms v JOIN memories m ON m.rowid = v.doc
+  WHERE v.term = ? AND m.namespace = ?)`, form, namespace).Scan(&one)
+		if err != nil {
+			return false, fmt.Errorf("look up fts term: %w", err)
+		}
+		if one == 1 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// closeTerms returns up to limit words of namespace near word, closest and
+// then most common first.
+func (s *SQLiteStore) closeTerms(ctx context.Context, namespace, word string, limit int) ([]string, error) {
+	maxDist := 1
+	if len([]rune(word)) > 5 {
+		maxDist = 2
+	}
+	rows, err := s.reader.QueryContext(ctx, `SELECT term, doc FROM memories_fts_vocab
+WHERE length(term) BETWEEN ? AND ?`, minSuggestLen, len([]rune(word))+maxDist)
+	if err != nil {
+		return nil, fmt.Errorf("scan fts vocabulary: %w", err)
+	}
+	type candidate struct {
+		term       string
+		dist, docs int
+	}
+	var cands []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.term, &c.docs); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("scan fts vocabulary: %w", err)
+		}
+		if !strings.ContainsFunc(c.term, unicode.IsLetter) || strings.ContainsFunc(c.term, isCJK) {
+			continue
+		}
+		if c.dist = termDistance(word, c.term); c.dist <= maxDist {
+			cands = append(cands, c)
+		}
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan fts vocabulary: %w", err)
+	}
+	slices.SortStableFunc(cands, func(a, b candidate) int {
+		if a.dist != b.dist {
+			return a.dist - b.dist
+		}
+		return b.docs - a.docs
+	})
+
+	var out []string
+	for _, c := range cands {
+		if len(out) == limit {
+			break
+		}
+		surface, ok, err := s.surfaceWord(ctx, namespace, c.term, word)
+		if err != nil {
+			return nil, err
+		}
+		if ok && !slices.Contains(out, surface) {
+			out = append(out, surface)
+		}
+	}
+	return out, nil
+}
+
+// surfaceWord finds a memory of namespace indexed under term and returns
+// the word of its text that produced term, closest to word when several
+// did; English memories index stems, which are poor suggestions.
+func (s *SQLiteStore) surfaceWord(ctx context.Context, namespace, term, word string) (string, bool, error) {
+	var content, summary string
+	err := s.reader.QueryRowContext(ctx, `SELECT m.content, m.summary
+FROM memories_fts_terms v JOIN memories m ON m.rowid = v.doc
+WHERE v.term = ? AND m.namespace = ?
+LIMIT 1`, term, namespace).Scan(&content, &summary)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("look up fts term: %w", err)
+	}
+	best, bestDist := term, -1
+	for _, w := range strings.FieldsFunc(searchText(content, summary), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if w != term && lang.Stem(w) != term {
+			continue
+		}
+		if d := editDistance(w, word); bestDist < 0 || d < bestDist {
+			best, bestDist = w, d
+		}
+	}
+	return best, true, nil
+}
+
+// stemSuffixes are endings the English stemmer may strip, so a misspelt
+// word can be compared with a stem without them.
+var stemSuffixes = []string{
+	"s", "es", "ed", "ing", "ly", "er", "ers", "ment", "ments", "ion", "ions",
+	"ation", "ations", "ness", "ity", "al", "ive", "able", "ent", "ence",
+}
+
+// termDistance is the edit distance from a query word to an index term,
+// which may be the stem of a longer word: an ending of word that stemming
+// strips costs nothing.
+func termDistance(word, term string) int {
+	d := editDistance(word, term)
+	for _, suffix := range stemSuffixes {
+		if stem, ok := strings.CutSuffix(word, suffix); ok && len([]rune(stem)) >= minSuggestLen {
+			d = min(d, editDistance(stem, term))
+		}
+	}
+	return d
+}
+
+// editDistance counts the insertions, deletions, substitutions and
+// adjacent transpositions that turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/xiy/memory-mcp/pkg/types"
+)
+
+func TestEditDistance(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"deploy", "deploy", 0},
+		{"sesion", "session", 1},
+		{"teh", "the", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+		{"データ", "デート", 1},
+	}
+	for _, tc := range cases {
+		if got := editDistance(tc.a, tc.b); got != tc.want {
+			t.Fatalf("editDistance(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+	if got := termDistance("deplyment", "deploy"); got != 1 {
+		t.Fatalf("termDistance() against a stem = %d, want 1", got)
+	}
+}
+
+func TestSQLiteStore_SuggestQueries(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "suggest.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	if !st.ftsEnabled {
+		t.Skip("FTS5 unavailable")
+	}
+	now := time.Now().UTC()
+	if _, err := st.InsertMemories(ctx, []types.MemoryRecord{
+		{ID: "m1", Namespace: "org/repo/task", Scope: "long", Content: "The deployment process runs canary checks.", CreatedAt: now, LastAccessedAt: now},
+		{ID: "m2", Namespace: "org/repo/task", Scope: "long", Content: "Deployments pause during the freeze.", CreatedAt: now, LastAccessedAt: now},
+		{ID: "m3", Namespace: "org/repo/other", Scope: "long", Content: "Kubernetes upgrade notes.", CreatedAt: now, LastAccessedAt: now},
+	}); err != nil {
+		t.Fatalf("InsertMemories() error = %v", err)
+	}
+	suggest := func(query string) []string {
+		t.Helper()
+		got, err := st.SuggestQueries(ctx, SearchQuery{Namespace: "org/repo/task", Query: query}, 3)
+		if err != nil {
+			t.Fatalf("SuggestQueries(%q) error = %v", query, err)
+		}
+		return got
+	}
+	if got := suggest("deplyment proces"); !slices.Equal(got, []string{"deployment process"}) {
+		t.Fatalf("SuggestQueries() = %q, want [deployment process]", got)
+	}
+	if got := suggest("canary freeze"); got != nil {
+		t.Fatalf("SuggestQueries() with known words = %q, want none", got)
+	}
+	if got := suggest("kubernetse"); got != nil {
+		t.Fatalf("SuggestQueries() = %q, want no words from other namespaces", got)
+	}
+}
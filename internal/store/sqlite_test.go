@@ -2,13 +2,20 @@ package store
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"io"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/charmbracelet/log"
 
+	"github.com/xiy/memory-mcp/internal/clock"
 	"github.com/xiy/memory-mcp/pkg/types"
 )
 
@@ -55,7 +62,7 @@ func TestSQLiteStore_RoundTripAndExpire(t *testing.T) {
 		t.Fatalf("InsertMemory(long) error = %v", err)
 	}
 
-	cands, err := st.SearchCandidates(ctx, "org/repo/task", "deployment", "", 10, now)
+	cands, err := st.SearchCandidates(ctx, SearchQuery{Namespace: "org/repo/task", Query: "deployment", Limit: 10, Now: now})
 	if err != nil {
 		t.Fatalf("SearchCandidates() error = %v", err)
 	}
@@ -78,7 +85,7 @@ func TestSQLiteStore_RoundTripAndExpire(t *testing.T) {
 		t.Fatalf("InsertMemory(hyphenRec) error = %v", err)
 	}
 
-	hyphenCands, err := st.SearchCandidates(ctx, "org/repo/task", "shared-memory verification", "", 10, now)
+	hyphenCands, err := st.SearchCandidates(ctx, SearchQuery{Namespace: "org/repo/task", Query: "shared-memory verification", Limit: 10, Now: now})
 	if err != nil {
 		t.Fatalf("SearchCandidates(hyphen query) error = %v", err)
 	}
@@ -196,6 +203,13 @@ func TestSQLiteStore_RequestLogsAndRecentMemories(t *testing.T) {
 	if logs[0].Success {
 		t.Fatalf("expected newest request success=false, got true")
 	}
+	tail, err := st.MCPRequestLogsAfter(ctx, logs[1].ID, 10)
+	if err != nil || len(tail) != 1 || tail[0].ID != logs[0].ID {
+		t.Fatalf("MCPRequestLogsAfter(%d) = %+v, %v, want only the newest request", logs[1].ID, tail, err)
+	}
+	if tail, err := st.MCPRequestLogsAfter(ctx, 0, 1); err != nil || len(tail) != 1 || tail[0].ID != logs[1].ID {
+		t.Fatalf("MCPRequestLogsAfter(0, 1) = %+v, %v, want the oldest request", tail, err)
+	}
 
 	recent, err := st.RecentMemories(ctx, 5)
 	if err != nil {
@@ -211,3 +225,1083 @@ func TestSQLiteStore_RequestLogsAndRecentMemories(t *testing.T) {
 		t.Fatalf("expected summary fallback from content, got %q", recent[0].Summary)
 	}
 }
+
+func TestSQLiteStore_InsertRollsBackWhenFTSFails(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "atomic.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	if !st.ftsEnabled {
+		t.Skip("FTS5 unavailable")
+	}
+	if _, err := st.db.ExecContext(ctx, `DROP TABLE memories_fts`); err != nil {
+		t.Fatalf("drop fts table: %v", err)
+	}
+
+	now := time.Now().UTC()
+	_, err = st.InsertMemory(ctx, types.MemoryRecord{
+		ID:             "m-orphan",
+		Namespace:      "org/repo/task",
+		Scope:          "long",
+		Content:        "never half-written",
+		CreatedAt:      now,
+		LastAccessedAt: now,
+	})
+	if err == nil {
+		t.Fatal("expected InsertMemory to fail without an FTS table")
+	}
+	if _, err := st.GetMemory(ctx, "m-orphan"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected memory row to be rolled back, GetMemory() error = %v", err)
+	}
+}
+
+func TestSQLiteStore_InsertMemoriesIsAllOrNothing(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "bulk.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+
+	now := time.Now().UTC()
+	batch := make([]types.MemoryRecord, 50)
+	for i := range batch {
+		batch[i] = types.MemoryRecord{
+			ID:             fmt.Sprintf("m-%d", i),
+			Namespace:      "org/repo/task",
+			Scope:          "long",
+			Content:        fmt.Sprintf("bulk imported note %d", i),
+			CreatedAt:      now,
+			LastAccessedAt: now,
+		}
+	}
+	if _, err := st.InsertMemories(ctx, batch); err != nil {
+		t.Fatalf("InsertMemories() error = %v", err)
+	}
+	cands, err := st.SearchCandidates(ctx, SearchQuery{Namespace: "org/repo/task", Query: "bulk", Limit: 100, Now: now})
+	if err != nil {
+		t.Fatalf("SearchCandidates() error = %v", err)
+	}
+	if len(cands) != 50 {
+		t.Fatalf("expected 50 searchable memories, got %d", len(cands))
+	}
+
+	dup := []types.MemoryRecord{
+		{ID: "m-new", Namespace: "org/repo/task", Scope: "long", Content: "fresh", CreatedAt: now, LastAccessedAt: now},
+		batch[0],
+	}
+	if _, err := st.InsertMemories(ctx, dup); err == nil {
+		t.Fatal("expected duplicate id to fail the batch")
+	}
+	if _, err := st.GetMemory(ctx, "m-new"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected failed batch to roll back, GetMemory() error = %v", err)
+	}
+}
+
+func TestSQLiteStore_RecordCacheInvalidatesOnMutation(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "records.db"), logger, WithRecordCache(8, time.Minute))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+
+	now := time.Now().UTC()
+	expires := now.Add(time.Hour)
+	rec := types.MemoryRecord{
+		ID: "m-hot", Namespace: "org/repo/task", Scope: "short", Content: "hot record",
+		CreatedAt: now, LastAccessedAt: now, ExpiresAt: &expires,
+	}
+	if _, err := st.InsertMemory(ctx, rec); err != nil {
+		t.Fatalf("InsertMemory() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := st.GetMemory(ctx, "m-hot"); err != nil {
+			t.Fatalf("GetMemory() error = %v", err)
+		}
+	}
+	if stats := st.RecordCacheStats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("unexpected cache stats %+v", stats)
+	}
+
+	if err := st.Promote(ctx, "m-hot", now); err != nil {
+		t.Fatalf("Promote() error = %v", err)
+	}
+	got, err := st.GetMemory(ctx, "m-hot")
+	if err != nil {
+		t.Fatalf("GetMemory() after promote error = %v", err)
+	}
+	if got.Scope != "long" || got.ExpiresAt != nil {
+		t.Fatalf("expected promoted record, got scope=%s expires=%v", got.Scope, got.ExpiresAt)
+	}
+
+	if _, err := st.DeleteMemories(ctx, []string{"m-hot"}); err != nil {
+		t.Fatalf("DeleteMemories() error = %v", err)
+	}
+	if _, err := st.GetMemory(ctx, "m-hot"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected deleted record to miss, GetMemory() error = %v", err)
+	}
+}
+
+func TestSQLiteStore_RecordCacheExpiresChangesFromOtherHandles(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	path := filepath.Join(t.TempDir(), "shared.db")
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewManual(now)
+	st, err := OpenSQLite(ctx, path, logger, WithClock(clk), WithRecordCache(8, 5*time.Second))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	other, err := OpenSQLite(ctx, path, logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite(other) error = %v", err)
+	}
+	defer other.Close()
+
+	rec := types.MemoryRecord{
+		ID: "m-shared", Namespace: "org/repo/task", Scope: "long", Content: "shared record",
+		CreatedAt: now, LastAccessedAt: now,
+	}
+	if _, err := st.InsertMemory(ctx, rec); err != nil {
+		t.Fatalf("InsertMemory() error = %v", err)
+	}
+	if _, err := st.GetMemory(ctx, "m-shared"); err != nil {
+		t.Fatalf("GetMemory() error = %v", err)
+	}
+	if _, err := other.DeleteMemories(ctx, []string{"m-shared"}); err != nil {
+		t.Fatalf("DeleteMemories(other) error = %v", err)
+	}
+	if _, err := st.GetMemory(ctx, "m-shared"); err != nil {
+		t.Fatalf("GetMemory() within the TTL error = %v, want the cached record", err)
+	}
+	clk.Advance(6 * time.Second)
+	if _, err := st.GetMemory(ctx, "m-shared"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetMemory() after the TTL error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestSQLiteStore_RecordCacheSkipsReadsThatRacedAWrite(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "race.db"), logger, WithRecordCache(8, time.Minute))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+
+	now := time.Now().UTC()
+	rec := types.MemoryRecord{
+		ID: "m-race", Namespace: "org/repo/task", Scope: "long", Content: "before",
+		CreatedAt: now, LastAccessedAt: now,
+	}
+	if _, err := st.InsertMemory(ctx, rec); err != nil {
+		t.Fatalf("InsertMemory() error = %v", err)
+	}
+	// A read that began before the delete must not be cached after it.
+	gen := st.recordGeneration()
+	if _, err := st.DeleteMemories(ctx, []string{"m-race"}); err != nil {
+		t.Fatalf("DeleteMemories() error = %v", err)
+	}
+	st.cacheRecord(rec, gen)
+	if _, err := st.GetMemory(ctx, "m-race"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetMemory() error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestLikeSearchQuery_UsesCoveringIndex(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "plan.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+
+	now := time.Now().UTC()
+	cases := []struct {
+		scope string
+		index string
+	}{
+		{scope: "short", index: "idx_memories_ns_scope_created"},
+		{scope: "", index: "idx_memories_ns_created"},
+	}
+	for _, tc := range cases {
+		q, args := likeSearchQuery(SearchQuery{Namespace: "org/repo/task", Query: "deploy", Scope: tc.scope, Limit: 10, Now: now}, []string{"deploy"}, nil)
+		rows, err := st.db.QueryContext(ctx, "EXPLAIN QUERY PLAN "+q, args...)
+		if err != nil {
+			t.Fatalf("EXPLAIN error = %v", err)
+		}
+		var plan []string
+		for rows.Next() {
+			var id, parent, notused int
+			var detail string
+			if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+				t.Fatalf("scan plan: %v", err)
+			}
+			plan = append(plan, detail)
+		}
+		rows.Close()
+
+		joined := strings.Join(plan, "\n")
+		if !strings.Contains(joined, tc.index) {
+			t.Fatalf("scope=%q: expected plan to use %s, got:\n%s", tc.scope, tc.index, joined)
+		}
+		if strings.Contains(joined, "TEMP B-TREE") {
+			t.Fatalf("scope=%q: expected index order without a sort step, got:\n%s", tc.scope, joined)
+		}
+	}
+}
+
+func TestOpenSQLite_MigratesLegacyFTSTable(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	dbPath := filepath.Join(t.TempDir(), "legacy.db")
+
+	raw, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("open raw db: %v", err)
+	}
+	for _, stmt := range []string{
+		`CREATE TABLE memories (
+  id TEXT PRIMARY KEY, namespace TEXT NOT NULL, scope TEXT NOT NULL, content TEXT NOT NULL,
+  summary TEXT NOT NULL DEFAULT '', importance INTEGER NOT NULL DEFAULT 3,
+  source_agent TEXT NOT NULL DEFAULT '', metadata_json TEXT NOT NULL DEFAULT '{}',
+  created_at TEXT NOT NULL, last_accessed_at TEXT NOT NULL, expires_at TEXT, promoted_at TEXT)`,
+		`CREATE VIRTUAL TABLE memories_fts USING fts5(id UNINDEXED, content, summary)`,
+		`INSERT INTO memories (id, namespace, scope, content, summary, created_at, last_accessed_at)
+  VALUES ('m-old', 'org/repo/task', 'long', 'legacy rollout checklist', 'legacy', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z')`,
+		`INSERT INTO memories_fts (id, content, summary) VALUES ('m-old', 'legacy rollout checklist', 'legacy')`,
+	} {
+		if _, err := raw.ExecContext(ctx, stmt); err != nil {
+			t.Skipf("cannot build legacy fixture: %v", err)
+		}
+	}
+	raw.Close()
+
+	st, err := OpenSQLite(ctx, dbPath, logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+
+	var ddl string
+	if err := st.db.QueryRowContext(ctx, `SELECT sql FROM sqlite_master WHERE name='memories_fts'`).Scan(&ddl); err != nil {
+		t.Fatalf("read fts ddl: %v", err)
+	}
+	if !strings.Contains(ddl, "content='memories'") {
+		t.Fatalf("expected external-content fts table, got %s", ddl)
+	}
+
+	now := time.Now().UTC()
+	cands, err := st.searchFTS(ctx, SearchQuery{Namespace: "org/repo/task", Limit: 10, Now: now}, buildFTSMatchQuery([]string{"rollout"}))
+	if err != nil || len(cands) != 1 || cands[0].Record.ID != "m-old" {
+		t.Fatalf("expected migrated row to be searchable via FTS, got %v, %v", cands, err)
+	}
+
+	if _, err := st.DeleteMemories(ctx, []string{"m-old"}); err != nil {
+		t.Fatalf("DeleteMemories() error = %v", err)
+	}
+	cands, err = st.searchFTS(ctx, SearchQuery{Namespace: "org/repo/task", Limit: 10, Now: now}, buildFTSMatchQuery([]string{"rollout"}))
+	if err != nil || len(cands) != 0 {
+		t.Fatalf("expected delete trigger to drop index entry, got %v, %v", cands, err)
+	}
+}
+
+func TestSQLiteStore_FTSStaysInSyncAcrossMutations(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	dbPath := filepath.Join(t.TempDir(), "sync.db")
+	st, err := OpenSQLite(ctx, dbPath, logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	if !st.ftsEnabled {
+		st.Close()
+		t.Skip("FTS5 unavailable")
+	}
+
+	now := time.Now().UTC()
+	past := now.Add(-time.Minute)
+	recs := []types.MemoryRecord{
+		{ID: "m-keep", Namespace: "org/repo/task", Scope: "long", Content: "alpha keep", CreatedAt: now, LastAccessedAt: now},
+		{ID: "m-edit", Namespace: "org/repo/task", Scope: "long", Content: "alpha edit", CreatedAt: now, LastAccessedAt: now},
+		{ID: "m-drop", Namespace: "org/repo/task", Scope: "long", Content: "alpha drop", CreatedAt: now, LastAccessedAt: now},
+		{ID: "m-expired", Namespace: "org/repo/task", Scope: "short", Content: "alpha expired", CreatedAt: past, LastAccessedAt: past, ExpiresAt: &past},
+	}
+	if _, err := st.InsertMemories(ctx, recs); err != nil {
+		t.Fatalf("InsertMemories() error = %v", err)
+	}
+	if _, err := st.UpdateMemory(ctx, "m-edit", func(rec *types.MemoryRecord) error {
+		rec.Content = "beta edited"
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateMemory() error = %v", err)
+	}
+	if _, err := st.DeleteMemories(ctx, []string{"m-drop"}); err != nil {
+		t.Fatalf("DeleteMemories() error = %v", err)
+	}
+	if _, err := st.ExpireShort(ctx, now); err != nil {
+		t.Fatalf("ExpireShort() error = %v", err)
+	}
+	if err := st.CheckFTS(ctx); err != nil {
+		t.Fatalf("CheckFTS() error = %v", err)
+	}
+
+	ids := func(term string) []string {
+		cands, err := st.searchFTS(ctx, SearchQuery{Namespace: "org/repo/task", Limit: 10, Now: now}, buildFTSMatchQuery([]string{term}))
+		if err != nil {
+			t.Fatalf("searchFTS(%q) error = %v", term, err)
+		}
+		var out []string
+		for _, c := range cands {
+			out = append(out, c.Record.ID)
+		}
+		return out
+	}
+	if got := ids("alpha"); len(got) != 1 || got[0] != "m-keep" {
+		t.Fatalf("expected only m-keep to match alpha, got %v", got)
+	}
+	if got := ids("beta"); len(got) != 1 || got[0] != "m-edit" {
+		t.Fatalf("expected edited content to be indexed, got %v", got)
+	}
+
+	// Simulate drift (rows written while triggers were missing) and check
+	// that reopening repairs it.
+	for _, stmt := range []string{
+		`DROP TRIGGER memories_fts_ai`,
+		`INSERT INTO memories (id, namespace, scope, content, created_at, last_accessed_at) VALUES ('m-ghost', 'org/repo/task', 'long', 'gamma ghost', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z')`,
+	} {
+		if _, err := st.db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("simulate drift: %v", err)
+		}
+	}
+	st.Close()
+
+	st, err = OpenSQLite(ctx, dbPath, logger)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer st.Close()
+	if err := st.CheckFTS(ctx); err != nil {
+		t.Fatalf("CheckFTS() after reopen error = %v", err)
+	}
+	if got := ids("gamma"); len(got) != 1 {
+		t.Fatalf("expected drifted row to be indexed after repair, got %v", got)
+	}
+}
+
+func TestSQLiteStore_RepairsFTSDriftWithMatchingCounts(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	dbPath := filepath.Join(t.TempDir(), "drift.db")
+	st, err := OpenSQLite(ctx, dbPath, logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	if !st.ftsEnabled {
+		st.Close()
+		t.Skip("FTS5 unavailable")
+	}
+	now := time.Now().UTC()
+	if _, err := st.InsertMemory(ctx, types.MemoryRecord{ID: "m-old", Namespace: "org/repo/task", Scope: "long", Content: "alpha old", CreatedAt: now, LastAccessedAt: now}); err != nil {
+		t.Fatalf("InsertMemory() error = %v", err)
+	}
+	// One unindexed insert and one unindexed delete leave the counts equal.
+	for _, stmt := range []string{
+		`DROP TRIGGER memories_fts_ai`,
+		`DROP TRIGGER memories_fts_ad`,
+		`INSERT INTO memories (id, namespace, scope, content, search_text, created_at, last_accessed_at) VALUES ('m-new', 'org/repo/task', 'long', 'gamma new', 'gamma new', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z')`,
+		`DELETE FROM memories WHERE id = 'm-old'`,
+	} {
+		if _, err := st.db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("simulate drift: %v", err)
+		}
+	}
+	st.Close()
+
+	st, err = OpenSQLite(ctx, dbPath, logger)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer st.Close()
+	if err := st.CheckFTS(ctx); err != nil {
+		t.Fatalf("CheckFTS() after reopen error = %v", err)
+	}
+	cands, err := st.searchFTS(ctx, SearchQuery{Namespace: "org/repo/task", Limit: 10, Now: now}, buildFTSMatchQuery([]string{"gamma"}))
+	if err != nil || len(cands) != 1 || cands[0].Record.ID != "m-new" {
+		t.Fatalf("expected the unindexed row to be indexed after repair, got %v, %v", cands, err)
+	}
+}
+
+func TestSQLiteStore_ReadPoolSeesWritesAndRejectsMutation(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "pool.db"), logger, WithReadConns(3))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	if st.reader == st.db {
+		t.Fatal("expected a separate read pool")
+	}
+
+	now := time.Now().UTC()
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 20; i++ {
+		rec := types.MemoryRecord{
+			ID:             fmt.Sprintf("m-%d", i),
+			Namespace:      "org/repo/task",
+			Scope:          "long",
+			Content:        fmt.Sprintf("pooled read %d", i),
+			CreatedAt:      now,
+			LastAccessedAt: now,
+		}
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := st.InsertMemory(ctx, rec)
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := st.SearchCandidates(ctx, SearchQuery{Namespace: "org/repo/task", Query: "pooled", Limit: 10, Now: now})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent access error = %v", err)
+		}
+	}
+
+	if _, err := st.GetMemory(ctx, "m-19"); err != nil {
+		t.Fatalf("GetMemory() after write error = %v", err)
+	}
+	if _, err := st.reader.ExecContext(ctx, `DELETE FROM memories`); err == nil {
+		t.Fatal("expected read pool to reject writes")
+	}
+}
+
+func TestSQLiteStore_WritePoolWaitsForTheWriteLock(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	dbPath := filepath.Join(t.TempDir(), "writers.db")
+	st, err := OpenSQLite(ctx, dbPath, logger, WithWriteConns(4), WithReadConns(2), WithBusyTimeout(2*time.Second))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	for name, db := range map[string]*sql.DB{"writer": st.db, "reader": st.reader} {
+		var timeout int
+		if err := db.QueryRowContext(ctx, `PRAGMA busy_timeout`).Scan(&timeout); err != nil || timeout != 2000 {
+			t.Fatalf("%s busy_timeout = %d, %v, want 2000", name, timeout, err)
+		}
+	}
+	var mode string
+	if err := st.db.QueryRowContext(ctx, `PRAGMA journal_mode`).Scan(&mode); err != nil || mode != "wal" {
+		t.Fatalf("journal_mode = %q, %v, want wal", mode, err)
+	}
+
+	// A second handle stands in for another process writing concurrently.
+	other, err := OpenSQLite(ctx, dbPath, logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite(second handle) error = %v", err)
+	}
+	defer other.Close()
+	now := time.Now().UTC()
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := range 40 {
+		target := st
+		if i%4 == 0 {
+			target = other
+		}
+		rec := types.MemoryRecord{
+			ID:             fmt.Sprintf("m-%d", i),
+			Namespace:      "org/repo/task",
+			Scope:          "long",
+			Content:        fmt.Sprintf("contended write %d", i),
+			CreatedAt:      now,
+			LastAccessedAt: now,
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := target.InsertMemory(ctx, rec)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent write error = %v", err)
+		}
+	}
+	counts, err := st.CountBy(ctx, GroupNamespace, ListFilter{})
+	if err != nil || len(counts) != 1 || counts[0].Count != 40 {
+		t.Fatalf("CountBy() = %+v, %v, want 40 memories", counts, err)
+	}
+}
+
+func benchStore(b *testing.B, rows int, opts ...Option) *SQLiteStore {
+	b.Helper()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	st, err := OpenSQLite(ctx, filepath.Join(b.TempDir(), "bench.db"), logger, opts...)
+	if err != nil {
+		b.Fatalf("OpenSQLite() error = %v", err)
+	}
+	b.Cleanup(func() { _ = st.Close() })
+
+	now := time.Now().UTC()
+	for i := 0; i < rows; i++ {
+		rec := types.MemoryRecord{
+			ID:             fmt.Sprintf("m-%d", i),
+			Namespace:      "org/repo/bench",
+			Scope:          "long",
+			Content:        fmt.Sprintf("note %d about deploy cache %d and schema migration %d", i, i%17, i%29),
+			Importance:     3,
+			CreatedAt:      now,
+			LastAccessedAt: now,
+		}
+		if _, err := st.InsertMemory(ctx, rec); err != nil {
+			b.Fatalf("InsertMemory() error = %v", err)
+		}
+	}
+	return st
+}
+
+func BenchmarkSQLiteStore_InsertMemory(b *testing.B) {
+	st := benchStore(b, 0)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rec := types.MemoryRecord{
+			ID:             fmt.Sprintf("b-%d", i),
+			Namespace:      "org/repo/bench",
+			Scope:          "short",
+			Content:        "benchmark insert payload with a handful of searchable words",
+			CreatedAt:      now,
+			LastAccessedAt: now,
+		}
+		if _, err := st.InsertMemory(ctx, rec); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSQLiteStore_SearchFTS(b *testing.B) {
+	st := benchStore(b, 5000)
+	benchmarkSearch(b, st)
+}
+
+func BenchmarkSQLiteStore_SearchLIKE(b *testing.B) {
+	st := benchStore(b, 5000, WithoutFTS())
+	benchmarkSearch(b, st)
+}
+
+func benchmarkSearch(b *testing.B, st *SQLiteStore) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := st.SearchCandidates(ctx, SearchQuery{Namespace: "org/repo/bench", Query: "deploy migration", Limit: 30, Now: now}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestSQLiteStore_FTSIndexesConfiguredMetadataKeys(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	dbPath := filepath.Join(t.TempDir(), "meta.db")
+	now := time.Now().UTC()
+
+	st, err := OpenSQLite(ctx, dbPath, logger, WithFTSMetadataKeys("ticket", "files"))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	rec := types.MemoryRecord{
+		ID: "m-meta", Namespace: "org/repo/task", Scope: "long", Content: "fixed the login redirect",
+		Metadata:  map[string]any{"ticket": "PROJ-1234", "files": []any{"auth.go", "session.go"}, "owner": "dana"},
+		CreatedAt: now, LastAccessedAt: now,
+	}
+	if _, err := st.InsertMemory(ctx, rec); err != nil {
+		t.Fatalf("InsertMemory() error = %v", err)
+	}
+	for _, q := range []string{"PROJ-1234", "session.go"} {
+		cands, err := st.searchFTS(ctx, SearchQuery{Namespace: "org/repo/task", Limit: 10, Now: now}, buildFTSMatchQuery(tokenizeQueryTerms(q, nil)))
+		if err != nil || len(cands) != 1 {
+			t.Fatalf("search %q: expected metadata match, got %v, %v", q, cands, err)
+		}
+	}
+	cands, err := st.searchFTS(ctx, SearchQuery{Namespace: "org/repo/task", Limit: 10, Now: now}, buildFTSMatchQuery([]string{"dana"}))
+	if err != nil || len(cands) != 0 {
+		t.Fatalf("expected unconfigured key to stay unindexed, got %v, %v", cands, err)
+	}
+	st.Close()
+
+	st, err = OpenSQLite(ctx, dbPath, logger, WithFTSMetadataKeys("owner"))
+	if err != nil {
+		t.Fatalf("reopen OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	cands, err = st.searchFTS(ctx, SearchQuery{Namespace: "org/repo/task", Limit: 10, Now: now}, buildFTSMatchQuery([]string{"dana"}))
+	if err != nil || len(cands) != 1 {
+		t.Fatalf("expected re-derived metadata to be searchable, got %v, %v", cands, err)
+	}
+	cands, err = st.searchFTS(ctx, SearchQuery{Namespace: "org/repo/task", Limit: 10, Now: now}, buildFTSMatchQuery([]string{"session"}))
+	if err != nil || len(cands) != 0 {
+		t.Fatalf("expected dropped key to leave the index, got %v, %v", cands, err)
+	}
+	if err := st.CheckFTS(ctx); err != nil {
+		t.Fatalf("CheckFTS() error = %v", err)
+	}
+}
+
+func TestSQLiteStore_MetadataColumnsAreIndexedAndDropped(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	dbPath := filepath.Join(t.TempDir(), "metacols.db")
+
+	st, err := OpenSQLite(ctx, dbPath, logger, WithMetadataColumns("ticket"))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	q, args := listMemoriesQuery(ListFilter{Metadata: map[string]string{"ticket": "OPS-7"}}, st.metaCols)
+	var plan []string
+	rows, err := st.db.QueryContext(ctx, "EXPLAIN QUERY PLAN "+q, args...)
+	if err != nil {
+		t.Fatalf("EXPLAIN error = %v", err)
+	}
+	for rows.Next() {
+		var id, parent, notused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+			t.Fatalf("scan plan: %v", err)
+		}
+		plan = append(plan, detail)
+	}
+	rows.Close()
+	if joined := strings.Join(plan, "\n"); !strings.Contains(joined, "idx_memories_md_ticket") {
+		t.Fatalf("expected plan to use idx_memories_md_ticket, got:\n%s", joined)
+	}
+	st.Close()
+
+	st, err = OpenSQLite(ctx, dbPath, logger)
+	if err != nil {
+		t.Fatalf("reopen OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	var n int
+	if err := st.db.QueryRowContext(ctx, `SELECT count(*) FROM pragma_table_xinfo('memories') WHERE name = 'md_ticket'`).Scan(&n); err != nil {
+		t.Fatalf("inspect columns: %v", err)
+	}
+	if n != 0 {
+		t.Fatal("expected unconfigured metadata column to be dropped")
+	}
+
+	if _, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "bad.db"), logger, WithMetadataColumns("bad-key")); err == nil {
+		t.Fatal("expected invalid metadata column key to fail")
+	}
+}
+
+func TestSQLiteStore_IndexHealthReportsCoverageAndReindex(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	st, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "health.db"), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	if !st.ftsEnabled {
+		t.Skip("FTS5 unavailable")
+	}
+	now := time.Now().UTC()
+	if _, err := st.InsertMemory(ctx, types.MemoryRecord{ID: "m1", Namespace: "org/repo", Scope: "long", Content: "health check",
+		Importance: 3, CreatedAt: now, LastAccessedAt: now}); err != nil {
+		t.Fatalf("InsertMemory() error = %v", err)
+	}
+
+	h, err := st.IndexHealth(ctx)
+	if err != nil {
+		t.Fatalf("IndexHealth() error = %v", err)
+	}
+	if !h.FTSEnabled || h.Memories != 1 || h.FTSRows != 1 || !h.InSync() || h.LastReindex.IsZero() {
+		t.Fatalf("unexpected health %+v", h)
+	}
+	names := map[string]bool{}
+	for _, o := range h.Sizes {
+		names[o.Name] = o.Bytes > 0
+	}
+	if !names["memories"] || !names["idx_memories_created_at"] {
+		t.Fatalf("expected table and index sizes, got %+v", h.Sizes)
+	}
+
+	before := h.LastReindex
+	time.Sleep(2 * time.Millisecond)
+	if err := st.RebuildFTS(ctx); err != nil {
+		t.Fatalf("RebuildFTS() error = %v", err)
+	}
+	if h, err = st.IndexHealth(ctx); err != nil || !h.LastReindex.After(before) {
+		t.Fatalf("expected RebuildFTS to record a reindex, got %v (before %v), %v", h.LastReindex, before, err)
+	}
+}
+
+func TestSQLiteStore_ServerLogsNewestFirst(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	st, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "logs.db"), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := st.InsertServerLogs(ctx, []ServerLog{
+		{Level: "info", Message: "starting MCP stdio server", CreatedAt: base},
+		{Level: "warn", Message: "fts query failed; fallback to LIKE", CreatedAt: base.Add(time.Second)},
+	}); err != nil {
+		t.Fatalf("InsertServerLogs() error = %v", err)
+	}
+
+	logs, err := st.RecentServerLogs(ctx, 5)
+	if err != nil {
+		t.Fatalf("RecentServerLogs() error = %v", err)
+	}
+	if len(logs) != 2 || logs[0].Level != "warn" || logs[1].Message != "starting MCP stdio server" || !logs[1].CreatedAt.Equal(base) {
+		t.Fatalf("unexpected server logs %+v", logs)
+	}
+}
+
+func TestSQLiteStore_RequestsByClient(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	st, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "clients.db"), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, rec := range []MCPRequestLog{
+		{Method: "tools/call", ToolName: "memory_search", Success: true, ClientName: "codex", ClientVersion: "1.2"},
+		{Method: "tools/call", ToolName: "memory_write", Success: false, ErrorText: "boom", ClientName: "codex", ClientVersion: "1.2"},
+		{Method: "initialize", Success: true, ClientName: "claude-code", ClientVersion: "2.0"},
+		{Method: "ping", Success: true},
+	} {
+		rec.CreatedAt = base.Add(time.Duration(i) * time.Second)
+		if err := st.InsertMCPRequestLog(ctx, rec); err != nil {
+			t.Fatalf("InsertMCPRequestLog() error = %v", err)
+		}
+	}
+
+	clients, err := st.RequestsByClient(ctx, 10)
+	if err != nil {
+		t.Fatalf("RequestsByClient() error = %v", err)
+	}
+	if len(clients) != 3 || clients[0].Client != "codex" || clients[0].Requests != 2 || clients[0].Errors != 1 || !clients[0].LastSeen.Equal(base.Add(time.Second)) {
+		t.Fatalf("unexpected client counts %+v", clients)
+	}
+	logs, err := st.RecentMCPRequestLogs(ctx, 2)
+	if err != nil || logs[1].ClientName != "claude-code" || logs[1].ClientVersion != "2.0" {
+		t.Fatalf("expected client info in request logs, got %+v, %v", logs, err)
+	}
+}
+
+func TestSQLiteStore_GetMCPRequestLogKeepsParams(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	st, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "params.db"), log.NewWithOptions(io.Discard, log.Options{}))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+
+	params := `{"arguments":{"query":"auth"},"name":"memory_search"}`
+	if err := st.InsertMCPRequestLog(ctx, MCPRequestLog{Method: "tools/call", ToolName: "memory_search", Success: true, ClientName: "codex", Params: params}); err != nil {
+		t.Fatalf("InsertMCPRequestLog() error = %v", err)
+	}
+	logs, err := st.RecentMCPRequestLogs(ctx, 1)
+	if err != nil || len(logs) != 1 {
+		t.Fatalf("RecentMCPRequestLogs() = %+v, %v", logs, err)
+	}
+	got, err := st.GetMCPRequestLog(ctx, logs[0].ID)
+	if err != nil {
+		t.Fatalf("GetMCPRequestLog() error = %v", err)
+	}
+	if got.Params != params || got.ToolName != "memory_search" || got.ClientName != "codex" {
+		t.Fatalf("unexpected request log %+v", got)
+	}
+	if _, err := st.GetMCPRequestLog(ctx, logs[0].ID+1); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetMCPRequestLog(missing) error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestSQLiteStore_SearchMatchesUnicodeVariants(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	now := time.Now().UTC()
+	for _, opts := range [][]Option{nil, {WithoutFTS()}} {
+		dbPath := filepath.Join(t.TempDir(), "unicode.db")
+		st, err := OpenSQLite(ctx, dbPath, logger, opts...)
+		if err != nil {
+			t.Fatalf("OpenSQLite() error = %v", err)
+		}
+		if _, err := st.InsertMemories(ctx, []types.MemoryRecord{
+			{ID: "m-width", Namespace: "org/repo/task", Scope: "long", Content: "ＡＰＩ gateway retries", CreatedAt: now, LastAccessedAt: now},
+			{ID: "m-accent", Namespace: "org/repo/task", Scope: "long", Content: "Cafe\u0301 menu sync", CreatedAt: now, LastAccessedAt: now},
+		}); err != nil {
+			t.Fatalf("InsertMemories() error = %v", err)
+		}
+		for query, want := range map[string]string{"api GATEWAY": "m-width", "caf\u00e9": "m-accent", "CAF\u00c9 MENU": "m-accent"} {
+			cands, err := st.SearchCandidates(ctx, SearchQuery{Namespace: "org/repo/task", Query: query, Limit: 10, Now: now})
+			if err != nil || len(cands) != 1 || cands[0].Record.ID != want {
+				t.Fatalf("SearchCandidates(%q) with %d options = %+v, %v, want %s", query, len(opts), cands, err, want)
+			}
+		}
+		st.Close()
+	}
+}
+
+func TestSQLiteStore_AdvancedQuery(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	now := time.Now().UTC()
+	st, err := OpenSQLite(ctx, filepath.Join(t.TempDir(), "advanced.db"), logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	if !st.ftsEnabled {
+		t.Skip("FTS5 unavailable")
+	}
+	if _, err := st.InsertMemories(ctx, []types.MemoryRecord{
+		{ID: "m-lag", Namespace: "org/repo/task", Scope: "long", Content: "kafka lag alert", CreatedAt: now, LastAccessedAt: now},
+		{ID: "m-vacuum", Namespace: "org/repo/task", Scope: "long", Content: "vacuum timeout", CreatedAt: now, LastAccessedAt: now},
+		{ID: "m-timeout", Namespace: "org/repo/task", Scope: "long", Content: "kafka timeout", CreatedAt: now, LastAccessedAt: now},
+	}); err != nil {
+		t.Fatalf("InsertMemories() error = %v", err)
+	}
+	for query, want := range map[string][]string{
+		"kafka OR vacuum":      {"m-lag", "m-timeout", "m-vacuum"},
+		"kafka NOT timeout":    {"m-lag"},
+		"NEAR(vacuum timeout)": {"m-vacuum"},
+		"vac*":                 {"m-vacuum"},
+		"search_text:(lag)":    {"m-lag"},
+		"kafka timeout":        {"m-timeout"},
+		`"timeout kafka"`:      nil,
+	} {
+		cands, err := st.SearchCandidates(ctx, SearchQuery{Namespace: "org/repo/task", Query: query, Advanced: true, Limit: 10, Now: now})
+		if err != nil {
+			t.Fatalf("SearchCandidates(%q) error = %v", query, err)
+		}
+		var got []string
+		for _, c := range cands {
+			got = append(got, c.Record.ID)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Fatalf("SearchCandidates(%q) = %v, want %v", query, got, want)
+		}
+	}
+	for _, query := range []string{"kafka AND", `"kafka lag`, "nosuch:kafka", ""} {
+		if _, err := st.SearchCandidates(ctx, SearchQuery{Namespace: "org/repo/task", Query: query, Advanced: true, Limit: 10, Now: now}); !errors.Is(err, ErrQuerySyntax) {
+			t.Fatalf("SearchCandidates(%q) error = %v, want ErrQuerySyntax", query, err)
+		}
+	}
+
+	mem := NewMemoryStore()
+	if _, err := mem.SearchCandidates(ctx, SearchQuery{Query: "kafka OR lag", Advanced: true, Limit: 10, Now: now}); !errors.Is(err, ErrAdvancedQueryUnsupported) {
+		t.Fatalf("MemoryStore.SearchCandidates() error = %v, want ErrAdvancedQueryUnsupported", err)
+	}
+}
+
+func TestSQLiteStore_UpgradesFTSToNormalizedText(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	dbPath := filepath.Join(t.TempDir(), "upgrade.db")
+	st, err := OpenSQLite(ctx, dbPath, logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	if !st.ftsEnabled {
+		st.Close()
+		t.Skip("FTS5 unavailable")
+	}
+	// Recreate the index over raw content as earlier versions did, with a
+	// row written before search_text existed.
+	for _, stmt := range []string{
+		`DROP TRIGGER memories_fts_ai`,
+		`DROP TRIGGER memories_fts_ad`,
+		`DROP TRIGGER memories_fts_au`,
+		`DROP TABLE memories_fts`,
+		`CREATE VIRTUAL TABLE memories_fts USING fts5(content, summary, meta_text, content='memories')`,
+		`INSERT INTO memories (id, namespace, scope, content, created_at, last_accessed_at) VALUES ('m-old', 'org/repo/task', 'long', 'Ｒｅｌｅａｓｅ checklist', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z')`,
+		`INSERT INTO memories_fts(memories_fts) VALUES ('rebuild')`,
+	} {
+		if _, err := st.db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("simulate old schema: %v", err)
+		}
+	}
+	st.Close()
+
+	st, err = OpenSQLite(ctx, dbPath, logger)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer st.Close()
+	if err := st.CheckFTS(ctx); err != nil {
+		t.Fatalf("CheckFTS() error = %v", err)
+	}
+	cands, err := st.searchFTS(ctx, SearchQuery{Namespace: "org/repo/task", Limit: 10, Now: time.Now()}, buildFTSMatchQuery(stemTerms(tokenizeQueryTerms("release", nil))))
+	if err != nil || len(cands) != 1 || cands[0].Record.ID != "m-old" {
+		t.Fatalf("expected upgraded index to match folded text, got %+v, %v", cands, err)
+	}
+	if rec, err := st.GetMemory(ctx, "m-old"); err != nil || rec.Language != "en" {
+		t.Fatalf("GetMemory() language = %q, %v, want backfilled en", rec.Language, err)
+	}
+}
+
+func TestSQLiteStore_BackupAndRestore(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	dir := t.TempDir()
+	st, err := OpenSQLite(ctx, filepath.Join(dir, "m.db"), logger, WithRecordCache(8, time.Minute))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+
+	now := time.Now().UTC()
+	for _, id := range []string{"m-first", "m-second"} {
+		rec := types.MemoryRecord{
+			ID: id, Namespace: "org/repo/task", Scope: "long", Content: "canary rollout checklist " + id,
+			CreatedAt: now, LastAccessedAt: now,
+		}
+		if _, err := st.InsertMemory(ctx, rec); err != nil {
+			t.Fatalf("InsertMemory() error = %v", err)
+		}
+	}
+	snap := filepath.Join(dir, "snap.db")
+	if err := st.Backup(ctx, snap); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	if err := st.Backup(ctx, snap); err == nil {
+		t.Fatal("Backup() over an existing file error = nil")
+	}
+
+	if _, err := st.DeleteMemories(ctx, []string{"m-first"}); err != nil {
+		t.Fatalf("DeleteMemories() error = %v", err)
+	}
+	if _, err := st.GetMemory(ctx, "m-second"); err != nil {
+		t.Fatalf("GetMemory() error = %v", err)
+	}
+	if err := st.Restore(ctx, snap); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if _, err := st.GetMemory(ctx, "m-first"); err != nil {
+		t.Fatalf("GetMemory() after restore error = %v", err)
+	}
+	cands, err := st.SearchCandidates(ctx, SearchQuery{Namespace: "org/repo/task", Query: "canary", Limit: 10, Now: now})
+	if err != nil {
+		t.Fatalf("SearchCandidates() error = %v", err)
+	}
+	if len(cands) != 2 {
+		t.Fatalf("SearchCandidates() after restore = %d results, want 2", len(cands))
+	}
+
+	alt := filepath.Join(dir, "alt.db")
+	if err := CopySQLite(ctx, snap, alt); err != nil {
+		t.Fatalf("CopySQLite() error = %v", err)
+	}
+	copied, err := OpenSQLite(ctx, alt, logger)
+	if err != nil {
+		t.Fatalf("OpenSQLite(copy) error = %v", err)
+	}
+	defer copied.Close()
+	if _, err := copied.GetMemory(ctx, "m-first"); err != nil {
+		t.Fatalf("GetMemory() on copy error = %v", err)
+	}
+}
+
+func TestSQLiteStore_ReadOnlyOpen(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	logger := log.NewWithOptions(io.Discard, log.Options{})
+	dbPath := filepath.Join(t.TempDir(), "m.db")
+	if _, err := OpenSQLite(ctx, dbPath, logger, WithReadOnly(false)); err == nil {
+		t.Fatal("OpenSQLite(read-only, missing file) error = nil")
+	}
+
+	st, err := OpenSQLite(ctx, dbPath, logger, WithMetadataColumns("ticket"))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer st.Close()
+	now := time.Now().UTC()
+	rec := types.MemoryRecord{
+		ID: "m-ro", Namespace: "org/repo/task", Scope: "long", Content: "canary rollout checklist",
+		Metadata: map[string]any{"ticket": "OPS-1"}, CreatedAt: now, LastAccessedAt: now,
+	}
+	if _, err := st.InsertMemory(ctx, rec); err != nil {
+		t.Fatalf("InsertMemory() error = %v", err)
+	}
+
+	// An immutable handle ignores the write-ahead log, so it reads a
+	// snapshot rather than the live file.
+	snap := filepath.Join(t.TempDir(), "snap.db")
+	if err := st.Backup(ctx, snap); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	for path, immutable := range map[string]bool{dbPath: false, snap: true} {
+		ro, err := OpenSQLite(ctx, path, logger, WithReadOnly(immutable), WithReadConns(2), WithMetadataColumns("ticket"))
+		if err != nil {
+			t.Fatalf("OpenSQLite(read-only) error = %v", err)
+		}
+		if h, err := ro.IndexHealth(ctx); err != nil || !h.FTSEnabled || h.Memories != 1 {
+			t.Fatalf("IndexHealth() = %+v, %v, want FTS with 1 memory", h, err)
+		}
+		cands, err := ro.SearchCandidates(ctx, SearchQuery{Namespace: "org/repo/task", Query: "canary", Now: now})
+		if err != nil || len(cands) != 1 {
+			t.Fatalf("SearchCandidates() = %d, %v, want 1", len(cands), err)
+		}
+		got, err := ro.ListMemories(ctx, ListFilter{Metadata: map[string]string{"ticket": "OPS-1"}})
+		if err != nil || len(got) != 1 {
+			t.Fatalf("ListMemories(ticket) = %d, %v, want 1", len(got), err)
+		}
+		if _, err := ro.DeleteMemories(ctx, []string{"m-ro"}); err == nil {
+			t.Fatal("DeleteMemories() on a read-only store error = nil")
+		}
+		ro.Close()
+	}
+}
@@ -0,0 +1,27 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ExpiryExtender is implemented by stores that can push back the expiry of
+// a short-term memory without rewriting the rest of it.
+type ExpiryExtender interface {
+	// ExtendExpiry sets the expiry of short-term memory id to expiresAt and
+	// its last access to now. Long-term and missing memories are skipped.
+	ExtendExpiry(ctx context.Context, id string, expiresAt, now time.Time) error
+}
+
+// ExtendExpiry updates the two timestamps only, so the FTS index is left
+// alone.
+func (s *SQLiteStore) ExtendExpiry(ctx context.Context, id string, expiresAt, now time.Time) error {
+	const q = `UPDATE memories SET expires_at = ?, last_accessed_at = ? WHERE id = ? AND scope = 'short'`
+	_, err := s.db.ExecContext(ctx, q, expiresAt.UTC().Format(time.RFC3339Nano), now.UTC().Format(time.RFC3339Nano), id)
+	s.uncacheRecords(id)
+	if err != nil {
+		return fmt.Errorf("extend memory expiry: %w", err)
+	}
+	return nil
+}
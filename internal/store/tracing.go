@@ -0,0 +1,27 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/XSAM/otelsql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+)
+
+// openTraced is sql.Open with every statement traced as a span of the
+// global OpenTelemetry tracer provider, which discards them until tracing
+// is configured.
+func openTraced(driverName, dsn string) (*sql.DB, error) {
+	system := semconv.DBSystemNameSQLite
+	if driverName == "pgx" {
+		system = semconv.DBSystemNamePostgreSQL
+	}
+	return otelsql.Open(driverName, dsn,
+		otelsql.WithAttributes(system),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitConnPrepare:      true,
+			OmitConnectorConnect: true,
+			OmitRows:             true,
+		}),
+	)
+}
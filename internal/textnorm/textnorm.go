@@ -0,0 +1,38 @@
+// Package textnorm normalizes Unicode so text that differs only in how a
+// client encoded it is stored, indexed and matched alike.
+package textnorm
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Canonical returns s in NFC, the form stored text is kept in. It only
+// composes equivalent sequences (e + U+0301 becomes é) and never changes
+// how the text reads.
+func Canonical(s string) string {
+	return norm.NFC.String(s)
+}
+
+// Fold returns the search key for s: NFKC-normalized and case-folded, so
+// decomposed accents, full-width forms, ligatures and case all compare
+// equal. Indexed text and query terms must both go through Fold.
+func Fold(s string) string {
+	if isASCII(s) {
+		return strings.ToLower(s)
+	}
+	// Casers are stateful, so one is made per call.
+	return norm.NFKC.String(cases.Fold().String(norm.NFKC.String(s)))
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
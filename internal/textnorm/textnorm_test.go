@@ -0,0 +1,30 @@
+package textnorm
+
+import "testing"
+
+func TestFold_MatchesEncodingVariants(t *testing.T) {
+	t.Parallel()
+	cases := []struct{ a, b string }{
+		{"cafe\u0301", "CAF\u00c9"},
+		{"ＡＰＩ Ｇａｔｅｗａｙ", "api gateway"},
+		{"ﬁle", "FILE"},
+		{"Straße", "STRASSE"},
+		{"ΣΊΣΥΦΟΣ", "σίσυφος"},
+		{"ｶﾀｶﾅ", "カタカナ"},
+	}
+	for _, tc := range cases {
+		if Fold(tc.a) != Fold(tc.b) {
+			t.Fatalf("Fold(%q) = %q, Fold(%q) = %q; want equal", tc.a, Fold(tc.a), tc.b, Fold(tc.b))
+		}
+	}
+}
+
+func TestCanonical_ComposesWithoutCompatibilityMapping(t *testing.T) {
+	t.Parallel()
+	if got := Canonical("cafe\u0301"); got != "caf\u00e9" {
+		t.Fatalf("Canonical() = %q, want composed é", got)
+	}
+	if got := Canonical("ＡＰＩ ﬁle"); got != "ＡＰＩ ﬁle" {
+		t.Fatalf("Canonical() = %q, want width and ligatures kept", got)
+	}
+}
@@ -0,0 +1,70 @@
+// Package tracing exports OpenTelemetry spans over OTLP/HTTP. Packages
+// instrument themselves through the global tracer provider, which discards
+// spans until Start installs an exporting one.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+
+	"github.com/xiy/memory-mcp/internal/config"
+)
+
+// tracesPath is appended to an endpoint given without a path, as for
+// OTEL_EXPORTER_OTLP_ENDPOINT.
+const tracesPath = "/v1/traces"
+
+// Start installs a global tracer provider exporting to cfg.Endpoint and
+// returns a func that flushes buffered spans and stops exporting. It is a
+// no-op when tracing is disabled. Export errors are logged, not returned.
+func Start(ctx context.Context, cfg config.TracingConfig, version string, logger *log.Logger) (func(context.Context) error, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+	var opts []otlptracehttp.Option
+	if cfg.Endpoint != "" {
+		u, err := url.Parse(cfg.Endpoint)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid tracing.endpoint %q (expected a URL like http://localhost:4318)", cfg.Endpoint)
+		}
+		if strings.Trim(u.Path, "/") == "" {
+			u.Path = tracesPath
+		}
+		opts = append(opts, otlptracehttp.WithEndpointURL(u.String()))
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(cfg.ServiceName), semconv.ServiceVersion(version)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tracing resource: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn("exporting traces failed", "error", err)
+	}))
+	logger.Info("tracing enabled", "endpoint", cfg.Endpoint, "sample_ratio", cfg.SampleRatio)
+	return tp.Shutdown, nil
+}
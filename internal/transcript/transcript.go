@@ -0,0 +1,230 @@
+// Package transcript records MCP stdio sessions and replays them against a
+// server so protocol and ranking regressions show up as byte differences.
+//
+// A transcript is a text file with one message per line: "> " precedes a
+// message the client sent and "< " one the server wrote, exactly as it was
+// written. Lines starting with "#" before the first message are kept as
+// comments; blank lines are ignored.
+package transcript
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Direction says which side sent a message.
+type Direction byte
+
+// Directions, written as the line marker.
+const (
+	Client Direction = '>'
+	Server Direction = '<'
+)
+
+// Entry is one message of a session.
+type Entry struct {
+	From    Direction
+	Payload []byte
+}
+
+// Transcript is a recorded session.
+type Transcript struct {
+	Comments []string
+	Entries  []Entry
+}
+
+// Parse reads a transcript written by Writer or WriteTo.
+func Parse(r io.Reader) (Transcript, error) {
+	var t Transcript
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), 16<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		switch {
+		case strings.TrimSpace(line) == "":
+		case strings.HasPrefix(line, "#"):
+			if len(t.Entries) == 0 {
+				t.Comments = append(t.Comments, line)
+			}
+		case len(line) > 2 && (line[0] == byte(Client) || line[0] == byte(Server)) && line[1] == ' ':
+			t.Entries = append(t.Entries, Entry{From: Direction(line[0]), Payload: []byte(line[2:])})
+		default:
+			return Transcript{}, fmt.Errorf("transcript line %d: expected \"> \" or \"< \" prefix", n)
+		}
+	}
+	return t, sc.Err()
+}
+
+// WriteTo writes t in the format Parse reads.
+func (t Transcript) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	for _, c := range t.Comments {
+		buf.WriteString(c)
+		buf.WriteByte('\n')
+	}
+	for _, e := range t.Entries {
+		writeEntry(&buf, e.From, e.Payload)
+	}
+	return buf.WriteTo(w)
+}
+
+// Writer appends messages to a transcript as a session runs. It is safe
+// for concurrent use.
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriter returns a Writer recording to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Record appends one message. Client messages are compacted so framed,
+// pretty-printed requests still fit on one line.
+func (tw *Writer) Record(from Direction, payload []byte) error {
+	var buf bytes.Buffer
+	writeEntry(&buf, from, payload)
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	_, err := buf.WriteTo(tw.w)
+	return err
+}
+
+func writeEntry(buf *bytes.Buffer, from Direction, payload []byte) {
+	buf.WriteByte(byte(from))
+	buf.WriteByte(' ')
+	if from == Client {
+		var compact bytes.Buffer
+		if json.Compact(&compact, payload) == nil {
+			payload = compact.Bytes()
+		} else {
+			payload = bytes.ReplaceAll(payload, []byte("\n"), []byte(" "))
+		}
+	}
+	buf.Write(payload)
+	buf.WriteByte('\n')
+}
+
+// ServeFunc runs one session over JSON-lines streams until in is closed.
+type ServeFunc func(ctx context.Context, in io.Reader, out io.Writer) error
+
+// Replay sends the client messages of t to serve one at a time and
+// returns the session as it happened. After each request it waits for the
+// server's response with the same id; notifications and replies to server
+// pings are not waited for.
+func Replay(ctx context.Context, serve ServeFunc, t Transcript) (Transcript, error) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := serve(ctx, inR, outW)
+		inR.Close()
+		outW.CloseWithError(err)
+		done <- err
+	}()
+	lines := make(chan []byte, 64)
+	go func() {
+		defer close(lines)
+		sc := bufio.NewScanner(outR)
+		sc.Buffer(make([]byte, 0, 64<<10), 16<<20)
+		for sc.Scan() {
+			lines <- bytes.Clone(sc.Bytes())
+		}
+	}()
+
+	got := Transcript{Comments: t.Comments}
+	var err error
+	for _, e := range t.Entries {
+		if e.From != Client {
+			continue
+		}
+		got.Entries = append(got.Entries, Entry{From: Client, Payload: e.Payload})
+		if _, err = inW.Write(append(bytes.Clone(e.Payload), '\n')); err != nil {
+			break
+		}
+		id, isRequest := requestID(e.Payload)
+		if !isRequest {
+			continue
+		}
+		if err = awaitResponse(ctx, lines, id, &got); err != nil {
+			break
+		}
+	}
+	inW.Close()
+	for line := range lines {
+		got.Entries = append(got.Entries, Entry{From: Server, Payload: line})
+	}
+	if serveErr := <-done; err == nil {
+		err = serveErr
+	}
+	return got, err
+}
+
+// requestID returns the id of a JSON-RPC request, which the server answers.
+func requestID(payload []byte) (json.RawMessage, bool) {
+	var msg struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	if json.Unmarshal(payload, &msg) != nil || len(msg.ID) == 0 || msg.Method == "" {
+		return nil, false
+	}
+	return msg.ID, true
+}
+
+func awaitResponse(ctx context.Context, lines <-chan []byte, id json.RawMessage, got *Transcript) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case line, ok := <-lines:
+			if !ok {
+				return fmt.Errorf("server closed the session before answering id %s", id)
+			}
+			got.Entries = append(got.Entries, Entry{From: Server, Payload: line})
+			var msg struct {
+				ID     json.RawMessage `json:"id"`
+				Method string          `json:"method"`
+			}
+			if json.Unmarshal(line, &msg) == nil && msg.Method == "" && bytes.Equal(msg.ID, id) {
+				return nil
+			}
+		}
+	}
+}
+
+// ErrMismatch is wrapped by Diff's error.
+var ErrMismatch = errors.New("transcript mismatch")
+
+// Diff compares a replayed session with the recorded one and describes the
+// first message that differs.
+func Diff(want, got Transcript) error {
+	for i := range max(len(want.Entries), len(got.Entries)) {
+		var w, g Entry
+		if i < len(want.Entries) {
+			w = want.Entries[i]
+		}
+		if i < len(got.Entries) {
+			g = got.Entries[i]
+		}
+		if w.From != g.From || !bytes.Equal(w.Payload, g.Payload) {
+			return fmt.Errorf("%w at message %d:\nwant %s\ngot  %s", ErrMismatch, i+1, describe(w), describe(g))
+		}
+	}
+	return nil
+}
+
+func describe(e Entry) string {
+	if e.From == 0 {
+		return "(end of session)"
+	}
+	return string(e.From) + " " + string(e.Payload)
+}
@@ -0,0 +1,78 @@
+package transcript
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// echoServe answers every request with its id and ignores notifications.
+func echoServe(_ context.Context, in io.Reader, out io.Writer) error {
+	sc := bufio.NewScanner(in)
+	for sc.Scan() {
+		if id, ok := requestID(sc.Bytes()); ok {
+			fmt.Fprintf(out, "{\"id\":%s,\"result\":{}}\n", id)
+		}
+	}
+	return sc.Err()
+}
+
+func TestWriterAndParse_RoundTrip(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	buf.WriteString("# handshake\n")
+	w := NewWriter(&buf)
+	if err := w.Record(Client, []byte("{\n  \"id\": 1,\n  \"method\": \"ping\"\n}")); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if err := w.Record(Server, []byte(`{"id":1,"result":{}}`)); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	tr, err := Parse(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(tr.Comments) != 1 || len(tr.Entries) != 2 || string(tr.Entries[0].Payload) != `{"id":1,"method":"ping"}` {
+		t.Fatalf("unexpected transcript %+v", tr)
+	}
+	var out bytes.Buffer
+	if _, err := tr.WriteTo(&out); err != nil || out.String() != buf.String() {
+		t.Fatalf("WriteTo() = %q, %v, want %q", out.String(), err, buf.String())
+	}
+	if _, err := Parse(strings.NewReader("? nonsense\n")); err == nil {
+		t.Fatal("expected an unprefixed line to be rejected")
+	}
+}
+
+func TestReplay_InterleavesResponsesAndDiffs(t *testing.T) {
+	t.Parallel()
+	want, err := Parse(strings.NewReader(`> {"id":1,"method":"initialize"}
+< {"id":1,"result":{}}
+> {"method":"notifications/initialized"}
+> {"id":2,"method":"ping"}
+< {"id":2,"result":{"changed":true}}
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	got, err := Replay(context.Background(), echoServe, want)
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if len(got.Entries) != 5 || got.Entries[1].From != Server || got.Entries[2].From != Client {
+		t.Fatalf("unexpected replay %+v", got.Entries)
+	}
+	err = Diff(want, got)
+	if !errors.Is(err, ErrMismatch) || !strings.Contains(err.Error(), "message 5") {
+		t.Fatalf("Diff() error = %v, want mismatch at message 5", err)
+	}
+	if err := Diff(got, got); err != nil {
+		t.Fatalf("Diff() of identical sessions error = %v", err)
+	}
+}
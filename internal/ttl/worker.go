@@ -28,7 +28,7 @@ func Start(ctx context.Context, logger *log.Logger, interval time.Duration, expi
 				continue
 			}
 			if n > 0 {
-				logger.Info("ttl cleanup removed expired short memories", "count", n)
+				logger.Info("ttl cleanup removed expired memories", "count", n)
 			}
 		}
 	}
@@ -16,6 +16,15 @@ type MemoryRecord struct {
 	LastAccessedAt time.Time      `json:"last_accessed_at"`
 	ExpiresAt      *time.Time     `json:"expires_at,omitempty"`
 	PromotedAt     *time.Time     `json:"promoted_at,omitempty"`
+	// Language is the ISO 639-1 code of the dominant language of content
+	// and summary, detected by the store on every write.
+	Language string `json:"language,omitempty"`
+	// ExternalKey is the client's identifier for the memory, unique within
+	// its namespace.
+	ExternalKey string `json:"external_key,omitempty"`
+	// AccessCount is how many times the memory was returned by a search or
+	// included in a context pack.
+	AccessCount int `json:"access_count,omitempty"`
 }
 
 // WriteInput describes a new memory write operation.
@@ -28,6 +37,9 @@ type WriteInput struct {
 	SourceAgent string         `json:"source_agent,omitempty"`
 	TTLSeconds  int            `json:"ttl_seconds,omitempty"`
 	Metadata    map[string]any `json:"metadata,omitempty"`
+	// ExternalKey makes the write an upsert: when the namespace already
+	// has a memory with this key, it is updated in place.
+	ExternalKey string `json:"external_key,omitempty"`
 }
 
 // SearchInput is used for search operations.
@@ -37,6 +49,27 @@ type SearchInput struct {
 	Scope           string `json:"scope,omitempty"`
 	K               int    `json:"k,omitempty"`
 	IncludeMetadata bool   `json:"include_metadata,omitempty"`
+	// Filter restricts results by metadata, e.g.
+	// `metadata.priority >= 2 and metadata.files contains "auth.go"`.
+	Filter string `json:"filter,omitempty"`
+	// SourceAgent identifies the searching agent for per-agent ranking.
+	SourceAgent string `json:"source_agent,omitempty"`
+	// WrittenBy keeps only memories whose source_agent equals it.
+	WrittenBy string `json:"written_by,omitempty"`
+	// Explain attaches a per-term score breakdown to each result.
+	Explain bool `json:"explain,omitempty"`
+	// IncludeAncestors also searches the parent namespaces, e.g. org/repo
+	// for org/repo/branch/task, and IncludeDescendants the namespaces
+	// below. Their memories rank lower the more levels away they are.
+	IncludeAncestors   bool `json:"include_ancestors,omitempty"`
+	IncludeDescendants bool `json:"include_descendants,omitempty"`
+	// CollapseSuperseded drops memories that another memory supersedes,
+	// through memory_link or a merge.
+	CollapseSuperseded bool `json:"collapse_superseded,omitempty"`
+	// AdvancedQuery passes Query to SQLite FTS5 as a match expression,
+	// e.g. `deploy* AND NEAR(rollback window, 5)`, instead of requiring
+	// every word.
+	AdvancedQuery bool `json:"advanced_query,omitempty"`
 }
 
 // SearchResult is a ranked item from search.
@@ -46,6 +79,49 @@ type SearchResult struct {
 	LexicalScore    float64      `json:"lexical_score"`
 	RecencyScore    float64      `json:"recency_score"`
 	ImportanceScore float64      `json:"importance_score"`
+	// FeedbackScore is the learned term from memory_feedback, in (-1, 1).
+	FeedbackScore float64 `json:"feedback_score,omitempty"`
+	// SemanticScore is the cosine similarity of the memory's embedding to
+	// the query's, when embeddings are configured.
+	SemanticScore float64 `json:"semantic_score,omitempty"`
+	// FrequencyScore grows with the memory's access count, in [0, 1).
+	FrequencyScore float64 `json:"frequency_score,omitempty"`
+	// Explanation is set when SearchInput.Explain is.
+	Explanation *ScoreExplanation `json:"explanation,omitempty"`
+	// Snippet is the part of the memory around its best run of matched
+	// words, which are wrapped in [ and ].
+	Snippet string `json:"snippet,omitempty"`
+	// MatchOffsets locate the matched words in the record's content, or
+	// its summary when nothing in the content matched.
+	MatchOffsets []MatchOffset `json:"match_offsets,omitempty"`
+}
+
+// MatchOffset is a matched word of a search result. Start and End are
+// rune offsets into Field, "content" or "summary", with End exclusive.
+type MatchOffset struct {
+	Field string `json:"field"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// ScoreExplanation breaks a result's score into the weighted contribution
+// of each ranking term; the terms sum to the score.
+type ScoreExplanation struct {
+	Lexical    float64 `json:"lexical"`
+	Semantic   float64 `json:"semantic,omitempty"`
+	Recency    float64 `json:"recency"`
+	Importance float64 `json:"importance"`
+	Frequency  float64 `json:"frequency,omitempty"`
+	Feedback   float64 `json:"feedback"`
+	Agent      float64 `json:"agent"`
+	// Namespace is the (negative) decay of a memory from an ancestor or
+	// descendant namespace.
+	Namespace float64 `json:"namespace,omitempty"`
+	// AgentTags splits Agent by the tag that earned it, and OwnAgent is
+	// the part earned by the searching agent having written the memory.
+	AgentTags   map[string]float64 `json:"agent_tags,omitempty"`
+	OwnAgent    float64            `json:"own_agent,omitempty"`
+	SourceAgent string             `json:"source_agent,omitempty"`
 }
 
 // ContextPackInput requests a compact context bundle.
@@ -55,13 +131,38 @@ type ContextPackInput struct {
 	TokenBudget int    `json:"token_budget"`
 	Scope       string `json:"scope,omitempty"`
 	K           int    `json:"k,omitempty"`
+	// SourceAgent identifies the requesting agent for per-agent ranking.
+	SourceAgent string `json:"source_agent,omitempty"`
+	// IncludeAncestors and IncludeDescendants widen the search as in
+	// SearchInput.
+	IncludeAncestors   bool `json:"include_ancestors,omitempty"`
+	IncludeDescendants bool `json:"include_descendants,omitempty"`
+	// Format is markdown, xml, json, plain or a name from
+	// context_pack.templates; empty uses context_pack.format.
+	Format string `json:"format,omitempty"`
+	// Sections groups the pack into durable knowledge, recent working
+	// notes and possibly stale memories, each with its own share of
+	// TokenBudget. context_pack.sections.enabled sections every pack.
+	Sections bool `json:"sections,omitempty"`
 }
 
 // ContextPack is optimized for prompt injection into agents.
 type ContextPack struct {
-	Text            string   `json:"text"`
+	Text string `json:"text"`
+	// Format is the format Text is rendered in.
+	Format          string   `json:"format"`
 	EstimatedTokens int      `json:"estimated_tokens"`
 	MemoryIDs       []string `json:"memory_ids"`
+	// Sections lists the sections of a sectioned pack that hold memories,
+	// in the order Text shows them.
+	Sections []ContextPackSection `json:"sections,omitempty"`
+}
+
+// ContextPackSection is one labeled group of a sectioned context pack.
+type ContextPackSection struct {
+	Name      string   `json:"name"`
+	Title     string   `json:"title"`
+	MemoryIDs []string `json:"memory_ids"`
 }
 
 // PromoteInput promotes an item to long-term memory.
@@ -69,4 +170,261 @@ type PromoteInput struct {
 	MemoryID    string `json:"memory_id"`
 	TargetScope string `json:"target_scope"`
 	Reason      string `json:"reason,omitempty"`
+	// SourceAgent is the agent requesting the promotion, kept in the
+	// promotion audit trail.
+	SourceAgent string `json:"source_agent,omitempty"`
+}
+
+// DemoteInput moves a long-term, project or global memory back to
+// short-term scope, e.g. when a durable decision was reversed.
+type DemoteInput struct {
+	MemoryID string `json:"memory_id"`
+	// TTLSeconds is the fresh TTL; 0 uses default_short_ttl_hours.
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
+	// SourceAgent is the agent requesting the demotion, kept in the audit
+	// log.
+	SourceAgent string `json:"source_agent,omitempty"`
+}
+
+// AppendInput adds an entry to the end of an existing memory's content.
+type AppendInput struct {
+	MemoryID string `json:"memory_id"`
+	Content  string `json:"content"`
+	// Delimiter separates the entry from existing content; default "\n\n".
+	Delimiter string `json:"delimiter,omitempty"`
+	// OmitTimestamp skips the "[<RFC 3339 time>] " prefix on the entry.
+	OmitTimestamp bool `json:"omit_timestamp,omitempty"`
+	// Summary replaces the summary; empty re-derives it from the content
+	// unless the current summary was written by hand.
+	Summary string `json:"summary,omitempty"`
+}
+
+// MergeInput consolidates several memories into one new record.
+type MergeInput struct {
+	MemoryIDs []string `json:"memory_ids"`
+	// Namespace of the merged record; defaults to the originals' shared
+	// namespace and is required when they differ.
+	Namespace   string `json:"namespace,omitempty"`
+	Summary     string `json:"summary,omitempty"`
+	SourceAgent string `json:"source_agent,omitempty"`
+	// ExpireOriginals hides the originals from search by expiring them now.
+	ExpireOriginals bool `json:"expire_originals,omitempty"`
+}
+
+// MergeResult reports the merged record and the originals it supersedes.
+type MergeResult struct {
+	Merged     MemoryRecord `json:"merged"`
+	Superseded []string     `json:"superseded"`
+	Expired    bool         `json:"expired"`
+}
+
+// FactsInput looks up extracted facts in a namespace. Subject and
+// predicate match exactly (case-insensitive); object matches a substring.
+type FactsInput struct {
+	Namespace string `json:"namespace"`
+	Subject   string `json:"subject,omitempty"`
+	Predicate string `json:"predicate,omitempty"`
+	Object    string `json:"object,omitempty"`
+	Limit     int    `json:"limit,omitempty"`
+}
+
+// AskInput is a natural-language question answered from one namespace.
+type AskInput struct {
+	Namespace string `json:"namespace"`
+	Question  string `json:"question"`
+	Scope     string `json:"scope,omitempty"`
+	// K bounds the memories consulted (default 8).
+	K int `json:"k,omitempty"`
+}
+
+// AskSource is a memory consulted for an answer.
+type AskSource struct {
+	ID      string  `json:"id"`
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+}
+
+// AskResult carries a synthesized answer when an LLM is configured, and
+// always the supporting memories. Citations lists source IDs the answer
+// refers to.
+type AskResult struct {
+	Question    string      `json:"question"`
+	Answer      string      `json:"answer,omitempty"`
+	Synthesized bool        `json:"synthesized"`
+	Citations   []string    `json:"citations"`
+	Sources     []AskSource `json:"sources"`
+	// LLMError explains why synthesis was skipped after an LLM failure.
+	LLMError string `json:"llm_error,omitempty"`
+}
+
+// FeedbackInput reports whether a memory returned for Query was useful
+// ("useful") or not ("irrelevant").
+type FeedbackInput struct {
+	MemoryID    string `json:"memory_id"`
+	Query       string `json:"query"`
+	Rating      string `json:"rating"`
+	SourceAgent string `json:"source_agent,omitempty"`
+}
+
+// FeedbackResult echoes the recorded rating and the memory's learned score
+// for the query after it.
+type FeedbackResult struct {
+	MemoryID string  `json:"memory_id"`
+	Rating   string  `json:"rating"`
+	Score    float64 `json:"score"`
+}
+
+// LinkInput records that FromID relates to ToID: supersedes, refines,
+// relates_to or derived_from.
+type LinkInput struct {
+	FromID      string `json:"from_id"`
+	ToID        string `json:"to_id"`
+	Relation    string `json:"relation"`
+	SourceAgent string `json:"source_agent,omitempty"`
+}
+
+// MemoryRelation is a recorded link between two memories.
+type MemoryRelation struct {
+	FromID      string    `json:"from_id"`
+	ToID        string    `json:"to_id"`
+	Relation    string    `json:"relation"`
+	SourceAgent string    `json:"source_agent,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ClusterInput groups a namespace's memories into topics.
+type ClusterInput struct {
+	Namespace string `json:"namespace"`
+	Scope     string `json:"scope,omitempty"`
+	// K is the number of clusters; 0 picks one from the memory count.
+	K int `json:"k,omitempty"`
+}
+
+// Cluster is one topic with a label, its size and a representative memory.
+type Cluster struct {
+	Label            string   `json:"label"`
+	Terms            []string `json:"terms"`
+	Size             int      `json:"size"`
+	RepresentativeID string   `json:"representative_id"`
+	Summary          string   `json:"summary"`
+	MemoryIDs        []string `json:"memory_ids"`
+}
+
+// ClusterResult lists a namespace's topics, largest first.
+type ClusterResult struct {
+	Namespace string    `json:"namespace"`
+	Memories  int       `json:"memories"`
+	Clusters  []Cluster `json:"clusters"`
+}
+
+// DuplicateGroup is a set of memories in one namespace that are exact or
+// near duplicates of each other, oldest first.
+type DuplicateGroup struct {
+	Namespace  string   `json:"namespace"`
+	MemoryIDs  []string `json:"memory_ids"`
+	Exact      bool     `json:"exact"`
+	Similarity float64  `json:"similarity"`
+	Summary    string   `json:"summary"`
+}
+
+// DeleteInput removes memories by ID. With DryRun set nothing is changed and
+// the result lists what would have been removed.
+type DeleteInput struct {
+	MemoryIDs []string `json:"memory_ids"`
+	DryRun    bool     `json:"dry_run,omitempty"`
+}
+
+// DeleteResult reports memories removed, or matched when DryRun is set.
+type DeleteResult struct {
+	DryRun   bool           `json:"dry_run"`
+	Count    int64          `json:"count"`
+	Memories []MemoryRecord `json:"memories"`
+	Missing  []string       `json:"missing,omitempty"`
+}
+
+// ListInput browses memories page by page, newest first by default. Zero
+// values do not filter.
+type ListInput struct {
+	// Namespace matches the namespace and its descendants; empty lists
+	// every namespace.
+	Namespace     string    `json:"namespace,omitempty"`
+	Scope         string    `json:"scope,omitempty"`
+	MinImportance int       `json:"min_importance,omitempty"`
+	MaxImportance int       `json:"max_importance,omitempty"`
+	CreatedAfter  time.Time `json:"created_after,omitzero"`
+	CreatedBefore time.Time `json:"created_before,omitzero"`
+	// WrittenBy keeps only memories whose source_agent equals it.
+	WrittenBy string `json:"written_by,omitempty"`
+	// Sort is created_desc (default), created_asc or importance_desc.
+	Sort  string `json:"sort,omitempty"`
+	Limit int    `json:"limit,omitempty"`
+	// Cursor is the NextCursor of the previous page.
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// ListResult is one page of memories. NextCursor is empty on the last
+// page.
+type ListResult struct {
+	Memories   []MemoryRecord `json:"memories"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// PurgeInput deletes, or with Redact blanks, every memory in any namespace
+// whose top-level metadata Key equals Value, compared as text, e.g. for a
+// data-deletion request about user_id 123.
+type PurgeInput struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Redact bool   `json:"redact,omitempty"`
+	DryRun bool   `json:"dry_run,omitempty"`
+}
+
+// PurgeResult reports memories purged, or matched when DryRun is set. It
+// lists where they were, never what they said.
+type PurgeResult struct {
+	DryRun bool `json:"dry_run"`
+	// Mode is "delete" or "redact".
+	Mode     string         `json:"mode"`
+	Count    int64          `json:"count"`
+	Memories []PurgedMemory `json:"memories"`
+	// Requests counts logged MCP requests whose params were cleared
+	// because they carried the key and value or a purged memory's ID or
+	// content.
+	Requests int64 `json:"requests,omitempty"`
+}
+
+// ForgetInput deletes every memory matching all of its filters, e.g. the
+// short-term memories of a finished task. Namespace or Metadata is
+// required so a forget never empties the whole store by accident.
+type ForgetInput struct {
+	Namespace string `json:"namespace,omitempty"`
+	// IncludeDescendants also forgets memories in namespaces below
+	// Namespace.
+	IncludeDescendants bool   `json:"include_descendants,omitempty"`
+	Scope              string `json:"scope,omitempty"`
+	// OlderThanSeconds keeps memories created more recently than this.
+	OlderThanSeconds int64 `json:"older_than_seconds,omitempty"`
+	// MaxImportance keeps memories more important than this; 0 matches
+	// any importance.
+	MaxImportance int `json:"max_importance,omitempty"`
+	// Metadata requires each top-level metadata key to equal the value,
+	// compared as text.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	DryRun   bool              `json:"dry_run,omitempty"`
+}
+
+// ForgetResult reports how many memories were forgotten, or matched when
+// DryRun is set. Dry runs also list the matches.
+type ForgetResult struct {
+	DryRun   bool           `json:"dry_run"`
+	Count    int64          `json:"count"`
+	Memories []PurgedMemory `json:"memories,omitempty"`
+}
+
+// PurgedMemory identifies one memory matched by a purge or forget.
+type PurgedMemory struct {
+	ID        string    `json:"id"`
+	Namespace string    `json:"namespace"`
+	Scope     string    `json:"scope"`
+	CreatedAt time.Time `json:"created_at"`
 }